@@ -0,0 +1,269 @@
+// Command payoutctl is the operator's admin tool for the payout engine.
+//
+//	payoutctl repair [-batch ID] [-apply] [-json]
+//	payoutctl audit verify
+//	payoutctl audit list -subject ID
+//	payoutctl views
+//	payoutctl payouts -view NAME [-page N] [-page-size N] [-json]
+//
+// repair reports payouts whose status contradicts their attempt history and
+// rebuilds batch counters and statuses from it. Without -apply it is a dry
+// run. audit checks the hash chain of the append-only audit log
+// (AUDIT_STORE=postgres) or prints the records about a batch or payout.
+// views lists the saved payout views, and payouts lists the payouts matching
+// one, the same triage queue the dashboard shows.
+// It connects with the same DB_* environment variables as the server.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/database"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "repair":
+		repair(os.Args[2:])
+	case "audit":
+		auditCmd(os.Args[2:])
+	case "views":
+		viewsCmd()
+	case "payouts":
+		payoutsCmd(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: payoutctl repair [-batch ID] [-apply] [-json]")
+	fmt.Fprintln(os.Stderr, "       payoutctl audit verify")
+	fmt.Fprintln(os.Stderr, "       payoutctl audit list -subject ID")
+	fmt.Fprintln(os.Stderr, "       payoutctl views")
+	fmt.Fprintln(os.Stderr, "       payoutctl payouts -view NAME [-page N] [-page-size N] [-json]")
+	os.Exit(2)
+}
+
+func repair(args []string) {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	batch := fs.String("batch", "", "repair one batch (default: all batches)")
+	apply := fs.Bool("apply", false, "write the repairs (default: dry run)")
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	fs.Parse(args)
+
+	batchID := uuid.Nil
+	if *batch != "" {
+		id, err := uuid.Parse(*batch)
+		if err != nil {
+			log.Fatalf("Invalid -batch: %v", err)
+		}
+		batchID = id
+	}
+
+	ctx := context.Background()
+	repo, closeDB := openRepository(ctx)
+	defer closeDB()
+
+	inconsistencies, err := repo.FindInconsistencies(ctx, batchID)
+	if err != nil {
+		log.Fatalf("Check failed: %v", err)
+	}
+
+	ids := []uuid.UUID{batchID}
+	if batchID == uuid.Nil {
+		if ids, err = repo.ListBatchIDs(ctx); err != nil {
+			log.Fatalf("List batches failed: %v", err)
+		}
+	}
+	var repairs []models.BatchRepair
+	for _, id := range ids {
+		r, err := repo.RepairBatch(ctx, id, *apply)
+		if err != nil {
+			log.Fatalf("Repair of batch %s failed: %v", id, err)
+		}
+		if r.Changed() || r.Skipped != "" {
+			repairs = append(repairs, *r)
+		}
+	}
+
+	if *asJSON {
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "  ")
+		out.Encode(map[string]any{
+			"applied":         *apply,
+			"inconsistencies": inconsistencies,
+			"batches":         repairs,
+		})
+		return
+	}
+
+	fmt.Printf("%d payout(s) contradict their attempt history\n", len(inconsistencies))
+	for _, p := range inconsistencies {
+		fix := ""
+		if p.Repairable {
+			fix = " [repairable]"
+		}
+		fmt.Printf("  payout %s (batch %s): %s - %s%s\n", p.PayoutID, p.BatchID, p.Kind, p.Detail, fix)
+	}
+
+	verb := "would change"
+	if *apply {
+		verb = "changed"
+	}
+	fmt.Printf("%d batch(es) %s\n", len(repairs), verb)
+	for _, r := range repairs {
+		if r.Skipped != "" {
+			fmt.Printf("  batch %s: skipped, %s\n", r.BatchID, r.Skipped)
+			continue
+		}
+		fmt.Printf("  batch %s: status %s -> %s, completed/failed/pending %d/%d/%d -> %d/%d/%d, %d payout(s) marked completed\n",
+			r.BatchID, r.StatusBefore, r.StatusAfter,
+			r.CountsBefore.Completed, r.CountsBefore.Failed, r.CountsBefore.Pending,
+			r.CountsAfter.Completed, r.CountsAfter.Failed, r.CountsAfter.Pending,
+			r.PayoutsRepaired)
+	}
+	if !*apply && len(repairs) > 0 {
+		fmt.Println("Dry run; re-run with -apply to write these changes.")
+	}
+}
+
+func auditCmd(args []string) {
+	if len(args) == 0 {
+		usage()
+	}
+	ctx := context.Background()
+	db, closeDB := openDB(ctx)
+	defer closeDB()
+	store := audit.NewPostgres(db)
+
+	switch args[0] {
+	case "verify":
+		res, err := store.Verify(ctx)
+		if err != nil {
+			log.Fatalf("Verify failed: %v", err)
+		}
+		if !res.Valid {
+			fmt.Printf("BROKEN at record %d: %s (%d records checked)\n", res.BrokenAt, res.Reason, res.Records)
+			os.Exit(1)
+		}
+		fmt.Printf("OK: %d records, head %s\n", res.Records, res.Head)
+	case "list":
+		fs := flag.NewFlagSet("audit list", flag.ExitOnError)
+		subject := fs.String("subject", "", "batch or payout ID")
+		fs.Parse(args[1:])
+		id, err := uuid.Parse(*subject)
+		if err != nil {
+			log.Fatalf("Invalid -subject: %v", err)
+		}
+		records, err := store.List(ctx, id)
+		if err != nil {
+			log.Fatalf("List failed: %v", err)
+		}
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "  ")
+		out.Encode(records)
+	default:
+		usage()
+	}
+}
+
+func viewsCmd() {
+	ctx := context.Background()
+	repo, closeDB := openRepository(ctx)
+	defer closeDB()
+
+	views, err := repo.ListPayoutViews(ctx)
+	if err != nil {
+		log.Fatalf("List views failed: %v", err)
+	}
+	for _, v := range views {
+		fmt.Printf("%s\t%s\n", v.Name, v.Description)
+	}
+}
+
+func payoutsCmd(args []string) {
+	fs := flag.NewFlagSet("payouts", flag.ExitOnError)
+	name := fs.String("view", "", "saved payout view")
+	page := fs.Int("page", 1, "page number")
+	pageSize := fs.Int("page-size", 50, "payouts per page")
+	asJSON := fs.Bool("json", false, "print the payouts as JSON")
+	fs.Parse(args)
+	if *name == "" || *page < 1 || *pageSize < 1 {
+		usage()
+	}
+
+	ctx := context.Background()
+	repo, closeDB := openRepository(ctx)
+	defer closeDB()
+
+	view, err := repo.GetPayoutView(ctx, *name)
+	if err != nil {
+		log.Fatalf("Get view failed: %v", err)
+	}
+	if view == nil {
+		log.Fatalf("No payout view named %q", *name)
+	}
+	payouts, total, err := repo.ListPayouts(ctx, view.Filter, *page, *pageSize)
+	if err != nil {
+		log.Fatalf("List payouts failed: %v", err)
+	}
+
+	if *asJSON {
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "  ")
+		out.Encode(models.PayoutListResponse{Payouts: payouts, TotalCount: total, Page: *page, PageSize: *pageSize})
+		return
+	}
+	fmt.Printf("%d payout(s) in view %s, page %d\n", total, view.Name, *page)
+	for _, p := range payouts {
+		reason := ""
+		if p.FailureReason != nil {
+			reason = " " + *p.FailureReason
+		}
+		fmt.Printf("  payout %s (batch %s): %s %.2f %s %s%s\n", p.ID, p.BatchID, p.VendorID, p.Amount, p.Currency, p.Status, reason)
+	}
+}
+
+func openRepository(ctx context.Context) (*repository.Repository, func() error) {
+	db, closeDB := openDB(ctx)
+	return repository.New(db), closeDB
+}
+
+func openDB(ctx context.Context) (*sql.DB, func() error) {
+	cfg := database.Config{
+		Driver:   getEnv("DB_DRIVER", database.DriverPostgres),
+		Host:     getEnv("DB_HOST", "localhost"),
+		Port:     getEnv("DB_PORT", "5432"),
+		User:     getEnv("DB_USER", "postgres"),
+		Password: getEnv("DB_PASSWORD", "postgres"),
+		Name:     getEnv("DB_NAME", "kaveri_payouts"),
+	}
+	db, closeDB, err := database.Open(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db, closeDB
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
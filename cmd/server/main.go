@@ -1,71 +1,700 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // BANK_CUTOFF_TZ must resolve in minimal containers
 
 	"coding-challenge/internal/api"
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/compliance"
+	"coding-challenge/internal/database"
+	"coding-challenge/internal/ingest"
+	"coding-challenge/internal/metrics"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/notify/email"
+	"coding-challenge/internal/notify/webhook"
+	"coding-challenge/internal/pgp"
+	"coding-challenge/internal/purpose"
+	"coding-challenge/internal/quota"
 	"coding-challenge/internal/repository"
+	"coding-challenge/internal/scheduler"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/statustoken"
+	"coding-challenge/internal/taxid"
 	"coding-challenge/internal/worker"
 
-	_ "github.com/lib/pq"
+	"github.com/google/uuid"
 )
 
 func main() {
-	// Configuration from environment variables
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "5432")
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPass := getEnv("DB_PASSWORD", "postgres")
-	dbName := getEnv("DB_NAME", "kaveri_payouts")
+	// Configuration from environment variables, optionally overridden by CONFIG_FILE
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadEnvFile(path); err != nil {
+			log.Fatalf("Invalid CONFIG_FILE: %v", err)
+		}
+	}
+	dbCfg := database.Config{
+		Driver:   getEnv("DB_DRIVER", database.DriverPostgres),
+		Host:     getEnv("DB_HOST", "localhost"),
+		Port:     getEnv("DB_PORT", "5432"),
+		User:     getEnv("DB_USER", "postgres"),
+		Password: getEnv("DB_PASSWORD", "postgres"),
+		Name:     getEnv("DB_NAME", "kaveri_payouts"),
+	}
 	serverPort := getEnv("SERVER_PORT", "8080")
 	concurrency, _ := strconv.Atoi(getEnv("WORKER_CONCURRENCY", "10"))
 	chunkSize, _ := strconv.Atoi(getEnv("WORKER_CHUNK_SIZE", "100"))
 
-	// Connect to PostgreSQL
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPass, dbName,
-	)
+	rampUp := getEnvDuration("WORKER_RAMP_UP", 0)
 
-	db, err := sql.Open("postgres", dsn)
+	bankCfg := bankConfig()
+	bank, err := service.NewBank(bankCfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Invalid bank configuration: %v", err)
+	}
+
+	// Demo mode runs every clock-driven wait (scheduled retries, backoff,
+	// cutoffs, leases, the watchdog) on an accelerated clock.
+	var clk clock.Clock
+	if speed := os.Getenv("DEMO_CLOCK_SPEED"); speed != "" {
+		factor, err := strconv.ParseFloat(speed, 64)
+		if err != nil || factor <= 0 {
+			log.Fatalf("Invalid DEMO_CLOCK_SPEED %q (want a factor > 0)", speed)
+		}
+		if bankCfg.Environment == models.EnvironmentProduction {
+			log.Fatal("DEMO_CLOCK_SPEED cannot be used with the production bank environment")
+		}
+		clk = clock.NewAccelerated(factor)
+		log.Printf("Demo mode: the clock runs %gx real time (a day every %s)", factor, time.Duration(float64(24*time.Hour)/factor).Round(time.Second))
+	}
+
+	bankCutoffs, err := loadBankCutoffs()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bankFees, err := service.ParseBankFees(os.Getenv("BANK_FEES"))
+	if err != nil {
+		log.Fatalf("Invalid BANK_FEES: %v", err)
+	}
+
+	taxIDThresholds, err := taxid.ParseThresholds(os.Getenv("TAX_ID_THRESHOLDS"))
+	if err != nil {
+		log.Fatalf("Invalid TAX_ID_THRESHOLDS: %v", err)
+	}
+
+	quotas, err := quota.Parse(os.Getenv("MERCHANT_QUOTAS"))
+	if err != nil {
+		log.Fatalf("Invalid MERCHANT_QUOTAS: %v", err)
+	}
+	if quotas.WarnAt, err = strconv.ParseFloat(getEnv("QUOTA_WARN_AT", "0.8"), 64); err != nil {
+		log.Fatalf("Invalid QUOTA_WARN_AT: %v", err)
+	}
+
+	var reporting compliance.Rules
+	reporting.Thresholds, err = compliance.ParseThresholds(os.Getenv("REPORTING_THRESHOLDS"))
+	if err != nil {
+		log.Fatalf("Invalid REPORTING_THRESHOLDS: %v", err)
+	}
+	reporting.RequireApproval, err = strconv.ParseBool(getEnv("REPORTING_APPROVAL", "false"))
+	if err != nil {
+		log.Fatalf("Invalid REPORTING_APPROVAL: %v", err)
+	}
+	purposeDefaults, err := purpose.ParseDefaults(os.Getenv("PURPOSE_CODE_DEFAULTS"))
+	if err != nil {
+		log.Fatalf("Invalid PURPOSE_CODE_DEFAULTS: %v", err)
+	}
+
+	inFlightLimits, err := worker.ParseInFlightLimits(os.Getenv("MAX_IN_FLIGHT"))
+	if err != nil {
+		log.Fatalf("Invalid MAX_IN_FLIGHT: %v", err)
+	}
+
+	claimStrategy, err := worker.ParseClaimStrategy(os.Getenv("WORKER_CLAIM_STRATEGY"))
+	if err != nil {
+		log.Fatalf("Invalid WORKER_CLAIM_STRATEGY: %v", err)
+	}
+
+	chunkLow, chunkHigh, err := worker.ParseChunkTarget(os.Getenv("WORKER_CHUNK_TARGET"))
+	if err != nil {
+		log.Fatalf("Invalid WORKER_CHUNK_TARGET: %v", err)
+	}
+
+	exportKey, err := loadExportKey()
+	if err != nil {
+		log.Fatal(err)
 	}
-	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Database unreachable: %v", err)
+	retryBackoff, err := worker.ParseRetryBackoff(os.Getenv("RETRY_BACKOFF"))
+	if err != nil {
+		log.Fatalf("Invalid RETRY_BACKOFF: %v", err)
+	}
+
+	statusTokens := statustoken.NewRandom()
+	if secret := os.Getenv("STATUS_TOKEN_SECRET"); secret != "" {
+		statusTokens = statustoken.New([]byte(secret))
+	} else {
+		log.Println("Warning: STATUS_TOKEN_SECRET not set; vendor status tokens will stop working on restart")
+	}
+
+	watchdogInterval := getEnvDuration("WATCHDOG_INTERVAL", time.Minute)
+	watchdogStallAfter := getEnvDuration("WATCHDOG_STALL_AFTER", 10*time.Minute)
+	shutdownTimeout := getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+
+	apiCfg := api.DefaultConfig()
+	apiCfg.ReadTimeout = getEnvDuration("REQUEST_TIMEOUT_READ", apiCfg.ReadTimeout)
+	apiCfg.WriteTimeout = getEnvDuration("REQUEST_TIMEOUT_WRITE", apiCfg.WriteTimeout)
+	apiCfg.CreateTimeout = getEnvDuration("REQUEST_TIMEOUT_CREATE", apiCfg.CreateTimeout)
+	apiCfg.BankCutoffs = bankCutoffs
+	apiCfg.Clock = clk
+	apiCfg.StatusTokens = statusTokens
+	apiCfg.BankFees = bankFees
+	apiCfg.ExportKey = exportKey
+	apiCfg.TaxIDThresholds = taxIDThresholds
+	apiCfg.Quotas = quotas
+	apiCfg.Reporting = reporting
+	apiCfg.EstimateHistory = getEnvDuration("ESTIMATE_HISTORY", apiCfg.EstimateHistory)
+	switch check := getEnv("DUPLICATE_CHECK", models.DuplicateCheckOff); check {
+	case models.DuplicateCheckOff, models.DuplicateCheckWarn, models.DuplicateCheckBlock:
+		apiCfg.DuplicateCheck = check
+	default:
+		log.Fatalf("Invalid DUPLICATE_CHECK %q (want off, warn or block)", check)
+	}
+	apiCfg.DuplicateWindow = getEnvDuration("DUPLICATE_CHECK_WINDOW", apiCfg.DuplicateWindow)
+	apiCfg.Admin = api.AdminConfig{
+		Token:    os.Getenv("ADMIN_TOKEN"),
+		Separate: os.Getenv("ADMIN_PORT") != "",
+		Reload:   reloadConfig(bankCutoffs, exportKey),
+	}
+	readOnly, err := strconv.ParseBool(getEnv("READ_ONLY", "false"))
+	if err != nil {
+		log.Fatalf("Invalid READ_ONLY: %v", err)
+	}
+	apiCfg.ReadOnly = readOnly
+	autoResume, err := strconv.ParseBool(getEnv("AUTO_RESUME", "false"))
+	if err != nil {
+		log.Fatalf("Invalid AUTO_RESUME: %v", err)
+	}
+	recoverOrphans, err := strconv.ParseBool(getEnv("RECOVER_ORPHANS_ON_START", "false"))
+	if err != nil {
+		log.Fatalf("Invalid RECOVER_ORPHANS_ON_START: %v", err)
+	}
+	apiCfg.Signing = api.SigningConfig{
+		Keys:    signingKeys(),
+		MaxSkew: getEnvDuration("REQUEST_SIGNING_MAX_SKEW", 5*time.Minute),
+	}
+	if sunset := os.Getenv("API_V1_SUNSET"); sunset != "" {
+		if apiCfg.V1Sunset, err = time.Parse(time.DateOnly, sunset); err != nil {
+			log.Fatalf("Invalid API_V1_SUNSET %q (want YYYY-MM-DD)", sunset)
+		}
+	}
+
+	// Connect to PostgreSQL (external, or embedded with DB_DRIVER=embedded)
+	db, closeDB, err := database.Open(context.Background(), dbCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	log.Println("Connected to PostgreSQL")
+	defer closeDB()
+	log.Printf("Connected to PostgreSQL (driver=%s)", dbCfg.Driver)
 
 	// Configure connection pool
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
 
 	// Initialize layers
-	repo := repository.New(db)
-	pool := worker.NewPool(repo, concurrency, chunkSize)
-	router := api.SetupRouter(repo, pool)
+	repoOpts := []repository.Option{repository.WithReportingRules(reporting), repository.WithPurposeDefaults(purposeDefaults)}
+	if clk != nil {
+		repoOpts = append(repoOpts, repository.WithClock(clk))
+	}
+	switch store := os.Getenv("AUDIT_STORE"); store {
+	case "":
+	case "postgres":
+		repoOpts = append(repoOpts, repository.WithAuditStore(audit.NewPostgres(db)))
+		log.Println("Audit records are copied to the append-only audit.records table")
+	default:
+		log.Fatalf("Invalid AUDIT_STORE %q (want postgres)", store)
+	}
+	repo := repository.New(db, repoOpts...)
+	poolOpts := []worker.Option{
+		worker.WithRampUp(rampUp),
+		worker.WithBankClient(bank),
+		worker.WithEnvironment(bankCfg.Environment),
+		worker.WithInFlightLimits(inFlightLimits),
+		worker.WithClaimStrategy(claimStrategy),
+		worker.WithChunkTarget(chunkLow, chunkHigh),
+		worker.WithRetryBackoff(retryBackoff),
+	}
+	if clk != nil {
+		poolOpts = append(poolOpts, worker.WithClock(clk))
+	}
+	if leaseTTL := getEnvDuration("BATCH_LEASE_TTL", 0); leaseTTL > 0 {
+		poolOpts = append(poolOpts, worker.WithLeases(repo, instanceID(), leaseTTL))
+	}
+	if claimTTL := getEnvDuration("PAYOUT_CLAIM_TTL", 0); claimTTL > 0 {
+		poolOpts = append(poolOpts, worker.WithClaimLeases(repo, instanceID(), claimTTL))
+	}
+	if every := getEnvDuration("STATS_SNAPSHOT_INTERVAL", time.Minute); every > 0 {
+		poolOpts = append(poolOpts, worker.WithStatisticsSnapshots(repo, every))
+	}
+	maxInFlight, _ := strconv.Atoi(getEnv("GOVERNOR_MAX_IN_FLIGHT", "0"))
+	maxWrites, _ := strconv.ParseFloat(getEnv("GOVERNOR_MAX_WRITES_PER_SEC", "0"), 64)
+	if maxInFlight > 0 || maxWrites > 0 {
+		poolOpts = append(poolOpts, worker.WithGovernor(worker.NewGovernor(repo, maxInFlight, maxWrites)))
+		log.Printf("Governor enabled: at most %d payouts in flight, %.1f writes/s (0 = unbounded)", maxInFlight, maxWrites)
+	}
+	if every := getEnvDuration("ATTEMPT_RETRY_INTERVAL", 5*time.Second); every > 0 {
+		queueMax, _ := strconv.Atoi(getEnv("ATTEMPT_RETRY_MAX", "10000"))
+		poolOpts = append(poolOpts, worker.WithAttemptRetry(every, queueMax, getEnv("ATTEMPT_SPILL_FILE", "pending-attempts.ndjson")))
+	}
+	if provider := emailProvider(); provider != nil {
+		statusURL := os.Getenv("NOTIFY_STATUS_URL")
+		notifier := email.NewNotifier(provider, repo, email.Config{
+			From: getEnv("EMAIL_FROM", "payouts@example.com"),
+			StatusURL: func(id uuid.UUID) string {
+				if statusURL == "" {
+					return ""
+				}
+				return statusURL + statusTokens.Sign(id)
+			},
+		})
+		go notifier.Run(context.Background())
+		poolOpts = append(poolOpts, worker.WithNotifier(notifier))
+		log.Printf("Vendor emails enabled via %s", provider.Name())
+	}
+	webhooks := webhook.NewDispatcher(repo, webhook.Config{Source: getEnv("EVENT_SOURCE", webhook.DefaultSource)})
+	go webhooks.Run(context.Background())
+	poolOpts = append(poolOpts, worker.WithNotifier(webhooks))
+	apiCfg.Webhooks = webhooks
+
+	payoutMetrics := metrics.NewPayouts(concurrency)
+	poolOpts = append(poolOpts, worker.WithMetrics(payoutMetrics), worker.WithHooks(payoutMetrics.Hook()))
+	apiCfg.Metrics = payoutMetrics
+	// A standby cannot write the usage it would meter.
+	usageFlush := getEnvDuration("USAGE_FLUSH_INTERVAL", time.Minute)
+	if usageFlush > 0 && !readOnly {
+		apiCfg.Usage = api.NewUsageMeter(repo, clk)
+	}
+
+	// A standby cannot record the job runs it would start.
+	if !readOnly {
+		apiCfg.Jobs = backgroundJobs(repo, clk)
+	}
+
+	pool := worker.NewPool(repo, concurrency, chunkSize, poolOpts...)
+	router := api.SetupRouter(repo, pool, apiCfg)
+
+	// Cancelled on SIGINT/SIGTERM: stops the consumer and watchdog, then
+	// starts the shutdown below.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	if readOnly {
+		// A standby never writes: nothing consumes instructions or pauses
+		// stalled batches, and the API rejects every mutation.
+		log.Println("Read-only mode: serving reads only; processing is left to the primary instance")
+	} else if proxy := os.Getenv("KAFKA_REST_URL"); proxy != "" {
+		source := &ingest.RESTProxy{
+			URL:   proxy,
+			Group: getEnv("KAFKA_GROUP", "kaveri-payouts"),
+			Topic: getEnv("KAFKA_TOPIC", "payout-instructions"),
+		}
+		maxBatch, _ := strconv.Atoi(getEnv("KAFKA_BATCH_MAX", "1000"))
+		consumer := ingest.NewConsumer(source, repo, pool, ingest.Config{
+			Window:       getEnvDuration("KAFKA_BATCH_WINDOW", time.Minute),
+			MaxBatchSize: maxBatch,
+		})
+		go consumer.Run(ctx)
+		log.Printf("Consuming payout instructions from Kafka topic %s (group %s) via %s", source.Topic, source.Group, proxy)
+	}
+
+	if !readOnly && watchdogInterval > 0 && watchdogStallAfter > 0 {
+		go worker.NewWatchdog(repo, pool, watchdogInterval, watchdogStallAfter).Run(ctx)
+	}
+	if !readOnly {
+		go pool.WatchLeases(ctx)
+		go pool.RetryAttemptLogs(ctx)
+	}
+	if apiCfg.Usage != nil {
+		go apiCfg.Usage.Run(ctx, usageFlush)
+	}
+	if apiCfg.Jobs != nil {
+		go apiCfg.Jobs.Run(ctx)
+	}
+
+	if recoverOrphans && !readOnly {
+		report, err := repo.RecoverOrphanedPayouts(ctx, true)
+		if err != nil {
+			log.Fatalf("Failed to recover orphaned payouts: %v", err)
+		}
+		log.Printf("Reset %d orphaned payouts to pending", report.Reset)
+		for _, b := range report.Batches {
+			if b.Skipped != "" {
+				log.Printf("  batch %s: skipped, %s", b.BatchID, b.Skipped)
+				continue
+			}
+			log.Printf("  batch %s: %d in processing, %d reset, %d leased, %d out of attempts", b.BatchID, b.Processing, b.Reset, b.Leased, b.Exhausted)
+		}
+	}
+	if autoResume && !readOnly {
+		resumed, err := pool.ResumeInProgress(ctx)
+		if err != nil {
+			log.Fatalf("Failed to resume in-progress batches: %v", err)
+		}
+		log.Printf("Resumed %d in-progress batches", resumed)
+	}
+
+	if len(apiCfg.Signing.Keys) > 0 {
+		log.Printf("Request signing required for mutating requests (%d keys, max skew %s)", len(apiCfg.Signing.Keys), apiCfg.Signing.MaxSkew)
+	}
+	if apiCfg.Admin.Token == "" {
+		log.Println("ADMIN_TOKEN not set; the admin API is disabled")
+	}
+	var servers []*http.Server
+	if apiCfg.Admin.Token != "" && apiCfg.Admin.Separate {
+		admin := &http.Server{Addr: ":" + os.Getenv("ADMIN_PORT"), Handler: api.SetupAdminRouter(repo, pool, apiCfg)}
+		servers = append(servers, admin)
+		go func() {
+			log.Printf("Admin API listening on %s", admin.Addr)
+			if err := admin.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Admin server failed: %v", err)
+			}
+		}()
+	}
 
 	// Start server
 	addr := ":" + serverPort
 	log.Printf("Kaveri Batch Payout Engine starting on %s", addr)
-	log.Printf("Config: concurrency=%d, chunk_size=%d", concurrency, chunkSize)
+	log.Printf("Bank adapter %s in the %s environment", bankCfg.Adapter, bankCfg.Environment)
+	log.Printf("Config: concurrency=%d, chunk_size=%d, ramp_up=%s, claim_strategy=%s", concurrency, chunkSize, rampUp, claimStrategy)
+	if chunkHigh > 0 {
+		log.Printf("Chunk size tuned to take %s-%s per chunk", chunkLow, chunkHigh)
+	}
+	if len(inFlightLimits) > 0 {
+		log.Printf("In-flight limits per currency: %v", inFlightLimits)
+	}
+	log.Printf("Request budgets: read=%s, write=%s, create=%s", apiCfg.ReadTimeout, apiCfg.WriteTimeout, apiCfg.CreateTimeout)
 	log.Println("Endpoints:")
-	log.Println("  POST   /api/v1/batches              - Create batch")
-	log.Println("  GET    /api/v1/batches/:id           - Batch status")
-	log.Println("  POST   /api/v1/batches/:id/start     - Start/resume")
-	log.Println("  POST   /api/v1/batches/:id/stop      - Stop processing")
-	log.Println("  GET    /api/v1/batches/:id/payouts   - List payouts")
+	log.Println("  GET    /api/v1/batches                  - List batches")
+	log.Println("  POST   /api/v1/batches                  - Create batch")
+	log.Println("  POST   /api/v1/batches/import           - Create batch from CSV")
+	log.Println("  GET    /api/v1/batches/:id              - Batch status")
+	log.Println("  DELETE /api/v1/batches/:id              - Soft-delete batch")
+	log.Println("  POST   /api/v1/batches/:id/restore      - Restore deleted batch")
+	log.Println("  POST   /api/v1/batches/:id/start        - Start/resume")
+	log.Println("  POST   /api/v1/batches/:id/stop         - Stop processing")
+	log.Println("  GET    /api/v1/batches/:id/payouts      - List payouts")
+	log.Println("  GET    /api/v1/batches/:id/statistics   - Stats by vendor attribute")
+	log.Println("  GET    /api/v1/batches/:id/financials   - Money totals per currency")
+	log.Println("  GET    /api/v1/batches/:id/export       - Payouts as CSV")
+	log.Println("  GET    /api/v1/batches/:id/runs         - Run history")
+	log.Println("  GET    /api/v1/batches/:id/estimate     - Expected duration, failures, fees")
 	log.Println("  POST   /api/v1/batches/:id/retry-failed - Retry failures")
+	log.Println("  POST   /api/v1/batches/:id/verify       - Consistency check")
+	log.Println("  GET    /api/v1/overview                 - System overview")
+	log.Println("  GET    /api/v1/reports/exposure         - Money in flight per currency")
+	log.Println("  GET    /api/v1/reports/settlement-cutoffs - Settles today vs later, per bank")
+	log.Println("  GET    /api/v1/vendors/search           - Vendor name search")
+	log.Println("  GET    /api/v1/funding-accounts         - Funding balances")
+	log.Println("  GET    /api/v1/import-profiles          - CSV import profiles")
+	log.Println("  PUT    /api/v1/import-profiles/:name    - Save import profile")
+	log.Println("  GET    /api/v1/payouts/:id              - Payout detail")
+	log.Println("  GET    /api/v1/payout-status/:token     - Vendor status lookup")
+	log.Println("  GET    /debug/vars                      - Runtime counters")
+	if apiCfg.Admin.Token != "" {
+		log.Println("Admin endpoints (bearer ADMIN_TOKEN + X-Operator):")
+		log.Println("  GET    /admin/v1/batches                - List batches, ?include_deleted=true")
+		log.Println("  POST   /admin/v1/batches/:id/settle     - Re-run funding settlement")
+		log.Println("  POST   /admin/v1/payouts/:id/force-complete - Mark paid outside the engine")
+		log.Println("  PUT    /admin/v1/funding-accounts/:currency - Set funding balance")
+		log.Println("  GET|PUT /admin/v1/simulator/chaos      - Simulator fault injection")
+		log.Println("  GET|PUT /admin/v1/maintenance          - Maintenance mode")
+		log.Println("  POST   /admin/v1/config/reload          - Reload CONFIG_FILE (bank cutoffs)")
+		if apiCfg.Jobs != nil {
+			log.Println("  GET    /admin/v1/jobs                   - Background jobs and last runs")
+			log.Println("  POST   /admin/v1/jobs/:name/run         - Run a job now")
+		}
+	}
+
+	srv := &http.Server{Addr: addr, Handler: router}
+	servers = append(servers, srv)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdown(servers, pool, apiCfg.Usage, shutdownTimeout)
+}
+
+// shutdown stops accepting requests and lets the ones in progress finish,
+// then writes the API usage they were metered for, stops the pool and waits
+// for its workers to finish the payouts they are transferring, so runs pause
+// their batches with nothing left in processing. Whatever is still running
+// after timeout is cut off.
+func shutdown(servers []*http.Server, pool *worker.Pool, usage *api.UsageMeter, timeout time.Duration) {
+	log.Printf("Shutting down (timeout %s)", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Warning: HTTP server on %s did not shut down cleanly: %v", srv.Addr, err)
+		}
 	}
+	if usage != nil {
+		if err := usage.Flush(ctx); err != nil {
+			log.Printf("Warning: API usage since the last flush was not written: %v", err)
+		}
+	}
+	if err := pool.Shutdown(ctx); err != nil {
+		log.Printf("Warning: runs still transferring after %s were cancelled; their payouts are reset on the next run: %v", timeout, err)
+		return
+	}
+	log.Println("Shutdown complete")
+}
+
+// loadBankCutoffs reads BANK_CUTOFFS in the BANK_CUTOFF_TZ time zone.
+func loadBankCutoffs() (*service.BankCutoffs, error) {
+	tz, err := time.LoadLocation(getEnv("BANK_CUTOFF_TZ", "Asia/Jakarta"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BANK_CUTOFF_TZ: %w", err)
+	}
+	cutoffs, err := service.ParseBankCutoffs(os.Getenv("BANK_CUTOFFS"), tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BANK_CUTOFFS: %w", err)
+	}
+	return cutoffs, nil
+}
+
+// loadExportKey reads the public key exports are encrypted to from the
+// file EXPORT_PGP_PUBLIC_KEY_FILE (e.g. a mounted secret) or, failing that,
+// from EXPORT_PGP_PUBLIC_KEY itself. Without either, encrypted exports are
+// refused.
+func loadExportKey() (*pgp.Key, error) {
+	data := []byte(os.Getenv("EXPORT_PGP_PUBLIC_KEY"))
+	if path := os.Getenv("EXPORT_PGP_PUBLIC_KEY_FILE"); path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("invalid EXPORT_PGP_PUBLIC_KEY_FILE: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return &pgp.Key{}, nil
+	}
+	key, err := pgp.ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export public key: %w", err)
+	}
+	return key, nil
+}
+
+// reloadConfig re-reads CONFIG_FILE, if set, and applies the settings that
+// can change without a restart: the bank cutoffs and the export encryption
+// key, so a rotated key takes effect without a restart.
+func reloadConfig(cutoffs *service.BankCutoffs, exportKey *pgp.Key) func() error {
+	return func() error {
+		if path := os.Getenv("CONFIG_FILE"); path != "" {
+			if err := loadEnvFile(path); err != nil {
+				return err
+			}
+		}
+		fresh, err := loadBankCutoffs()
+		if err != nil {
+			return err
+		}
+		key, err := loadExportKey()
+		if err != nil {
+			return err
+		}
+		cutoffs.Replace(fresh)
+		exportKey.Replace(key)
+		log.Println("Configuration reloaded")
+		return nil
+	}
+}
+
+// loadEnvFile sets environment variables from KEY=VALUE lines; blank lines
+// and lines starting with # are skipped.
+func loadEnvFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: want KEY=VALUE", path, i+1)
+		}
+		os.Setenv(strings.TrimSpace(key), strings.Trim(strings.TrimSpace(value), `"`))
+	}
+	return nil
+}
+
+// bankConfig reads the bank adapter configuration. The SIM_* variables are
+// still honoured as simulator options unless BANK_OPTIONS sets them, and
+// are refused in production, where no simulator settings belong.
+func bankConfig() service.BankConfig {
+	cfg := service.BankConfig{
+		Adapter:     getEnv("BANK_ADAPTER", "simulator"),
+		Environment: getEnv("BANK_ENVIRONMENT", models.EnvironmentSandbox),
+	}
+	if cfg.Environment == models.EnvironmentProduction {
+		for _, env := range []string{"SIM_LATENCY_PROFILE", "SIM_BANK_LATENCY", "SIM_LATENCY_SCALE"} {
+			if os.Getenv(env) != "" {
+				log.Fatalf("%s is set but BANK_ENVIRONMENT is %s; unset it to run against the real bank", env, models.EnvironmentProduction)
+			}
+		}
+	}
+	var err error
+	if cfg.Options, err = service.ParseBankSettings(os.Getenv("BANK_OPTIONS")); err != nil {
+		log.Fatalf("Invalid BANK_OPTIONS: %v", err)
+	}
+	if cfg.Credentials, err = service.ParseBankSettings(os.Getenv("BANK_CREDENTIALS")); err != nil {
+		log.Fatalf("Invalid BANK_CREDENTIALS: %v", err)
+	}
+	if cfg.Adapter == "simulator" {
+		for env, option := range map[string]string{
+			"SIM_LATENCY_PROFILE": "latency_profile",
+			"SIM_BANK_LATENCY":    "bank_latency",
+			"SIM_LATENCY_SCALE":   "latency_scale",
+		} {
+			if val := os.Getenv(env); val != "" && cfg.Options[option] == "" {
+				cfg.Options[option] = strings.ReplaceAll(val, ",", ";")
+			}
+		}
+	}
+	return cfg
+}
+
+// signingKeys reads request signing keys from REQUEST_SIGNING_KEYS, given
+// as "keyID=secret,keyID=secret". Empty leaves signing off.
+func signingKeys() map[string]string {
+	keys := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv("REQUEST_SIGNING_KEYS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(id) == "" || secret == "" {
+			log.Fatalf("Invalid REQUEST_SIGNING_KEYS entry for %q (want keyID=secret)", strings.TrimSpace(id))
+		}
+		keys[strings.TrimSpace(id)] = secret
+	}
+	return keys
+}
+
+// backgroundJobs registers the background jobs with a scheduler. Jobs run
+// on schedule only when named in JOBS_ENABLED (comma-separated); their
+// default schedules are overridden by JOB_SCHEDULES in the form
+// "orphan_sweep=*/5 * * * *;other=@daily". Every instance runs its own
+// scheduler, so a job meant to run once per deployment is enabled on one.
+func backgroundJobs(repo *repository.Repository, clk clock.Clock) *scheduler.Scheduler {
+	jobs := []scheduler.Job{
+		{
+			// Resets payouts left in processing by runs that died, as
+			// POST /admin/v1/recover-orphans?apply=true does.
+			Name:     "orphan_sweep",
+			Schedule: "*/15 * * * *",
+			Run: func(ctx context.Context) error {
+				report, err := repo.RecoverOrphanedPayouts(ctx, true)
+				if err != nil {
+					return err
+				}
+				if report.Reset > 0 {
+					log.Printf("[scheduler] orphan_sweep reset %d orphaned payouts in %d batches", report.Reset, len(report.Batches))
+				}
+				return nil
+			},
+		},
+	}
+	byName := map[string]*scheduler.Job{}
+	for i := range jobs {
+		byName[jobs[i].Name] = &jobs[i]
+	}
+
+	for _, entry := range strings.Split(os.Getenv("JOB_SCHEDULES"), ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, expr, ok := strings.Cut(entry, "=")
+		job := byName[strings.TrimSpace(name)]
+		if !ok || job == nil {
+			log.Fatalf("Invalid JOB_SCHEDULES entry %q (want job=cron expression for a known job)", entry)
+		}
+		job.Schedule = strings.TrimSpace(expr)
+	}
+	for _, name := range strings.Split(os.Getenv("JOBS_ENABLED"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		job := byName[name]
+		if job == nil {
+			log.Fatalf("Invalid JOBS_ENABLED: unknown job %q", name)
+		}
+		job.Enabled = true
+	}
+
+	s := scheduler.New(repo, scheduler.WithClock(clk))
+	for _, job := range jobs {
+		if err := s.Add(job); err != nil {
+			log.Fatalf("Invalid JOB_SCHEDULES: %v", err)
+		}
+	}
+	return s
+}
+
+// emailProvider builds the vendor email provider from EMAIL_PROVIDER, or
+// returns nil when vendor emails are disabled.
+func emailProvider() email.Provider {
+	switch name := os.Getenv("EMAIL_PROVIDER"); name {
+	case "":
+		return nil
+	case "log":
+		return email.LogProvider{}
+	case "smtp":
+		return &email.SMTP{
+			Host:     getEnv("SMTP_HOST", "localhost"),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	case "ses":
+		return email.NewSES(getEnv("SES_REGION", "ap-southeast-1"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	case "sendgrid":
+		return &email.SendGrid{APIKey: os.Getenv("SENDGRID_API_KEY")}
+	default:
+		log.Fatalf("Invalid EMAIL_PROVIDER %q (want log, smtp, ses or sendgrid)", name)
+		return nil
+	}
+}
+
+// instanceID names this instance as the holder of its batch leases:
+// INSTANCE_ID, or the host name and process ID.
+func instanceID() string {
+	if id := os.Getenv("INSTANCE_ID"); id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
 }
 
 func getEnv(key, fallback string) string {
@@ -74,3 +703,13 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s=%q, using %s", key, val, fallback)
+	}
+	return fallback
+}
@@ -0,0 +1,247 @@
+//go:build e2e
+
+// Package e2e drives the full payout lifecycle over HTTP against a disposable
+// database: create, start, stop mid-run, resume, retry failures. Run with
+// `make e2e`.
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/database/dbtest"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+const (
+	payoutCount = 200
+	blocked     = 5  // e2e_0000..0004 are permanently blocked
+	rateLimited = 10 // e2e_0190..0199 exhaust their retries, then succeed on manual retry
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	dbtest.Close()
+	os.Exit(code)
+}
+
+// slowBank delays every transfer so a run can be stopped part-way through.
+type slowBank struct {
+	service.BankClient
+	delay time.Duration
+}
+
+func (b slowBank) Transfer(ctx context.Context, p models.Payout) (service.SimulatedBankResult, error) {
+	select {
+	case <-time.After(b.delay):
+	case <-ctx.Done():
+		return service.SimulatedBankResult{}, ctx.Err()
+	}
+	return b.BankClient.Transfer(ctx, p)
+}
+
+// client is a minimal JSON client for the server under test.
+type client struct {
+	t    *testing.T
+	base string
+}
+
+func (c client) do(method, path string, body, out any) int {
+	c.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, _ := http.NewRequest(method, c.base+path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Operator", "e2e")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (c client) batch(id uuid.UUID) models.BatchSummary {
+	var summary models.BatchSummary
+	if code := c.do(http.MethodGet, "/api/v1/batches/"+id.String(), nil, &summary); code != http.StatusOK {
+		c.t.Fatalf("Expected 200 for batch status, got %d", code)
+	}
+	return summary
+}
+
+// waitFor polls until cond holds, failing the test after timeout.
+func (c client) waitFor(what string, timeout time.Duration, cond func() bool) {
+	c.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			c.t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c client) waitIdle() {
+	c.waitFor("processor to go idle", 30*time.Second, func() bool {
+		var overview models.SystemOverview
+		c.do(http.MethodGet, "/api/v1/overview", nil, &overview)
+		return !overview.Processor.Running
+	})
+}
+
+// TestPayoutLifecycle codifies the manual resumability demo.
+func TestPayoutLifecycle(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := repository.New(db)
+
+	sc := service.NewScenario()
+	sc.For(service.VendorRange("e2e_%04d", 0, blocked)).Always(models.FailureAccountBlocked)
+	sc.For(service.VendorRange("e2e_%04d", payoutCount-rateLimited, payoutCount)).
+		Fail(models.FailureRateLimited, models.DefaultMaxRetries).ThenSucceed()
+
+	pool := worker.NewPool(repo, 4, 20, worker.WithBankClient(slowBank{BankClient: sc, delay: 10 * time.Millisecond}))
+	srv := httptest.NewServer(api.SetupRouter(repo, pool, api.DefaultConfig()))
+	defer srv.Close()
+	c := client{t: t, base: srv.URL}
+
+	// 1. Create the batch
+	items := make([]models.CreatePayoutItem, payoutCount)
+	for i := range items {
+		items[i] = models.CreatePayoutItem{
+			VendorID:    fmt.Sprintf("e2e_%04d", i),
+			VendorName:  fmt.Sprintf("E2E Vendor %d", i),
+			Amount:      1000 + float64(i),
+			Currency:    "IDR",
+			BankAccount: fmt.Sprintf("ID****%04d", i),
+			BankName:    "BCA",
+		}
+	}
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+	}
+	if code := c.do(http.MethodPost, "/api/v1/batches", models.CreateBatchRequest{Payouts: items}, &created); code != http.StatusCreated {
+		t.Fatalf("Expected 201 on create, got %d", code)
+	}
+	batchID := created.BatchID
+
+	// 2. Start, then stop part-way through
+	if code := c.do(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/start", nil, nil); code != http.StatusAccepted {
+		t.Fatalf("Expected 202 on start, got %d", code)
+	}
+	c.waitFor("partial progress", 30*time.Second, func() bool {
+		s := c.batch(batchID).Statistics
+		return s.Completed+s.Failed >= 40
+	})
+	if code := c.do(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/stop", nil, nil); code != http.StatusOK {
+		t.Fatalf("Expected 200 on stop, got %d", code)
+	}
+	c.waitIdle()
+
+	stopped := c.batch(batchID)
+	if stopped.Batch.Status != models.BatchStatusPaused {
+		t.Errorf("Expected paused after stop, got %s", stopped.Batch.Status)
+	}
+	if done := stopped.Statistics.Completed + stopped.Statistics.Failed; done >= payoutCount {
+		t.Fatalf("Expected the stop to land mid-run, but all %d payouts finished", done)
+	}
+	if stopped.Statistics.Processing != 0 {
+		t.Errorf("Expected no payouts left in processing after a graceful stop, got %d", stopped.Statistics.Processing)
+	}
+
+	// 3. Resume to the end
+	if code := c.do(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/start", nil, nil); code != http.StatusAccepted {
+		t.Fatalf("Expected 202 on resume, got %d", code)
+	}
+	c.waitIdle()
+
+	resumed := c.batch(batchID)
+	if resumed.Batch.Status != models.BatchStatusPartiallyCompleted {
+		t.Errorf("Expected partially_completed after resume, got %s", resumed.Batch.Status)
+	}
+	if resumed.Statistics.Completed != payoutCount-blocked-rateLimited || resumed.Statistics.Failed != blocked+rateLimited {
+		t.Errorf("Expected completed=%d failed=%d, got completed=%d failed=%d",
+			payoutCount-blocked-rateLimited, blocked+rateLimited, resumed.Statistics.Completed, resumed.Statistics.Failed)
+	}
+
+	// 4. Retry the retryable failures
+	var retry struct {
+		Requeued int `json:"requeued"`
+	}
+	if code := c.do(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/retry-failed", nil, &retry); code != http.StatusAccepted {
+		t.Fatalf("Expected 202 on retry-failed, got %d", code)
+	}
+	if retry.Requeued != rateLimited {
+		t.Errorf("Expected %d payouts requeued, got %d", rateLimited, retry.Requeued)
+	}
+	c.waitIdle()
+
+	final := c.batch(batchID)
+	if final.Statistics.Completed != payoutCount-blocked || final.Statistics.Failed != blocked {
+		t.Errorf("Expected completed=%d failed=%d after retry, got completed=%d failed=%d",
+			payoutCount-blocked, blocked, final.Statistics.Completed, final.Statistics.Failed)
+	}
+
+	// 5. Every payout was paid at most once
+	assertNoDuplicatePayments(t, db, batchID)
+
+	// 6. The run history reflects what happened
+	var runs models.BatchRunListResponse
+	c.do(http.MethodGet, "/api/v1/batches/"+batchID.String()+"/runs", nil, &runs)
+	want := []struct{ trigger, status string }{
+		{models.RunTriggerStart, models.RunStatusStopped},
+		{models.RunTriggerStart, models.RunStatusFinished},
+		{models.RunTriggerRetryFailed, models.RunStatusFinished},
+	}
+	if len(runs.Runs) != len(want) {
+		t.Fatalf("Expected %d runs, got %d", len(want), len(runs.Runs))
+	}
+	for i, w := range want {
+		r := runs.Runs[i]
+		if r.Trigger != w.trigger || r.Status != w.status || r.TriggeredBy != "e2e" {
+			t.Errorf("Run %d: expected %s/%s by e2e, got %s/%s by %s", i+1, w.trigger, w.status, r.Trigger, r.Status, r.TriggeredBy)
+		}
+	}
+}
+
+func assertNoDuplicatePayments(t *testing.T, db *sql.DB, batchID uuid.UUID) {
+	t.Helper()
+	var dupes int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT a.payout_id FROM payout_attempts a
+			JOIN payouts p ON p.id = a.payout_id
+			WHERE p.batch_id = $1 AND a.status = $2
+			GROUP BY a.payout_id HAVING COUNT(*) > 1
+		) d`, batchID, models.PayoutStatusCompleted).Scan(&dupes)
+	if err != nil {
+		t.Fatalf("Duplicate check failed: %v", err)
+	}
+	if dupes != 0 {
+		t.Errorf("Expected no payout to be paid twice, found %d", dupes)
+	}
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminConfig controls the /admin/v1 API, which holds the operations that
+// can move money or change how the engine behaves.
+type AdminConfig struct {
+	// Token must be sent as "Authorization: Bearer <token>". Empty disables
+	// the admin API.
+	Token string
+	// Separate serves the admin API only from SetupAdminRouter, so it can be
+	// bound to its own (e.g. internal-only) listener.
+	Separate bool
+	// Reload re-reads the settings that can change without a restart; nil
+	// means config reload is unavailable.
+	Reload func() error
+}
+
+// adminKey marks requests authenticated by the admin API.
+const adminKey = "admin"
+
+// isAdmin reports whether the request came through the admin API.
+func isAdmin(c *gin.Context) bool {
+	return c.GetBool(adminKey)
+}
+
+// AdminAuth requires the admin bearer token and a named operator, so every
+// admin action is attributable.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "error.admin_unauthorized")})
+			return
+		}
+		if actor(c) == models.AnonymousOperator {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.operator_required")})
+			return
+		}
+		c.Set(adminKey, true)
+		c.Next()
+	}
+}
+
+// Maintenance is the maintenance-mode switch shared by the public and admin
+// routers. While it is on, the public API rejects every request that could
+// change state. It is safe for concurrent use.
+type Maintenance struct {
+	mu     sync.RWMutex
+	status models.MaintenanceStatus
+}
+
+// NewMaintenance returns a switch that is off.
+func NewMaintenance() *Maintenance {
+	return &Maintenance{}
+}
+
+// Status returns the current maintenance state.
+func (m *Maintenance) Status() models.MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+func (m *Maintenance) set(status models.MaintenanceStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = status
+}
+
+// Guard rejects non-read requests with 503 while maintenance mode is on.
+func (m *Maintenance) Guard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if status := m.Status(); status.Enabled {
+			c.Header("Retry-After", "300")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":       tr(c, "error.maintenance", status.Message),
+				"maintenance": status,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// registerAdmin mounts the admin routes under /admin/v1.
+func registerAdmin(r gin.IRouter, h *Handler, cfg Config) {
+	read := Deadline(cfg.ReadTimeout)
+	write := Deadline(cfg.WriteTimeout)
+
+	admin := r.Group("/admin/v1", append([]gin.HandlerFunc{AdminAuth(cfg.Admin.Token)}, h.signatureCheck()...)...)
+	{
+		admin.GET("/batches", read, h.ListBatches)                           // Including soft-deleted batches
+		admin.POST("/batches/:id/settle", write, h.SettleBatch)              // Re-run funding settlement
+		admin.POST("/payouts/:id/force-complete", write, h.ForceComplete)    // Paid outside the engine
+		admin.POST("/recover-orphans", write, h.RecoverOrphans)              // Reset payouts left in processing by dead runs
+		admin.GET("/usage", read, h.GetUsageReport)                          // API calls, payouts and amounts per merchant
+		admin.PUT("/funding-accounts/:currency", write, h.SetFundingBalance) // Set a currency's balance
+		admin.GET("/simulator/chaos", read, h.GetChaos)                      // Faults injected into the simulator
+		admin.PUT("/simulator/chaos", write, h.SetChaos)                     // Inject or clear faults
+		admin.GET("/maintenance", read, h.GetMaintenance)                    // Maintenance mode state
+		admin.PUT("/maintenance", write, h.SetMaintenance)                   // Turn maintenance mode on or off
+		admin.POST("/config/reload", write, h.ReloadConfig)                  // Re-read reloadable settings
+		admin.GET("/worker-config", read, h.GetWorkerConfig)                 // Concurrency, chunk size, retry backoff
+		admin.PATCH("/worker-config", write, h.UpdateWorkerConfig)           // Change them without a restart
+		if cfg.Jobs != nil {
+			admin.GET("/jobs", read, h.ListJobs)           // Background jobs, schedules and last runs
+			admin.POST("/jobs/:name/run", write, h.RunJob) // Run a job now
+		}
+	}
+}
+
+// ForceComplete marks a failed or pending payout as paid outside the engine.
+// POST /admin/v1/payouts/:id/force-complete
+func (h *Handler) ForceComplete(c *gin.Context) {
+	payoutID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_payout_id")})
+		return
+	}
+	var req models.ForceCompleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+
+	payout, err := h.repo.ForceCompletePayout(c.Request.Context(), payoutID, actor(c), req.Reason)
+	switch {
+	case errors.Is(err, repository.ErrRunLive):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.run_live")})
+	case errors.Is(err, repository.ErrPayoutNotForceable):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.payout_not_forceable")})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case payout == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+	default:
+		payout.Links = payoutLinks(c, payout)
+		c.JSON(http.StatusOK, payout)
+	}
+}
+
+// RecoverOrphans sweeps every batch for payouts left in processing by runs
+// that no longer exist and returns what it found. It only reports what it
+// would reset unless called with ?apply=true.
+// POST /admin/v1/recover-orphans
+func (h *Handler) RecoverOrphans(c *gin.Context) {
+	apply := c.Query("apply") == "true"
+	report, err := h.repo.RecoverOrphanedPayouts(c.Request.Context(), apply)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if apply {
+		log.Printf("[api] %s reset %d orphaned payouts in %d batches", actor(c), report.Reset, len(report.Batches))
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// SettleBatch re-runs funding settlement for a batch.
+// POST /admin/v1/batches/:id/settle
+func (h *Handler) SettleBatch(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	reservations, err := h.repo.SettleBatch(c.Request.Context(), batchID, actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"batch_id": batchID, "reservations": reservations})
+}
+
+// chaosController is implemented by bank clients that support fault injection.
+type chaosController interface {
+	Chaos() service.Chaos
+	SetChaos(service.Chaos) error
+}
+
+// GetChaos returns the faults injected into the simulated bank.
+// GET /admin/v1/simulator/chaos
+func (h *Handler) GetChaos(c *gin.Context) {
+	ctl, ok := h.pool.Bank().(chaosController)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.no_chaos_controls")})
+		return
+	}
+	c.JSON(http.StatusOK, ctl.Chaos())
+}
+
+// SetChaos replaces the faults injected into the simulated bank; an empty
+// body object clears them.
+// PUT /admin/v1/simulator/chaos
+func (h *Handler) SetChaos(c *gin.Context) {
+	ctl, ok := h.pool.Bank().(chaosController)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.no_chaos_controls")})
+		return
+	}
+	var chaos service.Chaos
+	if err := c.ShouldBindJSON(&chaos); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	if err := ctl.SetChaos(chaos); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ctl.Chaos())
+}
+
+// GetMaintenance returns the maintenance mode state.
+// GET /admin/v1/maintenance
+func (h *Handler) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cfg.Maintenance.Status())
+}
+
+// SetMaintenance turns maintenance mode on or off.
+// PUT /admin/v1/maintenance
+func (h *Handler) SetMaintenance(c *gin.Context) {
+	var req models.SetMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	status := models.MaintenanceStatus{Enabled: *req.Enabled}
+	if status.Enabled {
+		now := h.cfg.Clock.Now().UTC()
+		status.Message, status.Since, status.By = req.Message, &now, actor(c)
+	}
+	h.cfg.Maintenance.set(status)
+	c.JSON(http.StatusOK, status)
+}
+
+// GetWorkerConfig returns the worker pool's current configuration.
+// GET /admin/v1/worker-config
+func (h *Handler) GetWorkerConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pool.Config())
+}
+
+// UpdateWorkerConfig changes the worker pool's concurrency, chunk size or
+// retry backoff. Runs in progress take the new values up at their next
+// chunk, except settings they were started with.
+// PATCH /admin/v1/worker-config
+func (h *Handler) UpdateWorkerConfig(c *gin.Context) {
+	var req models.UpdateWorkerConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	cfg, err := h.pool.UpdateConfig(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_worker_config", err.Error())})
+		return
+	}
+	log.Printf("[api] %s changed the worker config: concurrency=%d, chunk=%d, retry backoff %s",
+		actor(c), cfg.Concurrency, cfg.ChunkSize, cfg.RetryBackoff)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// ReloadConfig re-reads the settings that can change without a restart.
+// POST /admin/v1/config/reload
+func (h *Handler) ReloadConfig(c *gin.Context) {
+	if h.cfg.Admin.Reload == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": tr(c, "error.reload_unavailable")})
+		return
+	}
+	if err := h.cfg.Admin.Reload(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.reload_failed", err.Error())})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reloaded": true})
+}
@@ -0,0 +1,330 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestAdminAuth verifies the admin API needs the token and a named operator,
+// and is not mounted at all without a token.
+func TestAdminAuth(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), adminConfig())
+
+	cases := []struct {
+		name   string
+		token  string
+		op     string
+		status int
+	}{
+		{"no token", "", "ops@example.com", http.StatusUnauthorized},
+		{"wrong token", "nope", "ops@example.com", http.StatusUnauthorized},
+		{"anonymous", testAdminToken, "", http.StatusBadRequest},
+		{"ok", testAdminToken, "ops@example.com", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/admin/v1/maintenance", nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		if tc.op != "" {
+			req.Header.Set("X-Operator", tc.op)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.status, w.Code)
+		}
+	}
+
+	public := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+	w := httptest.NewRecorder()
+	public.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/maintenance", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without an admin token configured, got %d", w.Code)
+	}
+}
+
+// TestSeparateAdminListener verifies a separate admin router serves the
+// admin API, the public router does not, and both share maintenance mode.
+func TestSeparateAdminListener(t *testing.T) {
+	cfg := adminConfig()
+	cfg.Admin.Separate = true
+	pool := worker.NewPool(nil, 1, 10)
+	public := api.SetupRouter(nil, pool, cfg)
+	admin := api.SetupAdminRouter(nil, pool, cfg)
+
+	w := httptest.NewRecorder()
+	public.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/maintenance", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected the public router not to serve the admin API, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, adminRequest(http.MethodPut, "/admin/v1/maintenance", `{"enabled":true,"message":"bank migration"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 enabling maintenance, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	public.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(`{}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 creating a batch in maintenance, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "bank migration") {
+		t.Errorf("Expected the maintenance message in the error, got %s", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	public.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected reads to keep working in maintenance, got %d", w.Code)
+	}
+}
+
+// TestReadOnlyMode verifies a read-only instance rejects every mutation,
+// public and admin, and keeps serving reads and health checks.
+func TestReadOnlyMode(t *testing.T) {
+	cfg := adminConfig()
+	cfg.ReadOnly = true
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), cfg)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(`{"payouts": []}`)),
+		httptest.NewRequest(http.MethodPost, "/api/v1/batches/00000000-0000-0000-0000-000000000001/start", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/v1/webhooks/00000000-0000-0000-0000-000000000001", nil),
+		adminRequest(http.MethodPut, "/admin/v1/maintenance", `{"enabled": true}`),
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "read-only") {
+			t.Errorf("%s %s: expected 503 read-only, got %d %s", req.Method, req.URL.Path, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/maintenance", ""))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected admin reads to keep working, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health struct {
+		ReadOnly bool `json:"read_only"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil || w.Code != http.StatusOK || !health.ReadOnly {
+		t.Errorf("Expected a healthy read-only instance, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+// TestChaosControls verifies simulator faults can be read, set and
+// validated through the admin API.
+func TestChaosControls(t *testing.T) {
+	sim := service.NewSimulator(service.UniformLatency{}, nil)
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10, worker.WithBankClient(sim)), adminConfig())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPut, "/admin/v1/simulator/chaos", `{"failure_rate":1,"failure_code":"RATE_LIMITED"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 setting chaos, got %d: %s", w.Code, w.Body.String())
+	}
+	result, _ := sim.Transfer(context.Background(), models.Payout{VendorID: "V1"})
+	if result.Success || result.FailureCode != models.FailureRateLimited || !result.IsRetryable {
+		t.Errorf("Expected a forced retryable RATE_LIMITED failure, got %+v", result)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPut, "/admin/v1/simulator/chaos", `{"failure_rate":2}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a failure rate above 1, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPost, "/admin/v1/config/reload", ""))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 reloading without a reload function, got %d", w.Code)
+	}
+
+	scenario := api.SetupRouter(nil, worker.NewPool(nil, 1, 10, worker.WithBankClient(service.NewScenario())), adminConfig())
+	w = httptest.NewRecorder()
+	scenario.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/simulator/chaos", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a bank without chaos controls, got %d", w.Code)
+	}
+}
+
+// TestWorkerConfig verifies the worker config can be read and changed
+// through the admin API, and that invalid changes leave it as it was.
+func TestWorkerConfig(t *testing.T) {
+	pool := worker.NewPool(nil, 4, 10)
+	r := api.SetupRouter(nil, pool, adminConfig())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPatch, "/admin/v1/worker-config", `{"concurrency": 2, "retry_backoff": "base=1s,max=10s"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 changing the config, got %d: %s", w.Code, w.Body.String())
+	}
+	var cfg models.WorkerConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := models.WorkerConfig{Concurrency: 2, ChunkSize: 10, RetryBackoff: "base=1s,multiplier=2,jitter=0.2,max=10s"}
+	if cfg != want {
+		t.Errorf("Expected %+v, got %+v", want, cfg)
+	}
+	if pool.Concurrency() != 2 {
+		t.Errorf("Expected the pool to use concurrency 2, got %d", pool.Concurrency())
+	}
+
+	for _, body := range []string{`{"chunk_size": 0}`, `{"concurrency": 1000}`, `{"retry_backoff": "base=1m,max=1s"}`} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, adminRequest(http.MethodPatch, "/admin/v1/worker-config", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+	if got := pool.Config(); got != want {
+		t.Errorf("Expected rejected changes to leave %+v, got %+v", want, got)
+	}
+}
+
+// TestForceComplete verifies a failed payout can be marked as paid outside
+// the engine, with the batch rebuilt and its history still consistent.
+func TestForceComplete(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, batchID := processedBatch(t, repo)
+
+	payouts, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, models.PayoutStatusFailed, 1, 10)
+	if err != nil || len(payouts) != 1 {
+		t.Fatalf("Expected one failed payout, got %d (%v)", len(payouts), err)
+	}
+	path := "/admin/v1/payouts/" + payouts[0].ID.String() + "/force-complete"
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPost, path, `{}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a reason, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPost, path, `{"reason":"paid by manual transfer"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var payout models.Payout
+	json.Unmarshal(w.Body.Bytes(), &payout)
+	if payout.Status != models.PayoutStatusCompleted {
+		t.Errorf("Expected payout completed, got %s", payout.Status)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPost, path, `{"reason":"again"}`))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 force-completing a completed payout, got %d", w.Code)
+	}
+
+	batch, _ := repo.GetBatch(context.Background(), batchID)
+	if batch.Status != models.BatchStatusCompleted || batch.FailedCount != 0 {
+		t.Errorf("Expected the batch completed with no failures, got %s (failed=%d)", batch.Status, batch.FailedCount)
+	}
+	v, err := repo.VerifyBatch(context.Background(), batchID)
+	if err != nil || !v.Consistent {
+		t.Errorf("Expected the batch to verify as consistent, got %+v (%v)", v, err)
+	}
+}
+
+// TestRecoverOrphans verifies the orphan sweep reports and then resets
+// payouts left in processing, keeping those under a live claim lease or out
+// of attempts, and skips batches with a live run or batch lease.
+func TestRecoverOrphans(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+	repo := repository.New(db)
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), adminConfig())
+
+	items := func(prefix string, n int) []models.CreatePayoutItem {
+		out := make([]models.CreatePayoutItem, n)
+		for i := range out {
+			out[i] = vendorItem(fmt.Sprintf("%s_%d", prefix, i), "Orphan Vendor", nil)
+		}
+		return out
+	}
+	orphaned := createBatch(t, repo, items("orphan", 4))
+	running := createBatch(t, repo, items("orphan_run", 1))
+	leased := createBatch(t, repo, items("orphan_lease", 1))
+	for _, id := range []uuid.UUID{orphaned, running, leased} {
+		if _, err := db.Exec(`UPDATE payouts SET status = 'processing' WHERE batch_id = $1`, id); err != nil {
+			t.Fatalf("Failed to mark payouts processing: %v", err)
+		}
+	}
+	// One payout under a live claim lease, one out of attempts.
+	db.Exec(`UPDATE payouts SET claimed_by = 'other', lease_expires_at = NOW() + interval '1 hour'
+	         WHERE batch_id = $1 AND seq = 0`, orphaned)
+	db.Exec(`UPDATE payouts SET attempt_count = max_retries WHERE batch_id = $1 AND seq = 1`, orphaned)
+
+	lock, err := repo.LockRun(ctx, running, func() error { return nil })
+	if err != nil {
+		t.Fatalf("LockRun failed: %v", err)
+	}
+	defer lock.Release()
+	if err := repo.AcquireBatchLease(ctx, leased, "other-instance", time.Hour); err != nil {
+		t.Fatalf("AcquireBatchLease failed: %v", err)
+	}
+
+	sweep := func(path string) models.OrphanRecovery {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, adminRequest(http.MethodPost, path, ""))
+		var report models.OrphanRecovery
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &report) != nil {
+			t.Fatalf("Expected 200 from %s, got %d: %s", path, w.Code, w.Body)
+		}
+		return report
+	}
+	byBatch := func(report models.OrphanRecovery) map[uuid.UUID]models.OrphanedBatch {
+		out := map[uuid.UUID]models.OrphanedBatch{}
+		for _, b := range report.Batches {
+			out[b.BatchID] = b
+		}
+		return out
+	}
+	processing := func() int {
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM payouts WHERE batch_id = $1 AND status = 'processing'`, orphaned).Scan(&n)
+		return n
+	}
+
+	dry := byBatch(sweep("/admin/v1/recover-orphans"))
+	if b := dry[orphaned]; b.Processing != 4 || b.Reset != 2 || b.Leased != 1 || b.Exhausted != 1 {
+		t.Errorf("Expected 4 processing, 2 to reset, 1 leased, 1 exhausted, got %+v", b)
+	}
+	if processing() != 4 {
+		t.Errorf("Expected a dry run to change nothing")
+	}
+
+	applied := sweep("/admin/v1/recover-orphans?apply=true")
+	got := byBatch(applied)
+	if !applied.Applied || got[orphaned].Reset != 2 || processing() != 2 {
+		t.Errorf("Expected 2 payouts reset, got %+v with %d still processing", got[orphaned], processing())
+	}
+	if got[running].Skipped == "" || got[running].Reset != 0 {
+		t.Errorf("Expected the batch with a live run skipped, got %+v", got[running])
+	}
+	if !strings.Contains(got[leased].Skipped, "other-instance") {
+		t.Errorf("Expected the leased batch skipped naming its holder, got %+v", got[leased])
+	}
+}
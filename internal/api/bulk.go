@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BulkPayouts applies one action (hold, release, cancel, retry, add_tag) to
+// up to models.MaxBulkPayouts payouts in one transaction and reports the
+// outcome per payout. With all_or_nothing, any skipped payout rolls the
+// request back and the report is returned with 409.
+// POST /api/v1/payouts/bulk
+func (h *Handler) BulkPayouts(c *gin.Context) {
+	var req models.BulkPayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	if req.Action == models.BulkAddTag && req.Tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.tag_required")})
+		return
+	}
+
+	resp, err := h.repo.BulkUpdatePayouts(c.Request.Context(), req, actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !resp.Committed {
+		c.JSON(http.StatusConflict, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,146 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestBulkValidation verifies bulk requests are checked before anything is
+// looked up.
+func TestBulkValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	tooMany := make([]string, models.MaxBulkPayouts+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", uuid.New())
+	}
+	id := fmt.Sprintf("%q", uuid.New())
+	cases := []struct {
+		body, want string
+	}{
+		{`{"payout_ids": [` + id + `]}`, "action is required"},
+		{`{"action": "delete", "payout_ids": [` + id + `]}`, "action must be one of"},
+		{`{"action": "hold", "payout_ids": []}`, "payout_ids must be at least 1"},
+		{`{"action": "hold", "payout_ids": [` + strings.Join(tooMany, ",") + `]}`, "payout_ids must be at most 500"},
+		{`{"action": "add_tag", "payout_ids": [` + id + `]}`, "tag is required"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/payouts/bulk", strings.NewReader(tc.body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("Expected 400 with %q, got %d: %s", tc.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestBulkPayouts verifies each bulk action, the per-payout report, that
+// held payouts pause a run until released, and that all_or_nothing rolls
+// back a request with any skipped payout.
+func TestBulkPayouts(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()), worker.WithHooks(declineAccount("BULK-BLOCKED")))
+	r := api.SetupRouter(repo, pool, api.DefaultConfig())
+
+	blocked := vendorItem("BULK-3", "Blocked Vendor", nil)
+	blocked.BankAccount = "BULK-BLOCKED"
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("BULK-0", "Held Vendor", nil),
+		vendorItem("BULK-1", "Cancelled Vendor", nil),
+		vendorItem("BULK-2", "Paid Vendor", nil),
+		blocked,
+	})
+	all, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, "", 1, 10)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Expected 4 payouts, got %d (%v)", len(all), err)
+	}
+	ids := map[string]uuid.UUID{}
+	for _, p := range all {
+		ids[p.VendorID] = p.ID
+	}
+
+	bulk := func(body string, ids ...uuid.UUID) (int, models.BulkPayoutResponse) {
+		quoted := make([]string, len(ids))
+		for i, id := range ids {
+			quoted[i] = fmt.Sprintf("%q", id)
+		}
+		body = fmt.Sprintf(`{%s, "payout_ids": [%s]}`, body, strings.Join(quoted, ","))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/payouts/bulk", strings.NewReader(body)))
+		var resp models.BulkPayoutResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	process := func() models.BatchSummary {
+		if err := pool.ProcessBatch(context.Background(), batchID); err != nil {
+			t.Fatalf("ProcessBatch failed: %v", err)
+		}
+		var summary models.BatchSummary
+		getJSON(t, r, "/api/v1/batches/"+batchID.String(), &summary)
+		return summary
+	}
+
+	unknown := uuid.New()
+	code, resp := bulk(`"action": "hold"`, ids["BULK-0"], ids["BULK-1"], unknown, ids["BULK-0"])
+	if code != http.StatusOK || resp.Applied != 2 || resp.Skipped != 2 {
+		t.Fatalf("Expected 2 held and 2 skipped, got %d: %+v", code, resp)
+	}
+	if resp.Results[2].Reason != models.BulkSkipNotFound || resp.Results[3].Reason != models.BulkSkipDuplicate {
+		t.Errorf("Expected not_found then duplicate, got %+v", resp.Results[2:])
+	}
+	if code, resp = bulk(`"action": "cancel"`, ids["BULK-1"]); code != http.StatusOK || resp.Results[0].Status != models.PayoutStatusCancelled {
+		t.Errorf("Expected the held payout cancelled, got %d: %+v", code, resp)
+	}
+
+	summary := process()
+	if summary.Batch.Status != models.BatchStatusPaused || summary.Statistics.Held != 1 || summary.Statistics.Cancelled != 1 {
+		t.Errorf("Expected the run to pause on 1 held payout with 1 cancelled, got %s %+v", summary.Batch.Status, summary.Statistics)
+	}
+
+	code, resp = bulk(`"action": "release", "all_or_nothing": true`, ids["BULK-0"], ids["BULK-2"])
+	if code != http.StatusConflict || resp.Committed || resp.Results[0].Result != models.BulkRolledBack {
+		t.Errorf("Expected all_or_nothing to roll back, got %d: %+v", code, resp)
+	}
+	if p, _ := repo.GetPayout(context.Background(), ids["BULK-0"]); p.HeldAt == nil {
+		t.Error("Expected the payout to stay held after the rollback")
+	}
+	if code, _ = bulk(`"action": "release"`, ids["BULK-0"]); code != http.StatusOK {
+		t.Fatalf("Expected 200 releasing, got %d", code)
+	}
+	summary = process()
+	if summary.Batch.Status != models.BatchStatusPartiallyCompleted || summary.Statistics.Completed != 2 || summary.Statistics.Failed != 2 {
+		t.Errorf("Expected 2 completed and 2 failed (1 cancelled), got %s %+v", summary.Batch.Status, summary.Statistics)
+	}
+
+	if code, resp = bulk(`"action": "add_tag", "tag": "reviewed"`, ids["BULK-0"], ids["BULK-1"]); resp.Applied != 2 {
+		t.Errorf("Expected 2 payouts tagged, got %d: %+v", code, resp)
+	}
+	if _, resp = bulk(`"action": "add_tag", "tag": "reviewed"`, ids["BULK-0"]); resp.Results[0].Reason != models.BulkSkipAlreadyTagged {
+		t.Errorf("Expected already_tagged, got %+v", resp.Results)
+	}
+	if p, _ := repo.GetPayout(context.Background(), ids["BULK-1"]); len(p.Tags) != 1 || p.Tags[0] != "reviewed" {
+		t.Errorf("Expected tags [reviewed], got %v", p.Tags)
+	}
+
+	if code, resp = bulk(`"action": "retry"`, ids["BULK-3"], ids["BULK-1"]); resp.Applied != 1 || resp.Results[1].Reason != models.BulkSkipNotEligible {
+		t.Errorf("Expected the failed payout retried and the cancelled one skipped, got %d: %+v", code, resp)
+	}
+	getJSON(t, r, "/api/v1/batches/"+batchID.String(), &summary)
+	if summary.Batch.Status != models.BatchStatusPaused || summary.Statistics.Pending != 1 {
+		t.Errorf("Expected the batch paused with 1 payout to retry, got %s %+v", summary.Batch.Status, summary.Statistics)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetRegulatoryReport lists the payouts flagged at creation for being above
+// their country's reporting threshold, optionally of one country. from and
+// to are creation dates (UTC); to is exclusive.
+// GET /api/v1/reports/regulatory?country=ID&from=2024-01-01&to=2024-02-01
+func (h *Handler) GetRegulatoryReport(c *gin.Context) {
+	report := models.RegulatoryReport{
+		Country:         strings.ToUpper(c.Query("country")),
+		Thresholds:      h.cfg.Reporting.Thresholds,
+		RequireApproval: h.cfg.Reporting.RequireApproval,
+		GeneratedAt:     h.cfg.Clock.Now().UTC(),
+	}
+	if report.Thresholds == nil {
+		report.Thresholds = []models.ReportingThreshold{}
+	}
+	if report.Country != "" && len(report.Country) != 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_country")})
+		return
+	}
+	for param, dst := range map[string]**time.Time{"from": &report.From, "to": &report.To} {
+		if v := c.Query(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_date", param)})
+				return
+			}
+			*dst = &date
+		}
+	}
+
+	payouts, err := h.repo.GetReportablePayouts(c.Request.Context(), report.Country, report.From, report.To)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	report.Payouts = payouts
+	for _, p := range payouts {
+		if p.AwaitingApproval {
+			report.AwaitingApproval++
+		}
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ApproveReportablePayout releases a payout held for its reporting
+// approval, so a run may send it. The approver is the X-Operator, who must
+// be named and must not own the payout's batch.
+// POST /api/v1/payouts/:id/reporting-approval
+func (h *Handler) ApproveReportablePayout(c *gin.Context) {
+	payoutID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_payout_id")})
+		return
+	}
+	approvedBy := actor(c)
+	if approvedBy == models.AnonymousOperator {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.approver_required")})
+		return
+	}
+
+	payout, err := h.repo.ApproveReportablePayout(c.Request.Context(), payoutID, approvedBy)
+	switch {
+	case errors.Is(err, repository.ErrNotAwaitingApproval):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.not_awaiting_approval")})
+	case errors.Is(err, repository.ErrReportingSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "error.reporting_self_approved")})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case payout == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+	default:
+		c.JSON(http.StatusOK, payout)
+	}
+}
@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/compliance"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestRegulatoryReportValidation verifies bad report filters and anonymous
+// approvals are refused before the store is consulted.
+func TestRegulatoryReportValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	cases := []struct {
+		name, method, path string
+	}{
+		{"bad country", http.MethodGet, "/api/v1/reports/regulatory?country=IDN"},
+		{"bad date", http.MethodGet, "/api/v1/reports/regulatory?to=tomorrow"},
+		{"bad payout ID", http.MethodPost, "/api/v1/payouts/nope/reporting-approval"},
+		{"anonymous approver", http.MethodPost, "/api/v1/payouts/" + uuid.NewString() + "/reporting-approval"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tc.name, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestReportablePayouts verifies payouts above their country's threshold
+// are flagged at creation and held until someone other than the batch
+// owner approves them, and that the regulatory report lists them.
+func TestReportablePayouts(t *testing.T) {
+	db := getTestDB(t)
+
+	rules := compliance.Rules{
+		Thresholds:      []models.ReportingThreshold{{Country: "ID", Currency: "IDR", Amount: 150}},
+		RequireApproval: true,
+	}
+	repo := repository.New(db, repository.WithReportingRules(rules))
+	cfg := api.DefaultConfig()
+	cfg.Reporting = rules
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()))
+	r := api.SetupRouter(repo, pool, cfg)
+
+	suffix := uuid.NewString()[:8]
+	large, abroad, small := "REG-1-"+suffix, "REG-2-"+suffix, "REG-3-"+suffix
+	items := []models.CreatePayoutItem{
+		vendorItem(large, "Toko Batik", map[string]string{"country": "ID"}),
+		vendorItem(abroad, "Sari-Sari Store", map[string]string{"country": "PH"}),
+		vendorItem(small, "Warung Kopi", nil),
+	}
+	items[0].Amount, items[1].Amount, items[2].Amount = 200, 200, 100
+	batch, err := repo.CreateBatch(context.Background(), items, models.BatchOptions{Owner: "maker@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create test batch: %v", err)
+	}
+	ids := map[string]uuid.UUID{}
+	for _, p := range mustPayouts(t, repo, batch.ID) {
+		ids[p.VendorID] = p.ID
+	}
+	flagged, err := repo.GetPayout(context.Background(), ids[large])
+	if err != nil || flagged.ReportingCountry == nil || *flagged.ReportingCountry != "ID" || flagged.HeldAt == nil {
+		t.Fatalf("Expected the large ID payout flagged and held, got %+v (%v)", flagged, err)
+	}
+
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"action": "release", "payout_ids": [%q]}`, ids[large])
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/payouts/bulk", strings.NewReader(body)))
+	var bulk models.BulkPayoutResponse
+	json.Unmarshal(w.Body.Bytes(), &bulk)
+	if len(bulk.Results) != 1 || bulk.Results[0].Reason != models.BulkSkipAwaitingApproval {
+		t.Errorf("Expected a bulk release to skip the payout awaiting approval, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var report models.RegulatoryReport
+	if code := getJSON(t, r, "/api/v1/reports/regulatory?country=id", &report); code != http.StatusOK {
+		t.Fatalf("Expected 200 for the regulatory report, got %d", code)
+	}
+	found := false
+	for _, p := range report.Payouts {
+		switch p.VendorID {
+		case large:
+			found = p.AwaitingApproval && p.Country == "ID"
+		case abroad, small:
+			t.Errorf("Expected only payouts above the ID threshold, got %+v", p)
+		}
+	}
+	if !found || !report.RequireApproval || report.AwaitingApproval < 1 {
+		t.Errorf("Expected the large payout awaiting approval in the report, got %+v", report)
+	}
+
+	approve := func(operator string, id uuid.UUID) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payouts/"+id.String()+"/reporting-approval", nil)
+		req.Header.Set("X-Operator", operator)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := approve("maker@example.com", ids[large]); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for the batch owner approving, got %d", code)
+	}
+	if code := approve("checker@example.com", ids[small]); code != http.StatusConflict {
+		t.Errorf("Expected 409 approving a payout not awaiting approval, got %d", code)
+	}
+	if code := approve("checker@example.com", uuid.New()); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown payout, got %d", code)
+	}
+	if code := approve("checker@example.com", ids[large]); code != http.StatusOK {
+		t.Fatalf("Expected 200 approving, got %d", code)
+	}
+
+	if err := pool.ProcessBatch(context.Background(), batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	approved, _ := repo.GetPayout(context.Background(), ids[large])
+	if approved.Status != models.PayoutStatusCompleted || approved.ReportingApprovedBy == nil || *approved.ReportingApprovedBy != "checker@example.com" {
+		t.Errorf("Expected the approved payout sent with its approver recorded, got %+v", approved)
+	}
+}
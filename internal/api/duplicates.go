@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// completedDuplicates checks a batch's items against the payouts completed
+// for the merchant within the duplicate window, under the request's
+// duplicate_check policy or else the configured one, to catch a file
+// uploaded twice. With block it answers 409 listing the matches; with warn
+// it returns them for the response to carry. positions, when some payouts
+// of the request were left out of items, gives the index in the request of
+// each item, which the matches report.
+func (h *Handler) completedDuplicates(c *gin.Context, policy string, items []models.CreatePayoutItem, positions []int) ([]models.DuplicatePayout, bool) {
+	if policy == "" {
+		policy = h.cfg.DuplicateCheck
+	}
+	if policy == "" || policy == models.DuplicateCheckOff {
+		return nil, true
+	}
+
+	dups, err := h.matchCompleted(c, items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if positions != nil {
+		for i := range dups {
+			dups[i].Index = positions[dups[i].Index]
+		}
+	}
+	if len(dups) > 0 && policy == models.DuplicateCheckBlock {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.duplicate_payouts", len(dups)), "duplicates": dups})
+		return nil, false
+	}
+	return dups, true
+}
+
+// matchCompleted returns the items matching a payout completed for the
+// merchant within the duplicate window.
+func (h *Handler) matchCompleted(c *gin.Context, items []models.CreatePayoutItem) ([]models.DuplicatePayout, error) {
+	vendorIDs := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if !seen[item.VendorID] {
+			seen[item.VendorID] = true
+			vendorIDs = append(vendorIDs, item.VendorID)
+		}
+	}
+	since := h.cfg.Clock.Now().Add(-h.cfg.DuplicateWindow)
+	completed, err := h.repo.ListCompletedPayouts(c.Request.Context(), merchant(c), vendorIDs, since)
+	if err != nil {
+		return nil, err
+	}
+	return models.MatchCompletedPayouts(items, completed), nil
+}
+
+// withDuplicates adds the items matching recently completed payouts to a
+// batch creation response as duplicate_warnings, when there are any.
+func withDuplicates(resp gin.H, dups []models.DuplicatePayout) gin.H {
+	if len(dups) > 0 {
+		resp["duplicate_warnings"] = dups
+	}
+	return resp
+}
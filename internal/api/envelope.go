@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// v2Base is the path prefix of the enveloped API.
+const v2Base = "/api/v2"
+
+// Context keys the envelope reads after the handler has run.
+const (
+	errorCodeKey    = "api.error_code"    // string: code of the last error message translated
+	fieldErrorsKey  = "api.field_errors"  // []models.APIError: per-field binding errors
+	envelopeMetaKey = "api.envelope_meta" // *models.EnvelopeMeta: page of a list
+)
+
+// statusCodes are the error codes of responses whose handler did not name
+// one, by HTTP status.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          "invalid_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusUnprocessableEntity: "unprocessable",
+	http.StatusTooManyRequests:     "rate_limited",
+	http.StatusServiceUnavailable:  "unavailable",
+	http.StatusGatewayTimeout:      "timeout",
+}
+
+// Envelope wraps the JSON responses of /api/v2 requests in a
+// models.Envelope. Handlers are shared with v1 and keep writing v1 bodies:
+// a success body becomes data, and a v1 {"error": ...} body becomes one
+// error whose code is the i18n key of its message without the "error."
+// prefix (e.g. "batch_not_found"), falling back to a code for the HTTP
+// status. Non-JSON responses such as CSV exports pass through unchanged.
+// It runs ahead of the other middleware so their errors are enveloped too.
+func Envelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, v2Base+"/") {
+			c.Next()
+			return
+		}
+		w := &envelopeWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if !w.passthrough {
+			writeEnvelope(c, w.Status(), w.body.Bytes())
+		}
+	}
+}
+
+// envelopeWriter holds back a JSON response body until the handler is done.
+type envelopeWriter struct {
+	gin.ResponseWriter
+	body        bytes.Buffer
+	passthrough bool
+}
+
+func (w *envelopeWriter) Write(b []byte) (int, error) {
+	if !w.passthrough && w.body.Len() == 0 && w.Status() < http.StatusBadRequest &&
+		!strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.passthrough = true
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is deferred to writeEnvelope, which sets the final status.
+func (w *envelopeWriter) WriteHeaderNow() {}
+
+func (w *envelopeWriter) Written() bool {
+	return w.passthrough || w.body.Len() > 0
+}
+
+func writeEnvelope(c *gin.Context, status int, body []byte) {
+	var env models.Envelope
+	if status < http.StatusBadRequest {
+		env.Data = body
+		if len(body) == 0 {
+			env.Data = json.RawMessage("null")
+		}
+		if meta, ok := c.Get(envelopeMetaKey); ok {
+			env.Meta = meta.(*models.EnvelopeMeta)
+		}
+	} else {
+		env.Data = json.RawMessage("null")
+		env.Errors = responseErrors(c, status, body)
+	}
+	out, err := json.Marshal(env)
+	if err != nil {
+		status, out = http.StatusInternalServerError, []byte(`{"data":null,"errors":[{"code":"internal_error","message":"encode response"}]}`)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.Writer.WriteHeader(status)
+	c.Writer.Write(out)
+}
+
+// responseErrors turns the v1 error body of a failed request into typed errors.
+func responseErrors(c *gin.Context, status int, body []byte) []models.APIError {
+	if fields, ok := c.Get(fieldErrorsKey); ok {
+		return fields.([]models.APIError)
+	}
+	var v1 struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &v1) != nil || v1.Error == "" {
+		v1.Error = strings.TrimSpace(string(body))
+		if v1.Error == "" {
+			v1.Error = http.StatusText(status)
+		}
+	}
+	code := c.GetString(errorCodeKey)
+	if code == "" {
+		if code = statusCodes[status]; code == "" {
+			code = "internal_error"
+		}
+	}
+	return []models.APIError{{Code: code, Message: v1.Error}}
+}
+
+// setPageMeta records the page of a cursor-paginated list for the envelope.
+// next is the position of the last item returned, or nil on the last page.
+func setPageMeta(c *gin.Context, limit int, next *models.PageCursor) {
+	meta := &models.EnvelopeMeta{Limit: limit, HasMore: next != nil}
+	if next != nil {
+		meta.NextCursor = encodeCursor(*next)
+	}
+	c.Set(envelopeMetaKey, meta)
+}
+
+// Deprecated marks responses of a superseded API version with a Deprecation
+// header, a Link to the successor version and, unless sunset is zero, the
+// date after which the version may stop working.
+func Deprecated(successor string, sunset time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", "true")
+		h.Set("Link", "<"+successor+`>; rel="successor-version"`)
+		if !sunset.IsZero() {
+			h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
@@ -2,12 +2,25 @@ package api
 
 import (
 	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/i18n"
 	"coding-challenge/internal/models"
+	"coding-challenge/internal/money"
+	"coding-challenge/internal/notify/webhook"
+	"coding-challenge/internal/purpose"
 	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
 	"coding-challenge/internal/worker"
 
 	"github.com/gin-gonic/gin"
@@ -16,44 +29,331 @@ import (
 
 // Handler holds dependencies for API handlers.
 type Handler struct {
-	repo *repository.Repository
+	repo Store
 	pool *worker.Pool
+	cfg  Config
 }
 
 // NewHandler creates a new handler with dependencies.
-func NewHandler(repo *repository.Repository, pool *worker.Pool) *Handler {
-	return &Handler{repo: repo, pool: pool}
+func NewHandler(repo Store, pool *worker.Pool, cfg Config) *Handler {
+	cfg.Clock = clock.OrReal(cfg.Clock)
+	if cfg.Maintenance == nil {
+		cfg.Maintenance = NewMaintenance()
+	}
+	if cfg.Webhooks == nil {
+		cfg.Webhooks = webhook.NewDispatcher(repo, webhook.Config{})
+	}
+	return &Handler{repo: repo, pool: pool, cfg: cfg}
+}
+
+// actor identifies who issued a request, from the X-Operator header.
+func actor(c *gin.Context) string {
+	if op := c.GetHeader("X-Operator"); op != "" {
+		return op
+	}
+	return models.AnonymousOperator
+}
+
+// withOwner makes the X-Operator creating a batch its owner unless the
+// request names one, and records the merchant the batch is created for.
+func withOwner(c *gin.Context, opts models.BatchOptions) models.BatchOptions {
+	opts.Merchant = merchant(c)
+	if opts.Owner == "" && c.GetHeader("X-Operator") != "" {
+		opts.Owner = actor(c)
+	}
+	return opts
 }
 
-// CreateBatch creates a new batch of payouts.
+// CreateBatch creates a new batch of payouts. With ?async=true it answers
+// 202 once the batch is recorded and ingests the payouts in the background.
+// In partial mode ("mode": "partial") the payouts that would refuse the
+// request are left out instead, listed in the response as rejected and
+// summarized on the batch.
 // POST /api/v1/batches
+// POST /api/v1/batches?async=true
 func (h *Handler) CreateBatch(c *gin.Context) {
 	var req models.CreateBatchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	err := c.ShouldBindJSON(&req)
+	submitted := len(req.Payouts)
+	var rejected []models.PayoutError
+	var positions []int // index in the request of each payout, in partial mode
+	if req.Mode == models.CreateModePartial {
+		var ok bool
+		if rejected, positions, ok = acceptPartial(c, &req, err); !ok {
+			return
+		}
+	} else {
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+			return
+		}
+		for i, item := range req.Payouts {
+			if _, msg := checkItem(c, i, item); msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+		}
+		if !dedupeVendors(c, &req) {
+			return
+		}
+	}
+	if p := req.RetryPolicy; p != nil && p.Backoff != "" {
+		if _, err := worker.ParseRetryBackoff(p.Backoff); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_retry_policy", err.Error())})
+			return
+		}
+	}
+
+	// A retry repeating the Idempotency-Key gets the first batch back, and
+	// is not counted against the quotas again.
+	opts := withOwner(c, req.Options())
+	if !idempotent(c, req, &opts) || h.replayBatch(c, opts) {
+		return
+	}
+	if rejected != nil {
+		opts.Rejections = models.NewRejectionSummary(submitted, rejected)
+	}
+	dups, ok := h.completedDuplicates(c, req.DuplicateCheck, req.Payouts, positions)
+	if !ok {
+		return
+	}
+	used, ok := h.quotaBatch(c, len(req.Payouts))
+	if !ok {
 		return
 	}
 
-	batch, err := h.repo.CreateBatch(c.Request.Context(), req.Payouts)
+	if c.Query("async") == "true" {
+		h.createBatchAsync(c, req, opts, used, dups, rejected)
+		return
+	}
+	batch, err := h.repo.CreateBatch(c.Request.Context(), req.Payouts, opts)
+	if errors.Is(err, repository.ErrDuplicateRequest) && h.replayBatch(c, opts) {
+		return // created by a concurrent retry
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create batch: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "error.create_failed", err.Error())})
 		return
 	}
+	h.quotaCreated(c, used, batch.TotalCount)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":  "Batch created successfully",
+	c.JSON(http.StatusCreated, withRejected(withDuplicates(gin.H{
+		"message":  tr(c, "msg.batch_created"),
 		"batch_id": batch.ID,
 		"total":    batch.TotalCount,
 		"status":   batch.Status,
-	})
+		"links":    batchLinks(c, batch),
+	}, dups), rejected))
+}
+
+// checkItem checks what binding cannot about the i-th payout of a batch: its
+// splits and its purpose code. It returns the check the item fails and why
+// it is refused, or "" for both.
+func checkItem(c *gin.Context, i int, item models.CreatePayoutItem) (rule, msg string) {
+	switch err := item.ValidateSplits(); {
+	case errors.Is(err, models.ErrSplitTooFew):
+		return "split_too_few", tr(c, "error.split_too_few", i)
+	case errors.Is(err, models.ErrSplitTotal):
+		return "split_total", tr(c, "error.split_total", i)
+	}
+	if err := purpose.Validate(item); err != nil {
+		return "purpose_code", tr(c, "error.invalid_purpose_code", i, err.Error())
+	}
+	return "", ""
+}
+
+// dedupeVendors applies the request's dedupe_strategy to vendors appearing
+// more than once. With reject, the default, it answers 422 listing them;
+// with merge, it merges each one's entries into one payout, answering 422
+// listing the vendors whose entries pay into different places.
+func dedupeVendors(c *gin.Context, req *models.CreateBatchRequest) bool {
+	if req.DedupeStrategy == models.DedupeMerge {
+		merged, conflicts := models.MergeDuplicateVendors(req.Payouts)
+		if len(conflicts) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": tr(c, "error.vendors_not_mergeable", strings.Join(conflicts, ", ")), "vendor_ids": conflicts})
+			return false
+		}
+		req.Payouts = merged
+		return true
+	}
+	if dups := models.DuplicateVendors(req.Payouts); len(dups) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": tr(c, "error.duplicate_vendors", strings.Join(dups, ", ")), "vendor_ids": dups})
+		return false
+	}
+	return true
+}
+
+// batchListFilter reads the filters shared by the batch lists: status,
+// assignee and a creation date range (created_from / created_to, dates in
+// UTC, created_to exclusive). It answers 400 for a malformed date.
+func batchListFilter(c *gin.Context) (models.BatchListFilter, bool) {
+	filter := models.BatchListFilter{
+		Status:     c.Query("status"),
+		AssignedTo: c.Query("assigned_to"),
+	}
+	for param, dst := range map[string]**time.Time{"created_from": &filter.CreatedFrom, "created_to": &filter.CreatedTo} {
+		if v := c.Query(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_date", param)})
+				return filter, false
+			}
+			*dst = &date
+		}
+	}
+	return filter, true
+}
+
+// ListBatches returns batches newest first, paginated. Soft-deleted batches
+// are only listed with ?include_deleted=true on the admin API. With
+// ?aggregates=true it also counts every matching batch by status and totals
+// their unfinished payouts per currency.
+// GET /api/v1/batches?status=completed&assigned_to=ops@example.com&created_from=2024-01-01&created_to=2024-02-01&page=1&page_size=50&aggregates=true
+// GET /admin/v1/batches?include_deleted=true
+func (h *Handler) ListBatches(c *gin.Context) {
+	filter, ok := batchListFilter(c)
+	if !ok {
+		return
+	}
+	filter.IncludeDeleted = isAdmin(c) && c.Query("include_deleted") == "true"
+	filter.Aggregates = c.Query("aggregates") == "true"
+	filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filter.PageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 200 {
+		filter.PageSize = 50
+	}
+
+	batches, total, err := h.repo.ListBatches(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range batches {
+		batches[i].Links = batchLinks(c, &batches[i])
+	}
+	resp := models.BatchListResponse{
+		Batches:    batches,
+		TotalCount: total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+	}
+	if filter.Aggregates {
+		if resp.Aggregates, err = h.repo.GetBatchListAggregates(c.Request.Context(), filter); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateBatch changes who owns or is assigned to a batch. The change is
+// recorded in the audit log with the X-Operator making it.
+// PATCH /api/v1/batches/:id
+func (h *Handler) UpdateBatch(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	var req models.UpdateBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	if req.Owner == nil && req.AssignedTo == nil && req.ProgressEvery == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.nothing_to_update")})
+		return
+	}
+
+	existing, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+	if existing.DeletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_deleted")})
+		return
+	}
+
+	batch, err := h.repo.AssignBatch(c.Request.Context(), batchID, req, actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	batch.Links = batchLinks(c, batch)
+	c.JSON(http.StatusOK, batch)
+}
+
+// DeleteBatch soft-deletes a finished batch. Nothing is removed, and the
+// batch can be brought back with restore.
+// DELETE /api/v1/batches/:id
+func (h *Handler) DeleteBatch(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.DeleteBatch(c.Request.Context(), batchID, actor(c))
+	if errors.Is(err, repository.ErrBatchNotTerminal) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_not_terminal")})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+	batch.Links = batchLinks(c, batch)
+	c.JSON(http.StatusOK, batch)
+}
+
+// RestoreBatch undoes a soft delete.
+// POST /api/v1/batches/:id/restore
+func (h *Handler) RestoreBatch(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.RestoreBatch(c.Request.Context(), batchID, actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+	batch.Links = batchLinks(c, batch)
+	c.JSON(http.StatusOK, batch)
 }
 
-// StartBatch begins or resumes processing a batch.
+// StartBatch begins or resumes processing a batch. While another batch is
+// being processed it is queued instead, and the response gives its place.
+// An optional body sets the run's concurrency and chunk size (see
+// models.StartBatchRequest).
 // POST /api/v1/batches/:id/start
 func (h *Handler) StartBatch(c *gin.Context) {
 	batchID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	var req models.StartBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
 		return
 	}
 
@@ -63,34 +363,121 @@ func (h *Handler) StartBatch(c *gin.Context) {
 		return
 	}
 	if batch == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+	if batch.DeletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_deleted")})
+		return
+	}
+	if batch.Status == models.BatchStatusCreating {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_creating")})
+		return
+	}
+	if batch.Status == models.BatchStatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_cancelled")})
+		return
+	}
+	if !h.quotaStart(c, batch) {
 		return
 	}
 
-	if h.pool.IsRunning() {
-		c.JSON(http.StatusConflict, gin.H{"error": "A batch is already being processed"})
+	// Start processing in background, or queue behind the running batch
+	run, pos, err := h.pool.EnqueueWith(batchID, models.RunTriggerStart, actor(c), models.RunSettings{
+		Concurrency: req.Concurrency,
+		ChunkSize:   req.ChunkSize,
+	})
+	if errors.Is(err, worker.ErrBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_busy")})
+		return
+	}
+	if errors.Is(err, worker.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "error.shutting_down")})
+		return
+	}
+	if errors.Is(err, repository.ErrEnvironmentMismatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.environment_mismatch", h.pool.Environment())})
+		return
+	}
+	if errors.Is(err, repository.ErrBatchLeased) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_leased")})
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientFunding) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Start processing in background
-	go func() {
-		ctx := context.Background()
-		if err := h.pool.ProcessBatch(ctx, batchID); err != nil {
-			log.Printf("[api] Error processing batch %s: %v", batchID, err)
-		}
-	}()
+	if run == nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":        tr(c, "msg.batch_queued", pos),
+			"batch_id":       batchID,
+			"queue_position": pos,
+		})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
-		"message":  "Batch processing started",
-		"batch_id": batchID,
+		"message":     tr(c, "msg.batch_started"),
+		"batch_id":    batchID,
+		"run_id":      run.ID,
+		"environment": run.Environment,
+		"concurrency": run.Concurrency,
+		"chunk_size":  run.ChunkSize,
 	})
 }
 
-// StopBatch stops processing a batch (graceful).
+// StopBatch stops processing a batch (graceful), leaving any other batch
+// running. It answers 409 if this server is not processing the batch.
 // POST /api/v1/batches/:id/stop
 func (h *Handler) StopBatch(c *gin.Context) {
-	h.pool.Stop()
-	c.JSON(http.StatusOK, gin.H{"message": "Stop signal sent. Processing will pause after current chunk."})
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	if !h.pool.StopBatch(batchID) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_not_running")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "msg.stop_sent")})
+}
+
+// CancelBatch abandons a batch that has not finished: its pending payouts
+// are cancelled, the batch becomes cancelled for good, and its run on this
+// server is stopped or it is taken out of the queue. Transfers already in
+// flight still finish. It answers 409 for finished, deleted or still
+// creating batches.
+// POST /api/v1/batches/:id/cancel
+func (h *Handler) CancelBatch(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.CancelBatch(c.Request.Context(), batchID, actor(c))
+	if errors.Is(err, repository.ErrBatchNotCancellable) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_not_cancellable")})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+	if h.pool.StopBatch(batchID) {
+		log.Printf("[api] Batch %s cancelled by %s, stopping its run", batchID, actor(c))
+	}
+	batch.Links = batchLinks(c, batch)
+	c.JSON(http.StatusOK, batch)
 }
 
 // GetBatch returns batch status with statistics.
@@ -98,7 +485,7 @@ func (h *Handler) StopBatch(c *gin.Context) {
 func (h *Handler) GetBatch(c *gin.Context) {
 	batchID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
 		return
 	}
 
@@ -108,7 +495,7 @@ func (h *Handler) GetBatch(c *gin.Context) {
 		return
 	}
 	if batch == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
 		return
 	}
 
@@ -118,10 +505,27 @@ func (h *Handler) GetBatch(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.BatchSummary{
-		Batch:      *batch,
-		Statistics: *stats,
-	})
+	batch.Links = batchLinks(c, batch)
+	summary := models.BatchSummary{
+		Batch:       *batch,
+		StatusLabel: i18n.StatusLabel(lang(c), batch.Status),
+		Statistics:  *stats,
+	}
+	if pos, ok := h.pool.QueuePosition(batchID); ok {
+		summary.QueuePosition = &pos
+	}
+	if batch.Status == models.BatchStatusInProgress {
+		estimate, err := h.estimate(c.Request.Context(), batchID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if d := estimate.ExpectedDurationSeconds; d != nil {
+			eta := estimate.GeneratedAt.Add(time.Duration(*d * float64(time.Second)))
+			summary.EstimatedCompletionAt = &eta
+		}
+	}
+	c.JSON(http.StatusOK, summary)
 }
 
 // GetBatchPayouts returns paginated payouts for a batch with optional status filter.
@@ -129,7 +533,7 @@ func (h *Handler) GetBatch(c *gin.Context) {
 func (h *Handler) GetBatchPayouts(c *gin.Context) {
 	batchID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
 		return
 	}
 
@@ -150,6 +554,13 @@ func (h *Handler) GetBatchPayouts(c *gin.Context) {
 		return
 	}
 
+	for i := range payouts {
+		if reason := payouts[i].FailureReason; reason != nil {
+			payouts[i].FailureDescription = i18n.FailureDescription(lang(c), *reason)
+		}
+		payouts[i].Links = payoutLinks(c, &payouts[i].Payout)
+	}
+
 	c.JSON(http.StatusOK, models.PayoutListResponse{
 		Payouts:    payouts,
 		TotalCount: total,
@@ -158,12 +569,27 @@ func (h *Handler) GetBatchPayouts(c *gin.Context) {
 	})
 }
 
-// RetryFailed retries all retryable failed payouts and restarts processing.
+// RetryFailed retries all retryable failed payouts and restarts processing,
+// queueing the batch while another one is being processed.
 // POST /api/v1/batches/:id/retry-failed
 func (h *Handler) RetryFailed(c *gin.Context) {
 	batchID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch != nil && batch.DeletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_deleted")})
+		return
+	}
+	if batch != nil && batch.Status == models.BatchStatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_cancelled")})
 		return
 	}
 
@@ -174,25 +600,774 @@ func (h *Handler) RetryFailed(c *gin.Context) {
 	}
 
 	if requeued == 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "No retryable payouts found"})
+		c.JSON(http.StatusOK, gin.H{"message": tr(c, "msg.no_retryable")})
 		return
 	}
 
-	if h.pool.IsRunning() {
-		c.JSON(http.StatusConflict, gin.H{"error": "A batch is already being processed"})
+	// Start processing again, or queue behind the running batch
+	run, pos, err := h.pool.Enqueue(batchID, models.RunTriggerRetryFailed, actor(c))
+	if errors.Is(err, worker.ErrBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_busy")})
+		return
+	}
+	if errors.Is(err, worker.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "error.shutting_down")})
+		return
+	}
+	if errors.Is(err, repository.ErrEnvironmentMismatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.environment_mismatch", h.pool.Environment())})
+		return
+	}
+	if errors.Is(err, repository.ErrBatchLeased) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_leased")})
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientFunding) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Start processing again
-	go func() {
-		ctx := context.Background()
-		if err := h.pool.ProcessBatch(ctx, batchID); err != nil {
-			log.Printf("[api] Error retrying batch %s: %v", batchID, err)
-		}
-	}()
+	if run == nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":        tr(c, "msg.batch_queued", pos),
+			"requeued":       requeued,
+			"queue_position": pos,
+		})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
-		"message":  "Retrying failed payouts",
-		"requeued": requeued,
+		"message":     tr(c, "msg.retrying"),
+		"requeued":    requeued,
+		"run_id":      run.ID,
+		"environment": run.Environment,
+	})
+}
+
+// RequeuePayouts copies failed payouts into a new pending batch, optionally
+// re-routing them to backup accounts. Each copy supersedes its original.
+// POST /api/v1/batches/requeue
+func (h *Handler) RequeuePayouts(c *gin.Context) {
+	var req models.RequeueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	used, ok := h.quotaBatch(c, len(req.Payouts))
+	if !ok {
+		return
+	}
+
+	batch, err := h.repo.RequeuePayouts(c.Request.Context(), req.Payouts, withOwner(c, req.Options()), actor(c))
+	var pe *repository.PayoutError
+	switch {
+	case errors.As(err, &pe) && errors.Is(err, repository.ErrPayoutNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.requeue_not_found", pe.PayoutID)})
+		return
+	case errors.As(err, &pe) && errors.Is(err, repository.ErrPayoutNotRequeueable):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.payout_not_requeueable", pe.PayoutID)})
+		return
+	case errors.Is(err, repository.ErrDuplicateVendor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.requeue_duplicate_vendor", err.Error())})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "error.create_failed", err.Error())})
+		return
+	}
+	h.quotaCreated(c, used, batch.TotalCount)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  tr(c, "msg.payouts_requeued"),
+		"batch_id": batch.ID,
+		"total":    batch.TotalCount,
+		"status":   batch.Status,
+		"links":    batchLinks(c, batch),
+	})
+}
+
+// CancelPayout takes a pending payout out of its batch for good, e.g. while
+// its vendor is in dispute, with the X-Operator and the reason journaled.
+// It answers 409 for a payout that is not pending (once claimed it is being
+// paid), is in a payment file, or is in a deleted or cancelled batch.
+// POST /api/v1/payouts/:id/cancel
+func (h *Handler) CancelPayout(c *gin.Context) {
+	payoutID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_payout_id")})
+		return
+	}
+	var req models.CancelPayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+
+	payout, err := h.repo.CancelPayout(c.Request.Context(), payoutID, actor(c), req.Reason)
+	switch {
+	case errors.Is(err, repository.ErrPayoutNotCancellable):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.payout_not_cancellable")})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case payout == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+	default:
+		payout.Links = payoutLinks(c, payout)
+		c.JSON(http.StatusOK, payout)
+	}
+}
+
+// GetPayout returns a single payout with its attempt history.
+// GET /api/v1/payouts/:id
+func (h *Handler) GetPayout(c *gin.Context) {
+	payoutID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_payout_id")})
+		return
+	}
+
+	payout, err := h.repo.GetPayout(c.Request.Context(), payoutID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if payout == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+		return
+	}
+
+	attempts, err := h.repo.GetPayoutAttempts(c.Request.Context(), payoutID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	payout.Links = payoutLinks(c, payout)
+	detail := models.PayoutDetail{
+		Payout:      *payout,
+		Attempts:    attempts,
+		StatusToken: h.cfg.StatusTokens.Sign(payout.ID),
+		Local:       localTimes(c, *payout),
+	}
+	if payout.SplitGroupID != nil {
+		if detail.Splits, err = h.repo.GetSplitPayouts(c.Request.Context(), *payout.SplitGroupID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if payout.Supersedes != nil || payout.SupersededBy != nil {
+		if detail.CorrectionChain, err = h.repo.GetCorrectionChain(c.Request.Context(), payoutID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if payout.Status == models.PayoutStatusWrittenOff {
+		if detail.WriteOff, err = h.repo.GetWriteOff(c.Request.Context(), payoutID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if payout.Status == models.PayoutStatusFailed || payout.Status == models.PayoutStatusWrittenOff {
+		if detail.Outreach, err = h.repo.ListOutreach(c.Request.Context(), payoutID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if payout.FailureReason != nil {
+		detail.FailureDescription = i18n.FailureDescription(lang(c), *payout.FailureReason)
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// GetPayoutStatus is the vendor-facing status lookup. The token identifies
+// the payout, so no other authentication is required; invalid tokens and
+// unknown payouts are indistinguishable.
+// GET /api/v1/payout-status/:token
+func (h *Handler) GetPayoutStatus(c *gin.Context) {
+	payoutID, err := h.cfg.StatusTokens.Verify(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+		return
+	}
+
+	payout, err := h.repo.GetPayout(c.Request.Context(), payoutID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "error.lookup_failed")})
+		return
+	}
+	if payout == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+		return
+	}
+
+	status := models.PublicPayoutStatus{
+		Status:      payout.Status,
+		StatusLabel: i18n.StatusLabel(lang(c), payout.Status),
+		Amount:      payout.Amount,
+		Currency:    payout.Currency,
+		CreatedAt:   payout.CreatedAt,
+		CompletedAt: payout.CompletedAt,
+		Local:       localTimes(c, *payout),
+	}
+	status.Local.AttemptedAt = nil
+	if payout.Status == models.PayoutStatusFailed && payout.FailureReason != nil {
+		status.Description = i18n.FailureDescription(lang(c), *payout.FailureReason)
+	}
+	if payout.Status == models.PayoutStatusPending || payout.Status == models.PayoutStatusProcessing {
+		if date, ok := h.cfg.BankCutoffs.ExpectedSettlement(payout.BankName, h.cfg.Clock.Now()); ok {
+			status.ExpectedDate = date.Format("2006-01-02")
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, status)
+}
+
+// GetBatchStatistics returns batch statistics grouped by a vendor attribute,
+// or with at, the batch's statistics as a run last recorded them by then.
+// GET /api/v1/batches/:id/statistics?group_by=country
+// GET /api/v1/batches/:id/statistics?at=2026-03-01T14:32:00+07:00
+func (h *Handler) GetBatchStatistics(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	if at := c.Query("at"); at != "" {
+		h.getStatisticsAt(c, batchID, at)
+		return
+	}
+
+	groupBy := c.Query("group_by")
+	if groupBy == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.group_by_required")})
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	segments, err := h.repo.GetSegmentStatistics(c.Request.Context(), batchID, groupBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SegmentedStatistics{
+		BatchID:  batchID,
+		GroupBy:  groupBy,
+		Segments: segments,
+	})
+}
+
+// GetBatchFinancials returns the money summary of a batch, one entry per currency.
+// GET /api/v1/batches/:id/financials
+func (h *Handler) GetBatchFinancials(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	totals, err := h.repo.GetBatchFinancials(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservations, err := h.repo.GetBatchReservations(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BatchFinancialSummary{
+		BatchID:      batchID,
+		Status:       batch.Status,
+		Currencies:   totals,
+		Reservations: reservations,
+	})
+}
+
+// GetBatchSettlementReport lists the batch's paid vendors with their
+// references, and the vendors not paid with the reason and the recommended
+// action, in full. It is what is posted to batch.settlement_report
+// subscribers when a batch ends partially completed, and can be fetched for
+// a batch in any status.
+// GET /api/v1/batches/:id/settlement-report
+func (h *Handler) GetBatchSettlementReport(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	report := models.NewSettlementReport(*batch, 0, h.cfg.Clock.Now().UTC())
+	if err := h.repo.EachPayout(c.Request.Context(), batchID, func(p models.Payout) error {
+		report.Add(p)
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetBatchEstimate forecasts how long processing the batch's unfinished
+// payouts would take, how many would fail and what they would cost in bank
+// fees, from the throughput model of recent runs.
+// GET /api/v1/batches/:id/estimate
+func (h *Handler) GetBatchEstimate(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	estimate, err := h.estimate(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	estimate.Status = batch.Status
+	c.JSON(http.StatusOK, estimate)
+}
+
+// estimate forecasts the batch's unfinished payouts from the throughput model.
+func (h *Handler) estimate(ctx context.Context, batchID uuid.UUID) (*models.BatchEstimate, error) {
+	volumes, err := h.repo.GetBankVolumes(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	now := h.cfg.Clock.Now().UTC()
+	since := now.Add(-h.cfg.EstimateHistory)
+	history, err := h.repo.GetThroughputModel(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := service.EstimateBatch(volumes, history, h.pool.Concurrency(), h.cfg.BankFees)
+	estimate.BatchID = batchID
+	estimate.HistorySince = since
+	estimate.GeneratedAt = now
+	return &estimate, nil
+}
+
+// ListFundingAccounts returns the balance, reserved and available amount of
+// every funding account.
+// GET /api/v1/funding-accounts
+func (h *Handler) ListFundingAccounts(c *gin.Context) {
+	accounts, err := h.repo.ListFundingAccounts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// SetFundingBalance creates or updates the funding account of a currency.
+// PUT /admin/v1/funding-accounts/:currency
+func (h *Handler) SetFundingBalance(c *gin.Context) {
+	currency := strings.ToUpper(c.Param("currency"))
+	if len(currency) != 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_currency")})
+		return
+	}
+
+	var req models.SetFundingBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+
+	account, err := h.repo.SetFundingBalance(c.Request.Context(), currency, *req.Balance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// ExportBatch streams a batch's payouts as CSV for finance, with amounts
+// formatted for the reader's locale (?locale=, else Accept-Language). Locales
+// that use a decimal comma get semicolon-separated files, as spreadsheets in
+// those markets expect. Completion times are given in UTC and, in
+// completed_at_local, in the request's time zone (?tz= or Accept-Timezone).
+// With ?encrypt=pgp the file is encrypted to the configured export public
+// key, ready to drop on a bank's SFTP server.
+// GET /api/v1/batches/:id/export
+func (h *Handler) ExportBatch(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	encrypt, ok := h.exportEncryption(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	taxIDs, err := h.repo.BatchVendorTaxIDs(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("batch-%s.csv", batchID)
+	if encrypt {
+		filename += ".pgp"
+	}
+	out, sealed, err := h.exportWriter(c, c.Writer, filename, encrypt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	err = writeExport(c, out, sealed, taxIDs, func(fn func(models.Payout) error) error {
+		return h.repo.EachPayout(c.Request.Context(), batchID, fn)
+	})
+	if err != nil {
+		// Headers are already sent; all we can do is cut the file short and log.
+		log.Printf("[api] Export of batch %s failed: %v", batchID, err)
+	}
+}
+
+// exportEncryption reads ?encrypt= for an export, answering the request
+// itself when it is invalid or no export key is configured.
+func (h *Handler) exportEncryption(c *gin.Context) (encrypt, ok bool) {
+	switch c.Query("encrypt") {
+	case "":
+		return false, true
+	case "pgp":
+		if !h.cfg.ExportKey.Configured() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.export_key_missing")})
+			return false, false
+		}
+		return true, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_encryption")})
+		return false, false
+	}
+}
+
+// exportWriter sends the headers of an export named filename and returns
+// where to write it: w itself, or an encrypting writer over w that must be
+// closed to finish the file (sealed).
+func (h *Handler) exportWriter(c *gin.Context, w io.Writer, filename string, encrypt bool) (out io.Writer, sealed io.WriteCloser, err error) {
+	out = w
+	if encrypt {
+		// The encrypted file carries the name of the CSV inside it.
+		if sealed, err = h.cfg.ExportKey.Encrypt(w, strings.TrimSuffix(filename, ".pgp")); err != nil {
+			return nil, nil, err
+		}
+		out = sealed
+		c.Header("Content-Type", "application/pgp-encrypted")
+		c.Header("X-Encryption-Key", h.cfg.ExportKey.Fingerprint())
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	return out, sealed, nil
+}
+
+// writeExport writes the payouts each yields to out as export CSV rows, in
+// the request's locale and time zone, with their vendors' tax IDs from
+// taxIDs, and finishes sealed, if set.
+func writeExport(c *gin.Context, out io.Writer, sealed io.WriteCloser, taxIDs map[string]string, each func(fn func(models.Payout) error) error) error {
+	locale := c.DefaultQuery("locale", lang(c))
+	w := csv.NewWriter(out)
+	if _, decimal := money.Separators(locale); decimal == "," {
+		w.Comma = ';'
+	}
+
+	loc := zone(c)
+	w.Write([]string{"payout_id", "vendor_id", "vendor_name", "vendor_tax_id", "bank_name", "currency", "amount", "amount_display",
+		"purpose_code", "status", "failure_reason", "completed_at", "completed_at_local"})
+	err := each(func(p models.Payout) error {
+		var purposeCode, reason, completed, completedLocal string
+		if p.PurposeCode != nil {
+			purposeCode = *p.PurposeCode
+		}
+		if p.FailureReason != nil {
+			reason = *p.FailureReason
+		}
+		if p.CompletedAt != nil {
+			completed = p.CompletedAt.UTC().Format(time.RFC3339)
+			completedLocal = p.CompletedAt.In(loc).Format(time.RFC3339)
+		}
+		return w.Write([]string{
+			p.ID.String(), csvText(p.VendorID), csvText(p.VendorName), taxIDs[p.VendorID], csvText(p.BankName), p.Currency,
+			money.FormatNumber(p.Amount, p.Currency, locale), money.Format(p.Amount, p.Currency, locale),
+			purposeCode, p.Status, reason, completed, completedLocal,
+		})
+	})
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err == nil && sealed != nil {
+		// An encrypted file is only readable once its message is finished.
+		err = sealed.Close()
+	}
+	return err
+}
+
+// csvText defuses text supplied by API clients for an export cell: a
+// spreadsheet would run a cell starting with =, +, - or @ as a formula, so
+// such values are prefixed with a quote to be shown as text.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// GetBatchRuns lists every processing run of a batch for post-incident review.
+// GET /api/v1/batches/:id/runs
+func (h *Handler) GetBatchRuns(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	runs, err := h.repo.ListRuns(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BatchRunListResponse{
+		BatchID: batchID,
+		Runs:    runs,
+	})
+}
+
+// VerifyBatch cross-checks a batch's counters, status, attempt history and
+// funding reservations and returns the discrepancies found. It changes
+// nothing; use payoutctl repair to fix what it reports.
+// POST /api/v1/batches/:id/verify
+func (h *Handler) VerifyBatch(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+
+	report, err := h.repo.VerifyBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetOverview returns a system-wide summary for the ops homepage.
+// GET /api/v1/overview
+func (h *Handler) GetOverview(c *gin.Context) {
+	overview, err := h.repo.GetOverview(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	overview.Processor.Running = h.pool.IsRunning()
+	if active, ok := h.pool.ActiveBatch(); ok {
+		overview.Processor.ActiveBatch = &active
+	}
+	overview.Processor.Queued = h.pool.Queued()
+
+	c.JSON(http.StatusOK, overview)
+}
+
+// GetExposure reports money in flight per currency for intraday liquidity
+// management, with the processor's limit for capped currencies, including
+// those with nothing in flight. It is never cached so treasury always sees
+// live figures.
+// GET /api/v1/reports/exposure
+func (h *Handler) GetExposure(c *gin.Context) {
+	exposure, err := h.repo.GetInFlightExposure(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if limits := h.pool.InFlightLimits(); len(limits) > 0 {
+		seen := map[string]bool{}
+		for i := range exposure {
+			if limit, ok := limits[exposure[i].Currency]; ok {
+				exposure[i].Limit = &limit
+			}
+			seen[exposure[i].Currency] = true
+		}
+		for currency, limit := range limits {
+			if !seen[currency] {
+				limit := limit
+				exposure = append(exposure, models.CurrencyExposure{Currency: currency, Limit: &limit})
+			}
+		}
+		sort.Slice(exposure, func(i, j int) bool { return exposure[i].Currency < exposure[j].Currency })
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, models.ExposureReport{
+		Currencies:  exposure,
+		GeneratedAt: h.cfg.Clock.Now().UTC(),
+	})
+}
+
+// GetSettlementCutoffs lists unfinished payouts by bank, split into those
+// that will still settle today and those that will not, given each bank's
+// cutoff, so ops can tell vendors when to expect their money.
+// GET /api/v1/reports/settlement-cutoffs?batch_id=...
+func (h *Handler) GetSettlementCutoffs(c *gin.Context) {
+	batchID := uuid.Nil
+	if raw := c.Query("batch_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+			return
+		}
+		batchID = id
+	}
+
+	payouts, err := h.repo.ListUnfinishedPayouts(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cutoffs := h.cfg.BankCutoffs
+	now := h.cfg.Clock.Now()
+	today := now.In(cutoffs.Location()).Format("2006-01-02")
+	report := models.SettlementCutoffReport{
+		Timezone:    cutoffs.Location().String(),
+		Today:       today,
+		SettleToday: []models.BankSettlement{},
+		SettleLater: []models.BankSettlement{},
+		NoCutoff:    []models.BankSettlement{},
+		GeneratedAt: now.UTC(),
+	}
+
+	// Payouts arrive ordered by bank, so each run of one bank is one entry.
+	for i := 0; i < len(payouts); {
+		bank := payouts[i].BankName
+		j := i
+		for j < len(payouts) && payouts[j].BankName == bank {
+			j++
+		}
+		entry := models.BankSettlement{
+			BankName:    bank,
+			PayoutCount: j - i,
+			Payouts:     payouts[i:j],
+		}
+		i = j
+
+		date, ok := cutoffs.ExpectedSettlement(bank, now)
+		if !ok {
+			report.NoCutoff = append(report.NoCutoff, entry)
+			continue
+		}
+		entry.Cutoff, _ = cutoffs.Cutoff(bank)
+		entry.ExpectedDate = date.Format("2006-01-02")
+		if entry.ExpectedDate == today {
+			report.SettleToday = append(report.SettleToday, entry)
+		} else {
+			report.SettleLater = append(report.SettleLater, entry)
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// minSearchLength is the shortest vendor search query accepted.
+const minSearchLength = 2
+
+// SearchVendors looks up vendors by (partial) name for support tooling.
+// GET /api/v1/vendors/search?q=bali&limit=20
+func (h *Handler) SearchVendors(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if len([]rune(query)) < minSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.query_too_short", minSearchLength)})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	matches, err := h.repo.SearchVendors(c.Request.Context(), query, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   query,
+		"vendors": matches,
 	})
 }
@@ -0,0 +1,1357 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/database/dbtest"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/pgp"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	dbtest.Close()
+	os.Exit(code)
+}
+
+// getTestDB returns a clean database for an integration test, skipping the
+// test when none is reachable. See dbtest for configuration.
+func getTestDB(t *testing.T) *sql.DB {
+	return dbtest.Open(t)
+}
+
+// processedBatch creates three payouts and runs them against a scripted bank:
+// vendor 0 times out twice then succeeds, vendor 1 is blocked, vendor 2 succeeds.
+func processedBatch(t *testing.T, repo *repository.Repository) (*gin.Engine, uuid.UUID) {
+	items := make([]models.CreatePayoutItem, 3)
+	for i := range items {
+		items[i] = models.CreatePayoutItem{
+			VendorID:    fmt.Sprintf("api_vendor_%d", i),
+			VendorName:  fmt.Sprintf("API Vendor %d", i),
+			Amount:      100,
+			Currency:    "USD",
+			BankAccount: fmt.Sprintf("ACC%010d", i),
+			BankName:    "Test Bank",
+		}
+	}
+	batchID := createBatch(t, repo, items)
+
+	sc := service.NewScenario()
+	sc.For(service.Vendors("api_vendor_0")).Fail(models.FailureBankTimeout, 2).ThenSucceed()
+	sc.For(service.Vendors("api_vendor_1")).Always(models.FailureAccountBlocked)
+
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(sc))
+	if err := pool.ProcessBatch(context.Background(), batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	return api.SetupRouter(repo, pool, adminConfig()), batchID
+}
+
+// testAdminToken is the admin API token of routers built by adminConfig.
+const testAdminToken = "test-admin-token"
+
+// adminConfig is the default config with the admin API enabled.
+func adminConfig() api.Config {
+	cfg := api.DefaultConfig()
+	cfg.Admin.Token = testAdminToken
+	return cfg
+}
+
+// adminRequest builds an authenticated admin API request.
+func adminRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	req.Header.Set("X-Operator", "ops@example.com")
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// createBatch inserts a batch of the given payouts.
+func createBatch(t *testing.T, repo *repository.Repository, items []models.CreatePayoutItem) uuid.UUID {
+	batch, err := repo.CreateBatch(context.Background(), items, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create test batch: %v", err)
+	}
+	return batch.ID
+}
+
+// vendorItem builds a payout for vendor id with the given name and metadata.
+func vendorItem(id, name string, metadata map[string]string) models.CreatePayoutItem {
+	return models.CreatePayoutItem{
+		VendorID:    id,
+		VendorName:  name,
+		Amount:      100,
+		Currency:    "IDR",
+		BankAccount: "ACC" + id,
+		BankName:    "BCA",
+		Metadata:    metadata,
+	}
+}
+
+// getJSON performs a GET and decodes the response body into out.
+func getJSON(t *testing.T, r *gin.Engine, path string, out any) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if out != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("Failed to decode %s: %v", path, err)
+		}
+	}
+	return w.Code
+}
+
+// TestBatchPayoutsShowLastAttempt verifies the payout list reports each
+// payout's most recent attempt, not an earlier one.
+func TestBatchPayoutsShowLastAttempt(t *testing.T) {
+	db := getTestDB(t)
+
+	r, batchID := processedBatch(t, repository.New(db))
+
+	var list models.PayoutListResponse
+	if code := getJSON(t, r, "/api/v1/batches/"+batchID.String()+"/payouts", &list); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if list.TotalCount != 3 {
+		t.Fatalf("Expected 3 payouts, got %d", list.TotalCount)
+	}
+
+	for _, item := range list.Payouts {
+		if item.LastAttemptAt == nil {
+			t.Errorf("Expected last_attempt_at for %s", item.VendorID)
+		}
+		switch item.VendorID {
+		case "api_vendor_0":
+			// Two timeouts followed by a success: the latest attempt has no error.
+			if item.LastError != nil {
+				t.Errorf("Expected no last_error after a successful retry, got %s", *item.LastError)
+			}
+		case "api_vendor_1":
+			if item.LastError == nil || *item.LastError != models.FailureAccountBlocked {
+				t.Errorf("Expected last_error %s, got %v", models.FailureAccountBlocked, item.LastError)
+			}
+		}
+	}
+}
+
+// TestGetPayoutDetail verifies the detail endpoint returns the payout with
+// its full attempt history.
+func TestGetPayoutDetail(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, batchID := processedBatch(t, repo)
+
+	payouts, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, "", 1, 10)
+	if err != nil {
+		t.Fatalf("GetPayoutsByBatch failed: %v", err)
+	}
+	var retried uuid.UUID
+	for _, p := range payouts {
+		if p.VendorID == "api_vendor_0" {
+			retried = p.ID
+		}
+	}
+
+	var detail models.PayoutDetail
+	if code := getJSON(t, r, "/api/v1/payouts/"+retried.String(), &detail); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if detail.Payout.Status != models.PayoutStatusCompleted {
+		t.Errorf("Expected status completed, got %s", detail.Payout.Status)
+	}
+	if len(detail.Attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(detail.Attempts))
+	}
+	for i, a := range detail.Attempts[:2] {
+		if a.Error == nil || *a.Error != models.FailureBankTimeout {
+			t.Errorf("Expected attempt %d to fail with %s, got %v", i+1, models.FailureBankTimeout, a.Error)
+		}
+	}
+	if last := detail.Attempts[2]; last.Status != models.PayoutStatusCompleted {
+		t.Errorf("Expected final attempt to complete, got %s", last.Status)
+	}
+
+	if code := getJSON(t, r, "/api/v1/payouts/"+uuid.New().String(), nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown payout, got %d", code)
+	}
+	if code := getJSON(t, r, "/api/v1/payouts/not-a-uuid", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid ID, got %d", code)
+	}
+}
+
+// TestGetBatchStatisticsBySegment verifies statistics grouped by a metadata
+// key and by a column, and that payouts without the key form their own segment.
+func TestGetBatchStatisticsBySegment(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("seg_id_1", "ID Vendor 1", map[string]string{"country": "ID"}),
+		vendorItem("seg_id_2", "ID Vendor 2", map[string]string{"country": "ID"}),
+		vendorItem("seg_ph_1", "PH Vendor 1", map[string]string{"country": "PH"}),
+		vendorItem("seg_none", "Vendor Without Country", nil),
+	})
+
+	sc := service.NewScenario()
+	sc.For(service.Vendors("seg_ph_1")).Always(models.FailureAccountBlocked)
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(sc))
+	if err := pool.ProcessBatch(context.Background(), batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	r := api.SetupRouter(repo, pool, api.DefaultConfig())
+
+	var stats models.SegmentedStatistics
+	path := "/api/v1/batches/" + batchID.String() + "/statistics"
+	if code := getJSON(t, r, path+"?group_by=country", &stats); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+
+	bySegment := map[string]models.SegmentStatistics{}
+	for _, seg := range stats.Segments {
+		bySegment[seg.Segment] = seg
+	}
+	if len(bySegment) != 3 {
+		t.Fatalf("Expected segments ID, PH and empty, got %+v", stats.Segments)
+	}
+	if seg := bySegment["ID"]; seg.Total != 2 || seg.Completed != 2 {
+		t.Errorf("Expected ID total=2 completed=2, got total=%d completed=%d", seg.Total, seg.Completed)
+	}
+	if seg := bySegment["PH"]; seg.Total != 1 || seg.Failed != 1 || seg.SuccessRate != 0 {
+		t.Errorf("Expected PH total=1 failed=1 success_rate=0, got total=%d failed=%d success_rate=%v",
+			seg.Total, seg.Failed, seg.SuccessRate)
+	}
+	if seg := bySegment[""]; seg.Total != 1 {
+		t.Errorf("Expected 1 payout without a country, got %d", seg.Total)
+	}
+
+	if code := getJSON(t, r, path+"?group_by=currency", &stats); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(stats.Segments) != 1 || stats.Segments[0].Segment != "IDR" || stats.Segments[0].Total != 4 {
+		t.Errorf("Expected a single IDR segment of 4, got %+v", stats.Segments)
+	}
+
+	if code := getJSON(t, r, path, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without group_by, got %d", code)
+	}
+	if code := getJSON(t, r, "/api/v1/batches/"+uuid.New().String()+"/statistics?group_by=country", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown batch, got %d", code)
+	}
+}
+
+// TestSearchVendors verifies prefix matches rank before substring and fuzzy
+// matches, and that repeated payouts to a vendor are collapsed.
+func TestSearchVendors(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("V-1", "Bali Crafts Co", nil),
+		vendorItem("V-1", "Bali Crafts Co", nil),
+		vendorItem("V-2", "Crafty Bali", nil),
+		vendorItem("V-3", "Jakarta Electronics", nil),
+	})
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+
+	var resp struct {
+		Vendors []models.VendorMatch `json:"vendors"`
+	}
+	if code := getJSON(t, r, "/api/v1/vendors/search?q=bali", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(resp.Vendors) != 2 {
+		t.Fatalf("Expected 2 vendors, got %+v", resp.Vendors)
+	}
+	if resp.Vendors[0].VendorID != "V-1" || resp.Vendors[1].VendorID != "V-2" {
+		t.Errorf("Expected prefix match V-1 before V-2, got %s, %s", resp.Vendors[0].VendorID, resp.Vendors[1].VendorID)
+	}
+	if resp.Vendors[0].PayoutCount != 2 {
+		t.Errorf("Expected 2 payouts for V-1, got %d", resp.Vendors[0].PayoutCount)
+	}
+
+	// A typo still finds the vendor through trigram similarity.
+	if code := getJSON(t, r, "/api/v1/vendors/search?q=balli+crafts", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(resp.Vendors) == 0 || resp.Vendors[0].VendorID != "V-1" {
+		t.Errorf("Expected fuzzy match V-1, got %+v", resp.Vendors)
+	}
+
+	if code := getJSON(t, r, "/api/v1/vendors/search?q=b", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a one-character query, got %d", code)
+	}
+}
+
+// TestBatchEnvironment verifies a batch reports the bank environment that
+// ran it, and that a server in another environment refuses to carry it on.
+func TestBatchEnvironment(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, batchID := processedBatch(t, repo)
+
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+batchID.String(), &summary)
+	if summary.Batch.Environment == nil || *summary.Batch.Environment != models.EnvironmentSandbox {
+		t.Errorf("Expected the batch run in the sandbox, got %v", summary.Batch.Environment)
+	}
+	var runs models.BatchRunListResponse
+	getJSON(t, r, "/api/v1/batches/"+batchID.String()+"/runs", &runs)
+	if len(runs.Runs) != 1 || runs.Runs[0].Environment != models.EnvironmentSandbox {
+		t.Errorf("Expected one sandbox run, got %+v", runs.Runs)
+	}
+
+	production := worker.NewPool(repo, 1, 10, worker.WithEnvironment(models.EnvironmentProduction))
+	w := httptest.NewRecorder()
+	api.SetupRouter(repo, production, api.DefaultConfig()).ServeHTTP(w,
+		httptest.NewRequest(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/start", nil))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), models.EnvironmentProduction) {
+		t.Errorf("Expected 409 starting in production, got %d: %s", w.Code, w.Body.String())
+	}
+	getJSON(t, r, "/api/v1/batches/"+batchID.String()+"/runs", &runs)
+	if len(runs.Runs) != 1 {
+		t.Errorf("Expected no run recorded for the refused start, got %d", len(runs.Runs))
+	}
+}
+
+// TestGetExposure verifies that only claimed payouts count as in flight,
+// that amounts are reported per currency, and that in-flight limits are
+// shown, including for capped currencies with nothing in flight.
+func TestGetExposure(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	usd := vendorItem("EXP-1", "Exposure One", nil)
+	usd.Currency, usd.Amount = "USD", 250
+	idr := vendorItem("EXP-2", "Exposure Two", nil)
+	idr.Amount = 1500000
+	pending := vendorItem("EXP-3", "Exposure Three", nil)
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{usd, idr, pending})
+
+	// Claims follow creation order, so EXP-3 stays pending.
+	if claimed, err := repo.ClaimChunk(context.Background(), batchID, models.PayoutOrderFIFO, 2, nil); len(claimed) != 2 || err != nil {
+		t.Fatalf("Failed to claim 2 payouts: %d claimed, err=%v", len(claimed), err)
+	}
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+
+	var resp models.ExposureReport
+	if code := getJSON(t, r, "/api/v1/reports/exposure", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(resp.Currencies) != 2 {
+		t.Fatalf("Expected 2 currencies, got %+v", resp.Currencies)
+	}
+	idrExp, usdExp := resp.Currencies[0], resp.Currencies[1]
+	if idrExp.Currency != "IDR" || idrExp.PayoutCount != 1 || idrExp.Amount != 1500000 {
+		t.Errorf("Expected IDR 1 payout of 1500000, got %+v", idrExp)
+	}
+	if usdExp.Currency != "USD" || usdExp.PayoutCount != 1 || usdExp.Amount != 250 {
+		t.Errorf("Expected USD 1 payout of 250, got %+v", usdExp)
+	}
+	if usdExp.OldestSince == nil {
+		t.Error("Expected oldest_since to be set")
+	}
+
+	capped := worker.NewPool(repo, 1, 10, worker.WithInFlightLimits(map[string]float64{"USD": 1000, "EUR": 500}))
+	getJSON(t, api.SetupRouter(repo, capped, api.DefaultConfig()), "/api/v1/reports/exposure", &resp)
+	if len(resp.Currencies) != 3 || resp.Currencies[0].Currency != "EUR" || resp.Currencies[0].PayoutCount != 0 {
+		t.Fatalf("Expected EUR listed with nothing in flight, got %+v", resp.Currencies)
+	}
+	if eur, idr, usd := resp.Currencies[0], resp.Currencies[1], resp.Currencies[2]; eur.Limit == nil || *eur.Limit != 500 || idr.Limit != nil || usd.Limit == nil || *usd.Limit != 1000 {
+		t.Errorf("Expected limits for EUR and USD only, got %+v", resp.Currencies)
+	}
+}
+
+// TestGetBatchEstimate verifies a new batch is forecast from the throughput
+// model rolled up when the earlier run finished, with fees for the payouts
+// expected to complete, and that an in-progress batch reports an ETA.
+func TestGetBatchEstimate(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	// 3 Test Bank payouts: one retried twice then paid, one failed, one paid.
+	processedBatch(t, repo)
+
+	items := make([]models.CreatePayoutItem, 6)
+	for i := range items {
+		items[i] = models.CreatePayoutItem{
+			VendorID:    fmt.Sprintf("est_vendor_%d", i),
+			VendorName:  fmt.Sprintf("Estimate Vendor %d", i),
+			Amount:      100,
+			Currency:    "USD",
+			BankAccount: fmt.Sprintf("EST%010d", i),
+			BankName:    "Test Bank",
+		}
+	}
+	batchID := createBatch(t, repo, items)
+
+	fees, err := service.ParseBankFees("Test Bank=1.5")
+	if err != nil {
+		t.Fatalf("ParseBankFees failed: %v", err)
+	}
+	cfg := api.DefaultConfig()
+	cfg.BankFees = fees
+	r := api.SetupRouter(repo, worker.NewPool(repo, 2, 10), cfg)
+
+	var est models.BatchEstimate
+	if code := getJSON(t, r, "/api/v1/batches/"+batchID.String()+"/estimate", &est); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if est.PayoutCount != 6 || est.Concurrency != 2 || len(est.Banks) != 1 {
+		t.Fatalf("Expected 6 payouts at concurrency 2 in one bank, got %+v", est)
+	}
+	bank := est.Banks[0]
+	if bank.Basis != models.EstimateBasisCurrency || bank.HistoricalPayouts != 3 || bank.AttemptsPerPayout != 5.0/3 {
+		t.Errorf("Expected 3 historical payouts with 5 attempts, got %+v", bank)
+	}
+	if est.ExpectedFailures == nil || *est.ExpectedFailures != 2 {
+		t.Errorf("Expected 2 failures (1 in 3), got %v", est.ExpectedFailures)
+	}
+	if est.ExpectedDurationSeconds == nil {
+		t.Error("Expected a duration forecast from the bank's history")
+	}
+	if len(est.ExpectedFees) != 1 || est.ExpectedFees[0].Currency != "USD" || est.ExpectedFees[0].Amount != 6 {
+		t.Errorf("Expected USD 6 in fees (4 payouts at 1.5), got %+v", est.ExpectedFees)
+	}
+
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+batchID.String(), &summary)
+	if summary.EstimatedCompletionAt != nil {
+		t.Errorf("Expected no ETA for a pending batch, got %s", summary.EstimatedCompletionAt)
+	}
+	if _, err := db.Exec(`UPDATE payout_batches SET status = $1 WHERE id = $2`, models.BatchStatusInProgress, batchID); err != nil {
+		t.Fatalf("Failed to mark the batch in progress: %v", err)
+	}
+	getJSON(t, r, "/api/v1/batches/"+batchID.String(), &summary)
+	if summary.EstimatedCompletionAt == nil {
+		t.Error("Expected an ETA for an in-progress batch")
+	}
+}
+
+// TestGetSettlementCutoffs verifies unfinished payouts are grouped by bank
+// and split by whether the bank's cutoff has passed.
+func TestGetSettlementCutoffs(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	late := vendorItem("CUT-1", "Cutoff One", nil)
+	late2 := vendorItem("CUT-2", "Cutoff Two", nil)
+	unknown := vendorItem("CUT-3", "Cutoff Three", nil)
+	unknown.BankName = "Bank Without Cutoff"
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{late, late2, unknown})
+
+	// A midnight cutoff has always passed, so BCA never settles today.
+	cutoffs, err := service.ParseBankCutoffs("BCA=00:00", time.UTC)
+	if err != nil {
+		t.Fatalf("ParseBankCutoffs failed: %v", err)
+	}
+	cfg := api.DefaultConfig()
+	cfg.BankCutoffs = cutoffs
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), cfg)
+
+	var resp models.SettlementCutoffReport
+	if code := getJSON(t, r, "/api/v1/reports/settlement-cutoffs?batch_id="+batchID.String(), &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(resp.SettleToday) != 0 {
+		t.Errorf("Expected nothing to settle today, got %+v", resp.SettleToday)
+	}
+	if len(resp.SettleLater) != 1 || resp.SettleLater[0].BankName != "BCA" || resp.SettleLater[0].PayoutCount != 2 {
+		t.Fatalf("Expected 2 BCA payouts settling later, got %+v", resp.SettleLater)
+	}
+	if resp.SettleLater[0].ExpectedDate <= resp.Today {
+		t.Errorf("Expected a date after %s, got %s", resp.Today, resp.SettleLater[0].ExpectedDate)
+	}
+	if len(resp.NoCutoff) != 1 || resp.NoCutoff[0].Payouts[0].VendorID != "CUT-3" {
+		t.Errorf("Expected CUT-3 under no_cutoff, got %+v", resp.NoCutoff)
+	}
+}
+
+// TestPayoutStatusToken verifies the token from the payout detail resolves
+// to a minimal public view, and that forged tokens are rejected.
+func TestPayoutStatusToken(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, batchID := processedBatch(t, repo)
+
+	payouts, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, models.PayoutStatusFailed, 1, 10)
+	if err != nil || len(payouts) != 1 {
+		t.Fatalf("Expected one failed payout, got %d (err=%v)", len(payouts), err)
+	}
+
+	var detail models.PayoutDetail
+	if code := getJSON(t, r, "/api/v1/payouts/"+payouts[0].ID.String(), &detail); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if detail.StatusToken == "" {
+		t.Fatal("Expected a status token in the payout detail")
+	}
+
+	var view map[string]any
+	if code := getJSON(t, r, "/api/v1/payout-status/"+detail.StatusToken, &view); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if view["status"] != models.PayoutStatusFailed || view["amount"] != 100.0 {
+		t.Errorf("Expected failed payout of 100, got %v", view)
+	}
+	for _, private := range []string{"id", "vendor_id", "bank_account", "failure_reason", "idempotency_key"} {
+		if _, ok := view[private]; ok {
+			t.Errorf("Expected %s to be omitted from the public view", private)
+		}
+	}
+
+	forged := detail.StatusToken[:len(detail.StatusToken)-1] + "A"
+	if forged == detail.StatusToken {
+		forged = detail.StatusToken[:len(detail.StatusToken)-1] + "B"
+	}
+	if code := getJSON(t, r, "/api/v1/payout-status/"+forged, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a forged token, got %d", code)
+	}
+}
+
+// TestExportBatchCSV verifies amounts are formatted per locale, that
+// decimal-comma locales get semicolon-separated files, and that text a
+// spreadsheet would run as a formula is quoted.
+func TestExportBatchCSV(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	item := vendorItem("EXP-1", "Toko Batik", nil)
+	item.Amount = 1500000
+	formula := vendorItem("EXP-2", "=SUM(1+1)", nil)
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{item, formula})
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+
+	cases := []struct {
+		locale string
+		want   string
+	}{
+		{"en", `EXP-1,Toko Batik,,BCA,IDR,"1,500,000","Rp 1,500,000",,pending`},
+		{"id", `EXP-1;Toko Batik;;BCA;IDR;1.500.000;Rp 1.500.000;;pending`},
+		{"en", `EXP-2,'=SUM(1+1),,BCA`},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/batches/"+batchID.String()+"/export?locale="+tc.locale, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("locale %s: expected a row containing %q, got:\n%s", tc.locale, tc.want, w.Body.String())
+		}
+	}
+}
+
+// TestExportBatchEncrypted verifies ?encrypt=pgp exports decrypt with the
+// recipient's private key, and are refused without a configured key.
+func TestExportBatchEncrypted(t *testing.T) {
+	path := "/api/v1/batches/00000000-0000-0000-0000-000000000001/export?encrypt=pgp"
+	w := httptest.NewRecorder()
+	api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig()).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a key, got %d: %s", w.Code, w.Body.String())
+	}
+
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{vendorItem("EXP-1", "Toko Batik", nil)})
+
+	bank, err := openpgp.NewEntity("Bank SFTP", "", "sftp@bank.example", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var public bytes.Buffer
+	if err := bank.Serialize(&public); err != nil {
+		t.Fatal(err)
+	}
+	cfg := api.DefaultConfig()
+	if cfg.ExportKey, err = pgp.ParseKey(public.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), cfg)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/batches/"+batchID.String()+"/export?encrypt=pgp", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, ".csv.pgp") {
+		t.Errorf("Expected a .csv.pgp file, got %s", got)
+	}
+	if w.Header().Get("X-Encryption-Key") != cfg.ExportKey.Fingerprint() {
+		t.Errorf("Expected the key fingerprint %s, got %s", cfg.ExportKey.Fingerprint(), w.Header().Get("X-Encryption-Key"))
+	}
+
+	md, err := openpgp.ReadMessage(w.Body, openpgp.EntityList{bank}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt the export: %v", err)
+	}
+	plain, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(plain), "EXP-1,Toko Batik") {
+		t.Errorf("Expected the decrypted CSV to list EXP-1, got:\n%s", plain)
+	}
+}
+
+// TestImportBatchWithProfile verifies a partner's CSV is imported through a
+// saved column-mapping profile, and that rows breaking the profile's rules
+// are reported by number.
+func TestImportBatchWithProfile(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+
+	profile := `{"columns": {"vendor_id": "Supplier Code", "vendor_name": "Supplier", "amount": "Nominal",
+		"bank_account": "Rekening", "bank_name": "Bank"},
+		"metadata": {"country": "Negara"}, "defaults": {"currency": "IDR"},
+		"delimiter": ";", "decimal_separator": ",",
+		"rules": {"bank_account_pattern": "[0-9]+", "max_amount": 5000000}}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/import-profiles/acme", strings.NewReader(profile)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 saving the profile, got %d: %s", w.Code, w.Body.String())
+	}
+
+	file := "Supplier Code;Supplier;Nominal;Rekening;Bank;Negara\n" +
+		"S-1;Toko Batik;1.500.000,50;123;BCA;ID\n" +
+		"S-2;Warung Kopi;250.000;456;BNI;ID\n"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches/import?profile=acme", strings.NewReader(file)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+		Total   int       `json:"total"`
+		Report  struct {
+			Accepted int `json:"accepted"`
+		} `json:"report"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Total != 2 || created.Report.Accepted != 2 {
+		t.Errorf("Expected 2 payouts accepted, got %d (report: %d)", created.Total, created.Report.Accepted)
+	}
+
+	payouts, _, err := repo.GetPayoutsByBatch(context.Background(), created.BatchID, "", 1, 10)
+	if err != nil {
+		t.Fatalf("GetPayoutsByBatch failed: %v", err)
+	}
+	for _, p := range payouts {
+		if p.VendorID == "S-1" && (p.Amount != 1500000.50 || p.Currency != "IDR" || p.Metadata["country"] != "ID") {
+			t.Errorf("Unexpected imported payout: %+v", p.Payout)
+		}
+	}
+
+	bad := "Supplier Code;Supplier;Nominal;Rekening;Bank;Negara\n" +
+		"S-1;Toko Batik;abc;123;BCA;ID\n" +
+		"S-2;Warung Kopi;250.000;ABC;BNI;ID\n"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches/import?profile=acme", strings.NewReader(bad)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", w.Code)
+	}
+	var rejected struct {
+		Report struct {
+			Errors []struct {
+				Row int `json:"row"`
+			} `json:"errors"`
+		} `json:"report"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &rejected)
+	if errs := rejected.Report.Errors; len(errs) != 2 || errs[0].Row != 1 || errs[1].Row != 2 {
+		t.Errorf("Expected errors for rows 1 and 2, got %s", w.Body.String())
+	}
+}
+
+// TestVerifyBatch verifies a processed batch is consistent and that manual
+// edits to its counters and status are reported as discrepancies.
+func TestVerifyBatch(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, batchID := processedBatch(t, repo)
+	path := "/api/v1/batches/" + batchID.String() + "/verify"
+
+	verify := func() models.BatchVerification {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var v models.BatchVerification
+		if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+			t.Fatalf("Failed to decode report: %v", err)
+		}
+		return v
+	}
+
+	if v := verify(); !v.Consistent || v.RunLive || len(v.Checks) != 4 {
+		t.Fatalf("Expected a consistent batch with all 4 checks run, got %+v", v)
+	}
+
+	if _, err := db.Exec(`UPDATE payout_batches SET completed_count = 0, status = 'completed' WHERE id = $1`, batchID); err != nil {
+		t.Fatalf("Failed to edit batch: %v", err)
+	}
+	v := verify()
+	if v.Consistent {
+		t.Fatal("Expected discrepancies after editing the batch")
+	}
+	fields := map[string]bool{}
+	for _, d := range v.Discrepancies {
+		fields[d.Check+"."+d.Field] = true
+	}
+	if !fields["counters.completed_count"] || !fields["status.status"] {
+		t.Errorf("Expected completed_count and status discrepancies, got %+v", v.Discrepancies)
+	}
+}
+
+// TestSplitPayoutValidation verifies split instructions must cover at least
+// two accounts and add up to 100%.
+func TestSplitPayoutValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	cases := []struct {
+		splits string
+		want   string
+	}{
+		{`[{"percent": 90, "bank_account": "A"}, {"percent": 5, "bank_account": "B"}]`, "payouts[0].splits percentages must add up to 100"},
+		{`[{"percent": 50, "bank_account": "A"}]`, "payouts[0].splits needs at least two accounts"},
+		{`[{"percent": 50, "bank_account": "A"}, {"percent": 50}]`, "payouts[0].splits[1].bank_account is required"},
+	}
+	for _, tc := range cases {
+		body := `{"payouts": [{"vendor_id": "V1", "amount": 100, "currency": "USD", "splits": ` + tc.splits + `}]}`
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("Expected 400 with %q, got %d: %s", tc.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestPurposeCodeValidation verifies a purpose code must be on its
+// country's list, the country coming from metadata or the currency.
+func TestPurposeCodeValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	cases := []struct {
+		item string
+		want string
+	}{
+		{`"currency": "IDR", "purpose_code": "SUPP"`, `unknown purpose code \"SUPP\" for ID`},
+		{`"currency": "USD", "metadata": {"country": "PH"}, "purpose_code": "99"`, `unknown purpose code \"99\" for PH`},
+		{`"currency": "USD", "purpose_code": "not-a-code"`, "want 1 to 10 letters and digits"},
+	}
+	for _, tc := range cases {
+		body := `{"payouts": [{"vendor_id": "V1", "amount": 100, "bank_account": "A", ` + tc.item + `}]}`
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "payouts[0].purpose_code") || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("Expected 400 with %q, got %d: %s", tc.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestSplitPayouts verifies a split item becomes one linked payout per
+// account, and that statistics count it once at the logical level.
+func TestSplitPayouts(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	// Declining the personal account by hook fails one share only.
+	blockPersonal := worker.Hook{
+		Name: "block-personal",
+		BeforeTransfer: func(_ context.Context, p models.Payout) error {
+			if p.BankAccount == "SPLIT-PERSONAL" {
+				return &worker.Decline{Code: models.FailureAccountBlocked}
+			}
+			return nil
+		},
+	}
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()), worker.WithHooks(blockPersonal))
+	r := api.SetupRouter(repo, pool, api.DefaultConfig())
+
+	body := `{"payouts": [
+		{"vendor_id": "SPLIT-1", "amount": 1000.01, "currency": "USD", "bank_name": "BCA", "splits": [
+			{"percent": 80, "bank_account": "SPLIT-BUSINESS"},
+			{"percent": 20, "bank_account": "SPLIT-PERSONAL", "bank_name": "BDO"}]},
+		{"vendor_id": "SPLIT-2", "amount": 50, "currency": "USD", "bank_account": "PLAIN"}]}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+		Total   int       `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Total != 3 {
+		t.Errorf("Expected 3 payouts (2 shares + 1), got %d", created.Total)
+	}
+	if err := pool.ProcessBatch(context.Background(), created.BatchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+created.BatchID.String(), &summary)
+	want := models.LogicalStatistics{Total: 2, Split: 1, Completed: 1, PartiallyCompleted: 1}
+	if summary.Statistics.Total != 3 || summary.Statistics.Logical != want {
+		t.Errorf("Expected 3 payouts in logical %+v, got %d in %+v", want, summary.Statistics.Total, summary.Statistics.Logical)
+	}
+
+	payouts, _, err := repo.GetPayoutsByBatch(context.Background(), created.BatchID, models.PayoutStatusFailed, 1, 10)
+	if err != nil || len(payouts) != 1 {
+		t.Fatalf("Expected one failed share, got %d (%v)", len(payouts), err)
+	}
+	var detail models.PayoutDetail
+	getJSON(t, r, "/api/v1/payouts/"+payouts[0].ID.String(), &detail)
+	if len(detail.Splits) != 2 {
+		t.Fatalf("Expected the detail to list 2 shares, got %d", len(detail.Splits))
+	}
+	business, personal := detail.Splits[0], detail.Splits[1]
+	if business.Amount != 800 || business.BankName != "BCA" || business.Status != models.PayoutStatusCompleted {
+		t.Errorf("Expected a completed 800 share at BCA, got %+v", business)
+	}
+	if personal.Amount != 200.01 || personal.BankName != "BDO" || *personal.SplitPercent != 20 {
+		t.Errorf("Expected a 20%% share of 200.01 at BDO, got %+v", personal)
+	}
+	if *business.SplitGroupID != *personal.SplitGroupID {
+		t.Error("Expected both shares in one split group")
+	}
+}
+
+// declineAccount fails every payout to account without calling the bank.
+func declineAccount(account string) worker.Hook {
+	return worker.Hook{
+		Name: "decline-" + account,
+		BeforeTransfer: func(_ context.Context, p models.Payout) error {
+			if p.BankAccount == account {
+				return &worker.Decline{Code: models.FailureAccountBlocked}
+			}
+			return nil
+		},
+	}
+}
+
+// TestRequeuePayouts verifies a failed payout can be re-routed into a new
+// batch once, that both payouts link to each other, and that the original
+// is no longer reported as failed money.
+func TestRequeuePayouts(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()), worker.WithHooks(declineAccount("REQ-PRIMARY")))
+	r := api.SetupRouter(repo, pool, api.DefaultConfig())
+
+	primary := vendorItem("REQ-1", "Requeued Vendor", nil)
+	primary.BankAccount = "REQ-PRIMARY"
+	first := createBatch(t, repo, []models.CreatePayoutItem{primary, vendorItem("REQ-2", "Paid Vendor", nil)})
+	if err := pool.ProcessBatch(context.Background(), first); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	failed, _, err := repo.GetPayoutsByBatch(context.Background(), first, models.PayoutStatusFailed, 1, 10)
+	if err != nil || len(failed) != 1 {
+		t.Fatalf("Expected one failed payout, got %d (%v)", len(failed), err)
+	}
+	paid, _, _ := repo.GetPayoutsByBatch(context.Background(), first, models.PayoutStatusCompleted, 1, 10)
+
+	requeue := func(payoutID uuid.UUID) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"payouts": [{"payout_id": %q, "bank_account": "REQ-BACKUP"}]}`, payoutID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches/requeue", strings.NewReader(body)))
+		return w
+	}
+	w := requeue(failed[0].ID)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+		Total   int       `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Total != 1 {
+		t.Errorf("Expected 1 payout in the new batch, got %d", created.Total)
+	}
+	for name, tc := range map[string]struct {
+		id     uuid.UUID
+		status int
+	}{
+		"again":     {failed[0].ID, http.StatusConflict},
+		"completed": {paid[0].ID, http.StatusConflict},
+		"unknown":   {uuid.New(), http.StatusNotFound},
+	} {
+		if w := requeue(tc.id); w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.status, w.Code, w.Body.String())
+		}
+	}
+
+	if err := pool.ProcessBatch(context.Background(), created.BatchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	var detail models.PayoutDetail
+	getJSON(t, r, "/api/v1/payouts/"+failed[0].ID.String(), &detail)
+	if len(detail.CorrectionChain) != 2 {
+		t.Fatalf("Expected a chain of 2 payouts, got %d", len(detail.CorrectionChain))
+	}
+	replacement := detail.CorrectionChain[1]
+	if *detail.Payout.SupersededBy != replacement.ID || *replacement.Supersedes != failed[0].ID {
+		t.Errorf("Expected the payouts linked both ways, got superseded_by %v and supersedes %v",
+			detail.Payout.SupersededBy, replacement.Supersedes)
+	}
+	if replacement.BatchID != created.BatchID || replacement.BankAccount != "REQ-BACKUP" || replacement.Status != models.PayoutStatusCompleted {
+		t.Errorf("Expected a completed payout to REQ-BACKUP in the new batch, got %+v", replacement)
+	}
+
+	var financials models.BatchFinancialSummary
+	getJSON(t, r, "/api/v1/batches/"+first.String()+"/financials", &financials)
+	if totals := financials.Currencies[0]; totals.FailedAmount != 0 || totals.SupersededAmount != failed[0].Amount {
+		t.Errorf("Expected the requeued amount reported as superseded, not failed, got %+v", totals)
+	}
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+first.String(), &summary)
+	if summary.Statistics.Failed != 1 || summary.Statistics.Superseded != 1 {
+		t.Errorf("Expected 1 failed payout, superseded, got %+v", summary.Statistics)
+	}
+}
+
+// TestSoftDeleteAndRestore verifies only finished batches can be deleted,
+// that deleted batches leave the list unless asked for and cannot be
+// retried, and that restore brings them back.
+func TestSoftDeleteAndRestore(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, finished := processedBatch(t, repo)
+	pending := createBatch(t, repo, []models.CreatePayoutItem{vendorItem("DEL-1", "Pending Vendor", nil)})
+
+	do := func(method, path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+	listed := func(query string) int {
+		var list models.BatchListResponse
+		if code := getJSON(t, r, "/api/v1/batches"+query, &list); code != http.StatusOK {
+			t.Fatalf("Expected 200 listing batches, got %d", code)
+		}
+		return list.TotalCount
+	}
+	listedByAdmin := func(query string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/batches"+query, ""))
+		var list models.BatchListResponse
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &list) != nil {
+			t.Fatalf("Expected 200 listing batches as admin, got %d", w.Code)
+		}
+		return list.TotalCount
+	}
+
+	if code := do(http.MethodDelete, "/api/v1/batches/"+pending.String()); code != http.StatusConflict {
+		t.Errorf("Expected 409 deleting an unfinished batch, got %d", code)
+	}
+	if code := do(http.MethodDelete, "/api/v1/batches/"+finished.String()); code != http.StatusOK {
+		t.Fatalf("Expected 200 deleting a finished batch, got %d", code)
+	}
+	if n := listed(""); n != 1 {
+		t.Errorf("Expected 1 batch listed after delete, got %d", n)
+	}
+	if n := listed("?include_deleted=true"); n != 1 {
+		t.Errorf("Expected include_deleted to be ignored outside the admin API, got %d batches", n)
+	}
+	if n := listedByAdmin("?include_deleted=true"); n != 2 {
+		t.Errorf("Expected 2 batches with include_deleted, got %d", n)
+	}
+	if code := do(http.MethodPost, "/api/v1/batches/"+finished.String()+"/retry-failed"); code != http.StatusConflict {
+		t.Errorf("Expected 409 retrying a deleted batch, got %d", code)
+	}
+
+	if code := do(http.MethodPost, "/api/v1/batches/"+finished.String()+"/restore"); code != http.StatusOK {
+		t.Fatalf("Expected 200 restoring, got %d", code)
+	}
+	if n := listed(""); n != 2 {
+		t.Errorf("Expected 2 batches listed after restore, got %d", n)
+	}
+}
+
+// TestBatchListAggregates verifies the list footer counts every matching
+// batch by status and totals unfinished payouts, whatever the page size.
+func TestBatchListAggregates(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, _ := processedBatch(t, repo)
+	usd := vendorItem("AGG-2", "Dollar Vendor", nil)
+	usd.Currency, usd.Amount = "USD", 40
+	createBatch(t, repo, []models.CreatePayoutItem{vendorItem("AGG-1", "Rupiah Vendor", nil), usd})
+
+	var list models.BatchListResponse
+	getJSON(t, r, "/api/v1/batches", &list)
+	if list.Aggregates != nil {
+		t.Errorf("Expected no aggregates unless asked for, got %+v", list.Aggregates)
+	}
+
+	getJSON(t, r, "/api/v1/batches?aggregates=true&page_size=1", &list)
+	agg := list.Aggregates
+	if len(list.Batches) != 1 || agg == nil {
+		t.Fatalf("Expected one batch listed with aggregates, got %d (%+v)", len(list.Batches), agg)
+	}
+	if len(agg.StatusCounts) != 2 || agg.StatusCounts[models.BatchStatusPending] != 1 || agg.StatusCounts[models.BatchStatusPartiallyCompleted] != 1 {
+		t.Errorf("Expected 1 pending and 1 partially completed batch, got %v", agg.StatusCounts)
+	}
+	want := []models.CurrencyAmount{{Currency: "IDR", Amount: 100}, {Currency: "USD", Amount: 40}}
+	if agg.PendingPayouts != 2 || len(agg.PendingAmounts) != 2 || agg.PendingAmounts[0] != want[0] || agg.PendingAmounts[1] != want[1] {
+		t.Errorf("Expected 2 pending payouts of IDR 100 and USD 40, got %d %+v", agg.PendingPayouts, agg.PendingAmounts)
+	}
+
+	getJSON(t, r, "/api/v1/batches?aggregates=true&status=partially_completed", &list)
+	if agg := list.Aggregates; len(agg.StatusCounts) != 1 || agg.PendingPayouts != 0 || len(agg.PendingAmounts) != 0 {
+		t.Errorf("Expected the aggregates to follow the status filter, got %+v", agg)
+	}
+}
+
+// TestBatchListDateRange verifies the batch list can be narrowed to batches
+// created in a date range, with the end date exclusive.
+func TestBatchListDateRange(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+	for _, path := range []string{"/api/v1/batches?created_from=yesterday", "/api/v2/batches?created_to=2024-13-01"} {
+		if code := getJSON(t, r, path, nil); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", path, code)
+		}
+	}
+
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r = api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+	created := map[string]uuid.UUID{}
+	for _, day := range []string{"2024-01-15", "2024-02-01", "2024-03-10"} {
+		id := createBatch(t, repo, []models.CreatePayoutItem{vendorItem("RANGE-"+day, "Dated Vendor", nil)})
+		if _, err := db.Exec(`UPDATE payout_batches SET created_at = $2 WHERE id = $1`, id, day+"T12:00:00Z"); err != nil {
+			t.Fatalf("Failed to backdate batch: %v", err)
+		}
+		created[day] = id
+	}
+
+	var list models.BatchListResponse
+	getJSON(t, r, "/api/v1/batches?created_from=2024-01-01&created_to=2024-02-01", &list)
+	if list.TotalCount != 1 || len(list.Batches) != 1 || list.Batches[0].ID != created["2024-01-15"] {
+		t.Errorf("Expected only the January batch, got %d", list.TotalCount)
+	}
+	getJSON(t, r, "/api/v1/batches?created_from=2024-02-01", &list)
+	if list.TotalCount != 2 {
+		t.Errorf("Expected 2 batches from February on, got %d", list.TotalCount)
+	}
+}
+
+// TestResponseLinks verifies batches and payouts carry links a client can
+// follow, and that start and stop are only offered when they apply.
+func TestResponseLinks(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, doneID := processedBatch(t, repo)
+	pendingID := createBatch(t, repo, []models.CreatePayoutItem{vendorItem("LINK-1", "Linked Vendor", nil)})
+
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+pendingID.String(), &summary)
+	links := summary.Batch.Links
+	if links == nil || links.Self != "/api/v1/batches/"+pendingID.String() || links.Start == "" || links.Stop != "" {
+		t.Fatalf("Expected self and start links on a pending batch, got %+v", links)
+	}
+	if code := getJSON(t, r, links.Payouts, nil); code != http.StatusOK {
+		t.Errorf("Expected the payouts link to resolve, got %d", code)
+	}
+
+	getJSON(t, r, "/api/v1/batches/"+doneID.String(), &summary)
+	if links = summary.Batch.Links; links.Start != "" || links.Stop != "" {
+		t.Errorf("Expected no start or stop on a finished batch, got %+v", links)
+	}
+	var page models.PayoutListResponse
+	getJSON(t, r, links.FailedPayouts, &page)
+	if len(page.Payouts) != 1 || page.Payouts[0].Status != models.PayoutStatusFailed {
+		t.Fatalf("Expected the failed payouts link to list 1 failed payout, got %d", len(page.Payouts))
+	}
+	var detail models.PayoutDetail
+	if code := getJSON(t, r, page.Payouts[0].Links.Self, &detail); code != http.StatusOK || detail.Payout.Links.Batch != links.Self {
+		t.Errorf("Expected the payout link to resolve back to its batch, got %d %+v", code, detail.Payout.Links)
+	}
+
+	var list models.BatchListResponse
+	getJSON(t, r, "/api/v1/batches", &list)
+	for _, b := range list.Batches {
+		if b.Links == nil || b.Links.Export == "" {
+			t.Errorf("Expected links on every listed batch, got %+v", b.Links)
+		}
+	}
+}
+
+// TestUpdateBatchValidation verifies ownership changes are checked before
+// the batch is looked up.
+func TestUpdateBatchValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	for _, tc := range []struct {
+		path, body string
+	}{
+		{"/api/v1/batches/not-a-uuid", `{"assigned_to": "ops@example.com"}`},
+		{"/api/v1/batches/" + uuid.New().String(), `{}`},
+		{"/api/v1/batches/" + uuid.New().String(), `{"owner": "` + strings.Repeat("o", 101) + `"}`},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, tc.path, strings.NewReader(tc.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d: %s", tc.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestStartBatchValidation verifies run settings out of bounds are refused
+// before the batch is looked up.
+func TestStartBatchValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	for _, body := range []string{
+		`{"concurrency": 0.5}`,
+		`{"concurrency": -1}`,
+		`{"concurrency": 101}`,
+		`{"chunk_size": 5001}`,
+		`not json`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches/"+uuid.New().String()+"/start", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestStopBatchNotRunning verifies stopping names a batch and is refused
+// for a batch this server is not processing.
+func TestStopBatchNotRunning(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	for path, want := range map[string]int{
+		"/api/v1/batches/not-a-uuid/stop":                  http.StatusBadRequest,
+		"/api/v1/batches/" + uuid.New().String() + "/stop": http.StatusConflict,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		if w.Code != want {
+			t.Errorf("Expected %d for %s, got %d: %s", want, path, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestBatchOwnership verifies a batch is owned by its creator, can be
+// reassigned, and is listed by assignee.
+func TestBatchOwnership(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+
+	send := func(method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Operator", "creator@example.com")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+	code, body := send(http.MethodPost, "/api/v1/batches", `{"payouts": [{"vendor_id": "OWN-1", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"}]}`)
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+	}
+	if code != http.StatusCreated || json.Unmarshal(body, &created) != nil {
+		t.Fatalf("Expected 201 creating a batch, got %d: %s", code, body)
+	}
+	send(http.MethodPost, "/api/v1/batches", `{"assigned_to": "lead@example.com", "payouts": [{"vendor_id": "OWN-2", "amount": 10, "currency": "IDR", "bank_account": "2", "bank_name": "BCA"}]}`)
+
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+created.BatchID.String(), &summary)
+	if summary.Batch.Owner == nil || *summary.Batch.Owner != "creator@example.com" || summary.Batch.AssignedTo != nil {
+		t.Errorf("Expected the creator as owner and nobody assigned, got %v / %v", summary.Batch.Owner, summary.Batch.AssignedTo)
+	}
+
+	path := "/api/v1/batches/" + created.BatchID.String()
+	code, body = send(http.MethodPatch, path, `{"assigned_to": "lead@example.com"}`)
+	var batch models.PayoutBatch
+	if code != http.StatusOK || json.Unmarshal(body, &batch) != nil {
+		t.Fatalf("Expected 200 assigning, got %d: %s", code, body)
+	}
+	if batch.AssignedTo == nil || *batch.AssignedTo != "lead@example.com" || batch.Owner == nil {
+		t.Errorf("Expected the batch assigned with its owner kept, got %+v", batch)
+	}
+
+	var list models.BatchListResponse
+	getJSON(t, r, "/api/v1/batches?assigned_to=lead@example.com", &list)
+	if list.TotalCount != 2 {
+		t.Errorf("Expected 2 batches assigned to lead@example.com, got %d", list.TotalCount)
+	}
+
+	if code, _ = send(http.MethodPatch, path, `{"assigned_to": ""}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 unassigning, got %d", code)
+	}
+	getJSON(t, r, "/api/v1/batches?assigned_to=lead@example.com", &list)
+	if list.TotalCount != 1 {
+		t.Errorf("Expected 1 batch still assigned, got %d", list.TotalCount)
+	}
+	if code, _ = send(http.MethodPatch, "/api/v1/batches/"+uuid.New().String(), `{"owner": "x"}`); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown batch, got %d", code)
+	}
+}
+
+// TestCreateBatchCopy verifies payouts copied in at creation keep values
+// COPY has to escape, and split shares, intact.
+func TestCreateBatchCopy(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+
+	repo := repository.New(db)
+	metadata := map[string]string{"note": "tab\there, line\nbreak, back\\slash", "quote": `"quoted"`}
+	batch, err := repo.CreateBatch(ctx, []models.CreatePayoutItem{
+		{VendorID: "COPY-1", VendorName: "Vendor\tOne", Amount: 1234.56, Currency: "IDR", BankAccount: "111", BankName: "BCA",
+			TransactionIDs: []string{"TXN,1", `TXN"2`, `TXN\3`}, Metadata: metadata, PurposeCode: "99"},
+		{VendorID: "COPY-2", Amount: 100, Currency: "USD", BankName: "BNI", Splits: []models.PayoutSplit{
+			{Percent: 60, BankAccount: "222"}, {Percent: 40, BankAccount: "333", BankName: "BRI"}}},
+	}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if batch.TotalCount != 3 {
+		t.Fatalf("Expected 3 payouts, got %d", batch.TotalCount)
+	}
+
+	var payouts []models.Payout
+	if err := repo.EachPayout(ctx, batch.ID, func(p models.Payout) error {
+		payouts = append(payouts, p)
+		return nil
+	}); err != nil || len(payouts) != 3 {
+		t.Fatalf("Expected 3 payouts read back, got %d (%v)", len(payouts), err)
+	}
+	first := payouts[0]
+	if first.VendorName != "Vendor\tOne" || first.Amount != 1234.56 || first.PurposeCode == nil || *first.PurposeCode != "99" {
+		t.Errorf("Expected the first payout's fields intact, got %+v", first)
+	}
+	if strings.Join(first.TransactionIDs, "|") != `TXN,1|TXN"2|TXN\3` {
+		t.Errorf("Expected the transaction IDs intact, got %q", first.TransactionIDs)
+	}
+	if first.Metadata["note"] != metadata["note"] || first.Metadata["quote"] != metadata["quote"] {
+		t.Errorf("Expected the metadata intact, got %q", first.Metadata)
+	}
+	if first.ReportingCountry != nil || first.HeldAt != nil {
+		t.Errorf("Expected no reporting flag or hold, got %v / %v", first.ReportingCountry, first.HeldAt)
+	}
+
+	shares := payouts[1:]
+	if shares[0].Amount != 60 || shares[1].Amount != 40 || shares[1].BankName != "BRI" ||
+		shares[0].SplitGroupID == nil || shares[1].SplitGroupID == nil || *shares[0].SplitGroupID != *shares[1].SplitGroupID {
+		t.Errorf("Expected two linked shares of 60 and 40, got %+v", shares)
+	}
+}
+
+// TestIngestBatch verifies in PostgreSQL that a batch begun in creating
+// status gets its payouts copied in past several progress updates and turns
+// pending, and that one whose ingestion stalled is failed.
+func TestIngestBatch(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+
+	repo := repository.New(db)
+	items := make([]models.CreatePayoutItem, 2500)
+	for i := range items {
+		items[i] = models.CreatePayoutItem{VendorID: fmt.Sprintf("INGEST-%04d", i), Amount: 10, Currency: "IDR", BankAccount: "111", BankName: "BCA"}
+	}
+	batch, err := repo.BeginBatch(ctx, items, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	got, _ := repo.GetBatch(ctx, batch.ID)
+	if got.Status != models.BatchStatusCreating || got.TotalCount != 2500 || got.IngestedCount == nil || *got.IngestedCount != 0 {
+		t.Fatalf("Expected a batch of 2500 creating with nothing ingested, got %+v", got)
+	}
+	if err := repo.IngestBatch(ctx, batch.ID, items); err != nil {
+		t.Fatalf("IngestBatch failed: %v", err)
+	}
+	got, _ = repo.GetBatch(ctx, batch.ID)
+	stats, _ := repo.GetBatchStatistics(ctx, batch.ID)
+	if got.Status != models.BatchStatusPending || got.PendingCount != 2500 || *got.IngestedCount != 2500 || stats.Pending != 2500 {
+		t.Errorf("Expected a pending batch with 2500 payouts ingested, got %+v (%+v)", got, stats)
+	}
+	if err := repo.IngestBatch(ctx, batch.ID, items); err == nil {
+		t.Error("Expected a second ingestion refused")
+	}
+
+	clk := clock.NewFake(time.Now())
+	later := repository.New(db, repository.WithClock(clk))
+	stalled, err := later.BeginBatch(ctx, items[:1], models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	clk.Advance(time.Hour)
+	if n, err := later.FailStalledCreations(ctx, 30*time.Minute); err != nil || n < 1 {
+		t.Fatalf("Expected the stalled creation failed, got %d (%v)", n, err)
+	}
+	got, _ = repo.GetBatch(ctx, stalled.ID)
+	if got.Status != models.BatchStatusFailed || got.CreationError == nil {
+		t.Errorf("Expected the stalled batch failed with a creation error, got %+v", got)
+	}
+}
+
+// BenchmarkCreateBatch creates a 100k-payout batch through the API,
+// reporting payouts per second:
+//
+//	go test -run '^$' -bench CreateBatch -benchtime 1x ./internal/api
+func BenchmarkCreateBatch(b *testing.B) {
+	const payouts = 100_000
+	var body bytes.Buffer
+	body.WriteString(`{"payouts": [`)
+	for i := 0; i < payouts; i++ {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		fmt.Fprintf(&body, `{"vendor_id": "bench_%06d", "amount": %d, "currency": "IDR", "bank_account": "ACC%010d", "bank_name": "BCA",`+
+			` "metadata": {"country": "ID"}}`, i, 10000+i, i)
+	}
+	body.WriteString(`]}`)
+
+	repo := repository.New(dbtest.Open(b))
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches", bytes.NewReader(body.Bytes())))
+		if w.Code != http.StatusCreated {
+			b.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	b.ReportMetric(float64(payouts*b.N)/b.Elapsed().Seconds(), "payouts/s")
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"strings"
+
+	"coding-challenge/internal/i18n"
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// langKey is the gin context key holding the negotiated language.
+const langKey = "lang"
+
+// Localize negotiates the response language from Accept-Language and
+// announces it with Content-Language.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(langKey, lang)
+		c.Header("Content-Language", lang)
+		c.Next()
+	}
+}
+
+// lang returns the language negotiated for the request.
+func lang(c *gin.Context) string {
+	if l := c.GetString(langKey); l != "" {
+		return l
+	}
+	return i18n.DefaultLanguage
+}
+
+// tr translates a message key for the request's language. Translating an
+// "error." key also records it as the request's error code for /api/v2.
+func tr(c *gin.Context, key string, args ...any) string {
+	if code, ok := strings.CutPrefix(key, "error."); ok {
+		c.Set(errorCodeKey, code)
+	}
+	return i18n.T(lang(c), key, args...)
+}
+
+// bindError turns a request binding error into a localized message, naming
+// fields by their JSON path (e.g. "payouts[0].amount"). Each field is also
+// recorded as its own /api/v2 error, coded by the rule it broke.
+func bindError(c *gin.Context, err error) string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return tr(c, "error.malformed_body")
+	}
+	msgs := make([]string, 0, len(verrs))
+	fields := make([]models.APIError, 0, len(verrs))
+	for _, fe := range verrs {
+		field := fieldError(c, fe)
+		msgs = append(msgs, field.Message)
+		fields = append(fields, field)
+	}
+	c.Set(fieldErrorsKey, fields)
+	return strings.Join(msgs, "; ")
+}
+
+// fieldError localizes the rule one field broke, coded by the rule.
+func fieldError(c *gin.Context, fe validator.FieldError) models.APIError {
+	tag := fe.Tag()
+	if tag == "required_without" {
+		tag = "required"
+	}
+	key := "validation." + tag
+	if !i18n.Has(key) {
+		key = "validation.invalid"
+	}
+	field := jsonPath(fe)
+	var msg string
+	if key == "validation.required" || key == "validation.invalid" {
+		msg = tr(c, key, field)
+	} else {
+		msg = tr(c, key, field, fe.Param())
+	}
+	return models.APIError{Code: strings.TrimPrefix(key, "validation."), Message: msg, Field: field}
+}
+
+// jsonPath converts a validator namespace such as
+// "CreateBatchRequest.Payouts[0].Amount" to "payouts[0].amount".
+func jsonPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if _, rest, ok := strings.Cut(ns, "."); ok {
+		ns = rest
+	}
+	parts := strings.Split(ns, ".")
+	for i, p := range parts {
+		name, index, _ := strings.Cut(p, "[")
+		if index != "" {
+			index = "[" + index
+		}
+		parts[i] = snake(name) + index
+	}
+	return strings.Join(parts, ".")
+}
+
+// snake converts a Go field name to the snake_case used by the JSON tags.
+func snake(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 && !(name[i-1] >= 'A' && name[i-1] <= 'Z') {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
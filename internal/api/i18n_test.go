@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/worker"
+)
+
+// TestLocalizedValidationErrors verifies binding errors are translated per
+// Accept-Language and name fields by their JSON path.
+func TestLocalizedValidationErrors(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	cases := []struct {
+		lang string
+		want string
+	}{
+		{"", "payouts[0].amount must be greater than 0"},
+		{"id-ID,id;q=0.9", "payouts[0].amount harus lebih besar dari 0"},
+		{"vi", "payouts[0].amount phải lớn hơn 0"},
+	}
+	body := `{"payouts":[{"vendor_id":"V1","amount":-5,"currency":"IDR","bank_account":"ACC1"}]}`
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.lang != "" {
+			req.Header.Set("Accept-Language", tc.lang)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected 400, got %d", w.Code)
+		}
+		var resp struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Error != tc.want {
+			t.Errorf("Accept-Language %q: expected %q, got %q", tc.lang, tc.want, resp.Error)
+		}
+	}
+}
+
+// TestLocalizedErrorMessage verifies plain handler errors are translated
+// and the chosen language is announced.
+func TestLocalizedErrorMessage(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/batches/not-a-uuid", nil)
+	req.Header.Set("Accept-Language", "fil-PH")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Language"); got != "fil" {
+		t.Errorf("Expected Content-Language fil, got %q", got)
+	}
+	if !strings.Contains(w.Body.String(), "Hindi wastong batch ID") {
+		t.Errorf("Expected a Filipino error, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key header accepted.
+const maxIdempotencyKey = 255
+
+// idempotent reads the Idempotency-Key header of a batch creation into
+// opts, with a fingerprint of req to tell a retry from a key reused for a
+// different batch. It answers 400 for a key that is too long.
+func idempotent(c *gin.Context, req models.CreateBatchRequest, opts *models.BatchOptions) bool {
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		return true
+	}
+	if len(key) > maxIdempotencyKey {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_idempotency_key", maxIdempotencyKey)})
+		return false
+	}
+	body, _ := json.Marshal(req)
+	sum := sha256.Sum256(body)
+	opts.IdempotencyKey, opts.RequestHash = key, hex.EncodeToString(sum[:])
+	return true
+}
+
+// replayBatch answers a batch creation repeating an idempotency key with
+// the batch first created under it, with 200 and an Idempotent-Replayed
+// header, or 422 if the key was used for a different request. It reports
+// whether it answered; it does not when no batch has the key.
+func (h *Handler) replayBatch(c *gin.Context, opts models.BatchOptions) bool {
+	if opts.IdempotencyKey == "" {
+		return false
+	}
+	batch, err := h.repo.GetBatchByIdempotencyKey(c.Request.Context(), opts.Merchant, opts.IdempotencyKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return true
+	}
+	if batch == nil {
+		return false
+	}
+	if batch.RequestHash == nil || *batch.RequestHash != opts.RequestHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": tr(c, "error.idempotency_key_reused"), "batch_id": batch.ID})
+		return true
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(http.StatusOK, gin.H{
+		"message":  tr(c, "msg.batch_replayed"),
+		"batch_id": batch.ID,
+		"total":    batch.TotalCount,
+		"status":   batch.Status,
+		"links":    batchLinks(c, batch),
+	})
+	return true
+}
@@ -0,0 +1,99 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// TestIdempotentCreateBatch verifies a batch creation retried with the same
+// Idempotency-Key returns the first batch instead of creating another, that
+// the key cannot be reused for a different batch, and that keys are scoped
+// to the merchant.
+func TestIdempotentCreateBatch(t *testing.T) {
+	_, _, r := newMemRouter(t, service.NewScenario())
+
+	const body = `{"payouts": [{"vendor_id": "IDEM-1", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"}]}`
+	type created struct {
+		BatchID string `json:"batch_id"`
+	}
+	send := func(path, key, merchant, body string) (*httptest.ResponseRecorder, created) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		if merchant != "" {
+			req.Header.Set("X-Merchant", merchant)
+		}
+		r.ServeHTTP(w, req)
+		var resp created
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, first := send("/api/v1/batches", "order-1", "", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 for the first request, got %d: %s", w.Code, w.Body)
+	}
+	w, retry := send("/api/v1/batches", "order-1", "", body)
+	if w.Code != http.StatusOK || retry.BatchID != first.BatchID || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the retry answered 200 with batch %s, got %d: %s", first.BatchID, w.Code, w.Body)
+	}
+	w, async := send("/api/v1/batches?async=true", "order-1", "", body)
+	if w.Code != http.StatusOK || async.BatchID != first.BatchID {
+		t.Errorf("Expected an async retry answered with batch %s, got %d: %s", first.BatchID, w.Code, w.Body)
+	}
+	var list models.BatchListResponse
+	getJSON(t, r, "/api/v1/batches", &list)
+	if list.TotalCount != 1 {
+		t.Errorf("Expected 1 batch after the retries, got %d", list.TotalCount)
+	}
+
+	if w, _ := send("/api/v1/batches", "order-1", "", strings.Replace(body, `"amount": 10`, `"amount": 20`, 1)); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 reusing the key for another batch, got %d: %s", w.Code, w.Body)
+	}
+	w, other := send("/api/v1/batches", "order-1", "acme", body)
+	if w.Code != http.StatusCreated || other.BatchID == first.BatchID {
+		t.Errorf("Expected another merchant's key to create its own batch, got %d: %s", w.Code, w.Body)
+	}
+	if w, _ := send("/api/v1/batches", strings.Repeat("k", 256), "", body); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a key over 255 characters, got %d", w.Code)
+	}
+}
+
+// TestIdempotencyKeyUnique verifies the database refuses a second batch
+// with a merchant's idempotency key, and finds the first by it.
+func TestIdempotencyKeyUnique(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+	repo := repository.New(db)
+
+	opts := models.BatchOptions{Merchant: "idem-merchant", IdempotencyKey: uuid.NewString(), RequestHash: "h"}
+	items := []models.CreatePayoutItem{vendorItem("idem_vendor_0", "Idem Vendor", nil)}
+	first, err := repo.CreateBatch(ctx, items, opts)
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if _, err := repo.CreateBatch(ctx, items, opts); !errors.Is(err, repository.ErrDuplicateRequest) {
+		t.Errorf("Expected ErrDuplicateRequest for a reused key, got %v", err)
+	}
+	if _, err := repo.BeginBatch(ctx, items, opts); !errors.Is(err, repository.ErrDuplicateRequest) {
+		t.Errorf("Expected ErrDuplicateRequest beginning a batch with a reused key, got %v", err)
+	}
+	found, err := repo.GetBatchByIdempotencyKey(ctx, "idem-merchant", opts.IdempotencyKey)
+	if err != nil || found == nil || found.ID != first.ID || found.RequestHash == nil || *found.RequestHash != "h" {
+		t.Errorf("Expected batch %s by its key, got %+v (%v)", first.ID, found, err)
+	}
+	if found, _ := repo.GetBatchByIdempotencyKey(ctx, "", opts.IdempotencyKey); found != nil {
+		t.Errorf("Expected the key scoped to its merchant, got batch %s", found.ID)
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"coding-challenge/internal/importer"
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// SaveImportProfile creates or replaces a partner's CSV column mapping.
+// PUT /api/v1/import-profiles/:name
+func (h *Handler) SaveImportProfile(c *gin.Context) {
+	var profile models.ImportProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	profile.Name = c.Param("name")
+	if err := importer.ValidateProfile(profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_profile", err.Error())})
+		return
+	}
+
+	saved, err := h.repo.SaveImportProfile(c.Request.Context(), profile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// GetImportProfile returns one import profile.
+// GET /api/v1/import-profiles/:name
+func (h *Handler) GetImportProfile(c *gin.Context) {
+	profile, err := h.repo.GetImportProfile(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if profile == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.profile_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// ListImportProfiles returns every import profile.
+// GET /api/v1/import-profiles
+func (h *Handler) ListImportProfiles(c *gin.Context) {
+	profiles, err := h.repo.ListImportProfiles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profiles": profiles, "fields": importer.Fields()})
+}
+
+// DeleteImportProfile removes an import profile.
+// DELETE /api/v1/import-profiles/:name
+func (h *Handler) DeleteImportProfile(c *gin.Context) {
+	deleted, err := h.repo.DeleteImportProfile(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.profile_not_found")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// importProfile returns the import profile named by ?profile=, or the
+// default profile without one, answering 404 for an unknown name.
+func (h *Handler) importProfile(c *gin.Context) (models.ImportProfile, bool) {
+	name := c.Query("profile")
+	if name == "" {
+		return importer.DefaultProfile, true
+	}
+	p, err := h.repo.GetImportProfile(c.Request.Context(), name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.ImportProfile{}, false
+	}
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.profile_not_found")})
+		return models.ImportProfile{}, false
+	}
+	return *p, true
+}
+
+// ImportBatch creates a batch from a CSV request body, or NDJSON when the
+// Content-Type is application/x-ndjson. ?profile= names the partner's import
+// profile, whose rules every row must pass; without one, headers must match
+// the JSON field names of a payout and other columns become metadata.
+// ?owner=, ?assigned_to= and ?duplicate_check= work as in a JSON batch. The
+// response carries the import report either way.
+// POST /api/v1/batches/import?profile=acme&payout_order=fifo
+func (h *Handler) ImportBatch(c *gin.Context) {
+	profile, ok := h.importProfile(c)
+	if !ok {
+		return
+	}
+
+	parse := importer.Parse
+	if ct := c.ContentType(); ct == "application/x-ndjson" || ct == "application/jsonl" {
+		parse = importer.ParseNDJSON
+	}
+	items, report, err := parse(c.Request.Body, profile)
+	var rowErrs *importer.Error
+	if errors.As(err, &rowErrs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_import", err.Error()), "report": report})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_import", err.Error())})
+		return
+	}
+
+	// Imported rows go through the same validation as a JSON batch.
+	req := models.CreateBatchRequest{
+		Payouts:        items,
+		PayoutOrder:    c.Query("payout_order"),
+		Owner:          c.Query("owner"),
+		AssignedTo:     c.Query("assigned_to"),
+		DuplicateCheck: c.Query("duplicate_check"),
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	dups, ok := h.completedDuplicates(c, req.DuplicateCheck, req.Payouts, nil)
+	if !ok {
+		return
+	}
+	used, ok := h.quotaBatch(c, len(req.Payouts))
+	if !ok {
+		return
+	}
+
+	batch, err := h.repo.CreateBatch(c.Request.Context(), req.Payouts, withOwner(c, req.Options()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "error.create_failed", err.Error())})
+		return
+	}
+	h.quotaCreated(c, used, batch.TotalCount)
+
+	c.JSON(http.StatusCreated, withDuplicates(gin.H{
+		"message":  tr(c, "msg.batch_created"),
+		"batch_id": batch.ID,
+		"total":    batch.TotalCount,
+		"status":   batch.Status,
+		"links":    batchLinks(c, batch),
+		"profile":  profile.Name,
+		"report":   report,
+	}, dups))
+}
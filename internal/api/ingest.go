@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/quota"
+	"coding-challenge/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// createBatchAsync records the batch in creating status, answers 202 and
+// writes its payouts in the background, outside the request's budget. GET
+// /batches/:id follows the progress in ingested_count; the batch becomes
+// pending once every payout is written, or failed with a creation_error.
+// used is what the merchant submitted this month before the batch.
+func (h *Handler) createBatchAsync(c *gin.Context, req models.CreateBatchRequest, opts models.BatchOptions, used int, dups []models.DuplicatePayout, rejected []models.PayoutError) {
+	batch, err := h.repo.BeginBatch(c.Request.Context(), req.Payouts, opts)
+	if errors.Is(err, repository.ErrDuplicateRequest) && h.replayBatch(c, opts) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "error.create_failed", err.Error())})
+		return
+	}
+	h.quotaCreated(c, used, batch.TotalCount)
+
+	go func() {
+		if err := h.repo.IngestBatch(context.Background(), batch.ID, req.Payouts); err != nil {
+			log.Printf("[api] Ingesting batch %s failed: %v", batch.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, withRejected(withDuplicates(gin.H{
+		"message":  tr(c, "msg.batch_creating"),
+		"batch_id": batch.ID,
+		"total":    batch.TotalCount,
+		"status":   batch.Status,
+		"links":    batchLinks(c, batch),
+	}, dups), rejected))
+}
+
+// maxStreamLine is the longest line StreamBatch reads as one payout.
+const maxStreamLine = 1 << 20
+
+// errEmptyStream is returned by a stream that held no payouts.
+var errEmptyStream = errors.New("no payouts in the stream")
+
+// streamLineError refuses the payout on one line of a stream.
+type streamLineError struct {
+	line int
+	msg  string
+}
+
+func (e *streamLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// StreamBatch handles POST /api/v1/batches/stream
+// Creates a batch from an application/x-ndjson body, one payout item per
+// line, writing the payouts as the lines are read instead of after the
+// whole body is. Each item is validated like those of a JSON batch; the
+// first invalid line aborts the request and nothing is created, as does
+// the line that takes the merchant over a quota. Batch options come from the
+// query: payout_order, owner and assigned_to.
+func (h *Handler) StreamBatch(c *gin.Context) {
+	req := models.CreateBatchRequest{
+		PayoutOrder: c.Query("payout_order"),
+		Owner:       c.Query("owner"),
+		AssignedTo:  c.Query("assigned_to"),
+	}
+	if err := binding.Validator.Engine().(*validator.Validate).StructExcept(&req, "Payouts"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	// The size is only known once the stream ends, so the quota is checked
+	// line by line.
+	used, ok := h.quotaBatch(c, 0)
+	if !ok {
+		return
+	}
+	limits, _ := h.cfg.Quotas.For(merchant(c))
+
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	line, count := 0, 0
+	next := func() (models.CreatePayoutItem, error) {
+		var item models.CreatePayoutItem
+		for scanner.Scan() {
+			line++
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+			if err := json.Unmarshal(raw, &item); err != nil {
+				return item, &streamLineError{line, tr(c, "error.malformed_body")}
+			}
+			if err := binding.Validator.ValidateStruct(&item); err != nil {
+				return item, &streamLineError{line, bindError(c, err)}
+			}
+			if _, msg := checkItem(c, count, item); msg != "" {
+				return item, &streamLineError{line, msg}
+			}
+			if err := limits.CheckBatch(count+1, used); err != nil {
+				return item, err
+			}
+			count++
+			return item, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return item, err
+		}
+		if count == 0 {
+			return item, errEmptyStream
+		}
+		return item, io.EOF
+	}
+
+	batch, err := h.repo.StreamBatch(c.Request.Context(), withOwner(c, req.Options()), next)
+	var lineErr *streamLineError
+	var exceeded *quota.ExceededError
+	switch {
+	case errors.As(err, &exceeded):
+		quotaExceeded(c, exceeded)
+		return
+	case errors.As(err, &lineErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_stream_line", lineErr.line, lineErr.msg)})
+		return
+	case errors.Is(err, bufio.ErrTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_stream_line", line+1, err.Error())})
+		return
+	case errors.Is(err, errEmptyStream):
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.empty_stream")})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "error.create_failed", err.Error())})
+		return
+	}
+	h.quotaCreated(c, used, batch.TotalCount)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  tr(c, "msg.batch_created"),
+		"batch_id": batch.ID,
+		"total":    batch.TotalCount,
+		"status":   batch.Status,
+		"links":    batchLinks(c, batch),
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"coding-challenge/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJobs lists the background jobs of this instance's scheduler with
+// their schedules, next run times and most recent runs.
+// GET /api/v1/jobs (and /admin/v1/jobs)
+func (h *Handler) ListJobs(c *gin.Context) {
+	jobs, err := h.cfg.Jobs.Jobs(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// ListJobRuns lists a background job's latest runs, newest first, with
+// their outcomes, durations and errors; 404 for an unknown job.
+// GET /api/v1/jobs/:name/runs?limit=50
+func (h *Handler) ListJobRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	runs, err := h.cfg.Jobs.Runs(c.Request.Context(), c.Param("name"), limit)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.job_not_found")})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": c.Param("name"), "runs": runs})
+}
+
+// RunJob starts a background job now, enabled or not, and answers 202 with
+// the run without waiting for it; 409 while the job runs already.
+// POST /admin/v1/jobs/:name/run
+func (h *Handler) RunJob(c *gin.Context) {
+	run, err := h.cfg.Jobs.Trigger(c.Param("name"), actor(c))
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.job_not_found")})
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.job_running")})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("[api] %s triggered job %s (run %s)", actor(c), run.Job, run.ID)
+	c.JSON(http.StatusAccepted, run)
+}
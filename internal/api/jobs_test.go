@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/scheduler"
+	"coding-challenge/internal/worker"
+)
+
+// TestJobsAPI verifies the admin API lists the scheduler's jobs, triggers
+// one with the operator recorded, answers 409 while it runs and 404 for an
+// unknown job, that the API lists each job's runs with their outcome,
+// duration and error, and that the routes are left out without a scheduler.
+func TestJobsAPI(t *testing.T) {
+	store := memstore.New()
+	jobs := scheduler.New(store)
+	release := make(chan struct{})
+	if err := jobs.Add(scheduler.Job{Name: "orphan_sweep", Schedule: "*/15 * * * *", Run: func(context.Context) error {
+		<-release
+		return nil
+	}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := jobs.Add(scheduler.Job{Name: "archiver", Schedule: "@daily", Run: func(context.Context) error {
+		return errors.New("archive unreachable")
+	}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	cfg := adminConfig()
+	cfg.Jobs = jobs
+	r := api.SetupRouter(memAPIStore{Store: store}, worker.NewPool(store, 1, 10), cfg)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPost, "/admin/v1/jobs/orphan_sweep/run", ""))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var run models.JobRun
+	json.Unmarshal(w.Body.Bytes(), &run)
+	if run.Job != "orphan_sweep" || run.Trigger != models.JobTriggerManual || run.TriggeredBy != "ops@example.com" {
+		t.Errorf("Expected a manual run by ops@example.com, got %+v", run)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPost, "/admin/v1/jobs/orphan_sweep/run", ""))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while the job runs, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodPost, "/admin/v1/jobs/reconcile/run", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", w.Code)
+	}
+	close(release)
+	if _, err := jobs.Trigger("archiver", ""); err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+	jobs.Wait()
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/jobs", ""))
+	var list struct{ Jobs []models.ScheduledJob }
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Jobs) != 2 || list.Jobs[0].Enabled || list.Jobs[0].LastRun == nil ||
+		list.Jobs[0].LastRun.Status != models.JobRunSucceeded {
+		t.Errorf("Expected the disabled job with its succeeded run, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the jobs listed outside the admin API too, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/archiver/runs", nil))
+	var history struct{ Runs []models.JobRun }
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if w.Code != http.StatusOK || len(history.Runs) != 1 || history.Runs[0].Status != models.JobRunFailed ||
+		history.Runs[0].Error == nil || *history.Runs[0].Error != "archive unreachable" || history.Runs[0].FinishedAt == nil {
+		t.Errorf("Expected the archiver's failed run with its error, got %d: %s", w.Code, w.Body.String())
+	}
+	if d := history.Runs[0].DurationSeconds; d < 0 {
+		t.Errorf("Expected a non-negative duration, got %f", d)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/outbox/runs", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for the runs of an unknown job, got %d", w.Code)
+	}
+
+	r = api.SetupRouter(memAPIStore{Store: store}, worker.NewPool(store, 1, 10), adminConfig())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/jobs", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected no jobs routes without a scheduler, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected no /api/v1/jobs without a scheduler, got %d", w.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"strings"
+
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiBase is the path prefix of the links put in a response: /api/v2 for
+// v2 requests and /api/v1 otherwise, admin requests included.
+func apiBase(c *gin.Context) string {
+	if strings.HasPrefix(c.Request.URL.Path, v2Base+"/") {
+		return v2Base
+	}
+	return "/api/v1"
+}
+
+// batchLinks returns the links of a batch in its current state.
+func batchLinks(c *gin.Context, b *models.PayoutBatch) *models.BatchLinks {
+	self := apiBase(c) + "/batches/" + b.ID.String()
+	links := &models.BatchLinks{
+		Self:          self,
+		Payouts:       self + "/payouts",
+		FailedPayouts: self + "/payouts?status=" + models.PayoutStatusFailed,
+		Export:        self + "/export",
+	}
+	if b.DeletedAt == nil {
+		switch b.Status {
+		case models.BatchStatusPending, models.BatchStatusPaused:
+			links.Start = self + "/start"
+			links.Cancel = self + "/cancel"
+		case models.BatchStatusInProgress:
+			links.Stop = self + "/stop"
+			links.Cancel = self + "/cancel"
+		}
+	}
+	return links
+}
+
+// payoutLinks returns the links of a payout.
+func payoutLinks(c *gin.Context, p *models.Payout) *models.PayoutLinks {
+	base := apiBase(c)
+	return &models.PayoutLinks{
+		Self:  base + "/payouts/" + p.ID.String(),
+		Batch: base + "/batches/" + p.BatchID.String(),
+	}
+}
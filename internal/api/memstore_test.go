@@ -0,0 +1,604 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// memAPIStore serves the batch endpoints from memory. The other domains are
+// left nil: these tests never reach them.
+type memAPIStore struct {
+	*memstore.Store
+	api.BatchAdminStore
+	api.PayoutStore
+	api.RecoveryStore
+	api.FundingStore
+	api.PaymentFileStore
+	api.VendorStore
+	api.ComplianceStore
+	api.ReportStore
+	api.UsageStore
+	api.QuotaStore
+	api.WebhookStore
+	api.SettingsStore
+	api.NonceStore
+}
+
+// newMemRouter serves the API from a fresh in-memory store, paying batches
+// through bank, with the default config adjusted by configure.
+func newMemRouter(t *testing.T, bank service.BankClient, configure ...func(*api.Config)) (*memstore.Store, *worker.Pool, *gin.Engine) {
+	t.Helper()
+	store := memstore.New()
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(bank))
+	cfg := api.DefaultConfig()
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+	return store, pool, api.SetupRouter(memAPIStore{Store: store}, pool, cfg)
+}
+
+// sendJSON performs a request with body and decodes the JSON response into
+// out, if set.
+func sendJSON(t *testing.T, r *gin.Engine, method, path, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("Failed to decode %s %s (%d): %v", method, path, w.Code, err)
+		}
+	}
+	return w
+}
+
+// TestBatchLifecycleInMemory verifies a batch can be created, started and
+// inspected through the API against the in-memory store, without a
+// database.
+func TestBatchLifecycleInMemory(t *testing.T) {
+	bank := service.NewSimulator(service.UniformLatency{Min: 0, Max: time.Millisecond}, nil, service.WithLatencyScale(0))
+	_, pool, r := newMemRouter(t, bank)
+
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+	}
+	w := sendJSON(t, r, http.MethodPost, "/api/v1/batches", `{"payouts": [
+		{"vendor_id": "MEM-1", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"},
+		{"vendor_id": "MEM-2", "amount": 20, "currency": "IDR", "bank_account": "2", "bank_name": "BNI"}]}`, &created)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating a batch, got %d: %s", w.Code, w.Body)
+	}
+	path := "/api/v1/batches/" + created.BatchID.String()
+
+	if w := sendJSON(t, r, http.MethodPost, path+"/start", "", nil); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202 starting the batch, got %d: %s", w.Code, w.Body)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Processing(created.BatchID) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var summary models.BatchSummary
+	if code := getJSON(t, r, path, &summary); code != http.StatusOK {
+		t.Fatalf("Expected 200 for the batch, got %d", code)
+	}
+	if !summary.Batch.IsTerminal() || summary.Statistics.Completed+summary.Statistics.Failed != 2 {
+		t.Errorf("Expected a finished batch with both payouts processed, got %s (%+v)", summary.Batch.Status, summary.Statistics)
+	}
+	var list models.PayoutListResponse
+	if code := getJSON(t, r, path+"/payouts", &list); code != http.StatusOK || list.TotalCount != 2 {
+		t.Errorf("Expected 2 payouts listed, got %d (%d)", list.TotalCount, code)
+	}
+	var runs struct {
+		Runs []models.BatchRun `json:"runs"`
+	}
+	if code := getJSON(t, r, path+"/runs", &runs); code != http.StatusOK || len(runs.Runs) != 1 {
+		t.Errorf("Expected one run, got %d (%d)", len(runs.Runs), code)
+	}
+}
+
+// TestAsyncBatchCreation verifies ?async=true answers 202 with a batch in
+// creating status that turns pending once its payouts are ingested, that a
+// batch still creating cannot be started, and that a failed ingestion
+// leaves the batch failed with its error.
+func TestAsyncBatchCreation(t *testing.T) {
+	store, _, r := newMemRouter(t, service.NewScenario())
+
+	await := func(batchID uuid.UUID) models.BatchSummary {
+		t.Helper()
+		var summary models.BatchSummary
+		deadline := time.Now().Add(5 * time.Second)
+		for getJSON(t, r, "/api/v1/batches/"+batchID.String(), &summary) == http.StatusOK &&
+			summary.Batch.Status == models.BatchStatusCreating && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		return summary
+	}
+
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+		Total   int       `json:"total"`
+		Status  string    `json:"status"`
+	}
+	w := sendJSON(t, r, http.MethodPost, "/api/v1/batches?async=true", `{"payouts": [
+		{"vendor_id": "ASYNC-1", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"},
+		{"vendor_id": "ASYNC-2", "amount": 20, "currency": "IDR", "bank_account": "2", "bank_name": "BNI"}]}`, &created)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202 creating a batch asynchronously, got %d: %s", w.Code, w.Body)
+	}
+	if created.Status != models.BatchStatusCreating || created.Total != 2 {
+		t.Errorf("Expected a batch of 2 creating, got %d %s", created.Total, created.Status)
+	}
+	summary := await(created.BatchID)
+	if b := summary.Batch; b.Status != models.BatchStatusPending || b.IngestedCount == nil || *b.IngestedCount != 2 || summary.Statistics.Pending != 2 {
+		t.Errorf("Expected a pending batch with 2 payouts ingested, got %s (%v, %+v)", b.Status, b.IngestedCount, summary.Statistics)
+	}
+
+	// Duplicate vendors are refused before the batch is recorded, so a
+	// failing ingestion is driven through the store.
+	duplicated := []models.CreatePayoutItem{
+		{VendorID: "ASYNC-3", Amount: 10, Currency: "IDR", BankAccount: "1"},
+		{VendorID: "ASYNC-3", Amount: 20, Currency: "IDR", BankAccount: "2"},
+	}
+	failed, err := store.BeginBatch(context.Background(), duplicated, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	if err := store.IngestBatch(context.Background(), failed.ID, duplicated); err == nil {
+		t.Error("Expected ingesting a duplicate vendor to fail")
+	}
+	summary = await(failed.ID)
+	if b := summary.Batch; b.Status != models.BatchStatusFailed || b.CreationError == nil || !strings.Contains(*b.CreationError, "ASYNC-3") {
+		t.Errorf("Expected the batch failed with the duplicate vendor, got %s (%v)", b.Status, b.CreationError)
+	}
+
+	creating, err := store.BeginBatch(context.Background(), []models.CreatePayoutItem{{VendorID: "ASYNC-4", Amount: 1, Currency: "IDR"}}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("BeginBatch failed: %v", err)
+	}
+	if w := sendJSON(t, r, http.MethodPost, "/api/v1/batches/"+creating.ID.String()+"/start", "", nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 starting a batch still creating, got %d: %s", w.Code, w.Body)
+	}
+}
+
+// TestDedupeVendors verifies a vendor appearing twice in a batch request is
+// refused with 422 naming it, or with dedupe_strategy merge becomes one
+// payout of the summed amount with the transaction IDs of both entries,
+// unless the entries pay different accounts.
+func TestDedupeVendors(t *testing.T) {
+	store, _, r := newMemRouter(t, service.NewScenario())
+
+	send := func(strategy, secondAccount string, out any) *httptest.ResponseRecorder {
+		return sendJSON(t, r, http.MethodPost, "/api/v1/batches", `{"dedupe_strategy": "`+strategy+`", "payouts": [
+			{"vendor_id": "DUP-1", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA", "transaction_ids": ["t1", "t2"]},
+			{"vendor_id": "DUP-2", "amount": 5, "currency": "IDR", "bank_account": "2", "bank_name": "BCA"},
+			{"vendor_id": "DUP-1", "amount": 15, "currency": "IDR", "bank_account": "`+secondAccount+`", "bank_name": "BCA", "transaction_ids": ["t2", "t3"]}]}`, out)
+	}
+	refused := func(w *httptest.ResponseRecorder) {
+		t.Helper()
+		var body struct {
+			VendorIDs []string `json:"vendor_ids"`
+		}
+		if w.Code != http.StatusUnprocessableEntity || json.Unmarshal(w.Body.Bytes(), &body) != nil ||
+			len(body.VendorIDs) != 1 || body.VendorIDs[0] != "DUP-1" {
+			t.Errorf("Expected 422 naming DUP-1, got %d: %s", w.Code, w.Body)
+		}
+	}
+
+	refused(send("", "1", nil))
+	refused(send("merge", "9", nil))
+	if w := send("first", "1", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown strategy, got %d", w.Code)
+	}
+
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+		Total   int       `json:"total"`
+	}
+	if w := send("merge", "1", &created); w.Code != http.StatusCreated || created.Total != 2 {
+		t.Fatalf("Expected 201 with 2 payouts after merging, got %d: %s", w.Code, w.Body)
+	}
+	var merged *models.Payout
+	store.EachPayout(context.Background(), created.BatchID, func(p models.Payout) error {
+		if p.VendorID == "DUP-1" {
+			merged = &p
+		}
+		return nil
+	})
+	if merged == nil || merged.Amount != 25 || strings.Join(merged.TransactionIDs, ",") != "t1,t2,t3" {
+		t.Errorf("Expected DUP-1 paid 25 for t1,t2,t3, got %+v", merged)
+	}
+}
+
+// TestCreateBatchRetryPolicy verifies a retry policy in the creation
+// payload is validated and kept on the batch.
+func TestCreateBatchRetryPolicy(t *testing.T) {
+	_, _, r := newMemRouter(t, service.NewScenario())
+
+	send := func(policy string, out any) *httptest.ResponseRecorder {
+		return sendJSON(t, r, http.MethodPost, "/api/v1/batches", `{"retry_policy": `+policy+`, "payouts": [
+			{"vendor_id": "POLICY-1", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"}]}`, out)
+	}
+	for _, policy := range []string{
+		`{"max_attempts": 50}`,
+		`{"retryable_codes": ["INVALID_ACCOUNT"]}`,
+		`{"backoff": "base=1m,max=1s"}`,
+	} {
+		if w := send(policy, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for retry policy %s, got %d: %s", policy, w.Code, w.Body)
+		}
+	}
+
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+	}
+	if w := send(`{"max_attempts": 5, "backoff": "base=1s", "retryable_codes": ["RATE_LIMITED"]}`, &created); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+created.BatchID.String(), &summary)
+	if p := summary.Batch.RetryPolicy; p == nil || p.MaxAttempts != 5 || p.Backoff != "base=1s" || len(p.RetryableCodes) != 1 {
+		t.Errorf("Expected the retry policy kept on the batch, got %+v", p)
+	}
+}
+
+// TestBatchSettlementReport verifies the settlement report endpoint lists
+// paid vendors with their references and unpaid ones with the action to
+// take.
+func TestBatchSettlementReport(t *testing.T) {
+	sc := service.NewScenario()
+	sc.For(service.Vendors("SETTLE-2")).Always(models.FailureInsufficientFunds)
+	store, pool, r := newMemRouter(t, sc)
+
+	batch, err := store.CreateBatch(context.Background(), []models.CreatePayoutItem{
+		{VendorID: "SETTLE-1", Amount: 10, Currency: "IDR", BankAccount: "1"},
+		{VendorID: "SETTLE-2", Amount: 20, Currency: "IDR", BankAccount: "2"},
+	}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(context.Background(), batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	var report models.SettlementReport
+	if code := getJSON(t, r, "/api/v1/batches/"+batch.ID.String()+"/settlement-report", &report); code != http.StatusOK {
+		t.Fatalf("Expected 200 for the settlement report, got %d", code)
+	}
+	if len(report.Paid) != 1 || report.Paid[0].VendorID != "SETTLE-1" || report.Paid[0].Reference == "" {
+		t.Errorf("Expected SETTLE-1 paid with its reference, got %+v", report.Paid)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Action != models.ActionFundAndRetry {
+		t.Errorf("Expected SETTLE-2 unpaid, to fund and retry, got %+v", report.Failed)
+	}
+	if code := getJSON(t, r, "/api/v1/batches/"+uuid.NewString()+"/settlement-report", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown batch, got %d", code)
+	}
+}
+
+// TestDuplicatePayoutCheck verifies payouts already completed in a recent
+// batch are refused with block, reported with warn and let through with
+// off, split payouts matching on their total and a payout for other
+// transactions not matching, and that the policy applies to CSV imports.
+func TestDuplicatePayoutCheck(t *testing.T) {
+	store, pool, r := newMemRouter(t, service.NewScenario(), func(cfg *api.Config) {
+		cfg.DuplicateCheck = models.DuplicateCheckWarn
+	})
+
+	paid, err := store.CreateBatch(context.Background(), []models.CreatePayoutItem{
+		{VendorID: "DUP-1", Amount: 10, Currency: "IDR", BankAccount: "1", TransactionIDs: []string{"T1", "T2"}},
+		{VendorID: "DUP-2", Amount: 30, Currency: "IDR", TransactionIDs: []string{"T3"}, Splits: []models.PayoutSplit{
+			{Percent: 50, BankAccount: "2a"}, {Percent: 50, BankAccount: "2b"}}},
+		{VendorID: "DUP-3", Amount: 5, Currency: "IDR", BankAccount: "3", TransactionIDs: []string{"T4"}},
+	}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(context.Background(), paid.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	send := func(check string) (int, map[string]json.RawMessage) {
+		var body map[string]json.RawMessage
+		w := sendJSON(t, r, http.MethodPost, "/api/v1/batches", `{"duplicate_check": "`+check+`", "payouts": [
+			{"vendor_id": "DUP-1", "amount": 10, "currency": "IDR", "bank_account": "1", "transaction_ids": ["T2", "T1"]},
+			{"vendor_id": "DUP-2", "amount": 30, "currency": "IDR", "transaction_ids": ["T3"],
+			 "splits": [{"percent": 50, "bank_account": "2a"}, {"percent": 50, "bank_account": "2b"}]},
+			{"vendor_id": "DUP-3", "amount": 5, "currency": "IDR", "bank_account": "3", "transaction_ids": ["T5"]}]}`, &body)
+		return w.Code, body
+	}
+
+	code, body := send(models.DuplicateCheckBlock)
+	var dups []models.DuplicatePayout
+	if code != http.StatusConflict || json.Unmarshal(body["duplicates"], &dups) != nil {
+		t.Fatalf("Expected 409 listing the duplicates, got %d: %s", code, body["error"])
+	}
+	if len(dups) != 2 || dups[0].Index != 0 || dups[1].VendorID != "DUP-2" || dups[0].PriorBatchID != paid.ID {
+		t.Errorf("Expected the first and split items matched to the paid batch, got %+v", dups)
+	}
+	if code, body := send(""); code != http.StatusCreated || body["duplicate_warnings"] == nil {
+		t.Errorf("Expected 201 with duplicate warnings by default, got %d: %s", code, body)
+	}
+	if code, body := send(models.DuplicateCheckOff); code != http.StatusCreated || body["duplicate_warnings"] != nil {
+		t.Errorf("Expected 201 without warnings when off, got %d: %s", code, body)
+	}
+
+	w := sendJSON(t, r, http.MethodPost, "/api/v1/batches/import?duplicate_check=block",
+		"vendor_id,amount,currency,bank_account,transaction_ids\nDUP-1,10,IDR,1,T1|T2\n", nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 importing a paid payout again, got %d: %s", w.Code, w.Body)
+	}
+}
+
+// TestPartialBatchCreation verifies partial mode creates a batch of the
+// valid payouts, lists each rejected one by its index in the request with
+// the check it failed, records the summary on the batch, reports duplicate
+// matches by request index, and answers 422 when nothing is left, while
+// the default mode still refuses the whole request.
+func TestPartialBatchCreation(t *testing.T) {
+	store, pool, r := newMemRouter(t, service.NewScenario(), func(cfg *api.Config) {
+		cfg.DuplicateCheck = models.DuplicateCheckWarn
+	})
+
+	paid, err := store.CreateBatch(context.Background(), []models.CreatePayoutItem{
+		{VendorID: "PART-PAID", Amount: 5, Currency: "IDR", BankAccount: "9"},
+	}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(context.Background(), paid.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	payouts := `"payouts": [
+		{"vendor_id": "PART-1", "amount": 10, "currency": "IDR", "bank_account": "1"},
+		{"vendor_id": "PART-2", "amount": -1, "currency": "IDR", "bank_account": "2"},
+		{"vendor_id": "PART-3", "amount": 10, "currency": "IDR", "splits": [{"percent": 50, "bank_account": "3"}, {"percent": 40, "bank_account": "4"}]},
+		{"vendor_id": "PART-1", "amount": 5, "currency": "IDR", "bank_account": "1"},
+		{"vendor_id": "PART-PAID", "amount": 5, "currency": "IDR", "bank_account": "9"}]`
+	send := func(body string) (int, map[string]json.RawMessage) {
+		var resp map[string]json.RawMessage
+		w := sendJSON(t, r, http.MethodPost, "/api/v1/batches", body, &resp)
+		return w.Code, resp
+	}
+
+	if code, _ := send(`{` + payouts + `}`); code != http.StatusBadRequest {
+		t.Errorf("Expected the default mode to refuse the request, got %d", code)
+	}
+
+	code, resp := send(`{"mode": "partial", ` + payouts + `}`)
+	if code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", code, resp["error"])
+	}
+	var rejected []models.PayoutError
+	var dups []models.DuplicatePayout
+	json.Unmarshal(resp["rejected"], &rejected)
+	json.Unmarshal(resp["duplicate_warnings"], &dups)
+	want := []struct {
+		index int
+		rule  string
+	}{{1, "gt"}, {2, "split_total"}, {3, "duplicate_vendor"}}
+	if len(rejected) != len(want) {
+		t.Fatalf("Expected %d rejected payouts, got %+v", len(want), rejected)
+	}
+	for i, w := range want {
+		if rejected[i].Index != w.index || rejected[i].Rule != w.rule {
+			t.Errorf("Expected payouts[%d] rejected by %s, got %+v", w.index, w.rule, rejected[i])
+		}
+	}
+	if string(resp["total"]) != "2" || len(dups) != 1 || dups[0].Index != 4 {
+		t.Errorf("Expected 2 payouts created and payouts[4] matched as paid, got total %s and %+v", resp["total"], dups)
+	}
+
+	var id uuid.UUID
+	json.Unmarshal(resp["batch_id"], &id)
+	batch, _ := store.GetBatch(context.Background(), id)
+	if s := batch.Rejections; s == nil || s.Submitted != 5 || s.Rejected != 3 || s.Violations["split_total"] != 1 || len(s.Errors) != 3 {
+		t.Errorf("Expected the rejection summary on the batch, got %+v", s)
+	}
+
+	code, resp = send(`{"mode": "partial", "payouts": [{"vendor_id": "PART-9", "amount": 0, "currency": "IDR", "bank_account": "1"}]}`)
+	if code != http.StatusUnprocessableEntity || resp["rejected"] == nil {
+		t.Errorf("Expected 422 listing the rejections when nothing is left, got %d: %s", code, resp["error"])
+	}
+	if code, _ := send(`{"mode": "partial", "payouts": []}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without payouts in partial mode too, got %d", code)
+	}
+}
+
+// TestCancelBatch verifies cancelling a batch cancels its pending payouts
+// for good: the batch is cancelled, counted and linked as finished, cannot
+// be cancelled again, started or processed, and finished batches cannot be
+// cancelled.
+func TestCancelBatch(t *testing.T) {
+	ctx := context.Background()
+	store, pool, r := newMemRouter(t, service.NewScenario())
+
+	items := []models.CreatePayoutItem{
+		{VendorID: "CANCEL-1", Amount: 10, Currency: "IDR", BankAccount: "1"},
+		{VendorID: "CANCEL-2", Amount: 20, Currency: "IDR", BankAccount: "2"},
+	}
+	pending, err := store.CreateBatch(ctx, items, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	done, err := store.CreateBatch(ctx, items, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(ctx, done.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	var batch models.PayoutBatch
+	if w := sendJSON(t, r, http.MethodPost, "/api/v1/batches/"+pending.ID.String()+"/cancel", "", &batch); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if batch.Status != models.BatchStatusCancelled || batch.FailedCount != 2 || batch.PendingCount != 0 || batch.CompletedAt == nil {
+		t.Errorf("Expected a cancelled batch with 2 payouts counted failed, got %+v", batch)
+	}
+	if links := batch.Links; links == nil || links.Start != "" || links.Cancel != "" {
+		t.Errorf("Expected no start or cancel link on a cancelled batch, got %+v", links)
+	}
+	payouts, _, err := store.GetPayoutsByBatch(ctx, pending.ID, "", 1, 10)
+	if err != nil {
+		t.Fatalf("GetPayoutsByBatch failed: %v", err)
+	}
+	for _, p := range payouts {
+		if p.Status != models.PayoutStatusCancelled {
+			t.Errorf("Expected payout %s cancelled, got %s", p.VendorID, p.Status)
+		}
+	}
+
+	for _, path := range []string{
+		"/api/v1/batches/" + pending.ID.String() + "/cancel",
+		"/api/v1/batches/" + pending.ID.String() + "/start",
+		"/api/v1/batches/" + pending.ID.String() + "/retry-failed",
+		"/api/v1/batches/" + done.ID.String() + "/cancel",
+	} {
+		if w := sendJSON(t, r, http.MethodPost, path, "", nil); w.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for %s, got %d", path, w.Code)
+		}
+	}
+	if w := sendJSON(t, r, http.MethodPost, "/api/v1/batches/"+uuid.New().String()+"/cancel", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown batch, got %d", w.Code)
+	}
+	if err := pool.ProcessBatch(ctx, pending.ID); !errors.Is(err, worker.ErrBatchCancelled) {
+		t.Errorf("Expected processing a cancelled batch to fail with ErrBatchCancelled, got %v", err)
+	}
+	if b, _ := store.GetBatch(ctx, pending.ID); b.Status != models.BatchStatusCancelled {
+		t.Errorf("Expected the batch to stay cancelled, got %s", b.Status)
+	}
+}
+
+// TestCancelPayout verifies a pending payout can be taken out of its batch
+// with a reason, and only while pending: the rest of the batch is paid, a
+// payout cancelled or paid already cannot be cancelled, and a batch left
+// with nothing to process is finished.
+func TestCancelPayout(t *testing.T) {
+	ctx := context.Background()
+	store, pool, r := newMemRouter(t, service.NewScenario())
+
+	batch, err := store.CreateBatch(ctx, []models.CreatePayoutItem{
+		{VendorID: "DISPUTED", Amount: 10, Currency: "IDR", BankAccount: "1"},
+		{VendorID: "PAID-1", Amount: 20, Currency: "IDR", BankAccount: "2"},
+		{VendorID: "PAID-2", Amount: 30, Currency: "IDR", BankAccount: "3"},
+	}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	payouts, _, err := store.GetPayoutsByBatch(ctx, batch.ID, "", 1, 10)
+	if err != nil {
+		t.Fatalf("GetPayoutsByBatch failed: %v", err)
+	}
+	ids := map[string]uuid.UUID{}
+	for _, p := range payouts {
+		ids[p.VendorID] = p.ID
+	}
+
+	cancel := func(id uuid.UUID, body string, out any) int {
+		return sendJSON(t, r, http.MethodPost, "/api/v1/payouts/"+id.String()+"/cancel", body, out).Code
+	}
+
+	if code := cancel(ids["DISPUTED"], `{}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a reason, got %d", code)
+	}
+	var payout models.Payout
+	if code := cancel(ids["DISPUTED"], `{"reason": "vendor dispute"}`, &payout); code != http.StatusOK || payout.Status != models.PayoutStatusCancelled {
+		t.Fatalf("Expected the payout cancelled, got %d: %+v", code, payout)
+	}
+	if code := cancel(ids["DISPUTED"], `{"reason": "again"}`, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a cancelled payout, got %d", code)
+	}
+	if code := cancel(uuid.New(), `{"reason": "unknown"}`, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown payout, got %d", code)
+	}
+
+	if err := pool.ProcessBatch(ctx, batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	if b, _ := store.GetBatch(ctx, batch.ID); b.Status != models.BatchStatusPartiallyCompleted || b.CompletedCount != 2 || b.FailedCount != 1 {
+		t.Errorf("Expected the other payouts paid around the cancelled one, got %+v", b)
+	}
+	if code := cancel(ids["PAID-1"], `{"reason": "too late"}`, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a paid payout, got %d", code)
+	}
+
+	single, err := store.CreateBatch(ctx, []models.CreatePayoutItem{
+		{VendorID: "ONLY", Amount: 10, Currency: "IDR", BankAccount: "1"},
+	}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	payouts, _, _ = store.GetPayoutsByBatch(ctx, single.ID, "", 1, 10)
+	if code := cancel(payouts[0].ID, `{"reason": "vendor dispute"}`, nil); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if b, _ := store.GetBatch(ctx, single.ID); b.Status != models.BatchStatusFailed || b.CompletedAt == nil {
+		t.Errorf("Expected a batch with nothing left to process finished, got %+v", b)
+	}
+}
+
+// TestStreamBatch verifies a batch is created from NDJSON lines, blank ones
+// skipped, and that an invalid line or an empty stream is refused naming
+// the problem, with nothing created.
+func TestStreamBatch(t *testing.T) {
+	store, _, r := newMemRouter(t, service.NewScenario())
+
+	stream := func(query, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/batches/stream"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := stream("?assigned_to=ops", `{"vendor_id": "STREAM-1", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"}
+
+{"vendor_id": "STREAM-2", "amount": 20, "currency": "IDR", "bank_account": "2", "bank_name": "BNI"}
+{"vendor_id": "STREAM-3", "amount": 30, "currency": "IDR", "bank_account": "3", "bank_name": "BRI"}
+`)
+	var created struct {
+		BatchID uuid.UUID `json:"batch_id"`
+		Total   int       `json:"total"`
+	}
+	if w.Code != http.StatusCreated || json.Unmarshal(w.Body.Bytes(), &created) != nil {
+		t.Fatalf("Expected 201 streaming a batch, got %d: %s", w.Code, w.Body)
+	}
+	if created.Total != 3 {
+		t.Errorf("Expected 3 payouts, got %d", created.Total)
+	}
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+created.BatchID.String(), &summary)
+	if summary.Statistics.Pending != 3 || summary.Batch.AssignedTo == nil || *summary.Batch.AssignedTo != "ops" {
+		t.Errorf("Expected 3 pending payouts assigned to ops, got %+v (%v)", summary.Statistics, summary.Batch.AssignedTo)
+	}
+
+	before, _, _ := store.ListBatches(context.Background(), models.BatchListFilter{})
+	w = stream("", `{"vendor_id": "STREAM-4", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"}
+{"vendor_id": "STREAM-5", "amount": -1, "currency": "IDR", "bank_account": "2", "bank_name": "BNI"}
+`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Line 2") {
+		t.Errorf("Expected 400 naming line 2, got %d: %s", w.Code, w.Body)
+	}
+	if w := stream("", "\n\n"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty stream, got %d: %s", w.Code, w.Body)
+	}
+	if after, _, _ := store.ListBatches(context.Background(), models.BatchListFilter{}); len(after) != len(before) {
+		t.Errorf("Expected no batch created by refused streams, got %d batches, had %d", len(after), len(before))
+	}
+}
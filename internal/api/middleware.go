@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slowFraction is the share of a route's budget after which a request is
+// reported as slow, so endpoints drifting towards their deadline show up
+// before they start timing out.
+const slowFraction = 0.8
+
+// Per-route ("METHOD /path") request counters, published on /debug/vars.
+var (
+	SlowRequests     = expvar.NewMap("api_slow_requests")      // crossed slowFraction of the budget
+	TimedOutRequests = expvar.NewMap("api_timed_out_requests") // used the whole budget
+)
+
+// Deadline attaches a timeout to the request context so repository calls made
+// by the handler are cancelled once the route's budget is spent. Requests that
+// come close to the budget are logged and counted as slow; requests that
+// exceed it are logged and counted as timed out.
+func Deadline(budget time.Duration) gin.HandlerFunc {
+	warnAfter := time.Duration(float64(budget) * slowFraction)
+
+	return func(c *gin.Context) {
+		if budget <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), budget)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		start := time.Now()
+		c.Next()
+		elapsed := time.Since(start)
+
+		route := c.Request.Method + " " + c.FullPath()
+		switch {
+		case elapsed >= budget:
+			TimedOutRequests.Add(route, 1)
+			log.Printf("[api] Request exceeded budget: %s took %s (budget %s, status %d)",
+				route, elapsed.Round(time.Millisecond), budget, c.Writer.Status())
+		case elapsed >= warnAfter:
+			SlowRequests.Add(route, 1)
+			log.Printf("[api] Slow request: %s took %s (budget %s, status %d)",
+				route, elapsed.Round(time.Millisecond), budget, c.Writer.Status())
+		}
+	}
+}
+
+// ReadOnly rejects every request that could change state with 503, for a
+// standby instance that serves dashboards while a primary owns processing.
+// Reads, health checks and metrics keep working.
+func ReadOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "error.read_only")})
+	}
+}
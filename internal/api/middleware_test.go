@@ -0,0 +1,106 @@
+package api_test
+
+import (
+	"bytes"
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestDeadlineCancelsContext verifies the handler context expires after the budget.
+func TestDeadlineCancelsContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/slow", api.Deadline(20*time.Millisecond), func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.Status(http.StatusGatewayTimeout)
+		case <-time.After(time.Second):
+			c.Status(http.StatusOK)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected context to be cancelled (504), got %d", w.Code)
+	}
+}
+
+// TestDeadlineZeroBudget verifies a zero budget leaves the request untouched.
+func TestDeadlineZeroBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/fast", api.Deadline(0), func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("Expected no deadline on request context")
+		}
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+}
+
+// TestDeadlineReportsSlowRequest verifies a request nearing its budget is
+// logged and counted before it starts timing out.
+func TestDeadlineReportsSlowRequest(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/drifting", api.Deadline(400*time.Millisecond), func(c *gin.Context) {
+		time.Sleep(340 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/drifting", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(logs.String(), "[api] Slow request: GET /drifting") {
+		t.Errorf("Expected slow request log, got %q", logs.String())
+	}
+	if n, ok := api.SlowRequests.Get("GET /drifting").(*expvar.Int); !ok || n.Value() != 1 {
+		t.Errorf("Expected slow request counter of 1, got %v", api.SlowRequests.Get("GET /drifting"))
+	}
+}
+
+// TestDeadlineCountsTimeout verifies a request that uses its whole budget is
+// counted as timed out rather than slow.
+func TestDeadlineCountsTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stuck", api.Deadline(20*time.Millisecond), func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.Status(http.StatusGatewayTimeout)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stuck", nil))
+
+	if n, ok := api.TimedOutRequests.Get("GET /stuck").(*expvar.Int); !ok || n.Value() != 1 {
+		t.Errorf("Expected timed out counter of 1, got %v", api.TimedOutRequests.Get("GET /stuck"))
+	}
+	if api.SlowRequests.Get("GET /stuck") != nil {
+		t.Error("Expected timed out request not to be counted as slow")
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultAwaitingDays is how long a failure must have waited on the vendor
+// to be reported when older_than_days is not given.
+const defaultAwaitingDays = 7
+
+// LogOutreach records a contact with the vendor of a failed payout, e.g. an
+// email asking for new bank details. X-Operator is recorded as recorded_by.
+// POST /api/v1/payouts/:id/outreach
+func (h *Handler) LogOutreach(c *gin.Context) {
+	payoutID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_payout_id")})
+		return
+	}
+	var req models.OutreachRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	if req.ContactedAt != nil && req.ContactedAt.After(h.cfg.Clock.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.contacted_in_future")})
+		return
+	}
+
+	outreach, err := h.repo.LogOutreach(c.Request.Context(), payoutID, req, actor(c))
+	switch {
+	case errors.Is(err, repository.ErrPayoutNotFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.payout_not_failed")})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	case outreach == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+		return
+	}
+	c.JSON(http.StatusCreated, outreach)
+}
+
+// GetAwaitingVendorReport lists failed payouts that only the vendor can
+// resolve and that have waited longer than older_than_days, with the
+// outreach made about each, as the remediation backlog.
+// GET /api/v1/reports/awaiting-vendor?older_than_days=7
+func (h *Handler) GetAwaitingVendorReport(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("older_than_days", strconv.Itoa(defaultAwaitingDays)))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_older_than_days")})
+		return
+	}
+
+	now := h.cfg.Clock.Now()
+	payouts, err := h.repo.ListAwaitingVendor(c.Request.Context(), now.Add(-time.Duration(days)*24*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range payouts {
+		payouts[i].DaysWaiting = int(now.Sub(payouts[i].FailedAt) / (24 * time.Hour))
+	}
+	c.JSON(http.StatusOK, models.AwaitingVendorReport{
+		OlderThanDays: days,
+		Payouts:       payouts,
+		GeneratedAt:   now.UTC(),
+	})
+}
@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestOutreachValidation verifies outreach entries and report queries are
+// checked before anything is looked up.
+func TestOutreachValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	path := "/api/v1/payouts/" + uuid.New().String() + "/outreach"
+	for _, body := range []string{
+		`{"note": "asked for new details"}`,
+		`{"channel": "pigeon"}`,
+		`{"channel": "email", "contacted_at": "2999-01-01T00:00:00Z"}`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+	for _, query := range []string{"?older_than_days=-1", "?older_than_days=week"} {
+		if code := getJSON(t, r, "/api/v1/reports/awaiting-vendor"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, code)
+		}
+	}
+}
+
+// TestAwaitingVendorReport verifies outreach is logged against failed
+// payouts only, and that the report lists failures waiting on the vendor
+// past the threshold with their latest outreach until they are closed out.
+func TestAwaitingVendorReport(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	_, batchID := processedBatch(t, repo)
+	cfg := api.DefaultConfig()
+	cfg.Clock = clock.NewFake(time.Now().Add(10*24*time.Hour + time.Hour))
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), cfg)
+
+	failed, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, models.PayoutStatusFailed, 1, 10)
+	if err != nil || len(failed) != 1 {
+		t.Fatalf("Expected one failed payout, got %d (%v)", len(failed), err)
+	}
+	completed, _, _ := repo.GetPayoutsByBatch(context.Background(), batchID, models.PayoutStatusCompleted, 1, 10)
+
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("X-Operator", "ops@example.com")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	outreach := "/api/v1/payouts/" + failed[0].ID.String() + "/outreach"
+	emailed := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	if code := post(outreach, `{"channel": "email", "note": "asked for new bank details", "contacted_at": "`+emailed+`"}`); code != http.StatusCreated {
+		t.Fatalf("Expected 201 logging an email, got %d", code)
+	}
+	if code := post(outreach, `{"channel": "phone"}`); code != http.StatusCreated {
+		t.Fatalf("Expected 201 logging a call, got %d", code)
+	}
+	if code := post("/api/v1/payouts/"+completed[0].ID.String()+"/outreach", `{"channel": "email"}`); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a completed payout, got %d", code)
+	}
+
+	var detail models.PayoutDetail
+	getJSON(t, r, "/api/v1/payouts/"+failed[0].ID.String(), &detail)
+	if len(detail.Outreach) != 2 || detail.Outreach[0].Channel != models.OutreachEmail || detail.Outreach[0].RecordedBy != "ops@example.com" {
+		t.Errorf("Expected the email then the call in the detail, got %+v", detail.Outreach)
+	}
+
+	var report models.AwaitingVendorReport
+	getJSON(t, r, "/api/v1/reports/awaiting-vendor?older_than_days=7", &report)
+	if len(report.Payouts) != 1 {
+		t.Fatalf("Expected 1 payout awaiting the vendor, got %d", len(report.Payouts))
+	}
+	if p := report.Payouts[0]; p.PayoutID != failed[0].ID || p.DaysWaiting != 10 || p.OutreachCount != 2 || p.LastChannel != models.OutreachPhone {
+		t.Errorf("Expected 10 days waiting after 2 contacts, the last by phone, got %+v", p)
+	}
+	getJSON(t, r, "/api/v1/reports/awaiting-vendor?older_than_days=30", &report)
+	if len(report.Payouts) != 0 {
+		t.Errorf("Expected nothing waiting over 30 days, got %d", len(report.Payouts))
+	}
+
+	if code := post("/api/v1/payouts/"+failed[0].ID.String()+"/write-off", `{"reason_code": "vendor_unreachable", "approved_by": "lead@example.com"}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 writing off, got %d", code)
+	}
+	getJSON(t, r, "/api/v1/reports/awaiting-vendor?older_than_days=7", &report)
+	if len(report.Payouts) != 0 {
+		t.Errorf("Expected a written-off payout to leave the report, got %d", len(report.Payouts))
+	}
+}
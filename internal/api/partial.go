@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// acceptPartial leaves out of a partial-mode batch request the payouts
+// creation would refuse, so the rest can be created: those failing binding
+// (bindErr), the split or purpose code checks, and a vendor's entries after
+// its first, or under merge those that cannot be merged with its first; the
+// rest are merged then. It returns the errors of the payouts left out, by
+// index in the request, and the index in the request of each payout kept.
+// It answers 400 for problems with the request itself, as without partial
+// mode, and 422 listing the rejections when no payout is left.
+func acceptPartial(c *gin.Context, req *models.CreateBatchRequest, bindErr error) ([]models.PayoutError, []int, bool) {
+	rejected := []models.PayoutError{}
+	out := map[int]bool{}
+	if bindErr != nil {
+		errs, err := payoutFieldErrors(c, req, bindErr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+			return nil, nil, false
+		}
+		for _, e := range errs {
+			rejected, out[e.Index] = append(rejected, e), true
+		}
+	}
+	reject := func(i int, field, rule, msg string) {
+		rejected = append(rejected, models.PayoutError{
+			Index: i, VendorID: req.Payouts[i].VendorID, Field: field, Rule: rule, Message: msg,
+		})
+		out[i] = true
+	}
+
+	first := map[string]int{} // vendor -> index of its first payout kept
+	for i, item := range req.Payouts {
+		if out[i] {
+			continue
+		}
+		if rule, msg := checkItem(c, i, item); rule != "" {
+			field := payoutField(i, "splits")
+			if rule == "purpose_code" {
+				field = payoutField(i, "purpose_code")
+			}
+			reject(i, field, rule, msg)
+			continue
+		}
+		j, seen := first[item.VendorID]
+		switch {
+		case !seen:
+			first[item.VendorID] = i
+		case req.DedupeStrategy != models.DedupeMerge:
+			field := payoutField(i, "vendor_id")
+			reject(i, field, "duplicate_vendor", tr(c, "validation.duplicate_vendor", field, j))
+		case !req.Payouts[j].MergeableWith(item):
+			field := payoutField(i, "vendor_id")
+			reject(i, field, "vendor_not_mergeable", tr(c, "validation.unmergeable_vendor", field, j))
+		}
+	}
+	sort.SliceStable(rejected, func(a, b int) bool { return rejected[a].Index < rejected[b].Index })
+
+	kept := make([]models.CreatePayoutItem, 0, len(req.Payouts)-len(out))
+	positions := make([]int, 0, len(first))
+	for i, item := range req.Payouts {
+		if out[i] {
+			continue
+		}
+		kept = append(kept, item)
+		if first[item.VendorID] == i {
+			positions = append(positions, i)
+		}
+	}
+	if len(kept) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": tr(c, "error.no_payouts_accepted"), "rejected": rejected})
+		return nil, nil, false
+	}
+	// Only mergeable entries are left, so merging cannot conflict.
+	req.Payouts, _ = models.MergeDuplicateVendors(kept)
+	return rejected, positions, true
+}
+
+// withRejected adds the payouts a partial-mode request left out to a batch
+// creation response; nil for requests in the default mode.
+func withRejected(resp gin.H, rejected []models.PayoutError) gin.H {
+	if rejected != nil {
+		resp["rejected"] = rejected
+	}
+	return resp
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"coding-challenge/internal/bankfile"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreatePaymentFile pays a batch by file rather than through the bank API:
+// its pending payouts go into a payment file, in the export CSV layout,
+// which is streamed back for upload to the bank. They are held until the
+// bank acknowledges the file, so no run sends them a second time. With
+// ?encrypt=pgp the file is encrypted to the export public key. The file's
+// ID is in the X-Payment-File-ID header.
+// POST /api/v1/batches/:id/payment-files
+func (h *Handler) CreatePaymentFile(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	encrypt, ok := h.exportEncryption(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+	if batch.DeletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.batch_deleted")})
+		return
+	}
+
+	taxIDs, err := h.repo.BatchVendorTaxIDs(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var encryptedTo string
+	if encrypt {
+		encryptedTo = h.cfg.ExportKey.Fingerprint()
+	}
+	file, payouts, err := h.repo.CreatePaymentFile(c.Request.Context(), batchID, encryptedTo, actor(c))
+	switch {
+	case errors.Is(err, repository.ErrInsufficientFunding):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, repository.ErrRunLive):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.run_live")})
+		return
+	case errors.Is(err, repository.ErrNothingToFile):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.nothing_to_file")})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	case file == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	// The checksum is of the file as the bank receives it.
+	sum := sha256.New()
+	c.Header("X-Payment-File-ID", file.ID.String())
+	out, sealed, err := h.exportWriter(c, io.MultiWriter(c.Writer, sum), file.Filename, encrypt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	err = writeExport(c, out, sealed, taxIDs, func(fn func(models.Payout) error) error {
+		for _, p := range payouts {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = h.repo.SetPaymentFileChecksum(c.Request.Context(), file.ID, hex.EncodeToString(sum.Sum(nil)))
+	}
+	if err != nil {
+		// The payouts stay held by the file; it can still be delivered by
+		// other means and acknowledged, or rejected in full.
+		log.Printf("[api] Payment file %s of batch %s failed: %v", file.ID, batchID, err)
+	}
+}
+
+// ListPaymentFiles lists a batch's payment files, newest first.
+// GET /api/v1/batches/:id/payment-files
+func (h *Handler) ListPaymentFiles(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	files, err := h.repo.ListPaymentFiles(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"payment_files": files})
+}
+
+// GetPaymentFile returns a payment file with the bank's answer for each of
+// its payouts.
+// GET /api/v1/payment-files/:id
+func (h *Handler) GetPaymentFile(c *gin.Context) {
+	fileID, ok := paymentFileID(c)
+	if !ok {
+		return
+	}
+	file, err := h.repo.GetPaymentFile(c.Request.Context(), fileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if file == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payment_file_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// DeliverPaymentFile records that a payment file was handed to the bank,
+// with the bank's reference for it (e.g. the uploaded file's name).
+// POST /api/v1/payment-files/:id/delivery
+func (h *Handler) DeliverPaymentFile(c *gin.Context) {
+	fileID, ok := paymentFileID(c)
+	if !ok {
+		return
+	}
+	var req models.PaymentFileDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+
+	file, err := h.repo.MarkPaymentFileDelivered(c.Request.Context(), fileID, req.Reference, actor(c))
+	switch {
+	case errors.Is(err, repository.ErrPaymentFileDelivered):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.file_delivered")})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case file == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payment_file_not_found")})
+	default:
+		c.JSON(http.StatusOK, file)
+	}
+}
+
+// AcknowledgePaymentFile records the bank's answer to a delivered payment
+// file: a result for the whole file, results per payout, or both, the
+// per-payout ones taking precedence. Accepted payouts complete; rejected
+// ones fail with BANK_REJECTED and can be retried through the API. Acks
+// may arrive in parts; the first answer for a payout stands.
+// POST /api/v1/payment-files/:id/acknowledgment
+func (h *Handler) AcknowledgePaymentFile(c *gin.Context) {
+	fileID, ok := paymentFileID(c)
+	if !ok {
+		return
+	}
+	var req models.PaymentFileAckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	if req.Result == "" && len(req.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.empty_acknowledgment")})
+		return
+	}
+
+	file, err := h.repo.AcknowledgePaymentFile(c.Request.Context(), fileID, req, actor(c))
+	switch {
+	case errors.Is(err, repository.ErrPaymentFileNotDelivered):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.file_not_delivered")})
+	case errors.Is(err, repository.ErrRunLive):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.run_live")})
+	case errors.Is(err, repository.ErrPayoutNotInFile):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case file == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payment_file_not_found")})
+	default:
+		c.JSON(http.StatusOK, file)
+	}
+}
+
+// maxBankFileSize bounds an uploaded bank file.
+const maxBankFileSize = 32 << 20
+
+// ReceiveBankFile applies a bank's acknowledgment or return file, a pain.002
+// status report or a NACHA return file, told apart by content. Answers
+// complete or fail the payouts of delivered payment files as an
+// acknowledgment would; the response lists each answer's outcome, with
+// returns of payouts already accepted reported as conflicts. The same file
+// is only applied once.
+// POST /api/v1/bank-files/ack
+func (h *Handler) ReceiveBankFile(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBankFileSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_bank_file", err.Error())})
+		return
+	}
+	if len(data) > maxBankFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tr(c, "error.bank_file_too_large", maxBankFileSize>>20)})
+		return
+	}
+	file, err := bankfile.Parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_bank_file", err.Error())})
+		return
+	}
+	sum := sha256.Sum256(data)
+	file.Checksum = hex.EncodeToString(sum[:])
+
+	file, err = h.repo.ApplyBankFile(c.Request.Context(), file, actor(c))
+	switch {
+	case errors.Is(err, repository.ErrBankFileDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.bank_file_duplicate")})
+	case errors.Is(err, repository.ErrUnknownPaymentFile):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": tr(c, "error.unknown_payment_file")})
+	case errors.Is(err, repository.ErrRunLive):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.run_live")})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, file)
+	}
+}
+
+// GetBankFile returns a received bank file with the outcome of each answer.
+// GET /api/v1/bank-files/:id
+func (h *Handler) GetBankFile(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_bank_file_id")})
+		return
+	}
+	file, err := h.repo.GetBankFile(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if file == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.bank_file_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// paymentFileID parses the :id of a payment file route, answering the
+// request itself when it is not a UUID.
+func paymentFileID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_payment_file_id")})
+		return uuid.Nil, false
+	}
+	return id, true
+}
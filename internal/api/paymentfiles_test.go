@@ -0,0 +1,273 @@
+package api_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestPaymentFileValidation verifies payment file requests are checked
+// before anything is looked up.
+func TestPaymentFileValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	file := "/api/v1/payment-files/" + uuid.New().String()
+	cases := []struct {
+		name, method, path, body string
+	}{
+		{"bad file id", http.MethodGet, "/api/v1/payment-files/nope", ""},
+		{"unknown encryption", http.MethodPost, "/api/v1/batches/" + uuid.New().String() + "/payment-files?encrypt=zip", ""},
+		{"no key", http.MethodPost, "/api/v1/batches/" + uuid.New().String() + "/payment-files?encrypt=pgp", ""},
+		{"no reference", http.MethodPost, file + "/delivery", `{}`},
+		{"empty ack", http.MethodPost, file + "/acknowledgment", `{}`},
+		{"unknown result", http.MethodPost, file + "/acknowledgment", `{"result": "maybe"}`},
+		{"item without result", http.MethodPost, file + "/acknowledgment", `{"items": [{"payout_id": "` + uuid.New().String() + `"}]}`},
+		{"bad bank file id", http.MethodGet, "/api/v1/bank-files/nope", ""},
+		{"unknown bank file", http.MethodPost, "/api/v1/bank-files/ack", "vendor_id,amount\nV-1,10\n"},
+		{"broken pain.002", http.MethodPost, "/api/v1/bank-files/ack", "<Document><CstmrPmtStsRpt>"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tc.name, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestPaymentFileLifecycle verifies a batch paid by file holds its payouts
+// until the bank answers, and that the answers complete or fail them.
+func TestPaymentFileLifecycle(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("FILE-1", "Toko Batik", nil),
+		vendorItem("FILE-2", "Warung Kopi", nil),
+	})
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Operator", "ops@example.com")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/payment-files", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 generating the file, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "FILE-1,Toko Batik") || !strings.Contains(w.Body.String(), "FILE-2,Warung Kopi") {
+		t.Errorf("Expected both payouts in the file, got:\n%s", w.Body.String())
+	}
+	sum := sha256.Sum256(w.Body.Bytes())
+	fileID, err := uuid.Parse(w.Header().Get("X-Payment-File-ID"))
+	if err != nil {
+		t.Fatalf("Expected the file ID in X-Payment-File-ID, got %q", w.Header().Get("X-Payment-File-ID"))
+	}
+	path := "/api/v1/payment-files/" + fileID.String()
+
+	if w := send(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/payment-files", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 filing the batch again, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, path+"/acknowledgment", `{"result": "accepted"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 acknowledging an undelivered file, got %d: %s", w.Code, w.Body.String())
+	}
+
+	payouts, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, "", 1, 10)
+	if err != nil || len(payouts) != 2 {
+		t.Fatalf("Expected two payouts, got %d (%v)", len(payouts), err)
+	}
+	bulk, _ := json.Marshal(models.BulkPayoutRequest{Action: "release", PayoutIDs: []uuid.UUID{payouts[0].ID}})
+	w = send(http.MethodPost, "/api/v1/payouts/bulk", string(bulk))
+	var released models.BulkPayoutResponse
+	if json.Unmarshal(w.Body.Bytes(), &released) != nil || released.Applied != 0 {
+		t.Errorf("Expected releasing a filed payout to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := send(http.MethodPost, path+"/delivery", `{"reference": "sftp://bank/in/payment.csv"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 recording delivery, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, path+"/delivery", `{"reference": "again"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 delivering twice, got %d", w.Code)
+	}
+	stranger := `{"items": [{"payout_id": "` + uuid.New().String() + `", "result": "accepted"}]}`
+	if w := send(http.MethodPost, path+"/acknowledgment", stranger); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a payout not in the file, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ack := `{"result": "accepted", "items": [{"payout_id": "` + payouts[1].ID.String() + `", "result": "rejected", "reason": "AC04"}]}`
+	if w := send(http.MethodPost, path+"/acknowledgment", ack); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 acknowledging the file, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var file models.PaymentFile
+	if code := getJSON(t, r, path, &file); code != http.StatusOK {
+		t.Fatalf("Expected 200 reading the file, got %d", code)
+	}
+	if file.Status != models.PaymentFileAcknowledged || file.AcceptedCount != 1 || file.RejectedCount != 1 {
+		t.Errorf("Expected an acknowledged file with 1 accepted and 1 rejected, got %s with %d/%d",
+			file.Status, file.AcceptedCount, file.RejectedCount)
+	}
+	if file.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected checksum %x, got %s", sum, file.Checksum)
+	}
+
+	for i, want := range []string{models.PayoutStatusCompleted, models.PayoutStatusFailed} {
+		p, err := repo.GetPayout(context.Background(), payouts[i].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Status != want {
+			t.Errorf("Expected payout %s to be %s, got %s", p.VendorID, want, p.Status)
+		}
+		if p.HeldAt != nil {
+			t.Errorf("Expected payout %s to be released from the file", p.VendorID)
+		}
+		if want == models.PayoutStatusFailed && (p.FailureReason == nil || *p.FailureReason != models.FailureBankRejected) {
+			t.Errorf("Expected %s, got %v", models.FailureBankRejected, p.FailureReason)
+		}
+	}
+
+	var list struct {
+		PaymentFiles []models.PaymentFile `json:"payment_files"`
+	}
+	if getJSON(t, r, "/api/v1/batches/"+batchID.String()+"/payment-files", &list); len(list.PaymentFiles) != 1 {
+		t.Errorf("Expected one payment file listed, got %d", len(list.PaymentFiles))
+	}
+}
+
+// TestBankFileAck verifies uploaded pain.002 and NACHA return files answer
+// for the payouts of delivered payment files, and that answers after the
+// first are reported rather than applied.
+func TestBankFileAck(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("ACK-1", "Toko Batik", nil),
+		vendorItem("ACK-2", "Warung Kopi", nil),
+		vendorItem("ACK-3", "Sari Roti", nil),
+	})
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Operator", "ops@example.com")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/v1/batches/"+batchID.String()+"/payment-files", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 generating the file, got %d: %s", w.Code, w.Body.String())
+	}
+	fileID := w.Header().Get("X-Payment-File-ID")
+	var file models.PaymentFile
+	getJSON(t, r, "/api/v1/payment-files/"+fileID, &file)
+	if len(file.Items) != 3 {
+		t.Fatalf("Expected 3 payouts in the file, got %d", len(file.Items))
+	}
+	refs := map[string]string{} // vendor ID -> reference
+	vendors := map[uuid.UUID]string{}
+	for _, p := range mustPayouts(t, repo, batchID) {
+		vendors[p.ID] = p.VendorID
+	}
+	for _, it := range file.Items {
+		refs[vendors[it.PayoutID]] = it.Reference
+	}
+
+	pain := func(group string, txs ...string) string {
+		return `<Document><CstmrPmtStsRpt><GrpHdr><MsgId>STS-` + uuid.NewString() + `</MsgId></GrpHdr>` +
+			`<OrgnlGrpInfAndSts><OrgnlMsgId>` + fileID + `</OrgnlMsgId><GrpSts>` + group + `</GrpSts></OrgnlGrpInfAndSts>` +
+			`<OrgnlPmtInfAndSts>` + strings.Join(txs, "") + `</OrgnlPmtInfAndSts></CstmrPmtStsRpt></Document>`
+	}
+	tx := func(ref, status, reason string) string {
+		return `<TxInfAndSts><OrgnlEndToEndId>` + ref + `</OrgnlEndToEndId><TxSts>` + status + `</TxSts>` +
+			`<StsRsnInf><Rsn><Cd>` + reason + `</Cd></Rsn></StsRsnInf></TxInfAndSts>`
+	}
+
+	early := pain("ACCP")
+	if w := send(http.MethodPost, "/api/v1/bank-files/ack", early); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a file answering an undelivered payment file, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, "/api/v1/payment-files/"+fileID+"/delivery", `{"reference": "H2H-0001"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 recording delivery, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The bank rejects ACK-2 per transaction and accepts the rest as a group.
+	report := pain("PART", tx(refs["ACK-2"], "RJCT", "AC04"), tx("ffffffffffffffffffffffffffffffff", "ACCP", ""))
+	w = send(http.MethodPost, "/api/v1/bank-files/ack", report)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 applying the report, got %d: %s", w.Code, w.Body.String())
+	}
+	var got models.BankFile
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Applied != 1 || got.Unmatched != 1 {
+		t.Errorf("Expected 1 applied and 1 unmatched answer, got %+v", got)
+	}
+	if w := send(http.MethodPost, "/api/v1/bank-files/ack", report); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 receiving the same file twice, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, "/api/v1/bank-files/ack", pain("ACCP")); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 accepting the rest, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A NACHA return of an accepted payout comes too late to apply.
+	nacha := fmt.Sprintf("%-94s\n%-94s\n%-94s\n",
+		"101 0910000191234567890"+"2610151200A094101",
+		"622"+strings.Repeat(" ", 36)+refs["ACK-1"][:15],
+		"799R01")
+	w = send(http.MethodPost, "/api/v1/bank-files/ack", nacha)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 receiving the return file, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Conflicts != 1 || len(got.Answers) != 1 || got.Answers[0].Outcome != models.BankAnswerConflict {
+		t.Errorf("Expected the return to be a conflict, got %+v", got)
+	}
+	var stored models.BankFile
+	if code := getJSON(t, r, "/api/v1/bank-files/"+got.ID.String(), &stored); code != http.StatusOK || stored.Conflicts != 1 {
+		t.Errorf("Expected the stored file with 1 conflict, got %d %+v", code, stored)
+	}
+
+	want := map[string]string{
+		"ACK-1": models.PayoutStatusCompleted,
+		"ACK-2": models.PayoutStatusFailed,
+		"ACK-3": models.PayoutStatusCompleted,
+	}
+	for _, p := range mustPayouts(t, repo, batchID) {
+		if p.Status != want[p.VendorID] {
+			t.Errorf("Expected %s to be %s, got %s", p.VendorID, want[p.VendorID], p.Status)
+		}
+	}
+}
+
+func mustPayouts(t *testing.T, repo *repository.Repository, batchID uuid.UUID) []models.PayoutListItem {
+	t.Helper()
+	payouts, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, "", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payouts
+}
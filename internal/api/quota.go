@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/quota"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quotaExceeded answers 429 for a request refused by a merchant's quota.
+func quotaExceeded(c *gin.Context, err *quota.ExceededError) {
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": tr(c, "error.quota_exceeded", err.Error()), "limit": err.Limit})
+}
+
+// quotaBatch refuses, with 429, a batch of size payouts that would take the
+// request's merchant over its batch size or monthly quota. It returns the
+// payouts the merchant submitted this month so far, for quotaCreated.
+func (h *Handler) quotaBatch(c *gin.Context, size int) (int, bool) {
+	limits, ok := h.cfg.Quotas.For(merchant(c))
+	if !ok {
+		return 0, true
+	}
+	used := 0
+	if limits.PayoutsPerMonth > 0 {
+		var err error
+		used, err = h.repo.CountMerchantPayouts(c.Request.Context(), merchant(c), quota.MonthStart(h.cfg.Clock.Now()))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return 0, false
+		}
+	}
+	var exceeded *quota.ExceededError
+	if errors.As(limits.CheckBatch(size, used), &exceeded) {
+		quotaExceeded(c, exceeded)
+		return 0, false
+	}
+	return used, true
+}
+
+// quotaCreated alerts when a batch of size payouts, created with used
+// payouts submitted this month before it, took its merchant past the
+// warning share of its monthly quota.
+func (h *Handler) quotaCreated(c *gin.Context, used, size int) {
+	id := merchant(c)
+	limits, ok := h.cfg.Quotas.For(id)
+	if ok && h.cfg.Quotas.Crossed(limits, used, used+size) {
+		log.Printf("[api] ALERT: merchant %s has submitted %d of its %d payouts this month",
+			id, used+size, limits.PayoutsPerMonth)
+	}
+}
+
+// quotaStart refuses, with 429, to start a batch whose merchant has as many
+// batches in progress as its quota allows. Resuming a batch already in
+// progress is not refused.
+func (h *Handler) quotaStart(c *gin.Context, batch *models.PayoutBatch) bool {
+	if batch.MerchantID == nil || batch.Status == models.BatchStatusInProgress {
+		return true
+	}
+	limits, ok := h.cfg.Quotas.For(*batch.MerchantID)
+	if !ok || limits.ConcurrentBatches == 0 {
+		return true
+	}
+	running, err := h.repo.CountMerchantRunning(c.Request.Context(), *batch.MerchantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	var exceeded *quota.ExceededError
+	if errors.As(limits.CheckStart(running), &exceeded) {
+		quotaExceeded(c, exceeded)
+		return false
+	}
+	return true
+}
+
+// GetQuota returns the quotas of the request's merchant and how much of
+// them it is using.
+// GET /api/v1/quota
+func (h *Handler) GetQuota(c *gin.Context) {
+	id := merchant(c)
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.merchant_required")})
+		return
+	}
+	status := models.QuotaStatus{MerchantID: id, MonthStart: quota.MonthStart(h.cfg.Clock.Now())}
+	limits, ok := h.cfg.Quotas.For(id)
+	if ok {
+		status.Limits = &models.QuotaLimits{
+			PayoutsPerMonth:   limits.PayoutsPerMonth,
+			BatchSize:         limits.BatchSize,
+			ConcurrentBatches: limits.ConcurrentBatches,
+		}
+	}
+	var err error
+	if status.PayoutsThisMonth, err = h.repo.CountMerchantPayouts(c.Request.Context(), id, status.MonthStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if status.BatchesRunning, err = h.repo.CountMerchantRunning(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status.Approaching = ok && h.cfg.Quotas.Crossed(limits, 0, status.PayoutsThisMonth)
+	c.JSON(http.StatusOK, status)
+}
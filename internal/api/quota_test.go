@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/quota"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+)
+
+// fixedQuotaUse reports the same usage for every merchant.
+type fixedQuotaUse struct {
+	payouts, running int
+}
+
+func (f *fixedQuotaUse) CountMerchantPayouts(context.Context, string, time.Time) (int, error) {
+	return f.payouts, nil
+}
+
+func (f *fixedQuotaUse) CountMerchantRunning(context.Context, string) (int, error) {
+	return f.running, nil
+}
+
+// TestMerchantQuotas verifies batches over a merchant's batch size or
+// monthly payouts, and starts beyond its concurrent batches, are refused
+// with 429 naming the limit, while requests naming no merchant are not
+// limited.
+func TestMerchantQuotas(t *testing.T) {
+	store := memstore.New()
+	use := &fixedQuotaUse{payouts: 98}
+	quotas, err := quota.Parse("acme:payouts_per_month=100;batch_size=3;concurrent_batches=1")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	quotas.WarnAt = 0.8
+	cfg := api.DefaultConfig()
+	cfg.Quotas = quotas
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(service.NewScenario()))
+	r := api.SetupRouter(memAPIStore{Store: store, QuotaStore: use}, pool, cfg)
+
+	items := func(n int) string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = `{"vendor_id": "QUOTA-` + string(rune('A'+i)) + `", "amount": 10, "currency": "IDR", "bank_account": "1", "bank_name": "BCA"}`
+		}
+		return strings.Join(lines, ",")
+	}
+	send := func(method, path, merchant, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if merchant != "" {
+			req.Header.Set("X-Merchant", merchant)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	refused := func(w *httptest.ResponseRecorder, limit string) {
+		t.Helper()
+		var body struct {
+			Limit string `json:"limit"`
+		}
+		if w.Code != http.StatusTooManyRequests || json.Unmarshal(w.Body.Bytes(), &body) != nil || body.Limit != limit {
+			t.Errorf("Expected 429 for %s, got %d: %s", limit, w.Code, w.Body)
+		}
+	}
+
+	refused(send(http.MethodPost, "/api/v1/batches", "acme", `{"payouts": [`+items(4)+`]}`), quota.LimitBatchSize)
+	refused(send(http.MethodPost, "/api/v1/batches", "acme", `{"payouts": [`+items(3)+`]}`), quota.LimitPayoutsPerMonth)
+	if w := send(http.MethodPost, "/api/v1/batches", "", `{"payouts": [`+items(4)+`]}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected a batch naming no merchant created, got %d: %s", w.Code, w.Body)
+	}
+	refused(send(http.MethodPost, "/api/v1/batches/stream", "acme", strings.ReplaceAll(items(3), "},", "}\n")), quota.LimitPayoutsPerMonth)
+
+	use.payouts = 90
+	w := send(http.MethodPost, "/api/v1/batches", "acme", `{"payouts": [`+items(2)+`]}`)
+	var created struct {
+		BatchID string `json:"batch_id"`
+	}
+	if w.Code != http.StatusCreated || json.Unmarshal(w.Body.Bytes(), &created) != nil {
+		t.Fatalf("Expected 201 within the quotas, got %d: %s", w.Code, w.Body)
+	}
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+created.BatchID, &summary)
+	if m := summary.Batch.MerchantID; m == nil || *m != "acme" {
+		t.Errorf("Expected the batch created for acme, got %v", m)
+	}
+
+	use.running = 1
+	refused(send(http.MethodPost, "/api/v1/batches/"+created.BatchID+"/start", "", ""), quota.LimitConcurrentBatches)
+
+	var status models.QuotaStatus
+	if w := send(http.MethodGet, "/api/v1/quota", "acme", ""); w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &status) != nil {
+		t.Fatalf("Expected 200 for the quota, got %d: %s", w.Code, w.Body)
+	}
+	if status.Limits == nil || status.Limits.PayoutsPerMonth != 100 || status.PayoutsThisMonth != 90 || !status.Approaching {
+		t.Errorf("Expected 90 of 100 payouts used and approaching, got %+v", status)
+	}
+	if w := send(http.MethodGet, "/api/v1/quota", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for the quota of no merchant, got %d", w.Code)
+	}
+}
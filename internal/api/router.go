@@ -1,36 +1,280 @@
 package api
 
 import (
-	"coding-challenge/internal/repository"
+	"expvar"
+	"net/http"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/compliance"
+	"coding-challenge/internal/notify/webhook"
+	"coding-challenge/internal/pgp"
+	"coding-challenge/internal/quota"
+	"coding-challenge/internal/scheduler"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/statustoken"
 	"coding-challenge/internal/worker"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Config holds per-route request budgets and report settings.
+type Config struct {
+	ReadTimeout   time.Duration // GET endpoints
+	WriteTimeout  time.Duration // start/stop/retry and other short mutations
+	CreateTimeout time.Duration // batch creation (large payloads)
+	// BankCutoffs drives the settlement cutoff report; nil means none configured.
+	BankCutoffs *service.BankCutoffs
+	// BankFees prices batch estimates; nil means no fees configured.
+	BankFees *service.BankFees
+	// EstimateHistory is how far back batch estimates look for throughput
+	// and failure rates.
+	EstimateHistory time.Duration
+	// StatusTokens signs and verifies vendor-facing payout status tokens.
+	StatusTokens *statustoken.Signer
+	// Clock dates reports and settlement estimates; nil means the system clock.
+	Clock clock.Clock
+	// Admin configures the /admin/v1 API.
+	Admin AdminConfig
+	// Maintenance is the maintenance-mode switch; routers built from the
+	// same Config share it.
+	Maintenance *Maintenance
+	// Signing requires mutating requests to be signed; off without keys.
+	Signing SigningConfig
+	// Webhooks delivers test pings to webhook subscriptions; nil means a
+	// dispatcher over the repository.
+	Webhooks *webhook.Dispatcher
+	// ReadOnly makes this a standby instance: every request that could
+	// change state is rejected (see ReadOnly).
+	ReadOnly bool
+	// ExportKey is the public key batch exports are encrypted to on request
+	// (?encrypt=pgp); nil or empty refuses encrypted exports.
+	ExportKey *pgp.Key
+	// Metrics serves /metrics to Prometheus; nil leaves the route out.
+	Metrics http.Handler
+	// TaxIDThresholds are the per-currency amounts above which a vendor's
+	// payouts over a period must be reported with a tax ID; nil means none.
+	TaxIDThresholds map[string]float64
+	// Reporting are the regulatory reporting thresholds the repository
+	// flags payouts under, shown with the regulatory report.
+	Reporting compliance.Rules
+	// Usage meters API calls per merchant; nil leaves metering off.
+	Usage *UsageMeter
+	// Quotas limits what merchants may submit and run; nil limits nobody.
+	Quotas *quota.Quotas
+	// DuplicateCheck is what batch creation does with payouts matching one
+	// completed within DuplicateWindow: off (or empty), warn or block. A
+	// request may name its own policy.
+	DuplicateCheck  string
+	DuplicateWindow time.Duration
+	// Jobs is this instance's background job scheduler, listed with its run
+	// history under /api/v1/jobs and triggered through the admin API; nil
+	// leaves those routes out.
+	Jobs *scheduler.Scheduler
+	// V1Sunset is announced in the Sunset header of /api/v1 responses; zero
+	// leaves the header out.
+	V1Sunset time.Time
+}
+
+// DefaultConfig returns the request budgets used when none are configured,
+// with a random status token secret.
+func DefaultConfig() Config {
+	return Config{
+		ReadTimeout:     5 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		CreateTimeout:   60 * time.Second,
+		EstimateHistory: 30 * 24 * time.Hour,
+		DuplicateWindow: 7 * 24 * time.Hour,
+		StatusTokens:    statustoken.NewRandom(),
+		Maintenance:     NewMaintenance(),
+	}
+}
+
 // SetupRouter creates and configures the Gin router with all routes.
-func SetupRouter(repo *repository.Repository, pool *worker.Pool) *gin.Engine {
+func SetupRouter(repo Store, pool *worker.Pool, cfg Config) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
+	r.Use(Envelope(), Localize(), TimeZone())
+	if cfg.ReadOnly {
+		r.Use(ReadOnly())
+	}
 
-	h := NewHandler(repo, pool)
+	h := NewHandler(repo, pool, cfg)
 
-	v1 := r.Group("/api/v1")
+	read := Deadline(cfg.ReadTimeout)
+	write := Deadline(cfg.WriteTimeout)
+	create := Deadline(cfg.CreateTimeout)
+
+	guards := append([]gin.HandlerFunc{h.cfg.Maintenance.Guard()}, h.signatureCheck()...)
+	if cfg.Usage != nil {
+		guards = append([]gin.HandlerFunc{Meter(cfg.Usage)}, guards...)
+	}
+
+	v1 := r.Group("/api/v1", append([]gin.HandlerFunc{Deprecated(v2Base, cfg.V1Sunset)}, guards...)...)
 	{
 		batches := v1.Group("/batches")
 		{
-			batches.POST("", h.CreateBatch)                  // Create a new batch
-			batches.GET("/:id", h.GetBatch)                  // Get batch status + stats
-			batches.POST("/:id/start", h.StartBatch)         // Start/resume processing
-			batches.POST("/:id/stop", h.StopBatch)           // Stop processing
-			batches.GET("/:id/payouts", h.GetBatchPayouts)   // List payouts (filterable)
-			batches.POST("/:id/retry-failed", h.RetryFailed) // Retry failed payouts
+			batches.GET("", read, h.ListBatches)                                    // List batches, newest first
+			batches.POST("", create, h.CreateBatch)                                 // Create a new batch
+			batches.POST("/import", create, h.ImportBatch)                          // Create a batch from a CSV file
+			batches.POST("/validate", create, h.ValidateBatch)                      // Check a batch request without creating it
+			batches.POST("/stream", create, h.StreamBatch)                          // Create a batch from NDJSON as it streams in
+			batches.POST("/requeue", create, h.RequeuePayouts)                      // Requeue failed payouts into a new batch
+			batches.GET("/:id", read, h.GetBatch)                                   // Get batch status + stats
+			batches.PATCH("/:id", write, h.UpdateBatch)                             // Change owner / assignee
+			batches.DELETE("/:id", write, h.DeleteBatch)                            // Soft-delete a finished batch
+			batches.POST("/:id/restore", write, h.RestoreBatch)                     // Undo a soft delete
+			batches.POST("/:id/start", write, h.StartBatch)                         // Start/resume processing
+			batches.POST("/:id/stop", write, h.StopBatch)                           // Stop processing
+			batches.POST("/:id/cancel", write, h.CancelBatch)                       // Cancel pending payouts for good
+			batches.GET("/:id/payouts", read, h.GetBatchPayouts)                    // List payouts (filterable)
+			batches.GET("/:id/statistics", read, h.GetBatchStatistics)              // Stats grouped by vendor attribute, or as of a time
+			batches.GET("/:id/financials", read, h.GetBatchFinancials)              // Money totals per currency
+			batches.GET("/:id/settlement-report", read, h.GetBatchSettlementReport) // Vendors paid and not, with actions
+			batches.GET("/:id/runs", read, h.GetBatchRuns)                          // Processing run history
+			batches.GET("/:id/estimate", read, h.GetBatchEstimate)                  // Expected duration, failures and fees
+			batches.GET("/:id/export", create, h.ExportBatch)                       // CSV for finance, locale-formatted
+			batches.POST("/:id/payment-files", create, h.CreatePaymentFile)         // Pay pending payouts by bank file
+			batches.GET("/:id/payment-files", read, h.ListPaymentFiles)             // Payment files, newest first
+			batches.POST("/:id/retry-failed", write, h.RetryFailed)                 // Retry failed payouts
+			batches.POST("/:id/verify", write, h.VerifyBatch)                       // Consistency discrepancy report
+		}
+
+		v1.GET("/overview", read, h.GetOverview)                            // System-wide dashboard summary
+		v1.GET("/quota", read, h.GetQuota)                                  // The calling merchant's quotas and usage
+		v1.GET("/reports/exposure", read, h.GetExposure)                    // Money in flight per currency
+		v1.GET("/reports/write-offs", read, h.GetWriteOffReport)            // Written-off amounts per period
+		v1.GET("/reports/awaiting-vendor", read, h.GetAwaitingVendorReport) // Failures waiting on vendors
+		v1.GET("/reports/tax-summary", read, h.GetTaxSummary)               // Completed payouts per vendor, with tax IDs
+		v1.GET("/reports/regulatory", read, h.GetRegulatoryReport)          // Payouts flagged for regulatory reporting
+		v1.GET("/vendors/search", read, h.SearchVendors)                    // Vendor name lookup
+		v1.GET("/vendors/:vendor_id/tax-id", read, h.GetVendorTaxID)        // The vendor's NPWP / TIN / MST
+		v1.PUT("/vendors/:vendor_id/tax-id", write, h.SetVendorTaxID)       // Record or replace it
+
+		v1.GET("/funding-accounts", read, h.ListFundingAccounts) // Balances, reserved and available
+
+		files := v1.Group("/payment-files")
+		{
+			files.GET("/:id", read, h.GetPaymentFile)                          // File + per-payout bank answers
+			files.POST("/:id/delivery", write, h.DeliverPaymentFile)           // Record hand-off to the bank
+			files.POST("/:id/acknowledgment", write, h.AcknowledgePaymentFile) // Record the bank's answer
+		}
+
+		bankFiles := v1.Group("/bank-files")
+		{
+			bankFiles.POST("/ack", create, h.ReceiveBankFile) // Apply a pain.002 or NACHA return file
+			bankFiles.GET("/:id", read, h.GetBankFile)        // A received file and each answer's outcome
+		}
+
+		profiles := v1.Group("/import-profiles")
+		{
+			profiles.GET("", read, h.ListImportProfiles)            // Partner CSV column mappings
+			profiles.GET("/:name", read, h.GetImportProfile)        // One mapping
+			profiles.PUT("/:name", write, h.SaveImportProfile)      // Create or replace a mapping
+			profiles.DELETE("/:name", write, h.DeleteImportProfile) // Remove a mapping
+		}
+
+		views := v1.Group("/payout-views")
+		{
+			views.GET("", read, h.ListPayoutViews)            // Saved triage filters
+			views.GET("/:name", read, h.GetPayoutView)        // One view
+			views.PUT("/:name", write, h.SavePayoutView)      // Create or replace a view
+			views.DELETE("/:name", write, h.DeletePayoutView) // Remove a view
+		}
+
+		payouts := v1.Group("/payouts")
+		{
+			payouts.GET("", read, h.ListPayouts)                                      // Payouts matching a saved view
+			payouts.GET("/:id", read, h.GetPayout)                                    // Payout detail + attempt history
+			payouts.POST("/bulk", write, h.BulkPayouts)                               // Hold, release, cancel, retry or tag many payouts
+			payouts.POST("/:id/cancel", write, h.CancelPayout)                        // Take a pending payout out of its batch
+			payouts.POST("/:id/write-off", write, h.WriteOffPayout)                   // Close out an unrecoverable failure
+			payouts.POST("/:id/outreach", write, h.LogOutreach)                       // Log contact with the vendor
+			payouts.POST("/:id/reporting-approval", write, h.ApproveReportablePayout) // Release a flagged payout
 		}
+
+		webhooks := v1.Group("/webhooks")
+		{
+			webhooks.GET("", read, h.ListWebhooks)                            // Event subscriptions
+			webhooks.POST("", write, h.CreateWebhook)                         // Subscribe an endpoint
+			webhooks.GET("/:id", read, h.GetWebhook)                          // One subscription + delivery stats
+			webhooks.PATCH("/:id", write, h.UpdateWebhook)                    // Change URL, events or active flag
+			webhooks.DELETE("/:id", write, h.DeleteWebhook)                   // Unsubscribe
+			webhooks.POST("/:id/ping", write, h.PingWebhook)                  // Send a test event now
+			webhooks.POST("/:id/rotate-secret", write, h.RotateWebhookSecret) // New signing secret
+			webhooks.GET("/:id/deliveries", read, h.GetWebhookDeliveries)     // Latest delivery attempts
+		}
+
+		v1.GET("/payout-status/:token", read, h.GetPayoutStatus) // Vendor self-service, no auth
+
+		if cfg.Jobs != nil {
+			v1.GET("/jobs", read, h.ListJobs)               // Background jobs, schedules and last runs
+			v1.GET("/jobs/:name/runs", read, h.ListJobRuns) // A job's run history
+		}
+
+	}
+
+	// v2 wraps responses in an envelope and pages lists by cursor (see Envelope).
+	v2 := r.Group(v2Base, guards...)
+	{
+		v2.GET("/batches", read, h.ListBatchesV2)                 // List batches, newest first
+		v2.POST("/batches", create, h.CreateBatch)                // Create a new batch
+		v2.GET("/batches/:id", read, h.GetBatch)                  // Get batch status + stats
+		v2.POST("/batches/:id/start", write, h.StartBatch)        // Start/resume processing
+		v2.POST("/batches/:id/stop", write, h.StopBatch)          // Stop processing
+		v2.POST("/batches/:id/cancel", write, h.CancelBatch)      // Cancel pending payouts for good
+		v2.GET("/batches/:id/payouts", read, h.GetBatchPayoutsV2) // List payouts (filterable)
+		v2.GET("/batches/:id/export", create, h.ExportBatch)      // CSV for finance, not enveloped
+		v2.GET("/payouts", read, h.ListPayoutsV2)                 // Payouts matching a saved view
+		v2.GET("/payouts/:id", read, h.GetPayout)                 // Payout detail + attempt history
+	}
+
+	if cfg.Admin.Token != "" && !cfg.Admin.Separate {
+		registerAdmin(r, h, cfg)
 	}
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+		c.JSON(200, gin.H{"status": "ok", "read_only": cfg.ReadOnly})
 	})
 
+	// Runtime counters (slow/timed-out requests per route, memstats)
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+
+	// Payout throughput and latency for Prometheus
+	if cfg.Metrics != nil {
+		r.GET("/metrics", gin.WrapH(cfg.Metrics))
+	}
+
+	return r
+}
+
+// signatureCheck returns the request signing middleware, or nothing when
+// signing is off.
+func (h *Handler) signatureCheck() []gin.HandlerFunc {
+	if len(h.cfg.Signing.Keys) == 0 {
+		return nil
+	}
+	nonces := h.cfg.Signing.Nonces
+	if nonces == nil {
+		nonces = h.repo
+	}
+	return []gin.HandlerFunc{RequireSignature(h.cfg.Signing, h.cfg.Clock, nonces)}
+}
+
+// SetupAdminRouter creates a router serving only the admin API, for binding
+// it to its own listener (AdminConfig.Separate). It shares cfg.Maintenance
+// with the public router.
+func SetupAdminRouter(repo Store, pool *worker.Pool, cfg Config) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.Default()
+	r.Use(Localize())
+	if cfg.ReadOnly {
+		r.Use(ReadOnly())
+	}
+	registerAdmin(r, NewHandler(repo, pool, cfg), cfg)
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok", "read_only": cfg.ReadOnly})
+	})
 	return r
 }
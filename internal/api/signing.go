@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coding-challenge/internal/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers of a signed request.
+const (
+	HeaderSignatureKey = "X-Signature-Key"       // ID of the signing key
+	HeaderTimestamp    = "X-Signature-Timestamp" // Unix seconds when the request was signed
+	HeaderNonce        = "X-Signature-Nonce"     // unique per request, at most 128 characters
+	HeaderSignature    = "X-Signature"           // hex HMAC-SHA256 of the canonical request
+)
+
+// maxNonceLen matches request_nonces.nonce.
+const maxNonceLen = 128
+
+// SigningConfig controls request signing for server-to-server callers. With
+// keys configured, every mutating request must be signed, and a signed
+// request is accepted only once and only within MaxSkew of its timestamp, so
+// a captured start or retry request cannot be replayed.
+type SigningConfig struct {
+	// Keys maps key IDs to their shared secrets. Empty disables signing.
+	Keys map[string]string
+	// MaxSkew is how far a request's timestamp may be from the server's
+	// clock; 0 means 5 minutes.
+	MaxSkew time.Duration
+	// Nonces remembers the nonces of accepted requests; nil means the
+	// repository's request_nonces table.
+	Nonces NonceStore
+}
+
+// NonceStore remembers request nonces until they expire.
+type NonceStore interface {
+	// ClaimNonce records nonce for keyID until expiresAt, returning false if
+	// it is already recorded.
+	ClaimNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error)
+}
+
+func (s SigningConfig) maxSkew() time.Duration {
+	if s.MaxSkew > 0 {
+		return s.MaxSkew
+	}
+	return 5 * time.Minute
+}
+
+// CanonicalRequest returns the string a request signature covers: the method,
+// path with query, timestamp, nonce and the hex SHA-256 of the body, one per
+// line.
+func CanonicalRequest(method, pathAndQuery, timestamp, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return method + "\n" + pathAndQuery + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(sum[:])
+}
+
+// Sign returns the hex HMAC-SHA256 of a canonical request under secret.
+func Sign(secret, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RequireSignature rejects mutating requests that are unsigned, badly signed,
+// outside the allowed clock skew or replayed. Reads pass through unchecked.
+func RequireSignature(cfg SigningConfig, clk clock.Clock, nonces NonceStore) gin.HandlerFunc {
+	clk = clock.OrReal(clk)
+	skew := cfg.maxSkew()
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		keyID := c.GetHeader(HeaderSignatureKey)
+		timestamp := c.GetHeader(HeaderTimestamp)
+		nonce := c.GetHeader(HeaderNonce)
+		signature := c.GetHeader(HeaderSignature)
+		if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "error.signature_required")})
+			return
+		}
+		secret, ok := cfg.Keys[keyID]
+		if !ok || len(nonce) > maxNonceLen {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "error.signature_invalid")})
+			return
+		}
+
+		secs, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "error.signature_invalid")})
+			return
+		}
+		signedAt := time.Unix(secs, 0)
+		if d := clk.Now().Sub(signedAt); d > skew || d < -skew {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "error.signature_expired", skew)})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		want := Sign(secret, CanonicalRequest(c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body))
+		if !hmac.Equal([]byte(signature), []byte(want)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "error.signature_invalid")})
+			return
+		}
+
+		// Checked last, so only correctly signed requests use up a nonce.
+		fresh, err := nonces.ClaimNonce(c.Request.Context(), keyID, nonce, signedAt.Add(skew))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !fresh {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": tr(c, "error.request_replayed")})
+			return
+		}
+		c.Set(signedByKey, keyID)
+		c.Next()
+	}
+}
@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memNonces is an in-memory api.NonceStore.
+type memNonces struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (m *memNonces) ClaimNonce(_ context.Context, keyID, nonce string, _ time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen[keyID+":"+nonce] {
+		return false, nil
+	}
+	m.seen[keyID+":"+nonce] = true
+	return true, nil
+}
+
+// signedRequest builds a request signed with key "partner" at the given time.
+func signedRequest(method, path, body, secret, nonce string, at time.Time) *http.Request {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(api.HeaderSignatureKey, "partner")
+	req.Header.Set(api.HeaderTimestamp, ts)
+	req.Header.Set(api.HeaderNonce, nonce)
+	req.Header.Set(api.HeaderSignature, api.Sign(secret, api.CanonicalRequest(method, path, ts, nonce, []byte(body))))
+	return req
+}
+
+// TestRequireSignature verifies mutating requests must carry a valid, fresh
+// signature and are accepted only once, while reads pass unsigned.
+func TestRequireSignature(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	cfg := api.SigningConfig{Keys: map[string]string{"partner": "s3cret"}, MaxSkew: time.Minute}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(api.Localize(), api.RequireSignature(cfg, clk, &memNonces{seen: map[string]bool{}}))
+	var gotBody string
+	r.POST("/batches/:id/start", func(c *gin.Context) {
+		b, _ := c.GetRawData()
+		gotBody = string(b)
+		c.Status(http.StatusAccepted)
+	})
+	r.GET("/batches/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	path := "/batches/b1/start?force=true"
+	body := `{"order":"fifo"}`
+	cases := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"unsigned", httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)), http.StatusUnauthorized},
+		{"read", httptest.NewRequest(http.MethodGet, "/batches/b1", nil), http.StatusOK},
+		{"wrong secret", signedRequest(http.MethodPost, path, body, "guess", "n1", now), http.StatusUnauthorized},
+		{"stale", signedRequest(http.MethodPost, path, body, "s3cret", "n2", now.Add(-2*time.Minute)), http.StatusUnauthorized},
+		{"valid", signedRequest(http.MethodPost, path, body, "s3cret", "n3", now), http.StatusAccepted},
+		{"replayed", signedRequest(http.MethodPost, path, body, "s3cret", "n3", now), http.StatusConflict},
+		{"new nonce", signedRequest(http.MethodPost, path, body, "s3cret", "n4", now.Add(30*time.Second)), http.StatusAccepted},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, tc.req)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.status, w.Code, w.Body.String())
+		}
+	}
+	if gotBody != body {
+		t.Errorf("Expected the handler to read the signed body %q, got %q", body, gotBody)
+	}
+
+	tampered := signedRequest(http.MethodPost, path, body, "s3cret", "n5", now)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"order":"lifo"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, tampered)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a tampered body, got %d", w.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// localLayouts are the forms of a timestamp without an offset, read in the
+// request's time zone.
+var localLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// parseInstant reads an RFC 3339 timestamp, or a local time in loc.
+func parseInstant(v string, loc *time.Location) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// getStatisticsAt answers GetBatchStatistics with the batch's latest
+// statistics snapshot taken at or before at.
+func (h *Handler) getStatisticsAt(c *gin.Context, batchID uuid.UUID, at string) {
+	if c.Query("group_by") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.at_with_group_by")})
+		return
+	}
+	instant, ok := parseInstant(at, zone(c))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_timestamp", "at")})
+		return
+	}
+
+	batch, err := h.repo.GetBatch(c.Request.Context(), batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if batch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.batch_not_found")})
+		return
+	}
+
+	snap, err := h.repo.GetStatisticsSnapshot(c.Request.Context(), batchID, instant)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if snap == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.no_snapshot", instant.Format(time.RFC3339))})
+		return
+	}
+	c.JSON(http.StatusOK, snap)
+}
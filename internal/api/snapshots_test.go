@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestStatisticsAtValidation verifies at must be a timestamp and cannot be
+// combined with group_by.
+func TestStatisticsAtValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+	path := "/api/v1/batches/" + uuid.New().String() + "/statistics"
+
+	for _, query := range []string{"?at=yesterday", "?at=2026-03-01", "?at=2026-03-01T14:32:00Z&group_by=country"} {
+		if code := getJSON(t, r, path+query, nil); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, code)
+		}
+	}
+}
+
+// TestStatisticsSnapshots verifies a run's snapshots are returned as of the
+// time asked for, including a local time in the request's time zone.
+func TestStatisticsSnapshots(t *testing.T) {
+	db := getTestDB(t)
+
+	start := time.Date(2026, 3, 1, 7, 30, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	repo := repository.New(db, repository.WithClock(clk))
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("snap_1", "Snapshot Vendor 1", nil),
+		vendorItem("snap_2", "Snapshot Vendor 2", nil),
+	})
+
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()),
+		worker.WithStatisticsSnapshots(repo, time.Minute))
+	clk.Advance(time.Minute)
+	if err := pool.ProcessBatch(context.Background(), batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	r := api.SetupRouter(repo, pool, api.DefaultConfig())
+	path := "/api/v1/batches/" + batchID.String() + "/statistics"
+
+	var snap models.StatisticsSnapshot
+	if code := getJSON(t, r, path+"?at=2026-03-01T07:31:00Z", &snap); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if snap.Reason != models.SnapshotRunEnded || snap.BatchStatus != models.BatchStatusCompleted || snap.Statistics.Completed != 2 {
+		t.Errorf("Expected the completed batch at the end of its run, got %+v", snap)
+	}
+	if code := getJSON(t, r, path+"?at=2026-03-01T14:31&tz=Asia/Jakarta", &snap); code != http.StatusOK || snap.Statistics.Completed != 2 {
+		t.Errorf("Expected the same snapshot at 14:31 in Jakarta, got %d: %+v", code, snap)
+	}
+	if code := getJSON(t, r, path+"?at=2026-03-01T07:30:59Z", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 before the first snapshot, got %d", code)
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/notify/webhook"
+
+	"github.com/google/uuid"
+)
+
+// Store is everything the handlers read and write; the repository
+// implements it. It is split by domain so tests can supply the parts they
+// exercise, e.g. memstore.Store for BatchStore.
+type Store interface {
+	BatchStore
+	BatchAdminStore
+	PayoutStore
+	RecoveryStore
+	FundingStore
+	PaymentFileStore
+	VendorStore
+	ComplianceStore
+	ReportStore
+	UsageStore
+	QuotaStore
+	WebhookStore
+	SettingsStore
+	NonceStore
+}
+
+// BatchStore creates batches and reads back their payouts, attempts and
+// runs.
+type BatchStore interface {
+	CreateBatch(ctx context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error)
+	BeginBatch(ctx context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error)
+	IngestBatch(ctx context.Context, batchID uuid.UUID, items []models.CreatePayoutItem) error
+	StreamBatch(ctx context.Context, opts models.BatchOptions, next func() (models.CreatePayoutItem, error)) (*models.PayoutBatch, error)
+	GetBatch(ctx context.Context, batchID uuid.UUID) (*models.PayoutBatch, error)
+	GetBatchByIdempotencyKey(ctx context.Context, merchant, key string) (*models.PayoutBatch, error)
+	ListCompletedPayouts(ctx context.Context, merchant string, vendorIDs []string, since time.Time) ([]models.Payout, error)
+	ListBatches(ctx context.Context, f models.BatchListFilter) ([]models.PayoutBatch, int, error)
+	ListBatchesAfter(ctx context.Context, f models.BatchListFilter, after *models.PageCursor, limit int) ([]models.PayoutBatch, error)
+	GetBatchStatistics(ctx context.Context, batchID uuid.UUID) (*models.BatchStatistics, error)
+	GetPayoutsByBatch(ctx context.Context, batchID uuid.UUID, status string, page, pageSize int) ([]models.PayoutListItem, int, error)
+	EachPayout(ctx context.Context, batchID uuid.UUID, fn func(models.Payout) error) error
+	GetPayout(ctx context.Context, payoutID uuid.UUID) (*models.Payout, error)
+	GetPayoutAttempts(ctx context.Context, payoutID uuid.UUID) ([]models.PayoutAttempt, error)
+	RetryFailedPayouts(ctx context.Context, batchID uuid.UUID) (int64, error)
+	CancelBatch(ctx context.Context, batchID uuid.UUID, operator string) (*models.PayoutBatch, error)
+	CancelPayout(ctx context.Context, payoutID uuid.UUID, operator, reason string) (*models.Payout, error)
+	ListRuns(ctx context.Context, batchID uuid.UUID) ([]models.BatchRun, error)
+}
+
+// BatchAdminStore assigns, deletes, restores, requeues and verifies batches.
+type BatchAdminStore interface {
+	AssignBatch(ctx context.Context, batchID uuid.UUID, req models.UpdateBatchRequest, operator string) (*models.PayoutBatch, error)
+	DeleteBatch(ctx context.Context, batchID uuid.UUID, deletedBy string) (*models.PayoutBatch, error)
+	RestoreBatch(ctx context.Context, batchID uuid.UUID, restoredBy string) (*models.PayoutBatch, error)
+	RequeuePayouts(ctx context.Context, items []models.RequeueItem, opts models.BatchOptions, operator string) (*models.PayoutBatch, error)
+	VerifyBatch(ctx context.Context, batchID uuid.UUID) (*models.BatchVerification, error)
+}
+
+// PayoutStore searches payouts across batches and changes them in bulk or
+// one by one.
+type PayoutStore interface {
+	ListPayouts(ctx context.Context, f models.PayoutFilter, page, pageSize int) ([]models.PayoutListItem, int, error)
+	ListPayoutsAfter(ctx context.Context, f models.PayoutFilter, after *models.PageCursor, limit int) ([]models.PayoutListItem, error)
+	SearchVendors(ctx context.Context, query string, limit int) ([]models.VendorMatch, error)
+	GetSplitPayouts(ctx context.Context, groupID uuid.UUID) ([]models.Payout, error)
+	GetCorrectionChain(ctx context.Context, payoutID uuid.UUID) ([]models.Payout, error)
+	BulkUpdatePayouts(ctx context.Context, req models.BulkPayoutRequest, operator string) (*models.BulkPayoutResponse, error)
+	ForceCompletePayout(ctx context.Context, payoutID uuid.UUID, operator, reason string) (*models.Payout, error)
+	RecoverOrphanedPayouts(ctx context.Context, apply bool) (*models.OrphanRecovery, error)
+}
+
+// RecoveryStore records write-offs and vendor outreach for failed payouts.
+type RecoveryStore interface {
+	WriteOffPayout(ctx context.Context, payoutID uuid.UUID, req models.WriteOffRequest, requestedBy string) (*models.PayoutWriteOff, error)
+	GetWriteOff(ctx context.Context, payoutID uuid.UUID) (*models.PayoutWriteOff, error)
+	GetWriteOffTotals(ctx context.Context, interval string, from, to *time.Time) ([]models.WriteOffPeriod, error)
+	LogOutreach(ctx context.Context, payoutID uuid.UUID, req models.OutreachRequest, recordedBy string) (*models.VendorOutreach, error)
+	ListOutreach(ctx context.Context, payoutID uuid.UUID) ([]models.VendorOutreach, error)
+	ListAwaitingVendor(ctx context.Context, failedBefore time.Time) ([]models.AwaitingVendorPayout, error)
+}
+
+// UsageStore records and reports API usage per merchant.
+type UsageStore interface {
+	UsageRecorder
+	GetUsageReport(ctx context.Context, from, to *time.Time, merchant string) ([]models.MerchantUsage, error)
+}
+
+// QuotaStore counts what merchants' quotas are measured against.
+type QuotaStore interface {
+	CountMerchantPayouts(ctx context.Context, merchant string, since time.Time) (int, error)
+	CountMerchantRunning(ctx context.Context, merchant string) (int, error)
+}
+
+// FundingStore manages funding accounts and what batches hold against them.
+type FundingStore interface {
+	ListFundingAccounts(ctx context.Context) ([]models.FundingAccount, error)
+	SetFundingBalance(ctx context.Context, currency string, balance float64) (*models.FundingAccount, error)
+	GetBatchFinancials(ctx context.Context, batchID uuid.UUID) ([]models.CurrencyTotals, error)
+	GetBatchReservations(ctx context.Context, batchID uuid.UUID) ([]models.FundingReservation, error)
+	ListUnfinishedPayouts(ctx context.Context, batchID uuid.UUID) ([]models.SettlementPayout, error)
+	SettleBatch(ctx context.Context, batchID uuid.UUID, operator string) ([]models.FundingReservation, error)
+	GetInFlightExposure(ctx context.Context) ([]models.CurrencyExposure, error)
+}
+
+// PaymentFileStore tracks the payment files handed to banks, from
+// generation through delivery to the bank's acknowledgment, and the
+// acknowledgment and return files banks send back.
+type PaymentFileStore interface {
+	CreatePaymentFile(ctx context.Context, batchID uuid.UUID, encryptedTo, operator string) (*models.PaymentFile, []models.Payout, error)
+	SetPaymentFileChecksum(ctx context.Context, fileID uuid.UUID, checksum string) error
+	MarkPaymentFileDelivered(ctx context.Context, fileID uuid.UUID, reference, operator string) (*models.PaymentFile, error)
+	AcknowledgePaymentFile(ctx context.Context, fileID uuid.UUID, req models.PaymentFileAckRequest, operator string) (*models.PaymentFile, error)
+	GetPaymentFile(ctx context.Context, fileID uuid.UUID) (*models.PaymentFile, error)
+	ListPaymentFiles(ctx context.Context, batchID uuid.UUID) ([]models.PaymentFile, error)
+	ApplyBankFile(ctx context.Context, f *models.BankFile, operator string) (*models.BankFile, error)
+	GetBankFile(ctx context.Context, id uuid.UUID) (*models.BankFile, error)
+}
+
+// VendorStore keeps vendors' tax IDs and totals their payouts for tax
+// reporting.
+type VendorStore interface {
+	SetVendorTaxID(ctx context.Context, vendorID, country, taxID, operator string) (*models.VendorTaxID, error)
+	GetVendorTaxID(ctx context.Context, vendorID string) (*models.VendorTaxID, error)
+	BatchVendorTaxIDs(ctx context.Context, batchID uuid.UUID) (map[string]string, error)
+	GetTaxSummary(ctx context.Context, from, to *time.Time) ([]models.TaxSummaryVendor, error)
+}
+
+// ComplianceStore lists the payouts flagged for regulatory reporting and
+// releases those held for an approval.
+type ComplianceStore interface {
+	GetReportablePayouts(ctx context.Context, country string, from, to *time.Time) ([]models.ReportablePayout, error)
+	ApproveReportablePayout(ctx context.Context, payoutID uuid.UUID, approvedBy string) (*models.Payout, error)
+}
+
+// ReportStore aggregates over batches and payouts for the overview and
+// reports.
+type ReportStore interface {
+	GetOverview(ctx context.Context) (*models.SystemOverview, error)
+	GetBatchListAggregates(ctx context.Context, f models.BatchListFilter) (*models.BatchListAggregates, error)
+	GetSegmentStatistics(ctx context.Context, batchID uuid.UUID, groupBy string) ([]models.SegmentStatistics, error)
+	GetStatisticsSnapshot(ctx context.Context, batchID uuid.UUID, at time.Time) (*models.StatisticsSnapshot, error)
+	GetBankVolumes(ctx context.Context, batchID uuid.UUID) ([]models.BankVolume, error)
+	GetThroughputModel(ctx context.Context, since time.Time) ([]models.BankHistory, error)
+}
+
+// WebhookStore manages webhook subscriptions and their delivery history.
+type WebhookStore interface {
+	webhook.Store
+	CreateWebhook(ctx context.Context, sub models.WebhookSubscription) (*models.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error)
+	GetWebhookStats(ctx context.Context, id uuid.UUID) (*models.WebhookStats, error)
+	UpdateWebhook(ctx context.Context, id uuid.UUID, req models.UpdateWebhookRequest) (*models.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) (bool, error)
+	RotateWebhookSecret(ctx context.Context, id uuid.UUID, secret string, grace time.Duration) (*models.WebhookSubscription, error)
+	ListWebhookDeliveries(ctx context.Context, id uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+}
+
+// SettingsStore keeps named import profiles and saved payout views.
+type SettingsStore interface {
+	ListImportProfiles(ctx context.Context) ([]models.ImportProfile, error)
+	GetImportProfile(ctx context.Context, name string) (*models.ImportProfile, error)
+	SaveImportProfile(ctx context.Context, p models.ImportProfile) (*models.ImportProfile, error)
+	DeleteImportProfile(ctx context.Context, name string) (bool, error)
+	ListPayoutViews(ctx context.Context) ([]models.PayoutView, error)
+	GetPayoutView(ctx context.Context, name string) (*models.PayoutView, error)
+	SavePayoutView(ctx context.Context, v models.PayoutView) (*models.PayoutView, error)
+	DeletePayoutView(ctx context.Context, name string) (bool, error)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// zoneKey is the gin context key holding the request's time zone.
+const zoneKey = "time_zone"
+
+// TimeZone resolves the time zone that exports and receipts render local
+// times in: an IANA name (e.g. Asia/Jakarta) from ?tz= or, failing that, the
+// Accept-Timezone header, and UTC otherwise. An unknown zone is refused with
+// 400 rather than silently shown in UTC. Other timestamps stay in UTC.
+func TimeZone() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Query("tz")
+		if name == "" {
+			name = c.GetHeader("Accept-Timezone")
+		}
+		if name == "" {
+			c.Next()
+			return
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_timezone", name)})
+			return
+		}
+		c.Set(zoneKey, loc)
+		c.Next()
+	}
+}
+
+// zone returns the time zone resolved for the request.
+func zone(c *gin.Context) *time.Location {
+	if loc, ok := c.Get(zoneKey); ok {
+		return loc.(*time.Location)
+	}
+	return time.UTC
+}
+
+// localTimes renders a payout's timestamps in the request's time zone.
+func localTimes(c *gin.Context, p models.Payout) models.LocalTimes {
+	loc := zone(c)
+	local := models.LocalTimes{TimeZone: loc.String(), CreatedAt: p.CreatedAt.In(loc)}
+	if p.AttemptedAt != nil {
+		t := p.AttemptedAt.In(loc)
+		local.AttemptedAt = &t
+	}
+	if p.CompletedAt != nil {
+		t := p.CompletedAt.In(loc)
+		local.CompletedAt = &t
+	}
+	return local
+}
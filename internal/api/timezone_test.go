@@ -0,0 +1,99 @@
+package api_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestTimeZoneValidation verifies unknown time zones are refused before
+// anything is looked up, from the query or the header.
+func TestTimeZoneValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	path := "/api/v1/payouts/" + uuid.New().String()
+	if code := getJSON(t, r, path+"?tz=Mars/Olympus", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for ?tz=Mars/Olympus, got %d", code)
+	}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept-Timezone", "GMT+7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "GMT+7") {
+		t.Errorf("Expected 400 naming the zone, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestLocalTimes verifies payout detail, the vendor status lookup and the
+// export render times in the requested zone next to the UTC ones.
+func TestLocalTimes(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, batchID := processedBatch(t, repo)
+	completed, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, models.PayoutStatusCompleted, 1, 10)
+	if err != nil || len(completed) == 0 {
+		t.Fatalf("Expected a completed payout, got %d (%v)", len(completed), err)
+	}
+	offset := func(tm *time.Time) int {
+		if tm == nil {
+			return -1
+		}
+		_, off := tm.Zone()
+		return off
+	}
+
+	var detail models.PayoutDetail
+	getJSON(t, r, "/api/v1/payouts/"+completed[0].ID.String()+"?tz=Asia/Jakarta", &detail)
+	local := detail.Local
+	if local.TimeZone != "Asia/Jakarta" || offset(local.CompletedAt) != 7*3600 || !local.CompletedAt.Equal(*detail.Payout.CompletedAt) {
+		t.Errorf("Expected the completion in Jakarta time, got %+v", local)
+	}
+	if offset(detail.Payout.CompletedAt) != 0 {
+		t.Errorf("Expected the payout's own timestamps to stay UTC, got %v", detail.Payout.CompletedAt)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/payout-status/"+detail.StatusToken, nil)
+	req.Header.Set("Accept-Timezone", "Asia/Manila")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var status models.PublicPayoutStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Expected a status JSON body, got %d: %s", w.Code, w.Body.String())
+	}
+	if status.Local.TimeZone != "Asia/Manila" || offset(status.Local.CompletedAt) != 8*3600 || status.Local.AttemptedAt != nil {
+		t.Errorf("Expected the receipt in Manila time without attempt times, got %+v", status.Local)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/batches/"+batchID.String()+"/export?tz=Asia/Jakarta", nil))
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil || len(rows) != 4 {
+		t.Fatalf("Expected a header and 3 rows, got %d (%v)", len(rows), err)
+	}
+	if rows[0][11] != "completed_at" || rows[0][12] != "completed_at_local" {
+		t.Fatalf("Expected UTC and local completion columns, got %v", rows[0])
+	}
+	for _, row := range rows[1:] {
+		if row[11] == "" {
+			continue
+		}
+		utc, _ := time.Parse(time.RFC3339, row[11])
+		jkt, _ := time.Parse(time.RFC3339, row[12])
+		if !strings.HasSuffix(row[11], "Z") || !strings.HasSuffix(row[12], "+07:00") || !utc.Equal(jkt) {
+			t.Errorf("Expected the same instant in UTC and Jakarta time, got %s and %s", row[11], row[12])
+		}
+	}
+}
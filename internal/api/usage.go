@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signedByKey holds the ID of the key a request was signed with.
+const signedByKey = "signed_by"
+
+// merchant identifies who a request is metered to: the key it was signed
+// with or, for unsigned requests, its X-Merchant header. It returns "" for
+// requests naming neither.
+func merchant(c *gin.Context) string {
+	if key := c.GetString(signedByKey); key != "" {
+		return key
+	}
+	return c.GetHeader("X-Merchant")
+}
+
+// UsageRecorder persists metered API calls; the repository implements it.
+type UsageRecorder interface {
+	RecordAPIUsage(ctx context.Context, usage []models.APIUsage) error
+}
+
+// usageKey is one merchant's calls on one UTC day.
+type usageKey struct {
+	merchant string
+	day      string
+}
+
+// UsageMeter counts API calls per merchant and day in memory and writes
+// them out on Flush, so metering costs a request no database write. Calls
+// counted since the last flush are lost if the process dies. It is safe for
+// concurrent use.
+type UsageMeter struct {
+	store UsageRecorder
+	clock clock.Clock
+
+	mu      sync.Mutex
+	pending map[usageKey]*models.APIUsage
+}
+
+// NewUsageMeter returns a meter writing to store; a nil clock means the
+// system clock.
+func NewUsageMeter(store UsageRecorder, clk clock.Clock) *UsageMeter {
+	return &UsageMeter{store: store, clock: clock.OrReal(clk), pending: map[usageKey]*models.APIUsage{}}
+}
+
+// Meter counts every request that names a merchant, once it is answered,
+// as a call and, if it was answered with a 5xx status, as an error.
+func Meter(m *UsageMeter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if id := merchant(c); id != "" {
+			m.record(id, c.Writer.Status() >= http.StatusInternalServerError)
+		}
+	}
+}
+
+func (m *UsageMeter) record(merchant string, failed bool) {
+	day := m.clock.Now().UTC().Truncate(24 * time.Hour)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.add(models.APIUsage{MerchantID: merchant, Day: day, Calls: 1, Errors: boolCount(failed)})
+}
+
+// add merges usage into the pending counts. m.mu must be held.
+func (m *UsageMeter) add(u models.APIUsage) {
+	key := usageKey{u.MerchantID, u.Day.Format("2006-01-02")}
+	p, ok := m.pending[key]
+	if !ok {
+		p = &models.APIUsage{MerchantID: u.MerchantID, Day: u.Day}
+		m.pending[key] = p
+	}
+	p.Calls += u.Calls
+	p.Errors += u.Errors
+}
+
+func boolCount(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Flush writes the calls counted since the last flush. If the write fails
+// they are kept for the next one.
+func (m *UsageMeter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = map[usageKey]*models.APIUsage{}
+	m.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usage := make([]models.APIUsage, 0, len(pending))
+	for _, u := range pending {
+		usage = append(usage, *u)
+	}
+	// A stable order keeps concurrent flushes from deadlocking on rows.
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].MerchantID != usage[j].MerchantID {
+			return usage[i].MerchantID < usage[j].MerchantID
+		}
+		return usage[i].Day.Before(usage[j].Day)
+	})
+	if err := m.store.RecordAPIUsage(ctx, usage); err != nil {
+		m.mu.Lock()
+		for _, u := range usage {
+			m.add(u)
+		}
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run flushes the meter every interval until ctx is cancelled.
+func (m *UsageMeter) Run(ctx context.Context, interval time.Duration) {
+	for {
+		timer := m.clock.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		if err := m.Flush(ctx); err != nil {
+			log.Printf("[api] Error writing API usage, kept for the next flush: %v", err)
+		}
+	}
+}
+
+// GetUsageReport totals usage per merchant: API calls, batches created, and
+// the payouts of those batches completed or failed with the amounts paid.
+// Query: from / to (dates in UTC, to exclusive) and merchant.
+// GET /admin/v1/usage
+func (h *Handler) GetUsageReport(c *gin.Context) {
+	report := models.UsageReport{GeneratedAt: h.cfg.Clock.Now().UTC()}
+	for param, dst := range map[string]**time.Time{"from": &report.From, "to": &report.To} {
+		if v := c.Query(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_date", param)})
+				return
+			}
+			*dst = &date
+		}
+	}
+
+	merchants, err := h.repo.GetUsageReport(c.Request.Context(), report.From, report.To, c.Query("merchant"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	report.Merchants = merchants
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memUsage records the usage flushed to it, or fails while down.
+type memUsage struct {
+	mu      sync.Mutex
+	down    bool
+	flushed []models.APIUsage
+}
+
+func (m *memUsage) RecordAPIUsage(_ context.Context, usage []models.APIUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return errors.New("connection refused")
+	}
+	m.flushed = append(m.flushed, usage...)
+	return nil
+}
+
+// TestUsageMeter verifies calls are metered to the key a request was
+// signed with, or else its X-Merchant header, with 5xx answers counted as
+// errors, and that a failed flush keeps the counts for the next one.
+func TestUsageMeter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	store := &memUsage{}
+	meter := api.NewUsageMeter(store, clk)
+	cfg := api.SigningConfig{Keys: map[string]string{"partner": "s3cret"}}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(api.Localize(), api.Meter(meter), api.RequireSignature(cfg, clk, &memNonces{seen: map[string]bool{}}))
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.POST("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(req *http.Request, merchant string) {
+		if merchant != "" {
+			req.Header.Set("X-Merchant", merchant)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send(httptest.NewRequest(http.MethodGet, "/ok", nil), "acme")
+	send(httptest.NewRequest(http.MethodGet, "/fail", nil), "acme")
+	send(httptest.NewRequest(http.MethodGet, "/ok", nil), "")
+	// The signing key wins over a header naming someone else.
+	send(signedRequest(http.MethodPost, "/ok", "", "s3cret", "n1", now), "acme")
+
+	store.down = true
+	if err := meter.Flush(context.Background()); err == nil {
+		t.Fatal("Expected the flush to fail while the store is down")
+	}
+	store.down = false
+	send(httptest.NewRequest(http.MethodGet, "/ok", nil), "acme")
+	if err := meter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	got := map[string]models.APIUsage{}
+	for _, u := range store.flushed {
+		got[u.MerchantID] = u
+	}
+	if len(store.flushed) != 2 {
+		t.Fatalf("Expected usage for 2 merchants, got %+v", store.flushed)
+	}
+	if u := got["acme"]; u.Calls != 3 || u.Errors != 1 || !u.Day.Equal(now.Truncate(24*time.Hour)) {
+		t.Errorf("Expected 3 calls and 1 error for acme on %s, got %+v", now.Format("2006-01-02"), u)
+	}
+	if u := got["partner"]; u.Calls != 1 || u.Errors != 0 {
+		t.Errorf("Expected 1 call for the signing key, got %+v", u)
+	}
+}
+
+// TestUsageReport verifies the usage report totals a merchant's API calls,
+// batches and processed payouts with the amounts paid, within the period.
+func TestUsageReport(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+	repo := repository.New(db)
+
+	batch, err := repo.CreateBatch(ctx, []models.CreatePayoutItem{
+		vendorItem("usage_vendor_0", "Usage Vendor", nil),
+		vendorItem("usage_vendor_1", "Usage Vendor", nil),
+	}, models.BatchOptions{Merchant: "usage-merchant"})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	db.Exec(`UPDATE payouts SET status = 'completed', completed_at = NOW() WHERE batch_id = $1 AND seq = 0`, batch.ID)
+	db.Exec(`UPDATE payouts SET status = 'failed' WHERE batch_id = $1 AND seq = 1`, batch.ID)
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	if err := repo.RecordAPIUsage(ctx, []models.APIUsage{
+		{MerchantID: "usage-merchant", Day: day, Calls: 5, Errors: 1},
+		{MerchantID: "usage-merchant", Day: day.AddDate(0, 0, -10), Calls: 7},
+	}); err != nil {
+		t.Fatalf("RecordAPIUsage failed: %v", err)
+	}
+
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), adminConfig())
+	var report models.UsageReport
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/v1/usage?merchant=usage-merchant&from="+day.Format("2006-01-02"), ""))
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &report) != nil {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if len(report.Merchants) != 1 {
+		t.Fatalf("Expected one merchant, got %+v", report.Merchants)
+	}
+	u := report.Merchants[0]
+	if u.APICalls != 5 || u.APIErrors != 1 || u.BatchesCreated != 1 || u.PayoutsCompleted != 1 || u.PayoutsFailed != 1 {
+		t.Errorf("Expected 5 calls, 1 error, 1 batch, 1 completed and 1 failed payout, got %+v", u)
+	}
+	if len(u.Amounts) != 1 || u.Amounts[0].Amount != 100 {
+		t.Errorf("Expected 100 paid, got %+v", u.Amounts)
+	}
+}
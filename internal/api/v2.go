@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"coding-challenge/internal/i18n"
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// --- API v2 lists ---
+//
+// v2 serves the same handlers as v1 inside an envelope (see Envelope),
+// except lists, which page by cursor instead of page number: ?limit= sets
+// the page size (1-200, default 50) and ?cursor= resumes after the previous
+// page's meta.next_cursor. Cursors are keyset positions, so a page never
+// repeats or skips items when newer ones are added.
+
+// encodeCursor renders a page cursor as an opaque URL-safe string.
+func encodeCursor(cur models.PageCursor) string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// pageCursor reads ?limit= and ?cursor=, answering 400 for a cursor that
+// was not issued by encodeCursor.
+func pageCursor(c *gin.Context) (int, *models.PageCursor, bool) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	raw := c.Query("cursor")
+	if raw == "" {
+		return limit, nil, true
+	}
+	var cur models.PageCursor
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || json.Unmarshal(b, &cur) != nil || cur.ID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_cursor")})
+		return 0, nil, false
+	}
+	return limit, &cur, true
+}
+
+// ListBatchesV2 returns batches newest first, a page at a time.
+// GET /api/v2/batches?status=completed&assigned_to=ops@example.com&created_from=2024-01-01&limit=50&cursor=...
+func (h *Handler) ListBatchesV2(c *gin.Context) {
+	filter, ok := batchListFilter(c)
+	if !ok {
+		return
+	}
+	limit, after, ok := pageCursor(c)
+	if !ok {
+		return
+	}
+
+	batches, err := h.repo.ListBatchesAfter(c.Request.Context(), filter, after, limit+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next *models.PageCursor
+	if len(batches) > limit {
+		batches = batches[:limit]
+		next = &models.PageCursor{CreatedAt: batches[limit-1].CreatedAt, ID: batches[limit-1].ID}
+	}
+	for i := range batches {
+		batches[i].Links = batchLinks(c, &batches[i])
+	}
+	setPageMeta(c, limit, next)
+	c.JSON(http.StatusOK, batches)
+}
+
+// GetBatchPayoutsV2 returns a batch's payouts oldest first, a page at a time.
+// GET /api/v2/batches/:id/payouts?status=failed&limit=50&cursor=...
+func (h *Handler) GetBatchPayoutsV2(c *gin.Context) {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_batch_id")})
+		return
+	}
+	filter := models.PayoutFilter{BatchID: &batchID}
+	if status := c.Query("status"); status != "" {
+		filter.Statuses = []string{status}
+	}
+	h.listPayoutsV2(c, filter)
+}
+
+// ListPayoutsV2 returns the payouts matching a saved view, oldest first, a
+// page at a time.
+// GET /api/v2/payouts?view=idr-permanent-failures&limit=50&cursor=...
+func (h *Handler) ListPayoutsV2(c *gin.Context) {
+	name := c.Query("view")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "validation.required", "view")})
+		return
+	}
+	view, err := h.repo.GetPayoutView(c.Request.Context(), name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if view == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.view_not_found")})
+		return
+	}
+	h.listPayoutsV2(c, view.Filter)
+}
+
+func (h *Handler) listPayoutsV2(c *gin.Context, filter models.PayoutFilter) {
+	limit, after, ok := pageCursor(c)
+	if !ok {
+		return
+	}
+
+	payouts, err := h.repo.ListPayoutsAfter(c.Request.Context(), filter, after, limit+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next *models.PageCursor
+	if len(payouts) > limit {
+		payouts = payouts[:limit]
+		next = &models.PageCursor{CreatedAt: payouts[limit-1].CreatedAt, ID: payouts[limit-1].ID}
+	}
+	for i := range payouts {
+		if reason := payouts[i].FailureReason; reason != nil {
+			payouts[i].FailureDescription = i18n.FailureDescription(lang(c), *reason)
+		}
+		payouts[i].Links = payoutLinks(c, &payouts[i].Payout)
+	}
+	setPageMeta(c, limit, next)
+	c.JSON(http.StatusOK, payouts)
+}
@@ -0,0 +1,138 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getEnvelope requests a v2 path and decodes the envelope, and its data
+// into out when given.
+func getEnvelope(t *testing.T, r *gin.Engine, path string, out any) (int, models.Envelope) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var env models.Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("Expected an envelope from %s, got %d: %s", path, w.Code, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			t.Fatalf("Failed to decode data of %s: %v", path, err)
+		}
+	}
+	return w.Code, env
+}
+
+// TestV2Envelope verifies v2 errors carry typed codes inside the envelope,
+// and that v1 responses announce their deprecation.
+func TestV2Envelope(t *testing.T) {
+	cfg := api.DefaultConfig()
+	cfg.V1Sunset = time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), cfg)
+
+	for _, tc := range []struct {
+		path, code string
+		status     int
+	}{
+		{"/api/v2/batches/not-a-uuid", "invalid_batch_id", http.StatusBadRequest},
+		{"/api/v2/batches?cursor=bogus", "invalid_cursor", http.StatusBadRequest},
+		{"/api/v2/batches?tz=Mars/Olympus", "invalid_timezone", http.StatusBadRequest},
+		{"/api/v2/no-such-thing", "not_found", http.StatusNotFound},
+	} {
+		code, env := getEnvelope(t, r, tc.path, nil)
+		if code != tc.status || len(env.Errors) != 1 || env.Errors[0].Code != tc.code || env.Errors[0].Message == "" {
+			t.Errorf("Expected %d %s for %s, got %d %+v", tc.status, tc.code, tc.path, code, env.Errors)
+		}
+		if string(env.Data) != "null" {
+			t.Errorf("Expected null data on an error, got %s", env.Data)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/batches", strings.NewReader(`{}`)))
+	var env models.Envelope
+	json.Unmarshal(w.Body.Bytes(), &env)
+	if w.Code != http.StatusBadRequest || len(env.Errors) != 1 || env.Errors[0].Code != "required" || env.Errors[0].Field != "payouts" {
+		t.Errorf("Expected a required error on payouts, got %d %+v", w.Code, env.Errors)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/batches/not-a-uuid", nil))
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Wed, 30 Jun 2027 00:00:00 GMT" ||
+		!strings.Contains(w.Header().Get("Link"), `</api/v2>; rel="successor-version"`) {
+		t.Errorf("Expected deprecation headers on v1, got %v", w.Header())
+	}
+	if strings.Contains(w.Body.String(), `"errors"`) {
+		t.Errorf("Expected v1 bodies to stay unwrapped, got %s", w.Body.String())
+	}
+}
+
+// TestV2CursorPagination verifies v2 lists page by cursor without repeating
+// or skipping items, and that links point at v2.
+func TestV2CursorPagination(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+	for _, id := range []string{"V2-1", "V2-2", "V2-3"} {
+		createBatch(t, repo, []models.CreatePayoutItem{vendorItem(id, "Paged Vendor", nil)})
+	}
+	batchID := createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("V2-4", "Paged Vendor", nil), vendorItem("V2-5", "Paged Vendor", nil), vendorItem("V2-6", "Paged Vendor", nil),
+	})
+
+	seen := map[string]bool{}
+	path := "/api/v2/batches?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 2 {
+			t.Fatal("Expected 4 batches in 2 pages")
+		}
+		var batches []models.PayoutBatch
+		code, env := getEnvelope(t, r, path, &batches)
+		if code != http.StatusOK || env.Meta == nil || env.Meta.Limit != 2 {
+			t.Fatalf("Expected 200 with page meta, got %d %+v", code, env.Meta)
+		}
+		for _, b := range batches {
+			if seen[b.ID.String()] {
+				t.Errorf("Expected batch %s once, got it again", b.ID)
+			}
+			seen[b.ID.String()] = true
+			if !strings.HasPrefix(b.Links.Self, "/api/v2/") {
+				t.Errorf("Expected v2 links, got %s", b.Links.Self)
+			}
+		}
+		path = ""
+		if env.Meta.HasMore {
+			path = "/api/v2/batches?limit=2&cursor=" + env.Meta.NextCursor
+		}
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected 4 batches across the pages, got %d", len(seen))
+	}
+
+	var payouts []models.PayoutListItem
+	_, env := getEnvelope(t, r, "/api/v2/batches/"+batchID.String()+"/payouts?limit=2", &payouts)
+	if len(payouts) != 2 || !env.Meta.HasMore {
+		t.Fatalf("Expected a first page of 2 payouts, got %d %+v", len(payouts), env.Meta)
+	}
+	_, env = getEnvelope(t, r, "/api/v2/batches/"+batchID.String()+"/payouts?limit=2&cursor="+env.Meta.NextCursor, &payouts)
+	if len(payouts) != 1 || env.Meta.HasMore || env.Meta.NextCursor != "" {
+		t.Errorf("Expected a last page of 1 payout, got %d %+v", len(payouts), env.Meta)
+	}
+
+	var summary models.BatchSummary
+	if code, _ := getEnvelope(t, r, "/api/v2/batches/"+batchID.String(), &summary); code != http.StatusOK || summary.Batch.ID != batchID {
+		t.Errorf("Expected the batch as data, got %d %+v", code, summary.Batch)
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"coding-challenge/internal/importer"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/money"
+	"coding-challenge/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidateBatch runs a batch request through the checks of batch creation
+// without creating anything, so finance can fix a file before committing
+// it, and reports every problem with the index of its payout. On top of
+// creation's checks it looks for currency codes that are not 3 capital
+// letters, amounts with more decimals than their currency has, breaches of
+// the rules of the import profile named by ?profile= (such as its bank
+// account pattern), and payouts completed within the duplicate window
+// unless duplicate_check is off. Problems with the request itself, such as
+// no payouts, answer 400 as on creation.
+// POST /api/v1/batches/validate?profile=acme
+func (h *Handler) ValidateBatch(c *gin.Context) {
+	var req models.CreateBatchRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.malformed_body")})
+		return
+	}
+	profile, ok := h.importProfile(c)
+	if !ok {
+		return
+	}
+	check, err := importer.CheckRules(profile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := &models.BatchValidation{Payouts: len(req.Payouts), Errors: []models.PayoutError{}}
+	reject := func(i int, field, rule, msg string) {
+		if result.Violations == nil {
+			result.Violations = map[string]int{}
+		}
+		result.Violations[rule]++
+		result.Errors = append(result.Errors, models.PayoutError{
+			Index: i, VendorID: req.Payouts[i].VendorID, Field: field, Rule: rule, Message: msg,
+		})
+	}
+
+	// Field errors of a payout are reported with it; those of the request
+	// refuse it.
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		errs, err := payoutFieldErrors(c, &req, err)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+			return
+		}
+		for _, e := range errs {
+			reject(e.Index, e.Field, e.Rule, e.Message)
+		}
+	}
+	if p := req.RetryPolicy; p != nil && p.Backoff != "" {
+		if _, err := worker.ParseRetryBackoff(p.Backoff); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_retry_policy", err.Error())})
+			return
+		}
+	}
+
+	first := map[string]int{} // vendor -> index of its first payout
+	for i, item := range req.Payouts {
+		switch err := item.ValidateSplits(); {
+		case errors.Is(err, models.ErrSplitTooFew):
+			reject(i, payoutField(i, "splits"), "split_too_few", tr(c, "error.split_too_few", i))
+		case errors.Is(err, models.ErrSplitTotal):
+			reject(i, payoutField(i, "splits"), "split_total", tr(c, "error.split_total", i))
+		}
+		for _, v := range check(item) {
+			reject(i, "", v.Rule, fmt.Sprintf("payouts[%d]: %s", i, v.Message))
+		}
+		if item.Currency != "" && !currencyCode(item.Currency) {
+			field := payoutField(i, "currency")
+			reject(i, field, "currency_code", tr(c, "validation.currency_code", field))
+		} else if d := money.Decimals(item.Currency); item.Amount > 0 && !wholeMinorUnits(item.Amount, d) {
+			field := payoutField(i, "amount")
+			reject(i, field, "amount_precision", tr(c, "validation.amount_precision", field, d, item.Currency))
+		}
+
+		j, seen := first[item.VendorID]
+		if !seen {
+			first[item.VendorID] = i
+			continue
+		}
+		switch {
+		case req.DedupeStrategy != models.DedupeMerge:
+			reject(i, payoutField(i, "vendor_id"), "duplicate_vendor", tr(c, "validation.duplicate_vendor", payoutField(i, "vendor_id"), j))
+		case !req.Payouts[j].MergeableWith(item):
+			reject(i, payoutField(i, "vendor_id"), "vendor_not_mergeable", tr(c, "validation.unmergeable_vendor", payoutField(i, "vendor_id"), j))
+		}
+	}
+
+	if req.DuplicateCheck != models.DuplicateCheckOff {
+		dups, err := h.matchCompleted(c, req.Payouts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, d := range dups {
+			completed := ""
+			if d.CompletedAt != nil {
+				completed = d.CompletedAt.UTC().Format("2006-01-02")
+			}
+			reject(d.Index, "", "already_paid", tr(c, "validation.already_paid", fmt.Sprintf("payouts[%d]", d.Index), completed, d.PriorBatchID))
+		}
+	}
+
+	sort.SliceStable(result.Errors, func(a, b int) bool { return result.Errors[a].Index < result.Errors[b].Index })
+	rejected := map[int]bool{}
+	for _, e := range result.Errors {
+		rejected[e.Index] = true
+	}
+	result.Rejected = len(rejected)
+	result.Accepted = result.Payouts - result.Rejected
+	result.Valid = result.Rejected == 0
+	c.JSON(http.StatusOK, result)
+}
+
+// payoutFieldErrors sorts the field errors of a batch request failing
+// binding into those of its payouts, returned by payout, and the rest,
+// returned as the error, nil when there are none.
+func payoutFieldErrors(c *gin.Context, req *models.CreateBatchRequest, err error) ([]models.PayoutError, error) {
+	var verrs, request validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil, err
+	}
+	var errs []models.PayoutError
+	for _, fe := range verrs {
+		field := fieldError(c, fe)
+		if i, ok := payoutIndex(field.Field); ok && i < len(req.Payouts) {
+			errs = append(errs, models.PayoutError{
+				Index: i, VendorID: req.Payouts[i].VendorID, Field: field.Field, Rule: field.Code, Message: field.Message,
+			})
+			continue
+		}
+		request = append(request, fe)
+	}
+	if len(request) > 0 {
+		return errs, request
+	}
+	return errs, nil
+}
+
+// payoutIndex returns the index of the payout a field path such as
+// "payouts[3].amount" belongs to.
+func payoutIndex(field string) (int, bool) {
+	rest, ok := strings.CutPrefix(field, "payouts[")
+	if !ok {
+		return 0, false
+	}
+	index, _, ok := strings.Cut(rest, "]")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(index)
+	return i, err == nil
+}
+
+// payoutField is the JSON path of a field of the i-th payout.
+func payoutField(i int, name string) string {
+	return fmt.Sprintf("payouts[%d].%s", i, name)
+}
+
+// currencyCode reports whether code is written as an ISO 4217 code: three
+// capital letters.
+func currencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// wholeMinorUnits reports whether amount has no more than decimals digits
+// after the point, allowing for float representation.
+func wholeMinorUnits(amount float64, decimals int) bool {
+	scaled := amount * math.Pow10(decimals)
+	return math.Abs(scaled-math.Round(scaled)) < 1e-6
+}
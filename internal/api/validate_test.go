@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+)
+
+// profileStore serves one import profile; nothing else of SettingsStore.
+type profileStore struct {
+	api.SettingsStore
+	profile models.ImportProfile
+}
+
+func (s profileStore) GetImportProfile(_ context.Context, name string) (*models.ImportProfile, error) {
+	if name != s.profile.Name {
+		return nil, nil
+	}
+	return &s.profile, nil
+}
+
+// TestValidateBatch verifies a dry run reports every problem with the index
+// of its payout, including the import profile's rules and payouts already
+// paid, that a request-level problem is refused with 400, and that nothing
+// is created.
+func TestValidateBatch(t *testing.T) {
+	store := memstore.New()
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(service.NewScenario()))
+	settings := profileStore{profile: models.ImportProfile{Name: "acme", Rules: models.ImportRules{BankAccountPattern: `[0-9]+`}}}
+	r := api.SetupRouter(memAPIStore{Store: store, SettingsStore: settings}, pool, api.DefaultConfig())
+
+	paid, err := store.CreateBatch(context.Background(), []models.CreatePayoutItem{
+		{VendorID: "VAL-PAID", Amount: 50, Currency: "IDR", BankAccount: "9"},
+	}, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(context.Background(), paid.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	validate := func(query, body string) (int, models.BatchValidation) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/batches/validate"+query, strings.NewReader(body)))
+		var result models.BatchValidation
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	code, result := validate("?profile=acme", `{"payouts": [
+		{"vendor_id": "VAL-1", "amount": 10, "currency": "IDR", "bank_account": "1"},
+		{"vendor_id": "VAL-2", "amount": 0, "currency": "IDR", "bank_account": "2"},
+		{"vendor_id": "VAL-3", "amount": 10, "currency": "usd", "bank_account": "3"},
+		{"vendor_id": "VAL-4", "amount": 10.5, "currency": "IDR", "bank_account": "4"},
+		{"vendor_id": "VAL-1", "amount": 10, "currency": "IDR", "bank_account": "1"},
+		{"vendor_id": "VAL-6", "amount": 10, "currency": "IDR", "splits": [{"percent": 50, "bank_account": "6"}, {"percent": 40, "bank_account": "7"}]},
+		{"vendor_id": "VAL-7", "amount": 10, "currency": "IDR", "bank_account": "ACC-7"},
+		{"vendor_id": "VAL-PAID", "amount": 50, "currency": "IDR", "bank_account": "9"}]}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	want := map[int]string{1: "required", 2: "currency_code", 3: "amount_precision", 4: "duplicate_vendor", 5: "split_total", 6: "bank_account_pattern", 7: "already_paid"}
+	got := map[int]string{}
+	for _, e := range result.Errors {
+		got[e.Index] = e.Rule
+	}
+	for i, rule := range want {
+		if got[i] != rule {
+			t.Errorf("Expected payouts[%d] to break %s, got %q", i, rule, got[i])
+		}
+	}
+	if result.Valid || result.Payouts != 8 || result.Accepted != 1 || result.Rejected != 7 || len(result.Errors) != 7 {
+		t.Errorf("Expected 1 of 8 payouts accepted with 7 errors, got %+v", result)
+	}
+
+	if code, result := validate("", `{"duplicate_check": "off", "payouts": [
+		{"vendor_id": "VAL-PAID", "amount": 50, "currency": "IDR", "bank_account": "9"}]}`); code != http.StatusOK || !result.Valid {
+		t.Errorf("Expected a valid batch with the duplicate check off, got %d: %+v", code, result)
+	}
+	if code, _ := validate("", `{"payouts": []}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without payouts, got %d", code)
+	}
+	if batches, total, _ := store.ListBatches(context.Background(), models.BatchListFilter{Page: 1, PageSize: 10}); total != 1 {
+		t.Errorf("Expected validation to create nothing, got %d batches: %+v", total, batches)
+	}
+}
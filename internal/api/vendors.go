@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/taxid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVendorTaxID returns the tax ID on file for a vendor.
+// GET /api/v1/vendors/:vendor_id/tax-id
+func (h *Handler) GetVendorTaxID(c *gin.Context) {
+	t, err := h.repo.GetVendorTaxID(c.Request.Context(), c.Param("vendor_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.tax_id_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// SetVendorTaxID records a vendor's NPWP, TIN or MST, checked against the
+// country's format, replacing any on file. It is then included in exports
+// and payment files and in the tax summary.
+// PUT /api/v1/vendors/:vendor_id/tax-id
+func (h *Handler) SetVendorTaxID(c *gin.Context) {
+	var req models.SetVendorTaxIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	country := strings.ToUpper(req.Country)
+	digits, err := taxid.Normalize(country, req.TaxID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_tax_id", err.Error())})
+		return
+	}
+
+	t, err := h.repo.SetVendorTaxID(c.Request.Context(), c.Param("vendor_id"), country, digits, actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// GetTaxSummary totals completed payouts per vendor and currency over a
+// period, with each vendor's tax ID, and flags the vendors paid above the
+// currency's TAX_ID_THRESHOLDS amount who have none on file.
+// GET /api/v1/reports/tax-summary?from=2026-01-01&to=2027-01-01&missing=true
+func (h *Handler) GetTaxSummary(c *gin.Context) {
+	report := models.TaxSummary{GeneratedAt: h.cfg.Clock.Now().UTC()}
+	for param, dst := range map[string]**time.Time{"from": &report.From, "to": &report.To} {
+		if v := c.Query(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_date", param)})
+				return
+			}
+			*dst = &date
+		}
+	}
+	missingOnly := c.Query("missing") == "true"
+
+	vendors, err := h.repo.GetTaxSummary(c.Request.Context(), report.From, report.To)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	report.Vendors = []models.TaxSummaryVendor{}
+	for _, v := range vendors {
+		if threshold, ok := h.cfg.TaxIDThresholds[v.Currency]; ok {
+			v.Threshold = &threshold
+			v.RequiresTaxID = v.Amount > threshold
+			v.MissingTaxID = v.RequiresTaxID && v.TaxID == ""
+		}
+		if v.MissingTaxID {
+			report.MissingIDs++
+		}
+		if missingOnly && !v.MissingTaxID {
+			continue
+		}
+		report.Vendors = append(report.Vendors, v)
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestVendorTaxIDValidation verifies tax IDs are checked against their
+// country's format before anything is stored.
+func TestVendorTaxIDValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	cases := []struct {
+		name, method, path, body string
+	}{
+		{"no country", http.MethodPut, "/api/v1/vendors/V-1/tax-id", `{"tax_id": "123-456-789"}`},
+		{"unsupported country", http.MethodPut, "/api/v1/vendors/V-1/tax-id", `{"country": "SG", "tax_id": "200312345A"}`},
+		{"short NPWP", http.MethodPut, "/api/v1/vendors/V-1/tax-id", `{"country": "ID", "tax_id": "01.234.567.8"}`},
+		{"bad date", http.MethodGet, "/api/v1/reports/tax-summary?from=January", ""},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tc.name, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestVendorTaxIDs verifies a vendor's tax ID is stored formatted, exported
+// with its payouts, and that the tax summary flags vendors paid above the
+// threshold without one.
+func TestVendorTaxIDs(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	cfg := api.DefaultConfig()
+	cfg.TaxIDThresholds = map[string]float64{"IDR": 150}
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()))
+	r := api.SetupRouter(repo, pool, cfg)
+
+	suffix := uuid.NewString()[:8]
+	filed, unfiled, small := "TAX-1-"+suffix, "TAX-2-"+suffix, "TAX-3-"+suffix
+	items := []models.CreatePayoutItem{
+		vendorItem(filed, "Toko Batik", nil),
+		vendorItem(unfiled, "Warung Kopi", nil),
+		vendorItem(small, "Sari Roti", nil),
+	}
+	items[0].Amount, items[1].Amount, items[2].Amount = 200, 200, 50
+	batchID := createBatch(t, repo, items)
+	if err := pool.ProcessBatch(context.Background(), batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/vendors/"+filed+"/tax-id", strings.NewReader(`{"country": "id", "tax_id": "012345678901000"}`))
+	req.Header.Set("X-Operator", "ops@example.com")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 recording the tax ID, got %d: %s", w.Code, w.Body.String())
+	}
+	var got models.VendorTaxID
+	if code := getJSON(t, r, "/api/v1/vendors/"+filed+"/tax-id", &got); code != http.StatusOK {
+		t.Fatalf("Expected 200 reading the tax ID, got %d", code)
+	}
+	if got.Country != "ID" || got.TaxID != "01.234.567.8-901.000" || got.UpdatedBy != "ops@example.com" {
+		t.Errorf("Expected the NPWP formatted and attributed, got %+v", got)
+	}
+	if code := getJSON(t, r, "/api/v1/vendors/"+unfiled+"/tax-id", &got); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a vendor without a tax ID, got %d", code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/batches/"+batchID.String()+"/export", nil))
+	if !strings.Contains(w.Body.String(), filed+",Toko Batik,01.234.567.8-901.000,") {
+		t.Errorf("Expected the tax ID in the export, got:\n%s", w.Body.String())
+	}
+
+	var summary models.TaxSummary
+	if code := getJSON(t, r, "/api/v1/reports/tax-summary", &summary); code != http.StatusOK {
+		t.Fatalf("Expected 200 for the tax summary, got %d", code)
+	}
+	want := map[string][2]bool{ // requires, missing
+		filed:   {true, false},
+		unfiled: {true, true},
+		small:   {false, false},
+	}
+	seen := 0
+	for _, v := range summary.Vendors {
+		flags, ok := want[v.VendorID]
+		if !ok {
+			continue
+		}
+		seen++
+		if v.RequiresTaxID != flags[0] || v.MissingTaxID != flags[1] {
+			t.Errorf("Expected %s to require a tax ID: %v, missing: %v; got %+v", v.VendorID, flags[0], flags[1], v)
+		}
+	}
+	if seen != 3 {
+		t.Errorf("Expected all 3 vendors in the summary, found %d", seen)
+	}
+
+	if getJSON(t, r, "/api/v1/reports/tax-summary?missing=true", &summary); len(summary.Vendors) != summary.MissingIDs {
+		t.Errorf("Expected only the %d vendors missing a tax ID, got %d", summary.MissingIDs, len(summary.Vendors))
+	}
+}
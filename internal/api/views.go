@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"coding-challenge/internal/i18n"
+	"coding-challenge/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxViewName is the longest payout view name (payout_views.name).
+const maxViewName = 100
+
+// SavePayoutView creates or replaces a named payout filter. X-Operator is
+// recorded as the last editor.
+// PUT /api/v1/payout-views/:name
+func (h *Handler) SavePayoutView(c *gin.Context) {
+	var req models.SavePayoutViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	name := c.Param("name")
+	if len(name) > maxViewName {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "validation.max", "name", strconv.Itoa(maxViewName))})
+		return
+	}
+	f := req.Filter
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MinAmount > *f.MaxAmount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_amount_range")})
+		return
+	}
+	for i, cur := range f.Currencies {
+		f.Currencies[i] = strings.ToUpper(cur)
+	}
+
+	view, err := h.repo.SavePayoutView(c.Request.Context(), models.PayoutView{
+		Name:        name,
+		Description: req.Description,
+		Filter:      f,
+		UpdatedBy:   actor(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// GetPayoutView returns one payout view.
+// GET /api/v1/payout-views/:name
+func (h *Handler) GetPayoutView(c *gin.Context) {
+	view, err := h.repo.GetPayoutView(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if view == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.view_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// ListPayoutViews returns every payout view.
+// GET /api/v1/payout-views
+func (h *Handler) ListPayoutViews(c *gin.Context) {
+	views, err := h.repo.ListPayoutViews(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"views": views})
+}
+
+// DeletePayoutView removes a payout view.
+// DELETE /api/v1/payout-views/:name
+func (h *Handler) DeletePayoutView(c *gin.Context) {
+	deleted, err := h.repo.DeletePayoutView(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.view_not_found")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListPayouts lists the payouts matching a saved view across all live
+// batches, oldest first.
+// GET /api/v1/payouts?view=idr-permanent-failures&page=1&page_size=50
+func (h *Handler) ListPayouts(c *gin.Context) {
+	name := c.Query("view")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "validation.required", "view")})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	view, err := h.repo.GetPayoutView(c.Request.Context(), name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if view == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.view_not_found")})
+		return
+	}
+
+	payouts, total, err := h.repo.ListPayouts(c.Request.Context(), view.Filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range payouts {
+		if reason := payouts[i].FailureReason; reason != nil {
+			payouts[i].FailureDescription = i18n.FailureDescription(lang(c), *reason)
+		}
+		payouts[i].Links = payoutLinks(c, &payouts[i].Payout)
+	}
+
+	c.JSON(http.StatusOK, models.PayoutListResponse{
+		Payouts:    payouts,
+		TotalCount: total,
+		Page:       page,
+		PageSize:   pageSize,
+	})
+}
@@ -0,0 +1,106 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+)
+
+// TestPayoutViewValidation verifies view definitions and view listings are
+// checked before anything is looked up.
+func TestPayoutViewValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	cases := []struct {
+		name, body, want string
+	}{
+		{"triage", `{"filter": {"statuses": ["lost"]}}`, "filter.statuses[0] must be one of"},
+		{"triage", `{"filter": {"currencies": ["RUPIAH"]}}`, "filter.currencies[0]"},
+		{"triage", `{"filter": {"min_amount": 500, "max_amount": 100}}`, "min_amount must not be greater than max_amount"},
+		{strings.Repeat("v", 101), `{"filter": {}}`, "name must be at most 100"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/payout-views/"+tc.name, strings.NewReader(tc.body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("Expected 400 with %q, got %d: %s", tc.want, w.Code, w.Body.String())
+		}
+	}
+	if code := getJSON(t, r, "/api/v1/payouts", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 listing payouts without a view, got %d", code)
+	}
+}
+
+// TestPayoutViews verifies saved views are shared by name and select the
+// same payouts across batches until they are deleted.
+func TestPayoutViews(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	processedBatch(t, repo)
+	createBatch(t, repo, []models.CreatePayoutItem{
+		vendorItem("VIEW-0", "Pending Vendor", nil),
+		vendorItem("VIEW-1", "Large Vendor", nil),
+	})
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+
+	put := func(name, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/payout-views/"+name, strings.NewReader(body))
+		req.Header.Set("X-Operator", "ops@example.com")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := put("usd-permanent-failures", `{"description": "Failures only the vendor can fix", "filter": {"statuses": ["failed"], "currencies": ["usd"], "retryable": false, "min_amount": 50}}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 saving a view, got %d", code)
+	}
+	if code := put("idr-pending", `{"filter": {"statuses": ["pending"], "currencies": ["IDR"]}}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 saving a view, got %d", code)
+	}
+
+	var view models.PayoutView
+	getJSON(t, r, "/api/v1/payout-views/usd-permanent-failures", &view)
+	if view.UpdatedBy != "ops@example.com" || len(view.Filter.Currencies) != 1 || view.Filter.Currencies[0] != "USD" {
+		t.Errorf("Expected the view saved by ops@example.com for USD, got %+v", view)
+	}
+	var views struct {
+		Views []models.PayoutView `json:"views"`
+	}
+	getJSON(t, r, "/api/v1/payout-views", &views)
+	if len(views.Views) != 2 || views.Views[0].Name != "idr-pending" {
+		t.Errorf("Expected 2 views by name, got %+v", views.Views)
+	}
+
+	var list models.PayoutListResponse
+	getJSON(t, r, "/api/v1/payouts?view=usd-permanent-failures", &list)
+	if list.TotalCount != 1 || list.Payouts[0].VendorID != "api_vendor_1" || list.Payouts[0].FailureDescription == "" {
+		t.Errorf("Expected the blocked account's payout, got %+v", list)
+	}
+	getJSON(t, r, "/api/v1/payouts?view=idr-pending&page_size=1", &list)
+	if list.TotalCount != 2 || len(list.Payouts) != 1 || list.Payouts[0].VendorID != "VIEW-0" {
+		t.Errorf("Expected 2 pending IDR payouts, oldest first, got %+v", list)
+	}
+
+	if code := put("usd-permanent-failures", `{"filter": {"statuses": ["failed"], "retryable": true}}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 replacing a view, got %d", code)
+	}
+	getJSON(t, r, "/api/v1/payouts?view=usd-permanent-failures", &list)
+	if list.TotalCount != 0 {
+		t.Errorf("Expected no transient failures, got %d", list.TotalCount)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/payout-views/idr-pending", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting a view, got %d", w.Code)
+	}
+	if code := getJSON(t, r, "/api/v1/payouts?view=idr-pending", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted view, got %d", code)
+	}
+}
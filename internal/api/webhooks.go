@@ -0,0 +1,206 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/notify/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxSecretGrace bounds how long a rotated-out webhook secret stays valid.
+const maxSecretGrace = 7 * 24 * time.Hour
+
+// CreateWebhook registers a webhook subscription. The response is the only
+// one showing the secret, generated unless the request sets one.
+// POST /api/v1/webhooks
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	sub := models.WebhookSubscription{
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+		Secret:      req.Secret,
+		Active:      req.Active == nil || *req.Active,
+		CreatedBy:   actor(c),
+	}
+	if sub.Secret == "" {
+		sub.Secret = webhook.NewSecret()
+	}
+
+	created, err := h.repo.CreateWebhook(c.Request.Context(), sub)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListWebhooks returns every webhook subscription, without secrets.
+// GET /api/v1/webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	subs, err := h.repo.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": subs})
+}
+
+// GetWebhook returns a subscription with its delivery statistics.
+// GET /api/v1/webhooks/:id
+func (h *Handler) GetWebhook(c *gin.Context) {
+	sub, ok := h.webhook(c)
+	if !ok {
+		return
+	}
+	stats, err := h.repo.GetWebhookStats(c.Request.Context(), sub.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sub.Secret, sub.Stats = "", stats
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateWebhook changes a subscription's URL, event types, description or
+// active flag.
+// PATCH /api/v1/webhooks/:id
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_webhook_id")})
+		return
+	}
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	if req.URL == nil && req.EventTypes == nil && req.Description == nil && req.Active == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.nothing_to_update")})
+		return
+	}
+
+	sub, err := h.repo.UpdateWebhook(c.Request.Context(), id, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.webhook_not_found")})
+		return
+	}
+	sub.Secret = ""
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhook removes a subscription and its delivery history.
+// DELETE /api/v1/webhooks/:id
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_webhook_id")})
+		return
+	}
+	deleted, err := h.repo.DeleteWebhook(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.webhook_not_found")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PingWebhook posts a ping event to a subscription right away, active or
+// not, and returns the recorded delivery.
+// POST /api/v1/webhooks/:id/ping
+func (h *Handler) PingWebhook(c *gin.Context) {
+	sub, ok := h.webhook(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.cfg.Webhooks.Deliver(c.Request.Context(), *sub, h.cfg.Webhooks.Ping(*sub)))
+}
+
+// RotateWebhookSecret replaces a subscription's secret and returns the new
+// one. Events stay signed with the old secret too for ?grace= (default
+// 24h, at most 168h; 0s retires it at once).
+// POST /api/v1/webhooks/:id/rotate-secret?grace=24h
+func (h *Handler) RotateWebhookSecret(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_webhook_id")})
+		return
+	}
+	grace, err := time.ParseDuration(c.DefaultQuery("grace", "24h"))
+	if err != nil || grace < 0 || grace > maxSecretGrace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_grace", maxSecretGrace.String())})
+		return
+	}
+
+	sub, err := h.repo.RotateWebhookSecret(c.Request.Context(), id, webhook.NewSecret(), grace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.webhook_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// GetWebhookDeliveries lists a subscription's latest delivery attempts,
+// newest first.
+// GET /api/v1/webhooks/:id/deliveries?limit=50
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	sub, ok := h.webhook(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	deliveries, err := h.repo.ListWebhookDeliveries(c.Request.Context(), sub.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// webhook loads the subscription named by the :id parameter, answering 400
+// or 404 when there is none.
+func (h *Handler) webhook(c *gin.Context) (*models.WebhookSubscription, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_webhook_id")})
+		return nil, false
+	}
+	sub, err := h.repo.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.webhook_not_found")})
+		return nil, false
+	}
+	return sub, true
+}
@@ -0,0 +1,127 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/notify/webhook"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestWebhookValidation verifies subscriptions are checked before anything
+// is stored or looked up.
+func TestWebhookValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	id := uuid.New().String()
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/v1/webhooks", `{"event_types": ["payout.failed"]}`},
+		{http.MethodPost, "/api/v1/webhooks", `{"url": "not a url", "event_types": ["payout.failed"]}`},
+		{http.MethodPost, "/api/v1/webhooks", `{"url": "https://example.com/hook", "event_types": ["payout.lost"]}`},
+		{http.MethodPost, "/api/v1/webhooks", `{"url": "https://example.com/hook", "event_types": ["payout.failed"], "secret": "short"}`},
+		{http.MethodPatch, "/api/v1/webhooks/" + id, `{}`},
+		{http.MethodPatch, "/api/v1/webhooks/nope", `{"active": false}`},
+		{http.MethodPost, "/api/v1/webhooks/" + id + "/rotate-secret?grace=720h", ``},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s %s %s, got %d: %s", tc.method, tc.path, tc.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestWebhookSubscriptions verifies a subscription's lifecycle: creation
+// shows the secret once, pings are delivered and counted, rotation keeps
+// the old secret signing, and deletion removes it.
+func TestWebhookSubscriptions(t *testing.T) {
+	db := getTestDB(t)
+
+	var signature string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhook.HeaderSignature)
+	}))
+	defer receiver.Close()
+
+	repo := repository.New(db)
+	r := api.SetupRouter(repo, worker.NewPool(repo, 1, 10), api.DefaultConfig())
+	send := func(method, path, body string, out any) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Operator", "ops@example.com")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+
+	var sub models.WebhookSubscription
+	if code := send(http.MethodPost, "/api/v1/webhooks", `{"url": "`+receiver.URL+`", "event_types": ["payout.failed", "batch.finished"]}`, &sub); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+	if !strings.HasPrefix(sub.Secret, "whsec_") || !sub.Active || sub.CreatedBy != "ops@example.com" {
+		t.Errorf("Expected an active subscription with a generated secret, got %+v", sub)
+	}
+	path := "/api/v1/webhooks/" + sub.ID.String()
+
+	var list struct {
+		Webhooks []models.WebhookSubscription `json:"webhooks"`
+	}
+	getJSON(t, r, "/api/v1/webhooks", &list)
+	if len(list.Webhooks) != 1 || list.Webhooks[0].Secret != "" {
+		t.Errorf("Expected 1 subscription without its secret, got %+v", list.Webhooks)
+	}
+
+	var updated models.WebhookSubscription
+	if code := send(http.MethodPatch, path, `{"active": false, "event_types": ["payout.completed"]}`, &updated); code != http.StatusOK || updated.Active || updated.EventTypes[0] != models.EventPayoutCompleted {
+		t.Errorf("Expected the subscription deactivated and re-targeted, got %d %+v", code, updated)
+	}
+
+	var delivery models.WebhookDelivery
+	if code := send(http.MethodPost, path+"/ping", "", &delivery); code != http.StatusOK || delivery.Status != models.WebhookStatusSent || delivery.EventType != webhook.EventType(models.EventPing) {
+		t.Fatalf("Expected an inactive subscription to still be pinged, got %d %+v", code, delivery)
+	}
+	if strings.Count(signature, "v1=") != 1 {
+		t.Errorf("Expected one signature before rotation, got %s", signature)
+	}
+
+	var rotated models.WebhookSubscription
+	if code := send(http.MethodPost, path+"/rotate-secret?grace=1h", "", &rotated); code != http.StatusOK || rotated.Secret == sub.Secret || rotated.PreviousSecretExpiresAt == nil {
+		t.Fatalf("Expected a new secret with the old one in grace, got %d %+v", code, rotated)
+	}
+	send(http.MethodPost, path+"/ping", "", nil)
+	if strings.Count(signature, "v1=") != 2 {
+		t.Errorf("Expected both secrets to sign during the grace period, got %s", signature)
+	}
+
+	var detail models.WebhookSubscription
+	getJSON(t, r, path, &detail)
+	if detail.Stats == nil || detail.Stats.Deliveries != 2 || detail.Stats.Sent != 2 || detail.Stats.SuccessRate != 100 || detail.Secret != "" {
+		t.Errorf("Expected 2 successful deliveries and no secret, got %+v", detail)
+	}
+	var deliveries struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}
+	getJSON(t, r, path+"/deliveries", &deliveries)
+	if len(deliveries.Deliveries) != 2 {
+		t.Errorf("Expected 2 deliveries listed, got %d", len(deliveries.Deliveries))
+	}
+
+	if code := send(http.MethodDelete, path, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting, got %d", code)
+	}
+	if code := getJSON(t, r, path, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after deleting, got %d", code)
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WriteOffPayout closes out a failed payout that will not be paid. The
+// request needs a reason code and an approver other than the X-Operator
+// sending it.
+// POST /api/v1/payouts/:id/write-off
+func (h *Handler) WriteOffPayout(c *gin.Context) {
+	payoutID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_payout_id")})
+		return
+	}
+	var req models.WriteOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(c, err)})
+		return
+	}
+	requestedBy := actor(c)
+	if req.ApprovedBy == requestedBy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.write_off_self_approved")})
+		return
+	}
+
+	writeOff, err := h.repo.WriteOffPayout(c.Request.Context(), payoutID, req, requestedBy)
+	switch {
+	case errors.Is(err, repository.ErrPayoutNotWriteOffable):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "error.payout_not_write_offable")})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	case writeOff == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "error.payout_not_found")})
+		return
+	}
+	c.JSON(http.StatusOK, writeOff)
+}
+
+// GetWriteOffReport totals written-off amounts per period and currency.
+// from and to are dates (UTC); to is exclusive.
+// GET /api/v1/reports/write-offs?interval=month&from=2024-01-01&to=2024-07-01
+func (h *Handler) GetWriteOffReport(c *gin.Context) {
+	report := models.WriteOffReport{
+		Interval:    c.DefaultQuery("interval", models.IntervalMonth),
+		GeneratedAt: h.cfg.Clock.Now().UTC(),
+	}
+	switch report.Interval {
+	case models.IntervalDay, models.IntervalWeek, models.IntervalMonth:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_interval")})
+		return
+	}
+	for param, dst := range map[string]**time.Time{"from": &report.From, "to": &report.To} {
+		if v := c.Query(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "error.invalid_date", param)})
+				return
+			}
+			*dst = &date
+		}
+	}
+
+	periods, err := h.repo.GetWriteOffTotals(c.Request.Context(), report.Interval, report.From, report.To)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	report.Periods = periods
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,123 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/api"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestWriteOffValidation verifies write-off requests and report queries are
+// checked before anything is looked up.
+func TestWriteOffValidation(t *testing.T) {
+	r := api.SetupRouter(nil, worker.NewPool(nil, 1, 10), api.DefaultConfig())
+
+	path := "/api/v1/payouts/" + uuid.New().String() + "/write-off"
+	cases := []struct {
+		name, body, want string
+	}{
+		{"no reason", `{"approved_by": "lead@example.com"}`, "reason_code is required"},
+		{"unknown reason", `{"reason_code": "bored", "approved_by": "lead@example.com"}`, "reason_code must be one of"},
+		{"no approver", `{"reason_code": "account_closed"}`, "approved_by is required"},
+		{"self-approved", `{"reason_code": "account_closed", "approved_by": "ops@example.com"}`, "someone other than the requesting operator"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tc.body))
+		req.Header.Set("X-Operator", "ops@example.com")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: expected 400 with %q, got %d: %s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	for _, query := range []string{"?interval=year", "?from=March"} {
+		if code := getJSON(t, r, "/api/v1/reports/write-offs"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, code)
+		}
+	}
+}
+
+// TestWriteOffPayout verifies a failed payout can be written off once, that
+// it stays counted as failed but is reported apart from failed money, and
+// that the report totals it.
+func TestWriteOffPayout(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	r, batchID := processedBatch(t, repo)
+	failed, _, err := repo.GetPayoutsByBatch(context.Background(), batchID, models.PayoutStatusFailed, 1, 10)
+	if err != nil || len(failed) != 1 {
+		t.Fatalf("Expected one failed payout, got %d (%v)", len(failed), err)
+	}
+	completed, _, _ := repo.GetPayoutsByBatch(context.Background(), batchID, models.PayoutStatusCompleted, 1, 10)
+
+	writeOff := func(payoutID uuid.UUID) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payouts/"+payoutID.String()+"/write-off",
+			strings.NewReader(`{"reason_code": "account_closed", "approved_by": "lead@example.com", "note": "vendor closed the account"}`))
+		req.Header.Set("X-Operator", "ops@example.com")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := writeOff(failed[0].ID); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	for name, tc := range map[string]struct {
+		id     uuid.UUID
+		status int
+	}{
+		"again":     {failed[0].ID, http.StatusConflict},
+		"completed": {completed[0].ID, http.StatusConflict},
+		"unknown":   {uuid.New(), http.StatusNotFound},
+	} {
+		if code := writeOff(tc.id); code != tc.status {
+			t.Errorf("%s: expected %d, got %d", name, tc.status, code)
+		}
+	}
+
+	var detail models.PayoutDetail
+	getJSON(t, r, "/api/v1/payouts/"+failed[0].ID.String(), &detail)
+	if detail.Payout.Status != models.PayoutStatusWrittenOff || detail.WriteOff == nil {
+		t.Fatalf("Expected a written-off payout with its write-off, got %s and %v", detail.Payout.Status, detail.WriteOff)
+	}
+	if w := detail.WriteOff; w.ReasonCode != models.WriteOffAccountClosed || w.RequestedBy != "ops@example.com" || w.ApprovedBy != "lead@example.com" {
+		t.Errorf("Expected the reason, requester and approver recorded, got %+v", w)
+	}
+
+	var summary models.BatchSummary
+	getJSON(t, r, "/api/v1/batches/"+batchID.String(), &summary)
+	if summary.Statistics.Failed != 1 || summary.Statistics.WrittenOff != 1 {
+		t.Errorf("Expected 1 failed payout, written off, got %+v", summary.Statistics)
+	}
+	var financials models.BatchFinancialSummary
+	getJSON(t, r, "/api/v1/batches/"+batchID.String()+"/financials", &financials)
+	if totals := financials.Currencies[0]; totals.FailedAmount != 0 || totals.WrittenOffAmount != 100 {
+		t.Errorf("Expected 100 written off and nothing failed, got %+v", totals)
+	}
+	verification, err := repo.VerifyBatch(context.Background(), batchID)
+	if err != nil || !verification.Consistent {
+		t.Errorf("Expected the batch to stay consistent, got %+v (%v)", verification, err)
+	}
+
+	var report models.WriteOffReport
+	getJSON(t, r, "/api/v1/reports/write-offs?interval=day", &report)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if len(report.Periods) != 1 || !report.Periods[0].PeriodStart.Equal(today) ||
+		report.Periods[0].Currency != "USD" || report.Periods[0].Amount != 100 {
+		t.Errorf("Expected 100 USD written off today, got %+v", report.Periods)
+	}
+	getJSON(t, r, "/api/v1/reports/write-offs?from="+today.AddDate(0, 0, 1).Format("2006-01-02"), &report)
+	if len(report.Periods) != 0 {
+		t.Errorf("Expected no write-offs from tomorrow, got %+v", report.Periods)
+	}
+}
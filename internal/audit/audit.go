@@ -0,0 +1,142 @@
+// Package audit writes a tamper-evident copy of payout attempts and operator
+// actions to append-only storage, for deployments that must prove records
+// were not changed after the fact.
+//
+// Every record carries the SHA-256 of its own content chained with the hash
+// of the record before it, so editing, removing or reordering any record
+// breaks every hash after it. Verify walks the chain. Removing the newest
+// records leaves a valid, shorter chain; keep the head hash elsewhere (e.g.
+// in reports) to detect that too.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Record kinds.
+const (
+	KindPayoutAttempt     = "payout_attempt"
+	KindRunStarted        = "run_started"
+	KindRunFinished       = "run_finished"
+	KindBatchDeleted      = "batch_deleted"
+	KindBatchRestored     = "batch_restored"
+	KindBatchAssigned     = "batch_assigned"
+	KindBatchCancelled    = "batch_cancelled"
+	KindForceComplete     = "payout_force_completed"
+	KindManualSettle      = "batch_settled"
+	KindPayoutRequeued    = "payout_requeued"
+	KindPayoutWrittenOff  = "payout_written_off"
+	KindPayoutBulkAction  = "payout_bulk_action"
+	KindPayoutCancelled   = "payout_cancelled"
+	KindPaymentFile       = "payment_file"
+	KindBankFile          = "bank_file"
+	KindReportingApproved = "payout_reporting_approved"
+)
+
+// Genesis is the previous hash of the first record.
+const Genesis = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Record is one entry of the append-only log.
+type Record struct {
+	Seq        int64           `json:"seq"`
+	Kind       string          `json:"kind"`
+	SubjectID  uuid.UUID       `json:"subject_id"`
+	Payload    json.RawMessage `json:"payload"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// Store is append-only storage for audit records. Implementations must not
+// offer a way to change or remove a record once appended.
+type Store interface {
+	// Append adds a record of kind about subject with payload encoded as JSON.
+	Append(ctx context.Context, kind string, subject uuid.UUID, payload any) error
+}
+
+// Hash returns the chained hash of a record's content given the previous
+// record's hash (Genesis for the first record).
+func Hash(prevHash string, kind string, subject uuid.UUID, payload []byte, recordedAt time.Time) (string, error) {
+	prev, err := hex.DecodeString(prevHash)
+	if err != nil || len(prev) != sha256.Size {
+		return "", fmt.Errorf("invalid previous hash %q", prevHash)
+	}
+	h := sha256.New()
+	h.Write(prev)
+	for _, part := range [][]byte{[]byte(kind), subject[:], payload, []byte(recordedAt.UTC().Format(time.RFC3339Nano))} {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyResult is the outcome of checking a chain of records.
+type VerifyResult struct {
+	Records  int    `json:"records"`
+	Valid    bool   `json:"valid"`
+	Head     string `json:"head,omitempty"`      // hash of the last valid record
+	BrokenAt int64  `json:"broken_at,omitempty"` // seq of the first record that does not verify
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Verifier checks records one at a time, in sequence order and starting
+// with the first ever appended. Sequence numbers may have gaps (e.g. from
+// rolled-back appends); the hashes are what link records.
+type Verifier struct {
+	res  VerifyResult
+	prev string
+}
+
+// NewVerifier starts a chain check.
+func NewVerifier() *Verifier {
+	return &Verifier{res: VerifyResult{Valid: true}, prev: Genesis}
+}
+
+// Add checks the next record and reports whether the chain is still intact.
+// Records after the first broken one are ignored.
+func (v *Verifier) Add(rec Record) bool {
+	if !v.res.Valid {
+		return false
+	}
+	v.res.Records++
+	fail := func(reason string) bool {
+		v.res.Valid, v.res.BrokenAt, v.res.Reason = false, rec.Seq, reason
+		return false
+	}
+	if rec.PrevHash != v.prev {
+		return fail("previous hash does not match the record before it")
+	}
+	want, err := Hash(rec.PrevHash, rec.Kind, rec.SubjectID, rec.Payload, rec.RecordedAt)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if want != rec.Hash {
+		return fail("content does not match its hash")
+	}
+	v.prev = rec.Hash
+	v.res.Head = rec.Hash
+	return true
+}
+
+// Result returns the outcome so far.
+func (v *Verifier) Result() VerifyResult {
+	return v.res
+}
+
+// Verify checks that records form an unbroken chain.
+func Verify(records []Record) VerifyResult {
+	v := NewVerifier()
+	for _, rec := range records {
+		if !v.Add(rec) {
+			break
+		}
+	}
+	return v.Result()
+}
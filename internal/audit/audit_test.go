@@ -0,0 +1,70 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+// chain builds n valid records.
+func chain(t *testing.T, n int) []audit.Record {
+	t.Helper()
+	records := make([]audit.Record, n)
+	prev := audit.Genesis
+	at := time.Date(2026, 10, 15, 9, 0, 0, 123456000, time.UTC)
+	for i := range records {
+		rec := audit.Record{
+			Seq:        int64(i + 1),
+			Kind:       audit.KindPayoutAttempt,
+			SubjectID:  uuid.New(),
+			Payload:    json.RawMessage(`{"attempt_num":1,"status":"completed"}`),
+			RecordedAt: at.Add(time.Duration(i) * time.Second),
+			PrevHash:   prev,
+		}
+		hash, err := audit.Hash(rec.PrevHash, rec.Kind, rec.SubjectID, rec.Payload, rec.RecordedAt)
+		if err != nil {
+			t.Fatalf("Hash failed: %v", err)
+		}
+		rec.Hash = hash
+		prev = hash
+		records[i] = rec
+	}
+	return records
+}
+
+// TestVerifyDetectsTampering verifies edits, removals and reordering break
+// the chain at the first affected record.
+func TestVerifyDetectsTampering(t *testing.T) {
+	if res := audit.Verify(chain(t, 5)); !res.Valid || res.Records != 5 {
+		t.Fatalf("Expected a valid chain of 5, got %+v", res)
+	}
+
+	edited := chain(t, 5)
+	edited[2].Payload = json.RawMessage(`{"attempt_num":1,"status":"failed"}`)
+	if res := audit.Verify(edited); res.Valid || res.BrokenAt != 3 {
+		t.Errorf("Expected an edit to break the chain at 3, got %+v", res)
+	}
+
+	removed := chain(t, 5)
+	removed = append(removed[:1], removed[2:]...)
+	if res := audit.Verify(removed); res.Valid || res.BrokenAt != 3 {
+		t.Errorf("Expected a removal to break the chain at 3, got %+v", res)
+	}
+
+	swapped := chain(t, 5)
+	swapped[3], swapped[4] = swapped[4], swapped[3]
+	if res := audit.Verify(swapped); res.Valid || res.BrokenAt != 5 {
+		t.Errorf("Expected reordering to break the chain at 5, got %+v", res)
+	}
+
+	// A rolled-back append leaves a sequence gap, which is fine.
+	gapped := chain(t, 3)
+	gapped[2].Seq = 10
+	if res := audit.Verify(gapped); !res.Valid {
+		t.Errorf("Expected a sequence gap to verify, got %+v", res)
+	}
+}
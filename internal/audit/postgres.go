@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// appendLockKey serializes appends so each record chains to the one before.
+const appendLockKey = 7302
+
+// Postgres stores records in audit.records, which a trigger keeps
+// append-only (see migrations/014_audit_log.sql). For full protection, run
+// the server as a role granted only INSERT and SELECT on it.
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres returns a store writing to audit.records in db.
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Append adds a record, chained to the latest one.
+func (p *Postgres) Append(ctx context.Context, kind string, subject uuid.UUID, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode audit payload: %w", err)
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, appendLockKey); err != nil {
+		return fmt.Errorf("lock audit log: %w", err)
+	}
+	prev := Genesis
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit.records ORDER BY seq DESC LIMIT 1`).Scan(&prev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("read audit head: %w", err)
+	}
+
+	// PostgreSQL keeps microseconds; hash what will be read back.
+	recordedAt := time.Now().UTC().Truncate(time.Microsecond)
+	hash, err := Hash(prev, kind, subject, body, recordedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO audit.records (kind, subject_id, payload, recorded_at, prev_hash, hash)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		kind, subject, string(body), recordedAt, prev, hash,
+	); err != nil {
+		return fmt.Errorf("append audit record: %w", err)
+	}
+	return tx.Commit()
+}
+
+// List returns the records about subject, oldest first.
+func (p *Postgres) List(ctx context.Context, subject uuid.UUID) ([]Record, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT seq, kind, subject_id, payload, recorded_at, prev_hash, hash
+		 FROM audit.records WHERE subject_id = $1 ORDER BY seq`, subject)
+	if err != nil {
+		return nil, fmt.Errorf("query audit records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// Verify walks the whole chain.
+func (p *Postgres) Verify(ctx context.Context) (VerifyResult, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT seq, kind, subject_id, payload, recorded_at, prev_hash, hash
+		 FROM audit.records ORDER BY seq`)
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("query audit records: %w", err)
+	}
+	defer rows.Close()
+
+	v := NewVerifier()
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return VerifyResult{}, err
+		}
+		if !v.Add(rec) {
+			break
+		}
+	}
+	return v.Result(), rows.Err()
+}
+
+func scanRecord(rows *sql.Rows) (Record, error) {
+	var rec Record
+	var payload []byte
+	if err := rows.Scan(&rec.Seq, &rec.Kind, &rec.SubjectID, &payload, &rec.RecordedAt, &rec.PrevHash, &rec.Hash); err != nil {
+		return rec, fmt.Errorf("scan audit record: %w", err)
+	}
+	rec.Payload = payload
+	return rec, nil
+}
@@ -0,0 +1,52 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/database/dbtest"
+
+	"github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	dbtest.Close()
+	os.Exit(code)
+}
+
+// TestPostgresAppendOnly verifies appended records chain and verify, and
+// that the table refuses updates and deletes.
+func TestPostgresAppendOnly(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	store := audit.NewPostgres(db)
+	batchID := uuid.New()
+	for i := 0; i < 3; i++ {
+		if err := store.Append(ctx, audit.KindRunStarted, batchID, map[string]any{"run": i, "note": "ünïcode"}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	res, err := store.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !res.Valid || res.Records != 3 {
+		t.Errorf("Expected 3 valid records, got %+v", res)
+	}
+	records, err := store.List(ctx, batchID)
+	if err != nil || len(records) != 3 || records[2].Hash != res.Head {
+		t.Errorf("Expected 3 records ending at the head, got %d (%v)", len(records), err)
+	}
+
+	if _, err := db.Exec(`UPDATE audit.records SET payload = '{}'`); err == nil {
+		t.Error("Expected UPDATE on audit.records to be rejected")
+	}
+	if _, err := db.Exec(`DELETE FROM audit.records`); err == nil {
+		t.Error("Expected DELETE on audit.records to be rejected")
+	}
+}
@@ -0,0 +1,230 @@
+// Package bankfile reads the acknowledgment and return files banks send
+// back for payment files: ISO 20022 pain.002 payment status reports and
+// NACHA return files. Each is turned into the bank's answer per payout,
+// keyed by the payout's payment file reference, for the repository to
+// apply.
+package bankfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownFormat is returned by Parse for a file that is neither a
+// pain.002 report nor a NACHA file.
+var ErrUnknownFormat = errors.New("not a pain.002 or NACHA return file")
+
+// nachaRefLen is how much of a payout reference fits a NACHA individual
+// identification number.
+const nachaRefLen = 15
+
+// Reference returns a payout's reference in bank files: its ID as 32
+// lowercase hex digits, which fits a pain.001 EndToEndId. NACHA entries
+// carry its first 15 digits.
+func Reference(payoutID uuid.UUID) string {
+	return strings.ReplaceAll(payoutID.String(), "-", "")
+}
+
+// normalizeRef turns a reference as the bank echoed it into the form
+// Reference returns. One that cannot be a payout reference is kept as is,
+// to be reported as unmatched.
+func normalizeRef(raw string) string {
+	raw = strings.TrimSpace(raw)
+	ref := strings.ToLower(strings.ReplaceAll(raw, "-", ""))
+	if len(ref) < nachaRefLen || len(ref) > 32 {
+		return raw
+	}
+	for _, c := range ref {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return raw
+		}
+	}
+	return ref
+}
+
+// Parse reads a bank file in either format, told apart by its content. The
+// returned file has no ID, checksum or receipt yet.
+func Parse(data []byte) (*models.BankFile, error) {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return ParsePain002(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("1")):
+		return ParseNACHAReturn(trimmed)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// --- pain.002 ---
+
+// pain002 is the part of a CustomerPaymentStatusReport read here. Element
+// names match in any version (namespace) of the message.
+type pain002 struct {
+	Report struct {
+		MsgID string `xml:"GrpHdr>MsgId"`
+		Group struct {
+			OrgnlMsgID string      `xml:"OrgnlMsgId"`
+			GrpSts     string      `xml:"GrpSts"`
+			Reasons    []statusRsn `xml:"StsRsnInf"`
+		} `xml:"OrgnlGrpInfAndSts"`
+		Payments []struct {
+			PmtInfSts string      `xml:"PmtInfSts"`
+			Reasons   []statusRsn `xml:"StsRsnInf"`
+			Txs       []struct {
+				OrgnlEndToEndID string      `xml:"OrgnlEndToEndId"`
+				TxSts           string      `xml:"TxSts"`
+				Reasons         []statusRsn `xml:"StsRsnInf"`
+			} `xml:"TxInfAndSts"`
+		} `xml:"OrgnlPmtInfAndSts"`
+	} `xml:"CstmrPmtStsRpt"`
+}
+
+type statusRsn struct {
+	Code        string `xml:"Rsn>Cd"`
+	Proprietary string `xml:"Rsn>Prtry"`
+}
+
+// reason returns the first reason code given, if any.
+func reason(rs []statusRsn) string {
+	for _, r := range rs {
+		if code := strings.TrimSpace(r.Code + r.Proprietary); code != "" {
+			return code
+		}
+	}
+	return ""
+}
+
+// painResult maps an ISO 20022 status code to a payment file answer; ""
+// means the bank has not decided yet (e.g. PDNG, ACTC) or, for a group,
+// answered per transaction (PART).
+func painResult(status string) string {
+	switch strings.TrimSpace(status) {
+	case "ACCP", "ACSP", "ACSC", "ACWC", "ACCC":
+		return models.PaymentFileAccepted
+	case "RJCT":
+		return models.PaymentFileRejected
+	default:
+		return ""
+	}
+}
+
+// ParsePain002 reads a pain.002 customer payment status report. A
+// transaction's status falls back to its payment information's, and a
+// group status answers for the payment file named by OrgnlMsgId, which is
+// the payment file's ID. Transactions without a decided status are counted
+// as pending.
+func ParsePain002(data []byte) (*models.BankFile, error) {
+	var doc pain002
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("read pain.002: %w", err)
+	}
+	rpt := doc.Report
+	if rpt.MsgID == "" && rpt.Group.OrgnlMsgID == "" {
+		return nil, errors.New("read pain.002: no CstmrPmtStsRpt group header")
+	}
+
+	f := &models.BankFile{Format: models.BankFilePain002, MessageID: strings.TrimSpace(rpt.MsgID), Answers: []models.BankFileAnswer{}}
+	if result := painResult(rpt.Group.GrpSts); result != "" {
+		id, err := uuid.Parse(strings.TrimSpace(rpt.Group.OrgnlMsgID))
+		if err != nil {
+			return nil, fmt.Errorf("read pain.002: group status %s for OrgnlMsgId %q, which is not a payment file ID",
+				rpt.Group.GrpSts, rpt.Group.OrgnlMsgID)
+		}
+		f.PaymentFileID, f.FileResult, f.FileReason = &id, result, reason(rpt.Group.Reasons)
+	}
+	for _, pmt := range rpt.Payments {
+		for _, tx := range pmt.Txs {
+			status, reasons := tx.TxSts, tx.Reasons
+			if strings.TrimSpace(status) == "" {
+				status, reasons = pmt.PmtInfSts, pmt.Reasons
+			}
+			result := painResult(status)
+			if result == "" {
+				f.Pending++
+				continue
+			}
+			f.Answers = append(f.Answers, models.BankFileAnswer{
+				Reference: normalizeRef(tx.OrgnlEndToEndID),
+				Result:    result,
+				Reason:    reason(reasons),
+			})
+		}
+	}
+	return f, nil
+}
+
+// --- NACHA ---
+
+// NACHA record layout: 94-character records; positions are 1-based in the
+// spec and 0-based here.
+const (
+	nachaRecordLen = 94
+
+	nachaFileHeader  = '1'
+	nachaEntryDetail = '6'
+	nachaAddenda     = '7'
+
+	nachaReturnAddenda = "99" // addenda type code of a return
+)
+
+// ParseNACHAReturn reads a NACHA file of returned entries (R01, R03...).
+// Every entry with a return addenda is a rejection of the payout whose
+// reference starts with the entry's individual identification number, for
+// the return reason code. Entries without one, such as notifications of
+// change, are not answers and are skipped.
+func ParseNACHAReturn(data []byte) (*models.BankFile, error) {
+	f := &models.BankFile{Format: models.BankFileNACHAReturn, Answers: []models.BankFileAnswer{}}
+
+	var entryRef string // individual ID of the entry awaiting its addenda
+	line := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line++
+		rec := strings.TrimRight(sc.Text(), " \r")
+		if rec == "" {
+			continue
+		}
+		if len(rec) < nachaRecordLen {
+			// Trailing blanks are often trimmed on the way.
+			rec += strings.Repeat(" ", nachaRecordLen-len(rec))
+		}
+		if len(rec) != nachaRecordLen {
+			return nil, fmt.Errorf("read NACHA: line %d is %d characters, want %d", line, len(rec), nachaRecordLen)
+		}
+		if line == 1 && rec[0] != nachaFileHeader {
+			return nil, errors.New("read NACHA: no file header record")
+		}
+		switch rec[0] {
+		case nachaFileHeader:
+			// Immediate origin, creation date and time, file ID modifier
+			f.MessageID = strings.TrimSpace(rec[13:23]) + "-" + rec[23:33] + rec[33:34]
+		case nachaEntryDetail:
+			entryRef = strings.TrimSpace(rec[39:54])
+		case nachaAddenda:
+			if rec[1:3] != nachaReturnAddenda {
+				continue
+			}
+			f.Answers = append(f.Answers, models.BankFileAnswer{
+				Reference: normalizeRef(entryRef),
+				Result:    models.PaymentFileRejected,
+				Reason:    strings.TrimSpace(rec[3:6]),
+			})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read NACHA: %w", err)
+	}
+	if line == 0 {
+		return nil, errors.New("read NACHA: empty file")
+	}
+	return f, nil
+}
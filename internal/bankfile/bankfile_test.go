@@ -0,0 +1,132 @@
+package bankfile_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/bankfile"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// record lays fields out in a 94-character NACHA record, by 0-based offset.
+func record(fields map[int]string) string {
+	rec := []byte(strings.Repeat(" ", 94))
+	for at, v := range fields {
+		copy(rec[at:], v)
+	}
+	return string(rec)
+}
+
+func TestReference(t *testing.T) {
+	id := uuid.MustParse("6F1C2A9E-0B3D-4E5F-8A7B-1C2D3E4F5A6B")
+	if got := bankfile.Reference(id); got != "6f1c2a9e0b3d4e5f8a7b1c2d3e4f5a6b" {
+		t.Errorf("Expected the ID as 32 hex digits, got %q", got)
+	}
+}
+
+func TestParsePain002(t *testing.T) {
+	fileID := uuid.New()
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.03">
+  <CstmrPmtStsRpt>
+    <GrpHdr><MsgId>BANK-STS-0001</MsgId></GrpHdr>
+    <OrgnlGrpInfAndSts>
+      <OrgnlMsgId>` + fileID.String() + `</OrgnlMsgId>
+      <GrpSts>ACCP</GrpSts>
+    </OrgnlGrpInfAndSts>
+    <OrgnlPmtInfAndSts>
+      <PmtInfSts>ACSC</PmtInfSts>
+      <TxInfAndSts><OrgnlEndToEndId>AAAAAAAA-BBBB-CCCC-DDDD-000000000001</OrgnlEndToEndId></TxInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>aaaaaaaabbbbccccdddd000000000002</OrgnlEndToEndId>
+        <TxSts>RJCT</TxSts>
+        <StsRsnInf><Rsn><Cd>AC04</Cd></Rsn></StsRsnInf>
+      </TxInfAndSts>
+      <TxInfAndSts><OrgnlEndToEndId>aaaaaaaabbbbccccdddd000000000003</OrgnlEndToEndId><TxSts>PDNG</TxSts></TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+  </CstmrPmtStsRpt>
+</Document>`
+
+	f, err := bankfile.Parse([]byte("\xef\xbb\xbf\n" + doc))
+	if err != nil {
+		t.Fatalf("Expected the report to parse, got %v", err)
+	}
+	if f.Format != models.BankFilePain002 || f.MessageID != "BANK-STS-0001" {
+		t.Errorf("Expected a pain.002 BANK-STS-0001, got %s %q", f.Format, f.MessageID)
+	}
+	if f.PaymentFileID == nil || *f.PaymentFileID != fileID || f.FileResult != models.PaymentFileAccepted {
+		t.Errorf("Expected the group status to accept file %s, got %v %q", fileID, f.PaymentFileID, f.FileResult)
+	}
+	if f.Pending != 1 {
+		t.Errorf("Expected 1 pending transaction, got %d", f.Pending)
+	}
+	want := []models.BankFileAnswer{
+		{Reference: "aaaaaaaabbbbccccdddd000000000001", Result: models.PaymentFileAccepted},
+		{Reference: "aaaaaaaabbbbccccdddd000000000002", Result: models.PaymentFileRejected, Reason: "AC04"},
+	}
+	if len(f.Answers) != len(want) {
+		t.Fatalf("Expected %d answers, got %+v", len(want), f.Answers)
+	}
+	for i, w := range want {
+		if a := f.Answers[i]; a.Reference != w.Reference || a.Result != w.Result || a.Reason != w.Reason {
+			t.Errorf("Expected answer %d to be %+v, got %+v", i, w, a)
+		}
+	}
+}
+
+func TestParsePain002GroupWithoutFile(t *testing.T) {
+	doc := `<Document><CstmrPmtStsRpt><GrpHdr><MsgId>X</MsgId></GrpHdr>` +
+		`<OrgnlGrpInfAndSts><OrgnlMsgId>BATCH-7</OrgnlMsgId><GrpSts>RJCT</GrpSts></OrgnlGrpInfAndSts>` +
+		`</CstmrPmtStsRpt></Document>`
+	if _, err := bankfile.Parse([]byte(doc)); err == nil {
+		t.Error("Expected a group status for an unknown message ID to be refused")
+	}
+}
+
+func TestParseNACHAReturn(t *testing.T) {
+	lines := []string{
+		record(map[int]string{0: "101", 3: " 091000019", 13: "1234567890", 23: "2610151200", 33: "A", 34: "094101"}),
+		record(map[int]string{0: "5220"}),
+		record(map[int]string{0: "6", 1: "22", 39: "AAAAAAAABBBBCCC", 54: "Toko Batik"}),
+		record(map[int]string{0: "799", 3: "R03", 6: "091000019999999"}),
+		// A notification of change is not an answer.
+		record(map[int]string{0: "6", 1: "21", 39: "AAAAAAAABBBBDDD"}),
+		record(map[int]string{0: "798", 3: "C01"}),
+		// Trailing blanks trimmed on the way.
+		strings.TrimRight(record(map[int]string{0: "6", 1: "22", 39: "aaaaaaaabbbbeee"}), " "),
+		strings.TrimRight(record(map[int]string{0: "799", 3: "R01"}), " "),
+		record(map[int]string{0: "9"}),
+	}
+	f, err := bankfile.Parse([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+	if err != nil {
+		t.Fatalf("Expected the return file to parse, got %v", err)
+	}
+	if f.Format != models.BankFileNACHAReturn || f.MessageID != "1234567890-2610151200A" {
+		t.Errorf("Expected a NACHA return 1234567890-2610151200A, got %s %q", f.Format, f.MessageID)
+	}
+	want := []models.BankFileAnswer{
+		{Reference: "aaaaaaaabbbbccc", Result: models.PaymentFileRejected, Reason: "R03"},
+		{Reference: "aaaaaaaabbbbeee", Result: models.PaymentFileRejected, Reason: "R01"},
+	}
+	if len(f.Answers) != len(want) {
+		t.Fatalf("Expected %d answers, got %+v", len(want), f.Answers)
+	}
+	for i, w := range want {
+		if a := f.Answers[i]; a.Reference != w.Reference || a.Result != w.Result || a.Reason != w.Reason {
+			t.Errorf("Expected answer %d to be %+v, got %+v", i, w, a)
+		}
+	}
+}
+
+func TestParseRejectsOtherFiles(t *testing.T) {
+	if _, err := bankfile.Parse([]byte("vendor_id,amount\nV-1,10\n")); !errors.Is(err, bankfile.ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat for a CSV, got %v", err)
+	}
+	long := record(map[int]string{0: "101"}) + "overflow"
+	if _, err := bankfile.Parse([]byte(long)); err == nil {
+		t.Error("Expected a record longer than 94 characters to be refused")
+	}
+}
@@ -0,0 +1,183 @@
+// Package clock abstracts the current time and timers so retry timing,
+// stall detection, cutoffs and scheduling can be tested deterministically
+// with a Fake instead of real sleeps.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates timers.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer that fires once d has passed on this clock.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a one-shot timer, like time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if the timer
+	// already fired or was stopped.
+	Stop() bool
+}
+
+// Real is the system clock.
+var Real Clock = realClock{}
+
+// OrReal returns c, or Real if c is nil, for optional Clock fields.
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+// Accelerated is a Clock running speed times as fast as the system clock
+// from the moment it is created, for demos: at 1440 a day passes in a
+// minute, so retries scheduled for tomorrow and waits of hours happen in
+// seconds. Durations measured on it are scaled alike.
+type Accelerated struct {
+	start time.Time
+	speed float64
+}
+
+// NewAccelerated returns a clock starting at the current time and running
+// speed times as fast; speed must be positive.
+func NewAccelerated(speed float64) *Accelerated {
+	return &Accelerated{start: time.Now(), speed: speed}
+}
+
+// Speed returns how many times faster than real time the clock runs.
+func (a *Accelerated) Speed() float64 { return a.speed }
+
+// Now returns the accelerated time.
+func (a *Accelerated) Now() time.Time {
+	return a.start.Add(time.Duration(float64(time.Since(a.start)) * a.speed))
+}
+
+// NewTimer returns a timer that fires once d has passed on the accelerated
+// clock, d/speed in real time, delivering the accelerated time.
+func (a *Accelerated) NewTimer(d time.Duration) Timer {
+	c := make(chan time.Time, 1)
+	t := time.AfterFunc(time.Duration(float64(d)/a.speed), func() { c <- a.Now() })
+	return acceleratedTimer{t: t, c: c}
+}
+
+type acceleratedTimer struct {
+	t *time.Timer
+	c chan time.Time
+}
+
+func (t acceleratedTimer) C() <-chan time.Time { return t.c }
+func (t acceleratedTimer) Stop() bool          { return t.t.Stop() }
+
+// Fake is a Clock that only moves when told to. Timers fire when Advance or
+// Set moves the time to or past their deadline. It is safe for concurrent use.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	added  chan struct{} // signalled when a timer is created
+}
+
+// NewFake returns a fake clock stopped at now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, added: make(chan struct{}, 1)}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer returns a timer that fires once the fake time reaches now+d. A
+// timer with d <= 0 fires immediately.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{f: f, at: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	select {
+	case f.added <- struct{}{}:
+	default:
+	}
+	return t
+}
+
+// Advance moves the fake time forward by d, firing due timers in deadline order.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the fake time to t, firing due timers in deadline order. Moving
+// it backwards fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+	sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].at.Before(f.timers[j].at) })
+	pending := f.timers[:0]
+	for _, timer := range f.timers {
+		if timer.at.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- timer.at
+	}
+	f.timers = pending
+}
+
+// Waiters returns the number of timers that have not fired or been stopped.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil waits until at least n timers are pending, so a test can
+// advance the clock only once the code under test is waiting on it.
+func (f *Fake) BlockUntil(n int) {
+	for f.Waiters() < n {
+		<-f.added
+	}
+}
+
+type fakeTimer struct {
+	f  *Fake
+	at time.Time
+	c  chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	for i, timer := range t.f.timers {
+		if timer == t {
+			t.f.timers = append(t.f.timers[:i], t.f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"coding-challenge/internal/clock"
+)
+
+var epoch = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// TestFakeTimersFireOnAdvance verifies fake timers fire only once the clock
+// reaches their deadline, and report the deadline as the fire time.
+func TestFakeTimersFireOnAdvance(t *testing.T) {
+	c := clock.NewFake(epoch)
+	short := c.NewTimer(time.Minute)
+	long := c.NewTimer(time.Hour)
+
+	c.Advance(30 * time.Second)
+	select {
+	case <-short.C():
+		t.Fatal("Expected the timer not to fire before its deadline")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	if got := <-short.C(); !got.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("Expected fire time %s, got %s", epoch.Add(time.Minute), got)
+	}
+	if c.Waiters() != 1 {
+		t.Errorf("Expected 1 pending timer, got %d", c.Waiters())
+	}
+
+	c.Set(epoch.Add(2 * time.Hour))
+	<-long.C()
+	if got := c.Now(); !got.Equal(epoch.Add(2 * time.Hour)) {
+		t.Errorf("Expected now %s, got %s", epoch.Add(2*time.Hour), got)
+	}
+}
+
+// TestFakeTimerStop verifies a stopped timer never fires.
+func TestFakeTimerStop(t *testing.T) {
+	c := clock.NewFake(epoch)
+	timer := c.NewTimer(time.Minute)
+	if !timer.Stop() {
+		t.Error("Expected Stop to report the timer as pending")
+	}
+	if timer.Stop() {
+		t.Error("Expected a second Stop to report false")
+	}
+
+	c.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Error("Expected a stopped timer not to fire")
+	default:
+	}
+}
+
+// TestFakeBlockUntil verifies a test can wait for code to start waiting on
+// the clock before advancing it.
+func TestFakeBlockUntil(t *testing.T) {
+	c := clock.NewFake(epoch)
+	done := make(chan time.Time)
+	go func() {
+		done <- <-c.NewTimer(5 * time.Second).C()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(5 * time.Second)
+	select {
+	case got := <-done:
+		if !got.Equal(epoch.Add(5 * time.Second)) {
+			t.Errorf("Expected fire time %s, got %s", epoch.Add(5*time.Second), got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiting goroutine to wake up")
+	}
+}
+
+// TestAccelerated verifies an accelerated clock's time and timers run at
+// its speed: an hour's timer fires within a fraction of a second at 36000x.
+func TestAccelerated(t *testing.T) {
+	c := clock.NewAccelerated(36000)
+	start := c.Now()
+	realStart := time.Now()
+
+	timer := c.NewTimer(time.Hour)
+	select {
+	case fired := <-timer.C():
+		if elapsed := fired.Sub(start); elapsed < time.Hour {
+			t.Errorf("Expected the timer to fire an hour on, got %s", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected an hour's timer to fire within 2s at 36000x")
+	}
+	if real := time.Since(realStart); real < 50*time.Millisecond {
+		t.Errorf("Expected an hour to take about 100ms of real time, took %s", real)
+	}
+
+	stopped := c.NewTimer(time.Hour)
+	if !stopped.Stop() {
+		t.Error("Expected Stop to report the timer as pending")
+	}
+}
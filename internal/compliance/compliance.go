@@ -0,0 +1,65 @@
+// Package compliance decides which payouts must be flagged for regulatory
+// reporting: those above a country's threshold, which some regulators also
+// want approved by a second person before they are sent.
+package compliance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"coding-challenge/internal/models"
+)
+
+// Rules are the reporting thresholds and whether flagged payouts wait for
+// an approval before they are processed. The zero value flags nothing.
+type Rules struct {
+	Thresholds      []models.ReportingThreshold
+	RequireApproval bool
+}
+
+// ParseThresholds parses per-country thresholds in the form
+// "ID:IDR=100000000,PH:PHP=500000".
+func ParseThresholds(spec string) ([]models.ReportingThreshold, error) {
+	var thresholds []models.ReportingThreshold
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, amount, ok := strings.Cut(entry, "=")
+		country, currency, ok2 := strings.Cut(strings.ToUpper(strings.TrimSpace(key)), ":")
+		if !ok || !ok2 || len(country) != 2 || len(currency) != 3 {
+			return nil, fmt.Errorf("invalid reporting threshold entry %q (want COUNTRY:CURRENCY=AMOUNT)", entry)
+		}
+		if seen[country+currency] {
+			return nil, fmt.Errorf("reporting threshold for %s:%s given twice", country, currency)
+		}
+		seen[country+currency] = true
+		t := models.ReportingThreshold{Country: country, Currency: currency}
+		var err error
+		if t.Amount, err = strconv.ParseFloat(strings.TrimSpace(amount), 64); err != nil || t.Amount < 0 {
+			return nil, fmt.Errorf("invalid threshold in %q: want an amount >= 0", entry)
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, nil
+}
+
+// Flag returns the country whose threshold an item is above, or "" if it
+// is not reportable. An item's country is its "country" metadata; one
+// without it is held to every threshold in its currency. Split items are
+// judged by their whole amount.
+func (r Rules) Flag(item models.CreatePayoutItem) string {
+	country := strings.ToUpper(strings.TrimSpace(item.Metadata["country"]))
+	for _, t := range r.Thresholds {
+		if t.Currency != strings.ToUpper(item.Currency) || country != "" && country != t.Country {
+			continue
+		}
+		if item.Amount > t.Amount {
+			return t.Country
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package compliance_test
+
+import (
+	"testing"
+
+	"coding-challenge/internal/compliance"
+	"coding-challenge/internal/models"
+)
+
+func TestParseThresholds(t *testing.T) {
+	got, err := compliance.ParseThresholds(" id:idr=100000000, PH:PHP=500000,")
+	if err != nil {
+		t.Fatalf("Expected the thresholds to parse, got %v", err)
+	}
+	want := []models.ReportingThreshold{
+		{Country: "ID", Currency: "IDR", Amount: 100000000},
+		{Country: "PH", Currency: "PHP", Amount: 500000},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d thresholds, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected threshold %d to be %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if got, err := compliance.ParseThresholds(""); err != nil || len(got) != 0 {
+		t.Errorf("Expected no thresholds for an empty spec, got %+v (%v)", got, err)
+	}
+	for _, spec := range []string{"IDR=100", "ID:IDR", "IDN:IDR=100", "ID:IDR=-1", "ID:IDR=lots", "ID:IDR=1,id:idr=2"} {
+		if _, err := compliance.ParseThresholds(spec); err == nil {
+			t.Errorf("Expected %q to be refused", spec)
+		}
+	}
+}
+
+func TestFlag(t *testing.T) {
+	rules := compliance.Rules{Thresholds: []models.ReportingThreshold{
+		{Country: "ID", Currency: "IDR", Amount: 1000},
+		{Country: "VN", Currency: "VND", Amount: 5000},
+	}}
+	cases := []struct {
+		name     string
+		amount   float64
+		currency string
+		country  string
+		want     string
+	}{
+		{"above", 1001, "IDR", "ID", "ID"},
+		{"at the threshold", 1000, "IDR", "ID", ""},
+		{"lowercase", 2000, "idr", "id", "ID"},
+		{"no country", 6000, "VND", "", "VN"},
+		{"other country", 2000, "IDR", "PH", ""},
+		{"no threshold", 1e9, "PHP", "PH", ""},
+	}
+	for _, tc := range cases {
+		item := models.CreatePayoutItem{VendorID: "V-1", Amount: tc.amount, Currency: tc.currency}
+		if tc.country != "" {
+			item.Metadata = map[string]string{"country": tc.country}
+		}
+		if got := rules.Flag(item); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+
+	if got := (compliance.Rules{}).Flag(models.CreatePayoutItem{Amount: 1e12, Currency: "IDR"}); got != "" {
+		t.Errorf("Expected the zero rules to flag nothing, got %q", got)
+	}
+}
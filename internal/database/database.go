@@ -0,0 +1,91 @@
+// Package database opens the service's storage according to configuration.
+//
+// Two drivers are supported. "postgres" (the default) connects to an external
+// PostgreSQL server whose schema is managed by Docker or `make migrate`.
+// "embedded" starts a throwaway PostgreSQL inside the process and applies the
+// migrations itself, so the full API runs without Docker. Both speak the same
+// SQL, so the repository works unchanged on either.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"coding-challenge/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+// Supported drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverEmbedded = "embedded"
+)
+
+// Config describes the database to open.
+type Config struct {
+	Driver   string
+	Host     string // ignored by the embedded driver
+	Port     string
+	User     string
+	Password string
+	Name     string
+}
+
+// DSN returns the lib/pq connection string for the config.
+func (c Config) DSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		c.Host, c.Port, c.User, c.Password, c.Name)
+}
+
+// Open connects to the configured database and verifies it is reachable. The
+// returned close function releases the connection and, for the embedded
+// driver, shuts the server down and discards its data.
+func Open(ctx context.Context, cfg Config) (*sql.DB, func() error, error) {
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		db, err := connect(ctx, cfg.DSN())
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, db.Close, nil
+
+	case DriverEmbedded:
+		stop, err := startEmbedded(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.Host = "localhost"
+		db, err := connect(ctx, cfg.DSN())
+		if err == nil {
+			err = migrations.Apply(ctx, db)
+		}
+		if err != nil {
+			if db != nil {
+				db.Close()
+			}
+			stop()
+			return nil, nil, err
+		}
+		return db, func() error {
+			db.Close()
+			return stop()
+		}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+func connect(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("database unreachable: %w", err)
+	}
+	return db, nil
+}
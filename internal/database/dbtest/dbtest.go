@@ -0,0 +1,157 @@
+// Package dbtest provides the database used by integration tests.
+//
+// By default tests connect to TEST_DB_DSN (or a local kaveri_payouts_test
+// database). With TEST_DB_DRIVER=embedded and -tags embeddeddb, a single
+// embedded PostgreSQL is started per test binary instead; call Close from
+// TestMain to shut it down.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	"coding-challenge/internal/database"
+)
+
+const defaultDSN = "host=localhost port=5432 user=postgres password=postgres dbname=kaveri_payouts_test sslmode=disable"
+
+// Tables in delete order (children before parents).
+var tables = []string{
+	"email_deliveries",
+	"webhook_deliveries",
+	"webhook_subscriptions",
+	"payout_write_offs",
+	"vendor_outreach",
+	"payout_attempts",
+	"payment_file_items",
+	"bank_files",
+	"payment_files",
+	"payouts",
+	"bank_throughput",
+	"batch_runs",
+	"funding_reservations",
+	"payout_batches",
+	"funding_accounts",
+	"import_profiles",
+	"payout_views",
+	"vendor_tax_ids",
+	"api_usage",
+	"job_runs",
+	"request_nonces",
+}
+
+var (
+	once      sync.Once
+	shared    *sql.DB
+	sharedDSN string
+	closeFn   func() error
+	startErr  error
+)
+
+// Open returns a connection to an empty test database, skipping the test
+// when none is reachable. The connection is closed when the test ends.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	var db *sql.DB
+	if embedded() {
+		once.Do(startShared)
+		if startErr != nil {
+			t.Skipf("Skipping integration test: embedded DB unavailable: %v", startErr)
+		}
+		db = shared
+	} else {
+		var err error
+		db, err = sql.Open("postgres", externalDSN())
+		if err != nil {
+			t.Skipf("Skipping integration test: cannot connect to DB: %v", err)
+		}
+		if err := db.Ping(); err != nil {
+			db.Close()
+			t.Skipf("Skipping integration test: DB not reachable: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+	}
+
+	for _, table := range tables {
+		if _, err := db.Exec("DELETE FROM " + table); err != nil {
+			t.Fatalf("Failed to clean %s: %v", table, err)
+		}
+	}
+	if err := cleanAuditLog(db); err != nil {
+		t.Fatalf("Failed to clean audit.records: %v", err)
+	}
+	return db
+}
+
+// cleanAuditLog empties the append-only audit log, bypassing the triggers
+// that protect it (the test user is a superuser).
+func cleanAuditLog(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SET LOCAL session_replication_role = replica`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM audit.records`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Connect opens an additional, independent connection pool to the database
+// returned by Open, for tests that simulate several processes. It does not
+// clean any tables.
+func Connect(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := externalDSN()
+	if embedded() {
+		dsn = sharedDSN
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open extra connection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Close stops the shared embedded database, if one was started.
+func Close() {
+	if closeFn != nil {
+		closeFn()
+	}
+}
+
+func embedded() bool {
+	return os.Getenv("TEST_DB_DRIVER") == database.DriverEmbedded
+}
+
+func externalDSN() string {
+	if dsn := os.Getenv("TEST_DB_DSN"); dsn != "" {
+		return dsn
+	}
+	return defaultDSN
+}
+
+func startShared() {
+	port := os.Getenv("TEST_DB_PORT")
+	if port == "" {
+		port = "54329"
+	}
+	cfg := database.Config{
+		Driver:   database.DriverEmbedded,
+		Host:     "localhost",
+		Port:     port,
+		User:     "postgres",
+		Password: "postgres",
+		Name:     "kaveri_payouts_test",
+	}
+	sharedDSN = cfg.DSN()
+	shared, closeFn, startErr = database.Open(context.Background(), cfg)
+}
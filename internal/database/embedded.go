@@ -0,0 +1,45 @@
+//go:build embeddeddb
+
+package database
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// startEmbedded launches PostgreSQL on cfg.Port with an empty data directory.
+// The first start downloads the PostgreSQL binaries into the user cache.
+func startEmbedded(cfg Config) (func() error, error) {
+	port, err := strconv.ParseUint(cfg.Port, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedded database port %q: %w", cfg.Port, err)
+	}
+
+	runtime, err := os.MkdirTemp("", "payout-db-")
+	if err != nil {
+		return nil, fmt.Errorf("create embedded database dir: %w", err)
+	}
+
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V15).
+		Port(uint32(port)).
+		Username(cfg.User).
+		Password(cfg.Password).
+		Database(cfg.Name).
+		RuntimePath(runtime).
+		Logger(io.Discard))
+	if err := pg.Start(); err != nil {
+		os.RemoveAll(runtime)
+		return nil, fmt.Errorf("start embedded postgres: %w", err)
+	}
+
+	return func() error {
+		err := pg.Stop()
+		os.RemoveAll(runtime)
+		return err
+	}, nil
+}
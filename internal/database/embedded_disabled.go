@@ -0,0 +1,11 @@
+//go:build !embeddeddb
+
+package database
+
+import "errors"
+
+// startEmbedded is unavailable unless built with -tags embeddeddb, which keeps
+// the embedded PostgreSQL downloader out of production binaries.
+func startEmbedded(Config) (func() error, error) {
+	return nil, errors.New("embedded database driver not compiled in; rebuild with -tags embeddeddb")
+}
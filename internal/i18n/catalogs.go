@@ -0,0 +1,550 @@
+package i18n
+
+// catalogs holds the messages of each supported language. Every key must be
+// present in the English catalog; other languages may omit keys.
+var catalogs = map[string]map[string]string{
+	"en": {
+		"error.invalid_batch_id":         "Invalid batch ID",
+		"error.invalid_payout_id":        "Invalid payout ID",
+		"error.invalid_currency":         "Invalid currency",
+		"error.batch_not_found":          "Batch not found",
+		"error.nothing_to_update":        "Nothing to update: set owner, assigned_to or progress_every",
+		"error.payout_not_found":         "Payout not found",
+		"error.batch_busy":               "A batch is already being processed",
+		"error.batch_leased":             "Another instance is processing this batch",
+		"error.shutting_down":            "The server is shutting down; start the batch again once it is back",
+		"error.batch_not_running":        "Batch is not being processed",
+		"error.job_not_found":            "Job not found",
+		"error.job_running":              "The job is already running",
+		"error.environment_mismatch":     "This batch was run in another bank environment and cannot be run in %s",
+		"error.invalid_timezone":         "Unknown time zone %q (use an IANA name such as Asia/Jakarta)",
+		"error.invalid_cursor":           "cursor is not valid; pass back the next_cursor of a previous page",
+		"error.invalid_webhook_id":       "Invalid webhook ID",
+		"error.webhook_not_found":        "Webhook subscription not found",
+		"error.invalid_grace":            "grace must be a duration between 0s and %s",
+		"error.create_failed":            "Failed to create batch: %s",
+		"error.lookup_failed":            "Failed to look up payout",
+		"error.group_by_required":        "group_by is required (e.g. country, category, currency, bank_name)",
+		"error.at_with_group_by":         "at cannot be combined with group_by: snapshots are not segmented",
+		"error.invalid_timestamp":        "%s must be a timestamp (RFC 3339, or YYYY-MM-DDTHH:MM in the request's time zone)",
+		"error.no_snapshot":              "No statistics snapshot of this batch at or before %s",
+		"error.query_too_short":          "q must be at least %d characters",
+		"error.malformed_body":           "Request body is not valid JSON",
+		"error.profile_not_found":        "Import profile not found",
+		"error.invalid_profile":          "Invalid import profile: %s",
+		"error.invalid_import":           "Import file is invalid: %s",
+		"error.invalid_stream_line":      "Line %d: %s",
+		"error.empty_stream":             "The stream holds no payouts",
+		"error.quota_exceeded":           "Quota exceeded: %s",
+		"error.merchant_required":        "Name the merchant with X-Merchant or a signed request",
+		"error.invalid_idempotency_key":  "Idempotency-Key must be at most %d characters",
+		"error.idempotency_key_reused":   "Idempotency-Key was already used for a different batch",
+		"error.duplicate_vendors":        "Vendors appear more than once: %s (send dedupe_strategy \"merge\" to merge them)",
+		"error.duplicate_payouts":        "%d payouts were already completed in a recent batch (send duplicate_check \"warn\" or \"off\" to create the batch anyway)",
+		"error.vendors_not_mergeable":    "Entries of these vendors pay different currencies, accounts, splits or purposes and cannot be merged: %s",
+		"error.no_payouts_accepted":      "None of the payouts can be created; see rejected for why",
+		"error.invalid_retry_policy":     "Invalid retry policy: %s",
+		"error.batch_deleted":            "Batch is deleted; restore it first",
+		"error.batch_creating":           "Batch is still being created; start it once its payouts are ingested",
+		"error.batch_not_terminal":       "Only finished batches can be deleted",
+		"error.batch_not_cancellable":    "Only batches that have not finished can be cancelled",
+		"error.batch_cancelled":          "Batch is cancelled and cannot be processed again",
+		"error.split_too_few":            "payouts[%d].splits needs at least two accounts",
+		"error.split_total":              "payouts[%d].splits percentages must add up to 100",
+		"error.invalid_purpose_code":     "payouts[%d].purpose_code: %s",
+		"error.maintenance":              "The payout API is in maintenance mode: %s",
+		"error.read_only":                "This instance is read-only; send changes to the primary instance",
+		"error.invalid_encryption":       "Unsupported encryption: use encrypt=pgp",
+		"error.export_key_missing":       "No export encryption key is configured",
+		"error.invalid_payment_file_id":  "Invalid payment file ID",
+		"error.payment_file_not_found":   "Payment file not found",
+		"error.nothing_to_file":          "The batch has no pending payouts to put in a payment file",
+		"error.file_delivered":           "The payment file was already delivered",
+		"error.file_not_delivered":       "The payment file has not been delivered to the bank yet",
+		"error.empty_acknowledgment":     "Give a result for the file or for its payouts",
+		"error.invalid_bank_file":        "Not a readable bank file: %s",
+		"error.bank_file_too_large":      "Bank files are limited to %d MiB",
+		"error.bank_file_duplicate":      "This bank file was already received",
+		"error.unknown_payment_file":     "The bank file answers for a payment file that does not exist or was not delivered",
+		"error.invalid_bank_file_id":     "Invalid bank file ID",
+		"error.bank_file_not_found":      "Bank file not found",
+		"error.admin_unauthorized":       "A valid admin token is required",
+		"error.operator_required":        "Admin requests must name an operator in the X-Operator header",
+		"error.signature_required":       "Mutating requests must be signed (X-Signature-Key, X-Signature-Timestamp, X-Signature-Nonce, X-Signature)",
+		"error.signature_invalid":        "Request signature is invalid",
+		"error.signature_expired":        "Request timestamp is more than %s from the server clock",
+		"error.request_replayed":         "This request was already received and will not be processed again",
+		"error.run_live":                 "A processing run is live on this batch; stop it first",
+		"error.payout_not_forceable":     "Only failed or pending payouts that were not requeued can be force-completed",
+		"error.requeue_not_found":        "Payout %s not found",
+		"error.payout_not_requeueable":   "Payout %s is not failed, was already requeued, or belongs to a deleted batch",
+		"error.payout_not_write_offable": "Only failed payouts that were not requeued can be written off",
+		"error.payout_not_cancellable":   "Only pending payouts can be cancelled, and not while in a payment file or in a deleted or cancelled batch",
+		"error.write_off_self_approved":  "A write-off must be approved by someone other than the requesting operator",
+		"error.invalid_interval":         "interval must be day, week or month",
+		"error.invalid_date":             "%s must be a date (YYYY-MM-DD)",
+		"error.payout_not_failed":        "Outreach can only be logged against failed payouts",
+		"error.contacted_in_future":      "contacted_at cannot be in the future",
+		"error.invalid_older_than_days":  "older_than_days must be a whole number of days, 0 or more",
+		"error.tag_required":             "tag is required for add_tag",
+		"error.view_not_found":           "Payout view not found",
+		"error.invalid_amount_range":     "min_amount must not be greater than max_amount",
+		"error.requeue_duplicate_vendor": "A vendor can only be requeued once per batch: %s",
+		"msg.payouts_requeued":           "Failed payouts requeued into a new batch",
+		"error.no_chaos_controls":        "The configured bank adapter has no chaos controls",
+		"error.reload_unavailable":       "Configuration reload is not available",
+		"error.reload_failed":            "Configuration reload failed: %s",
+		"error.invalid_worker_config":    "Invalid worker config: %s",
+		"error.invalid_tax_id":           "Invalid tax ID: %s",
+		"error.tax_id_not_found":         "No tax ID on file for this vendor",
+		"error.invalid_country":          "country must be a two-letter ISO country code",
+		"error.approver_required":        "Approvals must name the approver in the X-Operator header",
+		"error.not_awaiting_approval":    "Payout is not held for a reporting approval",
+		"error.reporting_self_approved":  "A reportable payout must be approved by someone other than its batch owner",
+		"msg.batch_created":              "Batch created successfully",
+		"msg.batch_creating":             "Batch accepted; its payouts are being ingested",
+		"msg.batch_replayed":             "Batch already created with this Idempotency-Key",
+		"msg.batch_started":              "Batch processing started",
+		"msg.batch_queued":               "Another batch is being processed; this one is queued at position %d and starts automatically",
+		"msg.stop_sent":                  "Stop signal sent. Processing will pause after current chunk.",
+		"msg.no_retryable":               "No retryable payouts found",
+		"msg.retrying":                   "Retrying failed payouts",
+		"validation.required":            "%s is required",
+		"validation.min":                 "%s must be at least %s",
+		"validation.max":                 "%s must be at most %s",
+		"validation.gt":                  "%s must be greater than %s",
+		"validation.gte":                 "%s must be at least %s",
+		"validation.lt":                  "%s must be less than %s",
+		"validation.oneof":               "%s must be one of: %s",
+		"validation.invalid":             "%s is invalid",
+		"validation.currency_code":       "%s must be a 3-letter ISO 4217 currency code",
+		"validation.amount_precision":    "%s has more than %d decimals for %s",
+		"validation.duplicate_vendor":    "%s repeats the vendor of payouts[%d]",
+		"validation.unmergeable_vendor":  "%s cannot be merged with payouts[%d]: they pay into different places",
+		"validation.already_paid":        "%s was already paid on %s in batch %s",
+		"failure.INVALID_BANK_ACCOUNT":   "The bank rejected the account details",
+		"failure.INSUFFICIENT_FUNDS":     "The paying account had insufficient funds at the time",
+		"failure.BANK_API_TIMEOUT":       "The bank did not respond in time",
+		"failure.ACCOUNT_BLOCKED":        "The receiving account is blocked",
+		"failure.RATE_LIMITED":           "The bank temporarily refused more transfers",
+		"failure.BANK_REJECTED":          "The bank rejected the payout in its payment file acknowledgment",
+		"status.pending":                 "Pending",
+		"status.processing":              "Being sent",
+		"status.completed":               "Sent",
+		"status.failed":                  "Failed",
+		"status.written_off":             "Written off",
+		"status.cancelled":               "Cancelled",
+		"status.in_progress":             "In progress",
+		"status.paused":                  "Paused",
+		"status.creating":                "Creating",
+		"status.partially_completed":     "Partially completed",
+	},
+	"id": {
+		"error.invalid_batch_id":         "ID batch tidak valid",
+		"error.invalid_payout_id":        "ID pembayaran tidak valid",
+		"error.invalid_currency":         "Mata uang tidak valid",
+		"error.batch_not_found":          "Batch tidak ditemukan",
+		"error.nothing_to_update":        "Tidak ada yang diperbarui: isi owner, assigned_to atau progress_every",
+		"error.payout_not_found":         "Pembayaran tidak ditemukan",
+		"error.batch_busy":               "Sebuah batch sedang diproses",
+		"error.batch_leased":             "Instans lain sedang memproses batch ini",
+		"error.shutting_down":            "Server sedang dimatikan; mulai batch lagi setelah server kembali",
+		"error.batch_not_running":        "Batch sedang tidak diproses",
+		"error.job_not_found":            "Job tidak ditemukan",
+		"error.job_running":              "Job sedang berjalan",
+		"error.environment_mismatch":     "Batch ini dijalankan di lingkungan bank lain dan tidak dapat dijalankan di %s",
+		"error.invalid_timezone":         "Zona waktu %q tidak dikenal (gunakan nama IANA seperti Asia/Jakarta)",
+		"error.invalid_cursor":           "cursor tidak valid; gunakan next_cursor dari halaman sebelumnya",
+		"error.invalid_webhook_id":       "ID webhook tidak valid",
+		"error.webhook_not_found":        "Langganan webhook tidak ditemukan",
+		"error.invalid_grace":            "grace harus berupa durasi antara 0s dan %s",
+		"error.create_failed":            "Gagal membuat batch: %s",
+		"error.lookup_failed":            "Gagal mencari pembayaran",
+		"error.group_by_required":        "group_by wajib diisi (mis. country, category, currency, bank_name)",
+		"error.at_with_group_by":         "at tidak dapat digabungkan dengan group_by: snapshot tidak dibagi per segmen",
+		"error.invalid_timestamp":        "%s harus berupa cap waktu (RFC 3339, atau YYYY-MM-DDTHH:MM dalam zona waktu permintaan)",
+		"error.no_snapshot":              "Tidak ada snapshot statistik batch ini pada atau sebelum %s",
+		"error.query_too_short":          "q minimal %d karakter",
+		"error.malformed_body":           "Isi permintaan bukan JSON yang valid",
+		"error.profile_not_found":        "Profil impor tidak ditemukan",
+		"error.invalid_profile":          "Profil impor tidak valid: %s",
+		"error.invalid_import":           "Berkas impor tidak valid: %s",
+		"error.invalid_stream_line":      "Baris %d: %s",
+		"error.empty_stream":             "Aliran tidak berisi pembayaran",
+		"error.quota_exceeded":           "Kuota terlampaui: %s",
+		"error.merchant_required":        "Sebutkan merchant dengan X-Merchant atau permintaan bertanda tangan",
+		"error.invalid_idempotency_key":  "Idempotency-Key paling banyak %d karakter",
+		"error.idempotency_key_reused":   "Idempotency-Key sudah digunakan untuk batch lain",
+		"error.duplicate_vendors":        "Vendor muncul lebih dari sekali: %s (kirim dedupe_strategy \"merge\" untuk menggabungkannya)",
+		"error.duplicate_payouts":        "%d pembayaran sudah selesai dalam batch baru-baru ini (kirim duplicate_check \"warn\" atau \"off\" untuk tetap membuat batch)",
+		"error.vendors_not_mergeable":    "Entri vendor ini membayar mata uang, rekening, pembagian, atau tujuan yang berbeda dan tidak dapat digabungkan: %s",
+		"error.no_payouts_accepted":      "Tidak ada pembayaran yang dapat dibuat; lihat rejected untuk alasannya",
+		"error.invalid_retry_policy":     "Kebijakan percobaan ulang tidak valid: %s",
+		"error.batch_deleted":            "Batch telah dihapus; pulihkan terlebih dahulu",
+		"error.batch_creating":           "Batch masih dibuat; mulai setelah semua pembayarannya dimasukkan",
+		"error.batch_not_terminal":       "Hanya batch yang sudah selesai yang dapat dihapus",
+		"error.batch_not_cancellable":    "Hanya batch yang belum selesai yang dapat dibatalkan",
+		"error.batch_cancelled":          "Batch telah dibatalkan dan tidak dapat diproses lagi",
+		"error.split_too_few":            "payouts[%d].splits memerlukan minimal dua rekening",
+		"error.split_total":              "Persentase payouts[%d].splits harus berjumlah 100",
+		"error.invalid_purpose_code":     "payouts[%d].purpose_code tidak valid: %s",
+		"error.maintenance":              "API pembayaran sedang dalam mode pemeliharaan: %s",
+		"error.read_only":                "Instans ini hanya-baca; kirim perubahan ke instans utama",
+		"error.invalid_encryption":       "Enkripsi tidak didukung: gunakan encrypt=pgp",
+		"error.export_key_missing":       "Kunci enkripsi ekspor belum dikonfigurasi",
+		"error.invalid_payment_file_id":  "ID file pembayaran tidak valid",
+		"error.payment_file_not_found":   "File pembayaran tidak ditemukan",
+		"error.nothing_to_file":          "Batch tidak memiliki pembayaran tertunda untuk dimasukkan ke file pembayaran",
+		"error.file_delivered":           "File pembayaran sudah dikirim",
+		"error.file_not_delivered":       "File pembayaran belum dikirim ke bank",
+		"error.empty_acknowledgment":     "Berikan hasil untuk file atau untuk pembayarannya",
+		"error.invalid_bank_file":        "Bukan file bank yang dapat dibaca: %s",
+		"error.bank_file_too_large":      "Ukuran file bank maksimal %d MiB",
+		"error.bank_file_duplicate":      "File bank ini sudah pernah diterima",
+		"error.unknown_payment_file":     "File bank menjawab untuk file pembayaran yang tidak ada atau belum dikirim",
+		"error.invalid_bank_file_id":     "ID file bank tidak valid",
+		"error.bank_file_not_found":      "File bank tidak ditemukan",
+		"error.admin_unauthorized":       "Diperlukan token admin yang valid",
+		"error.operator_required":        "Permintaan admin harus menyebutkan operator di header X-Operator",
+		"error.signature_required":       "Permintaan yang mengubah data harus ditandatangani (X-Signature-Key, X-Signature-Timestamp, X-Signature-Nonce, X-Signature)",
+		"error.signature_invalid":        "Tanda tangan permintaan tidak valid",
+		"error.signature_expired":        "Stempel waktu permintaan berselisih lebih dari %s dari jam server",
+		"error.request_replayed":         "Permintaan ini sudah diterima dan tidak akan diproses lagi",
+		"error.run_live":                 "Proses sedang berjalan pada batch ini; hentikan terlebih dahulu",
+		"error.payout_not_forceable":     "Hanya pembayaran gagal atau tertunda yang belum diantrekan ulang yang dapat diselesaikan paksa",
+		"error.requeue_not_found":        "Pembayaran %s tidak ditemukan",
+		"error.payout_not_requeueable":   "Pembayaran %s tidak gagal, sudah diantrekan ulang, atau milik batch yang dihapus",
+		"error.payout_not_write_offable": "Hanya pembayaran gagal yang belum diantrekan ulang yang dapat dihapusbukukan",
+		"error.payout_not_cancellable":   "Hanya pembayaran tertunda yang dapat dibatalkan, dan tidak saat berada dalam file pembayaran atau dalam batch yang dihapus atau dibatalkan",
+		"error.write_off_self_approved":  "Penghapusbukuan harus disetujui oleh orang selain operator yang meminta",
+		"error.invalid_interval":         "interval harus day, week, atau month",
+		"error.invalid_date":             "%s harus berupa tanggal (YYYY-MM-DD)",
+		"error.payout_not_failed":        "Kontak hanya dapat dicatat untuk pembayaran yang gagal",
+		"error.contacted_in_future":      "contacted_at tidak boleh di masa depan",
+		"error.invalid_older_than_days":  "older_than_days harus berupa jumlah hari bulat, 0 atau lebih",
+		"error.tag_required":             "tag wajib diisi untuk add_tag",
+		"error.view_not_found":           "Tampilan pembayaran tidak ditemukan",
+		"error.invalid_amount_range":     "min_amount tidak boleh lebih besar dari max_amount",
+		"error.requeue_duplicate_vendor": "Vendor hanya dapat diantrekan ulang sekali per batch: %s",
+		"msg.payouts_requeued":           "Pembayaran gagal diantrekan ulang ke batch baru",
+		"error.no_chaos_controls":        "Adaptor bank yang dikonfigurasi tidak memiliki kontrol chaos",
+		"error.reload_unavailable":       "Muat ulang konfigurasi tidak tersedia",
+		"error.reload_failed":            "Gagal memuat ulang konfigurasi: %s",
+		"error.invalid_worker_config":    "Konfigurasi worker tidak valid: %s",
+		"error.invalid_tax_id":           "Nomor pajak tidak valid: %s",
+		"error.tax_id_not_found":         "Belum ada nomor pajak untuk vendor ini",
+		"error.invalid_country":          "country harus berupa kode negara ISO dua huruf",
+		"error.approver_required":        "Persetujuan harus menyebutkan pemberi persetujuan di header X-Operator",
+		"error.not_awaiting_approval":    "Pembayaran tidak ditahan untuk persetujuan pelaporan",
+		"error.reporting_self_approved":  "Pembayaran yang wajib dilaporkan harus disetujui oleh orang selain pemilik batch",
+		"msg.batch_created":              "Batch berhasil dibuat",
+		"msg.batch_creating":             "Batch diterima; pembayarannya sedang dimasukkan",
+		"msg.batch_replayed":             "Batch sudah dibuat dengan Idempotency-Key ini",
+		"msg.batch_started":              "Pemrosesan batch dimulai",
+		"msg.batch_queued":               "Batch lain sedang diproses; batch ini masuk antrean di posisi %d dan dimulai otomatis",
+		"msg.stop_sent":                  "Sinyal berhenti dikirim. Pemrosesan akan dijeda setelah bagian saat ini.",
+		"msg.no_retryable":               "Tidak ada pembayaran yang dapat dicoba ulang",
+		"msg.retrying":                   "Mencoba ulang pembayaran yang gagal",
+		"validation.required":            "%s wajib diisi",
+		"validation.min":                 "%s minimal %s",
+		"validation.max":                 "%s maksimal %s",
+		"validation.gt":                  "%s harus lebih besar dari %s",
+		"validation.gte":                 "%s minimal %s",
+		"validation.lt":                  "%s harus kurang dari %s",
+		"validation.oneof":               "%s harus salah satu dari: %s",
+		"validation.invalid":             "%s tidak valid",
+		"validation.currency_code":       "%s harus berupa kode mata uang ISO 4217 tiga huruf",
+		"validation.amount_precision":    "%s memiliki lebih dari %d desimal untuk %s",
+		"validation.duplicate_vendor":    "%s mengulang vendor dari payouts[%d]",
+		"validation.unmergeable_vendor":  "%s tidak dapat digabungkan dengan payouts[%d]: keduanya dibayarkan ke tempat berbeda",
+		"validation.already_paid":        "%s sudah dibayar pada %s dalam batch %s",
+		"failure.INVALID_BANK_ACCOUNT":   "Bank menolak data rekening",
+		"failure.INSUFFICIENT_FUNDS":     "Saldo rekening pembayar tidak mencukupi saat itu",
+		"failure.BANK_API_TIMEOUT":       "Bank tidak merespons tepat waktu",
+		"failure.ACCOUNT_BLOCKED":        "Rekening penerima diblokir",
+		"failure.RATE_LIMITED":           "Bank untuk sementara menolak transfer tambahan",
+		"failure.BANK_REJECTED":          "Bank menolak pembayaran dalam konfirmasi file pembayaran",
+		"status.pending":                 "Menunggu",
+		"status.processing":              "Sedang dikirim",
+		"status.completed":               "Terkirim",
+		"status.failed":                  "Gagal",
+		"status.written_off":             "Dihapusbukukan",
+		"status.cancelled":               "Dibatalkan",
+		"status.in_progress":             "Sedang diproses",
+		"status.paused":                  "Dijeda",
+		"status.creating":                "Sedang dibuat",
+		"status.partially_completed":     "Selesai sebagian",
+	},
+	"fil": {
+		"error.invalid_batch_id":         "Hindi wastong batch ID",
+		"error.invalid_payout_id":        "Hindi wastong payout ID",
+		"error.invalid_currency":         "Hindi wastong currency",
+		"error.batch_not_found":          "Hindi nahanap ang batch",
+		"error.nothing_to_update":        "Walang babaguhin: itakda ang owner, assigned_to o progress_every",
+		"error.payout_not_found":         "Hindi nahanap ang payout",
+		"error.batch_busy":               "May batch na kasalukuyang pinoproseso",
+		"error.batch_leased":             "Ibang instance ang nagpoproseso ng batch na ito",
+		"error.shutting_down":            "Nagsasara ang server; simulan muli ang batch kapag bumalik na ito",
+		"error.batch_not_running":        "Hindi pinoproseso ang batch",
+		"error.job_not_found":            "Hindi nahanap ang job",
+		"error.job_running":              "Tumatakbo na ang job",
+		"error.environment_mismatch":     "Pinatakbo ang batch na ito sa ibang bank environment at hindi mapapatakbo sa %s",
+		"error.invalid_timezone":         "Hindi kilalang time zone %q (gumamit ng IANA name tulad ng Asia/Manila)",
+		"error.invalid_cursor":           "Hindi wasto ang cursor; ibalik ang next_cursor ng naunang pahina",
+		"error.invalid_webhook_id":       "Hindi wastong webhook ID",
+		"error.webhook_not_found":        "Hindi nakita ang webhook subscription",
+		"error.invalid_grace":            "Ang grace ay dapat tagal sa pagitan ng 0s at %s",
+		"error.create_failed":            "Hindi nagawa ang batch: %s",
+		"error.lookup_failed":            "Hindi nahanap ang payout dahil sa error",
+		"error.group_by_required":        "Kailangan ang group_by (hal. country, category, currency, bank_name)",
+		"error.at_with_group_by":         "Hindi maaaring pagsamahin ang at at group_by: hindi hinahati sa segment ang mga snapshot",
+		"error.invalid_timestamp":        "Dapat na timestamp ang %s (RFC 3339, o YYYY-MM-DDTHH:MM sa time zone ng request)",
+		"error.no_snapshot":              "Walang snapshot ng statistics ng batch na ito sa o bago ang %s",
+		"error.query_too_short":          "Ang q ay dapat hindi bababa sa %d karakter",
+		"error.malformed_body":           "Hindi wastong JSON ang request body",
+		"error.profile_not_found":        "Hindi nahanap ang import profile",
+		"error.invalid_profile":          "Hindi wastong import profile: %s",
+		"error.invalid_import":           "Hindi wasto ang import file: %s",
+		"error.invalid_stream_line":      "Linya %d: %s",
+		"error.empty_stream":             "Walang payout sa stream",
+		"error.quota_exceeded":           "Lumampas sa quota: %s",
+		"error.merchant_required":        "Pangalanan ang merchant gamit ang X-Merchant o isang signed request",
+		"error.invalid_idempotency_key":  "Ang Idempotency-Key ay hanggang %d na character lamang",
+		"error.idempotency_key_reused":   "Nagamit na ang Idempotency-Key para sa ibang batch",
+		"error.duplicate_vendors":        "Lumalabas nang higit sa isang beses ang mga vendor: %s (ipadala ang dedupe_strategy na \"merge\" para pagsamahin sila)",
+		"error.duplicate_payouts":        "%d payout ang nakumpleto na sa isang kamakailang batch (ipadala ang duplicate_check \"warn\" o \"off\" para gawin pa rin ang batch)",
+		"error.vendors_not_mergeable":    "Ang mga entry ng mga vendor na ito ay nagbabayad sa ibang currency, account, split o layunin at hindi mapagsasama: %s",
+		"error.no_payouts_accepted":      "Walang payout na magagawa; tingnan ang rejected para sa dahilan",
+		"error.invalid_retry_policy":     "Hindi wastong patakaran sa muling pagsubok: %s",
+		"error.batch_deleted":            "Binura na ang batch; ibalik muna ito",
+		"error.batch_creating":           "Ginagawa pa ang batch; simulan ito kapag naipasok na ang mga payout nito",
+		"error.batch_not_terminal":       "Mga tapos na batch lang ang maaaring burahin",
+		"error.batch_not_cancellable":    "Mga batch lang na hindi pa tapos ang maaaring kanselahin",
+		"error.batch_cancelled":          "Kinansela na ang batch at hindi na ito maaaring iproseso muli",
+		"error.split_too_few":            "Kailangan ng payouts[%d].splits ng hindi bababa sa dalawang account",
+		"error.split_total":              "Dapat umabot sa 100 ang kabuuan ng mga porsyento ng payouts[%d].splits",
+		"error.invalid_purpose_code":     "Hindi wasto ang payouts[%d].purpose_code: %s",
+		"error.maintenance":              "Nasa maintenance mode ang payout API: %s",
+		"error.read_only":                "Read-only ang instance na ito; ipadala ang mga pagbabago sa pangunahing instance",
+		"error.invalid_encryption":       "Hindi suportadong encryption: gamitin ang encrypt=pgp",
+		"error.export_key_missing":       "Walang naka-configure na encryption key para sa export",
+		"error.invalid_payment_file_id":  "Hindi wastong ID ng payment file",
+		"error.payment_file_not_found":   "Hindi nahanap ang payment file",
+		"error.nothing_to_file":          "Walang nakabinbing payout sa batch na maisasama sa payment file",
+		"error.file_delivered":           "Naipadala na ang payment file",
+		"error.file_not_delivered":       "Hindi pa naipapadala sa bangko ang payment file",
+		"error.empty_acknowledgment":     "Magbigay ng resulta para sa file o sa mga payout nito",
+		"error.invalid_bank_file":        "Hindi mabasang bank file: %s",
+		"error.bank_file_too_large":      "Hanggang %d MiB lang ang bank file",
+		"error.bank_file_duplicate":      "Natanggap na ang bank file na ito",
+		"error.unknown_payment_file":     "Sumasagot ang bank file para sa payment file na wala o hindi pa naipadala",
+		"error.invalid_bank_file_id":     "Di-wastong bank file ID",
+		"error.bank_file_not_found":      "Hindi nahanap ang bank file",
+		"error.admin_unauthorized":       "Kailangan ng wastong admin token",
+		"error.operator_required":        "Dapat pangalanan ng admin request ang operator sa X-Operator header",
+		"error.signature_required":       "Dapat pirmahan ang mga request na nagbabago ng data (X-Signature-Key, X-Signature-Timestamp, X-Signature-Nonce, X-Signature)",
+		"error.signature_invalid":        "Hindi wasto ang pirma ng request",
+		"error.signature_expired":        "Ang timestamp ng request ay lampas %s mula sa orasan ng server",
+		"error.request_replayed":         "Natanggap na ang request na ito at hindi na ipoproseso muli",
+		"error.run_live":                 "May tumatakbong proseso sa batch na ito; ihinto muna ito",
+		"error.payout_not_forceable":     "Mga bigo o nakabinbing payout lang na hindi pa muling ipinila ang maaaring sapilitang kumpletuhin",
+		"error.requeue_not_found":        "Hindi nahanap ang payout %s",
+		"error.payout_not_requeueable":   "Ang payout %s ay hindi bigo, naipila na muli, o kabilang sa binurang batch",
+		"error.payout_not_write_offable": "Mga bigong payout lang na hindi pa muling ipinila ang maaaring i-write off",
+		"error.payout_not_cancellable":   "Mga nakabinbing payout lang ang maaaring kanselahin, at hindi habang nasa isang payment file o nasa binura o kinanselang batch",
+		"error.write_off_self_approved":  "Ang write-off ay dapat aprubahan ng ibang tao maliban sa operator na humiling",
+		"error.invalid_interval":         "Ang interval ay dapat day, week o month",
+		"error.invalid_date":             "Ang %s ay dapat petsa (YYYY-MM-DD)",
+		"error.payout_not_failed":        "Maaari lang itala ang outreach sa mga bigong payout",
+		"error.contacted_in_future":      "Hindi maaaring nasa hinaharap ang contacted_at",
+		"error.invalid_older_than_days":  "Ang older_than_days ay dapat buong bilang ng araw, 0 o higit pa",
+		"error.tag_required":             "Kailangan ang tag para sa add_tag",
+		"error.view_not_found":           "Hindi nahanap ang payout view",
+		"error.invalid_amount_range":     "Hindi dapat mas malaki ang min_amount kaysa sa max_amount",
+		"error.requeue_duplicate_vendor": "Isang beses lang maaaring ipila muli ang vendor bawat batch: %s",
+		"msg.payouts_requeued":           "Muling ipinila ang mga nabigong payout sa bagong batch",
+		"error.no_chaos_controls":        "Walang chaos controls ang naka-configure na bank adapter",
+		"error.reload_unavailable":       "Hindi available ang pag-reload ng configuration",
+		"error.reload_failed":            "Nabigo ang pag-reload ng configuration: %s",
+		"error.invalid_worker_config":    "Di-wastong worker config: %s",
+		"error.invalid_tax_id":           "Di-wastong tax ID: %s",
+		"error.tax_id_not_found":         "Walang naka-file na tax ID para sa vendor na ito",
+		"error.invalid_country":          "Ang country ay dapat dalawang-titik na ISO country code",
+		"error.approver_required":        "Dapat pangalanan ng approval ang nag-apruba sa X-Operator header",
+		"error.not_awaiting_approval":    "Ang payout ay hindi naka-hold para sa reporting approval",
+		"error.reporting_self_approved":  "Ang reportable na payout ay dapat aprubahan ng ibang tao maliban sa may-ari ng batch",
+		"msg.batch_created":              "Matagumpay na nagawa ang batch",
+		"msg.batch_creating":             "Tinanggap ang batch; ipinapasok pa ang mga payout nito",
+		"msg.batch_replayed":             "Nagawa na ang batch gamit ang Idempotency-Key na ito",
+		"msg.batch_started":              "Sinimulan ang pagproseso ng batch",
+		"msg.batch_queued":               "May ibang batch na pinoproseso; nakapila ang batch na ito sa posisyon %d at awtomatikong magsisimula",
+		"msg.stop_sent":                  "Naipadala ang stop signal. Ihihinto ang pagproseso pagkatapos ng kasalukuyang bahagi.",
+		"msg.no_retryable":               "Walang payout na maaaring subukang muli",
+		"msg.retrying":                   "Sinusubukang muli ang mga nabigong payout",
+		"validation.required":            "Kailangan ang %s",
+		"validation.min":                 "Ang %s ay dapat hindi bababa sa %s",
+		"validation.max":                 "Ang %s ay dapat hindi hihigit sa %s",
+		"validation.gt":                  "Ang %s ay dapat mas malaki sa %s",
+		"validation.gte":                 "Ang %s ay dapat hindi bababa sa %s",
+		"validation.lt":                  "Dapat mas mababa ang %s sa %s",
+		"validation.oneof":               "Ang %s ay dapat isa sa: %s",
+		"validation.invalid":             "Hindi wasto ang %s",
+		"validation.currency_code":       "%s ay dapat na 3-letrang ISO 4217 na code ng currency",
+		"validation.amount_precision":    "%s ay may higit sa %d decimal para sa %s",
+		"validation.duplicate_vendor":    "%s ay inuulit ang vendor ng payouts[%d]",
+		"validation.unmergeable_vendor":  "%s ay hindi maisasama sa payouts[%d]: magkaiba ang pinagbabayaran nila",
+		"validation.already_paid":        "%s ay nabayaran na noong %s sa batch %s",
+		"failure.INVALID_BANK_ACCOUNT":   "Tinanggihan ng bangko ang detalye ng account",
+		"failure.INSUFFICIENT_FUNDS":     "Kulang ang pondo ng nagbabayad na account noong panahong iyon",
+		"failure.BANK_API_TIMEOUT":       "Hindi sumagot ang bangko sa takdang oras",
+		"failure.ACCOUNT_BLOCKED":        "Naka-block ang tumatanggap na account",
+		"failure.RATE_LIMITED":           "Pansamantalang tumanggi ang bangko sa karagdagang transfer",
+		"failure.BANK_REJECTED":          "Tinanggihan ng bangko ang payout sa acknowledgment ng payment file",
+		"status.pending":                 "Naghihintay",
+		"status.processing":              "Ipinapadala",
+		"status.completed":               "Naipadala",
+		"status.failed":                  "Nabigo",
+		"status.written_off":             "Na-write off",
+		"status.cancelled":               "Kinansela",
+		"status.in_progress":             "Pinoproseso",
+		"status.paused":                  "Naka-pause",
+		"status.creating":                "Ginagawa",
+		"status.partially_completed":     "Bahagyang natapos",
+	},
+	"vi": {
+		"error.invalid_batch_id":         "Mã lô không hợp lệ",
+		"error.invalid_payout_id":        "Mã khoản chi không hợp lệ",
+		"error.invalid_currency":         "Loại tiền tệ không hợp lệ",
+		"error.batch_not_found":          "Không tìm thấy lô",
+		"error.nothing_to_update":        "Không có gì để cập nhật: hãy đặt owner, assigned_to hoặc progress_every",
+		"error.payout_not_found":         "Không tìm thấy khoản chi",
+		"error.batch_busy":               "Đang có một lô được xử lý",
+		"error.batch_leased":             "Một phiên bản khác đang xử lý lô này",
+		"error.shutting_down":            "Máy chủ đang tắt; hãy bắt đầu lại lô khi máy chủ hoạt động trở lại",
+		"error.batch_not_running":        "Lô không đang được xử lý",
+		"error.job_not_found":            "Không tìm thấy tác vụ",
+		"error.job_running":              "Tác vụ đang chạy",
+		"error.environment_mismatch":     "Lô này đã chạy trong môi trường ngân hàng khác và không thể chạy trong %s",
+		"error.invalid_timezone":         "Múi giờ %q không xác định (dùng tên IANA như Asia/Ho_Chi_Minh)",
+		"error.invalid_cursor":           "cursor không hợp lệ; hãy dùng next_cursor của trang trước",
+		"error.invalid_webhook_id":       "ID webhook không hợp lệ",
+		"error.webhook_not_found":        "Không tìm thấy đăng ký webhook",
+		"error.invalid_grace":            "grace phải là khoảng thời gian từ 0s đến %s",
+		"error.create_failed":            "Không thể tạo lô: %s",
+		"error.lookup_failed":            "Không thể tra cứu khoản chi",
+		"error.group_by_required":        "Cần có group_by (ví dụ: country, category, currency, bank_name)",
+		"error.at_with_group_by":         "Không thể dùng at cùng với group_by: ảnh chụp không chia theo phân khúc",
+		"error.invalid_timestamp":        "%s phải là dấu thời gian (RFC 3339, hoặc YYYY-MM-DDTHH:MM theo múi giờ của yêu cầu)",
+		"error.no_snapshot":              "Không có ảnh chụp thống kê của lô này tại hoặc trước %s",
+		"error.query_too_short":          "q phải có ít nhất %d ký tự",
+		"error.malformed_body":           "Nội dung yêu cầu không phải JSON hợp lệ",
+		"error.profile_not_found":        "Không tìm thấy hồ sơ nhập",
+		"error.invalid_profile":          "Hồ sơ nhập không hợp lệ: %s",
+		"error.invalid_import":           "Tệp nhập không hợp lệ: %s",
+		"error.invalid_stream_line":      "Dòng %d: %s",
+		"error.empty_stream":             "Luồng không chứa khoản chi trả nào",
+		"error.quota_exceeded":           "Vượt hạn mức: %s",
+		"error.merchant_required":        "Hãy nêu merchant bằng X-Merchant hoặc một yêu cầu có chữ ký",
+		"error.invalid_idempotency_key":  "Idempotency-Key tối đa %d ký tự",
+		"error.idempotency_key_reused":   "Idempotency-Key đã được dùng cho một lô khác",
+		"error.duplicate_vendors":        "Nhà cung cấp xuất hiện nhiều lần: %s (gửi dedupe_strategy \"merge\" để gộp lại)",
+		"error.duplicate_payouts":        "%d khoản chi đã hoàn tất trong một lô gần đây (gửi duplicate_check \"warn\" hoặc \"off\" để vẫn tạo lô)",
+		"error.vendors_not_mergeable":    "Các mục của những nhà cung cấp này trả bằng tiền tệ, tài khoản, phân chia hoặc mục đích khác nhau và không thể gộp: %s",
+		"error.no_payouts_accepted":      "Không thể tạo khoản chi nào; xem rejected để biết lý do",
+		"error.invalid_retry_policy":     "Chính sách thử lại không hợp lệ: %s",
+		"error.batch_deleted":            "Lô đã bị xóa; hãy khôi phục trước",
+		"error.batch_creating":           "Lô vẫn đang được tạo; hãy bắt đầu khi các khoản chi đã được nhập xong",
+		"error.batch_not_terminal":       "Chỉ có thể xóa các lô đã hoàn tất",
+		"error.batch_not_cancellable":    "Chỉ có thể hủy các lô chưa hoàn tất",
+		"error.batch_cancelled":          "Lô đã bị hủy và không thể xử lý lại",
+		"error.split_too_few":            "payouts[%d].splits cần ít nhất hai tài khoản",
+		"error.split_total":              "Tổng tỷ lệ phần trăm của payouts[%d].splits phải bằng 100",
+		"error.invalid_purpose_code":     "payouts[%d].purpose_code không hợp lệ: %s",
+		"error.maintenance":              "API thanh toán đang ở chế độ bảo trì: %s",
+		"error.read_only":                "Phiên bản này chỉ đọc; hãy gửi thay đổi đến phiên bản chính",
+		"error.invalid_encryption":       "Không hỗ trợ kiểu mã hóa này: dùng encrypt=pgp",
+		"error.export_key_missing":       "Chưa cấu hình khóa mã hóa cho tệp xuất",
+		"error.invalid_payment_file_id":  "ID tệp thanh toán không hợp lệ",
+		"error.payment_file_not_found":   "Không tìm thấy tệp thanh toán",
+		"error.nothing_to_file":          "Lô không có khoản chi nào đang chờ để đưa vào tệp thanh toán",
+		"error.file_delivered":           "Tệp thanh toán đã được gửi",
+		"error.file_not_delivered":       "Tệp thanh toán chưa được gửi đến ngân hàng",
+		"error.empty_acknowledgment":     "Hãy cung cấp kết quả cho tệp hoặc cho các khoản chi trong tệp",
+		"error.invalid_bank_file":        "Không đọc được tệp ngân hàng: %s",
+		"error.bank_file_too_large":      "Tệp ngân hàng tối đa %d MiB",
+		"error.bank_file_duplicate":      "Tệp ngân hàng này đã được nhận trước đó",
+		"error.unknown_payment_file":     "Tệp ngân hàng phản hồi cho một tệp thanh toán không tồn tại hoặc chưa được gửi",
+		"error.invalid_bank_file_id":     "ID tệp ngân hàng không hợp lệ",
+		"error.bank_file_not_found":      "Không tìm thấy tệp ngân hàng",
+		"error.admin_unauthorized":       "Cần có mã thông báo quản trị hợp lệ",
+		"error.operator_required":        "Yêu cầu quản trị phải nêu tên người vận hành trong tiêu đề X-Operator",
+		"error.signature_required":       "Các yêu cầu thay đổi dữ liệu phải được ký (X-Signature-Key, X-Signature-Timestamp, X-Signature-Nonce, X-Signature)",
+		"error.signature_invalid":        "Chữ ký yêu cầu không hợp lệ",
+		"error.signature_expired":        "Dấu thời gian của yêu cầu lệch quá %s so với đồng hồ máy chủ",
+		"error.request_replayed":         "Yêu cầu này đã được nhận và sẽ không được xử lý lại",
+		"error.run_live":                 "Lô này đang được xử lý; hãy dừng trước",
+		"error.payout_not_forceable":     "Chỉ có thể buộc hoàn tất các khoản chi thất bại hoặc đang chờ chưa được xếp hàng lại",
+		"error.requeue_not_found":        "Không tìm thấy khoản chi %s",
+		"error.payout_not_requeueable":   "Khoản chi %s không thất bại, đã được xếp hàng lại hoặc thuộc lô đã xóa",
+		"error.payout_not_write_offable": "Chỉ có thể xóa sổ các khoản chi thất bại chưa được xếp hàng lại",
+		"error.payout_not_cancellable":   "Chỉ có thể hủy các khoản chi đang chờ, và không thể hủy khi đang nằm trong tệp thanh toán hoặc trong lô đã bị xóa hay bị hủy",
+		"error.write_off_self_approved":  "Việc xóa sổ phải được phê duyệt bởi người khác ngoài người vận hành yêu cầu",
+		"error.invalid_interval":         "interval phải là day, week hoặc month",
+		"error.invalid_date":             "%s phải là ngày (YYYY-MM-DD)",
+		"error.payout_not_failed":        "Chỉ có thể ghi nhận liên hệ cho các khoản chi thất bại",
+		"error.contacted_in_future":      "contacted_at không được ở tương lai",
+		"error.invalid_older_than_days":  "older_than_days phải là số ngày nguyên, từ 0 trở lên",
+		"error.tag_required":             "tag là bắt buộc với add_tag",
+		"error.view_not_found":           "Không tìm thấy chế độ xem khoản chi",
+		"error.invalid_amount_range":     "min_amount không được lớn hơn max_amount",
+		"error.requeue_duplicate_vendor": "Mỗi nhà cung cấp chỉ được xếp hàng lại một lần mỗi lô: %s",
+		"msg.payouts_requeued":           "Đã xếp hàng lại các khoản chi thất bại vào lô mới",
+		"error.no_chaos_controls":        "Bộ điều hợp ngân hàng đã cấu hình không có điều khiển chaos",
+		"error.reload_unavailable":       "Không thể tải lại cấu hình",
+		"error.reload_failed":            "Tải lại cấu hình thất bại: %s",
+		"error.invalid_worker_config":    "Cấu hình worker không hợp lệ: %s",
+		"error.invalid_tax_id":           "Mã số thuế không hợp lệ: %s",
+		"error.tax_id_not_found":         "Chưa có mã số thuế cho nhà cung cấp này",
+		"error.invalid_country":          "country phải là mã quốc gia ISO gồm hai chữ cái",
+		"error.approver_required":        "Phê duyệt phải nêu tên người phê duyệt trong tiêu đề X-Operator",
+		"error.not_awaiting_approval":    "Khoản chi không bị giữ để chờ phê duyệt báo cáo",
+		"error.reporting_self_approved":  "Khoản chi cần báo cáo phải được phê duyệt bởi người khác ngoài chủ sở hữu lô",
+		"msg.batch_created":              "Đã tạo lô thành công",
+		"msg.batch_creating":             "Đã nhận lô; các khoản chi đang được nhập",
+		"msg.batch_replayed":             "Lô đã được tạo với Idempotency-Key này",
+		"msg.batch_started":              "Đã bắt đầu xử lý lô",
+		"msg.batch_queued":               "Một lô khác đang được xử lý; lô này đang xếp hàng ở vị trí %d và sẽ tự động bắt đầu",
+		"msg.stop_sent":                  "Đã gửi tín hiệu dừng. Quá trình xử lý sẽ tạm dừng sau phần hiện tại.",
+		"msg.no_retryable":               "Không có khoản chi nào có thể thử lại",
+		"msg.retrying":                   "Đang thử lại các khoản chi thất bại",
+		"validation.required":            "%s là bắt buộc",
+		"validation.min":                 "%s phải tối thiểu là %s",
+		"validation.max":                 "%s tối đa là %s",
+		"validation.gt":                  "%s phải lớn hơn %s",
+		"validation.gte":                 "%s phải tối thiểu là %s",
+		"validation.lt":                  "%s phải nhỏ hơn %s",
+		"validation.oneof":               "%s phải là một trong: %s",
+		"validation.invalid":             "%s không hợp lệ",
+		"validation.currency_code":       "%s phải là mã tiền tệ ISO 4217 gồm 3 chữ cái",
+		"validation.amount_precision":    "%s có nhiều hơn %d chữ số thập phân cho %s",
+		"validation.duplicate_vendor":    "%s lặp lại nhà cung cấp của payouts[%d]",
+		"validation.unmergeable_vendor":  "%s không thể gộp với payouts[%d]: chúng thanh toán vào nơi khác nhau",
+		"validation.already_paid":        "%s đã được thanh toán ngày %s trong lô %s",
+		"failure.INVALID_BANK_ACCOUNT":   "Ngân hàng từ chối thông tin tài khoản",
+		"failure.INSUFFICIENT_FUNDS":     "Tài khoản chi trả không đủ số dư vào thời điểm đó",
+		"failure.BANK_API_TIMEOUT":       "Ngân hàng không phản hồi kịp thời",
+		"failure.ACCOUNT_BLOCKED":        "Tài khoản nhận đã bị khóa",
+		"failure.RATE_LIMITED":           "Ngân hàng tạm thời từ chối thêm giao dịch",
+		"failure.BANK_REJECTED":          "Ngân hàng từ chối khoản chi trong phản hồi tệp thanh toán",
+		"status.pending":                 "Đang chờ",
+		"status.processing":              "Đang gửi",
+		"status.completed":               "Đã gửi",
+		"status.failed":                  "Thất bại",
+		"status.written_off":             "Đã xóa sổ",
+		"status.cancelled":               "Đã hủy",
+		"status.in_progress":             "Đang xử lý",
+		"status.paused":                  "Tạm dừng",
+		"status.creating":                "Đang tạo",
+		"status.partially_completed":     "Hoàn thành một phần",
+	},
+}
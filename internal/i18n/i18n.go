@@ -0,0 +1,107 @@
+// Package i18n translates user-facing strings (API errors and messages,
+// validation errors, failure descriptions, status labels) into the
+// languages of the teams and vendors using the engine.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when no supported language is requested, and for
+// keys missing from a catalog.
+const DefaultLanguage = "en"
+
+// aliases maps language tags to the catalog that serves them.
+var aliases = map[string]string{
+	"in": "id", // legacy tag for Indonesian
+	"tl": "fil",
+}
+
+// Supported returns the supported language codes.
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Negotiate picks the best supported language for an Accept-Language header,
+// honouring q-values. Region subtags are ignored ("id-ID" matches "id").
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		lang := Base(tag)
+		if alias, ok := aliases[lang]; ok {
+			lang = alias
+		}
+		if _, ok := catalogs[lang]; ok && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Base reduces a language tag such as "id-ID" or "id_ID" to "id".
+func Base(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// T returns the message for key in lang, formatted with args. Missing
+// translations fall back to English, and unknown keys to the key itself.
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Has reports whether key exists in the default catalog.
+func Has(key string) bool {
+	_, ok := catalogs[DefaultLanguage][key]
+	return ok
+}
+
+// FailureDescription explains a bank failure code in lang.
+func FailureDescription(lang, code string) string {
+	return T(lang, "failure."+code)
+}
+
+// StatusLabel is the display label of a payout or batch status in lang.
+func StatusLabel(lang, status string) string {
+	return T(lang, "status."+status)
+}
+
+// Keys returns every message key of the default catalog.
+func Keys() []string {
+	keys := make([]string, 0, len(catalogs[DefaultLanguage]))
+	for key := range catalogs[DefaultLanguage] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
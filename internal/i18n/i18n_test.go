@@ -0,0 +1,53 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"coding-challenge/internal/i18n"
+)
+
+// TestNegotiate verifies q-values, region subtags, aliases and the default.
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          "en",
+		"id-ID,id;q=0.9,en;q=0.8":   "id",
+		"fr-FR, vi;q=0.5, en;q=0.4": "vi",
+		"en;q=0.3, fil;q=0.7":       "fil",
+		"tl-PH":                     "fil",
+		"de-DE,fr;q=0.9":            "en",
+		"id;q=abc, vi":              "vi",
+	}
+	for header, want := range cases {
+		if got := i18n.Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q): expected %s, got %s", header, want, got)
+		}
+	}
+}
+
+// TestTranslate verifies formatting and fallback to English and to the key.
+func TestTranslate(t *testing.T) {
+	if got := i18n.T("id", "error.query_too_short", 2); got != "q minimal 2 karakter" {
+		t.Errorf("Unexpected Indonesian message: %q", got)
+	}
+	if got := i18n.T("xx", "error.batch_not_found"); got != "Batch not found" {
+		t.Errorf("Expected English fallback, got %q", got)
+	}
+	if got := i18n.T("en", "no.such.key"); got != "no.such.key" {
+		t.Errorf("Expected the key for an unknown message, got %q", got)
+	}
+	if got := i18n.FailureDescription("vi", "ACCOUNT_BLOCKED"); got != "Tài khoản nhận đã bị khóa" {
+		t.Errorf("Unexpected Vietnamese failure description: %q", got)
+	}
+}
+
+// TestCatalogsComplete verifies every language translates every English key,
+// so new messages are not silently left untranslated.
+func TestCatalogsComplete(t *testing.T) {
+	for _, lang := range i18n.Supported() {
+		for _, key := range i18n.Keys() {
+			if i18n.T(lang, key) == i18n.T("en", key) && lang != "en" {
+				t.Errorf("%s: %s is not translated", lang, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Package importer turns partner CSV and NDJSON files into batch payout
+// items using column-mapping profiles, so each upstream file format is
+// onboarded once, and checks every row against the profile's rules.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"coding-challenge/internal/models"
+)
+
+// Payout fields a profile can map or default.
+const (
+	FieldVendorID       = "vendor_id"
+	FieldVendorName     = "vendor_name"
+	FieldAmount         = "amount"
+	FieldCurrency       = "currency"
+	FieldBankAccount    = "bank_account"
+	FieldBankName       = "bank_name"
+	FieldTransactionIDs = "transaction_ids" // "|"-separated in CSV files
+	FieldPurposeCode    = "purpose_code"
+)
+
+var fields = []string{
+	FieldVendorID, FieldVendorName, FieldAmount, FieldCurrency,
+	FieldBankAccount, FieldBankName, FieldTransactionIDs, FieldPurposeCode,
+}
+
+var isField = func() map[string]bool {
+	m := map[string]bool{}
+	for _, f := range fields {
+		m[f] = true
+	}
+	return m
+}()
+
+// requiredFields must be mapped to a column or given a default.
+var requiredFields = []string{FieldVendorID, FieldAmount, FieldCurrency, FieldBankAccount}
+
+// MaxRowErrors caps how many row errors are reported for one file.
+const MaxRowErrors = 50
+
+// RowError describes a problem with one data row (1-based, header excluded).
+type RowError struct {
+	Row     int    `json:"row"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Report summarizes an import: how many rows were read, accepted and
+// rejected, how often each rule was broken, and the first MaxRowErrors
+// problems.
+type Report struct {
+	Rows       int            `json:"rows"`
+	Accepted   int            `json:"accepted"`
+	Rejected   int            `json:"rejected"`
+	Violations map[string]int `json:"violations,omitempty"`
+	Errors     []RowError     `json:"errors,omitempty"`
+}
+
+func (r *Report) reject(row int, v violation) {
+	if r.Violations == nil {
+		r.Violations = map[string]int{}
+	}
+	r.Violations[v.rule]++
+	if len(r.Errors) < MaxRowErrors {
+		r.Errors = append(r.Errors, RowError{Row: row, Rule: v.rule, Message: v.message})
+	}
+}
+
+// Error is returned when rows are invalid. Its report lists them all (up to
+// MaxRowErrors) so partners can fix a file in one pass.
+type Error struct {
+	Report *Report
+}
+
+func (e *Error) Error() string {
+	first := e.Report.Errors[0]
+	return fmt.Sprintf("%d invalid rows (first: row %d: %s)", e.Report.Rejected, first.Row, first.Message)
+}
+
+// DefaultProfile maps each payout field to a column of the same name and
+// every other column to metadata. It is used when no profile is named.
+var DefaultProfile = models.ImportProfile{Name: "default"}
+
+// ValidateProfile checks that a profile only names known fields, covers
+// every required one, and has usable rules.
+func ValidateProfile(p models.ImportProfile) error {
+	for _, m := range []map[string]string{p.Columns, p.Defaults} {
+		for field := range m {
+			if !isField[field] {
+				return fmt.Errorf("unknown payout field %q (want one of %s)", field, strings.Join(fields, ", "))
+			}
+		}
+	}
+	for _, field := range requiredFields {
+		if p.Columns[field] == "" && p.Defaults[field] == "" {
+			return fmt.Errorf("%s must be mapped to a column or given a default", field)
+		}
+	}
+	if len([]rune(p.Delimiter)) > 1 {
+		return errors.New("delimiter must be a single character")
+	}
+	if p.DecimalSeparator != "" && p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return errors.New(`decimal_separator must be "." or ","`)
+	}
+	_, err := compileRules(p.Rules)
+	return err
+}
+
+// Parse reads a CSV file with a header row. Columns not mapped to a field
+// are ignored unless the profile maps them to metadata; the default profile
+// keeps every unmapped column as metadata.
+func Parse(r io.Reader, profile models.ImportProfile) ([]models.CreatePayoutItem, *Report, error) {
+	cr := csv.NewReader(r)
+	if profile.Delimiter != "" {
+		cr.Comma = []rune(profile.Delimiter)[0]
+	}
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read header: %w", err)
+	}
+	for i, name := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF"))
+	}
+	if profile.Name != DefaultProfile.Name {
+		present := map[string]bool{}
+		for _, name := range header {
+			present[name] = true
+		}
+		for field, column := range profile.Columns {
+			if !present[column] && profile.Defaults[field] == "" {
+				return nil, nil, fmt.Errorf("column %q (for %s) not found in header", column, field)
+			}
+		}
+	}
+
+	return build(profile, func() (map[string]string, error) {
+		record, err := cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) && name != "" {
+				values[name] = record[i]
+			}
+		}
+		return values, nil
+	})
+}
+
+// ParseNDJSON reads one flat JSON object per line, with keys playing the
+// role of CSV headers. Arrays are joined with "|" and the entries of a
+// "metadata" object are treated as columns of their own, so lines shaped
+// like the JSON batch payload import with the default profile.
+func ParseNDJSON(r io.Reader, profile models.ImportProfile) ([]models.CreatePayoutItem, *Report, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	return build(profile, func() (map[string]string, error) {
+		for sc.Scan() {
+			line++
+			text := bytes.TrimSpace(sc.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			values, err := flatten(text, profile.DecimalSeparator)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			return values, nil
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	})
+}
+
+// flatten decodes one NDJSON object into column values.
+func flatten(line []byte, decimalSeparator string) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %w", err)
+	}
+	values := make(map[string]string, len(obj))
+	var put func(key string, v any) error
+	put = func(key string, v any) error {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			values[key] = v
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		case json.Number:
+			s := v.String()
+			if decimalSeparator == "," {
+				s = strings.Replace(s, ".", ",", 1)
+			}
+			values[key] = s
+		case []any:
+			parts := make([]string, 0, len(v))
+			for _, item := range v {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(parts, "|")
+		case map[string]any:
+			if key != "metadata" {
+				return fmt.Errorf("%s: nested objects are not supported", key)
+			}
+			for k, item := range v {
+				if err := put(k, item); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("%s: unsupported value", key)
+		}
+		return nil
+	}
+	for key, v := range obj {
+		if err := put(key, v); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// build maps every record returned by next (until io.EOF) to a payout item
+// and checks it against the profile's rules.
+func build(profile models.ImportProfile, next func() (map[string]string, error)) ([]models.CreatePayoutItem, *Report, error) {
+	rules, err := compileRules(profile.Rules)
+	if err != nil {
+		return nil, nil, fmt.Errorf("profile %s: %w", profile.Name, err)
+	}
+
+	report := &Report{}
+	var items []models.CreatePayoutItem
+	for {
+		values, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read row %d: %w", report.Rows+1, err)
+		}
+		report.Rows++
+
+		item, violations := mapRow(values, profile)
+		if len(violations) == 0 {
+			violations = rules.check(item)
+		}
+		if len(violations) > 0 {
+			report.Rejected++
+			for _, v := range violations {
+				report.reject(report.Rows, v)
+			}
+			continue
+		}
+		report.Accepted++
+		items = append(items, item)
+	}
+
+	if report.Rejected > 0 {
+		return nil, report, &Error{Report: report}
+	}
+	if len(items) == 0 {
+		return nil, report, errors.New("file has no payout rows")
+	}
+	return items, report, nil
+}
+
+// mapRow builds one payout item from a record's column values.
+func mapRow(values map[string]string, profile models.ImportProfile) (models.CreatePayoutItem, []violation) {
+	value := func(field string) string {
+		column := field
+		if profile.Name != DefaultProfile.Name {
+			column = profile.Columns[field]
+		}
+		if v := strings.TrimSpace(values[column]); column != "" && v != "" {
+			return v
+		}
+		return profile.Defaults[field]
+	}
+
+	item := models.CreatePayoutItem{
+		VendorID:    value(FieldVendorID),
+		VendorName:  value(FieldVendorName),
+		Currency:    strings.ToUpper(value(FieldCurrency)),
+		BankAccount: value(FieldBankAccount),
+		BankName:    value(FieldBankName),
+		PurposeCode: value(FieldPurposeCode),
+	}
+	var violations []violation
+	for _, field := range requiredFields {
+		if value(field) == "" {
+			violations = append(violations, violation{ruleRequired, field + " is empty"})
+		}
+	}
+	if raw := value(FieldAmount); raw != "" {
+		amount, err := parseAmount(raw, profile.DecimalSeparator)
+		if err != nil {
+			violations = append(violations, violation{ruleAmount, err.Error()})
+		}
+		item.Amount = amount
+	}
+	if len(violations) > 0 {
+		return item, violations
+	}
+
+	if ids := value(FieldTransactionIDs); ids != "" {
+		for _, id := range strings.Split(ids, "|") {
+			if id = strings.TrimSpace(id); id != "" {
+				item.TransactionIDs = append(item.TransactionIDs, id)
+			}
+		}
+	}
+
+	metadata := profile.Metadata
+	if profile.Name == DefaultProfile.Name {
+		metadata = map[string]string{}
+		for column := range values {
+			if !isField[column] {
+				metadata[column] = column
+			}
+		}
+	}
+	for key, column := range metadata {
+		if v := strings.TrimSpace(values[column]); v != "" {
+			if item.Metadata == nil {
+				item.Metadata = map[string]string{}
+			}
+			item.Metadata[key] = v
+		}
+	}
+	return item, nil
+}
+
+// parseAmount reads an amount written with the given decimal separator
+// ("." by default), ignoring thousands separators and spaces.
+func parseAmount(raw, decimalSeparator string) (float64, error) {
+	thousands := ","
+	if decimalSeparator == "," {
+		thousands = "."
+	}
+	s := strings.NewReplacer(thousands, "", " ", "").Replace(raw)
+	if decimalSeparator == "," {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is not a number", raw)
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount %q must be greater than 0", raw)
+	}
+	return amount, nil
+}
+
+// Fields returns the payout fields a profile can map, sorted.
+func Fields() []string {
+	out := append([]string(nil), fields...)
+	sort.Strings(out)
+	return out
+}
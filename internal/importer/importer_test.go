@@ -0,0 +1,171 @@
+package importer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/importer"
+	"coding-challenge/internal/models"
+)
+
+// TestParseDefaultProfile verifies headers matching the JSON field names map
+// directly and every other column becomes metadata.
+func TestParseDefaultProfile(t *testing.T) {
+	file := "vendor_id,vendor_name,amount,currency,bank_account,bank_name,transaction_ids,country\n" +
+		"V-1,Toko Batik,1500.25,idr,123,BCA,T1|T2,ID\n"
+	items, _, err := importer.Parse(strings.NewReader(file), importer.DefaultProfile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(items))
+	}
+	item := items[0]
+	if item.VendorID != "V-1" || item.Amount != 1500.25 || item.Currency != "IDR" || item.BankName != "BCA" {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	if len(item.TransactionIDs) != 2 || item.TransactionIDs[1] != "T2" {
+		t.Errorf("Expected transaction IDs [T1 T2], got %v", item.TransactionIDs)
+	}
+	if item.Metadata["country"] != "ID" {
+		t.Errorf("Expected country metadata ID, got %v", item.Metadata)
+	}
+}
+
+// TestParseWithProfile verifies column mapping, defaults, the delimiter and
+// decimal-comma amounts.
+func TestParseWithProfile(t *testing.T) {
+	profile := models.ImportProfile{
+		Name:             "acme",
+		Columns:          map[string]string{"vendor_id": "Kode", "amount": "Nominal", "bank_account": "Rekening"},
+		Metadata:         map[string]string{"region": "Wilayah"},
+		Defaults:         map[string]string{"currency": "IDR", "bank_name": "BCA"},
+		Delimiter:        ";",
+		DecimalSeparator: ",",
+	}
+	if err := importer.ValidateProfile(profile); err != nil {
+		t.Fatalf("ValidateProfile failed: %v", err)
+	}
+
+	file := "Kode;Nominal;Rekening;Wilayah;Ignored\nS-1;1.250.000,50;999;Bali;x\n"
+	items, _, err := importer.Parse(strings.NewReader(file), profile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	item := items[0]
+	if item.Amount != 1250000.50 {
+		t.Errorf("Expected amount 1250000.50, got %v", item.Amount)
+	}
+	if item.Currency != "IDR" || item.BankName != "BCA" {
+		t.Errorf("Expected defaults IDR/BCA, got %s/%s", item.Currency, item.BankName)
+	}
+	if len(item.Metadata) != 1 || item.Metadata["region"] != "Bali" {
+		t.Errorf("Expected only region metadata, got %v", item.Metadata)
+	}
+}
+
+// TestParseReportsRowErrors verifies every bad row is reported by number.
+func TestParseReportsRowErrors(t *testing.T) {
+	file := "vendor_id,amount,currency,bank_account\n" +
+		"V-1,10,IDR,1\n" +
+		"V-2,-5,IDR,2\n" +
+		",10,IDR,3\n"
+	_, report, err := importer.Parse(strings.NewReader(file), importer.DefaultProfile)
+	var rowErrs *importer.Error
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("Expected row errors, got %v", err)
+	}
+	if report.Rows != 3 || report.Accepted != 1 || report.Rejected != 2 {
+		t.Errorf("Expected 3 rows, 1 accepted, 2 rejected, got %+v", report)
+	}
+	if len(report.Errors) != 2 || report.Errors[0].Row != 2 || report.Errors[1].Row != 3 {
+		t.Errorf("Expected errors for rows 2 and 3, got %+v", report.Errors)
+	}
+}
+
+// TestValidateProfile verifies unknown fields and unmapped required fields
+// are rejected.
+func TestValidateProfile(t *testing.T) {
+	cases := map[string]models.ImportProfile{
+		"unknown field": {Columns: map[string]string{"vendor_id": "a", "amount": "b", "currency": "c", "bank_account": "d", "iban": "e"}},
+		"missing field": {Columns: map[string]string{"vendor_id": "a", "amount": "b", "currency": "c"}},
+		"bad pattern":   {Columns: map[string]string{"vendor_id": "a", "amount": "b", "currency": "c", "bank_account": "d"}, Rules: models.ImportRules{BankAccountPattern: "[0-9"}},
+		"bad separator": {Columns: map[string]string{"vendor_id": "a", "amount": "b", "currency": "c", "bank_account": "d"}, DecimalSeparator: "'"},
+	}
+	for name, p := range cases {
+		if err := importer.ValidateProfile(p); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestRulesSummarizedInReport verifies each configured rule rejects the rows
+// that break it and is counted in the report.
+func TestRulesSummarizedInReport(t *testing.T) {
+	minAmount, maxAmount := 10.0, 1000.0
+	profile := importer.DefaultProfile
+	profile.Rules = models.ImportRules{
+		Required:           []string{"bank_name", "country"},
+		BankAccountPattern: `[0-9]{6,12}`,
+		MinAmount:          &minAmount,
+		MaxAmount:          &maxAmount,
+		Currencies:         []string{"IDR", "php"},
+	}
+
+	file := "vendor_id,amount,currency,bank_account,bank_name,country\n" +
+		"V-1,100,IDR,123456,BCA,ID\n" + // ok
+		"V-2,100,PHP,12-34,BDO,PH\n" + // bank account
+		"V-3,5,USD,123456,BCA,ID\n" + // min amount + currency
+		"V-4,5000,IDR,123456,,\n" // max amount + 2x required
+	_, report, err := importer.Parse(strings.NewReader(file), profile)
+	if err == nil {
+		t.Fatal("Expected an error for rows breaking rules")
+	}
+	if report.Accepted != 1 || report.Rejected != 3 {
+		t.Errorf("Expected 1 accepted and 3 rejected, got %+v", report)
+	}
+	want := map[string]int{"bank_account_pattern": 1, "min_amount": 1, "currencies": 1, "max_amount": 1, "required": 2}
+	for rule, n := range want {
+		if report.Violations[rule] != n {
+			t.Errorf("Expected %d %s violations, got %d", n, rule, report.Violations[rule])
+		}
+	}
+
+	ok := "vendor_id,amount,currency,bank_account,bank_name,country\nV-1,100,IDR,123456,BCA,ID\n"
+	if _, report, err := importer.Parse(strings.NewReader(ok), profile); err != nil || report.Accepted != 1 {
+		t.Errorf("Expected a clean import, got %v (%+v)", err, report)
+	}
+}
+
+// TestParseNDJSON verifies JSON lines import through the same mapping,
+// including arrays and a nested metadata object.
+func TestParseNDJSON(t *testing.T) {
+	file := `{"vendor_id": "V-1", "amount": 1500.5, "currency": "IDR", "bank_account": "123", "transaction_ids": ["T1", "T2"], "metadata": {"country": "ID"}}
+
+{"vendor_id": "V-2", "amount": "20", "currency": "PHP", "bank_account": "456"}
+`
+	items, report, err := importer.ParseNDJSON(strings.NewReader(file), importer.DefaultProfile)
+	if err != nil {
+		t.Fatalf("ParseNDJSON failed: %v", err)
+	}
+	if report.Rows != 2 || len(items) != 2 {
+		t.Fatalf("Expected 2 rows, got %d (%d items)", report.Rows, len(items))
+	}
+	if items[0].Amount != 1500.5 || len(items[0].TransactionIDs) != 2 || items[0].Metadata["country"] != "ID" {
+		t.Errorf("Unexpected first item: %+v", items[0])
+	}
+
+	profile := models.ImportProfile{
+		Name:             "eu",
+		Columns:          map[string]string{"vendor_id": "id", "amount": "total", "currency": "ccy", "bank_account": "iban"},
+		DecimalSeparator: ",",
+	}
+	items, _, err = importer.ParseNDJSON(strings.NewReader(`{"id": "V-3", "total": 12.5, "ccy": "EUR", "iban": "X"}`), profile)
+	if err != nil {
+		t.Fatalf("ParseNDJSON with profile failed: %v", err)
+	}
+	if items[0].Amount != 12.5 {
+		t.Errorf("Expected JSON number 12.5 regardless of the decimal separator, got %v", items[0].Amount)
+	}
+}
@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/purpose"
+)
+
+// Rule names, as counted in Report.Violations.
+const (
+	ruleRequired    = "required"
+	ruleAmount      = "amount"
+	ruleBankAccount = "bank_account_pattern"
+	ruleMinAmount   = "min_amount"
+	ruleMaxAmount   = "max_amount"
+	ruleCurrency    = "currencies"
+	rulePurposeCode = "purpose_code"
+)
+
+type violation struct {
+	rule    string
+	message string
+}
+
+// rules is a compiled models.ImportRules.
+type rules struct {
+	models.ImportRules
+	bankAccount *regexp.Regexp
+	currencies  map[string]bool
+}
+
+func compileRules(r models.ImportRules) (*rules, error) {
+	c := &rules{ImportRules: r}
+	if r.BankAccountPattern != "" {
+		re, err := regexp.Compile(`^(?:` + r.BankAccountPattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("bank_account_pattern: %w", err)
+		}
+		c.bankAccount = re
+	}
+	if r.MinAmount != nil && r.MaxAmount != nil && *r.MinAmount > *r.MaxAmount {
+		return nil, errors.New("min_amount is greater than max_amount")
+	}
+	if len(r.Currencies) > 0 {
+		c.currencies = map[string]bool{}
+		for _, cur := range r.Currencies {
+			if len(cur) != 3 {
+				return nil, fmt.Errorf("currencies: %q is not a 3-letter code", cur)
+			}
+			c.currencies[strings.ToUpper(cur)] = true
+		}
+	}
+	for _, name := range r.Required {
+		if strings.TrimSpace(name) == "" {
+			return nil, errors.New("required: empty field name")
+		}
+	}
+	return c, nil
+}
+
+// Violation is a rule a payout breaks, named as in Report.Violations.
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// CheckRules compiles a profile's rules into a check for payouts that did
+// not come in through an import, such as a JSON batch being validated. As
+// in an import, a purpose code is always checked.
+func CheckRules(profile models.ImportProfile) (func(models.CreatePayoutItem) []Violation, error) {
+	c, err := compileRules(profile.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profile.Name, err)
+	}
+	return func(item models.CreatePayoutItem) []Violation {
+		var out []Violation
+		for _, v := range c.check(item) {
+			out = append(out, Violation{Rule: v.rule, Message: v.message})
+		}
+		return out
+	}, nil
+}
+
+// check returns every rule the item breaks. A purpose code is always
+// checked against its country's list.
+func (c *rules) check(item models.CreatePayoutItem) []violation {
+	var out []violation
+	for _, name := range c.Required {
+		if fieldValue(item, name) == "" {
+			out = append(out, violation{ruleRequired, name + " is empty"})
+		}
+	}
+	if c.bankAccount != nil {
+		// A split payout is paid into its splits' accounts instead.
+		accounts := []string{item.BankAccount}
+		if len(item.Splits) > 0 {
+			accounts = accounts[:0]
+			for _, split := range item.Splits {
+				accounts = append(accounts, split.BankAccount)
+			}
+		}
+		for _, account := range accounts {
+			if !c.bankAccount.MatchString(account) {
+				out = append(out, violation{ruleBankAccount, fmt.Sprintf("bank_account %q does not match %s", account, c.BankAccountPattern)})
+			}
+		}
+	}
+	if c.MinAmount != nil && item.Amount < *c.MinAmount {
+		out = append(out, violation{ruleMinAmount, fmt.Sprintf("amount %v is below %v", item.Amount, *c.MinAmount)})
+	}
+	if c.MaxAmount != nil && item.Amount > *c.MaxAmount {
+		out = append(out, violation{ruleMaxAmount, fmt.Sprintf("amount %v is above %v", item.Amount, *c.MaxAmount)})
+	}
+	if c.currencies != nil && !c.currencies[item.Currency] {
+		out = append(out, violation{ruleCurrency, fmt.Sprintf("currency %s is not allowed", item.Currency)})
+	}
+	if err := purpose.Validate(item); err != nil {
+		out = append(out, violation{rulePurposeCode, err.Error()})
+	}
+	return out
+}
+
+// fieldValue returns a payout field, or else the metadata value of that name.
+func fieldValue(item models.CreatePayoutItem, name string) string {
+	switch name {
+	case FieldVendorID:
+		return item.VendorID
+	case FieldVendorName:
+		return item.VendorName
+	case FieldCurrency:
+		return item.Currency
+	case FieldBankAccount:
+		return item.BankAccount
+	case FieldBankName:
+		return item.BankName
+	case FieldAmount:
+		if item.Amount == 0 {
+			return ""
+		}
+		return fmt.Sprint(item.Amount)
+	case FieldTransactionIDs:
+		return strings.Join(item.TransactionIDs, "|")
+	case FieldPurposeCode:
+		return item.PurposeCode
+	}
+	return item.Metadata[name]
+}
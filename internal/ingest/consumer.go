@@ -0,0 +1,240 @@
+// Package ingest creates batches from payout instructions read off a Kafka
+// topic, an event-driven alternative to POST /batches: instructions are
+// validated, gathered into a batch per window and the batch is started.
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/purpose"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// Message is one record read from the topic.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       json.RawMessage
+	Value     json.RawMessage
+}
+
+// Source reads messages from a topic for a consumer group. Commit marks
+// every message up to and including the given ones as consumed.
+type Source interface {
+	Poll(ctx context.Context) ([]Message, error)
+	Commit(ctx context.Context, msgs []Message) error
+	Close(ctx context.Context) error
+}
+
+// Store creates batches; the repository implements it.
+type Store interface {
+	CreateBatch(ctx context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error)
+}
+
+// Starter starts processing a batch, or queues it while another runs; the
+// worker pool implements it.
+type Starter interface {
+	Enqueue(batchID uuid.UUID, trigger, triggeredBy string) (*models.BatchRun, int, error)
+}
+
+// Config holds Consumer settings.
+type Config struct {
+	// Window is how long a batch gathers instructions after its first one
+	// (default 1m).
+	Window time.Duration
+	// MaxBatchSize closes a batch early once it holds this many
+	// instructions (default 1000).
+	MaxBatchSize int
+	// PayoutOrder is the processing order of created batches (default fifo).
+	PayoutOrder string
+	// Operator owns the created batches and is recorded as starting them
+	// (default "kafka").
+	Operator string
+	// RetryInterval is how long to wait after a failed poll, create or
+	// commit before trying again (default 5s).
+	RetryInterval time.Duration
+}
+
+// Consumer turns payout instructions into batches. Offsets are committed
+// only once the instructions read so far are stored in a batch, so a crash
+// replays them rather than losing them.
+type Consumer struct {
+	source   Source
+	store    Store
+	starter  Starter
+	cfg      Config
+	validate *validator.Validate
+}
+
+// NewConsumer creates a consumer. Call Run to start consuming.
+func NewConsumer(source Source, store Store, starter Starter, cfg Config) *Consumer {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = 1000
+	}
+	if cfg.PayoutOrder == "" {
+		cfg.PayoutOrder = models.PayoutOrderFIFO
+	}
+	if cfg.Operator == "" {
+		cfg.Operator = "kafka"
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 5 * time.Second
+	}
+	// The binding tags are the ones POST /batches validates.
+	v := validator.New()
+	v.SetTagName("binding")
+	return &Consumer{source: source, store: store, starter: starter, cfg: cfg, validate: v}
+}
+
+// window is the batch being gathered and the messages read into it.
+type window struct {
+	opened  time.Time
+	full    bool // closed early: at MaxBatchSize, or a vendor came up again
+	items   []models.CreatePayoutItem
+	vendors map[string]bool
+	msgs    []Message // every message read since the last commit, invalid ones included
+}
+
+func newWindow() *window {
+	return &window{vendors: map[string]bool{}}
+}
+
+// Run consumes until ctx is cancelled, then closes the source. Messages
+// that fail validation are logged and skipped.
+func (c *Consumer) Run(ctx context.Context) {
+	defer func() {
+		if err := c.source.Close(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("[ingest] Warning: failed to close source: %v", err)
+		}
+	}()
+
+	w := newWindow()
+	var queued []Message // polled but not yet taken into a window
+	for ctx.Err() == nil {
+		if len(w.items) > 0 && (w.full || time.Since(w.opened) >= c.cfg.Window) {
+			if err := c.flush(ctx, w); err != nil {
+				log.Printf("[ingest] Error creating batch of %d instructions: %v", len(w.items), err)
+				c.sleep(ctx, c.cfg.RetryInterval)
+				continue
+			}
+			w = newWindow()
+		}
+
+		if len(queued) == 0 {
+			msgs, err := c.source.Poll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[ingest] Error polling: %v", err)
+					c.sleep(ctx, c.cfg.RetryInterval)
+				}
+				continue
+			}
+			queued = msgs
+		}
+		queued = c.take(w, queued)
+
+		// Only invalid messages so far: nothing to store, so move past them.
+		if len(w.items) == 0 && len(w.msgs) > 0 {
+			if err := c.source.Commit(ctx, w.msgs); err != nil {
+				log.Printf("[ingest] Warning: failed to commit skipped messages: %v", err)
+			} else {
+				w.msgs = nil
+			}
+		}
+	}
+}
+
+// take adds messages to the window until it is full, returning the rest.
+func (c *Consumer) take(w *window, msgs []Message) []Message {
+	for len(msgs) > 0 && !w.full {
+		msg := msgs[0]
+		item, err := c.Decode(msg.Value)
+		if err != nil {
+			log.Printf("[ingest] Skipping invalid instruction at %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+			w.msgs = append(w.msgs, msg)
+			msgs = msgs[1:]
+			continue
+		}
+		// A batch pays a vendor once, so a repeat waits for the next one.
+		if w.vendors[item.VendorID] {
+			w.full = true
+			break
+		}
+		if len(w.items) == 0 {
+			w.opened = time.Now()
+		}
+		w.items = append(w.items, item)
+		w.vendors[item.VendorID] = true
+		w.msgs = append(w.msgs, msg)
+		w.full = len(w.items) >= c.cfg.MaxBatchSize
+		msgs = msgs[1:]
+	}
+	return msgs
+}
+
+// flush creates and starts the window's batch and commits its messages.
+func (c *Consumer) flush(ctx context.Context, w *window) error {
+	batch, err := c.store.CreateBatch(ctx, w.items, models.BatchOptions{PayoutOrder: c.cfg.PayoutOrder, Owner: c.cfg.Operator})
+	if err != nil {
+		return err
+	}
+	log.Printf("[ingest] Created batch %s from %d instructions", batch.ID, len(w.items))
+	// The batch is stored, so a failed commit only risks the instructions
+	// being read again; it is logged rather than retried.
+	if err := c.source.Commit(ctx, w.msgs); err != nil {
+		log.Printf("[ingest] Warning: failed to commit batch %s offsets: %v", batch.ID, err)
+	}
+	if _, _, err := c.starter.Enqueue(batch.ID, models.RunTriggerStart, c.cfg.Operator); err != nil {
+		log.Printf("[ingest] Warning: batch %s created but not started: %v", batch.ID, err)
+	}
+	return nil
+}
+
+// Decode parses and validates one payout-instruction message against
+// schema version models.PayoutInstructionSchemaVersion. Unknown fields are
+// rejected.
+func (c *Consumer) Decode(value json.RawMessage) (models.CreatePayoutItem, error) {
+	var ins models.PayoutInstruction
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ins); err != nil {
+		return models.CreatePayoutItem{}, fmt.Errorf("decode: %w", err)
+	}
+	if ins.SchemaVersion != models.PayoutInstructionSchemaVersion {
+		return models.CreatePayoutItem{}, fmt.Errorf("unsupported schema_version %d", ins.SchemaVersion)
+	}
+	if err := c.validate.Struct(&ins); err != nil {
+		var verrs validator.ValidationErrors
+		if errors.As(err, &verrs) && len(verrs) > 0 {
+			return models.CreatePayoutItem{}, fmt.Errorf("%s fails %s", verrs[0].Namespace(), verrs[0].Tag())
+		}
+		return models.CreatePayoutItem{}, err
+	}
+	if err := ins.ValidateSplits(); err != nil {
+		return models.CreatePayoutItem{}, err
+	}
+	if err := purpose.Validate(ins.CreatePayoutItem); err != nil {
+		return models.CreatePayoutItem{}, err
+	}
+	return ins.CreatePayoutItem, nil
+}
+
+func (c *Consumer) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
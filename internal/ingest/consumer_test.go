@@ -0,0 +1,203 @@
+package ingest_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/ingest"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// fakeSource hands out queued polls, then nothing.
+type fakeSource struct {
+	mu        sync.Mutex
+	polls     [][]ingest.Message
+	committed []ingest.Message
+	closed    bool
+}
+
+func (f *fakeSource) Poll(ctx context.Context) ([]ingest.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.polls) == 0 {
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	}
+	msgs := f.polls[0]
+	f.polls = f.polls[1:]
+	return msgs, nil
+}
+
+func (f *fakeSource) Commit(_ context.Context, msgs []ingest.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, msgs...)
+	return nil
+}
+
+func (f *fakeSource) Close(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+type fakeStore struct {
+	mu      sync.Mutex
+	batches [][]models.CreatePayoutItem
+	started []uuid.UUID
+}
+
+func (f *fakeStore) CreateBatch(_ context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, items)
+	return &models.PayoutBatch{ID: uuid.New(), TotalCount: len(items)}, nil
+}
+
+func (f *fakeStore) Enqueue(batchID uuid.UUID, trigger, triggeredBy string) (*models.BatchRun, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = append(f.started, batchID)
+	return &models.BatchRun{BatchID: batchID}, 0, nil
+}
+
+func (f *fakeStore) sizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sizes []int
+	for _, b := range f.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func instruction(offset int64, vendor string) ingest.Message {
+	value := fmt.Sprintf(`{"schema_version": 1, "vendor_id": %q, "amount": 100, "currency": "IDR", "bank_account": "ID1"}`, vendor)
+	return ingest.Message{Topic: "payouts", Offset: offset, Value: json.RawMessage(value)}
+}
+
+// TestDecodeValidatesSchema verifies instructions are checked against the
+// schema before they are batched.
+func TestDecodeValidatesSchema(t *testing.T) {
+	c := ingest.NewConsumer(&fakeSource{}, &fakeStore{}, &fakeStore{}, ingest.Config{})
+
+	if _, err := c.Decode(instruction(0, "V1").Value); err != nil {
+		t.Errorf("Expected a valid instruction, got %v", err)
+	}
+	for _, value := range []string{
+		`not json`,
+		`{"vendor_id": "V1", "amount": 100, "currency": "IDR", "bank_account": "ID1"}`,
+		`{"schema_version": 2, "vendor_id": "V1", "amount": 100, "currency": "IDR", "bank_account": "ID1"}`,
+		`{"schema_version": 1, "vendor_id": "V1", "amount": -5, "currency": "IDR", "bank_account": "ID1"}`,
+		`{"schema_version": 1, "vendor_id": "V1", "amount": 100, "currency": "IDR"}`,
+		`{"schema_version": 1, "vendor_id": "V1", "amount": 100, "currency": "IDR", "bank_account": "ID1", "priority": "high"}`,
+		`{"schema_version": 1, "vendor_id": "V1", "amount": 100, "currency": "IDR", "splits": [{"percent": 50, "bank_account": "A"}]}`,
+	} {
+		if _, err := c.Decode(json.RawMessage(value)); err == nil {
+			t.Errorf("Expected %s to be rejected", value)
+		}
+	}
+}
+
+// TestConsumerBatchesInstructions verifies instructions are gathered into
+// batches closed by size, a repeated vendor or the window, that every
+// batch is started, and that offsets, invalid messages included, are all
+// committed.
+func TestConsumerBatchesInstructions(t *testing.T) {
+	invalid := ingest.Message{Topic: "payouts", Offset: 2, Value: json.RawMessage(`{"schema_version": 1}`)}
+	source := &fakeSource{polls: [][]ingest.Message{
+		{instruction(0, "V1"), instruction(1, "V2"), invalid, instruction(3, "V3"), instruction(4, "V4")},
+		{instruction(5, "V4"), instruction(6, "V5")},
+	}}
+	store := &fakeStore{}
+	c := ingest.NewConsumer(source, store, store, ingest.Config{Window: 50 * time.Millisecond, MaxBatchSize: 3})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.sizes()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// V1-V3 fill a batch, V4 repeats in the next poll, V4+V5 close by window.
+	if got := fmt.Sprint(store.sizes()); got != "[3 1 2]" {
+		t.Errorf("Expected batches of [3 1 2], got %s", got)
+	}
+	if len(store.started) != 3 {
+		t.Errorf("Expected every batch started, got %d", len(store.started))
+	}
+	if len(source.committed) != 7 || !source.closed {
+		t.Errorf("Expected all 7 messages committed and the source closed, got %d (closed=%v)", len(source.committed), source.closed)
+	}
+}
+
+// TestRESTProxy verifies the REST Proxy source joins the group, reads JSON
+// records, commits the latest offset per partition and leaves on Close.
+func TestRESTProxy(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var committed string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/consumers/payouts-engine":
+			json.NewEncoder(w).Encode(map[string]string{"instance_id": "c1", "base_uri": srv.URL + "/consumers/payouts-engine/instances/c1"})
+		case strings.HasSuffix(r.URL.Path, "/subscription"), r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/records"):
+			if r.Header.Get("Accept") != "application/vnd.kafka.json.v2+json" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Write([]byte(`[{"topic": "payouts", "partition": 0, "offset": 7, "key": null, "value": {"schema_version": 1}},
+				{"topic": "payouts", "partition": 0, "offset": 8, "key": null, "value": {"schema_version": 1}},
+				{"topic": "payouts", "partition": 1, "offset": 3, "key": null, "value": {"schema_version": 1}}]`))
+		case strings.HasSuffix(r.URL.Path, "/offsets"):
+			body, _ := io.ReadAll(r.Body)
+			committed = string(body)
+		}
+	}))
+	defer srv.Close()
+
+	src := &ingest.RESTProxy{URL: srv.URL, Group: "payouts-engine", Topic: "payouts"}
+	ctx := context.Background()
+	msgs, err := src.Poll(ctx)
+	if err != nil || len(msgs) != 3 || msgs[1].Offset != 8 || string(msgs[0].Value) != `{"schema_version": 1}` {
+		t.Fatalf("Expected 3 records, got %+v (%v)", msgs, err)
+	}
+	if err := src.Commit(ctx, msgs); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	want := `{"offsets":[{"topic":"payouts","partition":0,"offset":8},{"topic":"payouts","partition":1,"offset":3}]}`
+	if committed != want {
+		t.Errorf("Expected commit %s, got %s", want, committed)
+	}
+	if err := src.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	wantCalls := "[POST /consumers/payouts-engine POST /consumers/payouts-engine/instances/c1/subscription GET /consumers/payouts-engine/instances/c1/records POST /consumers/payouts-engine/instances/c1/offsets DELETE /consumers/payouts-engine/instances/c1]"
+	if got := fmt.Sprint(calls); got != wantCalls {
+		t.Errorf("Expected calls %s, got %s", wantCalls, got)
+	}
+}
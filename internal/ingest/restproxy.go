@@ -0,0 +1,163 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kafka REST Proxy v2 media types.
+const (
+	restProxyContentType = "application/vnd.kafka.v2+json"
+	restProxyJSONRecords = "application/vnd.kafka.json.v2+json"
+)
+
+// RESTProxy is a Source reading a topic through a Kafka REST Proxy (v2 API)
+// as a member of a consumer group, with JSON-encoded records. Offsets are
+// only committed explicitly. The consumer instance is created on the first
+// Poll.
+type RESTProxy struct {
+	URL   string // e.g. http://kafka-rest:8082
+	Group string
+	Topic string
+	// Wait is how long a poll waits for records (default 1s).
+	Wait   time.Duration
+	Client *http.Client // defaults to http.DefaultClient
+
+	baseURI string // consumer instance, once created
+}
+
+// Poll returns the next records, waiting up to Wait for some.
+func (r *RESTProxy) Poll(ctx context.Context) ([]Message, error) {
+	if r.baseURI == "" {
+		if err := r.subscribe(ctx); err != nil {
+			return nil, err
+		}
+	}
+	wait := r.Wait
+	if wait <= 0 {
+		wait = time.Second
+	}
+	var records []struct {
+		Topic     string          `json:"topic"`
+		Key       json.RawMessage `json:"key"`
+		Value     json.RawMessage `json:"value"`
+		Partition int32           `json:"partition"`
+		Offset    int64           `json:"offset"`
+	}
+	path := "/records?timeout=" + strconv.FormatInt(wait.Milliseconds(), 10)
+	if err := r.do(ctx, http.MethodGet, r.baseURI+path, nil, &records); err != nil {
+		return nil, fmt.Errorf("poll records: %w", err)
+	}
+	msgs := make([]Message, len(records))
+	for i, rec := range records {
+		msgs[i] = Message{Topic: rec.Topic, Partition: rec.Partition, Offset: rec.Offset, Key: rec.Key, Value: rec.Value}
+	}
+	return msgs, nil
+}
+
+// Commit commits the highest offset of msgs in each partition.
+func (r *RESTProxy) Commit(ctx context.Context, msgs []Message) error {
+	type offset struct {
+		Topic     string `json:"topic"`
+		Partition int32  `json:"partition"`
+		Offset    int64  `json:"offset"`
+	}
+	latest := map[string]int{}
+	var offsets []offset
+	for _, m := range msgs {
+		key := m.Topic + "/" + strconv.Itoa(int(m.Partition))
+		if i, ok := latest[key]; ok {
+			offsets[i].Offset = max(offsets[i].Offset, m.Offset)
+			continue
+		}
+		latest[key] = len(offsets)
+		offsets = append(offsets, offset{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset})
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	if err := r.do(ctx, http.MethodPost, r.baseURI+"/offsets", map[string]any{"offsets": offsets}, nil); err != nil {
+		return fmt.Errorf("commit offsets: %w", err)
+	}
+	return nil
+}
+
+// Close deletes the consumer instance, leaving the group.
+func (r *RESTProxy) Close(ctx context.Context) error {
+	if r.baseURI == "" {
+		return nil
+	}
+	err := r.do(ctx, http.MethodDelete, r.baseURI, nil, nil)
+	r.baseURI = ""
+	return err
+}
+
+// subscribe creates the consumer instance and subscribes it to the topic.
+func (r *RESTProxy) subscribe(ctx context.Context) error {
+	var created struct {
+		BaseURI string `json:"base_uri"`
+	}
+	err := r.do(ctx, http.MethodPost, strings.TrimRight(r.URL, "/")+"/consumers/"+url.PathEscape(r.Group), map[string]string{
+		"format":             "json",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}, &created)
+	if err != nil {
+		return fmt.Errorf("create consumer: %w", err)
+	}
+	if err := r.do(ctx, http.MethodPost, created.BaseURI+"/subscription", map[string][]string{"topics": {r.Topic}}, nil); err != nil {
+		r.do(ctx, http.MethodDelete, created.BaseURI, nil, nil)
+		return fmt.Errorf("subscribe to %s: %w", r.Topic, err)
+	}
+	r.baseURI = created.BaseURI
+	return nil
+}
+
+func (r *RESTProxy) do(ctx context.Context, method, target string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", restProxyContentType)
+	}
+	req.Header.Set("Accept", restProxyContentType)
+	if method == http.MethodGet {
+		req.Header.Set("Accept", restProxyJSONRecords)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("rest proxy returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
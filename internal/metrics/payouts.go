@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/worker"
+)
+
+// Payouts collects the worker pool's metrics: attempts by outcome, failures
+// by code, bank call latency, chunk durations, worker utilization, time
+// throttled and attempt records lost. Add it to the pool with
+// worker.WithMetrics and worker.WithHooks(p.Hook()), and serve it from
+// /metrics.
+type Payouts struct {
+	*Registry
+
+	attempts    *Counter
+	failures    *Counter
+	bankLatency *Histogram
+	chunkTime   *Histogram
+	chunkSize   *Histogram
+	throttled   *Counter
+	dropped     *Counter
+	busy        atomic.Int64
+}
+
+// NewPayouts registers the payout metrics for a pool of the given
+// concurrency.
+func NewPayouts(concurrency int) *Payouts {
+	r := NewRegistry()
+	p := &Payouts{
+		Registry: r,
+		attempts: r.NewCounter("payouts_attempts_total",
+			"Payout attempts by outcome: completed, failed (for good) or retried.", "outcome"),
+		failures: r.NewCounter("payouts_failures_total",
+			"Failed payout attempts by failure code, retried ones included.", "code"),
+		bankLatency: r.NewHistogram("payouts_bank_call_duration_seconds",
+			"Time the bank took to answer a transfer.",
+			0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30),
+		chunkTime: r.NewHistogram("payouts_chunk_duration_seconds",
+			"Time taken to process a chunk of payouts.",
+			1, 5, 10, 30, 60, 120, 300, 600),
+		chunkSize: r.NewHistogram("payouts_chunk_size",
+			"Payouts per processed chunk.",
+			1, 10, 50, 100, 250, 500, 1000, 5000),
+		throttled: r.NewCounter("payouts_throttled_seconds_total",
+			"Time runs were held back by the governor, by reason: in_flight or db_writes.", "reason"),
+		dropped: r.NewCounter("payouts_attempt_logs_dropped_total",
+			"Payout attempts whose audit record was lost: its write failed and it could be neither retried nor spilled."),
+	}
+	r.NewGaugeFunc("payouts_workers_busy", "Workers processing a payout right now.", func() float64 {
+		return float64(p.busy.Load())
+	})
+	r.NewGaugeFunc("payouts_workers_capacity", "Workers a run uses once fully ramped up.", func() float64 {
+		return float64(concurrency)
+	})
+	r.NewGaugeFunc("payouts_worker_utilization", "Share of the worker capacity in use, from 0 to 1.", func() float64 {
+		if concurrency <= 0 {
+			return 0
+		}
+		return float64(p.busy.Load()) / float64(concurrency)
+	})
+	return p
+}
+
+// Hook returns the processing hook that counts attempt outcomes.
+func (p *Payouts) Hook() worker.Hook {
+	return worker.Hook{
+		Name: "metrics",
+		AfterResult: func(_ context.Context, _ models.Payout, o worker.Outcome) {
+			outcome := o.Status
+			if outcome == models.PayoutStatusPending {
+				outcome = "retried"
+			}
+			p.attempts.Inc(outcome)
+			if o.Attempt.Status == models.PayoutStatusFailed {
+				p.failures.Inc(o.Result.FailureCode)
+			}
+		},
+	}
+}
+
+// BankCall implements worker.Metrics.
+func (p *Payouts) BankCall(took time.Duration) {
+	p.bankLatency.Observe(took.Seconds())
+}
+
+// ChunkProcessed implements worker.Metrics.
+func (p *Payouts) ChunkProcessed(payouts int, took time.Duration) {
+	p.chunkTime.Observe(took.Seconds())
+	p.chunkSize.Observe(float64(payouts))
+}
+
+// Throttled implements worker.Metrics.
+func (p *Payouts) Throttled(reason string, took time.Duration) {
+	p.throttled.Add(took.Seconds(), reason)
+}
+
+// AttemptLogsDropped implements worker.Metrics.
+func (p *Payouts) AttemptLogsDropped(n int) {
+	p.dropped.Add(float64(n))
+}
+
+// WorkerBusy implements worker.Metrics.
+func (p *Payouts) WorkerBusy(delta int) {
+	p.busy.Add(int64(delta))
+}
@@ -0,0 +1,107 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/metrics"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+)
+
+// TestPayoutMetrics verifies a processed batch shows up in the scrape:
+// attempts by outcome, failures by code, a bank call per transfer, one
+// observation per chunk and no workers left busy.
+func TestPayoutMetrics(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	items := make([]models.CreatePayoutItem, 40)
+	for i := range items {
+		items[i] = models.CreatePayoutItem{
+			VendorID:    fmt.Sprintf("metrics_vendor_%04d", i),
+			Amount:      100,
+			Currency:    "USD",
+			BankAccount: fmt.Sprintf("ACC%010d", i),
+		}
+	}
+	batch, err := store.CreateBatch(ctx, items, models.BatchOptions{})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	sc := service.NewScenario()
+	sc.For(service.Vendors("metrics_vendor_0001")).Fail(models.FailureBankTimeout, 1).ThenSucceed()
+	sc.For(service.Vendors("metrics_vendor_0002")).Always(models.FailureAccountBlocked)
+	m := metrics.NewPayouts(4)
+	pool := worker.NewPool(store, 4, 10, worker.WithBankClient(sc), worker.WithMetrics(m), worker.WithHooks(m.Hook()))
+	if err := pool.ProcessBatch(ctx, batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the Prometheus text format, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE payouts_attempts_total counter\n",
+		`payouts_attempts_total{outcome="completed"} 39` + "\n",
+		`payouts_attempts_total{outcome="failed"} 1` + "\n",
+		`payouts_attempts_total{outcome="retried"} 1` + "\n",
+		`payouts_failures_total{code="ACCOUNT_BLOCKED"} 1` + "\n",
+		`payouts_failures_total{code="BANK_API_TIMEOUT"} 1` + "\n",
+		"# TYPE payouts_bank_call_duration_seconds histogram\n",
+		`payouts_bank_call_duration_seconds_bucket{le="+Inf"} 41` + "\n",
+		"payouts_bank_call_duration_seconds_count 41\n",
+		"payouts_chunk_size_sum 41\n",
+		"payouts_workers_busy 0\n",
+		"payouts_workers_capacity 4\n",
+		"payouts_worker_utilization 0\n",
+		"payouts_attempt_logs_dropped_total 0\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the scrape to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestRegistryFormat verifies the exposition format: cumulative buckets,
+// escaped label values and unlabelled counters reported from zero.
+func TestRegistryFormat(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := r.NewCounter("things_total", "Things.\nCounted.", "kind")
+	r.NewCounter("empty_total", "Nothing yet.")
+	h := r.NewHistogram("wait_seconds", "Waits.", 1, 5)
+	c.Inc(`a "quoted" \ value`)
+	for _, v := range []float64{0.5, 1, 3, 9} {
+		h.Observe(v)
+	}
+
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := `# HELP things_total Things.\nCounted.
+# TYPE things_total counter
+things_total{kind="a \"quoted\" \\ value"} 1
+# HELP empty_total Nothing yet.
+# TYPE empty_total counter
+empty_total 0
+# HELP wait_seconds Waits.
+# TYPE wait_seconds histogram
+wait_seconds_bucket{le="1"} 2
+wait_seconds_bucket{le="5"} 3
+wait_seconds_bucket{le="+Inf"} 4
+wait_seconds_sum 13.5
+wait_seconds_count 4
+`
+	if got := b.String(); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -1,6 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,11 +14,18 @@ import (
 
 // Batch statuses
 const (
+	// BatchStatusCreating is a batch created asynchronously whose payouts
+	// are still being ingested.
+	BatchStatusCreating           = "creating"
 	BatchStatusPending            = "pending"
 	BatchStatusInProgress         = "in_progress"
+	BatchStatusPaused             = "paused"
 	BatchStatusCompleted          = "completed"
 	BatchStatusFailed             = "failed"
 	BatchStatusPartiallyCompleted = "partially_completed"
+	// BatchStatusCancelled is a batch abandoned by an operator: its pending
+	// payouts were cancelled and it is never processed again.
+	BatchStatusCancelled = "cancelled"
 )
 
 // Payout statuses
@@ -21,8 +34,88 @@ const (
 	PayoutStatusProcessing = "processing"
 	PayoutStatusCompleted  = "completed"
 	PayoutStatusFailed     = "failed"
+	// PayoutStatusWrittenOff closes out a failed payout that will not be paid.
+	// Batch counters count it as failed.
+	PayoutStatusWrittenOff = "written_off"
+	// PayoutStatusCancelled closes out a payout before it was sent. Batch
+	// counters count it as failed.
+	PayoutStatusCancelled = "cancelled"
 )
 
+// Write-off reason codes
+const (
+	WriteOffVendorUnreachable = "vendor_unreachable"
+	WriteOffAccountClosed     = "account_closed"
+	WriteOffDuplicate         = "duplicate"
+	WriteOffBelowThreshold    = "below_threshold"
+	WriteOffOther             = "other"
+)
+
+// Kinds of disagreement between a payout's status and its attempt history
+const (
+	// InconsistencySuccessNotRecorded: an attempt succeeded but the payout is
+	// not completed, so it could be paid again. Repair marks it completed.
+	InconsistencySuccessNotRecorded = "success_not_recorded"
+	// InconsistencyCompletedWithoutSuccess: completed with no successful attempt.
+	InconsistencyCompletedWithoutSuccess = "completed_without_success"
+	// InconsistencyFailedWithoutAttempt: failed with no failed attempt.
+	InconsistencyFailedWithoutAttempt = "failed_without_attempt"
+	// InconsistencyPaidMoreThanOnce: more than one attempt succeeded.
+	InconsistencyPaidMoreThanOnce = "paid_more_than_once"
+	// InconsistencyAttemptsExceedCount: more attempts logged than attempt_count.
+	InconsistencyAttemptsExceedCount = "attempts_exceed_count"
+)
+
+// Payout processing orders
+const (
+	PayoutOrderFIFO           = "fifo"
+	PayoutOrderLargestFirst   = "largest_first"
+	PayoutOrderSmallestFirst  = "smallest_first"
+	PayoutOrderBankRoundRobin = "bank_round_robin"
+)
+
+// Batch run statuses
+const (
+	RunStatusRunning  = "running"
+	RunStatusFinished = "finished"
+	RunStatusStopped  = "stopped"
+	RunStatusFailed   = "failed"
+)
+
+// Batch run triggers
+const (
+	RunTriggerStart       = "start"
+	RunTriggerRetryFailed = "retry_failed"
+	RunTriggerAutoResume  = "auto_resume"    // resumed on startup after a crash
+	RunTriggerTakeover    = "lease_takeover" // taken over from an instance whose lease expired
+)
+
+// Scheduled job run statuses and triggers
+const (
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// Why a statistics snapshot was taken
+const (
+	SnapshotRunStarted = "run_started"
+	SnapshotInterval   = "interval"
+	SnapshotRunEnded   = "run_ended" // stopped, finished or failed
+)
+
+// Bank environments. Transfers in the sandbox move no real money.
+const (
+	EnvironmentSandbox    = "sandbox"
+	EnvironmentProduction = "production"
+)
+
+// AnonymousOperator is recorded as triggered_by when no operator is identified.
+const AnonymousOperator = "anonymous"
+
 // Failure reasons (simulated)
 const (
 	FailureInvalidBankAccount = "INVALID_BANK_ACCOUNT"
@@ -30,6 +123,32 @@ const (
 	FailureBankTimeout        = "BANK_API_TIMEOUT"
 	FailureAccountBlocked     = "ACCOUNT_BLOCKED"
 	FailureRateLimited        = "RATE_LIMITED"
+	// FailureBankRejected is a payout the bank rejected in its
+	// acknowledgment of a payment file.
+	FailureBankRejected = "BANK_REJECTED"
+)
+
+// VendorActionFailures are the failure reasons only the vendor can resolve,
+// e.g. by sending new bank details.
+var VendorActionFailures = []string{FailureInvalidBankAccount, FailureAccountBlocked}
+
+// RetryableFailures are the transient failure reasons; see IsRetryable.
+var RetryableFailures = []string{FailureBankTimeout, FailureRateLimited, FailureInsufficientFunds}
+
+// Vendor outreach channels
+const (
+	OutreachEmail = "email"
+	OutreachPhone = "phone"
+	OutreachSMS   = "sms"
+	OutreachChat  = "chat"
+	OutreachOther = "other"
+)
+
+// Email delivery statuses
+const (
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed"
+	EmailStatusDropped = "dropped" // never handed to the provider (queue full)
 )
 
 // PayoutBatch represents a batch of payouts to be processed.
@@ -40,32 +159,124 @@ type PayoutBatch struct {
 	CompletedCount int        `json:"completed_count"`
 	FailedCount    int        `json:"failed_count"`
 	PendingCount   int        `json:"pending_count"`
+	PayoutOrder    string     `json:"payout_order"`
 	CreatedAt      time.Time  `json:"created_at"`
 	StartedAt      *time.Time `json:"started_at,omitempty"`
 	CompletedAt    *time.Time `json:"completed_at,omitempty"`
 	UpdatedAt      time.Time  `json:"updated_at"`
+	// DeletedAt is set while the batch is soft-deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *string    `json:"deleted_by,omitempty"`
+	// Owner created the batch; AssignedTo is shepherding its runs now and is
+	// emailed when a run ends, if it is an email address.
+	Owner      *string `json:"owner,omitempty"`
+	AssignedTo *string `json:"assigned_to,omitempty"`
+	// Environment is the bank environment that executed the payouts, set by
+	// the first run; later runs must use the same one.
+	Environment *string `json:"environment,omitempty"`
+	// ProgressEvery is how often runs persist the counts: 0 once per chunk,
+	// N every N recorded payout attempts.
+	ProgressEvery int `json:"progress_every"`
+	// IngestedCount is how many of the payouts of a batch created
+	// asynchronously are written so far; unset for batches created at once.
+	IngestedCount *int `json:"ingested_count,omitempty"`
+	// CreationError says why ingesting an asynchronously created batch
+	// failed, leaving it failed without payouts.
+	CreationError *string `json:"creation_error,omitempty"`
+	// MerchantID is the merchant the batch was created for, whose usage its
+	// payouts count towards.
+	MerchantID *string `json:"merchant_id,omitempty"`
+	// IdempotencyKey is the Idempotency-Key the batch was created with; a
+	// request repeating it gets this batch back.
+	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+	// RequestHash fingerprints the request that created the batch under
+	// IdempotencyKey.
+	RequestHash *string `json:"-"`
+	// RetryPolicy is how the batch's payouts are retried, if not as the
+	// worker is configured to.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+	// Rejections are the payouts a partial-mode creation request left out.
+	Rejections *RejectionSummary `json:"rejections,omitempty"`
+	// Links are set by the API for navigating from the batch.
+	Links *BatchLinks `json:"links,omitempty"`
+}
+
+// BatchLinks are the API paths of a batch and what can be done with it, so
+// clients follow them instead of hard-coding URL templates. Start is only
+// set while the batch can be started or resumed, and Stop while it runs.
+type BatchLinks struct {
+	Self          string `json:"self"`
+	Payouts       string `json:"payouts"`
+	FailedPayouts string `json:"failed_payouts"`
+	Export        string `json:"export"`
+	Start         string `json:"start,omitempty"`
+	Stop          string `json:"stop,omitempty"`
+	Cancel        string `json:"cancel,omitempty"`
+}
+
+// IsTerminal reports whether the batch has finished processing.
+func (b *PayoutBatch) IsTerminal() bool {
+	return b.Status == BatchStatusCompleted || b.Status == BatchStatusFailed || b.Status == BatchStatusPartiallyCompleted ||
+		b.Status == BatchStatusCancelled
 }
 
 // Payout represents an individual payout within a batch.
 type Payout struct {
-	ID             uuid.UUID  `json:"id"`
-	BatchID        uuid.UUID  `json:"batch_id"`
-	IdempotencyKey string     `json:"idempotency_key"`
-	VendorID       string     `json:"vendor_id"`
-	VendorName     string     `json:"vendor_name,omitempty"`
-	Amount         float64    `json:"amount"`
-	Currency       string     `json:"currency"`
-	BankAccount    string     `json:"bank_account,omitempty"`
-	BankName       string     `json:"bank_name,omitempty"`
-	TransactionIDs []string   `json:"transaction_ids,omitempty"`
-	Status         string     `json:"status"`
-	FailureReason  *string    `json:"failure_reason,omitempty"`
-	AttemptCount   int        `json:"attempt_count"`
-	MaxRetries     int        `json:"max_retries"`
-	CreatedAt      time.Time  `json:"created_at"`
-	AttemptedAt    *time.Time `json:"attempted_at,omitempty"`
-	CompletedAt    *time.Time `json:"completed_at,omitempty"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID             uuid.UUID         `json:"id"`
+	BatchID        uuid.UUID         `json:"batch_id"`
+	IdempotencyKey string            `json:"idempotency_key"`
+	VendorID       string            `json:"vendor_id"`
+	VendorName     string            `json:"vendor_name,omitempty"`
+	Amount         float64           `json:"amount"`
+	Currency       string            `json:"currency"`
+	BankAccount    string            `json:"bank_account,omitempty"`
+	BankName       string            `json:"bank_name,omitempty"`
+	TransactionIDs []string          `json:"transaction_ids,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	Status         string            `json:"status"`
+	FailureReason  *string           `json:"failure_reason,omitempty"`
+	AttemptCount   int               `json:"attempt_count"`
+	MaxRetries     int               `json:"max_retries"`
+	CreatedAt      time.Time         `json:"created_at"`
+	AttemptedAt    *time.Time        `json:"attempted_at,omitempty"`
+	CompletedAt    *time.Time        `json:"completed_at,omitempty"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	// SplitGroupID links the payouts a vendor's payout was split into; it is
+	// the ID of the logical payout. SplitPercent is this payout's share.
+	SplitGroupID *uuid.UUID `json:"split_group_id,omitempty"`
+	SplitPercent *float64   `json:"split_percent,omitempty"`
+	// Supersedes is the failed payout this one was requeued from, and
+	// SupersededBy the payout that replaced this one.
+	Supersedes   *uuid.UUID `json:"supersedes,omitempty"`
+	SupersededBy *uuid.UUID `json:"superseded_by,omitempty"`
+	// HeldAt is set while a pending payout is on hold and is not processed.
+	HeldAt *time.Time `json:"held_at,omitempty"`
+	HeldBy *string    `json:"held_by,omitempty"`
+	Tags   []string   `json:"tags,omitempty"`
+	// NextRetryAt is when a pending payout backing off after a retryable
+	// failure may be claimed again.
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	// ReportingCountry is set on a payout above that country's regulatory
+	// reporting threshold. When approval is required it is held until
+	// someone approves it, recorded in ReportingApprovedBy and -At.
+	ReportingCountry    *string    `json:"reporting_country,omitempty"`
+	ReportingApprovedBy *string    `json:"reporting_approved_by,omitempty"`
+	ReportingApprovedAt *time.Time `json:"reporting_approved_at,omitempty"`
+	// PurposeCode is the purpose of payment sent to the bank.
+	PurposeCode *string `json:"purpose_code,omitempty"`
+	// ClaimedBy is the instance whose run claimed the payout, and
+	// LeaseExpiresAt when its claim lapses unless renewed; both are set
+	// only with claim leases on.
+	ClaimedBy      *string    `json:"claimed_by,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	// Links are set by the API for navigating from the payout.
+	Links *PayoutLinks `json:"links,omitempty"`
+}
+
+// PayoutLinks are the API paths of a payout and its batch.
+type PayoutLinks struct {
+	Self  string `json:"self"`
+	Batch string `json:"batch"`
 }
 
 // PayoutAttempt records each attempt to process a payout.
@@ -79,28 +290,848 @@ type PayoutAttempt struct {
 	FinishedAt *time.Time `json:"finished_at,omitempty"`
 }
 
+// EmailDelivery records one attempt to email a vendor about a payout, or a
+// batch's assignee about the batch.
+type EmailDelivery struct {
+	ID                uuid.UUID  `json:"id"`
+	PayoutID          *uuid.UUID `json:"payout_id,omitempty"`
+	BatchID           *uuid.UUID `json:"batch_id,omitempty"`
+	Template          string     `json:"template"`
+	Locale            string     `json:"locale"`
+	Recipient         string     `json:"recipient"`
+	Provider          string     `json:"provider"`
+	Status            string     `json:"status"`
+	ProviderMessageID *string    `json:"provider_message_id,omitempty"`
+	Error             *string    `json:"error,omitempty"`
+	AttemptedAt       time.Time  `json:"attempted_at"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookStatusSent    = "sent"
+	WebhookStatusFailed  = "failed"  // no answer, or not a 2xx answer
+	WebhookStatusDropped = "dropped" // never posted (queue full)
+)
+
+// Webhook event types. EventPing is only sent on request, to every
+// subscription, whatever its event types.
+const (
+	EventPayoutCompleted = "payout.completed"
+	EventPayoutFailed    = "payout.failed"
+	EventBatchFinished   = "batch.finished"
+	// EventBatchSettlementReport carries a SettlementReport, sent when a
+	// batch ends partially completed.
+	EventBatchSettlementReport = "batch.settlement_report"
+	EventPing                  = "ping"
+)
+
+// WebhookSubscription is an endpoint told about the events it subscribed
+// to. The secret is only returned when it is set, on creation and rotation.
+type WebhookSubscription struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description,omitempty"`
+	Secret      string    `json:"secret,omitempty"`
+	// PreviousSecret is also signed with until PreviousSecretExpiresAt,
+	// while the receiver switches to a rotated secret.
+	PreviousSecret          string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+	Active                  bool       `json:"active"`
+	CreatedBy               string     `json:"created_by"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+	// Stats summarises deliveries; only filled in for a single subscription.
+	Stats *WebhookStats `json:"stats,omitempty"`
+}
+
+// Subscribes reports whether the subscription wants events of eventType.
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	if eventType == EventPing {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookRequest registers a subscription. Without a secret one is
+// generated.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2000"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1,dive,oneof=payout.completed payout.failed batch.finished batch.settlement_report"`
+	Description string   `json:"description" binding:"max=500"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=100"`
+	Active      *bool    `json:"active"`
+}
+
+// UpdateWebhookRequest changes a subscription; omitted fields are kept.
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,url,max=2000"`
+	EventTypes  []string `json:"event_types" binding:"omitempty,min=1,dive,oneof=payout.completed payout.failed batch.finished batch.settlement_report"`
+	Description *string  `json:"description" binding:"omitempty,max=500"`
+	Active      *bool    `json:"active"`
+}
+
+// CloudEvent is an event in CloudEvents 1.0 structured JSON form, as posted
+// to webhook subscriptions. Type is the versioned event type (e.g.
+// "com.kaveri.payouts.payout.failed.v1") and DataSchema names the schema of
+// Data for that version.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              uuid.UUID `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	DataSchema      string    `json:"dataschema,omitempty"`
+	Data            any       `json:"data"`
+}
+
+// WebhookDelivery records one attempt to post an event to a subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID `json:"id"`
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	EventID        uuid.UUID `json:"event_id"`
+	EventType      string    `json:"event_type"` // CloudEvent type
+	Status         string    `json:"status"`     // WebhookStatus*
+	ResponseCode   *int      `json:"response_code,omitempty"`
+	Error          *string   `json:"error,omitempty"`
+	DurationMs     int       `json:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+}
+
+// WebhookStats summarises a subscription's deliveries.
+type WebhookStats struct {
+	Deliveries    int        `json:"deliveries"`
+	Sent          int        `json:"sent"`
+	Failed        int        `json:"failed"`
+	Dropped       int        `json:"dropped"`
+	SuccessRate   float64    `json:"success_rate_percent"`
+	AvgDurationMs float64    `json:"avg_duration_ms"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	LastStatus    string     `json:"last_status,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+}
+
+// BatchRun records one processing execution of a batch. A batch that is
+// stopped and resumed, or retried, accumulates several runs.
+type BatchRun struct {
+	ID             uuid.UUID  `json:"id"`
+	BatchID        uuid.UUID  `json:"batch_id"`
+	Trigger        string     `json:"trigger"`
+	TriggeredBy    string     `json:"triggered_by"`
+	Environment    string     `json:"environment,omitempty"`
+	Status         string     `json:"status"`
+	ChunksCount    int        `json:"chunks_processed"`
+	ProcessedCount int        `json:"processed_count"`
+	CompletedCount int        `json:"completed_count"`
+	FailedCount    int        `json:"failed_count"`
+	Error          *string    `json:"error,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	// DurationSeconds is computed on read; for a running run it is the time elapsed so far.
+	DurationSeconds float64 `json:"duration_seconds"`
+	// RunSettings are what the run was started with (see StartBatchRequest).
+	RunSettings
+}
+
+// RunSettings are the worker settings a run processes its batch with: how
+// many payouts it sends to the bank at once, and how many it claims per
+// chunk to start with. Runs from before they were recorded report zeros.
+type RunSettings struct {
+	Concurrency int `json:"concurrency"`
+	ChunkSize   int `json:"chunk_size"`
+}
+
 // --- API Request/Response types ---
 
+// StartBatchRequest is the optional payload for starting a batch.
+// Concurrency and ChunkSize override WORKER_CONCURRENCY and
+// WORKER_CHUNK_SIZE for the run, e.g. to throttle a huge batch or hurry a
+// small urgent one; zero keeps the server's setting.
+type StartBatchRequest struct {
+	Concurrency int `json:"concurrency" binding:"omitempty,min=1,max=100"`
+	ChunkSize   int `json:"chunk_size" binding:"omitempty,min=1,max=5000"`
+}
+
 // CreateBatchRequest is the payload for creating a new batch.
 type CreateBatchRequest struct {
-	Payouts []CreatePayoutItem `json:"payouts" binding:"required,min=1"`
+	Payouts []CreatePayoutItem `json:"payouts" binding:"required,min=1,dive"`
+	// PayoutOrder selects the processing order; defaults to fifo.
+	PayoutOrder string `json:"payout_order" binding:"omitempty,oneof=fifo largest_first smallest_first bank_round_robin"`
+	// Owner defaults to the X-Operator creating the batch.
+	Owner      string `json:"owner" binding:"max=100"`
+	AssignedTo string `json:"assigned_to" binding:"max=100"`
+	// ProgressEvery sets how often progress counts are persisted; 0 (the
+	// default) once per chunk.
+	ProgressEvery int `json:"progress_every" binding:"min=0,max=1000000"`
+	// DedupeStrategy says what to do with a vendor appearing more than once:
+	// reject the request (the default) or merge its entries into one payout.
+	DedupeStrategy string `json:"dedupe_strategy" binding:"omitempty,oneof=reject merge"`
+	// RetryPolicy overrides how the worker retries the batch's payouts.
+	RetryPolicy *RetryPolicy `json:"retry_policy" binding:"omitempty"`
+	// DuplicateCheck says what to do with payouts already completed in a
+	// recent batch: off, warn or block; empty takes the configured default.
+	DuplicateCheck string `json:"duplicate_check,omitempty" binding:"omitempty,oneof=off warn block"`
+	// Mode says what to do with payouts that cannot be created: refuse the
+	// whole request (all, the default) or leave them out of the batch
+	// (partial).
+	Mode string `json:"mode,omitempty" binding:"omitempty,oneof=all partial"`
+}
+
+// Batch creation modes
+const (
+	CreateModeAll     = "all"
+	CreateModePartial = "partial"
+)
+
+// RetryPolicy is how a batch's payouts are retried, in place of the
+// worker's settings. Settings left out keep the worker's.
+type RetryPolicy struct {
+	// MaxAttempts caps the transfer attempts per payout; 0 keeps
+	// DefaultMaxRetries. 1 fails fast, leaving failures to manual review.
+	MaxAttempts int `json:"max_attempts,omitempty" binding:"min=0,max=20"`
+	// Backoff spaces out the retries, in the form of RETRY_BACKOFF
+	// ("base=5s,multiplier=2,max=5m", or "off" to retry at once).
+	Backoff string `json:"backoff,omitempty"`
+	// RetryableCodes narrows the failures retried to these, from
+	// RetryableFailures; empty retries all of them.
+	RetryableCodes []string `json:"retryable_codes,omitempty" binding:"omitempty,dive,oneof=BANK_API_TIMEOUT RATE_LIMITED INSUFFICIENT_FUNDS"`
+}
+
+// Attempts returns the attempt budget of a payout under the policy; a nil
+// policy has the default one.
+func (p *RetryPolicy) Attempts() int {
+	if p == nil || p.MaxAttempts == 0 {
+		return DefaultMaxRetries
+	}
+	return p.MaxAttempts
+}
+
+// Retries reports whether a retryable failure with code is retried under
+// the policy; a nil policy retries them all.
+func (p *RetryPolicy) Retries(code string) bool {
+	return p == nil || len(p.RetryableCodes) == 0 || slices.Contains(p.RetryableCodes, code)
+}
+
+// BatchOptions holds batch-level settings chosen at creation time.
+type BatchOptions struct {
+	PayoutOrder   string
+	Owner         string
+	AssignedTo    string
+	ProgressEvery int
+	Merchant      string
+	// IdempotencyKey and RequestHash are stored on the batch; see
+	// PayoutBatch.IdempotencyKey.
+	IdempotencyKey string
+	RequestHash    string
+	RetryPolicy    *RetryPolicy
+	Rejections     *RejectionSummary
+}
+
+// Options returns the batch-level settings of the request with defaults applied.
+func (r *CreateBatchRequest) Options() BatchOptions {
+	opts := BatchOptions{PayoutOrder: r.PayoutOrder, Owner: r.Owner, AssignedTo: r.AssignedTo, ProgressEvery: r.ProgressEvery, RetryPolicy: r.RetryPolicy}
+	if opts.PayoutOrder == "" {
+		opts.PayoutOrder = PayoutOrderFIFO
+	}
+	return opts
 }
 
 // CreatePayoutItem represents a single payout in a batch creation request.
 type CreatePayoutItem struct {
-	VendorID    string  `json:"vendor_id" binding:"required"`
-	VendorName  string  `json:"vendor_name"`
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
-	Currency    string  `json:"currency" binding:"required"`
-	BankAccount     string   `json:"bank_account" binding:"required"`
-	BankName        string   `json:"bank_name"`
-	TransactionIDs  []string `json:"transaction_ids"`
+	VendorID       string   `json:"vendor_id" binding:"required"`
+	VendorName     string   `json:"vendor_name"`
+	Amount         float64  `json:"amount" binding:"required,gt=0"`
+	Currency       string   `json:"currency" binding:"required"`
+	BankAccount    string   `json:"bank_account" binding:"required_without=Splits"`
+	BankName       string   `json:"bank_name"`
+	TransactionIDs []string `json:"transaction_ids"`
+	// Metadata holds free-form vendor attributes (e.g. country, category) used for segment reporting.
+	Metadata map[string]string `json:"metadata"`
+	// Splits pays the amount into several accounts instead of BankAccount,
+	// one payout per account. The percentages must add up to 100.
+	Splits []PayoutSplit `json:"splits,omitempty" binding:"omitempty,dive"`
+	// PurposeCode is the purpose of payment the rail requires, from its
+	// country's list; empty takes the country's configured default.
+	PurposeCode string `json:"purpose_code,omitempty"`
+}
+
+// WriteOffRequest is the payload for writing off a failed payout. The
+// approver must be someone other than the operator sending the request.
+type WriteOffRequest struct {
+	ReasonCode string `json:"reason_code" binding:"required,oneof=vendor_unreachable account_closed duplicate below_threshold other"`
+	ApprovedBy string `json:"approved_by" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// PayoutWriteOff records why and on whose approval a payout was written off.
+type PayoutWriteOff struct {
+	PayoutID     uuid.UUID `json:"payout_id"`
+	ReasonCode   string    `json:"reason_code"`
+	Note         string    `json:"note,omitempty"`
+	RequestedBy  string    `json:"requested_by"`
+	ApprovedBy   string    `json:"approved_by"`
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency"`
+	WrittenOffAt time.Time `json:"written_off_at"`
+}
+
+// Payment file statuses.
+const (
+	PaymentFileGenerated    = "generated"
+	PaymentFileDelivered    = "delivered"
+	PaymentFileAcknowledged = "acknowledged" // the bank answered for every payout
+)
+
+// Bank answers for a payout in a payment file.
+const (
+	PaymentFileAccepted = "accepted"
+	PaymentFileRejected = "rejected"
+)
+
+// PaymentFile is a batch's pending payouts written out for a bank's
+// file-based channel, tracked until the bank has answered for each payout.
+type PaymentFile struct {
+	ID          uuid.UUID `json:"id"`
+	BatchID     uuid.UUID `json:"batch_id"`
+	Filename    string    `json:"filename"`
+	Status      string    `json:"status"`
+	PayoutCount int       `json:"payout_count"`
+	// AcceptedCount and RejectedCount are the payouts the bank has answered
+	// for so far.
+	AcceptedCount int `json:"accepted_count"`
+	RejectedCount int `json:"rejected_count"`
+	// EncryptedTo is the fingerprint of the key the file was encrypted to.
+	EncryptedTo string `json:"encrypted_to,omitempty"`
+	// Checksum is the SHA-256 of the file as served, in hex.
+	Checksum          string            `json:"checksum,omitempty"`
+	GeneratedBy       string            `json:"generated_by"`
+	GeneratedAt       time.Time         `json:"generated_at"`
+	DeliveredBy       string            `json:"delivered_by,omitempty"`
+	DeliveredAt       *time.Time        `json:"delivered_at,omitempty"`
+	DeliveryReference string            `json:"delivery_reference,omitempty"`
+	AcknowledgedAt    *time.Time        `json:"acknowledged_at,omitempty"`
+	Items             []PaymentFileItem `json:"items,omitempty"` // detail only
+}
+
+// PaymentFileItem is a payout in a payment file and the bank's answer for it.
+// Reference identifies the payout in bank files (see BankFile).
+type PaymentFileItem struct {
+	PayoutID   uuid.UUID  `json:"payout_id"`
+	Reference  string     `json:"reference"`
+	Result     string     `json:"result,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+}
+
+// PaymentFileDeliveryRequest records where a payment file was delivered,
+// e.g. the SFTP path or the bank's receipt number.
+type PaymentFileDeliveryRequest struct {
+	Reference string `json:"reference" binding:"required,max=500"`
+}
+
+// PaymentFileAckRequest is a bank's acknowledgment of a payment file (e.g.
+// a pain.002 status report), translated to JSON. Items answer for single
+// payouts; Result, if set, answers for every payout of the file not listed,
+// e.g. when the bank rejected the whole file.
+type PaymentFileAckRequest struct {
+	Result string               `json:"result" binding:"omitempty,oneof=accepted rejected"`
+	Reason string               `json:"reason" binding:"max=500"`
+	Items  []PaymentFileAckItem `json:"items" binding:"dive"`
+}
+
+// PaymentFileAckItem is the bank's answer for one payout. Reason is the
+// bank's reason code for a rejection.
+type PaymentFileAckItem struct {
+	PayoutID uuid.UUID `json:"payout_id" binding:"required"`
+	Result   string    `json:"result" binding:"required,oneof=accepted rejected"`
+	Reason   string    `json:"reason" binding:"max=500"`
+}
+
+// Bank file formats.
+const (
+	BankFilePain002     = "pain.002"     // ISO 20022 customer payment status report
+	BankFileNACHAReturn = "nacha_return" // NACHA file of returned entries
+)
+
+// Outcomes of a bank file's answer for a payout.
+const (
+	BankAnswerApplied  = "applied"          // recorded, completing or failing the payout
+	BankAnswerAnswered = "already_answered" // the payout already had this answer
+	// BankAnswerConflict is an answer contradicting an earlier one, e.g. a
+	// return of a payout the bank had accepted. It is not applied.
+	BankAnswerConflict  = "conflict"
+	BankAnswerUnmatched = "unmatched" // no delivered payment file has the payout
+)
+
+// BankFile is an acknowledgment or return file a bank sent back for payment
+// files, and what its answers did. Payouts are matched by their payment
+// file reference: the payout ID as 32 hex digits, or for NACHA its first
+// 15, as carried in the individual identification number.
+type BankFile struct {
+	ID        uuid.UUID `json:"id"`
+	Format    string    `json:"format"`
+	MessageID string    `json:"message_id,omitempty"`
+	// PaymentFileID is the payment file the bank answered for as a whole
+	// with FileResult, which then stands for every payout of the file not
+	// answered one by one.
+	PaymentFileID *uuid.UUID `json:"payment_file_id,omitempty"`
+	FileResult    string     `json:"file_result,omitempty"`
+	FileReason    string     `json:"file_reason,omitempty"`
+	Checksum      string     `json:"checksum"`
+	ReceivedBy    string     `json:"received_by"`
+	ReceivedAt    time.Time  `json:"received_at"`
+	// Counts of Answers by outcome.
+	Applied         int `json:"applied"`
+	AlreadyAnswered int `json:"already_answered"`
+	Conflicts       int `json:"conflicts"`
+	Unmatched       int `json:"unmatched"`
+	// Pending counts payouts the bank reported as not decided yet.
+	Pending int              `json:"pending"`
+	Answers []BankFileAnswer `json:"answers"`
+}
+
+// BankFileAnswer is a bank file's answer for one payout. Reason is the
+// bank's reason code for a rejection or return, e.g. AC04 or R03.
+type BankFileAnswer struct {
+	Reference     string     `json:"reference"`
+	Result        string     `json:"result"`
+	Reason        string     `json:"reason,omitempty"`
+	PayoutID      *uuid.UUID `json:"payout_id,omitempty"`
+	PaymentFileID *uuid.UUID `json:"payment_file_id,omitempty"`
+	Outcome       string     `json:"outcome,omitempty"`
+}
+
+// OutreachRequest is the payload for logging contact with a vendor about a
+// failed payout. ContactedAt defaults to now.
+type OutreachRequest struct {
+	Channel     string     `json:"channel" binding:"required,oneof=email phone sms chat other"`
+	Note        string     `json:"note"`
+	ContactedAt *time.Time `json:"contacted_at"`
+}
+
+// VendorOutreach is one logged contact with a vendor about a failed payout.
+type VendorOutreach struct {
+	ID          uuid.UUID `json:"id"`
+	PayoutID    uuid.UUID `json:"payout_id"`
+	Channel     string    `json:"channel"`
+	Note        string    `json:"note,omitempty"`
+	ContactedAt time.Time `json:"contacted_at"`
+	RecordedBy  string    `json:"recorded_by"`
+}
+
+// Bulk payout actions
+const (
+	BulkHold    = "hold"
+	BulkRelease = "release"
+	BulkCancel  = "cancel"
+	BulkRetry   = "retry"
+	BulkAddTag  = "add_tag"
+)
+
+// MaxBulkPayouts is the most payouts one bulk request may act on.
+const MaxBulkPayouts = 500
+
+// BulkPayoutRequest applies one action to many payouts in one transaction.
+// Payouts the action does not apply to are skipped, or, with AllOrNothing,
+// cause nothing to be applied.
+type BulkPayoutRequest struct {
+	Action       string      `json:"action" binding:"required,oneof=hold release cancel retry add_tag"`
+	PayoutIDs    []uuid.UUID `json:"payout_ids" binding:"required,min=1,max=500"`
+	Tag          string      `json:"tag" binding:"omitempty,max=50"`
+	AllOrNothing bool        `json:"all_or_nothing"`
+}
+
+// Bulk payout results and skip reasons
+const (
+	BulkApplied           = "applied"
+	BulkSkipped           = "skipped"
+	BulkRolledBack        = "rolled_back" // eligible, but all_or_nothing undid the request
+	BulkSkipNotFound      = "not_found"
+	BulkSkipDuplicate     = "duplicate"
+	BulkSkipNotEligible   = "not_eligible"
+	BulkSkipAlreadyTagged = "already_tagged"
+	// BulkSkipAwaitingApproval is a release of a payout held for its
+	// reporting approval, which only the approval releases.
+	BulkSkipAwaitingApproval = "awaiting_approval"
+)
+
+// BulkPayoutResult is the outcome of a bulk action for one payout.
+type BulkPayoutResult struct {
+	PayoutID uuid.UUID `json:"payout_id"`
+	Result   string    `json:"result"`
+	Reason   string    `json:"reason,omitempty"`
+	// Status is the payout's status after the action.
+	Status string `json:"status,omitempty"`
+}
+
+// BulkPayoutResponse reports a bulk action per payout, in request order.
+// Committed is false when AllOrNothing rolled everything back.
+type BulkPayoutResponse struct {
+	Action    string             `json:"action"`
+	Committed bool               `json:"committed"`
+	Applied   int                `json:"applied"`
+	Skipped   int                `json:"skipped"`
+	Results   []BulkPayoutResult `json:"results"`
+	// Batches lists the batches of applied payouts.
+	Batches []uuid.UUID `json:"batches"`
+}
+
+// PayoutFilter selects payouts across batches for triage. Empty fields match
+// every payout; payouts of deleted batches are never listed.
+type PayoutFilter struct {
+	Statuses       []string `json:"statuses,omitempty" binding:"omitempty,dive,oneof=pending processing completed failed written_off cancelled"`
+	Currencies     []string `json:"currencies,omitempty" binding:"omitempty,dive,len=3"`
+	FailureReasons []string `json:"failure_reasons,omitempty" binding:"omitempty,dive,oneof=INVALID_BANK_ACCOUNT INSUFFICIENT_FUNDS BANK_API_TIMEOUT ACCOUNT_BLOCKED RATE_LIMITED BANK_REJECTED"`
+	// Retryable keeps payouts whose failure is transient (true) or
+	// permanent (false); payouts without a failure never match it.
+	Retryable *bool    `json:"retryable,omitempty"`
+	MinAmount *float64 `json:"min_amount,omitempty" binding:"omitempty,gte=0"` // inclusive
+	MaxAmount *float64 `json:"max_amount,omitempty" binding:"omitempty,gte=0"` // inclusive
+	BankNames []string `json:"bank_names,omitempty"`
+	// Tags keeps payouts carrying every one of these tags.
+	Tags    []string   `json:"tags,omitempty"`
+	Held    *bool      `json:"held,omitempty"`
+	BatchID *uuid.UUID `json:"batch_id,omitempty"`
+}
+
+// PayoutView is a named, saved PayoutFilter shared by the dashboard and
+// payoutctl as a triage queue.
+type PayoutView struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Filter      PayoutFilter `json:"filter"`
+	UpdatedBy   string       `json:"updated_by"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// SavePayoutViewRequest creates or replaces a payout view.
+type SavePayoutViewRequest struct {
+	Description string       `json:"description" binding:"max=500"`
+	Filter      PayoutFilter `json:"filter"`
+}
+
+// RequeueRequest is the payload for requeuing failed payouts into a new batch.
+type RequeueRequest struct {
+	Payouts []RequeueItem `json:"payouts" binding:"required,min=1,dive"`
+	// PayoutOrder selects the new batch's processing order; defaults to fifo.
+	PayoutOrder string `json:"payout_order" binding:"omitempty,oneof=fifo largest_first smallest_first bank_round_robin"`
+}
+
+// Options returns the new batch's settings with defaults applied.
+func (r *RequeueRequest) Options() BatchOptions {
+	opts := BatchOptions{PayoutOrder: r.PayoutOrder}
+	if opts.PayoutOrder == "" {
+		opts.PayoutOrder = PayoutOrderFIFO
+	}
+	return opts
+}
+
+// RequeueItem names a failed payout to requeue. Setting BankAccount
+// re-routes it to a backup account; BankName defaults to the original's.
+type RequeueItem struct {
+	PayoutID    uuid.UUID `json:"payout_id" binding:"required"`
+	BankAccount string    `json:"bank_account"`
+	BankName    string    `json:"bank_name"`
+}
+
+// PayoutSplit is one account's share of a split payout.
+type PayoutSplit struct {
+	Percent     float64 `json:"percent" binding:"required,gt=0,lt=100"`
+	BankAccount string  `json:"bank_account" binding:"required"`
+	BankName    string  `json:"bank_name"` // defaults to the item's bank_name
+}
+
+// Split instruction errors.
+var (
+	ErrSplitTooFew = errors.New("a split needs at least two accounts")
+	ErrSplitTotal  = errors.New("split percentages must add up to 100")
+)
+
+// ValidateSplits checks the item's split instructions, if it has any.
+func (i CreatePayoutItem) ValidateSplits() error {
+	if len(i.Splits) == 0 {
+		return nil
+	}
+	if len(i.Splits) < 2 {
+		return ErrSplitTooFew
+	}
+	var total float64
+	for _, s := range i.Splits {
+		total += s.Percent
+	}
+	if math.Abs(total-100) > 1e-9 {
+		return ErrSplitTotal
+	}
+	return nil
+}
+
+// Strategies for a vendor appearing more than once in a batch request.
+const (
+	DedupeReject = "reject"
+	DedupeMerge  = "merge"
+)
+
+// DuplicateVendors returns the vendor IDs appearing more than once among
+// items, in order of first appearance.
+func DuplicateVendors(items []CreatePayoutItem) []string {
+	seen := make(map[string]int, len(items))
+	var dups []string
+	for _, item := range items {
+		seen[item.VendorID]++
+		if seen[item.VendorID] == 2 {
+			dups = append(dups, item.VendorID)
+		}
+	}
+	return dups
+}
+
+// MergeDuplicateVendors merges each vendor's entries into its first one:
+// amounts are summed, transaction IDs joined without repeats, and metadata
+// and the vendor name filled in from later entries where the first has
+// none. Entries paying a different currency, account, bank, split or
+// purpose than the first cannot be merged; their vendor IDs are returned
+// and the items are then left as they were.
+func MergeDuplicateVendors(items []CreatePayoutItem) ([]CreatePayoutItem, []string) {
+	index := make(map[string]int, len(items))
+	merged := make([]CreatePayoutItem, 0, len(items))
+	var conflicts []string
+	for _, item := range items {
+		i, ok := index[item.VendorID]
+		if !ok {
+			index[item.VendorID] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+		first := &merged[i]
+		if !samePayment(*first, item) {
+			if !slices.Contains(conflicts, item.VendorID) {
+				conflicts = append(conflicts, item.VendorID)
+			}
+			continue
+		}
+		// The first entry's slice and map are the caller's; copy them
+		// before adding to them.
+		first.TransactionIDs, first.Metadata = slices.Clone(first.TransactionIDs), maps.Clone(first.Metadata)
+		first.Amount += item.Amount
+		for _, id := range item.TransactionIDs {
+			if !slices.Contains(first.TransactionIDs, id) {
+				first.TransactionIDs = append(first.TransactionIDs, id)
+			}
+		}
+		for k, v := range item.Metadata {
+			if _, ok := first.Metadata[k]; !ok {
+				if first.Metadata == nil {
+					first.Metadata = map[string]string{}
+				}
+				first.Metadata[k] = v
+			}
+		}
+		if first.VendorName == "" {
+			first.VendorName = item.VendorName
+		}
+	}
+	if len(conflicts) > 0 {
+		return items, conflicts
+	}
+	return merged, nil
+}
+
+// MergeableWith reports whether another entry for the same vendor can be
+// merged into this one by MergeDuplicateVendors.
+func (i CreatePayoutItem) MergeableWith(other CreatePayoutItem) bool {
+	return samePayment(i, other)
+}
+
+// samePayment reports whether two entries pay into the same place in the
+// same way, and so can be merged into one payout.
+func samePayment(a, b CreatePayoutItem) bool {
+	return a.Currency == b.Currency && a.BankAccount == b.BankAccount && a.BankName == b.BankName &&
+		a.PurposeCode == b.PurposeCode && slices.Equal(a.Splits, b.Splits)
+}
+
+// Policies for payouts matching one completed in a recent batch.
+const (
+	DuplicateCheckOff   = "off"
+	DuplicateCheckWarn  = "warn"
+	DuplicateCheckBlock = "block"
+)
+
+// DuplicatePayout is an item of a batch request matching a payout already
+// completed: same vendor, amount, currency and transaction IDs.
+type DuplicatePayout struct {
+	// Index is the item's position in the request.
+	Index          int        `json:"index"`
+	VendorID       string     `json:"vendor_id"`
+	Amount         float64    `json:"amount"`
+	Currency       string     `json:"currency"`
+	TransactionIDs []string   `json:"transaction_ids,omitempty"`
+	PriorPayoutID  uuid.UUID  `json:"prior_payout_id"`
+	PriorBatchID   uuid.UUID  `json:"prior_batch_id"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// MatchCompletedPayouts returns the items matching one of the completed
+// payouts, in item order. The parts of a split payout are matched as one
+// payout of their total; transaction IDs match in any order.
+func MatchCompletedPayouts(items []CreatePayoutItem, completed []Payout) []DuplicatePayout {
+	var whole []Payout
+	splits := map[uuid.UUID]int{} // split group -> index in whole
+	for _, p := range completed {
+		if p.SplitGroupID != nil {
+			if i, ok := splits[*p.SplitGroupID]; ok {
+				whole[i].Amount += p.Amount
+				continue
+			}
+			splits[*p.SplitGroupID] = len(whole)
+		}
+		whole = append(whole, p)
+	}
+	prior := make(map[paymentKey]Payout, len(whole))
+	for _, p := range whole {
+		prior[keyPayment(p.VendorID, p.Amount, p.Currency, p.TransactionIDs)] = p
+	}
+
+	var dups []DuplicatePayout
+	for i, item := range items {
+		p, ok := prior[keyPayment(item.VendorID, item.Amount, item.Currency, item.TransactionIDs)]
+		if !ok {
+			continue
+		}
+		dups = append(dups, DuplicatePayout{
+			Index: i, VendorID: item.VendorID, Amount: item.Amount, Currency: item.Currency, TransactionIDs: item.TransactionIDs,
+			PriorPayoutID: p.ID, PriorBatchID: p.BatchID, CompletedAt: p.CompletedAt,
+		})
+	}
+	return dups
+}
+
+// paymentKey identifies a payment for duplicate detection. Amounts are
+// kept to the cent, so the summed parts of a split payout still match.
+type paymentKey struct {
+	vendorID, currency, transactionIDs string
+	cents                              int64
+}
+
+func keyPayment(vendorID string, amount float64, currency string, transactionIDs []string) paymentKey {
+	ids := slices.Clone(transactionIDs)
+	slices.Sort(ids)
+	return paymentKey{vendorID: vendorID, currency: currency, transactionIDs: strings.Join(ids, "\x00"), cents: int64(math.Round(amount * 100))}
+}
+
+// BatchValidation is the result of validating a batch request without
+// creating it: how many payouts passed, how often each check failed, and
+// every problem found, by payout.
+type BatchValidation struct {
+	Valid      bool           `json:"valid"`
+	Payouts    int            `json:"payouts"`
+	Accepted   int            `json:"accepted"`
+	Rejected   int            `json:"rejected"`
+	Violations map[string]int `json:"violations,omitempty"`
+	Errors     []PayoutError  `json:"errors"`
+}
+
+// PayoutError is a problem with one payout of a batch request. Index is the
+// payout's position in the request; Rule names the check it failed.
+type PayoutError struct {
+	Index    int    `json:"index"`
+	VendorID string `json:"vendor_id,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// MaxRecordedRejections caps the rejected payouts a batch records; the
+// creation response lists them all.
+const MaxRecordedRejections = 1000
+
+// RejectionSummary is what a partial-mode creation request left out of the
+// batch it created: how many payouts were submitted and rejected, how often
+// each check failed, and the rejected payouts by index in the request.
+type RejectionSummary struct {
+	Submitted  int            `json:"submitted"`
+	Rejected   int            `json:"rejected"`
+	Violations map[string]int `json:"violations"`
+	Errors     []PayoutError  `json:"errors"`
+	// Truncated is set when more than MaxRecordedRejections were rejected.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// NewRejectionSummary summarizes the errors of the payouts rejected from a
+// request of submitted payouts, one or more per rejected payout.
+func NewRejectionSummary(submitted int, errs []PayoutError) *RejectionSummary {
+	s := &RejectionSummary{Submitted: submitted, Violations: map[string]int{}, Errors: errs}
+	rejected := map[int]bool{}
+	for _, e := range errs {
+		rejected[e.Index] = true
+		s.Violations[e.Rule]++
+	}
+	s.Rejected = len(rejected)
+	if len(errs) > MaxRecordedRejections {
+		s.Errors, s.Truncated = errs[:MaxRecordedRejections], true
+	}
+	return s
+}
+
+// PayoutInstructionSchemaVersion is the version of PayoutInstruction that
+// the Kafka consumer accepts.
+const PayoutInstructionSchemaVersion = 1
+
+// PayoutInstruction is a payout-instruction message read from Kafka: one
+// payout, in the shape of a batch creation item, tagged with its schema
+// version.
+type PayoutInstruction struct {
+	SchemaVersion int `json:"schema_version"`
+	CreatePayoutItem
+}
+
+// ImportProfile describes how one partner's CSV files map to payouts.
+type ImportProfile struct {
+	Name string `json:"name"`
+	// Columns maps payout fields (vendor_id, amount, ...) to CSV headers.
+	Columns map[string]string `json:"columns"`
+	// Metadata maps metadata keys to CSV headers.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Defaults fills fields that are not in the file or empty in a row.
+	Defaults         map[string]string `json:"defaults,omitempty"`
+	Delimiter        string            `json:"delimiter,omitempty"`         // default ","
+	DecimalSeparator string            `json:"decimal_separator,omitempty"` // "." (default) or ","
+	Rules            ImportRules       `json:"rules"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// ImportRules are a profile's data-quality checks, applied to every imported
+// row on top of the fields a payout always requires.
+type ImportRules struct {
+	// Required lists payout fields or metadata keys that must be non-empty.
+	Required []string `json:"required,omitempty"`
+	// BankAccountPattern is a regular expression the whole bank account must match.
+	BankAccountPattern string   `json:"bank_account_pattern,omitempty"`
+	MinAmount          *float64 `json:"min_amount,omitempty"`
+	MaxAmount          *float64 `json:"max_amount,omitempty"`
+	// Currencies, if set, is the only currencies accepted.
+	Currencies []string `json:"currencies,omitempty"`
 }
 
 // BatchSummary is the response for batch status queries.
 type BatchSummary struct {
-	Batch      PayoutBatch       `json:"batch"`
-	Statistics BatchStatistics   `json:"statistics"`
+	Batch       PayoutBatch     `json:"batch"`
+	StatusLabel string          `json:"status_label"` // localized display label of the status
+	Statistics  BatchStatistics `json:"statistics"`
+	// EstimatedCompletionAt is the live ETA of an in-progress batch from the
+	// throughput model; absent when there is no history to go on.
+	EstimatedCompletionAt *time.Time `json:"estimated_completion_at,omitempty"`
+	// QueuePosition is the batch's place in this server's start queue (1
+	// runs next); absent when the batch is not queued.
+	QueuePosition *int `json:"queue_position,omitempty"`
 }
 
 // BatchStatistics holds aggregated counts.
@@ -112,16 +1143,866 @@ type BatchStatistics struct {
 	Processing     int     `json:"processing"`
 	SuccessRate    float64 `json:"success_rate_percent"`
 	CompletionRate float64 `json:"completion_rate_percent"`
+	// Superseded counts the failed payouts that were requeued elsewhere, and
+	// WrittenOff those closed out by a write-off; both are included in Failed.
+	Superseded int `json:"superseded"`
+	WrittenOff int `json:"written_off"`
+	// Cancelled counts payouts cancelled before they were sent, and Held the
+	// pending payouts on hold; they are included in Failed and Pending.
+	Cancelled int `json:"cancelled"`
+	Held      int `json:"held"`
+	// Logical counts payouts as created, with the shares of a split payout
+	// counted once.
+	Logical LogicalStatistics `json:"logical"`
+}
+
+// LogicalStatistics counts logical payouts. A split payout is completed or
+// failed once all its shares are, and partially completed when its shares
+// finished with both outcomes.
+type LogicalStatistics struct {
+	Total              int `json:"total"`
+	Split              int `json:"split"`
+	Completed          int `json:"completed"`
+	PartiallyCompleted int `json:"partially_completed"`
+	Failed             int `json:"failed"`
+	Unfinished         int `json:"unfinished"`
+}
+
+// PayoutInconsistency is a payout whose status contradicts its attempt history.
+type PayoutInconsistency struct {
+	PayoutID   uuid.UUID `json:"payout_id"`
+	BatchID    uuid.UUID `json:"batch_id"`
+	Status     string    `json:"status"`
+	Kind       string    `json:"kind"`
+	Detail     string    `json:"detail"`
+	Repairable bool      `json:"repairable"`
+}
+
+// BatchRepair describes what repairing a batch changed.
+type BatchRepair struct {
+	BatchID         uuid.UUID   `json:"batch_id"`
+	StatusBefore    string      `json:"status_before"`
+	StatusAfter     string      `json:"status_after"`
+	CountsBefore    BatchCounts `json:"counts_before"`
+	CountsAfter     BatchCounts `json:"counts_after"`
+	PayoutsRepaired int         `json:"payouts_repaired"`
+	// Skipped is set when the batch was left alone, e.g. while a run is live.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Consistency checks run by batch verification
+const (
+	CheckCounters = "counters" // stored counters vs payout rows
+	CheckStatus   = "status"   // batch status vs payout statuses
+	CheckAttempts = "attempts" // payout statuses vs attempt history
+	CheckLedger   = "ledger"   // funding reservations vs completed amounts
+)
+
+// Discrepancy is one disagreement found by batch verification.
+type Discrepancy struct {
+	Check    string     `json:"check"`
+	Field    string     `json:"field,omitempty"`
+	PayoutID *uuid.UUID `json:"payout_id,omitempty"`
+	Expected any        `json:"expected,omitempty"`
+	Actual   any        `json:"actual,omitempty"`
+	Detail   string     `json:"detail"`
+}
+
+// BatchVerification is the discrepancy report for one batch.
+type BatchVerification struct {
+	BatchID    uuid.UUID `json:"batch_id"`
+	Status     string    `json:"status"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Consistent bool      `json:"consistent"`
+	// RunLive means a processing run holds the batch, so the status and
+	// ledger checks were skipped: mid-run they are expected to disagree.
+	RunLive       bool          `json:"run_live"`
+	Checks        []string      `json:"checks"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// BatchCounts are a batch's stored payout counters.
+type BatchCounts struct {
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Pending   int `json:"pending"` // pending and processing
+}
+
+// Changed reports whether the repair changed anything.
+func (r *BatchRepair) Changed() bool {
+	return r.StatusBefore != r.StatusAfter || r.CountsBefore != r.CountsAfter || r.PayoutsRepaired > 0
+}
+
+// OrphanRecovery reports a sweep of every batch for payouts left in
+// processing by runs that no longer exist.
+type OrphanRecovery struct {
+	Applied bool `json:"applied"`
+	// Reset is how many payouts were (or, unapplied, would be) put back to
+	// pending across all batches.
+	Reset   int             `json:"reset"`
+	Batches []OrphanedBatch `json:"batches"`
+}
+
+// OrphanedBatch is what the sweep found in one batch with payouts in
+// processing.
+type OrphanedBatch struct {
+	BatchID    uuid.UUID `json:"batch_id"`
+	Processing int       `json:"processing"`
+	Reset      int       `json:"reset"`
+	// Leased payouts are held by a live claim lease and left alone.
+	Leased int `json:"leased"`
+	// Exhausted payouts used up their attempts, so whether the last one
+	// paid is unknown; they are left for verification.
+	Exhausted int `json:"exhausted"`
+	// Skipped is set when the batch was left alone, e.g. while a run is live.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// SegmentStatistics holds batch statistics for one value of a vendor attribute.
+type SegmentStatistics struct {
+	Segment string `json:"segment"`
+	BatchStatistics
+}
+
+// SegmentedStatistics is the response for batch statistics grouped by a vendor attribute.
+type SegmentedStatistics struct {
+	BatchID  uuid.UUID           `json:"batch_id"`
+	GroupBy  string              `json:"group_by"`
+	Segments []SegmentStatistics `json:"segments"`
+}
+
+// StatisticsSnapshot is a batch's statistics as they stood when a run
+// recorded them.
+type StatisticsSnapshot struct {
+	BatchID     uuid.UUID       `json:"batch_id"`
+	RunID       *uuid.UUID      `json:"run_id,omitempty"`
+	TakenAt     time.Time       `json:"taken_at"`
+	Reason      string          `json:"reason"`
+	BatchStatus string          `json:"batch_status"`
+	Statistics  BatchStatistics `json:"statistics"`
+}
+
+// CurrencyTotals is the money breakdown of a batch for one currency.
+// Amounts are never summed across currencies.
+type CurrencyTotals struct {
+	Currency        string  `json:"currency"`
+	PayoutCount     int     `json:"payout_count"`
+	GrossAmount     float64 `json:"gross_amount"`
+	DisbursedAmount float64 `json:"disbursed_amount"`
+	PendingAmount   float64 `json:"pending_amount"`
+	// FailedAmount leaves out failed payouts that were requeued, which are
+	// counted in SupersededAmount instead, so the money is not reported as
+	// failed here and again as disbursed or pending in the new batch.
+	FailedAmount     float64 `json:"failed_amount"`
+	SupersededAmount float64 `json:"superseded_amount"`
+	WrittenOffAmount float64 `json:"written_off_amount"`
+	CancelledAmount  float64 `json:"cancelled_amount"`
+}
+
+// BatchFinancialSummary is the response for a batch's financial summary.
+type BatchFinancialSummary struct {
+	BatchID      uuid.UUID            `json:"batch_id"`
+	Status       string               `json:"status"`
+	Currencies   []CurrencyTotals     `json:"currencies"`
+	Reservations []FundingReservation `json:"reservations"`
+}
+
+// BatchRunListResponse lists the processing runs of a batch, oldest first.
+type BatchRunListResponse struct {
+	BatchID uuid.UUID  `json:"batch_id"`
+	Runs    []BatchRun `json:"runs"`
+}
+
+// CurrencyExposure is the money currently being transferred in one currency.
+type CurrencyExposure struct {
+	Currency    string  `json:"currency"`
+	PayoutCount int     `json:"payout_count"`
+	Amount      float64 `json:"amount"`
+	// OldestSince is when the longest-outstanding of these transfers was sent.
+	OldestSince *time.Time `json:"oldest_since,omitempty"`
+	// Limit is the most the processor keeps in flight in this currency, if capped.
+	Limit *float64 `json:"limit,omitempty"`
+}
+
+// ExposureReport is the treasury view of money sent to banks but not yet
+// confirmed, computed on every request.
+type ExposureReport struct {
+	Currencies  []CurrencyExposure `json:"currencies"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// APIUsage counts one merchant's API calls on one UTC day.
+type APIUsage struct {
+	MerchantID string    `json:"merchant_id"`
+	Day        time.Time `json:"day"`
+	Calls      int64     `json:"calls"`
+	// Errors are the calls answered with a 5xx status.
+	Errors int64 `json:"errors"`
+}
+
+// MerchantUsage is what one merchant used over a usage report's period:
+// API calls, and the payouts of the batches created for it that finished
+// in the period.
+type MerchantUsage struct {
+	MerchantID       string           `json:"merchant_id"`
+	APICalls         int64            `json:"api_calls"`
+	APIErrors        int64            `json:"api_errors"`
+	BatchesCreated   int              `json:"batches_created"`
+	PayoutsCompleted int              `json:"payouts_completed"`
+	PayoutsFailed    int              `json:"payouts_failed"`
+	Amounts          []CurrencyAmount `json:"amounts"`
+}
+
+// UsageReport totals usage per merchant for chargeback or billing. From and
+// To are UTC dates, To exclusive; either may be open.
+type UsageReport struct {
+	From        *time.Time      `json:"from,omitempty"`
+	To          *time.Time      `json:"to,omitempty"`
+	Merchants   []MerchantUsage `json:"merchants"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
+
+// JobRun records one run of a scheduled background job, on schedule or
+// triggered by hand.
+type JobRun struct {
+	ID          uuid.UUID  `json:"id"`
+	Job         string     `json:"job"`
+	Trigger     string     `json:"trigger"`
+	TriggeredBy string     `json:"triggered_by,omitempty"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// DurationSeconds is computed on read; for a running run it is the time elapsed so far.
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// ScheduledJob is a background job as the scheduler knows it. NextRunAt is
+// unset for a disabled job.
+type ScheduledJob struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	Enabled   bool       `json:"enabled"`
+	Running   bool       `json:"running"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRun   *JobRun    `json:"last_run,omitempty"`
+}
+
+// QuotaLimits are a merchant's quotas; 0 leaves a limit off.
+type QuotaLimits struct {
+	PayoutsPerMonth   int `json:"payouts_per_month"`
+	BatchSize         int `json:"batch_size"`
+	ConcurrentBatches int `json:"concurrent_batches"`
+}
+
+// QuotaStatus is a merchant's quotas and what it is using of them. Limits
+// is nil for a merchant no quota applies to.
+type QuotaStatus struct {
+	MerchantID       string       `json:"merchant_id"`
+	Limits           *QuotaLimits `json:"limits,omitempty"`
+	MonthStart       time.Time    `json:"month_start"`
+	PayoutsThisMonth int          `json:"payouts_this_month"`
+	BatchesRunning   int          `json:"batches_running"`
+	// Approaching is set once the monthly payouts pass the warning share
+	// of the quota.
+	Approaching bool `json:"approaching"`
+}
+
+// Write-off report intervals
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// WriteOffPeriod totals one currency's write-offs in one period.
+type WriteOffPeriod struct {
+	PeriodStart time.Time `json:"period_start"`
+	Currency    string    `json:"currency"`
+	PayoutCount int       `json:"payout_count"`
+	Amount      float64   `json:"amount"`
+}
+
+// WriteOffReport totals written-off amounts per period (in UTC) and
+// currency, oldest period first.
+type WriteOffReport struct {
+	Interval    string           `json:"interval"`
+	From        *time.Time       `json:"from,omitempty"`
+	To          *time.Time       `json:"to,omitempty"`
+	Periods     []WriteOffPeriod `json:"periods"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// ReportingThreshold flags a country's payouts in Currency above Amount
+// for regulatory reporting.
+type ReportingThreshold struct {
+	Country  string  `json:"country"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// ReportablePayout is a payout flagged for regulatory reporting.
+type ReportablePayout struct {
+	PayoutID         uuid.UUID  `json:"payout_id"`
+	BatchID          uuid.UUID  `json:"batch_id"`
+	VendorID         string     `json:"vendor_id"`
+	VendorName       string     `json:"vendor_name,omitempty"`
+	Country          string     `json:"country"`
+	Amount           float64    `json:"amount"`
+	Currency         string     `json:"currency"`
+	Status           string     `json:"status"`
+	CreatedAt        time.Time  `json:"created_at"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	AwaitingApproval bool       `json:"awaiting_approval"`
+	ApprovedBy       *string    `json:"approved_by,omitempty"`
+	ApprovedAt       *time.Time `json:"approved_at,omitempty"`
+}
+
+// RegulatoryReport lists the payouts flagged for reporting that were
+// created in a period (in UTC), oldest first, under the thresholds in force.
+type RegulatoryReport struct {
+	Country          string               `json:"country,omitempty"`
+	From             *time.Time           `json:"from,omitempty"`
+	To               *time.Time           `json:"to,omitempty"`
+	Thresholds       []ReportingThreshold `json:"thresholds"`
+	RequireApproval  bool                 `json:"require_approval"`
+	Payouts          []ReportablePayout   `json:"payouts"`
+	AwaitingApproval int                  `json:"awaiting_approval"`
+	GeneratedAt      time.Time            `json:"generated_at"`
+}
+
+// VendorTaxID is a vendor's tax identifier: an NPWP (ID), TIN (PH) or MST
+// (VN), formatted as the tax authority prints it.
+type VendorTaxID struct {
+	VendorID  string    `json:"vendor_id"`
+	Country   string    `json:"country"`
+	TaxID     string    `json:"tax_id"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetVendorTaxIDRequest is the payload for recording a vendor's tax ID,
+// validated against the country's format.
+type SetVendorTaxIDRequest struct {
+	Country string `json:"country" binding:"required,len=2"`
+	TaxID   string `json:"tax_id" binding:"required"`
+}
+
+// TaxSummary totals completed payouts per vendor and currency over a
+// period (in UTC) for tax reporting, flagging vendors paid above the
+// currency's threshold without a tax ID on file.
+type TaxSummary struct {
+	From        *time.Time         `json:"from,omitempty"`
+	To          *time.Time         `json:"to,omitempty"`
+	Vendors     []TaxSummaryVendor `json:"vendors"`
+	MissingIDs  int                `json:"missing_tax_ids"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// TaxSummaryVendor is one vendor's completed payouts in a currency.
+// RequiresTaxID is set when they exceed the currency's threshold, and
+// MissingTaxID when such a vendor has no tax ID on file.
+type TaxSummaryVendor struct {
+	VendorID      string   `json:"vendor_id"`
+	VendorName    string   `json:"vendor_name,omitempty"`
+	Country       string   `json:"country,omitempty"`
+	TaxID         string   `json:"tax_id,omitempty"`
+	Currency      string   `json:"currency"`
+	PayoutCount   int      `json:"payout_count"`
+	Amount        float64  `json:"amount"`
+	Threshold     *float64 `json:"threshold,omitempty"`
+	RequiresTaxID bool     `json:"requires_tax_id"`
+	MissingTaxID  bool     `json:"missing_tax_id"`
+}
+
+// AwaitingVendorPayout is a failed payout only the vendor can resolve, with
+// the outreach made about it so far.
+type AwaitingVendorPayout struct {
+	PayoutID      uuid.UUID  `json:"payout_id"`
+	BatchID       uuid.UUID  `json:"batch_id"`
+	VendorID      string     `json:"vendor_id"`
+	VendorName    string     `json:"vendor_name,omitempty"`
+	Amount        float64    `json:"amount"`
+	Currency      string     `json:"currency"`
+	FailureReason string     `json:"failure_reason"`
+	FailedAt      time.Time  `json:"failed_at"`
+	DaysWaiting   int        `json:"days_waiting"`
+	OutreachCount int        `json:"outreach_count"`
+	LastContactAt *time.Time `json:"last_contact_at,omitempty"`
+	LastChannel   string     `json:"last_channel,omitempty"`
+}
+
+// AwaitingVendorReport lists failed payouts awaiting vendor action for
+// longer than OlderThanDays, longest waiting first.
+type AwaitingVendorReport struct {
+	OlderThanDays int                    `json:"older_than_days"`
+	Payouts       []AwaitingVendorPayout `json:"payouts"`
+	GeneratedAt   time.Time              `json:"generated_at"`
+}
+
+// Actions recommended for a failed payout in a settlement report.
+const (
+	// ActionUpdateBankDetails: the vendor must send working bank details,
+	// then the payout is requeued to them.
+	ActionUpdateBankDetails = "update_bank_details"
+	// ActionFundAndRetry: top up the funding account, then retry.
+	ActionFundAndRetry = "fund_and_retry"
+	// ActionRetry: the failure was transient; retry the batch's failures.
+	ActionRetry = "retry"
+	// ActionContactBank: the bank refused the payout; ask it why.
+	ActionContactBank = "contact_bank"
+)
+
+// RecommendedAction returns what to do about a payout that failed for
+// reason.
+func RecommendedAction(reason string) string {
+	switch {
+	case slices.Contains(VendorActionFailures, reason):
+		return ActionUpdateBankDetails
+	case reason == FailureInsufficientFunds:
+		return ActionFundAndRetry
+	case IsRetryable(reason):
+		return ActionRetry
+	default:
+		return ActionContactBank
+	}
+}
+
+// SettledPayout is a payout in a settlement report. Reference is the
+// reference the transfer was sent to the bank under.
+type SettledPayout struct {
+	PayoutID       uuid.UUID  `json:"payout_id"`
+	VendorID       string     `json:"vendor_id"`
+	VendorName     string     `json:"vendor_name,omitempty"`
+	Amount         float64    `json:"amount"`
+	Currency       string     `json:"currency"`
+	Reference      string     `json:"reference"`
+	TransactionIDs []string   `json:"transaction_ids,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	FailureReason  string     `json:"failure_reason,omitempty"`
+	Action         string     `json:"recommended_action,omitempty"`
+}
+
+// SettlementReport tells a merchant how a batch settled: which vendors were
+// paid, and which were not, why, and what to do about it. The counts and
+// amounts cover every payout; the lists stop at Limit entries each, with
+// Truncated set, when a limit is given.
+type SettlementReport struct {
+	BatchID       uuid.UUID        `json:"batch_id"`
+	Status        string           `json:"status"`
+	MerchantID    *string          `json:"merchant_id,omitempty"`
+	PaidCount     int              `json:"paid_count"`
+	FailedCount   int              `json:"failed_count"`
+	PaidAmounts   []CurrencyAmount `json:"paid_amounts"`
+	FailedAmounts []CurrencyAmount `json:"failed_amounts"`
+	Paid          []SettledPayout  `json:"paid"`
+	Failed        []SettledPayout  `json:"failed"`
+	Truncated     bool             `json:"truncated,omitempty"`
+	Limit         int              `json:"-"`
+	GeneratedAt   time.Time        `json:"generated_at"`
+}
+
+// NewSettlementReport starts the settlement report of a batch, to Add its
+// payouts to; limit caps each list, 0 for none.
+func NewSettlementReport(batch PayoutBatch, limit int, now time.Time) *SettlementReport {
+	return &SettlementReport{
+		BatchID: batch.ID, Status: batch.Status, MerchantID: batch.MerchantID,
+		PaidAmounts: []CurrencyAmount{}, FailedAmounts: []CurrencyAmount{},
+		Paid: []SettledPayout{}, Failed: []SettledPayout{},
+		Limit: limit, GeneratedAt: now,
+	}
+}
+
+// Add counts a payout into the report if it was paid or failed for good;
+// payouts requeued into another batch are left to that batch's report.
+func (r *SettlementReport) Add(p Payout) {
+	if p.SupersededBy != nil {
+		return
+	}
+	entry := SettledPayout{
+		PayoutID: p.ID, VendorID: p.VendorID, VendorName: p.VendorName, Amount: p.Amount, Currency: p.Currency,
+		Reference: p.IdempotencyKey, TransactionIDs: p.TransactionIDs,
+	}
+	var list *[]SettledPayout
+	switch p.Status {
+	case PayoutStatusCompleted:
+		r.PaidCount++
+		r.PaidAmounts = addAmount(r.PaidAmounts, p.Currency, p.Amount)
+		entry.CompletedAt, list = p.CompletedAt, &r.Paid
+	case PayoutStatusFailed:
+		r.FailedCount++
+		r.FailedAmounts = addAmount(r.FailedAmounts, p.Currency, p.Amount)
+		if p.FailureReason != nil {
+			entry.FailureReason = *p.FailureReason
+		}
+		entry.Action, list = RecommendedAction(entry.FailureReason), &r.Failed
+	default:
+		return
+	}
+	if r.Limit > 0 && len(*list) >= r.Limit {
+		r.Truncated = true
+		return
+	}
+	*list = append(*list, entry)
+}
+
+// addAmount adds amount to the total of its currency in totals.
+func addAmount(totals []CurrencyAmount, currency string, amount float64) []CurrencyAmount {
+	for i := range totals {
+		if totals[i].Currency == currency {
+			totals[i].Amount += amount
+			return totals
+		}
+	}
+	return append(totals, CurrencyAmount{Currency: currency, Amount: amount})
+}
+
+// BankVolume is the unfinished part of a batch for one bank and currency.
+type BankVolume struct {
+	BankName    string
+	Currency    string
+	PayoutCount int
+	Amount      float64
+}
+
+// BankHistory is the throughput model of one bank and currency over recent
+// runs: how many payouts finished, how many of those failed, and how many
+// attempts they took and for how long.
+type BankHistory struct {
+	BankName          string
+	Currency          string
+	Payouts           int
+	FailedPayouts     int
+	Attempts          int
+	AvgAttemptSeconds float64
+}
+
+// BankEstimate is the part of a batch estimate for one bank and currency.
+type BankEstimate struct {
+	BankName    string `json:"bank_name"`
+	Currency    string `json:"currency"`
+	PayoutCount int    `json:"payout_count"`
+	// Basis names the history the rates below come from: the bank in this
+	// currency, the bank in any currency, all banks, or none.
+	Basis string `json:"basis"`
+	// HistoricalPayouts is how many recent payouts that history covers.
+	HistoricalPayouts int     `json:"historical_payouts"`
+	FailureRate       float64 `json:"failure_rate"`
+	AttemptsPerPayout float64 `json:"attempts_per_payout"`
+	AvgAttemptSeconds float64 `json:"avg_attempt_seconds"`
+	ExpectedFailures  float64 `json:"expected_failures"`
+}
+
+// Bases of a BankEstimate's rates.
+const (
+	EstimateBasisCurrency = "bank_currency"
+	EstimateBasisBank     = "bank"
+	EstimateBasisAll      = "all_banks"
+	EstimateBasisNone     = "none"
+)
+
+// CurrencyAmount is an amount of money in one currency.
+type CurrencyAmount struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// BatchEstimate forecasts a run of a batch's unfinished payouts from recent
+// history, so ops can decide whether to run it now or off-peak.
+type BatchEstimate struct {
+	BatchID     uuid.UUID `json:"batch_id"`
+	Status      string    `json:"status"`
+	PayoutCount int       `json:"payout_count"` // unfinished payouts the run would process
+	Concurrency int       `json:"concurrency"`
+	// ExpectedDurationSeconds and ExpectedFailures are null when no payout
+	// has been processed in the history window.
+	ExpectedDurationSeconds *float64 `json:"expected_duration_seconds"`
+	ExpectedFailures        *float64 `json:"expected_failures"`
+	// ExpectedFees are the configured per-transfer bank fees of the payouts
+	// expected to complete, per currency; empty without BANK_FEES.
+	ExpectedFees []CurrencyAmount `json:"expected_fees"`
+	Banks        []BankEstimate   `json:"banks"`
+	HistorySince time.Time        `json:"history_since"`
+	GeneratedAt  time.Time        `json:"generated_at"`
+}
+
+// SettlementCutoffReport groups unfinished payouts by bank according to
+// whether they can still settle today given each bank's cutoff.
+type SettlementCutoffReport struct {
+	Timezone    string           `json:"timezone"`
+	Today       string           `json:"today"`
+	SettleToday []BankSettlement `json:"settles_today"`
+	SettleLater []BankSettlement `json:"settles_later"`
+	NoCutoff    []BankSettlement `json:"no_cutoff"` // banks without a configured cutoff
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// BankSettlement lists the unfinished payouts to one bank and when they are
+// expected to arrive.
+type BankSettlement struct {
+	BankName     string             `json:"bank_name"`
+	Cutoff       string             `json:"cutoff,omitempty"`
+	ExpectedDate string             `json:"expected_date,omitempty"`
+	PayoutCount  int                `json:"payout_count"`
+	Payouts      []SettlementPayout `json:"payouts"`
+}
+
+// SettlementPayout is a payout as listed in the settlement cutoff report.
+type SettlementPayout struct {
+	ID         uuid.UUID `json:"id"`
+	BatchID    uuid.UUID `json:"batch_id"`
+	VendorID   string    `json:"vendor_id"`
+	VendorName string    `json:"vendor_name,omitempty"`
+	BankName   string    `json:"-"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	Status     string    `json:"status"`
+}
+
+// FundingAccount is the balance payouts in one currency are paid from.
+// Reserved is held for unfinished payouts of started batches.
+type FundingAccount struct {
+	Currency  string    `json:"currency"`
+	Balance   float64   `json:"balance"`
+	Reserved  float64   `json:"reserved"`
+	Available float64   `json:"available"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FundingReservation is what one batch holds against a funding account.
+type FundingReservation struct {
+	Currency  string    `json:"currency"`
+	Held      float64   `json:"held"`    // reserved for pending/processing payouts
+	Debited   float64   `json:"debited"` // taken from the balance for completed payouts
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetFundingBalanceRequest is the payload for setting a funding account balance.
+type SetFundingBalanceRequest struct {
+	Balance *float64 `json:"balance" binding:"required,gte=0"`
+}
+
+// ForceCompleteRequest is the payload for marking a payout as paid outside the engine.
+type ForceCompleteRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// CancelPayoutRequest is the payload for taking a pending payout out of its batch.
+type CancelPayoutRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// SetMaintenanceRequest is the payload for turning maintenance mode on or off.
+type SetMaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Message string `json:"message"`
+}
+
+// MaintenanceStatus is the state of maintenance mode.
+type MaintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+	By      string     `json:"by,omitempty"`
+}
+
+// WorkerConfig is the worker pool's configuration that can change on a
+// running server: WORKER_CONCURRENCY, WORKER_CHUNK_SIZE and RETRY_BACKOFF.
+type WorkerConfig struct {
+	Concurrency  int    `json:"concurrency"`
+	ChunkSize    int    `json:"chunk_size"`
+	RetryBackoff string `json:"retry_backoff"` // as RETRY_BACKOFF, e.g. "base=2s,max=1m" or "off"
+}
+
+// UpdateWorkerConfigRequest is the payload for changing the worker config;
+// settings left out keep their value. RetryBackoff replaces the whole
+// policy, as RETRY_BACKOFF does.
+type UpdateWorkerConfigRequest struct {
+	Concurrency  *int    `json:"concurrency" binding:"omitempty,min=1,max=100"`
+	ChunkSize    *int    `json:"chunk_size" binding:"omitempty,min=1,max=5000"`
+	RetryBackoff *string `json:"retry_backoff"`
+}
+
+// SystemOverview summarizes system-wide state for the ops dashboard.
+type SystemOverview struct {
+	BatchesByStatus   map[string]int     `json:"batches_by_status"`
+	PendingPayouts    int                `json:"pending_payouts"`    // waiting to be claimed
+	ProcessingPayouts int                `json:"processing_payouts"` // claimed, transfer in progress
+	InFlight          []CurrencyExposure `json:"in_flight"`
+	// ThroughputPerMinute counts payout attempts finished in the last minute.
+	ThroughputPerMinute int            `json:"throughput_per_minute"`
+	Processor           ProcessorState `json:"processor"`
+	GeneratedAt         time.Time      `json:"generated_at"`
+}
+
+// ProcessorState describes what this instance's worker pool is doing.
+type ProcessorState struct {
+	Running     bool       `json:"running"`
+	ActiveBatch *uuid.UUID `json:"active_batch,omitempty"`
+	// Queued lists the batches waiting to be started, next first.
+	Queued []uuid.UUID `json:"queued,omitempty"`
+}
+
+// VendorMatch is one vendor returned by the name search, ranked by Score.
+type VendorMatch struct {
+	VendorID     string    `json:"vendor_id"`
+	VendorName   string    `json:"vendor_name"`
+	PayoutCount  int       `json:"payout_count"`
+	LastPayoutAt time.Time `json:"last_payout_at"`
+	Score        float64   `json:"score"`
+}
+
+// PayoutListItem is a payout with a summary of its most recent attempt.
+type PayoutListItem struct {
+	Payout
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	// FailureDescription explains FailureReason in the request's language.
+	FailureDescription string `json:"failure_description,omitempty"`
 }
 
 // PayoutListResponse wraps a paginated list of payouts.
 type PayoutListResponse struct {
-	Payouts    []Payout `json:"payouts"`
-	TotalCount int      `json:"total_count"`
-	Page       int      `json:"page"`
-	PageSize   int      `json:"page_size"`
+	Payouts    []PayoutListItem `json:"payouts"`
+	TotalCount int              `json:"total_count"`
+	Page       int              `json:"page"`
+	PageSize   int              `json:"page_size"`
 }
 
+// BatchListFilter selects batches for the batch list.
+type BatchListFilter struct {
+	Status         string
+	AssignedTo     string
+	IncludeDeleted bool
+	// CreatedFrom and CreatedTo bound the creation time; CreatedTo is
+	// exclusive. Nil leaves that side open.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Page        int
+	PageSize    int
+	// Aggregates asks for totals over every matching batch.
+	Aggregates bool
+}
+
+// UpdateBatchRequest changes who owns or is assigned to a batch, or how
+// often its progress is persisted. Omitted fields are left as they are; an
+// empty string clears the field.
+type UpdateBatchRequest struct {
+	Owner         *string `json:"owner" binding:"omitempty,max=100"`
+	AssignedTo    *string `json:"assigned_to" binding:"omitempty,max=100"`
+	ProgressEvery *int    `json:"progress_every" binding:"omitempty,min=0,max=1000000"`
+}
+
+// BatchListResponse wraps a paginated list of batches, newest first.
+type BatchListResponse struct {
+	Batches    []PayoutBatch        `json:"batches"`
+	TotalCount int                  `json:"total_count"`
+	Page       int                  `json:"page"`
+	PageSize   int                  `json:"page_size"`
+	Aggregates *BatchListAggregates `json:"aggregates,omitempty"`
+}
+
+// BatchListAggregates summarises every batch matching a list's filters, not
+// only the page returned, for overview widgets.
+type BatchListAggregates struct {
+	// StatusCounts counts batches per status; statuses with none are left out.
+	StatusCounts map[string]int `json:"status_counts"`
+	// PendingPayouts counts payouts not yet finished (pending or processing).
+	PendingPayouts int `json:"pending_payouts"`
+	// PendingAmounts totals those payouts per currency.
+	PendingAmounts []CurrencyAmount `json:"pending_amounts"`
+}
+
+// Envelope is the body of every /api/v2 response: Data on success, Errors
+// on failure, and Meta on lists read a page at a time.
+type Envelope struct {
+	Data   json.RawMessage `json:"data"`
+	Meta   *EnvelopeMeta   `json:"meta,omitempty"`
+	Errors []APIError      `json:"errors,omitempty"`
+}
+
+// EnvelopeMeta describes a page of a cursor-paginated list. NextCursor is
+// passed back as ?cursor= for the following page and is empty on the last.
+type EnvelopeMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// APIError is one error of a /api/v2 response. Code is stable and meant for
+// programs; Message is localized and meant for people. Field names the
+// offending request field of a validation error.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// PageCursor is the position after which a keyset-paginated list resumes:
+// the creation time and ID of the last item returned.
+type PageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// PayoutDetail is the response for a single payout with its attempt history.
+type PayoutDetail struct {
+	Payout   Payout          `json:"payout"`
+	Attempts []PayoutAttempt `json:"attempts"`
+	// FailureDescription explains the payout's FailureReason in the request's language.
+	FailureDescription string `json:"failure_description,omitempty"`
+	// StatusToken is the vendor-facing lookup token for notifications.
+	StatusToken string `json:"status_token"`
+	// Splits lists every share of a split payout, this one included.
+	Splits []Payout `json:"splits,omitempty"`
+	// CorrectionChain lists the payouts this one was requeued from and into,
+	// oldest first and this one included.
+	CorrectionChain []Payout `json:"correction_chain,omitempty"`
+	// WriteOff is set for a written-off payout.
+	WriteOff *PayoutWriteOff `json:"write_off,omitempty"`
+	// Outreach lists contacts with the vendor about this payout, oldest first.
+	Outreach []VendorOutreach `json:"outreach,omitempty"`
+	// Local renders the payout's timestamps in the requested time zone.
+	Local LocalTimes `json:"local"`
+}
+
+// LocalTimes are a payout's timestamps in the time zone a request asked for
+// with ?tz= or Accept-Timezone (UTC by default), alongside the UTC ones, for
+// reconciling in local time.
+type LocalTimes struct {
+	TimeZone    string     `json:"time_zone"` // IANA name, e.g. Asia/Jakarta
+	CreatedAt   time.Time  `json:"created_at"`
+	AttemptedAt *time.Time `json:"attempted_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// PublicPayoutStatus is the vendor-facing view of a payout, looked up by
+// status token. It deliberately omits bank details, vendor identity and
+// failure codes.
+type PublicPayoutStatus struct {
+	Status       string     `json:"status"`
+	StatusLabel  string     `json:"status_label"`          // in the request's language
+	Description  string     `json:"description,omitempty"` // why a failed payout failed
+	Amount       float64    `json:"amount"`
+	Currency     string     `json:"currency"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	ExpectedDate string     `json:"expected_date,omitempty"` // for unfinished payouts, from the bank's cutoff
+	Local        LocalTimes `json:"local"`
+}
+
+// ComputeRates fills in the success and completion percentages from the counts.
+func (s *BatchStatistics) ComputeRates() {
+	if s.Total > 0 {
+		s.SuccessRate = float64(s.Completed) / float64(s.Total) * 100
+		processed := s.Completed + s.Failed
+		s.CompletionRate = float64(processed) / float64(s.Total) * 100
+	}
+}
+
+// DefaultMaxRetries is the attempt budget of a new payout (payouts.max_retries default).
+const DefaultMaxRetries = 3
+
 // IsRetryable returns true if the failure reason is transient.
 func IsRetryable(reason string) bool {
 	switch reason {
@@ -131,3 +2012,14 @@ func IsRetryable(reason string) bool {
 		return false
 	}
 }
+
+// IsKnownFailure returns true if code is one of the Failure* codes.
+func IsKnownFailure(code string) bool {
+	switch code {
+	case FailureInvalidBankAccount, FailureInsufficientFunds, FailureBankTimeout,
+		FailureAccountBlocked, FailureRateLimited, FailureBankRejected:
+		return true
+	default:
+		return false
+	}
+}
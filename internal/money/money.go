@@ -0,0 +1,118 @@
+// Package money formats amounts for finance documents, emails and exports
+// according to the reader's locale and the currency's conventions.
+package money
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"coding-challenge/internal/i18n"
+)
+
+// currency describes how amounts in a currency are written.
+type currency struct {
+	symbol   string
+	decimals int
+	suffix   bool // symbol after the number, e.g. "150.000 ₫"
+}
+
+// currencies lists the currencies paid out in our markets. Others are
+// written with their ISO code and two decimals.
+var currencies = map[string]currency{
+	"IDR": {symbol: "Rp", decimals: 0},
+	"PHP": {symbol: "₱", decimals: 2},
+	"VND": {symbol: "₫", decimals: 0, suffix: true},
+	"MYR": {symbol: "RM", decimals: 2},
+	"SGD": {symbol: "S$", decimals: 2},
+	"THB": {symbol: "฿", decimals: 2},
+	"USD": {symbol: "US$", decimals: 2},
+	"JPY": {symbol: "¥", decimals: 0},
+	"KRW": {symbol: "₩", decimals: 0},
+}
+
+// Decimals returns the number of minor-unit digits written for a currency.
+func Decimals(code string) int {
+	if c, ok := currencies[code]; ok {
+		return c.decimals
+	}
+	return 2
+}
+
+// Split divides amount into shares of the given percentages, rounded to the
+// currency's minor unit. Rounding differences go to the last share, so the
+// shares always add up to the rounded amount.
+func Split(amount float64, code string, percents []float64) []float64 {
+	scale := math.Pow10(Decimals(code))
+	total := int64(math.Round(amount * scale))
+	shares := make([]float64, len(percents))
+	var allocated int64
+	for i, pct := range percents {
+		minor := total - allocated
+		if i < len(percents)-1 {
+			minor = int64(math.Floor(float64(total) * pct / 100))
+		}
+		allocated += minor
+		shares[i] = float64(minor) / scale
+	}
+	return shares
+}
+
+// Separators returns the thousands and decimal separators of a locale.
+func Separators(locale string) (thousands, decimal string) {
+	switch i18n.Base(locale) {
+	case "id", "vi", "de", "es", "pt":
+		return ".", ","
+	default:
+		return ",", "."
+	}
+}
+
+// Format writes an amount with the currency symbol, e.g. "Rp 1.500.000" for
+// Indonesian, "₱1,250.50" or "150.000 ₫".
+func Format(amount float64, code, locale string) string {
+	number := FormatNumber(amount, code, locale)
+	c, ok := currencies[code]
+	if !ok {
+		return code + " " + number
+	}
+	switch {
+	case c.suffix:
+		return number + " " + c.symbol
+	case len([]rune(c.symbol)) == 1:
+		return c.symbol + number
+	default:
+		return c.symbol + " " + number
+	}
+}
+
+// FormatNumber writes an amount without a symbol, using the locale's
+// separators and the currency's decimals, e.g. "1.500.000" or "1,250.50".
+func FormatNumber(amount float64, code, locale string) string {
+	decimals := Decimals(code)
+	thousands, decimal := Separators(locale)
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	scale := int64(math.Pow10(decimals))
+	units := int64(math.Round(amount * float64(scale)))
+	whole := strconv.FormatInt(units/scale, 10)
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(d)
+	}
+	if decimals > 0 {
+		frac := strconv.FormatInt(units%scale, 10)
+		b.WriteString(decimal)
+		b.WriteString(strings.Repeat("0", decimals-len(frac)) + frac)
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package money_test
+
+import (
+	"testing"
+
+	"coding-challenge/internal/money"
+)
+
+// TestFormat verifies symbols, separators and decimals per market.
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		amount float64
+		code   string
+		locale string
+		want   string
+	}{
+		{1500000, "IDR", "id-ID", "Rp 1.500.000"},
+		{1500000, "IDR", "en", "Rp 1,500,000"},
+		{1250.5, "PHP", "fil", "₱1,250.50"},
+		{150000, "VND", "vi", "150.000 ₫"},
+		{1250.5, "USD", "id", "US$ 1.250,50"},
+		{999.999, "SGD", "", "S$ 1,000.00"},
+		{-42.1, "MYR", "en", "RM -42.10"},
+		{12.3, "CHF", "en", "CHF 12.30"},
+	}
+	for _, tc := range cases {
+		if got := money.Format(tc.amount, tc.code, tc.locale); got != tc.want {
+			t.Errorf("Format(%v, %s, %q): expected %q, got %q", tc.amount, tc.code, tc.locale, tc.want, got)
+		}
+	}
+}
+
+// TestFormatNumber verifies amounts without symbols, as used in CSV columns.
+func TestFormatNumber(t *testing.T) {
+	if got := money.FormatNumber(1234567.891, "USD", "en"); got != "1,234,567.89" {
+		t.Errorf("Expected 1,234,567.89, got %q", got)
+	}
+	if got := money.FormatNumber(0.4, "IDR", "id"); got != "0" {
+		t.Errorf("Expected 0, got %q", got)
+	}
+	if got := money.FormatNumber(100, "PHP", "vi"); got != "100,00" {
+		t.Errorf("Expected 100,00, got %q", got)
+	}
+}
+
+// TestSplit verifies shares are rounded to the currency's minor unit and
+// always add up to the amount.
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		amount   float64
+		code     string
+		percents []float64
+		want     []float64
+	}{
+		{1000, "USD", []float64{80, 20}, []float64{800, 200}},
+		{100, "USD", []float64{33.33, 33.33, 33.34}, []float64{33.33, 33.33, 33.34}},
+		{10.01, "USD", []float64{50, 50}, []float64{5, 5.01}},
+		{1000001, "IDR", []float64{70, 30}, []float64{700000, 300001}},
+	}
+	for _, tc := range cases {
+		got := money.Split(tc.amount, tc.code, tc.percents)
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Errorf("Split(%v, %s, %v): expected %v, got %v", tc.amount, tc.code, tc.percents, tc.want, got)
+				break
+			}
+		}
+	}
+}
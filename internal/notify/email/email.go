@@ -0,0 +1,30 @@
+// Package email renders localized vendor notification emails and sends them
+// through a pluggable provider, recording every send attempt.
+package email
+
+import (
+	"context"
+
+	"coding-challenge/internal/models"
+)
+
+// Message is a rendered email ready to send.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string // plain text
+}
+
+// Provider delivers messages (SMTP, SES, SendGrid, ...).
+type Provider interface {
+	// Name identifies the provider in delivery records.
+	Name() string
+	// Send delivers msg and returns the provider's message ID, if it has one.
+	Send(ctx context.Context, msg Message) (string, error)
+}
+
+// Store records send attempts; the repository implements it.
+type Store interface {
+	RecordEmailDelivery(ctx context.Context, d *models.EmailDelivery) error
+}
@@ -0,0 +1,276 @@
+package email_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/notify/email"
+
+	"github.com/google/uuid"
+)
+
+// TestRenderFallsBackToBaseLanguage verifies locale resolution.
+func TestRenderFallsBackToBaseLanguage(t *testing.T) {
+	data := email.Data{VendorName: "Toko Batik", Amount: "Rp 1.500.000"}
+
+	subject, body, used, err := email.Render(email.TemplatePayoutSent, "id-ID", data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if used != "id" {
+		t.Errorf("Expected locale id, got %s", used)
+	}
+	if !strings.Contains(subject, "Rp 1.500.000") || !strings.Contains(body, "Halo Toko Batik") {
+		t.Errorf("Unexpected Indonesian email: %q / %q", subject, body)
+	}
+
+	if _, _, used, _ := email.Render(email.TemplatePayoutSent, "fr-FR", data); used != email.DefaultLocale {
+		t.Errorf("Expected fallback to %s, got %s", email.DefaultLocale, used)
+	}
+	if _, _, _, err := email.Render("no_such_template", "en", data); err == nil {
+		t.Error("Expected an error for an unknown template")
+	}
+}
+
+// TestRenderFailureAction verifies the failure email explains what to do.
+func TestRenderFailureAction(t *testing.T) {
+	_, body, _, err := email.Render(email.TemplatePayoutFailed, "en", email.Data{
+		VendorName: "Bali Crafts", Amount: "US$ 10.00", Reason: models.FailureInvalidBankAccount,
+		StatusURL: "https://pay.example.com/s/abc",
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(body, "update your bank account") || !strings.Contains(body, "https://pay.example.com/s/abc") {
+		t.Errorf("Expected account update instructions and status link, got %q", body)
+	}
+}
+
+type fakeProvider struct {
+	mu   sync.Mutex
+	sent []email.Message
+	err  error
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) Send(_ context.Context, msg email.Message) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return "", f.err
+	}
+	f.sent = append(f.sent, msg)
+	return "msg-1", nil
+}
+
+type memStore struct {
+	mu         sync.Mutex
+	deliveries []models.EmailDelivery
+}
+
+func (m *memStore) RecordEmailDelivery(_ context.Context, d *models.EmailDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, *d)
+	return nil
+}
+
+func (m *memStore) wait(t *testing.T, n int) []models.EmailDelivery {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		if len(m.deliveries) >= n {
+			out := append([]models.EmailDelivery(nil), m.deliveries...)
+			m.mu.Unlock()
+			return out
+		}
+		m.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d deliveries, got %d", n, len(m.deliveries))
+	return nil
+}
+
+func payout(vendor, mail, locale string) models.Payout {
+	md := map[string]string{}
+	if mail != "" {
+		md[email.MetadataEmail] = mail
+	}
+	if locale != "" {
+		md[email.MetadataLocale] = locale
+	}
+	return models.Payout{ID: uuid.New(), VendorID: vendor, VendorName: vendor, Amount: 100, Currency: "USD", Metadata: md}
+}
+
+// TestNotifierTracksDeliveries verifies sends, provider errors and skipped
+// vendors are recorded as expected.
+func TestNotifierTracksDeliveries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &fakeProvider{}
+	store := &memStore{}
+	n := email.NewNotifier(provider, store, email.Config{From: "payouts@example.com"})
+	go n.Run(ctx)
+
+	n.PayoutSent(ctx, payout("V1", "v1@example.com", "id"))
+	n.PayoutSent(ctx, payout("V2", "", ""))                                            // no email: skipped
+	n.PayoutFailed(ctx, payout("V3", "v3@example.com", ""), models.FailureBankTimeout) // not actionable: skipped
+	n.PayoutFailed(ctx, payout("V4", "v4@example.com", ""), models.FailureAccountBlocked)
+
+	got := store.wait(t, 2)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %+v", got)
+	}
+	if got[0].Template != email.TemplatePayoutSent || got[0].Locale != "id" || got[0].Status != models.EmailStatusSent {
+		t.Errorf("Unexpected first delivery: %+v", got[0])
+	}
+	if got[0].ProviderMessageID == nil || *got[0].ProviderMessageID != "msg-1" {
+		t.Errorf("Expected provider message ID msg-1, got %v", got[0].ProviderMessageID)
+	}
+	if got[1].Template != email.TemplatePayoutFailed || got[1].Recipient != "v4@example.com" {
+		t.Errorf("Unexpected second delivery: %+v", got[1])
+	}
+
+	provider.mu.Lock()
+	provider.err = errors.New("mailbox unavailable")
+	provider.mu.Unlock()
+	n.PayoutSent(ctx, payout("V5", "v5@example.com", ""))
+	got = store.wait(t, 3)
+	if got[2].Status != models.EmailStatusFailed || got[2].Error == nil || *got[2].Error != "mailbox unavailable" {
+		t.Errorf("Expected a failed delivery with the provider error, got %+v", got[2])
+	}
+}
+
+// TestNotifierBatchFinished verifies batch outcomes go to the assignee, or
+// the owner, only when that is an email address.
+func TestNotifierBatchFinished(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &fakeProvider{}
+	store := &memStore{}
+	n := email.NewNotifier(provider, store, email.Config{From: "payouts@example.com"})
+	go n.Run(ctx)
+
+	who := func(s string) *string { return &s }
+	batch := models.PayoutBatch{ID: uuid.New(), Status: models.BatchStatusPartiallyCompleted, TotalCount: 3, CompletedCount: 2, FailedCount: 1}
+	unassigned := batch
+	unassigned.ID, unassigned.Owner = uuid.New(), who("owner@example.com")
+	batch.Owner, batch.AssignedTo = who("owner@example.com"), who("lead@example.com")
+	n.BatchFinished(ctx, models.PayoutBatch{ID: uuid.New(), Owner: who("alice")}) // not an address: skipped
+	n.BatchFinished(ctx, batch)
+	n.BatchFinished(ctx, unassigned)
+
+	got := store.wait(t, 2)
+	if got[0].Recipient != "lead@example.com" || got[0].BatchID == nil || *got[0].BatchID != batch.ID || got[0].PayoutID != nil {
+		t.Errorf("Expected the assignee emailed about the batch, got %+v", got[0])
+	}
+	if got[1].Recipient != "owner@example.com" || got[1].Template != email.TemplateBatchFinished {
+		t.Errorf("Expected the owner emailed without an assignee, got %+v", got[1])
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if msg := provider.sent[0]; !strings.Contains(msg.Subject, "partially_completed") || !strings.Contains(msg.Body, "Completed: 2 of 3") {
+		t.Errorf("Expected the outcome in the email, got %q / %q", msg.Subject, msg.Body)
+	}
+}
+
+// TestNotifierSettlementReport verifies the settlement report goes to the
+// batch's assignee, listing each vendor not paid with the action to take.
+func TestNotifierSettlementReport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &fakeProvider{}
+	store := &memStore{}
+	n := email.NewNotifier(provider, store, email.Config{From: "payouts@example.com"})
+	go n.Run(ctx)
+
+	lead := "lead@example.com"
+	batch := models.PayoutBatch{ID: uuid.New(), Status: models.BatchStatusPartiallyCompleted, TotalCount: 3, CompletedCount: 2, FailedCount: 1, AssignedTo: &lead}
+	report := models.NewSettlementReport(batch, 0, time.Now())
+	reason := models.FailureInvalidBankAccount
+	report.Add(models.Payout{ID: uuid.New(), VendorID: "V1", VendorName: "Warung Sari", Amount: 100, Currency: "USD", Status: models.PayoutStatusFailed, FailureReason: &reason})
+	n.SettlementReport(ctx, batch, *report)
+
+	got := store.wait(t, 1)
+	if got[0].Template != email.TemplateSettlementReport || got[0].Recipient != lead {
+		t.Errorf("Expected the settlement report emailed to the assignee, got %+v", got[0])
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if body := provider.sent[0].Body; !strings.Contains(body, "Warung Sari") || !strings.Contains(body, models.ActionUpdateBankDetails) {
+		t.Errorf("Expected the unpaid vendor and its action in the email, got %q", body)
+	}
+}
+
+// TestNotifierDropsWhenQueueFull verifies overflow is recorded rather than blocking.
+func TestNotifierDropsWhenQueueFull(t *testing.T) {
+	store := &memStore{}
+	n := email.NewNotifier(&fakeProvider{}, store, email.Config{QueueSize: 1})
+
+	// Run is not started, so the second email cannot be queued.
+	n.PayoutSent(context.Background(), payout("V1", "v1@example.com", ""))
+	n.PayoutSent(context.Background(), payout("V2", "v2@example.com", ""))
+
+	got := store.wait(t, 1)
+	if got[0].Status != models.EmailStatusDropped || got[0].Recipient != "v2@example.com" {
+		t.Errorf("Expected V2 to be dropped, got %+v", got[0])
+	}
+}
+
+// TestSendGridRequest verifies the request sent to the SendGrid API.
+func TestSendGridRequest(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Expected bearer auth, got %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("X-Message-Id", "sg-123")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sg := &email.SendGrid{APIKey: "key", Endpoint: srv.URL}
+	id, err := sg.Send(context.Background(), email.Message{From: "a@example.com", To: "b@example.com", Subject: "Hi", Body: "Hello"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if id != "sg-123" {
+		t.Errorf("Expected message ID sg-123, got %q", id)
+	}
+	if body["subject"] != "Hi" {
+		t.Errorf("Expected subject Hi, got %v", body["subject"])
+	}
+}
+
+// TestSMTPRejectsHeaderInjection verifies that line breaks in the recipient
+// or subject are refused before anything is sent.
+func TestSMTPRejectsHeaderInjection(t *testing.T) {
+	s := &email.SMTP{Host: "127.0.0.1", Port: "1"}
+	for _, msg := range []email.Message{
+		{From: "a@example.com", To: "b@example.com\r\nBcc: c@example.com", Subject: "Hi"},
+		{From: "a@example.com", To: "b@example.com", Subject: "Hi\nBcc: c@example.com"},
+	} {
+		_, err := s.Send(context.Background(), msg)
+		if err == nil || !strings.Contains(err.Error(), "line break") {
+			t.Errorf("Expected line break error for %q / %q, got %v", msg.To, msg.Subject, err)
+		}
+	}
+	_, err := s.Send(context.Background(), email.Message{From: "a@example.com", To: "not an address", Subject: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "recipient") {
+		t.Errorf("Expected recipient error, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package email
+
+import (
+	"context"
+	"log"
+)
+
+// LogProvider writes messages to the log instead of sending them, for local
+// runs and demos.
+type LogProvider struct{}
+
+// Name identifies the provider in delivery records.
+func (LogProvider) Name() string { return "log" }
+
+// Send logs msg.
+func (LogProvider) Send(_ context.Context, msg Message) (string, error) {
+	log.Printf("[email] To: %s | Subject: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return "", nil
+}
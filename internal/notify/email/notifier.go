@@ -0,0 +1,238 @@
+package email
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Payout metadata keys holding the vendor's contact details.
+const (
+	MetadataEmail  = "email"
+	MetadataLocale = "locale"
+)
+
+// sendTimeout bounds a single provider call.
+const sendTimeout = 30 * time.Second
+
+// Config holds Notifier settings.
+type Config struct {
+	From string
+	// StatusURL returns the vendor status page of a payout; optional.
+	StatusURL func(payoutID uuid.UUID) string
+	// QueueSize is how many emails may wait to be sent (default 1000).
+	QueueSize int
+}
+
+// maxUnpaidListed caps the vendors listed in a settlement_report email.
+const maxUnpaidListed = 50
+
+// job is one queued email, about a payout or, when batch is set, a batch,
+// with its settlement report if it has one.
+type job struct {
+	template string
+	payout   models.Payout
+	batch    *models.PayoutBatch
+	report   *models.SettlementReport
+	reason   string
+	to       string
+	locale   string
+}
+
+// Notifier emails vendors about payout outcomes, and batch assignees about
+// the end of a run. Emails are queued and sent by Run so that slow providers
+// never hold up payout processing; every attempt is recorded in the Store.
+//
+// Vendors are only emailed when their payout metadata has an "email"; the
+// optional "locale" picks the language and number format. Failure emails are
+// only sent when the vendor can act on them (bad or blocked account).
+// Batches are reported to their assignee, or their owner if nobody is
+// assigned, when that is an email address.
+type Notifier struct {
+	provider Provider
+	store    Store
+	cfg      Config
+	queue    chan job
+}
+
+// NewNotifier creates a notifier. Call Run to start sending.
+func NewNotifier(provider Provider, store Store, cfg Config) *Notifier {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	return &Notifier{provider: provider, store: store, cfg: cfg, queue: make(chan job, cfg.QueueSize)}
+}
+
+// PayoutSent queues a "payout sent" email.
+func (n *Notifier) PayoutSent(ctx context.Context, payout models.Payout) {
+	n.enqueue(ctx, vendorJob(TemplatePayoutSent, payout, ""))
+}
+
+// PayoutFailed queues a "payout failed, action needed" email if the vendor
+// can fix the failure themselves.
+func (n *Notifier) PayoutFailed(ctx context.Context, payout models.Payout, reason string) {
+	if !vendorActionable(reason) {
+		return
+	}
+	n.enqueue(ctx, vendorJob(TemplatePayoutFailed, payout, reason))
+}
+
+// BatchFinished queues a "batch run ended" email to the batch's assignee,
+// falling back to its owner.
+func (n *Notifier) BatchFinished(ctx context.Context, batch models.PayoutBatch) {
+	n.enqueue(ctx, job{template: TemplateBatchFinished, batch: &batch, to: batchRecipient(batch)})
+}
+
+// SettlementReport queues a settlement report email, listing the vendors
+// not paid, to the batch's assignee, falling back to its owner.
+func (n *Notifier) SettlementReport(ctx context.Context, batch models.PayoutBatch, report models.SettlementReport) {
+	n.enqueue(ctx, job{template: TemplateSettlementReport, batch: &batch, report: &report, to: batchRecipient(batch)})
+}
+
+// batchRecipient returns who is emailed about a batch: its assignee, or its
+// owner, when that is an email address; "" for nobody.
+func batchRecipient(batch models.PayoutBatch) string {
+	to := batch.AssignedTo
+	if to == nil {
+		to = batch.Owner
+	}
+	if to == nil || !strings.Contains(*to, "@") {
+		return ""
+	}
+	return *to
+}
+
+func vendorJob(template string, payout models.Payout, reason string) job {
+	return job{
+		template: template,
+		payout:   payout,
+		reason:   reason,
+		to:       payout.Metadata[MetadataEmail],
+		locale:   payout.Metadata[MetadataLocale],
+	}
+}
+
+// vendorActionable reports whether the vendor can resolve a failure.
+func vendorActionable(reason string) bool {
+	return reason == models.FailureInvalidBankAccount || reason == models.FailureAccountBlocked
+}
+
+func (n *Notifier) enqueue(ctx context.Context, j job) {
+	if j.to == "" {
+		return
+	}
+	select {
+	case n.queue <- j:
+	default:
+		n.record(ctx, j, j.locale, models.EmailStatusDropped, nil, "notification queue full")
+	}
+}
+
+// Run sends queued emails until ctx is cancelled.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-n.queue:
+			n.deliver(ctx, j)
+		}
+	}
+}
+
+// deliver renders and sends one email and records the attempt.
+func (n *Notifier) deliver(ctx context.Context, j job) {
+	var data Data
+	if b := j.batch; b != nil {
+		data = Data{
+			BatchID:     b.ID.String(),
+			BatchStatus: b.Status,
+			Total:       b.TotalCount,
+			Completed:   b.CompletedCount,
+			Failed:      b.FailedCount,
+			Pending:     b.PendingCount,
+		}
+		if r := j.report; r != nil {
+			for i, f := range r.Failed {
+				if i == maxUnpaidListed {
+					break
+				}
+				vendor := f.VendorName
+				if vendor == "" {
+					vendor = f.VendorID
+				}
+				data.Unpaid = append(data.Unpaid, UnpaidVendor{
+					Vendor: vendor, Amount: money.Format(f.Amount, f.Currency, j.locale), Reason: f.FailureReason, Action: f.Action,
+				})
+			}
+			data.More = r.FailedCount - len(data.Unpaid)
+		}
+	} else {
+		p := j.payout
+		data = Data{
+			VendorName: p.VendorName,
+			Amount:     money.Format(p.Amount, p.Currency, j.locale),
+			Reason:     j.reason,
+		}
+		if data.VendorName == "" {
+			data.VendorName = p.VendorID
+		}
+		if n.cfg.StatusURL != nil {
+			data.StatusURL = n.cfg.StatusURL(p.ID)
+		}
+	}
+
+	subject, body, used, err := Render(j.template, j.locale, data)
+	if err != nil {
+		n.record(ctx, j, j.locale, models.EmailStatusFailed, nil, err.Error())
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	id, err := n.provider.Send(sendCtx, Message{From: n.cfg.From, To: j.to, Subject: subject, Body: body})
+	if err != nil {
+		n.record(ctx, j, used, models.EmailStatusFailed, nil, err.Error())
+		return
+	}
+	var providerID *string
+	if id != "" {
+		providerID = &id
+	}
+	n.record(ctx, j, used, models.EmailStatusSent, providerID, "")
+}
+
+func (n *Notifier) record(ctx context.Context, j job, locale, status string, providerID *string, errMsg string) {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	d := &models.EmailDelivery{
+		ID:                uuid.New(),
+		Template:          j.template,
+		Locale:            locale,
+		Recipient:         j.to,
+		Provider:          n.provider.Name(),
+		Status:            status,
+		ProviderMessageID: providerID,
+		AttemptedAt:       time.Now().UTC(),
+	}
+	about := "payout " + j.payout.ID.String()
+	if j.batch != nil {
+		d.BatchID = &j.batch.ID
+		about = "batch " + j.batch.ID.String()
+	} else {
+		d.PayoutID = &j.payout.ID
+	}
+	if errMsg != "" {
+		d.Error = &errMsg
+	}
+	if err := n.store.RecordEmailDelivery(context.WithoutCancel(ctx), d); err != nil {
+		log.Printf("[email] Warning: failed to record delivery for %s: %v", about, err)
+	}
+}
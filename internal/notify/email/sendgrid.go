@@ -0,0 +1,70 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// sendGridEndpoint is the SendGrid v3 mail send API.
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGrid sends mail through the SendGrid v3 HTTP API.
+type SendGrid struct {
+	APIKey   string
+	Endpoint string       // defaults to the public API
+	Client   *http.Client // defaults to http.DefaultClient
+}
+
+// Name identifies the provider in delivery records.
+func (s *SendGrid) Name() string { return "sendgrid" }
+
+// Send delivers msg and returns SendGrid's X-Message-Id.
+func (s *SendGrid) Send(ctx context.Context, msg Message) (string, error) {
+	type address struct {
+		Email string `json:"email"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	payload, err := json.Marshal(map[string]any{
+		"personalizations": []map[string]any{{"to": []address{{Email: msg.To}}}},
+		"from":             address{Email: msg.From},
+		"subject":          msg.Subject,
+		"content":          []content{{Type: "text/plain", Value: msg.Body}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode sendgrid request: %w", err)
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = sendGridEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sendgrid request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("sendgrid returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.Header.Get("X-Message-Id"), nil
+}
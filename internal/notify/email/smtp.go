@@ -0,0 +1,88 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP sends mail through an SMTP relay using STARTTLS and PLAIN auth when
+// credentials are set.
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// ProviderName overrides Name, e.g. "ses" for the SES SMTP interface.
+	ProviderName string
+}
+
+// NewSES returns a provider for Amazon SES through its SMTP interface, using
+// SES SMTP credentials (not IAM access keys).
+func NewSES(region, username, password string) *SMTP {
+	return &SMTP{
+		Host:         "email-smtp." + region + ".amazonaws.com",
+		Port:         "587",
+		Username:     username,
+		Password:     password,
+		ProviderName: "ses",
+	}
+}
+
+// Name identifies the provider in delivery records.
+func (s *SMTP) Name() string {
+	if s.ProviderName != "" {
+		return s.ProviderName
+	}
+	return "smtp"
+}
+
+// Send delivers msg and returns the Message-ID it was sent with. net/smtp
+// has no context support, so ctx only bounds the wait before sending.
+func (s *SMTP) Send(ctx context.Context, msg Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	// The recipient comes from payout metadata and the subject carries the
+	// vendor name, so a line break in either could inject headers.
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return "", errors.New("smtp send: line break in recipient or subject")
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return "", fmt.Errorf("smtp send: recipient: %w", err)
+	}
+
+	id := messageID(s.Host)
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Message-ID: %s\r\n", id)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	if err := smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, msg.From, []string{to.Address}, []byte(b.String())); err != nil {
+		return "", fmt.Errorf("smtp send: %w", err)
+	}
+	return id, nil
+}
+
+func messageID(host string) string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "<" + hex.EncodeToString(buf) + "@" + host + ">"
+}
@@ -0,0 +1,93 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"coding-challenge/internal/i18n"
+)
+
+// Template names.
+const (
+	TemplatePayoutSent    = "payout_sent"
+	TemplatePayoutFailed  = "payout_failed"
+	TemplateBatchFinished = "batch_finished"
+	// TemplateSettlementReport follows batch_finished for a batch that
+	// ended partially completed, listing the vendors not paid.
+	TemplateSettlementReport = "settlement_report"
+)
+
+// DefaultLocale is used when a vendor's locale has no translation.
+const DefaultLocale = "en"
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templates maps "name.locale" to a template defining "subject" and "body".
+var templates = loadTemplates()
+
+func loadTemplates() map[string]*template.Template {
+	entries, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	parsed := map[string]*template.Template{}
+	for _, e := range entries {
+		key := strings.TrimSuffix(e.Name(), ".tmpl")
+		parsed[key] = template.Must(template.ParseFS(templateFiles, "templates/"+e.Name()))
+	}
+	return parsed
+}
+
+// Data is what templates can refer to.
+type Data struct {
+	VendorName string
+	Amount     string // already formatted for the locale
+	Reason     string // failure code, for payout_failed
+	StatusURL  string // vendor status page, if configured
+
+	// Batch outcome, for batch_finished.
+	BatchID     string
+	BatchStatus string
+	Total       int
+	Completed   int
+	Failed      int
+	Pending     int
+
+	// Vendors not paid, for settlement_report, and how many more the email
+	// leaves out.
+	Unpaid []UnpaidVendor
+	More   int
+}
+
+// UnpaidVendor is a failed payout listed in a settlement_report email.
+type UnpaidVendor struct {
+	Vendor string
+	Amount string // already formatted
+	Reason string
+	Action string // recommended action
+}
+
+// Render produces the subject and body of a template in the vendor's
+// locale, falling back to the base language and then DefaultLocale. It
+// returns the locale actually used.
+func Render(name, locale string, data Data) (subject, body, used string, err error) {
+	for _, candidate := range []string{strings.ToLower(locale), i18n.Base(locale), DefaultLocale} {
+		tmpl, ok := templates[name+"."+candidate]
+		if !ok {
+			continue
+		}
+		var s, b bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&s, "subject", data); err != nil {
+			return "", "", "", fmt.Errorf("render %s subject: %w", name, err)
+		}
+		if err := tmpl.ExecuteTemplate(&b, "body", data); err != nil {
+			return "", "", "", fmt.Errorf("render %s body: %w", name, err)
+		}
+		return strings.TrimSpace(s.String()), strings.TrimSpace(b.String()) + "\n", candidate, nil
+	}
+	return "", "", "", fmt.Errorf("unknown email template %q", name)
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// CloudEvents attributes shared by every event.
+const (
+	SpecVersion = "1.0"
+	// TypePrefix namespaces event types: a subscription's "payout.failed"
+	// is sent as "com.kaveri.payouts.payout.failed.v1".
+	TypePrefix = "com.kaveri.payouts."
+	// DefaultSource is the source of events when Config.Source is empty.
+	DefaultSource = "/kaveri/payouts"
+	// ContentType is the Content-Type of a delivery (structured mode).
+	ContentType = "application/cloudevents+json"
+)
+
+// schemaVersions is the current data schema version of each event. A
+// version only ever gains fields; renaming or removing one, or changing its
+// meaning, needs a new version, which changes the event type so consumers
+// of the old one are not broken.
+var schemaVersions = map[string]string{
+	models.EventPayoutCompleted: "v1",
+	models.EventPayoutFailed:    "v1",
+	models.EventBatchFinished:   "v1",
+	models.EventPing:            "v1",
+	// The data of batch.settlement_report v1 is models.SettlementReport.
+	models.EventBatchSettlementReport: "v1",
+}
+
+// EventType returns the CloudEvents type of a subscription event name.
+func EventType(name string) string {
+	return TypePrefix + name + "." + schemaVersions[name]
+}
+
+// DataSchema returns the identifier of the data schema of an event name.
+func DataSchema(name string) string {
+	return "urn:kaveri:payouts:schema:" + name + ":" + schemaVersions[name]
+}
+
+// PayoutData is the data of payout.* events, schema v1.
+type PayoutData struct {
+	ID            uuid.UUID `json:"id"`
+	BatchID       uuid.UUID `json:"batch_id"`
+	VendorID      string    `json:"vendor_id"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	FailureReason *string   `json:"failure_reason,omitempty"`
+	AttemptCount  int       `json:"attempt_count"`
+}
+
+// BatchData is the data of batch.* events, schema v1.
+type BatchData struct {
+	ID             uuid.UUID `json:"id"`
+	Status         string    `json:"status"`
+	Environment    *string   `json:"environment,omitempty"`
+	TotalCount     int       `json:"total_count"`
+	CompletedCount int       `json:"completed_count"`
+	FailedCount    int       `json:"failed_count"`
+	PendingCount   int       `json:"pending_count"`
+}
+
+// PingData is the data of ping events, schema v1.
+type PingData struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+}
+
+func payoutData(p models.Payout) PayoutData {
+	return PayoutData{
+		ID: p.ID, BatchID: p.BatchID, VendorID: p.VendorID, Amount: p.Amount, Currency: p.Currency,
+		Status: p.Status, FailureReason: p.FailureReason, AttemptCount: p.AttemptCount,
+	}
+}
+
+func batchData(b models.PayoutBatch) BatchData {
+	return BatchData{
+		ID: b.ID, Status: b.Status, Environment: b.Environment, TotalCount: b.TotalCount,
+		CompletedCount: b.CompletedCount, FailedCount: b.FailedCount, PendingCount: b.PendingCount,
+	}
+}
+
+// NewEvent creates an event named name (a models.Event* value) about
+// subject, e.g. "payouts/<id>", from source.
+func NewEvent(source, name, subject string, data any) models.CloudEvent {
+	if source == "" {
+		source = DefaultSource
+	}
+	return models.CloudEvent{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.New(),
+		Source:          source,
+		Type:            EventType(name),
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		DataSchema:      DataSchema(name),
+		Data:            data,
+	}
+}
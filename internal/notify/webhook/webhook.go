@@ -0,0 +1,223 @@
+// Package webhook posts payout and batch events to subscribed endpoints as
+// CloudEvents 1.0, signing each request and recording every delivery attempt.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Request headers of a delivery. The signature header holds one
+// "v1=<hex>" per valid secret, comma-separated: the current one and, while
+// a rotation is in its grace period, the previous one.
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+)
+
+// Store looks up subscriptions and records delivery attempts; the
+// repository implements it.
+type Store interface {
+	ListActiveWebhooks(ctx context.Context, eventType string) ([]models.WebhookSubscription, error)
+	RecordWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error
+}
+
+// Config holds Dispatcher settings.
+type Config struct {
+	// Client posts events (default: a client with a 10s timeout).
+	Client *http.Client
+	// QueueSize is how many events may wait to be delivered (default 1000).
+	QueueSize int
+	// Source is the CloudEvents source of events (default DefaultSource).
+	Source string
+}
+
+// Dispatcher delivers payout and batch events to the active subscriptions
+// to their type. It implements worker.Notifier: events are queued and
+// posted by Run, so slow endpoints never hold up payout processing. Each
+// event is posted once per subscription, without retries, and every attempt
+// is recorded in the Store.
+type Dispatcher struct {
+	store Store
+	cfg   Config
+	queue chan queued
+}
+
+// queued is an event waiting for delivery to the subscriptions to name.
+type queued struct {
+	name string
+	ev   models.CloudEvent
+}
+
+// NewDispatcher creates a dispatcher. Call Run to start delivering.
+func NewDispatcher(store Store, cfg Config) *Dispatcher {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	return &Dispatcher{store: store, cfg: cfg, queue: make(chan queued, cfg.QueueSize)}
+}
+
+// PayoutSent queues a payout.completed event.
+func (d *Dispatcher) PayoutSent(ctx context.Context, payout models.Payout) {
+	payout.Status = models.PayoutStatusCompleted
+	d.enqueue(ctx, models.EventPayoutCompleted, "payouts/"+payout.ID.String(), payoutData(payout))
+}
+
+// PayoutFailed queues a payout.failed event.
+func (d *Dispatcher) PayoutFailed(ctx context.Context, payout models.Payout, reason string) {
+	payout.Status = models.PayoutStatusFailed
+	payout.FailureReason = &reason
+	d.enqueue(ctx, models.EventPayoutFailed, "payouts/"+payout.ID.String(), payoutData(payout))
+}
+
+// BatchFinished queues a batch.finished event.
+func (d *Dispatcher) BatchFinished(ctx context.Context, batch models.PayoutBatch) {
+	d.enqueue(ctx, models.EventBatchFinished, "batches/"+batch.ID.String(), batchData(batch))
+}
+
+// SettlementReport queues a batch.settlement_report event.
+func (d *Dispatcher) SettlementReport(ctx context.Context, _ models.PayoutBatch, report models.SettlementReport) {
+	d.enqueue(ctx, models.EventBatchSettlementReport, "batches/"+report.BatchID.String(), report)
+}
+
+// Ping returns a ping event for a subscription, to pass to Deliver.
+func (d *Dispatcher) Ping(sub models.WebhookSubscription) models.CloudEvent {
+	return NewEvent(d.cfg.Source, models.EventPing, "webhooks/"+sub.ID.String(), PingData{SubscriptionID: sub.ID})
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, name, subject string, data any) {
+	q := queued{name: name, ev: NewEvent(d.cfg.Source, name, subject, data)}
+	select {
+	case d.queue <- q:
+	default:
+		subs, err := d.store.ListActiveWebhooks(ctx, name)
+		if err != nil {
+			log.Printf("[webhook] Warning: dropped %s event %s: %v", name, q.ev.ID, err)
+			return
+		}
+		for _, sub := range subs {
+			d.record(ctx, sub, q.ev, models.WebhookStatusDropped, nil, "webhook queue full", 0)
+		}
+	}
+}
+
+// Run delivers queued events until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-d.queue:
+			subs, err := d.store.ListActiveWebhooks(ctx, q.name)
+			if err != nil {
+				log.Printf("[webhook] Error loading subscriptions for %s event %s: %v", q.name, q.ev.ID, err)
+				continue
+			}
+			for _, sub := range subs {
+				d.Deliver(ctx, sub, q.ev)
+			}
+		}
+	}
+}
+
+// Deliver posts one event to one subscription now and records the attempt.
+// A delivery succeeds when the endpoint answers 2xx.
+func (d *Dispatcher) Deliver(ctx context.Context, sub models.WebhookSubscription, ev models.CloudEvent) models.WebhookDelivery {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return d.record(ctx, sub, ev, models.WebhookStatusFailed, nil, "encode event: "+err.Error(), 0)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return d.record(ctx, sub, ev, models.WebhookStatusFailed, nil, err.Error(), 0)
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set(HeaderID, ev.ID.String())
+	req.Header.Set(HeaderEvent, ev.Type)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, signatures(sub, now, body))
+
+	resp, err := d.cfg.Client.Do(req)
+	elapsed := time.Since(now)
+	if err != nil {
+		return d.record(ctx, sub, ev, models.WebhookStatusFailed, nil, err.Error(), elapsed)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	code := resp.StatusCode
+	if code < 200 || code > 299 {
+		return d.record(ctx, sub, ev, models.WebhookStatusFailed, &code, "HTTP "+strconv.Itoa(code), elapsed)
+	}
+	return d.record(ctx, sub, ev, models.WebhookStatusSent, &code, "", elapsed)
+}
+
+// signatures signs a delivery with every secret of sub still valid at now.
+func signatures(sub models.WebhookSubscription, now time.Time, body []byte) string {
+	ts := now.Unix()
+	sigs := []string{"v1=" + Sign(sub.Secret, ts, body)}
+	if sub.PreviousSecret != "" && sub.PreviousSecretExpiresAt != nil && now.Before(*sub.PreviousSecretExpiresAt) {
+		sigs = append(sigs, "v1="+Sign(sub.PreviousSecret, ts, body))
+	}
+	return strings.Join(sigs, ",")
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" under secret,
+// which receivers compare with the signature header.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random subscription secret.
+func NewSecret() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic("webhook: read random secret: " + err.Error())
+	}
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+func (d *Dispatcher) record(ctx context.Context, sub models.WebhookSubscription, ev models.CloudEvent, status string, code *int, errMsg string, elapsed time.Duration) models.WebhookDelivery {
+	del := models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		EventID:        ev.ID,
+		EventType:      ev.Type,
+		Status:         status,
+		ResponseCode:   code,
+		DurationMs:     int(elapsed.Milliseconds()),
+		AttemptedAt:    time.Now().UTC(),
+	}
+	if errMsg != "" {
+		del.Error = &errMsg
+	}
+	if err := d.store.RecordWebhookDelivery(context.WithoutCancel(ctx), &del); err != nil {
+		log.Printf("[webhook] Warning: failed to record delivery of %s to %s: %v", ev.ID, sub.ID, err)
+	}
+	return del
+}
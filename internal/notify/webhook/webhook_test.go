@@ -0,0 +1,192 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/notify/webhook"
+
+	"github.com/google/uuid"
+)
+
+type memStore struct {
+	mu         sync.Mutex
+	subs       []models.WebhookSubscription
+	deliveries []models.WebhookDelivery
+}
+
+func (m *memStore) ListActiveWebhooks(_ context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	for _, s := range m.subs {
+		if s.Active && s.Subscribes(eventType) {
+			subs = append(subs, s)
+		}
+	}
+	return subs, nil
+}
+
+func (m *memStore) RecordWebhookDelivery(_ context.Context, d *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, *d)
+	return nil
+}
+
+func (m *memStore) recorded() []models.WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.WebhookDelivery(nil), m.deliveries...)
+}
+
+// TestDeliverSignsWithEverySecret verifies deliveries are signed with the
+// current secret, and also the previous one while its grace period lasts.
+func TestDeliverSignsWithEverySecret(t *testing.T) {
+	var got http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, body = r.Header.Clone(), must(io.ReadAll(r.Body))
+	}))
+	defer srv.Close()
+
+	store := &memStore{}
+	d := webhook.NewDispatcher(store, webhook.Config{})
+	later := time.Now().Add(time.Hour)
+	sub := models.WebhookSubscription{ID: uuid.New(), URL: srv.URL, Secret: "new-secret", PreviousSecret: "old-secret", PreviousSecretExpiresAt: &later}
+
+	del := d.Deliver(context.Background(), sub, d.Ping(sub))
+	if del.Status != models.WebhookStatusSent || del.ResponseCode == nil || *del.ResponseCode != http.StatusOK {
+		t.Fatalf("Expected a sent delivery, got %+v", del)
+	}
+	ts, _ := strconv.ParseInt(got.Get(webhook.HeaderTimestamp), 10, 64)
+	want := "v1=" + webhook.Sign("new-secret", ts, body) + ",v1=" + webhook.Sign("old-secret", ts, body)
+	if got.Get(webhook.HeaderSignature) != want {
+		t.Errorf("Expected signatures %s, got %s", want, got.Get(webhook.HeaderSignature))
+	}
+	var ev models.CloudEvent
+	if err := json.Unmarshal(body, &ev); err != nil || ev.Type != "com.kaveri.payouts.ping.v1" || got.Get(webhook.HeaderID) != ev.ID.String() {
+		t.Errorf("Expected a ping event matching its headers, got %s (%v)", body, err)
+	}
+
+	earlier := time.Now().Add(-time.Minute)
+	sub.PreviousSecretExpiresAt = &earlier
+	d.Deliver(context.Background(), sub, d.Ping(sub))
+	if strings.Contains(got.Get(webhook.HeaderSignature), ",") {
+		t.Errorf("Expected an expired secret to stop signing, got %s", got.Get(webhook.HeaderSignature))
+	}
+}
+
+// TestDispatcherRoutesEvents verifies events reach only active
+// subscriptions to their type, and that non-2xx answers are failures.
+func TestDispatcherRoutesEvents(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string][]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path] = append(hits[r.URL.Path], r.Header.Get(webhook.HeaderEvent))
+		mu.Unlock()
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	store := &memStore{subs: []models.WebhookSubscription{
+		{ID: uuid.New(), URL: srv.URL + "/failures", EventTypes: []string{models.EventPayoutFailed}, Active: true, Secret: "s"},
+		{ID: uuid.New(), URL: srv.URL + "/broken", EventTypes: []string{models.EventPayoutFailed, models.EventBatchFinished}, Active: true, Secret: "s"},
+		{ID: uuid.New(), URL: srv.URL + "/off", EventTypes: []string{models.EventPayoutFailed}, Active: false, Secret: "s"},
+	}}
+	d := webhook.NewDispatcher(store, webhook.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.PayoutSent(ctx, models.Payout{ID: uuid.New()})
+	d.PayoutFailed(ctx, models.Payout{ID: uuid.New()}, models.FailureAccountBlocked)
+	d.BatchFinished(ctx, models.PayoutBatch{ID: uuid.New(), Status: models.BatchStatusCompleted})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.recorded()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hits["/failures"]) != 1 || len(hits["/broken"]) != 2 || len(hits["/off"]) != 0 {
+		t.Errorf("Expected 1 failure event, 2 events to the broken endpoint and none to the inactive one, got %v", hits)
+	}
+	failed := 0
+	for _, del := range store.recorded() {
+		if del.Status == models.WebhookStatusFailed {
+			failed++
+			if del.Error == nil || *del.Error != "HTTP 502" {
+				t.Errorf("Expected HTTP 502 recorded, got %v", del.Error)
+			}
+		}
+	}
+	if failed != 2 {
+		t.Errorf("Expected 2 failed deliveries, got %d", failed)
+	}
+}
+
+// TestEventsAreCloudEvents verifies deliveries are structured-mode
+// CloudEvents 1.0 with versioned types, a subject and the v1 data schema.
+func TestEventsAreCloudEvents(t *testing.T) {
+	var contentType string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType, body = r.Header.Get("Content-Type"), must(io.ReadAll(r.Body))
+	}))
+	defer srv.Close()
+
+	store := &memStore{subs: []models.WebhookSubscription{
+		{ID: uuid.New(), URL: srv.URL, EventTypes: []string{models.EventPayoutFailed}, Active: true, Secret: "s"},
+	}}
+	d := webhook.NewDispatcher(store, webhook.Config{Source: "/test/payouts"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	payout := models.Payout{ID: uuid.New(), BatchID: uuid.New(), VendorID: "V1", Amount: 12.5, Currency: "IDR", AttemptCount: 2}
+	d.PayoutFailed(ctx, payout, models.FailureAccountBlocked)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.recorded()) < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if contentType != webhook.ContentType {
+		t.Errorf("Expected Content-Type %s, got %s", webhook.ContentType, contentType)
+	}
+	var ev struct {
+		models.CloudEvent
+		Data webhook.PayoutData `json:"data"`
+	}
+	if err := json.Unmarshal(body, &ev); err != nil {
+		t.Fatalf("Expected a JSON event, got %s (%v)", body, err)
+	}
+	if ev.SpecVersion != "1.0" || ev.Type != "com.kaveri.payouts.payout.failed.v1" || ev.Source != "/test/payouts" ||
+		ev.Subject != "payouts/"+payout.ID.String() || ev.DataSchema != "urn:kaveri:payouts:schema:payout.failed:v1" {
+		t.Errorf("Expected CloudEvents attributes, got %s", body)
+	}
+	if ev.Data.ID != payout.ID || ev.Data.Status != models.PayoutStatusFailed || ev.Data.FailureReason == nil ||
+		*ev.Data.FailureReason != models.FailureAccountBlocked || ev.Data.AttemptCount != 2 {
+		t.Errorf("Expected the failed payout as data, got %+v", ev.Data)
+	}
+	if del := store.recorded()[0]; del.EventType != ev.Type || del.EventID != ev.ID {
+		t.Errorf("Expected the delivery to record the event, got %+v", del)
+	}
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,101 @@
+// Package pgp encrypts export files to a recipient's OpenPGP public key, as
+// banks and partners expect of files dropped on their SFTP servers.
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/openpgp"
+
+	// openpgp refuses to encrypt unless a hash the key accepts is linked in,
+	// even though unsigned messages never use it. Keys without preferences
+	// accept only RIPEMD-160.
+	_ "crypto/sha256"
+	_ "golang.org/x/crypto/ripemd160"
+)
+
+// Key is the public key exports are encrypted to. It is safe for
+// concurrent use, and Replace swaps the key in place, e.g. to rotate it on
+// a config reload. The zero value holds no key.
+type Key struct {
+	mu       sync.RWMutex
+	entities openpgp.EntityList
+}
+
+// ParseKey parses an armored or binary OpenPGP public key. The key must be
+// able to encrypt, i.e. have a valid encryption key or subkey.
+func ParseKey(data []byte) (*Key, error) {
+	var entities openpgp.EntityList
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "-----BEGIN") {
+		entities, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("want exactly one public key, got %d", len(entities))
+	}
+	// Catches expired, revoked or signing-only keys now rather than on the
+	// first export.
+	w, err := openpgp.Encrypt(io.Discard, entities, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("key cannot encrypt: %w", err)
+	}
+	w.Close()
+	return &Key{entities: entities}, nil
+}
+
+// Replace swaps in the key held by other.
+func (k *Key) Replace(other *Key) {
+	other.mu.RLock()
+	entities := other.entities
+	other.mu.RUnlock()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.entities = entities
+}
+
+// Configured reports whether a key is held.
+func (k *Key) Configured() bool {
+	if k == nil {
+		return false
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.entities) > 0
+}
+
+// Fingerprint returns the key's fingerprint in upper-case hex, as shown by
+// gpg, or "" without a key.
+func (k *Key) Fingerprint() string {
+	if !k.Configured() {
+		return ""
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return fmt.Sprintf("%X", k.entities[0].PrimaryKey.Fingerprint)
+}
+
+// ErrNoKey is returned by Encrypt when no key is configured.
+var ErrNoKey = errors.New("no export encryption key configured")
+
+// Encrypt returns a writer that encrypts everything written to it to the
+// key, as a binary OpenPGP message carrying filename, and writes it to w.
+// Close must be called to finish the message; it does not close w.
+func (k *Key) Encrypt(w io.Writer, filename string) (io.WriteCloser, error) {
+	if !k.Configured() {
+		return nil, ErrNoKey
+	}
+	k.mu.RLock()
+	entities := k.entities
+	k.mu.RUnlock()
+	return openpgp.Encrypt(w, entities, nil, &openpgp.FileHints{IsBinary: true, FileName: filename}, nil)
+}
@@ -0,0 +1,119 @@
+package pgp_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"coding-challenge/internal/pgp"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// newEntity generates a key pair for a recipient such as a bank.
+func newEntity(t *testing.T) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity("Bank SFTP", "", "sftp@bank.example", nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return e
+}
+
+func publicKey(t *testing.T, e *openpgp.Entity, armored bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := io.WriteCloser(nopCloser{&buf})
+	if armored {
+		var err error
+		if w, err = armor.Encode(&buf, openpgp.PublicKeyType, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// TestEncryptRoundTrip verifies a file encrypted to an armored or binary
+// public key decrypts with the private key, under its file name.
+func TestEncryptRoundTrip(t *testing.T) {
+	e := newEntity(t)
+	for _, armored := range []bool{true, false} {
+		key, err := pgp.ParseKey(publicKey(t, e, armored))
+		if err != nil {
+			t.Fatalf("armored=%v: Failed to parse key: %v", armored, err)
+		}
+		if want := fmt.Sprintf("%X", e.PrimaryKey.Fingerprint); key.Fingerprint() != want {
+			t.Errorf("Expected fingerprint %s, got %s", want, key.Fingerprint())
+		}
+
+		var out bytes.Buffer
+		w, err := key.Encrypt(&out, "batch.csv")
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		io.WriteString(w, "payout_id,amount\n")
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(out.Bytes(), []byte("payout_id")) {
+			t.Fatal("Expected the output to be encrypted")
+		}
+
+		md, err := openpgp.ReadMessage(&out, openpgp.EntityList{e}, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		plain, _ := io.ReadAll(md.UnverifiedBody)
+		if string(plain) != "payout_id,amount\n" || md.LiteralData.FileName != "batch.csv" {
+			t.Errorf("Expected batch.csv with the CSV, got %s: %q", md.LiteralData.FileName, plain)
+		}
+	}
+}
+
+// TestParseKeyRejects verifies garbage and keys that can't encrypt are
+// refused up front.
+func TestParseKeyRejects(t *testing.T) {
+	signOnly := newEntity(t)
+	signOnly.Subkeys = nil
+
+	for name, data := range map[string][]byte{
+		"garbage":   []byte("not a key"),
+		"sign-only": publicKey(t, signOnly, true),
+	} {
+		if _, err := pgp.ParseKey(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestKeyReplace verifies a rotated key takes over, and that an empty key
+// refuses to encrypt.
+func TestKeyReplace(t *testing.T) {
+	var key pgp.Key
+	if key.Configured() {
+		t.Fatal("Expected the zero Key to hold no key")
+	}
+	if _, err := key.Encrypt(io.Discard, "batch.csv"); err != pgp.ErrNoKey {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+
+	e := newEntity(t)
+	fresh, err := pgp.ParseKey(publicKey(t, e, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Replace(fresh)
+	if key.Fingerprint() != fresh.Fingerprint() {
+		t.Errorf("Expected fingerprint %s after Replace, got %s", fresh.Fingerprint(), key.Fingerprint())
+	}
+}
@@ -0,0 +1,130 @@
+// Package purpose validates the purpose-of-payment codes that transfer
+// rails require: Indonesia's BI-FAST transaction purpose codes and, for the
+// Philippines and Vietnam, ISO 20022 purpose codes. Each payout's country
+// is its "country" metadata or else its currency's.
+package purpose
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"coding-challenge/internal/models"
+)
+
+// ErrUnknownCode is returned for a code not on its country's list.
+var ErrUnknownCode = errors.New("unknown purpose code")
+
+// codes are the allowed purpose codes per country, with what they mean.
+var codes = map[string]map[string]string{
+	"ID": {
+		"01": "Investment",
+		"02": "Transfer of wealth",
+		"03": "Purchase",
+		"99": "Other",
+	},
+	"PH": iso20022,
+	"VN": iso20022,
+}
+
+// iso20022 is the subset of ISO 20022 ExternalPurpose1Code used for
+// vendor payouts.
+var iso20022 = map[string]string{
+	"COMM": "Commission",
+	"GDDS": "Purchase of goods",
+	"OTHR": "Other",
+	"RENT": "Rent",
+	"SALA": "Salary",
+	"SCVE": "Purchase of services",
+	"SUPP": "Supplier payment",
+	"TRAD": "Trade services",
+}
+
+// currencies maps a currency to the country whose rail pays it.
+var currencies = map[string]string{"IDR": "ID", "PHP": "PH", "VND": "VN"}
+
+// Country returns the country whose codes apply to an item: its "country"
+// metadata, or else its currency's country; "" if neither is known.
+func Country(item models.CreatePayoutItem) string {
+	if c := strings.ToUpper(strings.TrimSpace(item.Metadata["country"])); c != "" {
+		return c
+	}
+	return currencies[strings.ToUpper(item.Currency)]
+}
+
+// Codes returns a country's allowed codes, sorted, or nil if the country
+// has no list.
+func Codes(country string) []string {
+	list := codes[strings.ToUpper(country)]
+	if list == nil {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for code := range list {
+		out = append(out, code)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Check returns code, upper-cased, if it is allowed in country. Countries
+// without a list take any code of 1 to 10 letters and digits.
+func Check(country, code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if list := codes[strings.ToUpper(country)]; list != nil {
+		if _, ok := list[code]; !ok {
+			return "", fmt.Errorf("%w %q for %s (want one of %s)", ErrUnknownCode, code, country, strings.Join(Codes(country), ", "))
+		}
+		return code, nil
+	}
+	if len(code) == 0 || len(code) > 10 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" {
+		return "", fmt.Errorf("%w %q (want 1 to 10 letters and digits)", ErrUnknownCode, code)
+	}
+	return code, nil
+}
+
+// Validate checks an item's purpose code, if it has one.
+func Validate(item models.CreatePayoutItem) error {
+	if item.PurposeCode == "" {
+		return nil
+	}
+	_, err := Check(Country(item), item.PurposeCode)
+	return err
+}
+
+// Defaults are the purpose codes given to payouts created without one, per
+// country.
+type Defaults map[string]string
+
+// ParseDefaults parses default codes in the form "ID=99,PH=SUPP", each
+// checked against its country's list.
+func ParseDefaults(spec string) (Defaults, error) {
+	defaults := Defaults{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		country, code, ok := strings.Cut(entry, "=")
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !ok || len(country) != 2 {
+			return nil, fmt.Errorf("invalid purpose code default %q (want COUNTRY=CODE)", entry)
+		}
+		checked, err := Check(country, code)
+		if err != nil {
+			return nil, err
+		}
+		defaults[country] = checked
+	}
+	return defaults, nil
+}
+
+// For returns the purpose code a payout is created with: the item's own,
+// upper-cased, or else its country's default, if any.
+func (d Defaults) For(item models.CreatePayoutItem) string {
+	if item.PurposeCode != "" {
+		return strings.ToUpper(strings.TrimSpace(item.PurposeCode))
+	}
+	return d[Country(item)]
+}
@@ -0,0 +1,80 @@
+package purpose_test
+
+import (
+	"errors"
+	"testing"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/purpose"
+)
+
+func TestCountry(t *testing.T) {
+	cases := []struct {
+		currency string
+		country  string
+		want     string
+	}{
+		{"IDR", "", "ID"},
+		{"php", "", "PH"},
+		{"USD", "vn", "VN"},
+		{"IDR", "SG", "SG"},
+		{"USD", "", ""},
+	}
+	for _, tc := range cases {
+		item := models.CreatePayoutItem{Currency: tc.currency}
+		if tc.country != "" {
+			item.Metadata = map[string]string{"country": tc.country}
+		}
+		if got := purpose.Country(item); got != tc.want {
+			t.Errorf("Expected %s/%q to be %q, got %q", tc.currency, tc.country, tc.want, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		country, code string
+		want          string
+	}{
+		{"ID", "99", "99"},
+		{"PH", " supp ", "SUPP"},
+		{"vn", "SALA", "SALA"},
+		{"SG", "IVPT", "IVPT"},
+		{"", "X1", "X1"},
+	}
+	for _, tc := range cases {
+		if got, err := purpose.Check(tc.country, tc.code); err != nil || got != tc.want {
+			t.Errorf("Expected %s/%q to give %q, got %q (%v)", tc.country, tc.code, tc.want, got, err)
+		}
+	}
+	for _, tc := range []struct{ country, code string }{{"ID", "SUPP"}, {"PH", "99"}, {"SG", "TOO-LONG-CODE"}, {"", ""}} {
+		if _, err := purpose.Check(tc.country, tc.code); !errors.Is(err, purpose.ErrUnknownCode) {
+			t.Errorf("Expected ErrUnknownCode for %s/%q, got %v", tc.country, tc.code, err)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	defaults, err := purpose.ParseDefaults(" id=99, PH=supp,")
+	if err != nil {
+		t.Fatalf("Expected the defaults to parse, got %v", err)
+	}
+	if len(defaults) != 2 || defaults["ID"] != "99" || defaults["PH"] != "SUPP" {
+		t.Errorf("Expected ID=99 and PH=SUPP, got %v", defaults)
+	}
+	for _, spec := range []string{"ID", "IDN=99", "ID=SUPP", "SG=?"} {
+		if _, err := purpose.ParseDefaults(spec); err == nil {
+			t.Errorf("Expected %q to be refused", spec)
+		}
+	}
+
+	if got := defaults.For(models.CreatePayoutItem{Currency: "IDR"}); got != "99" {
+		t.Errorf("Expected the ID default, got %q", got)
+	}
+	if got := defaults.For(models.CreatePayoutItem{Currency: "IDR", PurposeCode: "01"}); got != "01" {
+		t.Errorf("Expected the item's own code, got %q", got)
+	}
+	if got := defaults.For(models.CreatePayoutItem{Currency: "VND"}); got != "" {
+		t.Errorf("Expected no code without a VN default, got %q", got)
+	}
+}
@@ -0,0 +1,141 @@
+// Package quota holds the limits merchants of the platform are held to:
+// payouts submitted per calendar month, payouts per batch, and batches
+// processing at once.
+package quota
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits a merchant can exceed.
+const (
+	LimitPayoutsPerMonth   = "payouts_per_month"
+	LimitBatchSize         = "batch_size"
+	LimitConcurrentBatches = "concurrent_batches"
+)
+
+// Default names the limits of merchants without their own.
+const Default = "*"
+
+// Limits are one merchant's quotas; 0 leaves a limit off.
+type Limits struct {
+	PayoutsPerMonth   int `json:"payouts_per_month,omitempty"`
+	BatchSize         int `json:"batch_size,omitempty"`
+	ConcurrentBatches int `json:"concurrent_batches,omitempty"`
+}
+
+// ExceededError refuses a request that would take a merchant over a limit.
+type ExceededError struct {
+	Limit     string
+	Max       int
+	Used      int
+	Requested int
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s quota of %d exceeded: %d used, %d requested", e.Limit, e.Max, e.Used, e.Requested)
+}
+
+// CheckBatch refuses a batch of size payouts from a merchant that submitted
+// usedThisMonth payouts so far this month.
+func (l Limits) CheckBatch(size, usedThisMonth int) error {
+	if l.BatchSize > 0 && size > l.BatchSize {
+		return &ExceededError{Limit: LimitBatchSize, Max: l.BatchSize, Requested: size}
+	}
+	if l.PayoutsPerMonth > 0 && usedThisMonth+size > l.PayoutsPerMonth {
+		return &ExceededError{Limit: LimitPayoutsPerMonth, Max: l.PayoutsPerMonth, Used: usedThisMonth, Requested: size}
+	}
+	return nil
+}
+
+// CheckStart refuses to start another batch for a merchant with running
+// batches processing already.
+func (l Limits) CheckStart(running int) error {
+	if l.ConcurrentBatches > 0 && running >= l.ConcurrentBatches {
+		return &ExceededError{Limit: LimitConcurrentBatches, Max: l.ConcurrentBatches, Used: running, Requested: 1}
+	}
+	return nil
+}
+
+// Quotas holds each merchant's limits. A nil *Quotas limits nobody.
+type Quotas struct {
+	limits map[string]Limits
+	// WarnAt is the share of the monthly payout quota, e.g. 0.8, past which
+	// a merchant is approaching it; 0 never warns.
+	WarnAt float64
+}
+
+// Parse parses per-merchant limits in the form
+// "acme:payouts_per_month=100000;batch_size=5000;concurrent_batches=2,*:batch_size=10000",
+// where "*" applies to merchants not listed.
+func Parse(spec string) (*Quotas, error) {
+	q := &Quotas{limits: map[string]Limits{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		merchant, settings, ok := strings.Cut(entry, ":")
+		merchant = strings.TrimSpace(merchant)
+		if !ok || merchant == "" {
+			return nil, fmt.Errorf("invalid quota entry %q (want MERCHANT:LIMIT=N;LIMIT=N)", entry)
+		}
+		var l Limits
+		for _, setting := range strings.Split(settings, ";") {
+			name, value, ok := strings.Cut(setting, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid quota setting %q for %s (want LIMIT=N)", setting, merchant)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s for %s: want a count >= 0", strings.TrimSpace(name), merchant)
+			}
+			switch strings.TrimSpace(name) {
+			case LimitPayoutsPerMonth:
+				l.PayoutsPerMonth = n
+			case LimitBatchSize:
+				l.BatchSize = n
+			case LimitConcurrentBatches:
+				l.ConcurrentBatches = n
+			default:
+				return nil, fmt.Errorf("unknown quota %q for %s (want %s, %s or %s)",
+					strings.TrimSpace(name), merchant, LimitPayoutsPerMonth, LimitBatchSize, LimitConcurrentBatches)
+			}
+		}
+		q.limits[merchant] = l
+	}
+	return q, nil
+}
+
+// For returns a merchant's limits, or the default ones, and whether any
+// apply. Requests naming no merchant are never limited.
+func (q *Quotas) For(merchant string) (Limits, bool) {
+	if q == nil || merchant == "" {
+		return Limits{}, false
+	}
+	if l, ok := q.limits[merchant]; ok {
+		return l, true
+	}
+	l, ok := q.limits[Default]
+	return l, ok
+}
+
+// Crossed reports whether going from before to after payouts this month
+// passes the warning share of the monthly quota.
+func (q *Quotas) Crossed(l Limits, before, after int) bool {
+	if q == nil || q.WarnAt <= 0 || l.PayoutsPerMonth == 0 {
+		return false
+	}
+	mark := q.WarnAt * float64(l.PayoutsPerMonth)
+	return float64(before) < mark && float64(after) >= mark
+}
+
+// MonthStart returns the start of t's calendar month in UTC, from which
+// monthly quotas count.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
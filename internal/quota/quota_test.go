@@ -0,0 +1,76 @@
+package quota_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/quota"
+)
+
+func TestParse(t *testing.T) {
+	q, err := quota.Parse("acme:payouts_per_month=1000;batch_size=100, *:concurrent_batches=2")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if l, ok := q.For("acme"); !ok || l != (quota.Limits{PayoutsPerMonth: 1000, BatchSize: 100}) {
+		t.Errorf("Expected acme's own limits, got %+v (%v)", l, ok)
+	}
+	if l, ok := q.For("other"); !ok || l != (quota.Limits{ConcurrentBatches: 2}) {
+		t.Errorf("Expected the default limits for an unlisted merchant, got %+v (%v)", l, ok)
+	}
+	if _, ok := q.For(""); ok {
+		t.Error("Expected no limits for a request naming no merchant")
+	}
+	var none *quota.Quotas
+	if _, ok := none.For("acme"); ok {
+		t.Error("Expected nil quotas to limit nobody")
+	}
+
+	for _, spec := range []string{"acme", "acme:batch_size", "acme:batch_size=-1", "acme:payouts=10", ":batch_size=1"} {
+		if _, err := quota.Parse(spec); err == nil {
+			t.Errorf("Expected %q to be refused", spec)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	l := quota.Limits{PayoutsPerMonth: 1000, BatchSize: 100, ConcurrentBatches: 2}
+	cases := []struct {
+		name  string
+		err   error
+		limit string
+	}{
+		{"fits", l.CheckBatch(100, 900), ""},
+		{"batch too large", l.CheckBatch(101, 0), quota.LimitBatchSize},
+		{"month used up", l.CheckBatch(50, 951), quota.LimitPayoutsPerMonth},
+		{"room to start", l.CheckStart(1), ""},
+		{"too many running", l.CheckStart(2), quota.LimitConcurrentBatches},
+	}
+	for _, tc := range cases {
+		var exceeded *quota.ExceededError
+		switch {
+		case tc.limit == "" && tc.err != nil:
+			t.Errorf("%s: expected no error, got %v", tc.name, tc.err)
+		case tc.limit != "" && (!errors.As(tc.err, &exceeded) || exceeded.Limit != tc.limit):
+			t.Errorf("%s: expected %s exceeded, got %v", tc.name, tc.limit, tc.err)
+		}
+	}
+	if err := (quota.Limits{}).CheckBatch(1e6, 1e9); err != nil {
+		t.Errorf("Expected no limits to refuse nothing, got %v", err)
+	}
+}
+
+func TestCrossed(t *testing.T) {
+	q := &quota.Quotas{WarnAt: 0.8}
+	l := quota.Limits{PayoutsPerMonth: 1000}
+	if !q.Crossed(l, 700, 800) {
+		t.Error("Expected reaching 80% to cross the warning mark")
+	}
+	if q.Crossed(l, 800, 900) || q.Crossed(l, 100, 200) {
+		t.Error("Expected a warning only when the mark is crossed")
+	}
+	if got := quota.MonthStart(time.Date(2024, 3, 17, 23, 0, 0, 0, time.FixedZone("", -5*3600))); !got.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the month to start on March 1 UTC, got %s", got)
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Operator Overrides ---
+
+// ErrRunLive is returned for overrides that must not race a processing run.
+var ErrRunLive = errors.New("a processing run is live on the batch")
+
+// ErrPayoutNotForceable is returned when force-completing a payout that is
+// already completed, is being processed, or was requeued elsewhere.
+var ErrPayoutNotForceable = errors.New("payout is not failed or pending, or was requeued")
+
+// ForceCompletePayout marks a failed or pending payout as completed because
+// it was paid outside the engine (e.g. by manual transfer). A completed
+// attempt is recorded so the attempt history stays consistent, and the
+// batch's counters, status and funding are then rebuilt as by RepairBatch.
+// It returns nil if the payout does not exist, and ErrRunLive while a run
+// is processing the batch.
+func (r *Repository) ForceCompletePayout(ctx context.Context, payoutID uuid.UUID, operator, reason string) (*models.Payout, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var batchID uuid.UUID
+	var status string
+	var attempts int
+	var superseded bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT batch_id, status, attempt_count, superseded_by IS NOT NULL FROM payouts WHERE id = $1 FOR UPDATE`, payoutID,
+	).Scan(&batchID, &status, &attempts, &superseded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+
+	var idle bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_xact_lock($1, hashtext($2))`, runLockClass, batchID.String(),
+	).Scan(&idle); err != nil {
+		return nil, fmt.Errorf("try run lock: %w", err)
+	}
+	if !idle {
+		return nil, ErrRunLive
+	}
+	if status != models.PayoutStatusFailed && status != models.PayoutStatusPending || superseded {
+		return nil, ErrPayoutNotForceable
+	}
+
+	now := r.now()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payouts SET status = $1, failure_reason = NULL, completed_at = $2, updated_at = $2,
+		        attempt_count = attempt_count + 1
+		 WHERE id = $3`,
+		models.PayoutStatusCompleted, now, payoutID); err != nil {
+		return nil, fmt.Errorf("force-complete payout: %w", err)
+	}
+	attempt := &models.PayoutAttempt{
+		ID:         uuid.New(),
+		PayoutID:   payoutID,
+		AttemptNum: attempts + 1,
+		Status:     models.PayoutStatusCompleted,
+		StartedAt:  now,
+		FinishedAt: &now,
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO payout_attempts (id, payout_id, attempt_num, status, error, started_at, finished_at)
+		 VALUES ($1, $2, $3, $4, NULL, $5, $6)`,
+		attempt.ID, attempt.PayoutID, attempt.AttemptNum, attempt.Status, attempt.StartedAt, attempt.FinishedAt,
+	); err != nil {
+		return nil, fmt.Errorf("insert attempt: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	if err := r.journal(ctx, audit.KindForceComplete, payoutID, map[string]any{
+		"attempt": attempt, "previous_status": status, "operator": operator, "reason": reason,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := r.RepairBatch(ctx, batchID, true); err != nil {
+		return nil, err
+	}
+	return r.GetPayout(ctx, payoutID)
+}
+
+// SettleBatch re-runs funding settlement for a batch on an operator's
+// request, e.g. after payouts were corrected by hand, and returns its
+// reservations afterwards. Settlement is idempotent.
+func (r *Repository) SettleBatch(ctx context.Context, batchID uuid.UUID, operator string) ([]models.FundingReservation, error) {
+	if err := r.SettleFunding(ctx, batchID); err != nil {
+		return nil, err
+	}
+	reservations, err := r.GetBatchReservations(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	return reservations, r.journal(ctx, audit.KindManualSettle, batchID, map[string]any{
+		"reservations": reservations, "operator": operator,
+	})
+}
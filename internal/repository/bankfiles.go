@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// --- Bank Files ---
+
+var (
+	// ErrBankFileDuplicate is returned for a bank file that was already
+	// received, by checksum.
+	ErrBankFileDuplicate = errors.New("bank file was already received")
+	// ErrUnknownPaymentFile is returned for a bank file answering as a whole
+	// for a payment file that does not exist or was never delivered.
+	ErrUnknownPaymentFile = errors.New("bank file answers for an unknown or undelivered payment file")
+)
+
+// ApplyBankFile applies the answers of a parsed bank file to the delivered
+// payment files holding the payouts, as AcknowledgePaymentFile does, and
+// records the file with the outcome of each answer. An answer for a payout
+// that already has one is not applied: it is already_answered if the same,
+// or a conflict, e.g. a return after the bank accepted the payout. It
+// returns ErrBankFileDuplicate if a file with the same checksum was
+// received before, ErrUnknownPaymentFile if the file answers for a payment
+// file as a whole that was not delivered, and ErrRunLive while a run is
+// processing a batch with a payout in the file; the file is then not
+// recorded and can be uploaded again once the run ends, the answers
+// applied before the error being found already answered.
+func (r *Repository) ApplyBankFile(ctx context.Context, f *models.BankFile, operator string) (*models.BankFile, error) {
+	var seen uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM bank_files WHERE checksum = $1`, f.Checksum).Scan(&seen)
+	if err == nil {
+		return nil, ErrBankFileDuplicate
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("look up bank file: %w", err)
+	}
+
+	// Each answered payout's item in the latest delivered payment file that
+	// has it. References shorter than a NACHA one match nothing.
+	type fileItem struct {
+		fileID, payoutID uuid.UUID
+		result           string
+	}
+	refs := make([]string, len(f.Answers))
+	for i, a := range f.Answers {
+		refs[i] = a.Reference
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT ON (t.ref) t.ref, i.file_id, i.payout_id, COALESCE(i.result, '')
+		 FROM unnest($1::text[]) AS t(ref)
+		 JOIN payment_file_items i ON left(i.reference, 15) = left(t.ref, 15) AND i.reference LIKE t.ref || '%'
+		 JOIN payment_files f ON f.id = i.file_id AND f.status <> $2
+		 WHERE length(t.ref) >= 15
+		 ORDER BY t.ref, f.generated_at DESC`,
+		pq.Array(refs), models.PaymentFileGenerated)
+	if err != nil {
+		return nil, fmt.Errorf("match bank file answers: %w", err)
+	}
+	items := map[string]fileItem{}
+	for rows.Next() {
+		var ref string
+		var it fileItem
+		if err := rows.Scan(&ref, &it.fileID, &it.payoutID, &it.result); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan bank file match: %w", err)
+		}
+		items[ref] = it
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	acks := map[uuid.UUID][]models.PaymentFileAckItem{}
+	var files []uuid.UUID // in the order first answered
+	answered := map[uuid.UUID]string{}
+	decide := func(a *models.BankFileAnswer, it fileItem) {
+		a.PayoutID, a.PaymentFileID = &it.payoutID, &it.fileID
+		if prev, ok := answered[it.payoutID]; ok {
+			it.result = prev // answered earlier in this file
+		}
+		switch {
+		case it.result == "":
+			a.Outcome = models.BankAnswerApplied
+			answered[it.payoutID] = a.Result
+			if _, ok := acks[it.fileID]; !ok {
+				files = append(files, it.fileID)
+			}
+			acks[it.fileID] = append(acks[it.fileID], models.PaymentFileAckItem{PayoutID: it.payoutID, Result: a.Result, Reason: a.Reason})
+		case it.result == a.Result:
+			a.Outcome = models.BankAnswerAnswered
+		default:
+			a.Outcome = models.BankAnswerConflict
+		}
+	}
+	for i := range f.Answers {
+		it, ok := items[f.Answers[i].Reference]
+		if !ok {
+			f.Answers[i].Outcome = models.BankAnswerUnmatched
+			continue
+		}
+		decide(&f.Answers[i], it)
+	}
+	if f.PaymentFileID != nil {
+		pf, err := r.GetPaymentFile(ctx, *f.PaymentFileID)
+		if err != nil {
+			return nil, err
+		}
+		if pf == nil || pf.Status == models.PaymentFileGenerated {
+			return nil, ErrUnknownPaymentFile
+		}
+		// The file-level answer stands for every payout not answered yet.
+		for _, it := range pf.Items {
+			if _, ok := answered[it.PayoutID]; ok || it.Result != "" {
+				continue
+			}
+			a := models.BankFileAnswer{Reference: it.Reference, Result: f.FileResult, Reason: f.FileReason}
+			decide(&a, fileItem{fileID: pf.ID, payoutID: it.PayoutID})
+			f.Answers = append(f.Answers, a)
+		}
+	}
+
+	for _, fileID := range files {
+		if _, err := r.AcknowledgePaymentFile(ctx, fileID, models.PaymentFileAckRequest{Items: acks[fileID]}, operator); err != nil {
+			return nil, fmt.Errorf("acknowledge payment file %s: %w", fileID, err)
+		}
+	}
+
+	f.ID, f.ReceivedBy, f.ReceivedAt = uuid.New(), operator, r.now()
+	for _, a := range f.Answers {
+		switch a.Outcome {
+		case models.BankAnswerApplied:
+			f.Applied++
+		case models.BankAnswerAnswered:
+			f.AlreadyAnswered++
+		case models.BankAnswerConflict:
+			f.Conflicts++
+		case models.BankAnswerUnmatched:
+			f.Unmatched++
+		}
+	}
+	answers, err := json.Marshal(f.Answers)
+	if err != nil {
+		return nil, fmt.Errorf("encode answers: %w", err)
+	}
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO bank_files (id, format, message_id, payment_file_id, file_result, file_reason, checksum,
+		        received_by, received_at, applied, already_answered, conflicts, unmatched, pending, answers)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 ON CONFLICT (checksum) DO NOTHING`,
+		f.ID, f.Format, f.MessageID, f.PaymentFileID, f.FileResult, f.FileReason, f.Checksum,
+		f.ReceivedBy, f.ReceivedAt, f.Applied, f.AlreadyAnswered, f.Conflicts, f.Unmatched, f.Pending, answers)
+	if err != nil {
+		return nil, fmt.Errorf("insert bank file: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrBankFileDuplicate // received concurrently
+	}
+	return f, r.journal(ctx, audit.KindBankFile, f.ID, f)
+}
+
+// GetBankFile returns a received bank file with its answers, or nil if it
+// does not exist.
+func (r *Repository) GetBankFile(ctx context.Context, id uuid.UUID) (*models.BankFile, error) {
+	f := &models.BankFile{}
+	var answers []byte
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, format, COALESCE(message_id, ''), payment_file_id, COALESCE(file_result, ''), COALESCE(file_reason, ''),
+		        checksum, received_by, received_at, applied, already_answered, conflicts, unmatched, pending, answers
+		 FROM bank_files WHERE id = $1`, id,
+	).Scan(&f.ID, &f.Format, &f.MessageID, &f.PaymentFileID, &f.FileResult, &f.FileReason,
+		&f.Checksum, &f.ReceivedBy, &f.ReceivedAt, &f.Applied, &f.AlreadyAnswered, &f.Conflicts, &f.Unmatched, &f.Pending, &answers)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get bank file: %w", err)
+	}
+	if err := json.Unmarshal(answers, &f.Answers); err != nil {
+		return nil, fmt.Errorf("decode answers: %w", err)
+	}
+	return f, nil
+}
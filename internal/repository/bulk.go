@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// --- Bulk Payout Actions ---
+
+// bulkTarget is what deciding a bulk action needs to know about a payout.
+type bulkTarget struct {
+	batchID    uuid.UUID
+	status     string
+	held       bool
+	superseded bool
+	tagged     bool
+	deleted    bool // the batch is soft-deleted
+	cancelled  bool // the batch is cancelled
+	filed      bool // held by a payment file until the bank answers
+	reviewing  bool // held until its reporting approval
+}
+
+// bulkEligible reports whether action applies to the payout, and if not, why.
+func bulkEligible(action string, p bulkTarget) (bool, string) {
+	var ok bool
+	if (p.deleted || p.cancelled || p.filed) && action != models.BulkAddTag {
+		return false, models.BulkSkipNotEligible
+	}
+	if p.reviewing && action == models.BulkRelease {
+		return false, models.BulkSkipAwaitingApproval
+	}
+	switch action {
+	case models.BulkHold:
+		ok = p.status == models.PayoutStatusPending && !p.held
+	case models.BulkRelease:
+		ok = p.status == models.PayoutStatusPending && p.held
+	case models.BulkCancel:
+		ok = p.status == models.PayoutStatusPending
+	case models.BulkRetry:
+		ok = p.status == models.PayoutStatusFailed && !p.superseded
+	case models.BulkAddTag:
+		if p.tagged {
+			return false, models.BulkSkipAlreadyTagged
+		}
+		ok = true
+	}
+	if !ok {
+		return false, models.BulkSkipNotEligible
+	}
+	return true, ""
+}
+
+// BulkUpdatePayouts applies one action to many payouts in one transaction:
+//   - hold keeps pending payouts from being processed, release undoes it;
+//   - cancel closes out pending payouts, held or not, as cancelled;
+//   - retry puts failed payouts that were not requeued back to pending with
+//     a fresh retry budget, whatever their failure;
+//   - add_tag adds a tag to payouts in any status.
+//
+// Payouts the action does not apply to, whose batch is deleted or cancelled
+// or that are in a payment file awaiting the bank's answer (except for
+// tags), are skipped, as are releases of payouts held for their reporting
+// approval, or with all_or_nothing roll the whole request back. Processing
+// runs skip held and cancelled payouts, so no run lock is needed. Batches
+// whose payouts were cancelled or retried are repaired afterwards, which
+// recounts them and pauses a finished batch that has payouts to process
+// again; the caller restarts it.
+func (r *Repository) BulkUpdatePayouts(ctx context.Context, req models.BulkPayoutRequest, operator string) (*models.BulkPayoutResponse, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, len(req.PayoutIDs))
+	for i, id := range req.PayoutIDs {
+		ids[i] = id.String()
+	}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT p.id, p.batch_id, p.status, p.held_at IS NOT NULL, p.superseded_by IS NOT NULL,
+		        $2 = ANY(p.tags), b.deleted_at IS NOT NULL, b.status = $5, COALESCE(p.held_by, '') LIKE $3, COALESCE(p.held_by, '') = $4
+		 FROM payouts p JOIN payout_batches b ON b.id = p.batch_id
+		 WHERE p.id = ANY($1::uuid[]) ORDER BY p.id FOR UPDATE OF p`,
+		pq.Array(ids), req.Tag, paymentFileHold+"%", reportingHold, models.BatchStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("lock payouts: %w", err)
+	}
+	targets := make(map[uuid.UUID]bulkTarget, len(req.PayoutIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var t bulkTarget
+		if err := rows.Scan(&id, &t.batchID, &t.status, &t.held, &t.superseded, &t.tagged, &t.deleted, &t.cancelled, &t.filed, &t.reviewing); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan payout: %w", err)
+		}
+		targets[id] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := &models.BulkPayoutResponse{Action: req.Action, Results: make([]models.BulkPayoutResult, 0, len(req.PayoutIDs)), Batches: []uuid.UUID{}}
+	seen := make(map[uuid.UUID]bool, len(req.PayoutIDs))
+	touched := map[uuid.UUID]bool{}
+	for _, id := range req.PayoutIDs {
+		res := models.BulkPayoutResult{PayoutID: id, Result: models.BulkSkipped}
+		t, found := targets[id]
+		switch {
+		case seen[id]:
+			res.Reason = models.BulkSkipDuplicate
+		case !found:
+			res.Reason = models.BulkSkipNotFound
+		default:
+			res.Status = t.status
+			ok, reason := bulkEligible(req.Action, t)
+			if !ok {
+				res.Reason = reason
+				break
+			}
+			if res.Status, err = r.applyBulk(ctx, tx, req, id, operator); err != nil {
+				return nil, err
+			}
+			res.Result = models.BulkApplied
+			if !touched[t.batchID] {
+				touched[t.batchID] = true
+				resp.Batches = append(resp.Batches, t.batchID)
+			}
+		}
+		seen[id] = true
+		if res.Result == models.BulkApplied {
+			resp.Applied++
+		} else {
+			resp.Skipped++
+		}
+		resp.Results = append(resp.Results, res)
+	}
+	if req.AllOrNothing && resp.Skipped > 0 {
+		for i := range resp.Results {
+			if resp.Results[i].Result == models.BulkApplied {
+				resp.Results[i].Result = models.BulkRolledBack
+			}
+		}
+		resp.Applied, resp.Batches = 0, []uuid.UUID{}
+		return resp, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	resp.Committed = true
+
+	for _, res := range resp.Results {
+		if res.Result != models.BulkApplied {
+			continue
+		}
+		if err := r.journal(ctx, audit.KindPayoutBulkAction, res.PayoutID, map[string]any{
+			"action": req.Action, "tag": req.Tag, "status": res.Status, "operator": operator,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if req.Action == models.BulkCancel || req.Action == models.BulkRetry {
+		for _, batchID := range resp.Batches {
+			if _, err := r.RepairBatch(ctx, batchID, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resp, nil
+}
+
+// applyBulk applies a bulk action to one eligible payout and returns its new status.
+func (r *Repository) applyBulk(ctx context.Context, tx *sql.Tx, req models.BulkPayoutRequest, id uuid.UUID, operator string) (string, error) {
+	var set string
+	args := []any{id, r.now()}
+	switch req.Action {
+	case models.BulkHold:
+		set, args = `held_at = $2, held_by = $3`, append(args, operator)
+	case models.BulkRelease:
+		set = `held_at = NULL, held_by = NULL`
+	case models.BulkCancel:
+		set, args = `status = $3, held_at = NULL, held_by = NULL`, append(args, models.PayoutStatusCancelled)
+	case models.BulkRetry:
+		set = `status = $3, failure_reason = NULL, max_retries = attempt_count + ` + batchAttempts("$4")
+		args = append(args, models.PayoutStatusPending, models.DefaultMaxRetries)
+	case models.BulkAddTag:
+		set, args = `tags = array_append(tags, $3)`, append(args, req.Tag)
+	}
+	var status string
+	if err := tx.QueryRowContext(ctx,
+		`UPDATE payouts SET `+set+`, updated_at = $2 WHERE id = $1 RETURNING status`, args...,
+	).Scan(&status); err != nil {
+		return "", fmt.Errorf("%s payout %s: %w", req.Action, id, err)
+	}
+	return status, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Batch Cancellation ---
+
+// ErrBatchNotCancellable is returned by CancelBatch for batches that have
+// finished, are still being created, or are deleted.
+var ErrBatchNotCancellable = errors.New("batch cannot be cancelled")
+
+// ErrPayoutNotCancellable is returned by CancelPayout for payouts that are
+// not pending, are in a payment file awaiting the bank's answer, or whose
+// batch is deleted or cancelled.
+var ErrPayoutNotCancellable = errors.New("payout cannot be cancelled")
+
+// releasedStatus is, in an UPDATE of payouts, the status a payout handed
+// back from processing takes: pending, or cancelled if its batch was
+// cancelled meanwhile, since nothing will process it again.
+const releasedStatus = `CASE WHEN (SELECT pb.status FROM payout_batches pb WHERE pb.id = payouts.batch_id) = '` +
+	models.BatchStatusCancelled + `' THEN '` + models.PayoutStatusCancelled + `' ELSE '` + models.PayoutStatusPending + `' END`
+
+// CancelBatch abandons a batch that has not finished: its pending payouts,
+// held or not, are cancelled and the batch takes the terminal cancelled
+// status, recounted and with the funding held for the cancelled payouts
+// released, all in one transaction. Payouts in a payment file awaiting the
+// bank's answer are left pending, as the bank may still pay them. Payouts
+// in flight finish their transfer; the run they are in hands back the rest
+// of its claims as cancelled (see releasedStatus) and FinalizeBatch settles
+// them. It returns the batch, or nil if it does not exist.
+func (r *Repository) CancelBatch(ctx context.Context, batchID uuid.UUID, operator string) (*models.PayoutBatch, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	batch := &models.PayoutBatch{}
+	err = scanBatch(tx.QueryRowContext(ctx,
+		`SELECT `+batchColumns+` FROM payout_batches b WHERE b.id = $1 FOR UPDATE`, batchID,
+	), batch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock batch: %w", err)
+	}
+	if batch.DeletedAt != nil || batch.Status == models.BatchStatusCreating || batch.IsTerminal() {
+		return nil, ErrBatchNotCancellable
+	}
+
+	now := r.now()
+	result, err := tx.ExecContext(ctx,
+		`UPDATE payouts SET status = $2, held_at = NULL, held_by = NULL, updated_at = $3
+		 WHERE batch_id = $1 AND status = $4 AND COALESCE(held_by, '') NOT LIKE $5`,
+		batchID, models.PayoutStatusCancelled, now, models.PayoutStatusPending, paymentFileHold+"%")
+	if err != nil {
+		return nil, fmt.Errorf("cancel payouts: %w", err)
+	}
+	cancelled, _ := result.RowsAffected()
+
+	_, counts, err := countPayouts(ctx, tx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if err := scanBatch(tx.QueryRowContext(ctx,
+		`UPDATE payout_batches b SET status = $2, completed_count = $3, failed_count = $4, pending_count = $5,
+		        completed_at = $6, updated_at = $6
+		 WHERE b.id = $1
+		 RETURNING `+batchColumns,
+		batchID, models.BatchStatusCancelled, counts.Completed, counts.Failed, counts.Pending, now,
+	), batch); err != nil {
+		return nil, fmt.Errorf("cancel batch: %w", err)
+	}
+	if err := settleFunding(ctx, tx, batchID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return batch, r.journal(ctx, audit.KindBatchCancelled, batchID, map[string]any{
+		"batch": batch, "cancelled_payouts": cancelled, "cancelled_by": operator,
+	})
+}
+
+// CancelPayout takes one pending payout out of its batch, held or not, as
+// cancelled, e.g. while its vendor is in dispute. Its row lock keeps a run
+// from claiming it meanwhile, and once cancelled no run will. The operator
+// and reason are journaled, and the batch is then repaired as after a bulk
+// cancel: recounted, finished if nothing is left to process, and its
+// funding settled. It returns the payout, or nil if it does not exist.
+func (r *Repository) CancelPayout(ctx context.Context, payoutID uuid.UUID, operator, reason string) (*models.Payout, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var batchID uuid.UUID
+	var status, batchStatus string
+	var filed, deleted bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT p.batch_id, p.status, COALESCE(p.held_by, '') LIKE $2, b.status, b.deleted_at IS NOT NULL
+		 FROM payouts p JOIN payout_batches b ON b.id = p.batch_id
+		 WHERE p.id = $1 FOR UPDATE OF p`, payoutID, paymentFileHold+"%",
+	).Scan(&batchID, &status, &filed, &batchStatus, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+	if status != models.PayoutStatusPending || filed || deleted || batchStatus == models.BatchStatusCancelled {
+		return nil, ErrPayoutNotCancellable
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payouts SET status = $2, held_at = NULL, held_by = NULL, updated_at = $3 WHERE id = $1`,
+		payoutID, models.PayoutStatusCancelled, r.now()); err != nil {
+		return nil, fmt.Errorf("cancel payout: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	if err := r.journal(ctx, audit.KindPayoutCancelled, payoutID, map[string]any{
+		"batch_id": batchID, "previous_status": status, "operator": operator, "reason": reason,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := r.RepairBatch(ctx, batchID, true); err != nil {
+		return nil, err
+	}
+	return r.GetPayout(ctx, payoutID)
+}
+
+// settleCancelled recounts a cancelled batch and settles its funding once
+// none of its payouts is in processing any more, within tx, which it
+// commits.
+func settleCancelled(ctx context.Context, tx *sql.Tx, batchID uuid.UUID, now time.Time) error {
+	_, counts, err := countPayouts(ctx, tx, batchID)
+	if err != nil {
+		return err
+	}
+	var processing bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM payouts WHERE batch_id = $1 AND status = $2)`,
+		batchID, models.PayoutStatusProcessing,
+	).Scan(&processing); err != nil {
+		return fmt.Errorf("look up payouts in processing: %w", err)
+	}
+	if processing {
+		return nil // Left to the run that has them
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payout_batches SET completed_count = $2, failed_count = $3, pending_count = $4, updated_at = $5 WHERE id = $1`,
+		batchID, counts.Completed, counts.Failed, counts.Pending, now,
+	); err != nil {
+		return fmt.Errorf("recount batch: %w", err)
+	}
+	if err := settleFunding(ctx, tx, batchID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Regulatory Reporting ---
+
+var (
+	// ErrNotAwaitingApproval is returned when approving a payout that is not
+	// pending and held for its reporting approval.
+	ErrNotAwaitingApproval = errors.New("payout is not awaiting a reporting approval")
+	// ErrReportingSelfApproval is returned when the owner of a payout's
+	// batch approves it.
+	ErrReportingSelfApproval = errors.New("a batch owner cannot approve its own payouts")
+)
+
+// reportingHold is the held_by of payouts flagged for regulatory reporting
+// that wait for an approval before a run may send them.
+const reportingHold = "reporting_approval"
+
+// ApproveReportablePayout releases a payout held for its reporting
+// approval, recording who approved it. It returns nil if the payout does
+// not exist, ErrNotAwaitingApproval if it is not held for one and
+// ErrReportingSelfApproval if approvedBy owns its batch.
+func (r *Repository) ApproveReportablePayout(ctx context.Context, payoutID uuid.UUID, approvedBy string) (*models.Payout, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status, heldBy, owner string
+	err = tx.QueryRowContext(ctx,
+		`SELECT p.status, COALESCE(p.held_by, ''), COALESCE(b.owner, '')
+		 FROM payouts p JOIN payout_batches b ON b.id = p.batch_id
+		 WHERE p.id = $1 FOR UPDATE OF p`, payoutID,
+	).Scan(&status, &heldBy, &owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+	if status != models.PayoutStatusPending || heldBy != reportingHold {
+		return nil, ErrNotAwaitingApproval
+	}
+	if owner == approvedBy {
+		return nil, ErrReportingSelfApproval
+	}
+
+	now := r.now()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payouts SET held_at = NULL, held_by = NULL, reporting_approved_by = $2, reporting_approved_at = $3, updated_at = $3
+		 WHERE id = $1`, payoutID, approvedBy, now); err != nil {
+		return nil, fmt.Errorf("approve payout: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	if err := r.journal(ctx, audit.KindReportingApproved, payoutID,
+		map[string]any{"approved_by": approvedBy, "approved_at": now}); err != nil {
+		return nil, err
+	}
+	return r.GetPayout(ctx, payoutID)
+}
+
+// GetReportablePayouts lists the payouts flagged for regulatory reporting,
+// optionally of one country and created in [from, to), oldest first.
+func (r *Repository) GetReportablePayouts(ctx context.Context, country string, from, to *time.Time) ([]models.ReportablePayout, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, batch_id, vendor_id, COALESCE(vendor_name, ''), reporting_country, amount, currency, status, created_at, completed_at,
+		       COALESCE(held_by, '') = $1, reporting_approved_by, reporting_approved_at
+		FROM payouts
+		WHERE reporting_country IS NOT NULL
+		  AND ($2::text = '' OR reporting_country = $2)
+		  AND ($3::timestamptz IS NULL OR created_at >= $3)
+		  AND ($4::timestamptz IS NULL OR created_at < $4)
+		ORDER BY created_at, seq`, reportingHold, country, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query reportable payouts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := []models.ReportablePayout{}
+	for rows.Next() {
+		var p models.ReportablePayout
+		if err := rows.Scan(&p.PayoutID, &p.BatchID, &p.VendorID, &p.VendorName, &p.Country, &p.Amount, &p.Currency,
+			&p.Status, &p.CreatedAt, &p.CompletedAt, &p.AwaitingApproval, &p.ApprovedBy, &p.ApprovedAt); err != nil {
+			return nil, fmt.Errorf("scan reportable payout: %w", err)
+		}
+		payouts = append(payouts, p)
+	}
+	return payouts, rows.Err()
+}
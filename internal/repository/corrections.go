@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Correction Chains ---
+
+// Requeue errors, wrapped in a *PayoutError naming the payout.
+var (
+	ErrPayoutNotFound = errors.New("payout not found")
+	// ErrPayoutNotRequeueable is returned for a payout that is not failed,
+	// was already requeued, or belongs to a deleted batch.
+	ErrPayoutNotRequeueable = errors.New("payout is not failed or was already requeued")
+)
+
+// PayoutError ties an error to the payout it concerns.
+type PayoutError struct {
+	PayoutID uuid.UUID
+	Err      error
+}
+
+func (e *PayoutError) Error() string { return fmt.Sprintf("payout %s: %v", e.PayoutID, e.Err) }
+
+func (e *PayoutError) Unwrap() error { return e.Err }
+
+// RequeuePayouts creates a new batch holding a copy of each failed payout,
+// re-routed to the item's bank account where one is given. Each copy
+// supersedes its original, which is left failed in its own batch but is no
+// longer retried, force-completed or counted as failed money. The new batch
+// is pending, to be started like any other.
+func (r *Repository) RequeuePayouts(ctx context.Context, items []models.RequeueItem, opts models.BatchOptions, operator string) (*models.PayoutBatch, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	originals := make([]models.Payout, len(items))
+	create := make([]models.CreatePayoutItem, len(items))
+	seen := make(map[uuid.UUID]bool, len(items))
+	for i, item := range items {
+		if seen[item.PayoutID] {
+			return nil, &PayoutError{item.PayoutID, ErrPayoutNotRequeueable}
+		}
+		seen[item.PayoutID] = true
+
+		p := &originals[i]
+		var deleted bool
+		err := scanPayout(tx.QueryRowContext(ctx,
+			`SELECT `+payoutColumns+`, b.deleted_at IS NOT NULL
+			 FROM payouts p JOIN payout_batches b ON b.id = p.batch_id
+			 WHERE p.id = $1 FOR UPDATE OF p`, item.PayoutID), p, &deleted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &PayoutError{item.PayoutID, ErrPayoutNotFound}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get payout %s: %w", item.PayoutID, err)
+		}
+		if p.Status != models.PayoutStatusFailed || p.SupersededBy != nil || deleted {
+			return nil, &PayoutError{item.PayoutID, ErrPayoutNotRequeueable}
+		}
+
+		create[i] = models.CreatePayoutItem{
+			VendorID:       p.VendorID,
+			VendorName:     p.VendorName,
+			Amount:         p.Amount,
+			Currency:       p.Currency,
+			BankAccount:    p.BankAccount,
+			BankName:       p.BankName,
+			TransactionIDs: p.TransactionIDs,
+			Metadata:       p.Metadata,
+		}
+		if item.BankAccount != "" {
+			create[i].BankAccount = item.BankAccount
+		}
+		if item.BankName != "" {
+			create[i].BankName = item.BankName
+		}
+	}
+
+	batch, ids, err := r.createBatch(ctx, tx, create, opts)
+	if err != nil {
+		return nil, err
+	}
+	now := r.now()
+	for i, id := range ids {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE payouts SET supersedes = $1 WHERE id = $2`, originals[i].ID, id); err != nil {
+			return nil, fmt.Errorf("link payout %s: %w", id, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE payouts SET superseded_by = $1, updated_at = $2 WHERE id = $3`, id, now, originals[i].ID); err != nil {
+			return nil, fmt.Errorf("supersede payout %s: %w", originals[i].ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	for i, id := range ids {
+		if err := r.journal(ctx, audit.KindPayoutRequeued, originals[i].ID, map[string]any{
+			"superseded_by": id, "batch_id": batch.ID, "bank_account": create[i].BankAccount,
+			"bank_name": create[i].BankName, "operator": operator,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return batch, nil
+}
+
+// GetCorrectionChain returns every payout linked to payoutID by requeues,
+// oldest first. A payout that was never requeued is its own chain.
+func (r *Repository) GetCorrectionChain(ctx context.Context, payoutID uuid.UUID) ([]models.Payout, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH RECURSIVE earlier AS (
+			SELECT id, supersedes, 0 AS depth FROM payouts WHERE id = $1
+			UNION ALL
+			SELECT p.id, p.supersedes, e.depth - 1 FROM payouts p JOIN earlier e ON p.id = e.supersedes
+		), later AS (
+			SELECT id, superseded_by, 0 AS depth FROM payouts WHERE id = $1
+			UNION ALL
+			SELECT p.id, p.superseded_by, l.depth + 1 FROM payouts p JOIN later l ON p.id = l.superseded_by
+		), chain AS (
+			SELECT id, depth FROM earlier UNION SELECT id, depth FROM later
+		)
+		SELECT `+payoutColumns+` FROM chain JOIN payouts p ON p.id = chain.id ORDER BY chain.depth`, payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("query correction chain: %w", err)
+	}
+	defer rows.Close()
+	return scanPayouts(rows)
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Email Deliveries ---
+
+// RecordEmailDelivery stores one attempt to email a vendor or a batch assignee.
+func (r *Repository) RecordEmailDelivery(ctx context.Context, d *models.EmailDelivery) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO email_deliveries (id, payout_id, batch_id, template, locale, recipient, provider, status,
+		        provider_message_id, error, attempted_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		d.ID, d.PayoutID, d.BatchID, d.Template, d.Locale, d.Recipient, d.Provider, d.Status,
+		d.ProviderMessageID, d.Error, d.AttemptedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert email delivery: %w", err)
+	}
+	return nil
+}
+
+// ListEmailDeliveries returns the email attempts for a payout, oldest first.
+func (r *Repository) ListEmailDeliveries(ctx context.Context, payoutID uuid.UUID) ([]models.EmailDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, payout_id, batch_id, template, locale, recipient, provider, status, provider_message_id, error, attempted_at
+		 FROM email_deliveries WHERE payout_id = $1
+		 ORDER BY attempted_at ASC`, payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("query email deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.EmailDelivery{}
+	for rows.Next() {
+		var d models.EmailDelivery
+		if err := rows.Scan(&d.ID, &d.PayoutID, &d.BatchID, &d.Template, &d.Locale, &d.Recipient, &d.Provider,
+			&d.Status, &d.ProviderMessageID, &d.Error, &d.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("scan email delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// --- Duplicate Detection ---
+
+// ListCompletedPayouts returns the payouts to the given vendors completed
+// since a time in the merchant's batches ("" for batches of no merchant),
+// for checking a new batch against (see models.MatchCompletedPayouts).
+// Deleted batches are included: their payouts were still paid.
+func (r *Repository) ListCompletedPayouts(ctx context.Context, merchant string, vendorIDs []string, since time.Time) ([]models.Payout, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+payoutColumns+` FROM payouts p
+		 JOIN payout_batches b ON b.id = p.batch_id
+		 WHERE p.vendor_id = ANY($1) AND p.status = $2 AND p.completed_at >= $3
+		   AND b.merchant_id IS NOT DISTINCT FROM NULLIF($4, '')`,
+		pq.Array(vendorIDs), models.PayoutStatusCompleted, since, merchant)
+	if err != nil {
+		return nil, fmt.Errorf("list completed payouts: %w", err)
+	}
+	defer rows.Close()
+
+	var payouts []models.Payout
+	for rows.Next() {
+		var p models.Payout
+		if err := scanPayout(rows, &p); err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, p)
+	}
+	return payouts, rows.Err()
+}
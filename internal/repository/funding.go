@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrInsufficientFunding is returned by ReserveFunding when a funding account
+// cannot cover a batch on top of what other batches already hold.
+var ErrInsufficientFunding = errors.New("insufficient funding")
+
+// --- Funding ---
+
+// SetFundingBalance creates or updates the funding account of a currency.
+// The balance cannot drop below what batches currently hold against it.
+func (r *Repository) SetFundingBalance(ctx context.Context, currency string, balance float64) (*models.FundingAccount, error) {
+	var a models.FundingAccount
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO funding_accounts (currency, balance, updated_at) VALUES ($1, $2, NOW())
+		 ON CONFLICT (currency) DO UPDATE SET balance = EXCLUDED.balance, updated_at = NOW()
+		 RETURNING currency, balance, reserved, updated_at`,
+		currency, balance,
+	).Scan(&a.Currency, &a.Balance, &a.Reserved, &a.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("set funding balance: %w", err)
+	}
+	a.Available = a.Balance - a.Reserved
+	return &a, nil
+}
+
+// ListFundingAccounts returns every funding account, by currency.
+func (r *Repository) ListFundingAccounts(ctx context.Context) ([]models.FundingAccount, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT currency, balance, reserved, updated_at FROM funding_accounts ORDER BY currency`)
+	if err != nil {
+		return nil, fmt.Errorf("query funding accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.FundingAccount{}
+	for rows.Next() {
+		var a models.FundingAccount
+		if err := rows.Scan(&a.Currency, &a.Balance, &a.Reserved, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan funding account: %w", err)
+		}
+		a.Available = a.Balance - a.Reserved
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// ReserveFunding holds the amount of the batch's unfinished payouts against
+// the funding account of each currency, so overlapping batches cannot
+// together overdraw it. Amounts already held for the batch are not reserved
+// twice, which makes it safe to call on every start, resume and retry.
+// Currencies without a funding account are not tracked.
+func (r *Repository) ReserveFunding(ctx context.Context, batchID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the accounts first, in a fixed order, so concurrent reservations
+	// serialize without deadlocking.
+	if _, err := tx.ExecContext(ctx,
+		`SELECT 1 FROM funding_accounts
+		 WHERE currency IN (SELECT DISTINCT currency FROM payouts WHERE batch_id = $1)
+		 ORDER BY currency FOR UPDATE`, batchID); err != nil {
+		return fmt.Errorf("lock funding accounts: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT a.currency, a.balance - a.reserved, COALESCE(fr.held, 0),
+		        COALESCE(SUM(p.amount) FILTER (WHERE p.status IN ($2, $3)), 0),
+		        COALESCE(SUM(p.amount) FILTER (WHERE p.status = $4), 0)
+		 FROM payouts p
+		 JOIN funding_accounts a ON a.currency = p.currency
+		 LEFT JOIN funding_reservations fr ON fr.batch_id = p.batch_id AND fr.currency = p.currency
+		 WHERE p.batch_id = $1
+		 GROUP BY a.currency, a.balance, a.reserved, fr.held
+		 ORDER BY a.currency`,
+		batchID, models.PayoutStatusPending, models.PayoutStatusProcessing, models.PayoutStatusCompleted)
+	if err != nil {
+		return fmt.Errorf("query funding needs: %w", err)
+	}
+	type need struct {
+		currency                          string
+		available, held, total, completed float64
+	}
+	var needs []need
+	for rows.Next() {
+		var n need
+		if err := rows.Scan(&n.currency, &n.available, &n.held, &n.total, &n.completed); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan funding need: %w", err)
+		}
+		needs = append(needs, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, n := range needs {
+		delta := n.total - n.held
+		if delta <= 0 {
+			continue
+		}
+		if delta > n.available {
+			return fmt.Errorf("%w: %s needs %.2f, %.2f available", ErrInsufficientFunding, n.currency, delta, n.available)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE funding_accounts SET reserved = reserved + $1, updated_at = NOW() WHERE currency = $2`,
+			delta, n.currency); err != nil {
+			return fmt.Errorf("reserve funding: %w", err)
+		}
+		// Payouts completed before the batch was first reserved were never
+		// held, so they are not debited either.
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO funding_reservations (batch_id, currency, held, debited, updated_at) VALUES ($1, $2, $3, $4, NOW())
+			 ON CONFLICT (batch_id, currency) DO UPDATE SET held = EXCLUDED.held, updated_at = NOW()`,
+			batchID, n.currency, n.total, n.completed); err != nil {
+			return fmt.Errorf("record reservation: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SettleFunding brings the batch's reservations in line with its payouts:
+// completed amounts are debited from the balance, and amounts held for
+// payouts that have since failed (or otherwise left pending/processing) are
+// released. It can be called at any point; already-settled amounts are not
+// applied twice.
+func (r *Repository) SettleFunding(ctx context.Context, batchID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := settleFunding(ctx, tx, batchID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// settleFunding is SettleFunding within tx.
+func settleFunding(ctx context.Context, tx *sql.Tx, batchID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx,
+		`SELECT 1 FROM funding_accounts
+		 WHERE currency IN (SELECT currency FROM funding_reservations WHERE batch_id = $1)
+		 ORDER BY currency FOR UPDATE`, batchID); err != nil {
+		return fmt.Errorf("lock funding accounts: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT fr.currency, fr.held, fr.debited,
+		        COALESCE(SUM(p.amount) FILTER (WHERE p.status IN ($2, $3)), 0),
+		        COALESCE(SUM(p.amount) FILTER (WHERE p.status = $4), 0)
+		 FROM funding_reservations fr
+		 LEFT JOIN payouts p ON p.batch_id = fr.batch_id AND p.currency = fr.currency
+		 WHERE fr.batch_id = $1
+		 GROUP BY fr.currency, fr.held, fr.debited`,
+		batchID, models.PayoutStatusPending, models.PayoutStatusProcessing, models.PayoutStatusCompleted)
+	if err != nil {
+		return fmt.Errorf("query reservations: %w", err)
+	}
+	type settlement struct {
+		currency                            string
+		held, debited, unfinished, complete float64
+	}
+	var settlements []settlement
+	for rows.Next() {
+		var s settlement
+		if err := rows.Scan(&s.currency, &s.held, &s.debited, &s.unfinished, &s.complete); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan reservation: %w", err)
+		}
+		settlements = append(settlements, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range settlements {
+		held := s.unfinished
+		if held > s.held {
+			held = s.held // never reserve more here; that is ReserveFunding's job
+		}
+		debit := s.complete - s.debited
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE funding_accounts SET balance = balance - $1, reserved = reserved - $2, updated_at = NOW()
+			 WHERE currency = $3`,
+			debit, s.held-held, s.currency); err != nil {
+			return fmt.Errorf("settle funding: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE funding_reservations SET held = $1, debited = $2, updated_at = NOW()
+			 WHERE batch_id = $3 AND currency = $4`,
+			held, s.complete, batchID, s.currency); err != nil {
+			return fmt.Errorf("settle reservation: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetBatchReservations returns what a batch holds against each funding account.
+func (r *Repository) GetBatchReservations(ctx context.Context, batchID uuid.UUID) ([]models.FundingReservation, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT currency, held, debited, updated_at FROM funding_reservations
+		 WHERE batch_id = $1 ORDER BY currency`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("query reservations: %w", err)
+	}
+	defer rows.Close()
+
+	reservations := []models.FundingReservation{}
+	for rows.Next() {
+		var res models.FundingReservation
+		if err := rows.Scan(&res.Currency, &res.Held, &res.Debited, &res.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan reservation: %w", err)
+		}
+		reservations = append(reservations, res)
+	}
+	return reservations, rows.Err()
+}
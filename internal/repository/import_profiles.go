@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/models"
+)
+
+// --- Import Profiles ---
+
+const importProfileColumns = `name, columns, metadata, defaults, rules, delimiter, decimal_separator, created_at, updated_at`
+
+// SaveImportProfile creates or replaces the import profile with p.Name.
+func (r *Repository) SaveImportProfile(ctx context.Context, p models.ImportProfile) (*models.ImportProfile, error) {
+	if p.Delimiter == "" {
+		p.Delimiter = ","
+	}
+	if p.DecimalSeparator == "" {
+		p.DecimalSeparator = "."
+	}
+	columns, err := marshalMetadata(p.Columns)
+	if err != nil {
+		return nil, fmt.Errorf("marshal columns: %w", err)
+	}
+	metadata, err := marshalMetadata(p.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshal metadata: %w", err)
+	}
+	defaults, err := marshalMetadata(p.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("marshal defaults: %w", err)
+	}
+	rules, err := json.Marshal(p.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("marshal rules: %w", err)
+	}
+
+	saved, err := scanImportProfile(r.db.QueryRowContext(ctx,
+		`INSERT INTO import_profiles (name, columns, metadata, defaults, rules, delimiter, decimal_separator)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (name) DO UPDATE SET columns = EXCLUDED.columns, metadata = EXCLUDED.metadata,
+		     defaults = EXCLUDED.defaults, rules = EXCLUDED.rules, delimiter = EXCLUDED.delimiter,
+		     decimal_separator = EXCLUDED.decimal_separator, updated_at = NOW()
+		 RETURNING `+importProfileColumns,
+		p.Name, columns, metadata, defaults, string(rules), p.Delimiter, p.DecimalSeparator,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("save import profile: %w", err)
+	}
+	return saved, nil
+}
+
+// GetImportProfile returns the named import profile, or nil if it does not exist.
+func (r *Repository) GetImportProfile(ctx context.Context, name string) (*models.ImportProfile, error) {
+	p, err := scanImportProfile(r.db.QueryRowContext(ctx,
+		`SELECT `+importProfileColumns+` FROM import_profiles WHERE name = $1`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get import profile: %w", err)
+	}
+	return p, nil
+}
+
+// ListImportProfiles returns every import profile, by name.
+func (r *Repository) ListImportProfiles(ctx context.Context) ([]models.ImportProfile, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+importProfileColumns+` FROM import_profiles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("query import profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []models.ImportProfile{}
+	for rows.Next() {
+		p, err := scanImportProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan import profile: %w", err)
+		}
+		profiles = append(profiles, *p)
+	}
+	return profiles, rows.Err()
+}
+
+// DeleteImportProfile removes the named profile and reports whether it existed.
+func (r *Repository) DeleteImportProfile(ctx context.Context, name string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM import_profiles WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("delete import profile: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete import profile: %w", err)
+	}
+	return n > 0, nil
+}
+
+func scanImportProfile(row rowScanner) (*models.ImportProfile, error) {
+	var p models.ImportProfile
+	var columns, metadata, defaults, rules []byte
+	if err := row.Scan(&p.Name, &columns, &metadata, &defaults, &rules, &p.Delimiter, &p.DecimalSeparator,
+		&p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	for _, m := range []struct {
+		raw []byte
+		dst *map[string]string
+	}{{columns, &p.Columns}, {metadata, &p.Metadata}, {defaults, &p.Defaults}} {
+		if err := json.Unmarshal(m.raw, m.dst); err != nil {
+			return nil, fmt.Errorf("decode import profile %s: %w", p.Name, err)
+		}
+	}
+	if err := json.Unmarshal(rules, &p.Rules); err != nil {
+		return nil, fmt.Errorf("decode import profile %s rules: %w", p.Name, err)
+	}
+	return &p, nil
+}
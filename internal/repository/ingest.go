@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Asynchronous Batch Creation ---
+
+// ErrCreationStalled is recorded as the creation_error of batches failed by
+// FailStalledCreations.
+var ErrCreationStalled = errors.New("ingestion stopped making progress")
+
+// ingestProgressEvery is how many payouts are copied between updates of an
+// asynchronously created batch's ingested_count.
+const ingestProgressEvery = 1000
+
+// BeginBatch records a batch in creating status for IngestBatch to write
+// its payouts, so that a very large request can be answered before they
+// are all written. The batch is counted at its full size from the start.
+func (r *Repository) BeginBatch(ctx context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error) {
+	batch := r.newBatch(items, opts, models.BatchStatusCreating)
+	if err := insertBatch(ctx, r.db, batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// IngestBatch writes the payouts of a batch begun with BeginBatch and makes
+// it pending. The payouts are written in one transaction, so they appear
+// all at once; ingested_count is updated outside it as they are copied. If
+// ingestion fails, the batch is left failed without payouts and the error
+// is recorded in creation_error.
+func (r *Repository) IngestBatch(ctx context.Context, batchID uuid.UUID, items []models.CreatePayoutItem) error {
+	err := r.ingestBatch(ctx, batchID, items)
+	if err == nil {
+		return nil
+	}
+	if _, ferr := r.db.ExecContext(context.Background(),
+		`UPDATE payout_batches SET status = $2, creation_error = $3, updated_at = $4 WHERE id = $1 AND status = $5`,
+		batchID, models.BatchStatusFailed, err.Error(), r.now(), models.BatchStatusCreating); ferr != nil {
+		return fmt.Errorf("%w (and failing the batch: %v)", err, ferr)
+	}
+	return err
+}
+
+func (r *Repository) ingestBatch(ctx context.Context, batchID uuid.UUID, items []models.CreatePayoutItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	batch, err := r.GetBatch(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	if batch == nil {
+		return fmt.Errorf("batch %s not found", batchID)
+	}
+
+	// The progress updates take no lock the copy's foreign key checks wait
+	// for, so they can run beside the transaction.
+	progress := func(copied int) error {
+		if _, err := r.db.ExecContext(ctx,
+			`UPDATE payout_batches SET ingested_count = $2, updated_at = $3 WHERE id = $1`,
+			batchID, copied, r.now()); err != nil {
+			return fmt.Errorf("record ingestion progress: %w", err)
+		}
+		return nil
+	}
+	if _, err := r.copyPayouts(ctx, tx, batch, items, progress); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE payout_batches SET status = $2, pending_count = total_count, ingested_count = total_count, updated_at = $3
+		 WHERE id = $1 AND status = $4`,
+		batchID, models.BatchStatusPending, r.now(), models.BatchStatusCreating)
+	if err != nil {
+		return fmt.Errorf("make batch pending: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("batch %s is no longer being created", batchID)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// FailStalledCreations fails batches still creating whose ingestion made no
+// progress for idleFor, e.g. because the instance ingesting them stopped.
+// Their payouts were never committed. It returns how many it failed.
+func (r *Repository) FailStalledCreations(ctx context.Context, idleFor time.Duration) (int64, error) {
+	now := r.now()
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE payout_batches SET status = $1, creation_error = $2, updated_at = $3
+		 WHERE status = $4 AND updated_at < $5`,
+		models.BatchStatusFailed, ErrCreationStalled.Error(), now, models.BatchStatusCreating, now.Add(-idleFor))
+	if err != nil {
+		return 0, fmt.Errorf("fail stalled creations: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// --- Streamed Batch Creation ---
+
+// StreamBatch creates a batch from payout items read one at a time from
+// next until it returns io.EOF, copying each in as it is read, so a batch
+// too large to hold in memory never is. Like CreateBatch it is atomic: an
+// error from next or from a write leaves nothing behind, and is returned as
+// it is.
+func (r *Repository) StreamBatch(ctx context.Context, opts models.BatchOptions, next func() (models.CreatePayoutItem, error)) (*models.PayoutBatch, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The counts are only known once the stream ends.
+	batch := r.newBatch(nil, opts, models.BatchStatusPending)
+	if err := insertBatch(ctx, tx, batch); err != nil {
+		return nil, err
+	}
+	cp, err := r.newPayoutCopy(ctx, tx, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer cp.stmt.Close()
+	for {
+		item, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := cp.add(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+	if err := cp.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	batch.TotalCount, batch.PendingCount = len(cp.ids), len(cp.ids)
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payout_batches SET total_count = $2, pending_count = $2 WHERE id = $1`,
+		batch.ID, batch.TotalCount); err != nil {
+		return nil, fmt.Errorf("count streamed payouts: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return batch, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"coding-challenge/internal/models"
+)
+
+// --- Scheduled Job Runs ---
+
+// CreateJobRun records the start of a run of a scheduled job.
+func (r *Repository) CreateJobRun(ctx context.Context, run *models.JobRun) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO job_runs (id, job, trigger, triggered_by, status, started_at)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`,
+		run.ID, run.Job, run.Trigger, run.TriggeredBy, run.Status, run.StartedAt)
+	if err != nil {
+		return fmt.Errorf("insert job run: %w", err)
+	}
+	return nil
+}
+
+// FinishJobRun records the outcome of a run of a scheduled job.
+func (r *Repository) FinishJobRun(ctx context.Context, run *models.JobRun) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE job_runs SET status = $1, error = $2, finished_at = $3 WHERE id = $4`,
+		run.Status, run.Error, run.FinishedAt, run.ID)
+	if err != nil {
+		return fmt.Errorf("update job run: %w", err)
+	}
+	return nil
+}
+
+// ListJobRuns returns the most recent runs of a scheduled job, newest
+// first, at most limit of them.
+func (r *Repository) ListJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, job, trigger, COALESCE(triggered_by, ''), status, error, started_at, finished_at
+		 FROM job_runs WHERE job = $1 ORDER BY started_at DESC LIMIT $2`, job, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	now := r.now()
+	runs := []models.JobRun{}
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(&run.ID, &run.Job, &run.Trigger, &run.TriggeredBy, &run.Status,
+			&run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("scan job run: %w", err)
+		}
+		end := now
+		if run.FinishedAt != nil {
+			end = *run.FinishedAt
+		}
+		run.DurationSeconds = end.Sub(run.StartedAt).Seconds()
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query job runs: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// --- Batch Leases ---
+
+// ErrBatchLeased is returned when another instance holds a live lease on
+// the batch.
+var ErrBatchLeased = errors.New("batch is being processed by another instance")
+
+// AcquireBatchLease gives holder the batch's lease until ttl from now. A
+// lease holder already has is renewed; one held by another instance is
+// only taken once it expired. It returns ErrBatchLeased otherwise.
+func (r *Repository) AcquireBatchLease(ctx context.Context, batchID uuid.UUID, holder string, ttl time.Duration) error {
+	now := r.now()
+	var got string
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO batch_leases (batch_id, holder, acquired_at, heartbeat_at, expires_at)
+		 VALUES ($1, $2, $3, $3, $4)
+		 ON CONFLICT (batch_id) DO UPDATE SET
+		     holder = EXCLUDED.holder,
+		     acquired_at = CASE WHEN batch_leases.holder = EXCLUDED.holder THEN batch_leases.acquired_at ELSE EXCLUDED.acquired_at END,
+		     heartbeat_at = EXCLUDED.heartbeat_at,
+		     expires_at = EXCLUDED.expires_at
+		 WHERE batch_leases.holder = EXCLUDED.holder OR batch_leases.expires_at <= EXCLUDED.heartbeat_at
+		 RETURNING holder`,
+		batchID, holder, now, now.Add(ttl),
+	).Scan(&got)
+	if errors.Is(err, sql.ErrNoRows) {
+		var other string
+		var until time.Time
+		if err := r.db.QueryRowContext(ctx,
+			`SELECT holder, expires_at FROM batch_leases WHERE batch_id = $1`, batchID,
+		).Scan(&other, &until); err != nil {
+			return ErrBatchLeased // released or taken over meanwhile
+		}
+		return fmt.Errorf("%w (%s, until %s)", ErrBatchLeased, other, until.UTC().Format(time.RFC3339))
+	}
+	if err != nil {
+		return fmt.Errorf("acquire batch lease: %w", err)
+	}
+	return nil
+}
+
+// RenewBatchLease extends holder's lease on the batch until ttl from now.
+// It reports false if holder no longer has the lease, e.g. because it
+// expired and another instance took the batch over.
+func (r *Repository) RenewBatchLease(ctx context.Context, batchID uuid.UUID, holder string, ttl time.Duration) (bool, error) {
+	now := r.now()
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE batch_leases SET heartbeat_at = $3, expires_at = $4 WHERE batch_id = $1 AND holder = $2`,
+		batchID, holder, now, now.Add(ttl))
+	if err != nil {
+		return false, fmt.Errorf("renew batch lease: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseBatchLease gives up holder's lease on the batch, if it has it.
+func (r *Repository) ReleaseBatchLease(ctx context.Context, batchID uuid.UUID, holder string) error {
+	if _, err := r.db.ExecContext(ctx,
+		`DELETE FROM batch_leases WHERE batch_id = $1 AND holder = $2`, batchID, holder); err != nil {
+		return fmt.Errorf("release batch lease: %w", err)
+	}
+	return nil
+}
+
+// FindExpiredLeases returns the in_progress batches whose lease expired
+// without being released, i.e. whose holder died or lost the database,
+// oldest expiry first.
+func (r *Repository) FindExpiredLeases(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT l.batch_id FROM batch_leases l JOIN payout_batches b ON b.id = l.batch_id
+		 WHERE l.expires_at <= $1 AND b.status = $2 AND b.deleted_at IS NULL
+		 ORDER BY l.expires_at`,
+		r.now(), models.BatchStatusInProgress)
+	if err != nil {
+		return nil, fmt.Errorf("find expired leases: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan expired lease: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// --- Payout Claim Leases ---
+
+// claimLeaseExpired matches payouts whose claim lease lapsed by now, or that
+// were claimed without one; now is the query parameter holding the time.
+func claimLeaseExpired(now string) string {
+	return `(lease_expires_at IS NULL OR lease_expires_at <= ` + now + `)`
+}
+
+// LeaseClaims gives holder the claim lease on the payouts, until ttl from
+// now, both just after claiming them and to renew it while their transfers
+// are in flight. Payouts no longer in processing, or leased live by another
+// holder, are left alone. It returns how many payouts holder now leases.
+func (r *Repository) LeaseClaims(ctx context.Context, payoutIDs []uuid.UUID, holder string, ttl time.Duration) (int64, error) {
+	ids := make([]string, len(payoutIDs))
+	for i, id := range payoutIDs {
+		ids[i] = id.String()
+	}
+	now := r.now()
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE payouts SET claimed_by = $2, lease_expires_at = $3
+		 WHERE id = ANY($1::uuid[]) AND status = $4
+		   AND (claimed_by IS NULL OR claimed_by = $2 OR lease_expires_at <= $5)`,
+		pq.Array(ids), holder, now.Add(ttl), models.PayoutStatusProcessing, now)
+	if err != nil {
+		return 0, fmt.Errorf("lease claims: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ResetExpiredClaims puts a batch's payouts in processing whose claim lease
+// lapsed back to pending (cancelled in a cancelled batch), even while other
+// runs are live: their holder stopped renewing, e.g. because its instance
+// died mid-transfer. Payouts claimed without a lease are left to
+// ResetStuckProcessing.
+func (r *Repository) ResetExpiredClaims(ctx context.Context, batchID uuid.UUID) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE payouts SET status = `+releasedStatus+`, updated_at = $3, claimed_by = NULL, lease_expires_at = NULL
+		 WHERE batch_id = $1 AND status = $2 AND attempt_count < max_retries AND lease_expires_at <= $3`,
+		batchID, models.PayoutStatusProcessing, r.now())
+	if err != nil {
+		return 0, fmt.Errorf("reset expired claims: %w", err)
+	}
+	return result.RowsAffected()
+}
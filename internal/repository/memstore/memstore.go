@@ -0,0 +1,1108 @@
+// Package memstore keeps batches, payouts, attempts and runs in memory, so
+// the worker pool and the batch API can be tested without PostgreSQL. It
+// implements worker.Store, api.BatchStore and scheduler.Store with the
+// repository's semantics, for a single process: claims never hand a payout
+// out twice, crash recovery only runs while no run holds the batch, and
+// finalization happens once. Funding is not tracked, as for currencies
+// without a funding account, and nothing is written to an audit store.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/money"
+	"coding-challenge/internal/purpose"
+	"coding-challenge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Store is an in-memory batch store. The zero value is not usable; call New.
+type Store struct {
+	clock clock.Clock
+
+	mu       sync.Mutex
+	batches  map[uuid.UUID]*models.PayoutBatch
+	payouts  map[uuid.UUID]*models.Payout
+	order    map[uuid.UUID][]uuid.UUID // payout IDs of each batch, in submission order
+	seq      map[uuid.UUID]int         // position of each payout in its batch
+	attempts map[uuid.UUID][]models.PayoutAttempt
+	runs     map[uuid.UUID][]*models.BatchRun
+	live     map[uuid.UUID]int // runs holding each batch's run lock
+	jobRuns  []models.JobRun
+
+	runMu sync.Mutex // serializes LockRun, like the exclusive run lock
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithClock sets the clock used for the timestamps the store writes and for
+// stall cutoffs (the system clock by default).
+func WithClock(c clock.Clock) Option {
+	return func(s *Store) { s.clock = c }
+}
+
+// New creates an empty store.
+func New(opts ...Option) *Store {
+	s := &Store{
+		clock:    clock.Real,
+		batches:  map[uuid.UUID]*models.PayoutBatch{},
+		payouts:  map[uuid.UUID]*models.Payout{},
+		order:    map[uuid.UUID][]uuid.UUID{},
+		seq:      map[uuid.UUID]int{},
+		attempts: map[uuid.UUID][]models.PayoutAttempt{},
+		runs:     map[uuid.UUID][]*models.BatchRun{},
+		live:     map[uuid.UUID]int{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) now() time.Time {
+	return s.clock.Now().UTC()
+}
+
+// --- Batches ---
+
+// CreateBatch stores a batch and its payouts; see Repository.CreateBatch.
+func (s *Store) CreateBatch(_ context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error) {
+	batch := s.newBatch(opts, models.BatchStatusPending)
+	payouts, err := s.newPayouts(batch.ID, batch.RetryPolicy.Attempts(), items)
+	if err != nil {
+		return nil, err
+	}
+	batch.TotalCount, batch.PendingCount = len(payouts), len(payouts)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keyTaken(batch) {
+		return nil, repository.ErrDuplicateRequest
+	}
+	s.batches[batch.ID] = batch
+	s.addPayouts(batch.ID, payouts)
+	copied := *batch
+	return &copied, nil
+}
+
+// BeginBatch stores a batch in creating status for IngestBatch to add its
+// payouts to; see Repository.BeginBatch.
+func (s *Store) BeginBatch(_ context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error) {
+	batch := s.newBatch(opts, models.BatchStatusCreating)
+	for _, item := range items {
+		batch.TotalCount += max(len(item.Splits), 1)
+	}
+	batch.IngestedCount = new(int)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keyTaken(batch) {
+		return nil, repository.ErrDuplicateRequest
+	}
+	s.batches[batch.ID] = batch
+	copied := *batch
+	return &copied, nil
+}
+
+// IngestBatch adds the payouts of a batch begun with BeginBatch, all at
+// once, and makes it pending; if that fails, the batch is left failed with
+// the error as its creation_error. See Repository.IngestBatch.
+func (s *Store) IngestBatch(_ context.Context, batchID uuid.UUID, items []models.CreatePayoutItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchID]
+	if !ok || b.Status != models.BatchStatusCreating {
+		return fmt.Errorf("batch %s is no longer being created", batchID)
+	}
+	payouts, err := s.newPayouts(batchID, b.RetryPolicy.Attempts(), items)
+	b.UpdatedAt = s.now()
+	if err != nil {
+		msg := err.Error()
+		b.Status, b.CreationError = models.BatchStatusFailed, &msg
+		return err
+	}
+	s.addPayouts(batchID, payouts)
+	ingested := len(payouts)
+	b.Status, b.PendingCount, b.IngestedCount = models.BatchStatusPending, ingested, &ingested
+	return nil
+}
+
+// FailStalledCreations fails batches still creating with no update for
+// idleFor; see Repository.FailStalledCreations.
+func (s *Store) FailStalledCreations(_ context.Context, idleFor time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var failed int64
+	for _, b := range s.batches {
+		if b.Status == models.BatchStatusCreating && b.UpdatedAt.Before(now.Add(-idleFor)) {
+			msg := repository.ErrCreationStalled.Error()
+			b.Status, b.CreationError, b.UpdatedAt = models.BatchStatusFailed, &msg, now
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// StreamBatch creates a batch from the items read from next until io.EOF;
+// see Repository.StreamBatch. The store holds them all anyway, so they are
+// gathered first.
+func (s *Store) StreamBatch(ctx context.Context, opts models.BatchOptions, next func() (models.CreatePayoutItem, error)) (*models.PayoutBatch, error) {
+	var items []models.CreatePayoutItem
+	for {
+		item, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return s.CreateBatch(ctx, items, opts)
+}
+
+// GetBatchByIdempotencyKey returns the batch the merchant created with an
+// idempotency key, or nil; see Repository.GetBatchByIdempotencyKey.
+func (s *Store) GetBatchByIdempotencyKey(_ context.Context, merchant, key string) (*models.PayoutBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.batches {
+		if b.IdempotencyKey != nil && *b.IdempotencyKey == key && merchantOf(b) == merchant {
+			copied := *b
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListCompletedPayouts returns the payouts to the given vendors completed
+// since a time in the merchant's batches.
+func (s *Store) ListCompletedPayouts(_ context.Context, merchant string, vendorIDs []string, since time.Time) ([]models.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var payouts []models.Payout
+	for _, p := range s.payouts {
+		if p.Status == models.PayoutStatusCompleted && p.CompletedAt != nil && !p.CompletedAt.Before(since) &&
+			slices.Contains(vendorIDs, p.VendorID) && merchantOf(s.batches[p.BatchID]) == merchant {
+			payouts = append(payouts, *p)
+		}
+	}
+	return payouts, nil
+}
+
+// keyTaken reports whether another batch of the merchant has batch's
+// idempotency key. The caller holds s.mu.
+func (s *Store) keyTaken(batch *models.PayoutBatch) bool {
+	if batch.IdempotencyKey == nil {
+		return false
+	}
+	for _, b := range s.batches {
+		if b.IdempotencyKey != nil && *b.IdempotencyKey == *batch.IdempotencyKey && merchantOf(b) == merchantOf(batch) {
+			return true
+		}
+	}
+	return false
+}
+
+// merchantOf returns the merchant a batch was created for, or "".
+func merchantOf(b *models.PayoutBatch) string {
+	if b.MerchantID == nil {
+		return ""
+	}
+	return *b.MerchantID
+}
+
+// newBatch returns an empty batch in status.
+func (s *Store) newBatch(opts models.BatchOptions, status string) *models.PayoutBatch {
+	now := s.now()
+	if opts.PayoutOrder == "" {
+		opts.PayoutOrder = models.PayoutOrderFIFO
+	}
+	batch := &models.PayoutBatch{
+		ID:            uuid.New(),
+		Status:        status,
+		PayoutOrder:   opts.PayoutOrder,
+		ProgressEvery: opts.ProgressEvery,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if opts.Owner != "" {
+		batch.Owner = &opts.Owner
+	}
+	if opts.Merchant != "" {
+		batch.MerchantID = &opts.Merchant
+	}
+	if opts.AssignedTo != "" {
+		batch.AssignedTo = &opts.AssignedTo
+	}
+	if opts.IdempotencyKey != "" {
+		batch.IdempotencyKey, batch.RequestHash = &opts.IdempotencyKey, &opts.RequestHash
+	}
+	batch.RetryPolicy, batch.Rejections = opts.RetryPolicy, opts.Rejections
+	return batch
+}
+
+// newPayouts builds the batch's payouts for items, in submission order,
+// each with an attempt budget of attempts.
+func (s *Store) newPayouts(batchID uuid.UUID, attempts int, items []models.CreatePayoutItem) ([]*models.Payout, error) {
+	now := s.now()
+	var payouts []*models.Payout
+	vendors := make(map[string]bool, len(items))
+	for _, item := range items {
+		if vendors[item.VendorID] {
+			return nil, fmt.Errorf("vendor %s %w", item.VendorID, repository.ErrDuplicateVendor)
+		}
+		vendors[item.VendorID] = true
+
+		// The store has no configured defaults; only the item's own code
+		// is kept.
+		var purposeCode *string
+		if code := (purpose.Defaults{}).For(item); code != "" {
+			purposeCode = &code
+		}
+
+		payout := func(key string, amount float64, account, bank string) *models.Payout {
+			return &models.Payout{
+				ID:             uuid.New(),
+				BatchID:        batchID,
+				IdempotencyKey: key,
+				VendorID:       item.VendorID,
+				VendorName:     item.VendorName,
+				Amount:         amount,
+				Currency:       item.Currency,
+				BankAccount:    account,
+				BankName:       bank,
+				TransactionIDs: item.TransactionIDs,
+				Metadata:       item.Metadata,
+				PurposeCode:    purposeCode,
+				Status:         models.PayoutStatusPending,
+				MaxRetries:     attempts,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+		}
+		if len(item.Splits) == 0 {
+			payouts = append(payouts, payout(fmt.Sprintf("%s:%s", item.VendorID, batchID), item.Amount, item.BankAccount, item.BankName))
+			continue
+		}
+		percents := make([]float64, len(item.Splits))
+		for i, split := range item.Splits {
+			percents[i] = split.Percent
+		}
+		amounts := money.Split(item.Amount, item.Currency, percents)
+		group := uuid.New()
+		for i, split := range item.Splits {
+			bank := split.BankName
+			if bank == "" {
+				bank = item.BankName
+			}
+			p := payout(fmt.Sprintf("%s:%s:split-%d", item.VendorID, batchID, i+1), amounts[i], split.BankAccount, bank)
+			p.SplitGroupID, p.SplitPercent = &group, &item.Splits[i].Percent
+			payouts = append(payouts, p)
+		}
+	}
+	return payouts, nil
+}
+
+// addPayouts stores a batch's payouts in order. s.mu must be held.
+func (s *Store) addPayouts(batchID uuid.UUID, payouts []*models.Payout) {
+	ids := make([]uuid.UUID, len(payouts))
+	for i, p := range payouts {
+		s.payouts[p.ID] = p
+		s.seq[p.ID] = i
+		ids[i] = p.ID
+	}
+	s.order[batchID] = ids
+}
+
+// GetBatch returns a batch, or nil if there is none.
+func (s *Store) GetBatch(_ context.Context, batchID uuid.UUID) (*models.PayoutBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *b
+	return &copied, nil
+}
+
+// ListBatches returns a page of the batches matching f, newest first.
+func (s *Store) ListBatches(_ context.Context, f models.BatchListFilter) ([]models.PayoutBatch, int, error) {
+	batches := s.listBatches(f)
+	from := min(max(f.Page-1, 0)*f.PageSize, len(batches))
+	to := min(from+f.PageSize, len(batches))
+	return batches[from:to], len(batches), nil
+}
+
+// ListBatchesAfter returns up to limit batches matching f after the cursor,
+// newest first.
+func (s *Store) ListBatchesAfter(_ context.Context, f models.BatchListFilter, after *models.PageCursor, limit int) ([]models.PayoutBatch, error) {
+	batches := s.listBatches(f)
+	if after != nil {
+		i := sort.Search(len(batches), func(i int) bool {
+			b := batches[i]
+			return b.CreatedAt.Before(after.CreatedAt) || (b.CreatedAt.Equal(after.CreatedAt) && b.ID.String() > after.ID.String())
+		})
+		batches = batches[i:]
+	}
+	return batches[:min(limit, len(batches))], nil
+}
+
+// listBatches returns the batches matching f in list order.
+func (s *Store) listBatches(f models.BatchListFilter) []models.PayoutBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches := []models.PayoutBatch{}
+	for _, b := range s.batches {
+		switch {
+		case f.Status != "" && b.Status != f.Status,
+			!f.IncludeDeleted && b.DeletedAt != nil,
+			f.AssignedTo != "" && (b.AssignedTo == nil || *b.AssignedTo != f.AssignedTo),
+			f.CreatedFrom != nil && b.CreatedAt.Before(*f.CreatedFrom),
+			f.CreatedTo != nil && !b.CreatedAt.Before(*f.CreatedTo):
+			continue
+		}
+		batches = append(batches, *b)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		if !batches[i].CreatedAt.Equal(batches[j].CreatedAt) {
+			return batches[i].CreatedAt.After(batches[j].CreatedAt)
+		}
+		return batches[i].ID.String() < batches[j].ID.String()
+	})
+	return batches
+}
+
+// GetBatchStatistics counts a batch's payouts by status.
+func (s *Store) GetBatchStatistics(_ context.Context, batchID uuid.UUID) (*models.BatchStatistics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := &models.BatchStatistics{}
+	type logical struct{ n, completed, failed int }
+	groups := map[uuid.UUID]*logical{}
+	var keys []uuid.UUID
+	for _, p := range s.batchPayouts(batchID) {
+		stats.Total++
+		switch p.Status {
+		case models.PayoutStatusCompleted:
+			stats.Completed++
+		case models.PayoutStatusFailed, models.PayoutStatusWrittenOff, models.PayoutStatusCancelled:
+			stats.Failed++
+		case models.PayoutStatusPending:
+			stats.Pending++
+			if p.HeldAt != nil {
+				stats.Held++
+			}
+		case models.PayoutStatusProcessing:
+			stats.Processing++
+		}
+		switch {
+		case p.Status == models.PayoutStatusFailed && p.SupersededBy != nil:
+			stats.Superseded++
+		case p.Status == models.PayoutStatusWrittenOff:
+			stats.WrittenOff++
+		case p.Status == models.PayoutStatusCancelled:
+			stats.Cancelled++
+		}
+
+		key := p.ID
+		if p.SplitGroupID != nil {
+			key = *p.SplitGroupID
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &logical{}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.n++
+		switch p.Status {
+		case models.PayoutStatusCompleted:
+			g.completed++
+		case models.PayoutStatusFailed, models.PayoutStatusWrittenOff, models.PayoutStatusCancelled:
+			g.failed++
+		}
+	}
+
+	l := &stats.Logical
+	for _, key := range keys {
+		g := groups[key]
+		l.Total++
+		if g.n > 1 {
+			l.Split++
+		}
+		switch {
+		case g.completed == g.n:
+			l.Completed++
+		case g.failed == g.n:
+			l.Failed++
+		case g.completed+g.failed == g.n:
+			l.PartiallyCompleted++
+		default:
+			l.Unfinished++
+		}
+	}
+	stats.ComputeRates()
+	return stats, nil
+}
+
+// UpdateBatchStatus sets a batch's status and the matching timestamp. A
+// cancelled batch stays cancelled.
+func (s *Store) UpdateBatchStatus(_ context.Context, batchID uuid.UUID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchID]
+	if !ok || b.Status == models.BatchStatusCancelled {
+		return nil
+	}
+	now := s.now()
+	b.Status, b.UpdatedAt = status, now
+	switch status {
+	case models.BatchStatusInProgress:
+		b.StartedAt = &now
+	case models.BatchStatusCompleted, models.BatchStatusPartiallyCompleted, models.BatchStatusFailed:
+		b.CompletedAt = &now
+	}
+	return nil
+}
+
+// PauseBatch moves an in-progress batch to paused, returning false if it was
+// not in progress.
+func (s *Store) PauseBatch(_ context.Context, batchID uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchID]
+	if !ok || b.Status != models.BatchStatusInProgress {
+		return false, nil
+	}
+	b.Status, b.UpdatedAt = models.BatchStatusPaused, s.now()
+	return true, nil
+}
+
+// CancelBatch cancels the pending payouts of a batch that has not finished
+// and gives it the cancelled status; see Repository.CancelBatch. It returns
+// the batch, or nil if it does not exist.
+func (s *Store) CancelBatch(_ context.Context, batchID uuid.UUID, _ string) (*models.PayoutBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchID]
+	if !ok {
+		return nil, nil
+	}
+	if b.DeletedAt != nil || b.Status == models.BatchStatusCreating || b.IsTerminal() {
+		return nil, repository.ErrBatchNotCancellable
+	}
+	now := s.now()
+	for _, p := range s.batchPayouts(batchID) {
+		if p.Status == models.PayoutStatusPending {
+			p.Status, p.HeldAt, p.HeldBy, p.UpdatedAt = models.PayoutStatusCancelled, nil, nil, now
+		}
+	}
+	b.Status, b.CompletedAt = models.BatchStatusCancelled, &now
+	s.refreshCounts(b)
+	batch := *b
+	return &batch, nil
+}
+
+// CancelPayout cancels one pending payout and recounts its batch, finishing
+// a batch no run holds that has nothing left to process; see
+// Repository.CancelPayout. It returns the payout, or nil if it does not
+// exist.
+func (s *Store) CancelPayout(_ context.Context, payoutID uuid.UUID, _, _ string) (*models.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.payouts[payoutID]
+	if !ok {
+		return nil, nil
+	}
+	b := s.batches[p.BatchID]
+	if p.Status != models.PayoutStatusPending || b.DeletedAt != nil || b.Status == models.BatchStatusCancelled {
+		return nil, repository.ErrPayoutNotCancellable
+	}
+	now := s.now()
+	p.Status, p.HeldAt, p.HeldBy, p.UpdatedAt = models.PayoutStatusCancelled, nil, nil, now
+	s.refreshCounts(b)
+	if s.live[b.ID] == 0 && b.PendingCount == 0 && (b.Status == models.BatchStatusPending || b.Status == models.BatchStatusPaused) {
+		b.Status, b.CompletedAt = finishedStatus(b.CompletedCount, b.FailedCount), &now
+	}
+	payout := *p
+	return &payout, nil
+}
+
+// released is the status a payout handed back from processing takes:
+// pending, or cancelled if its batch was cancelled.
+func (s *Store) released(p *models.Payout) string {
+	if s.batches[p.BatchID].Status == models.BatchStatusCancelled {
+		return models.PayoutStatusCancelled
+	}
+	return models.PayoutStatusPending
+}
+
+// RefreshBatchCounts recalculates a batch's counts from its payouts.
+func (s *Store) RefreshBatchCounts(_ context.Context, batchID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.batches[batchID]; ok {
+		s.refreshCounts(b)
+	}
+	return nil
+}
+
+func (s *Store) refreshCounts(b *models.PayoutBatch) {
+	b.CompletedCount, b.FailedCount, b.PendingCount = 0, 0, 0
+	for _, p := range s.batchPayouts(b.ID) {
+		switch p.Status {
+		case models.PayoutStatusCompleted:
+			b.CompletedCount++
+		case models.PayoutStatusFailed, models.PayoutStatusWrittenOff, models.PayoutStatusCancelled:
+			b.FailedCount++
+		case models.PayoutStatusPending, models.PayoutStatusProcessing:
+			b.PendingCount++
+		}
+	}
+	b.UpdatedAt = s.now()
+}
+
+// FinalizeBatch gives an in-progress batch whose payouts are all finished
+// its outcome, once; see Repository.FinalizeBatch.
+func (s *Store) FinalizeBatch(_ context.Context, batchID uuid.UUID) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchID]
+	if !ok {
+		return "", false, fmt.Errorf("lock batch: batch %s not found", batchID)
+	}
+	if b.Status == models.BatchStatusCancelled {
+		s.refreshCounts(b)
+		return b.Status, false, nil
+	}
+	if b.Status != models.BatchStatusInProgress {
+		return b.Status, false, nil
+	}
+
+	var completed, failed, held int
+	for _, p := range s.batchPayouts(batchID) {
+		switch {
+		case p.Status == models.PayoutStatusCompleted:
+			completed++
+		case p.Status == models.PayoutStatusFailed, p.Status == models.PayoutStatusWrittenOff, p.Status == models.PayoutStatusCancelled:
+			failed++
+		case p.Status == models.PayoutStatusPending && p.HeldAt != nil:
+			held++
+		case p.Status == models.PayoutStatusPending, p.Status == models.PayoutStatusProcessing:
+			return b.Status, false, nil
+		}
+	}
+
+	now := s.now()
+	b.Status = models.BatchStatusPaused
+	if held == 0 {
+		b.Status = finishedStatus(completed, failed)
+		b.CompletedAt = &now
+	}
+	b.UpdatedAt = now
+	return b.Status, true, nil
+}
+
+// finishedStatus is the terminal status of a batch whose payouts are all
+// finished.
+func finishedStatus(completed, failed int) string {
+	switch {
+	case failed == 0:
+		return models.BatchStatusCompleted
+	case completed == 0:
+		return models.BatchStatusFailed
+	default:
+		return models.BatchStatusPartiallyCompleted
+	}
+}
+
+// PinEnvironment records the environment of a batch's first run and returns
+// repository.ErrEnvironmentMismatch for a run in another one.
+func (s *Store) PinEnvironment(_ context.Context, batchID uuid.UUID, environment string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[batchID]
+	if !ok {
+		return fmt.Errorf("pin environment: batch %s not found", batchID)
+	}
+	if b.Environment == nil {
+		b.Environment = &environment
+	}
+	if *b.Environment != environment {
+		return fmt.Errorf("%w: %s, not %s", repository.ErrEnvironmentMismatch, *b.Environment, environment)
+	}
+	return nil
+}
+
+// ReserveFunding does nothing: the store has no funding accounts.
+func (s *Store) ReserveFunding(context.Context, uuid.UUID) error {
+	return nil
+}
+
+// FindStalledBatches returns in-progress batches with no batch or payout
+// updates for at least idleFor.
+func (s *Store) FindStalledBatches(_ context.Context, idleFor time.Duration) ([]models.PayoutBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := s.now().Add(-idleFor)
+	var batches []models.PayoutBatch
+	for _, b := range s.batches {
+		if s.stalled(b, cutoff) {
+			batches = append(batches, *b)
+		}
+	}
+	return batches, nil
+}
+
+// PauseStalledBatch pauses a batch still in progress and idle for idleFor,
+// unless a run holds it, and resets its payouts stuck in processing since
+// before the cutoff.
+func (s *Store) PauseStalledBatch(_ context.Context, batchID uuid.UUID, idleFor time.Duration) (bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	cutoff := now.Add(-idleFor)
+	b, ok := s.batches[batchID]
+	if !ok || s.live[batchID] > 0 || !s.stalled(b, cutoff) {
+		return false, 0, nil
+	}
+	b.Status, b.UpdatedAt = models.BatchStatusPaused, now
+
+	var reset int64
+	for _, p := range s.batchPayouts(batchID) {
+		if p.Status == models.PayoutStatusProcessing && p.AttemptCount < p.MaxRetries && p.UpdatedAt.Before(cutoff) {
+			p.Status, p.UpdatedAt = models.PayoutStatusPending, now
+			reset++
+		}
+	}
+	return true, reset, nil
+}
+
+// stalled reports whether b is in progress with no updates since cutoff.
+// s.mu must be held.
+func (s *Store) stalled(b *models.PayoutBatch, cutoff time.Time) bool {
+	if b.Status != models.BatchStatusInProgress {
+		return false
+	}
+	last := b.UpdatedAt
+	for _, p := range s.batchPayouts(b.ID) {
+		if p.UpdatedAt.After(last) {
+			last = p.UpdatedAt
+		}
+	}
+	return last.Before(cutoff)
+}
+
+// --- Runs ---
+
+// CreateRun records the start of a processing run.
+func (s *Store) CreateRun(_ context.Context, batchID uuid.UUID, trigger, triggeredBy, environment string, settings models.RunSettings) (*models.BatchRun, error) {
+	run := &models.BatchRun{
+		ID:          uuid.New(),
+		BatchID:     batchID,
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Environment: environment,
+		Status:      models.RunStatusRunning,
+		StartedAt:   s.now(),
+		RunSettings: settings,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *run
+	s.runs[batchID] = append(s.runs[batchID], &stored)
+	return run, nil
+}
+
+// FinishRun records the outcome and counts of a run.
+func (s *Store) FinishRun(_ context.Context, run *models.BatchRun) error {
+	now := s.now()
+	run.FinishedAt = &now
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stored := range s.runs[run.BatchID] {
+		if stored.ID == run.ID {
+			*stored = *run
+		}
+	}
+	return nil
+}
+
+// ListRuns returns the runs of a batch, oldest first.
+func (s *Store) ListRuns(_ context.Context, batchID uuid.UUID) ([]models.BatchRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	runs := []models.BatchRun{}
+	for _, stored := range s.runs[batchID] {
+		run := *stored
+		end := now
+		if run.FinishedAt != nil {
+			end = *run.FinishedAt
+		}
+		run.DurationSeconds = end.Sub(run.StartedAt).Seconds()
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// LockRun marks a run of the batch as live until the lock is released,
+// calling recoverStuck first if no other run is live.
+func (s *Store) LockRun(_ context.Context, batchID uuid.UUID, recoverStuck func() error) (*repository.RunLock, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.mu.Lock()
+	idle := s.live[batchID] == 0
+	s.mu.Unlock()
+	if idle {
+		if err := recoverStuck(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.live[batchID]++
+	s.mu.Unlock()
+	return repository.NewRunLock(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.live[batchID]--
+	}), nil
+}
+
+// ResetStuckProcessing puts a batch's payouts in processing back to pending
+// (cancelled in a cancelled batch), for crash recovery.
+func (s *Store) ResetStuckProcessing(_ context.Context, batchID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var reset int64
+	for _, p := range s.batchPayouts(batchID) {
+		if p.Status == models.PayoutStatusProcessing && p.AttemptCount < p.MaxRetries {
+			p.Status, p.UpdatedAt = s.released(p), now
+			reset++
+		}
+	}
+	return reset, nil
+}
+
+// --- Payouts ---
+
+// CountInFlight counts the payouts in processing across all batches.
+func (s *Store) CountInFlight(context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, p := range s.payouts {
+		if p.Status == models.PayoutStatusProcessing {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ClaimChunk claims up to limit pending payouts in the batch's order; see
+// Repository.ClaimChunk.
+func (s *Store) ClaimChunk(ctx context.Context, batchID uuid.UUID, order string, limit int, inFlightCaps map[string]float64) ([]models.Payout, error) {
+	return s.ClaimChunkSpread(ctx, batchID, order, limit, inFlightCaps, repository.ClaimSpread{})
+}
+
+// ClaimChunkSpread is ClaimChunk limited to the payouts selected by spread;
+// see Repository.ClaimChunkSpread.
+func (s *Store) ClaimChunkSpread(_ context.Context, batchID uuid.UUID, order string, limit int, inFlightCaps map[string]float64, spread repository.ClaimSpread) ([]models.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var candidates []*models.Payout
+	for _, p := range s.batchPayouts(batchID) {
+		if p.Status != models.PayoutStatusPending || p.HeldAt != nil || p.NextRetryAt != nil && p.NextRetryAt.After(now) {
+			continue
+		}
+		if order == models.PayoutOrderFIFO || order == "" {
+			seq := s.seq[p.ID]
+			if seq < spread.FromSeq || (spread.Buckets > 1 && seq%spread.Buckets != spread.Bucket) {
+				continue
+			}
+		}
+		candidates = append(candidates, p)
+	}
+	s.sortClaimOrder(candidates, order)
+	candidates = candidates[:min(limit, len(candidates))]
+
+	inFlight := map[string]float64{}
+	for _, p := range s.payouts {
+		if p.Status == models.PayoutStatusProcessing {
+			inFlight[p.Currency] += p.Amount
+		}
+	}
+	// As in the repository, the running total covers every candidate so each
+	// currency's claims stay a prefix of its processing order.
+	running := map[string]float64{}
+	claimed := []models.Payout{}
+	for _, p := range candidates {
+		running[p.Currency] += p.Amount
+		if ceiling, capped := inFlightCaps[p.Currency]; capped {
+			f, busy := inFlight[p.Currency]
+			if f+running[p.Currency] > ceiling && (busy || running[p.Currency] != p.Amount) {
+				continue
+			}
+		}
+		p.Status, p.AttemptedAt, p.UpdatedAt, p.NextRetryAt = models.PayoutStatusProcessing, &now, now, nil
+		p.AttemptCount++
+		claimed = append(claimed, *p)
+	}
+	return claimed, nil
+}
+
+// sortClaimOrder sorts payouts of one batch into a processing order.
+func (s *Store) sortClaimOrder(payouts []*models.Payout, order string) {
+	switch order {
+	case models.PayoutOrderLargestFirst:
+		sort.SliceStable(payouts, func(i, j int) bool { return payouts[i].Amount > payouts[j].Amount })
+	case models.PayoutOrderSmallestFirst:
+		sort.SliceStable(payouts, func(i, j int) bool { return payouts[i].Amount < payouts[j].Amount })
+	case models.PayoutOrderBankRoundRobin:
+		// The oldest payout of every bank, then the second of every bank...
+		rank := map[uuid.UUID]int{}
+		seen := map[string]int{}
+		for _, p := range payouts {
+			seen[p.BankName]++
+			rank[p.ID] = seen[p.BankName]
+		}
+		sort.SliceStable(payouts, func(i, j int) bool {
+			if rank[payouts[i].ID] != rank[payouts[j].ID] {
+				return rank[payouts[i].ID] < rank[payouts[j].ID]
+			}
+			return payouts[i].BankName < payouts[j].BankName
+		})
+	}
+}
+
+// HasClaimablePayouts reports whether a batch has pending payouts not on
+// hold.
+func (s *Store) HasClaimablePayouts(_ context.Context, batchID uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.batchPayouts(batchID) {
+		if p.Status == models.PayoutStatusPending && p.HeldAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReleaseClaims hands claimed payouts back to pending (cancelled in a
+// cancelled batch), undoing their attempt.
+func (s *Store) ReleaseClaims(_ context.Context, payoutIDs []uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var released int64
+	for _, id := range payoutIDs {
+		if p, ok := s.payouts[id]; ok && p.Status == models.PayoutStatusProcessing {
+			p.Status, p.UpdatedAt = s.released(p), now
+			p.AttemptCount--
+			released++
+		}
+	}
+	return released, nil
+}
+
+// LogAttempt records a payout attempt, unless it is already recorded.
+func (s *Store) LogAttempt(_ context.Context, attempt *models.PayoutAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.attempts[attempt.PayoutID] {
+		if a.ID == attempt.ID {
+			return nil
+		}
+	}
+	s.attempts[attempt.PayoutID] = append(s.attempts[attempt.PayoutID], *attempt)
+	return nil
+}
+
+// CompletePayout marks a claimed payout completed.
+func (s *Store) CompletePayout(_ context.Context, payoutID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.payouts[payoutID]; ok && p.Status == models.PayoutStatusProcessing {
+		now := s.now()
+		p.Status, p.CompletedAt, p.UpdatedAt = models.PayoutStatusCompleted, &now, now
+	}
+	return nil
+}
+
+// FailPayout marks a claimed payout failed with a reason.
+func (s *Store) FailPayout(_ context.Context, payoutID uuid.UUID, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.payouts[payoutID]; ok && p.Status == models.PayoutStatusProcessing {
+		p.Status, p.FailureReason, p.UpdatedAt = models.PayoutStatusFailed, &reason, s.now()
+	}
+	return nil
+}
+
+// RequeuePayout puts a claimed payout with attempts left back to pending,
+// claimable again from retryAt, or cancels it in a cancelled batch.
+func (s *Store) RequeuePayout(_ context.Context, payoutID uuid.UUID, retryAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.payouts[payoutID]; ok && p.Status == models.PayoutStatusProcessing && p.AttemptCount < p.MaxRetries {
+		p.Status, p.FailureReason, p.UpdatedAt = s.released(p), nil, s.now()
+		p.NextRetryAt = nil
+		if !retryAt.IsZero() {
+			at := retryAt.UTC()
+			p.NextRetryAt = &at
+		}
+	}
+	return nil
+}
+
+// RetryFailedPayouts resets a batch's retryable failed payouts to pending
+// with a fresh attempt budget.
+func (s *Store) RetryFailedPayouts(_ context.Context, batchID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var requeued int64
+	for _, p := range s.batchPayouts(batchID) {
+		if p.Status != models.PayoutStatusFailed || p.SupersededBy != nil || p.FailureReason == nil || !models.IsRetryable(*p.FailureReason) {
+			continue
+		}
+		p.Status, p.FailureReason, p.UpdatedAt = models.PayoutStatusPending, nil, now
+		p.MaxRetries = p.AttemptCount + s.batches[batchID].RetryPolicy.Attempts()
+		requeued++
+	}
+	return requeued, nil
+}
+
+// GetPayout returns a payout, or nil if there is none.
+func (s *Store) GetPayout(_ context.Context, payoutID uuid.UUID) (*models.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.payouts[payoutID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *p
+	return &copied, nil
+}
+
+// GetPayoutAttempts returns a payout's attempts, oldest first.
+func (s *Store) GetPayoutAttempts(_ context.Context, payoutID uuid.UUID) ([]models.PayoutAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts := append([]models.PayoutAttempt{}, s.attempts[payoutID]...)
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].AttemptNum < attempts[j].AttemptNum })
+	return attempts, nil
+}
+
+// GetPayoutsByBatch returns a page of a batch's payouts, optionally of one
+// status, with their latest attempt.
+func (s *Store) GetPayoutsByBatch(_ context.Context, batchID uuid.UUID, status string, page, pageSize int) ([]models.PayoutListItem, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := []models.PayoutListItem{}
+	for _, p := range s.batchPayouts(batchID) {
+		if status != "" && p.Status != status {
+			continue
+		}
+		item := models.PayoutListItem{Payout: *p}
+		var last *models.PayoutAttempt
+		for i, a := range s.attempts[p.ID] {
+			if last == nil || a.AttemptNum > last.AttemptNum {
+				last = &s.attempts[p.ID][i]
+			}
+		}
+		if last != nil {
+			item.LastAttemptAt, item.LastError = &last.StartedAt, last.Error
+		}
+		items = append(items, item)
+	}
+	from := min(max(page-1, 0)*pageSize, len(items))
+	to := min(from+pageSize, len(items))
+	return items[from:to], len(items), nil
+}
+
+// EachPayout calls fn for every payout of a batch in submission order.
+func (s *Store) EachPayout(_ context.Context, batchID uuid.UUID, fn func(models.Payout) error) error {
+	s.mu.Lock()
+	var payouts []models.Payout
+	for _, p := range s.batchPayouts(batchID) {
+		payouts = append(payouts, *p)
+	}
+	s.mu.Unlock()
+
+	for _, p := range payouts {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchPayouts returns a batch's payouts in submission order. s.mu must be
+// held.
+func (s *Store) batchPayouts(batchID uuid.UUID) []*models.Payout {
+	ids := s.order[batchID]
+	payouts := make([]*models.Payout, len(ids))
+	for i, id := range ids {
+		payouts[i] = s.payouts[id]
+	}
+	return payouts
+}
+
+// CreateJobRun records the start of a run of a scheduled job.
+func (s *Store) CreateJobRun(_ context.Context, run *models.JobRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobRuns = append(s.jobRuns, *run)
+	return nil
+}
+
+// FinishJobRun records the outcome of a run of a scheduled job.
+func (s *Store) FinishJobRun(_ context.Context, run *models.JobRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.jobRuns {
+		if s.jobRuns[i].ID == run.ID {
+			s.jobRuns[i] = *run
+		}
+	}
+	return nil
+}
+
+// ListJobRuns returns the most recent runs of a scheduled job, newest first.
+func (s *Store) ListJobRuns(_ context.Context, job string, limit int) ([]models.JobRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	runs := []models.JobRun{}
+	for i := len(s.jobRuns) - 1; i >= 0 && len(runs) < limit; i-- {
+		if run := s.jobRuns[i]; run.Job == job {
+			end := now
+			if run.FinishedAt != nil {
+				end = *run.FinishedAt
+			}
+			run.DurationSeconds = end.Sub(run.StartedAt).Seconds()
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
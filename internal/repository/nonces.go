@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// --- Request Nonces ---
+
+// ClaimNonce records a signed request's nonce for keyID until expiresAt. It
+// returns false if the nonce was already claimed and has not expired, i.e.
+// the request is a replay. Expired nonces are purged as a side effect.
+func (r *Repository) ClaimNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error) {
+	if _, err := r.db.ExecContext(ctx,
+		`DELETE FROM request_nonces WHERE expires_at < $1`, r.now()); err != nil {
+		return false, fmt.Errorf("purge nonces: %w", err)
+	}
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO request_nonces (key_id, nonce, expires_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (key_id, nonce) DO NOTHING`,
+		keyID, nonce, expiresAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("claim nonce: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim nonce: %w", err)
+	}
+	return n == 1, nil
+}
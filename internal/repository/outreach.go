@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// --- Vendor Outreach ---
+
+// ErrPayoutNotFailed is returned when logging outreach about a payout that
+// is not failed.
+var ErrPayoutNotFailed = errors.New("payout is not failed")
+
+// LogOutreach records a contact with the vendor of a failed payout. It
+// returns nil if the payout does not exist.
+func (r *Repository) LogOutreach(ctx context.Context, payoutID uuid.UUID, req models.OutreachRequest, recordedBy string) (*models.VendorOutreach, error) {
+	var status string
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM payouts WHERE id = $1`, payoutID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+	if status != models.PayoutStatusFailed {
+		return nil, ErrPayoutNotFailed
+	}
+
+	now := r.now()
+	o := &models.VendorOutreach{
+		ID:          uuid.New(),
+		PayoutID:    payoutID,
+		Channel:     req.Channel,
+		Note:        req.Note,
+		ContactedAt: now,
+		RecordedBy:  recordedBy,
+	}
+	if req.ContactedAt != nil {
+		o.ContactedAt = req.ContactedAt.UTC()
+	}
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT INTO vendor_outreach (id, payout_id, channel, note, contacted_at, recorded_by, created_at)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)`,
+		o.ID, o.PayoutID, o.Channel, o.Note, o.ContactedAt, o.RecordedBy, now,
+	); err != nil {
+		return nil, fmt.Errorf("insert outreach: %w", err)
+	}
+	return o, nil
+}
+
+// ListOutreach returns the outreach logged about a payout, oldest first.
+func (r *Repository) ListOutreach(ctx context.Context, payoutID uuid.UUID) ([]models.VendorOutreach, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, payout_id, channel, COALESCE(note, ''), contacted_at, recorded_by
+		 FROM vendor_outreach WHERE payout_id = $1 ORDER BY contacted_at, created_at`, payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("query outreach: %w", err)
+	}
+	defer rows.Close()
+
+	var outreach []models.VendorOutreach
+	for rows.Next() {
+		var o models.VendorOutreach
+		if err := rows.Scan(&o.ID, &o.PayoutID, &o.Channel, &o.Note, &o.ContactedAt, &o.RecordedBy); err != nil {
+			return nil, fmt.Errorf("scan outreach: %w", err)
+		}
+		outreach = append(outreach, o)
+	}
+	return outreach, rows.Err()
+}
+
+// ListAwaitingVendor returns failed payouts with a reason only the vendor
+// can resolve that failed before failedBefore and were neither requeued nor
+// written off, longest waiting first, with a summary of their outreach.
+// DaysWaiting is left for the caller to fill in.
+func (r *Repository) ListAwaitingVendor(ctx context.Context, failedBefore time.Time) ([]models.AwaitingVendorPayout, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT p.id, p.batch_id, p.vendor_id, COALESCE(p.vendor_name, ''), p.amount, p.currency,
+		        p.failure_reason, p.updated_at, COUNT(o.id), MAX(o.contacted_at),
+		        COALESCE((ARRAY_AGG(o.channel ORDER BY o.contacted_at DESC) FILTER (WHERE o.id IS NOT NULL))[1], '')
+		 FROM payouts p
+		 JOIN payout_batches b ON b.id = p.batch_id AND b.deleted_at IS NULL
+		 LEFT JOIN vendor_outreach o ON o.payout_id = p.id
+		 WHERE p.status = $1 AND p.superseded_by IS NULL
+		   AND p.failure_reason = ANY($2) AND p.updated_at < $3
+		 GROUP BY p.id
+		 ORDER BY p.updated_at, p.id`,
+		models.PayoutStatusFailed, pq.Array(models.VendorActionFailures), failedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("query payouts awaiting vendor: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := []models.AwaitingVendorPayout{}
+	for rows.Next() {
+		var p models.AwaitingVendorPayout
+		if err := rows.Scan(&p.PayoutID, &p.BatchID, &p.VendorID, &p.VendorName, &p.Amount, &p.Currency,
+			&p.FailureReason, &p.FailedAt, &p.OutreachCount, &p.LastContactAt, &p.LastChannel); err != nil {
+			return nil, fmt.Errorf("scan payout awaiting vendor: %w", err)
+		}
+		payouts = append(payouts, p)
+	}
+	return payouts, rows.Err()
+}
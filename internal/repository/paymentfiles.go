@@ -0,0 +1,417 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/bankfile"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// --- Payment Files ---
+
+var (
+	// ErrNothingToFile is returned when a batch has no pending payouts to
+	// put in a payment file.
+	ErrNothingToFile = errors.New("batch has no pending payouts to file")
+	// ErrPaymentFileDelivered is returned when recording the delivery of a
+	// payment file that was already delivered.
+	ErrPaymentFileDelivered = errors.New("payment file was already delivered")
+	// ErrPaymentFileNotDelivered is returned for an acknowledgment of a
+	// payment file that was never delivered.
+	ErrPaymentFileNotDelivered = errors.New("payment file was not delivered")
+	// ErrPayoutNotInFile is returned for an acknowledgment naming a payout
+	// that is not in the file.
+	ErrPayoutNotInFile = errors.New("payout is not in the payment file")
+)
+
+// paymentFileHold is the held_by of payouts in a payment file, which keeps
+// runs from also sending them through the bank API.
+const paymentFileHold = "payment_file:"
+
+// CreatePaymentFile puts a batch's pending payouts that are not on hold
+// into a new payment file, holding them until the bank answers for them,
+// and returns the file with the payouts to write into it in processing
+// order (round-robin batches oldest first). Their amount is reserved
+// against funding as by a start. It returns nil if the batch does not
+// exist, ErrRunLive while a run is processing the batch and
+// ErrNothingToFile if no payout is left to file. The checksum is recorded
+// with SetPaymentFileChecksum once the file is written.
+func (r *Repository) CreatePaymentFile(ctx context.Context, batchID uuid.UUID, encryptedTo, operator string) (*models.PaymentFile, []models.Payout, error) {
+	if err := r.ReserveFunding(ctx, batchID); err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var order string
+	err = tx.QueryRowContext(ctx,
+		`SELECT payout_order FROM payout_batches WHERE id = $1 FOR UPDATE`, batchID,
+	).Scan(&order)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("get batch: %w", err)
+	}
+	var idle bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_xact_lock($1, hashtext($2))`, runLockClass, batchID.String(),
+	).Scan(&idle); err != nil {
+		return nil, nil, fmt.Errorf("try run lock: %w", err)
+	}
+	if !idle {
+		return nil, nil, ErrRunLive
+	}
+
+	now := r.now()
+	f := &models.PaymentFile{
+		ID:          uuid.New(),
+		BatchID:     batchID,
+		Status:      models.PaymentFileGenerated,
+		EncryptedTo: encryptedTo,
+		GeneratedBy: operator,
+		GeneratedAt: now,
+	}
+	f.Filename = fmt.Sprintf("payment-%s.csv", f.ID)
+	if encryptedTo != "" {
+		f.Filename += ".pgp"
+	}
+	hold := paymentFileHold + f.ID.String()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payouts SET held_at = $3, held_by = $4, updated_at = $3
+		 WHERE batch_id = $1 AND status = $2 AND held_at IS NULL`,
+		batchID, models.PayoutStatusPending, now, hold,
+	); err != nil {
+		return nil, nil, fmt.Errorf("hold payouts: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+payoutColumns+` FROM payouts p WHERE p.batch_id = $1 AND p.held_by = $2
+		 ORDER BY `+payoutOrderBy(order), batchID, hold)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query filed payouts: %w", err)
+	}
+	payouts, err := scanPayouts(rows)
+	rows.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(payouts) == 0 {
+		return nil, nil, ErrNothingToFile
+	}
+	f.PayoutCount = len(payouts)
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO payment_files (id, batch_id, filename, status, payout_count, encrypted_to, generated_by, generated_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)`,
+		f.ID, f.BatchID, f.Filename, f.Status, f.PayoutCount, f.EncryptedTo, f.GeneratedBy, f.GeneratedAt,
+	); err != nil {
+		return nil, nil, fmt.Errorf("insert payment file: %w", err)
+	}
+	ids := make([]string, len(payouts))
+	refs := make([]string, len(payouts))
+	for i, p := range payouts {
+		ids[i], refs[i] = p.ID.String(), bankfile.Reference(p.ID)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO payment_file_items (file_id, payout_id, reference)
+		 SELECT $1, id, ref FROM unnest($2::uuid[], $3::text[]) AS t(id, ref)`,
+		f.ID, pq.Array(ids), pq.Array(refs),
+	); err != nil {
+		return nil, nil, fmt.Errorf("insert payment file items: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return f, payouts, r.journal(ctx, audit.KindPaymentFile, f.ID, map[string]any{
+		"event": models.PaymentFileGenerated, "file": f, "payouts": ids,
+	})
+}
+
+// SetPaymentFileChecksum records the SHA-256 of a payment file as written.
+func (r *Repository) SetPaymentFileChecksum(ctx context.Context, fileID uuid.UUID, checksum string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE payment_files SET checksum = $2 WHERE id = $1`, fileID, checksum)
+	if err != nil {
+		return fmt.Errorf("set payment file checksum: %w", err)
+	}
+	return nil
+}
+
+// MarkPaymentFileDelivered records that a payment file was handed to the
+// bank. It returns nil if the file does not exist and
+// ErrPaymentFileDelivered if its delivery was already recorded.
+func (r *Repository) MarkPaymentFileDelivered(ctx context.Context, fileID uuid.UUID, reference, operator string) (*models.PaymentFile, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE payment_files SET status = $2, delivered_by = $3, delivered_at = $4, delivery_reference = $5
+		 WHERE id = $1 AND status = $6`,
+		fileID, models.PaymentFileDelivered, operator, r.now(), reference, models.PaymentFileGenerated)
+	if err != nil {
+		return nil, fmt.Errorf("mark payment file delivered: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		f, err := r.GetPaymentFile(ctx, fileID)
+		if err != nil || f == nil {
+			return nil, err
+		}
+		return nil, ErrPaymentFileDelivered
+	}
+	if err := r.journal(ctx, audit.KindPaymentFile, fileID, map[string]any{
+		"event": models.PaymentFileDelivered, "reference": reference, "operator": operator,
+	}); err != nil {
+		return nil, err
+	}
+	return r.GetPaymentFile(ctx, fileID)
+}
+
+// AcknowledgePaymentFile applies the bank's answers for the payouts of a
+// delivered payment file. Accepted payouts are completed and rejected ones
+// failed with FailureBankRejected, each with an attempt recorded as for a
+// transfer, and released from the file's hold; the batch's counters, status
+// and funding are then rebuilt as by RepairBatch. Payouts the bank already
+// answered for keep their first answer, so a resent acknowledgment changes
+// nothing. The file is acknowledged once every payout is answered for. It
+// returns nil if the file does not exist, ErrPaymentFileNotDelivered before
+// its delivery is recorded, ErrPayoutNotInFile for an item naming another
+// payout, and ErrRunLive while a run is processing the batch.
+func (r *Repository) AcknowledgePaymentFile(ctx context.Context, fileID uuid.UUID, req models.PaymentFileAckRequest, operator string) (*models.PaymentFile, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var batchID uuid.UUID
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT batch_id, status FROM payment_files WHERE id = $1 FOR UPDATE`, fileID,
+	).Scan(&batchID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payment file: %w", err)
+	}
+	if status == models.PaymentFileGenerated {
+		return nil, ErrPaymentFileNotDelivered
+	}
+	var idle bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_xact_lock($1, hashtext($2))`, runLockClass, batchID.String(),
+	).Scan(&idle); err != nil {
+		return nil, fmt.Errorf("try run lock: %w", err)
+	}
+	if !idle {
+		return nil, ErrRunLive
+	}
+
+	// The payouts still waiting on an answer, with their attempt counts.
+	rows, err := tx.QueryContext(ctx,
+		`SELECT i.payout_id, i.result IS NOT NULL, p.attempt_count
+		 FROM payment_file_items i JOIN payouts p ON p.id = i.payout_id
+		 WHERE i.file_id = $1 ORDER BY i.payout_id FOR UPDATE OF p`, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("lock payment file payouts: %w", err)
+	}
+	answered := map[uuid.UUID]bool{}
+	attempts := map[uuid.UUID]int{}
+	for rows.Next() {
+		var id uuid.UUID
+		var done bool
+		var n int
+		if err := rows.Scan(&id, &done, &n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan payment file item: %w", err)
+		}
+		answered[id], attempts[id] = done, n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	answers := map[uuid.UUID]models.PaymentFileAckItem{}
+	for _, item := range req.Items {
+		if _, ok := answered[item.PayoutID]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPayoutNotInFile, item.PayoutID)
+		}
+		if _, dup := answers[item.PayoutID]; !dup {
+			answers[item.PayoutID] = item
+		}
+	}
+	if req.Result != "" {
+		for id := range answered {
+			if _, ok := answers[id]; !ok {
+				answers[id] = models.PaymentFileAckItem{PayoutID: id, Result: req.Result, Reason: req.Reason}
+			}
+		}
+	}
+
+	now := r.now()
+	var applied []models.PaymentFileAckItem
+	for id, item := range answers {
+		if answered[id] {
+			continue
+		}
+		if err := r.applyFileAnswer(ctx, tx, item, attempts[id]+1, now); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE payment_file_items SET result = $3, reason = NULLIF($4, ''), answered_at = $5
+			 WHERE file_id = $1 AND payout_id = $2`,
+			fileID, id, item.Result, item.Reason, now,
+		); err != nil {
+			return nil, fmt.Errorf("record answer for payout %s: %w", id, err)
+		}
+		applied = append(applied, item)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payment_files SET status = $2, acknowledged_at = $3
+		 WHERE id = $1 AND status <> $2
+		   AND NOT EXISTS (SELECT 1 FROM payment_file_items WHERE file_id = $1 AND result IS NULL)`,
+		fileID, models.PaymentFileAcknowledged, now,
+	); err != nil {
+		return nil, fmt.Errorf("acknowledge payment file: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	if len(applied) > 0 {
+		if err := r.journal(ctx, audit.KindPaymentFile, fileID, map[string]any{
+			"event": "acknowledgment", "answers": applied, "operator": operator,
+		}); err != nil {
+			return nil, err
+		}
+		if _, err := r.RepairBatch(ctx, batchID, true); err != nil {
+			return nil, err
+		}
+	}
+	return r.GetPaymentFile(ctx, fileID)
+}
+
+// applyFileAnswer completes or fails a filed payout on the bank's answer,
+// recording it as attempt attemptNum.
+func (r *Repository) applyFileAnswer(ctx context.Context, tx *sql.Tx, item models.PaymentFileAckItem, attemptNum int, now time.Time) error {
+	attempt := &models.PayoutAttempt{
+		ID:         uuid.New(),
+		PayoutID:   item.PayoutID,
+		AttemptNum: attemptNum,
+		Status:     models.PayoutStatusCompleted,
+		StartedAt:  now,
+		FinishedAt: &now,
+	}
+	var err error
+	if item.Result == models.PaymentFileAccepted {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payouts SET status = $2, failure_reason = NULL, completed_at = $3, attempted_at = $3,
+			        attempt_count = $4, held_at = NULL, held_by = NULL, updated_at = $3
+			 WHERE id = $1`,
+			item.PayoutID, models.PayoutStatusCompleted, now, attemptNum)
+	} else {
+		reason := models.FailureBankRejected
+		// The attempt keeps the bank's own reason code, e.g. "BANK_REJECTED: AC04".
+		detail := reason
+		if item.Reason != "" {
+			detail += ": " + item.Reason
+		}
+		attempt.Status, attempt.Error = models.PayoutStatusFailed, &detail
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payouts SET status = $2, failure_reason = $3, attempted_at = $4,
+			        attempt_count = $5, held_at = NULL, held_by = NULL, updated_at = $4
+			 WHERE id = $1`,
+			item.PayoutID, models.PayoutStatusFailed, reason, now, attemptNum)
+	}
+	if err != nil {
+		return fmt.Errorf("apply answer for payout %s: %w", item.PayoutID, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO payout_attempts (id, payout_id, attempt_num, status, error, started_at, finished_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		attempt.ID, attempt.PayoutID, attempt.AttemptNum, attempt.Status, attempt.Error, attempt.StartedAt, attempt.FinishedAt,
+	); err != nil {
+		return fmt.Errorf("insert attempt: %w", err)
+	}
+	return nil
+}
+
+const paymentFileColumns = `f.id, f.batch_id, f.filename, f.status, f.payout_count,
+	COUNT(i.payout_id) FILTER (WHERE i.result = 'accepted'), COUNT(i.payout_id) FILTER (WHERE i.result = 'rejected'),
+	COALESCE(f.encrypted_to, ''), COALESCE(f.checksum, ''), f.generated_by, f.generated_at,
+	COALESCE(f.delivered_by, ''), f.delivered_at, COALESCE(f.delivery_reference, ''), f.acknowledged_at`
+
+func scanPaymentFile(row rowScanner, f *models.PaymentFile) error {
+	if err := row.Scan(&f.ID, &f.BatchID, &f.Filename, &f.Status, &f.PayoutCount,
+		&f.AcceptedCount, &f.RejectedCount, &f.EncryptedTo, &f.Checksum, &f.GeneratedBy, &f.GeneratedAt,
+		&f.DeliveredBy, &f.DeliveredAt, &f.DeliveryReference, &f.AcknowledgedAt); err != nil {
+		return fmt.Errorf("scan payment file: %w", err)
+	}
+	return nil
+}
+
+// GetPaymentFile returns a payment file with the bank's answer for each of
+// its payouts, or nil if it does not exist.
+func (r *Repository) GetPaymentFile(ctx context.Context, fileID uuid.UUID) (*models.PaymentFile, error) {
+	f := &models.PaymentFile{}
+	err := scanPaymentFile(r.db.QueryRowContext(ctx,
+		`SELECT `+paymentFileColumns+`
+		 FROM payment_files f LEFT JOIN payment_file_items i ON i.file_id = f.id
+		 WHERE f.id = $1 GROUP BY f.id`, fileID), f)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT i.payout_id, i.reference, COALESCE(i.result, ''), COALESCE(i.reason, ''), i.answered_at
+		 FROM payment_file_items i JOIN payouts p ON p.id = i.payout_id
+		 WHERE i.file_id = $1 ORDER BY p.created_at, p.seq`, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("query payment file items: %w", err)
+	}
+	defer rows.Close()
+	f.Items = []models.PaymentFileItem{}
+	for rows.Next() {
+		var it models.PaymentFileItem
+		if err := rows.Scan(&it.PayoutID, &it.Reference, &it.Result, &it.Reason, &it.AnsweredAt); err != nil {
+			return nil, fmt.Errorf("scan payment file item: %w", err)
+		}
+		f.Items = append(f.Items, it)
+	}
+	return f, rows.Err()
+}
+
+// ListPaymentFiles lists a batch's payment files, newest first, without
+// their items.
+func (r *Repository) ListPaymentFiles(ctx context.Context, batchID uuid.UUID) ([]models.PaymentFile, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+paymentFileColumns+`
+		 FROM payment_files f LEFT JOIN payment_file_items i ON i.file_id = f.id
+		 WHERE f.batch_id = $1 GROUP BY f.id ORDER BY f.generated_at DESC, f.id`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("query payment files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []models.PaymentFile{}
+	for rows.Next() {
+		var f models.PaymentFile
+		if err := scanPaymentFile(rows, &f); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
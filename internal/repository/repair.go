@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Consistency & Repair ---
+//
+// There is no separate event log: payout_attempts is the history that
+// payout statuses and batch counters are checked and rebuilt against.
+
+// FindInconsistencies returns payouts whose status contradicts their attempt
+// history, for one batch or, with uuid.Nil, across all batches. On a batch
+// with a live run, a payout completed moments ago may briefly show as
+// completed_without_success until its attempt is logged.
+func (r *Repository) FindInconsistencies(ctx context.Context, batchID uuid.UUID) ([]models.PayoutInconsistency, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT p.id, p.batch_id, p.status, p.attempt_count,
+		        COUNT(a.id),
+		        COUNT(a.id) FILTER (WHERE a.status = $2),
+		        COUNT(a.id) FILTER (WHERE a.status = $3)
+		 FROM payouts p
+		 LEFT JOIN payout_attempts a ON a.payout_id = p.id
+		 WHERE ($1 = '00000000-0000-0000-0000-000000000000'::uuid OR p.batch_id = $1)
+		 GROUP BY p.id
+		 HAVING (p.status <> $2 AND COUNT(a.id) FILTER (WHERE a.status = $2) > 0)
+		     OR (p.status = $2 AND COUNT(a.id) FILTER (WHERE a.status = $2) = 0)
+		     OR (p.status = $3 AND COUNT(a.id) FILTER (WHERE a.status = $3) = 0)
+		     OR COUNT(a.id) FILTER (WHERE a.status = $2) > 1
+		     OR COUNT(a.id) > p.attempt_count
+		 ORDER BY p.batch_id, p.seq`,
+		batchID, models.PayoutStatusCompleted, models.PayoutStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("query inconsistencies: %w", err)
+	}
+	defer rows.Close()
+
+	found := []models.PayoutInconsistency{}
+	for rows.Next() {
+		var (
+			p                                  models.PayoutInconsistency
+			attemptCount, attempts, succ, fail int
+		)
+		if err := rows.Scan(&p.PayoutID, &p.BatchID, &p.Status, &attemptCount, &attempts, &succ, &fail); err != nil {
+			return nil, fmt.Errorf("scan inconsistency: %w", err)
+		}
+		add := func(kind, detail string) {
+			p.Kind, p.Detail, p.Repairable = kind, detail, kind == models.InconsistencySuccessNotRecorded
+			found = append(found, p)
+		}
+		if p.Status != models.PayoutStatusCompleted && succ > 0 {
+			add(models.InconsistencySuccessNotRecorded, fmt.Sprintf("status %s but %d successful attempt(s)", p.Status, succ))
+		}
+		if p.Status == models.PayoutStatusCompleted && succ == 0 {
+			add(models.InconsistencyCompletedWithoutSuccess, fmt.Sprintf("completed with %d attempt(s), none successful", attempts))
+		}
+		if p.Status == models.PayoutStatusFailed && fail == 0 {
+			add(models.InconsistencyFailedWithoutAttempt, "failed with no failed attempt")
+		}
+		if succ > 1 {
+			add(models.InconsistencyPaidMoreThanOnce, fmt.Sprintf("%d successful attempts", succ))
+		}
+		if attempts > attemptCount {
+			add(models.InconsistencyAttemptsExceedCount, fmt.Sprintf("%d attempts logged, attempt_count is %d", attempts, attemptCount))
+		}
+	}
+	return found, rows.Err()
+}
+
+// ListBatchIDs returns the ID of every batch, oldest first.
+func (r *Repository) ListBatchIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM payout_batches ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("query batch ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan batch id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RepairBatch rebuilds a batch from its payouts' attempt history: payouts
+// with a successful attempt are marked completed, the counters are
+// recalculated, and the batch status is derived again — the same way a run
+// finishes a batch when nothing is left to process, or paused if a
+// finished-looking batch still has unfinished payouts. With apply false it
+// only reports what would change. Batches with a live run are skipped.
+func (r *Repository) RepairBatch(ctx context.Context, batchID uuid.UUID, apply bool) (*models.BatchRepair, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	repair := &models.BatchRepair{BatchID: batchID}
+
+	var idle bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_xact_lock($1, hashtext($2))`, runLockClass, batchID.String(),
+	).Scan(&idle); err != nil {
+		return nil, fmt.Errorf("try run lock: %w", err)
+	}
+
+	var before models.BatchCounts
+	if err := tx.QueryRowContext(ctx,
+		`SELECT status, completed_count, failed_count, pending_count FROM payout_batches WHERE id = $1 FOR UPDATE`, batchID,
+	).Scan(&repair.StatusBefore, &before.Completed, &before.Failed, &before.Pending); err != nil {
+		return nil, fmt.Errorf("get batch: %w", err)
+	}
+	repair.CountsBefore = before
+	repair.StatusAfter, repair.CountsAfter = repair.StatusBefore, before
+	if !idle {
+		repair.Skipped = "a processing run is live"
+		return repair, nil
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE payouts p SET status = $2, failure_reason = NULL,
+		        completed_at = COALESCE(p.completed_at, a.finished_at, $3), updated_at = $3
+		 FROM (SELECT payout_id, MIN(finished_at) AS finished_at FROM payout_attempts
+		       WHERE status = $2 GROUP BY payout_id) a
+		 WHERE a.payout_id = p.id AND p.batch_id = $1 AND p.status <> $2`,
+		batchID, models.PayoutStatusCompleted, r.now())
+	if err != nil {
+		return nil, fmt.Errorf("repair payouts: %w", err)
+	}
+	repaired, _ := result.RowsAffected()
+	repair.PayoutsRepaired = int(repaired)
+
+	total, after, err := countPayouts(ctx, tx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	repair.CountsAfter = after
+	repair.StatusAfter = derivedBatchStatus(repair.StatusBefore, total, after)
+
+	if !apply || !repair.Changed() {
+		return repair, nil
+	}
+
+	now := r.now()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payout_batches SET status = $2, completed_count = $3, failed_count = $4, pending_count = $5,
+		        completed_at = CASE WHEN $2 IN ($6, $7, $8) THEN COALESCE(completed_at, $9) ELSE completed_at END,
+		        updated_at = $9
+		 WHERE id = $1`,
+		batchID, repair.StatusAfter, after.Completed, after.Failed, after.Pending,
+		models.BatchStatusCompleted, models.BatchStatusFailed, models.BatchStatusPartiallyCompleted, now,
+	); err != nil {
+		return nil, fmt.Errorf("repair batch: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	// Repaired payouts change what the batch owes its funding accounts.
+	if err := r.SettleFunding(ctx, batchID); err != nil {
+		return repair, err
+	}
+	return repair, nil
+}
+
+// RecoverOrphanedPayouts sweeps every batch for payouts left in processing
+// by runs that no longer exist, e.g. on an instance that crashed, and puts
+// them back to pending, or cancels them if their batch was cancelled.
+// ResetStuckProcessing does the same for one batch
+// when a run starts; this catches batches nobody starts again. Batches with
+// a live run or a live batch lease are skipped, and payouts under a live
+// claim lease or out of attempts are left alone. With apply false it only
+// reports what would be reset.
+func (r *Repository) RecoverOrphanedPayouts(ctx context.Context, apply bool) (*models.OrphanRecovery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT batch_id FROM payouts WHERE status = $1 ORDER BY batch_id`, models.PayoutStatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("query orphan candidates: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan batch id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query orphan candidates: %w", err)
+	}
+
+	report := &models.OrphanRecovery{Applied: apply, Batches: []models.OrphanedBatch{}}
+	for _, id := range ids {
+		b, err := r.recoverOrphans(ctx, id, apply)
+		if err != nil {
+			return report, fmt.Errorf("batch %s: %w", id, err)
+		}
+		report.Reset += b.Reset
+		report.Batches = append(report.Batches, *b)
+	}
+	return report, nil
+}
+
+// recoverOrphans resets one batch's orphaned payouts, holding its run lock
+// so that no run starts meanwhile.
+func (r *Repository) recoverOrphans(ctx context.Context, batchID uuid.UUID, apply bool) (*models.OrphanedBatch, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	b := &models.OrphanedBatch{BatchID: batchID}
+	now := r.now()
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+		        COUNT(*) FILTER (WHERE attempt_count >= max_retries),
+		        COUNT(*) FILTER (WHERE attempt_count < max_retries AND NOT `+claimLeaseExpired("$3")+`)
+		 FROM payouts WHERE batch_id = $1 AND status = $2`,
+		batchID, models.PayoutStatusProcessing, now,
+	).Scan(&b.Processing, &b.Exhausted, &b.Leased); err != nil {
+		return nil, fmt.Errorf("count processing payouts: %w", err)
+	}
+
+	var idle bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_xact_lock($1, hashtext($2))`, runLockClass, batchID.String(),
+	).Scan(&idle); err != nil {
+		return nil, fmt.Errorf("try run lock: %w", err)
+	}
+	if !idle {
+		b.Skipped = "a processing run is live"
+		return b, nil
+	}
+	var holder string
+	err = tx.QueryRowContext(ctx,
+		`SELECT holder FROM batch_leases WHERE batch_id = $1 AND expires_at > $2`, batchID, now,
+	).Scan(&holder)
+	if err == nil {
+		b.Skipped = "leased by " + holder
+		return b, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("look up batch lease: %w", err)
+	}
+
+	if !apply {
+		b.Reset = b.Processing - b.Exhausted - b.Leased
+		return b, nil
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE payouts SET status = `+releasedStatus+`, updated_at = $3, claimed_by = NULL, lease_expires_at = NULL
+		 WHERE batch_id = $1 AND status = $2 AND attempt_count < max_retries AND `+claimLeaseExpired("$3"),
+		batchID, models.PayoutStatusProcessing, now)
+	if err != nil {
+		return nil, fmt.Errorf("reset orphaned payouts: %w", err)
+	}
+	reset, _ := result.RowsAffected()
+	b.Reset = int(reset)
+	// Those of a cancelled batch were cancelled instead: release what was
+	// held for them.
+	if err := settleFunding(ctx, tx, batchID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return b, nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// countPayouts counts a batch's payout rows by status, with written-off and
+// cancelled payouts counted as failed.
+func countPayouts(ctx context.Context, q queryRower, batchID uuid.UUID) (int, models.BatchCounts, error) {
+	var c models.BatchCounts
+	var total int
+	if err := q.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+		        COUNT(*) FILTER (WHERE status = $2),
+		        COUNT(*) FILTER (WHERE status IN ($3, $6, $7)),
+		        COUNT(*) FILTER (WHERE status IN ($4, $5))
+		 FROM payouts WHERE batch_id = $1`,
+		batchID, models.PayoutStatusCompleted, models.PayoutStatusFailed,
+		models.PayoutStatusPending, models.PayoutStatusProcessing, models.PayoutStatusWrittenOff,
+		models.PayoutStatusCancelled,
+	).Scan(&total, &c.Completed, &c.Failed, &c.Pending); err != nil {
+		return 0, c, fmt.Errorf("count payouts: %w", err)
+	}
+	return total, c, nil
+}
+
+// runLive reports whether any process holds the batch's run lock. Unlike
+// trying the lock, looking it up cannot make a starting run skip recovery.
+func (r *Repository) runLive(ctx context.Context, batchID uuid.UUID) (bool, error) {
+	var live bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_locks
+		                WHERE locktype = 'advisory' AND granted AND objsubid = 2
+		                  AND classid = $1::oid AND objid = (hashtext($2)::bigint & 4294967295)::oid)`,
+		runLockClass, batchID.String(),
+	).Scan(&live)
+	if err != nil {
+		return false, fmt.Errorf("look up run lock: %w", err)
+	}
+	return live, nil
+}
+
+// VerifyBatch cross-checks a batch without changing it: stored counters
+// against its payout rows, its status against its payouts', payout statuses
+// against their attempts, and funding reservations against completed and
+// unfinished amounts. Counter, status and ledger checks are skipped while a
+// run is live, since they only catch up at chunk and run boundaries. It
+// returns nil if the batch does not exist.
+func (r *Repository) VerifyBatch(ctx context.Context, batchID uuid.UUID) (*models.BatchVerification, error) {
+	batch, err := r.GetBatch(ctx, batchID)
+	if err != nil || batch == nil {
+		return nil, err
+	}
+	v := &models.BatchVerification{
+		BatchID:       batchID,
+		Status:        batch.Status,
+		CheckedAt:     r.now(),
+		Discrepancies: []models.Discrepancy{},
+	}
+	if v.RunLive, err = r.runLive(ctx, batchID); err != nil {
+		return nil, err
+	}
+
+	total, counts, err := countPayouts(ctx, r.db, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if total != batch.TotalCount {
+		v.Discrepancies = append(v.Discrepancies, models.Discrepancy{
+			Check: models.CheckCounters, Field: "total_count", Expected: total, Actual: batch.TotalCount,
+			Detail: "total_count does not match the number of payout rows",
+		})
+	}
+	v.Checks = append(v.Checks, models.CheckCounters)
+	if !v.RunLive {
+		for _, c := range []struct {
+			field          string
+			actual, stored int
+		}{
+			{"completed_count", counts.Completed, batch.CompletedCount},
+			{"failed_count", counts.Failed, batch.FailedCount},
+			{"pending_count", counts.Pending, batch.PendingCount},
+		} {
+			if c.actual != c.stored {
+				v.Discrepancies = append(v.Discrepancies, models.Discrepancy{
+					Check: models.CheckCounters, Field: c.field, Expected: c.actual, Actual: c.stored,
+					Detail: c.field + " does not match the payout rows",
+				})
+			}
+		}
+
+		v.Checks = append(v.Checks, models.CheckStatus)
+		if want := derivedBatchStatus(batch.Status, total, counts); want != batch.Status {
+			v.Discrepancies = append(v.Discrepancies, models.Discrepancy{
+				Check: models.CheckStatus, Field: "status", Expected: want, Actual: batch.Status,
+				Detail: fmt.Sprintf("%d completed, %d failed and %d unfinished payouts imply %s", counts.Completed, counts.Failed, counts.Pending, want),
+			})
+		}
+	}
+
+	v.Checks = append(v.Checks, models.CheckAttempts)
+	inconsistencies, err := r.FindInconsistencies(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range inconsistencies {
+		id := p.PayoutID
+		v.Discrepancies = append(v.Discrepancies, models.Discrepancy{
+			Check: models.CheckAttempts, Field: p.Kind, PayoutID: &id, Actual: p.Status, Detail: p.Detail,
+		})
+	}
+
+	if !v.RunLive {
+		v.Checks = append(v.Checks, models.CheckLedger)
+		ledger, err := r.verifyLedger(ctx, batchID)
+		if err != nil {
+			return nil, err
+		}
+		v.Discrepancies = append(v.Discrepancies, ledger...)
+	}
+
+	v.Consistent = len(v.Discrepancies) == 0
+	return v, nil
+}
+
+// verifyLedger compares each funding reservation of a batch with its
+// payouts: everything completed must have been debited, and no more may be
+// held than is still unfinished.
+func (r *Repository) verifyLedger(ctx context.Context, batchID uuid.UUID) ([]models.Discrepancy, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT fr.currency, fr.held, fr.debited,
+		        COALESCE(SUM(p.amount) FILTER (WHERE p.status IN ($2, $3)), 0),
+		        COALESCE(SUM(p.amount) FILTER (WHERE p.status = $4), 0)
+		 FROM funding_reservations fr
+		 LEFT JOIN payouts p ON p.batch_id = fr.batch_id AND p.currency = fr.currency
+		 WHERE fr.batch_id = $1
+		 GROUP BY fr.currency, fr.held, fr.debited
+		 ORDER BY fr.currency`,
+		batchID, models.PayoutStatusPending, models.PayoutStatusProcessing, models.PayoutStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("query reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.Discrepancy
+	for rows.Next() {
+		var currency string
+		var held, debited, unfinished, completed float64
+		if err := rows.Scan(&currency, &held, &debited, &unfinished, &completed); err != nil {
+			return nil, fmt.Errorf("scan reservation: %w", err)
+		}
+		if debited != completed {
+			out = append(out, models.Discrepancy{
+				Check: models.CheckLedger, Field: currency + ".debited", Expected: completed, Actual: debited,
+				Detail: "debited amount does not match completed payouts",
+			})
+		}
+		if held > unfinished {
+			out = append(out, models.Discrepancy{
+				Check: models.CheckLedger, Field: currency + ".held", Expected: unfinished, Actual: held,
+				Detail: "more is held than unfinished payouts need",
+			})
+		}
+	}
+	return out, rows.Err()
+}
+
+// derivedBatchStatus is the status a batch should have given its payouts.
+// Batches still being worked on keep their status unless they claim to be
+// finished, and cancelled batches stay cancelled.
+func derivedBatchStatus(current string, total int, c models.BatchCounts) string {
+	terminal := current == models.BatchStatusCompleted || current == models.BatchStatusFailed ||
+		current == models.BatchStatusPartiallyCompleted
+	switch {
+	case total == 0, current == models.BatchStatusCancelled:
+		return current
+	case c.Pending > 0 && terminal:
+		return models.BatchStatusPaused
+	case c.Pending > 0:
+		return current
+	case c.Failed == 0:
+		return models.BatchStatusCompleted
+	case c.Completed == 0:
+		return models.BatchStatusFailed
+	default:
+		return models.BatchStatusPartiallyCompleted
+	}
+}
@@ -3,10 +3,18 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/compliance"
 	"coding-challenge/internal/models"
+	"coding-challenge/internal/money"
+	"coding-challenge/internal/purpose"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -15,90 +23,321 @@ import (
 
 // Repository handles all database operations.
 type Repository struct {
-	db *sql.DB
+	db        *sql.DB
+	audit     audit.Store
+	clock     clock.Clock
+	reporting compliance.Rules
+	purposes  purpose.Defaults
+}
+
+// Option configures a Repository.
+type Option func(*Repository)
+
+// WithAuditStore copies payout attempts, processing runs and batch
+// deletions to an append-only audit store as they are written.
+func WithAuditStore(store audit.Store) Option {
+	return func(r *Repository) { r.audit = store }
+}
+
+// WithClock sets the clock used for the timestamps the repository writes
+// and for stall cutoffs (the system clock by default).
+func WithClock(c clock.Clock) Option {
+	return func(r *Repository) { r.clock = c }
+}
+
+// WithReportingRules flags new payouts above a country's reporting
+// threshold and, if the rules require it, holds them for approval.
+func WithReportingRules(rules compliance.Rules) Option {
+	return func(r *Repository) { r.reporting = rules }
+}
+
+// WithPurposeDefaults gives new payouts created without a purpose code
+// their country's default.
+func WithPurposeDefaults(defaults purpose.Defaults) Option {
+	return func(r *Repository) { r.purposes = defaults }
 }
 
 // New creates a new repository with the given database connection.
-func New(db *sql.DB) *Repository {
-	return &Repository{db: db}
+func New(db *sql.DB, opts ...Option) *Repository {
+	r := &Repository{db: db, clock: clock.Real}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// now returns the repository clock's current time in UTC.
+func (r *Repository) now() time.Time {
+	return r.clock.Now().UTC()
+}
+
+// journal copies a record to the audit store, if one is configured.
+func (r *Repository) journal(ctx context.Context, kind string, subject uuid.UUID, payload any) error {
+	if r.audit == nil {
+		return nil
+	}
+	if err := r.audit.Append(ctx, kind, subject, payload); err != nil {
+		return fmt.Errorf("audit %s: %w", kind, err)
+	}
+	return nil
 }
 
 // --- Batch Operations ---
 
+// ErrDuplicateVendor is returned when a batch would pay a vendor twice.
+var ErrDuplicateVendor = errors.New("appears more than once")
+
 // CreateBatch creates a new payout batch and inserts all payouts atomically.
-func (r *Repository) CreateBatch(ctx context.Context, items []models.CreatePayoutItem) (*models.PayoutBatch, error) {
+// An item with splits becomes one payout per account, all sharing a split
+// group ID.
+func (r *Repository) CreateBatch(ctx context.Context, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("begin tx: %w", err)
 	}
 	defer tx.Rollback()
 
-	batchID := uuid.New()
-	now := time.Now().UTC()
-	totalCount := len(items)
+	batch, _, err := r.createBatch(ctx, tx, items, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return batch, nil
+}
+
+// payoutCopyColumns are the payout columns createBatch copies in.
+var payoutCopyColumns = []string{
+	"id", "batch_id", "idempotency_key", "vendor_id", "vendor_name", "amount", "currency", "bank_account", "bank_name",
+	"transaction_ids", "metadata", "status", "seq", "created_at", "updated_at", "split_group_id", "split_percent",
+	"reporting_country", "held_at", "held_by", "purpose_code", "max_retries",
+}
 
-	// Insert batch
-	_, err = tx.ExecContext(ctx,
-		`INSERT INTO payout_batches (id, status, total_count, pending_count, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
-		batchID, models.BatchStatusPending, totalCount, totalCount, now, now,
-	)
+// createBatch inserts a batch and its payouts in tx, returning the payout
+// IDs in insertion order.
+func (r *Repository) createBatch(ctx context.Context, tx *sql.Tx, items []models.CreatePayoutItem, opts models.BatchOptions) (*models.PayoutBatch, []uuid.UUID, error) {
+	batch := r.newBatch(items, opts, models.BatchStatusPending)
+	if err := insertBatch(ctx, tx, batch); err != nil {
+		return nil, nil, err
+	}
+	ids, err := r.copyPayouts(ctx, tx, batch, items, nil)
 	if err != nil {
-		return nil, fmt.Errorf("insert batch: %w", err)
+		return nil, nil, err
 	}
+	return batch, ids, nil
+}
 
-	// Insert all payouts
-	stmt, err := tx.PrepareContext(ctx,
-		`INSERT INTO payouts (id, batch_id, idempotency_key, vendor_id, vendor_name, amount, currency, bank_account, bank_name, transaction_ids, status, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`)
+// newBatch returns the batch to insert for items, in status: pending, or
+// creating with nothing ingested yet.
+func (r *Repository) newBatch(items []models.CreatePayoutItem, opts models.BatchOptions, status string) *models.PayoutBatch {
+	now := r.now()
+	totalCount := 0
+	for _, item := range items {
+		totalCount += max(len(item.Splits), 1)
+	}
+	if opts.PayoutOrder == "" {
+		opts.PayoutOrder = models.PayoutOrderFIFO
+	}
+	batch := &models.PayoutBatch{
+		ID:            uuid.New(),
+		Status:        status,
+		TotalCount:    totalCount,
+		PendingCount:  totalCount,
+		PayoutOrder:   opts.PayoutOrder,
+		ProgressEvery: opts.ProgressEvery,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if status == models.BatchStatusCreating {
+		batch.PendingCount, batch.IngestedCount = 0, new(int)
+	}
+	if opts.Owner != "" {
+		batch.Owner = &opts.Owner
+	}
+	if opts.AssignedTo != "" {
+		batch.AssignedTo = &opts.AssignedTo
+	}
+	if opts.Merchant != "" {
+		batch.MerchantID = &opts.Merchant
+	}
+	if opts.IdempotencyKey != "" {
+		batch.IdempotencyKey, batch.RequestHash = &opts.IdempotencyKey, &opts.RequestHash
+	}
+	batch.RetryPolicy, batch.Rejections = opts.RetryPolicy, opts.Rejections
+	return batch
+}
+
+// ErrDuplicateRequest is returned when the merchant already created a batch
+// with the same idempotency key.
+var ErrDuplicateRequest = errors.New("a batch was already created with this idempotency key")
+
+// insertBatch inserts the batch row, or returns ErrDuplicateRequest if its
+// idempotency key is taken.
+func insertBatch(ctx context.Context, db execer, b *models.PayoutBatch) error {
+	var policy, rejections []byte
+	if b.RetryPolicy != nil {
+		var err error
+		if policy, err = json.Marshal(b.RetryPolicy); err != nil {
+			return fmt.Errorf("encode retry policy: %w", err)
+		}
+	}
+	if b.Rejections != nil {
+		var err error
+		if rejections, err = json.Marshal(b.Rejections); err != nil {
+			return fmt.Errorf("encode rejections: %w", err)
+		}
+	}
+	res, err := db.ExecContext(ctx,
+		`INSERT INTO payout_batches (id, status, total_count, pending_count, payout_order, created_at, updated_at, owner, assigned_to, progress_every, ingested_count, merchant_id, idempotency_key, request_hash, retry_policy, rejections)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		 ON CONFLICT DO NOTHING`,
+		b.ID, b.Status, b.TotalCount, b.PendingCount, b.PayoutOrder, b.CreatedAt, b.UpdatedAt, b.Owner, b.AssignedTo, b.ProgressEvery, b.IngestedCount, b.MerchantID,
+		b.IdempotencyKey, b.RequestHash, policy, rejections,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("prepare stmt: %w", err)
+		return fmt.Errorf("insert batch: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDuplicateRequest
 	}
-	defer stmt.Close()
+	return nil
+}
 
+// copyPayouts writes the batch's payouts in tx, returning their IDs in
+// insertion order; see payoutCopy. progress, if set, is told the number of
+// payouts copied every ingestProgressEvery payouts.
+func (r *Repository) copyPayouts(ctx context.Context, tx *sql.Tx, batch *models.PayoutBatch, items []models.CreatePayoutItem, progress func(copied int) error) ([]uuid.UUID, error) {
+	cp, err := r.newPayoutCopy(ctx, tx, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer cp.stmt.Close()
+	cp.progress = progress
 	for _, item := range items {
-		payoutID := uuid.New()
-		idempotencyKey := fmt.Sprintf("%s:%s", item.VendorID, batchID.String())
-
-		_, err = stmt.ExecContext(ctx,
-			payoutID, batchID, idempotencyKey,
-			item.VendorID, item.VendorName, item.Amount, item.Currency,
-			item.BankAccount, item.BankName, pq.Array(item.TransactionIDs),
-			models.PayoutStatusPending, now, now,
+		if err := cp.add(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+	if err := cp.flush(ctx); err != nil {
+		return nil, err
+	}
+	return cp.ids, nil
+}
+
+// payoutCopy streams a batch's payouts into tx with COPY rather than
+// inserting them one by one, so a 100k-payout batch takes seconds; a
+// conflict (e.g. on an idempotency key) fails the whole copy when it is
+// flushed.
+type payoutCopy struct {
+	r        *Repository
+	stmt     *sql.Stmt
+	batchID  uuid.UUID
+	attempts int // max_retries of the payouts, from the batch's retry policy
+	now      time.Time
+	vendors  map[string]bool
+	ids      []uuid.UUID // of the payouts copied, in order
+	progress func(copied int) error
+}
+
+// newPayoutCopy starts copying payouts of the batch into tx. The caller
+// closes stmt.
+func (r *Repository) newPayoutCopy(ctx context.Context, tx *sql.Tx, batch *models.PayoutBatch) (*payoutCopy, error) {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("payouts", payoutCopyColumns...))
+	if err != nil {
+		return nil, fmt.Errorf("prepare copy: %w", err)
+	}
+	return &payoutCopy{
+		r: r, stmt: stmt, batchID: batch.ID, attempts: batch.RetryPolicy.Attempts(), now: r.now(), vendors: map[string]bool{},
+	}, nil
+}
+
+// add copies the payouts of one item: one, or one per split share.
+func (c *payoutCopy) add(ctx context.Context, item models.CreatePayoutItem) error {
+	// Split shares have their own idempotency keys, so a vendor appearing
+	// twice is caught here rather than by the unique key.
+	if c.vendors[item.VendorID] {
+		return fmt.Errorf("vendor %s %w", item.VendorID, ErrDuplicateVendor)
+	}
+	c.vendors[item.VendorID] = true
+
+	metadata, err := marshalMetadata(item.Metadata)
+	if err != nil {
+		return fmt.Errorf("metadata for vendor %s: %w", item.VendorID, err)
+	}
+
+	// A payout above a reporting threshold is flagged, and held for
+	// approval if the rules want one.
+	var country, heldBy, purposeCode *string
+	var heldAt *time.Time
+	if flagged := c.r.reporting.Flag(item); flagged != "" {
+		country = &flagged
+		if c.r.reporting.RequireApproval {
+			hold := reportingHold
+			heldAt, heldBy = &c.now, &hold
+		}
+	}
+	if code := c.r.purposes.For(item); code != "" {
+		purposeCode = &code
+	}
+
+	insert := func(idempotencyKey string, amount float64, account, bank string, group *uuid.UUID, percent *float64) error {
+		id := uuid.New()
+		seq := len(c.ids)
+		_, err := c.stmt.ExecContext(ctx,
+			id, c.batchID, idempotencyKey,
+			item.VendorID, item.VendorName, amount, item.Currency,
+			account, bank, pq.Array(item.TransactionIDs), metadata,
+			models.PayoutStatusPending, seq, c.now, c.now, group, percent,
+			country, heldAt, heldBy, purposeCode, c.attempts,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("insert payout for vendor %s: %w", item.VendorID, err)
+			return fmt.Errorf("copy payout for vendor %s: %w", item.VendorID, err)
 		}
+		c.ids = append(c.ids, id)
+		if c.progress != nil && len(c.ids)%ingestProgressEvery == 0 {
+			return c.progress(len(c.ids))
+		}
+		return nil
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit: %w", err)
+	if len(item.Splits) == 0 {
+		return insert(fmt.Sprintf("%s:%s", item.VendorID, c.batchID.String()), item.Amount, item.BankAccount, item.BankName, nil, nil)
+	}
+	percents := make([]float64, len(item.Splits))
+	for i, split := range item.Splits {
+		percents[i] = split.Percent
 	}
+	amounts := money.Split(item.Amount, item.Currency, percents)
+	group := uuid.New()
+	for i, split := range item.Splits {
+		bank := split.BankName
+		if bank == "" {
+			bank = item.BankName
+		}
+		key := fmt.Sprintf("%s:%s:split-%d", item.VendorID, c.batchID.String(), i+1)
+		if err := insert(key, amounts[i], split.BankAccount, bank, &group, &item.Splits[i].Percent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	batch := &models.PayoutBatch{
-		ID:         batchID,
-		Status:     models.BatchStatusPending,
-		TotalCount: totalCount,
-		PendingCount: totalCount,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+// flush ends the copy; COPY reports constraint violations only now.
+func (c *payoutCopy) flush(ctx context.Context) error {
+	if _, err := c.stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("copy payouts: %w", err)
 	}
-	return batch, nil
+	return nil
 }
 
 // GetBatch retrieves a batch by ID.
 func (r *Repository) GetBatch(ctx context.Context, batchID uuid.UUID) (*models.PayoutBatch, error) {
 	batch := &models.PayoutBatch{}
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, status, total_count, completed_count, failed_count, pending_count,
-		        created_at, started_at, completed_at, updated_at
-		 FROM payout_batches WHERE id = $1`, batchID,
-	).Scan(
-		&batch.ID, &batch.Status, &batch.TotalCount, &batch.CompletedCount,
-		&batch.FailedCount, &batch.PendingCount, &batch.CreatedAt,
-		&batch.StartedAt, &batch.CompletedAt, &batch.UpdatedAt,
-	)
-	if err == sql.ErrNoRows {
+	err := scanBatch(r.db.QueryRowContext(ctx,
+		`SELECT `+batchColumns+` FROM payout_batches b WHERE b.id = $1`, batchID,
+	), batch)
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
@@ -107,9 +346,227 @@ func (r *Repository) GetBatch(ctx context.Context, batchID uuid.UUID) (*models.P
 	return batch, nil
 }
 
+// GetBatchByIdempotencyKey returns the batch the merchant ("" for none)
+// created with an idempotency key, or nil.
+func (r *Repository) GetBatchByIdempotencyKey(ctx context.Context, merchant, key string) (*models.PayoutBatch, error) {
+	batch := &models.PayoutBatch{}
+	err := scanBatch(r.db.QueryRowContext(ctx,
+		`SELECT `+batchColumns+` FROM payout_batches b
+		 WHERE COALESCE(b.merchant_id, '') = $1 AND b.idempotency_key = $2`, merchant, key,
+	), batch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get batch by idempotency key: %w", err)
+	}
+	return batch, nil
+}
+
+// ErrBatchNotTerminal is returned by DeleteBatch for batches that have not
+// finished processing.
+var ErrBatchNotTerminal = errors.New("batch has not finished processing")
+
+// ListBatches returns a page of batches, newest first. Soft-deleted batches
+// are left out unless the filter includes them.
+func (r *Repository) ListBatches(ctx context.Context, f models.BatchListFilter) ([]models.PayoutBatch, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM payout_batches b WHERE `+batchListWhere, batchListArgs(f)...,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count batches: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+batchColumns+` FROM payout_batches b WHERE `+batchListWhere+`
+		 ORDER BY b.created_at DESC, b.id LIMIT $6 OFFSET $7`,
+		append(batchListArgs(f), f.PageSize, (f.Page-1)*f.PageSize)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []models.PayoutBatch{}
+	for rows.Next() {
+		var b models.PayoutBatch
+		if err := scanBatch(rows, &b); err != nil {
+			return nil, 0, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, total, rows.Err()
+}
+
+// ListBatchesAfter returns up to limit batches matching f that come after
+// the cursor in list order (newest first); a nil cursor starts at the top.
+// f's Page and PageSize are ignored.
+func (r *Repository) ListBatchesAfter(ctx context.Context, f models.BatchListFilter, after *models.PageCursor, limit int) ([]models.PayoutBatch, error) {
+	afterAt, afterID := cursorArgs(after)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+batchColumns+` FROM payout_batches b WHERE `+batchListWhere+`
+		   AND ($6::timestamptz IS NULL OR b.created_at < $6 OR (b.created_at = $6 AND b.id > $7))
+		 ORDER BY b.created_at DESC, b.id LIMIT $8`,
+		append(batchListArgs(f), afterAt, afterID, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("query batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []models.PayoutBatch{}
+	for rows.Next() {
+		var b models.PayoutBatch
+		if err := scanBatch(rows, &b); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+// cursorArgs binds a page cursor as a creation time and ID, both NULL when
+// there is none.
+func cursorArgs(after *models.PageCursor) (sql.NullTime, uuid.NullUUID) {
+	if after == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: after.CreatedAt, Valid: true}, uuid.NullUUID{UUID: after.ID, Valid: true}
+}
+
+// batchListWhere filters batches by a BatchListFilter bound by
+// batchListArgs as $1-$5.
+const batchListWhere = `($1 = '' OR b.status = $1) AND ($2 OR b.deleted_at IS NULL) AND ($3 = '' OR b.assigned_to = $3)
+	AND ($4::timestamptz IS NULL OR b.created_at >= $4) AND ($5::timestamptz IS NULL OR b.created_at < $5)`
+
+func batchListArgs(f models.BatchListFilter) []any {
+	return []any{f.Status, f.IncludeDeleted, f.AssignedTo, f.CreatedFrom, f.CreatedTo}
+}
+
+// GetBatchListAggregates counts the batches matching a list filter by status
+// and totals their unfinished payouts per currency, ignoring pagination.
+func (r *Repository) GetBatchListAggregates(ctx context.Context, f models.BatchListFilter) (*models.BatchListAggregates, error) {
+	agg := &models.BatchListAggregates{StatusCounts: map[string]int{}, PendingAmounts: []models.CurrencyAmount{}}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT b.status, COUNT(*) FROM payout_batches b WHERE `+batchListWhere+` GROUP BY b.status`,
+		batchListArgs(f)...)
+	if err != nil {
+		return nil, fmt.Errorf("count batches by status: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan status count: %w", err)
+		}
+		agg.StatusCounts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	amounts, err := r.db.QueryContext(ctx,
+		`SELECT p.currency, COUNT(*), SUM(p.amount)
+		 FROM payouts p JOIN payout_batches b ON b.id = p.batch_id
+		 WHERE `+batchListWhere+` AND p.status IN ($6, $7)
+		 GROUP BY p.currency ORDER BY p.currency`,
+		append(batchListArgs(f), models.PayoutStatusPending, models.PayoutStatusProcessing)...)
+	if err != nil {
+		return nil, fmt.Errorf("total pending amounts: %w", err)
+	}
+	defer amounts.Close()
+	for amounts.Next() {
+		var a models.CurrencyAmount
+		var count int
+		if err := amounts.Scan(&a.Currency, &count, &a.Amount); err != nil {
+			return nil, fmt.Errorf("scan pending amount: %w", err)
+		}
+		agg.PendingPayouts += count
+		agg.PendingAmounts = append(agg.PendingAmounts, a)
+	}
+	return agg, amounts.Err()
+}
+
+// DeleteBatch soft-deletes a finished batch; its rows are kept so it can be
+// restored. Deleting an already deleted batch is a no-op. It returns the
+// batch, or nil if it does not exist.
+func (r *Repository) DeleteBatch(ctx context.Context, batchID uuid.UUID, deletedBy string) (*models.PayoutBatch, error) {
+	batch := &models.PayoutBatch{}
+	err := scanBatch(r.db.QueryRowContext(ctx,
+		`UPDATE payout_batches b SET deleted_at = COALESCE(b.deleted_at, $6), deleted_by = COALESCE(b.deleted_by, $2)
+		 WHERE b.id = $1 AND b.status IN ($3, $4, $5, $7)
+		 RETURNING `+batchColumns,
+		batchID, deletedBy, models.BatchStatusCompleted, models.BatchStatusFailed, models.BatchStatusPartiallyCompleted, r.now(),
+		models.BatchStatusCancelled,
+	), batch)
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err := r.GetBatch(ctx, batchID)
+		if err != nil || existing == nil {
+			return nil, err
+		}
+		return nil, ErrBatchNotTerminal
+	}
+	if err != nil {
+		return nil, fmt.Errorf("delete batch: %w", err)
+	}
+	return batch, r.journal(ctx, audit.KindBatchDeleted, batchID, batch)
+}
+
+// RestoreBatch undoes a soft delete. It returns the batch, or nil if it does
+// not exist.
+func (r *Repository) RestoreBatch(ctx context.Context, batchID uuid.UUID, restoredBy string) (*models.PayoutBatch, error) {
+	batch := &models.PayoutBatch{}
+	err := scanBatch(r.db.QueryRowContext(ctx,
+		`UPDATE payout_batches b SET deleted_at = NULL, deleted_by = NULL
+		 WHERE b.id = $1
+		 RETURNING `+batchColumns, batchID,
+	), batch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restore batch: %w", err)
+	}
+	return batch, r.journal(ctx, audit.KindBatchRestored, batchID, map[string]any{"batch": batch, "restored_by": restoredBy})
+}
+
+// AssignBatch changes the owner, assignee and/or progress granularity of a
+// batch; nil fields are left as they are and empty ones cleared. It returns
+// the batch, or nil if it does not exist.
+func (r *Repository) AssignBatch(ctx context.Context, batchID uuid.UUID, req models.UpdateBatchRequest, operator string) (*models.PayoutBatch, error) {
+	batch := &models.PayoutBatch{}
+	err := scanBatch(r.db.QueryRowContext(ctx,
+		`UPDATE payout_batches b
+		 SET owner = CASE WHEN $2 THEN NULLIF($3, '') ELSE b.owner END,
+		     assigned_to = CASE WHEN $4 THEN NULLIF($5, '') ELSE b.assigned_to END,
+		     progress_every = COALESCE($7, b.progress_every),
+		     updated_at = $6
+		 WHERE b.id = $1
+		 RETURNING `+batchColumns,
+		batchID, req.Owner != nil, deref(req.Owner), req.AssignedTo != nil, deref(req.AssignedTo), r.now(), req.ProgressEvery,
+	), batch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("assign batch: %w", err)
+	}
+	return batch, r.journal(ctx, audit.KindBatchAssigned, batchID, map[string]any{
+		"owner": batch.Owner, "assigned_to": batch.AssignedTo, "progress_every": batch.ProgressEvery, "operator": operator,
+	})
+}
+
+// deref returns *s, or "" for nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 // UpdateBatchStatus updates the batch status and timestamps.
 func (r *Repository) UpdateBatchStatus(ctx context.Context, batchID uuid.UUID, status string) error {
-	now := time.Now().UTC()
+	now := r.now()
 	var query string
 
 	switch status {
@@ -121,97 +578,407 @@ func (r *Repository) UpdateBatchStatus(ctx context.Context, batchID uuid.UUID, s
 		query = `UPDATE payout_batches SET status = $1, updated_at = $2 WHERE id = $3`
 	}
 
-	_, err := r.db.ExecContext(ctx, query, status, now, batchID)
+	// A cancelled batch stays cancelled, e.g. when a run was starting on it.
+	_, err := r.db.ExecContext(ctx, query+` AND status <> '`+models.BatchStatusCancelled+`'`, status, now, batchID)
 	return err
 }
 
+// stalledCondition matches in-progress batches with no batch or payout
+// updates since the cutoff ($2); the batch status is bound to $1.
+const stalledCondition = `b.status = $1
+   AND GREATEST(b.updated_at, COALESCE((SELECT MAX(p.updated_at) FROM payouts p WHERE p.batch_id = b.id), b.updated_at)) < $2`
+
+// FindStalledBatches returns in-progress batches with no batch or payout
+// updates for at least idleFor.
+func (r *Repository) FindStalledBatches(ctx context.Context, idleFor time.Duration) ([]models.PayoutBatch, error) {
+	cutoff := r.now().Add(-idleFor)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+batchColumns+`
+		 FROM payout_batches b
+		 WHERE `+stalledCondition,
+		models.BatchStatusInProgress, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("query stalled batches: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []models.PayoutBatch
+	for rows.Next() {
+		var b models.PayoutBatch
+		if err := scanBatch(rows, &b); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+// PauseBatch moves an in-progress batch to paused. It returns false if the
+// batch was no longer in progress (e.g. it was resumed concurrently).
+func (r *Repository) PauseBatch(ctx context.Context, batchID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE payout_batches SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`,
+		models.BatchStatusPaused, r.now(), batchID, models.BatchStatusInProgress,
+	)
+	if err != nil {
+		return false, fmt.Errorf("pause batch: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// FinalizeBatch settles a batch whose run found nothing left to claim, at
+// most once however many runs, on however many instances, get here. Under
+// the batch row lock it checks the batch is still in progress and that no
+// payout is pending or processing (one may still be in another run's
+// hands, and that run finalizes instead). It then pauses the batch if
+// payouts are on hold, or gives it its terminal status and settles its
+// funding, all in one transaction. A cancelled batch is only recounted and
+// settled once nothing of it is in processing. It returns the batch's
+// status and whether this call finalized it; only the caller that did
+// should announce the outcome.
+func (r *Repository) FinalizeBatch(ctx context.Context, batchID uuid.UUID) (string, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRowContext(ctx,
+		`SELECT status FROM payout_batches WHERE id = $1 FOR UPDATE`, batchID,
+	).Scan(&status); err != nil {
+		return "", false, fmt.Errorf("lock batch: %w", err)
+	}
+	if status == models.BatchStatusCancelled {
+		// Its payouts in flight when it was cancelled have finished.
+		return status, false, settleCancelled(ctx, tx, batchID, r.now())
+	}
+	if status != models.BatchStatusInProgress {
+		return status, false, nil
+	}
+
+	var completed, failed, unfinished, held int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'completed'),
+			COUNT(*) FILTER (WHERE status IN ('failed', 'written_off', 'cancelled')),
+			COUNT(*) FILTER (WHERE (status = 'pending' AND held_at IS NULL) OR status = 'processing'),
+			COUNT(*) FILTER (WHERE status = 'pending' AND held_at IS NOT NULL)
+		FROM payouts WHERE batch_id = $1`, batchID,
+	).Scan(&completed, &failed, &unfinished, &held); err != nil {
+		return "", false, fmt.Errorf("count payouts: %w", err)
+	}
+	if unfinished > 0 {
+		return status, false, nil
+	}
+
+	now := r.now()
+	switch {
+	case held > 0:
+		// Payouts on hold are still owed, so the batch waits for their release.
+		status = models.BatchStatusPaused
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payout_batches SET status = $1, updated_at = $2 WHERE id = $3`, status, now, batchID)
+	default:
+		switch {
+		case failed == 0:
+			status = models.BatchStatusCompleted
+		case completed == 0:
+			status = models.BatchStatusFailed
+		default:
+			status = models.BatchStatusPartiallyCompleted
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payout_batches SET status = $1, completed_at = $2, updated_at = $2 WHERE id = $3`, status, now, batchID)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("finalize batch: %w", err)
+	}
+	// Debit what was paid and release what was held for failed payouts.
+	if status != models.BatchStatusPaused {
+		if err := settleFunding(ctx, tx, batchID); err != nil {
+			return "", false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", false, fmt.Errorf("commit: %w", err)
+	}
+	return status, true, nil
+}
+
+// PauseStalledBatch pauses a batch only if it is still in progress and idle
+// for at least idleFor, and in the same transaction resets its payouts stuck
+// in processing. Holding the batch row lock means a run resuming the batch
+// cannot mark it in progress (and start claiming) until the reset is done,
+// and payouts claimed since the cutoff, or whose claim lease is still live,
+// are never reset. It returns whether the batch was paused and how many
+// payouts were reset.
+func (r *Repository) PauseStalledBatch(ctx context.Context, batchID uuid.UUID, idleFor time.Duration) (bool, int64, error) {
+	now := r.now()
+	cutoff := now.Add(-idleFor)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A live run anywhere (not just in this process) holds the run lock.
+	var idle bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_xact_lock($1, hashtext($2))`, runLockClass, batchID.String(),
+	).Scan(&idle); err != nil {
+		return false, 0, fmt.Errorf("try run lock: %w", err)
+	}
+	if !idle {
+		return false, 0, nil
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE payout_batches b SET status = $3, updated_at = $4
+		 WHERE b.id = $5 AND `+stalledCondition,
+		models.BatchStatusInProgress, cutoff, models.BatchStatusPaused, now, batchID,
+	)
+	if err != nil {
+		return false, 0, fmt.Errorf("pause stalled batch: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, 0, nil // Resumed, finished or active again
+	}
+
+	result, err = tx.ExecContext(ctx,
+		`UPDATE payouts SET status = $1, updated_at = $2, claimed_by = NULL, lease_expires_at = NULL
+		 WHERE batch_id = $3 AND status = $4 AND attempt_count < max_retries AND updated_at < $5 AND `+claimLeaseExpired("$2"),
+		models.PayoutStatusPending, now, batchID, models.PayoutStatusProcessing, cutoff,
+	)
+	if err != nil {
+		return false, 0, fmt.Errorf("reset stalled payouts: %w", err)
+	}
+	reset, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return true, reset, nil
+}
+
 // RefreshBatchCounts recalculates batch counts from actual payout statuses.
 func (r *Repository) RefreshBatchCounts(ctx context.Context, batchID uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE payout_batches SET
 			completed_count = (SELECT COUNT(*) FROM payouts WHERE batch_id = $1 AND status = 'completed'),
-			failed_count    = (SELECT COUNT(*) FROM payouts WHERE batch_id = $1 AND status = 'failed'),
+			failed_count    = (SELECT COUNT(*) FROM payouts WHERE batch_id = $1 AND status IN ('failed', 'written_off', 'cancelled')),
 			pending_count   = (SELECT COUNT(*) FROM payouts WHERE batch_id = $1 AND status IN ('pending', 'processing')),
-			updated_at      = NOW()
-		WHERE id = $1`, batchID)
+			updated_at      = $2
+		WHERE id = $1`, batchID, r.now())
 	return err
 }
 
 // --- Payout Operations ---
 
-// GetPendingPayouts retrieves payouts that need processing (pending only).
-// Crash recovery for stuck "processing" payouts is handled separately by ResetStuckProcessing.
-func (r *Repository) GetPendingPayouts(ctx context.Context, batchID uuid.UUID, limit int) ([]models.Payout, error) {
+// ClaimChunk atomically selects up to limit pending payouts of a batch, in
+// its processing order, and marks them processing with their attempt
+// counted, returning them as claimed. Payouts locked by a concurrent run are
+// skipped rather than waited on, so runs sharing a batch take disjoint
+// chunks and a payout is never claimed twice. Held payouts are never
+// claimed, nor are payouts still backing off before a retry. Crash recovery
+// for stuck "processing" payouts is handled separately by
+// ResetStuckProcessing, and claims a run does not get to are handed back
+// with ReleaseClaims.
+//
+// inFlightCaps optionally caps the amount in processing per currency, across
+// all batches: payouts of a capped currency are claimed, in order, only while
+// they fit under the cap, so the chunk may come back short or empty. A
+// payout larger than the cap is still claimed once nothing else of its
+// currency is in flight, so it cannot block the batch forever. Runs claiming
+// at the same moment may each see the same headroom.
+func (r *Repository) ClaimChunk(ctx context.Context, batchID uuid.UUID, order string, limit int, inFlightCaps map[string]float64) ([]models.Payout, error) {
+	return r.ClaimChunkSpread(ctx, batchID, order, limit, inFlightCaps, ClaimSpread{})
+}
+
+// ClaimSpread narrows a claim to part of a fifo batch, so runs sharing a
+// batch claim from different places rather than all contending for the
+// first pending rows. The zero value claims from the start of the batch.
+type ClaimSpread struct {
+	// FromSeq skips payouts submitted before this position.
+	FromSeq int
+	// Buckets, when above 1, splits payouts by position modulo Buckets and
+	// only claims those in Bucket.
+	Buckets, Bucket int
+}
+
+// ClaimChunkSpread is ClaimChunk limited to the payouts selected by spread.
+// The spread only applies to fifo batches; other orders are always claimed
+// strictly in order. An empty result only means nothing is claimable in the
+// spread: the caller falls back to ClaimChunk before taking the batch as
+// done.
+func (r *Repository) ClaimChunkSpread(ctx context.Context, batchID uuid.UUID, order string, limit int, inFlightCaps map[string]float64, spread ClaimSpread) ([]models.Payout, error) {
+	currencies := make([]string, 0, len(inFlightCaps))
+	caps := make([]float64, 0, len(inFlightCaps))
+	for currency, amount := range inFlightCaps {
+		currencies = append(currencies, currency)
+		caps = append(caps, amount)
+	}
+	args := []any{batchID, models.PayoutStatusPending, limit, models.PayoutStatusProcessing, r.now(),
+		pq.Array(currencies), pq.Array(caps)}
+
+	var candidates, orderBy string
+	switch order {
+	case models.PayoutOrderBankRoundRobin:
+		// Take the oldest payout of every bank, then the second of every bank, and so on.
+		candidates = `SELECT p.id, p.currency, p.amount, p.bank_name, r.bank_rank
+		 FROM payouts p
+		 JOIN (
+		     SELECT id, ROW_NUMBER() OVER (PARTITION BY bank_name ORDER BY created_at, seq) AS bank_rank
+		     FROM payouts WHERE batch_id = $1 AND status = $2 AND held_at IS NULL
+		       AND (next_retry_at IS NULL OR next_retry_at <= $5)
+		 ) r ON r.id = p.id
+		 ORDER BY r.bank_rank ASC, p.bank_name ASC
+		 LIMIT $3 FOR UPDATE OF p SKIP LOCKED`
+		orderBy = "p.bank_rank ASC, p.bank_name ASC"
+	default:
+		var filter string
+		if order == models.PayoutOrderFIFO || order == "" {
+			if spread.FromSeq > 0 {
+				args = append(args, spread.FromSeq)
+				filter += fmt.Sprintf(" AND p.seq >= $%d", len(args))
+			}
+			if spread.Buckets > 1 {
+				args = append(args, spread.Buckets, spread.Bucket)
+				filter += fmt.Sprintf(" AND p.seq %% $%d = $%d", len(args)-1, len(args))
+			}
+		}
+		candidates = `SELECT p.id, p.currency, p.amount, p.created_at, p.seq, 0::bigint AS bank_rank
+		 FROM payouts p
+		 WHERE p.batch_id = $1 AND p.status = $2 AND p.held_at IS NULL
+		   AND (p.next_retry_at IS NULL OR p.next_retry_at <= $5)` + filter + `
+		 ORDER BY ` + payoutOrderBy(order) + `
+		 LIMIT $3 FOR UPDATE SKIP LOCKED`
+		orderBy = payoutOrderBy(order)
+	}
+
+	// Candidates beyond their currency's headroom stay pending; the running
+	// total keeps each currency's claims a prefix of its processing order.
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, batch_id, idempotency_key, vendor_id, vendor_name, amount, currency,
-		        bank_account, bank_name, transaction_ids, status, failure_reason, attempt_count, max_retries,
-		        created_at, attempted_at, completed_at, updated_at
-		 FROM payouts
-		 WHERE batch_id = $1 AND status = $2
-		 ORDER BY created_at ASC
-		 LIMIT $3`,
-		batchID, models.PayoutStatusPending, limit,
-	)
+		`WITH candidates AS (`+candidates+`),
+		 next AS (
+		     SELECT p.id, p.bank_rank
+		     FROM (
+		         SELECT p.*,
+		                SUM(p.amount) OVER (PARTITION BY p.currency ORDER BY `+orderBy+` ROWS UNBOUNDED PRECEDING) AS running
+		         FROM candidates p
+		     ) p
+		     LEFT JOIN UNNEST($6::text[], $7::numeric[]) AS cap(currency, amount) ON cap.currency = p.currency
+		     LEFT JOIN (
+		         SELECT currency, SUM(amount) AS amount FROM payouts WHERE status = $4 GROUP BY currency
+		     ) f ON f.currency = p.currency
+		     WHERE cap.amount IS NULL
+		        OR COALESCE(f.amount, 0) + p.running <= cap.amount
+		        OR (f.amount IS NULL AND p.running = p.amount)
+		 ),
+		 claimed AS (
+		     UPDATE payouts p SET status = $4, attempted_at = $5, attempt_count = p.attempt_count + 1, next_retry_at = NULL, updated_at = $5,
+		                          claimed_by = NULL, lease_expires_at = NULL
+		     FROM next WHERE p.id = next.id
+		     RETURNING p.*, next.bank_rank
+		 )
+		 SELECT `+payoutColumns+` FROM claimed p ORDER BY `+orderBy,
+		args...)
 	if err != nil {
-		return nil, fmt.Errorf("query pending payouts: %w", err)
+		return nil, fmt.Errorf("claim chunk: %w", err)
 	}
 	defer rows.Close()
 
 	return scanPayouts(rows)
 }
 
-// ClaimPayout atomically transitions a payout from pending to processing.
-// Returns true if the payout was successfully claimed.
-// Only claims payouts in "pending" state to prevent concurrent workers from
-// double-processing the same payout.
-func (r *Repository) ClaimPayout(ctx context.Context, payoutID uuid.UUID) (bool, error) {
-	now := time.Now().UTC()
+// HasClaimablePayouts reports whether a batch has pending payouts that are
+// not on hold, e.g. ones ClaimChunk left behind for an in-flight cap or
+// because they are backing off.
+func (r *Repository) HasClaimablePayouts(ctx context.Context, batchID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM payouts WHERE batch_id = $1 AND status = $2 AND held_at IS NULL)`,
+		batchID, models.PayoutStatusPending,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check claimable payouts: %w", err)
+	}
+	return exists, nil
+}
+
+// ReleaseClaims hands claimed payouts that were never attempted back to
+// pending (cancelled if their batch was cancelled), undoing the attempt
+// counted by ClaimChunk. Payouts no longer in processing are left untouched.
+func (r *Repository) ReleaseClaims(ctx context.Context, payoutIDs []uuid.UUID) (int64, error) {
+	ids := make([]string, len(payoutIDs))
+	for i, id := range payoutIDs {
+		ids[i] = id.String()
+	}
 	result, err := r.db.ExecContext(ctx,
-		`UPDATE payouts SET status = $1, attempted_at = $2, attempt_count = attempt_count + 1, updated_at = $2
-		 WHERE id = $3 AND status = $4`,
-		models.PayoutStatusProcessing, now, payoutID,
-		models.PayoutStatusPending,
+		`UPDATE payouts SET status = `+releasedStatus+`, attempt_count = attempt_count - 1, updated_at = $1, claimed_by = NULL, lease_expires_at = NULL
+		 WHERE id = ANY($2::uuid[]) AND status = $3`,
+		r.now(), pq.Array(ids), models.PayoutStatusProcessing,
 	)
 	if err != nil {
-		return false, fmt.Errorf("claim payout: %w", err)
+		return 0, fmt.Errorf("release claims: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// payoutOrderBy returns the ORDER BY clause for a processing order. Amount
+// orders compare raw amounts across currencies, so they are only meaningful
+// for single-currency batches.
+func payoutOrderBy(order string) string {
+	switch order {
+	case models.PayoutOrderLargestFirst:
+		return "p.amount DESC, p.created_at ASC, p.seq ASC"
+	case models.PayoutOrderSmallestFirst:
+		return "p.amount ASC, p.created_at ASC, p.seq ASC"
+	default:
+		return "p.created_at ASC, p.seq ASC"
 	}
-	affected, _ := result.RowsAffected()
-	return affected > 0, nil
 }
 
-// CompletePayout marks a payout as completed.
+// CompletePayout marks a claimed payout as completed. Payouts no longer in
+// processing (e.g. reset by recovery) are left untouched.
 func (r *Repository) CompletePayout(ctx context.Context, payoutID uuid.UUID) error {
-	now := time.Now().UTC()
+	now := r.now()
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE payouts SET status = $1, completed_at = $2, updated_at = $2 WHERE id = $3`,
-		models.PayoutStatusCompleted, now, payoutID,
+		`UPDATE payouts SET status = $1, completed_at = $2, updated_at = $2 WHERE id = $3 AND status = $4`,
+		models.PayoutStatusCompleted, now, payoutID, models.PayoutStatusProcessing,
 	)
 	return err
 }
 
-// FailPayout marks a payout as failed with a reason.
+// FailPayout marks a claimed payout as failed with a reason.
 func (r *Repository) FailPayout(ctx context.Context, payoutID uuid.UUID, reason string) error {
-	now := time.Now().UTC()
+	now := r.now()
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE payouts SET status = $1, failure_reason = $2, updated_at = $3 WHERE id = $4`,
-		models.PayoutStatusFailed, reason, now, payoutID,
+		`UPDATE payouts SET status = $1, failure_reason = $2, updated_at = $3 WHERE id = $4 AND status = $5`,
+		models.PayoutStatusFailed, reason, now, payoutID, models.PayoutStatusProcessing,
 	)
 	return err
 }
 
-// RequeuePayout puts a failed retryable payout back to pending.
-func (r *Repository) RequeuePayout(ctx context.Context, payoutID uuid.UUID) error {
-	now := time.Now().UTC()
+// RequeuePayout puts a claimed payout with a retryable failure back to
+// pending, to be claimed again no earlier than retryAt (the zero time for at
+// once), or cancels it if its batch was cancelled.
+func (r *Repository) RequeuePayout(ctx context.Context, payoutID uuid.UUID, retryAt time.Time) error {
+	now := r.now()
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE payouts SET status = $1, failure_reason = NULL, updated_at = $2
-		 WHERE id = $3 AND attempt_count < max_retries`,
-		models.PayoutStatusPending, now, payoutID,
+		`UPDATE payouts SET status = `+releasedStatus+`, failure_reason = NULL, next_retry_at = $4, updated_at = $1
+		 WHERE id = $2 AND status = $3 AND attempt_count < max_retries`,
+		now, payoutID, models.PayoutStatusProcessing,
+		sql.NullTime{Time: retryAt.UTC(), Valid: !retryAt.IsZero()},
 	)
 	return err
 }
 
 // GetPayoutsByBatch retrieves payouts for a batch with optional status filter and pagination.
-func (r *Repository) GetPayoutsByBatch(ctx context.Context, batchID uuid.UUID, status string, page, pageSize int) ([]models.Payout, int, error) {
+// Each item carries a summary of its latest attempt so list views don't need a call per payout.
+func (r *Repository) GetPayoutsByBatch(ctx context.Context, batchID uuid.UUID, status string, page, pageSize int) ([]models.PayoutListItem, int, error) {
 	offset := (page - 1) * pageSize
 
 	// Count total
@@ -236,19 +1003,25 @@ func (r *Repository) GetPayoutsByBatch(ctx context.Context, batchID uuid.UUID, s
 	var err error
 	if status != "" {
 		rows, err = r.db.QueryContext(ctx,
-			`SELECT id, batch_id, idempotency_key, vendor_id, vendor_name, amount, currency,
-			        bank_account, bank_name, transaction_ids, status, failure_reason, attempt_count, max_retries,
-			        created_at, attempted_at, completed_at, updated_at
-			 FROM payouts WHERE batch_id = $1 AND status = $2
-			 ORDER BY created_at ASC LIMIT $3 OFFSET $4`,
+			`SELECT `+payoutColumns+`, a.started_at, a.error
+			 FROM payouts p
+			 LEFT JOIN LATERAL (
+			     SELECT started_at, error FROM payout_attempts
+			     WHERE payout_id = p.id ORDER BY attempt_num DESC LIMIT 1
+			 ) a ON true
+			 WHERE p.batch_id = $1 AND p.status = $2
+			 ORDER BY p.created_at ASC LIMIT $3 OFFSET $4`,
 			batchID, status, pageSize, offset)
 	} else {
 		rows, err = r.db.QueryContext(ctx,
-			`SELECT id, batch_id, idempotency_key, vendor_id, vendor_name, amount, currency,
-			        bank_account, bank_name, transaction_ids, status, failure_reason, attempt_count, max_retries,
-			        created_at, attempted_at, completed_at, updated_at
-			 FROM payouts WHERE batch_id = $1
-			 ORDER BY created_at ASC LIMIT $2 OFFSET $3`,
+			`SELECT `+payoutColumns+`, a.started_at, a.error
+			 FROM payouts p
+			 LEFT JOIN LATERAL (
+			     SELECT started_at, error FROM payout_attempts
+			     WHERE payout_id = p.id ORDER BY attempt_num DESC LIMIT 1
+			 ) a ON true
+			 WHERE p.batch_id = $1
+			 ORDER BY p.created_at ASC LIMIT $2 OFFSET $3`,
 			batchID, pageSize, offset)
 	}
 	if err != nil {
@@ -256,8 +1029,49 @@ func (r *Repository) GetPayoutsByBatch(ctx context.Context, batchID uuid.UUID, s
 	}
 	defer rows.Close()
 
+	items, err := scanPayoutListItems(rows)
+	return items, totalCount, err
+}
+
+// EachPayout calls fn for every payout of a batch in creation order,
+// streaming rows so large batches are never held in memory.
+func (r *Repository) EachPayout(ctx context.Context, batchID uuid.UUID, fn func(models.Payout) error) error {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+payoutColumns+` FROM payouts p WHERE p.batch_id = $1 ORDER BY p.created_at, p.seq`, batchID)
+	if err != nil {
+		return fmt.Errorf("query batch payouts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Payout
+		if err := scanPayout(rows, &p); err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetPayout retrieves a single payout by ID.
+func (r *Repository) GetPayout(ctx context.Context, payoutID uuid.UUID) (*models.Payout, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+payoutColumns+` FROM payouts p WHERE p.id = $1`, payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+	defer rows.Close()
+
 	payouts, err := scanPayouts(rows)
-	return payouts, totalCount, err
+	if err != nil {
+		return nil, err
+	}
+	if len(payouts) == 0 {
+		return nil, nil
+	}
+	return &payouts[0], nil
 }
 
 // GetBatchStatistics returns detailed statistics for a batch.
@@ -267,29 +1081,141 @@ func (r *Repository) GetBatchStatistics(ctx context.Context, batchID uuid.UUID)
 		SELECT
 			COUNT(*) as total,
 			COUNT(*) FILTER (WHERE status = 'completed') as completed,
-			COUNT(*) FILTER (WHERE status = 'failed') as failed,
+			COUNT(*) FILTER (WHERE status IN ('failed', 'written_off', 'cancelled')) as failed,
 			COUNT(*) FILTER (WHERE status = 'pending') as pending,
-			COUNT(*) FILTER (WHERE status = 'processing') as processing
+			COUNT(*) FILTER (WHERE status = 'processing') as processing,
+			COUNT(*) FILTER (WHERE status = 'failed' AND superseded_by IS NOT NULL) as superseded,
+			COUNT(*) FILTER (WHERE status = 'written_off') as written_off,
+			COUNT(*) FILTER (WHERE status = 'cancelled') as cancelled,
+			COUNT(*) FILTER (WHERE status = 'pending' AND held_at IS NOT NULL) as held
 		FROM payouts WHERE batch_id = $1`, batchID,
-	).Scan(&stats.Total, &stats.Completed, &stats.Failed, &stats.Pending, &stats.Processing)
+	).Scan(&stats.Total, &stats.Completed, &stats.Failed, &stats.Pending, &stats.Processing,
+		&stats.Superseded, &stats.WrittenOff, &stats.Cancelled, &stats.Held)
 	if err != nil {
 		return nil, err
 	}
 
-	if stats.Total > 0 {
-		stats.SuccessRate = float64(stats.Completed) / float64(stats.Total) * 100
-		processed := stats.Completed + stats.Failed
-		stats.CompletionRate = float64(processed) / float64(stats.Total) * 100
+	l := &stats.Logical
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE n > 1),
+			COUNT(*) FILTER (WHERE completed = n),
+			COUNT(*) FILTER (WHERE completed > 0 AND failed > 0 AND completed + failed = n),
+			COUNT(*) FILTER (WHERE failed = n),
+			COUNT(*) FILTER (WHERE completed + failed < n)
+		FROM (
+			SELECT COUNT(*) AS n,
+			       COUNT(*) FILTER (WHERE status = 'completed') AS completed,
+			       COUNT(*) FILTER (WHERE status IN ('failed', 'written_off', 'cancelled')) AS failed
+			FROM payouts WHERE batch_id = $1
+			GROUP BY COALESCE(split_group_id, id)
+		) logical`, batchID,
+	).Scan(&l.Total, &l.Split, &l.Completed, &l.PartiallyCompleted, &l.Failed, &l.Unfinished)
+	if err != nil {
+		return nil, fmt.Errorf("query logical statistics: %w", err)
 	}
+
+	stats.ComputeRates()
 	return stats, nil
 }
 
-// ResetStuckProcessing resets payouts stuck in "processing" back to "pending" (for crash recovery).
+// GetSplitPayouts returns the payouts of a split group in account order.
+func (r *Repository) GetSplitPayouts(ctx context.Context, groupID uuid.UUID) ([]models.Payout, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+payoutColumns+` FROM payouts p WHERE p.split_group_id = $1 ORDER BY p.seq`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("query split payouts: %w", err)
+	}
+	defer rows.Close()
+	return scanPayouts(rows)
+}
+
+// GetBatchFinancials returns gross, disbursed, pending, failed, superseded,
+// written-off and cancelled amounts per currency.
+func (r *Repository) GetBatchFinancials(ctx context.Context, batchID uuid.UUID) ([]models.CurrencyTotals, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			currency,
+			COUNT(*),
+			COALESCE(SUM(amount), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'completed'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status IN ('pending', 'processing')), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'failed' AND superseded_by IS NULL), 0),
+			COALESCE(SUM(amount) FILTER (WHERE superseded_by IS NOT NULL), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'written_off'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'cancelled'), 0)
+		FROM payouts WHERE batch_id = $1
+		GROUP BY currency ORDER BY currency`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("query batch financials: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []models.CurrencyTotals{}
+	for rows.Next() {
+		var t models.CurrencyTotals
+		if err := rows.Scan(&t.Currency, &t.PayoutCount, &t.GrossAmount, &t.DisbursedAmount, &t.PendingAmount, &t.FailedAmount, &t.SupersededAmount, &t.WrittenOffAmount, &t.CancelledAmount); err != nil {
+			return nil, fmt.Errorf("scan batch financials: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// segmentColumns maps group_by values backed by real columns; any other value
+// is looked up as a key in the payout metadata.
+var segmentColumns = map[string]string{
+	"currency":  "currency",
+	"bank_name": "bank_name",
+}
+
+// GetSegmentStatistics returns batch statistics grouped by a vendor attribute.
+// Payouts without the attribute are reported under an empty segment.
+func (r *Repository) GetSegmentStatistics(ctx context.Context, batchID uuid.UUID, groupBy string) ([]models.SegmentStatistics, error) {
+	expr := "metadata->>$2"
+	args := []any{batchID, groupBy}
+	if col, ok := segmentColumns[groupBy]; ok {
+		expr = col
+		args = args[:1]
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			COALESCE(`+expr+`, '') as segment,
+			COUNT(*) as total,
+			COUNT(*) FILTER (WHERE status = 'completed') as completed,
+			COUNT(*) FILTER (WHERE status IN ('failed', 'written_off', 'cancelled')) as failed,
+			COUNT(*) FILTER (WHERE status = 'pending') as pending,
+			COUNT(*) FILTER (WHERE status = 'processing') as processing
+		FROM payouts WHERE batch_id = $1
+		GROUP BY 1 ORDER BY 1`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query segment statistics: %w", err)
+	}
+	defer rows.Close()
+
+	segments := []models.SegmentStatistics{}
+	for rows.Next() {
+		var seg models.SegmentStatistics
+		if err := rows.Scan(&seg.Segment, &seg.Total, &seg.Completed, &seg.Failed, &seg.Pending, &seg.Processing); err != nil {
+			return nil, fmt.Errorf("scan segment statistics: %w", err)
+		}
+		seg.ComputeRates()
+		segments = append(segments, seg)
+	}
+	return segments, rows.Err()
+}
+
+// ResetStuckProcessing resets payouts stuck in "processing" back to
+// "pending" (for crash recovery), or to "cancelled" in a cancelled batch.
+// Payouts whose claim lease is still live are in flight on another
+// instance and left alone; see claimLeaseExpired.
 func (r *Repository) ResetStuckProcessing(ctx context.Context, batchID uuid.UUID) (int64, error) {
 	result, err := r.db.ExecContext(ctx,
-		`UPDATE payouts SET status = $1, updated_at = NOW()
-		 WHERE batch_id = $2 AND status = $3 AND attempt_count < max_retries`,
-		models.PayoutStatusPending, batchID, models.PayoutStatusProcessing,
+		`UPDATE payouts SET status = `+releasedStatus+`, updated_at = $3, claimed_by = NULL, lease_expires_at = NULL
+		 WHERE batch_id = $1 AND status = $2 AND attempt_count < max_retries AND `+claimLeaseExpired("$3"),
+		batchID, models.PayoutStatusProcessing, r.now(),
 	)
 	if err != nil {
 		return 0, err
@@ -297,14 +1223,30 @@ func (r *Repository) ResetStuckProcessing(ctx context.Context, batchID uuid.UUID
 	return result.RowsAffected()
 }
 
-// RetryFailedPayouts resets retryable failed payouts back to pending.
+// CountInFlight counts the payouts in processing across all batches, for
+// the worker's governor.
+func (r *Repository) CountInFlight(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM payouts WHERE status = $1`, models.PayoutStatusProcessing,
+	).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count in-flight payouts: %w", err)
+	}
+	return n, nil
+}
+
+// RetryFailedPayouts resets retryable failed payouts back to pending with a
+// fresh attempt budget, that of the batch's retry policy if it has one; the
+// worker only fails a retryable payout once its budget is used up, so
+// without one the retry would fail immediately.
 func (r *Repository) RetryFailedPayouts(ctx context.Context, batchID uuid.UUID) (int64, error) {
 	result, err := r.db.ExecContext(ctx,
-		`UPDATE payouts SET status = $1, failure_reason = NULL, updated_at = NOW()
-		 WHERE batch_id = $2 AND status = $3 AND attempt_count < max_retries
+		`UPDATE payouts SET status = $1, failure_reason = NULL, max_retries = attempt_count + `+batchAttempts("$7")+`, updated_at = $8
+		 WHERE batch_id = $2 AND status = $3 AND superseded_by IS NULL
 		 AND failure_reason IN ($4, $5, $6)`,
 		models.PayoutStatusPending, batchID, models.PayoutStatusFailed,
 		models.FailureBankTimeout, models.FailureRateLimited, models.FailureInsufficientFunds,
+		models.DefaultMaxRetries, r.now(),
 	)
 	if err != nil {
 		return 0, err
@@ -312,36 +1254,503 @@ func (r *Repository) RetryFailedPayouts(ctx context.Context, batchID uuid.UUID)
 	return result.RowsAffected()
 }
 
+// batchAttempts is, in an UPDATE of payouts, the attempt budget of each
+// payout's batch: the max_attempts of its retry policy, or def, the query
+// parameter holding the default.
+func batchAttempts(def string) string {
+	return `COALESCE((SELECT NULLIF((pb.retry_policy->>'max_attempts')::int, 0) FROM payout_batches pb WHERE pb.id = payouts.batch_id), ` + def + `)`
+}
+
+// --- Reporting ---
+
+// GetOverview gathers system-wide batch and payout figures. The processor
+// state is filled in by the caller.
+func (r *Repository) GetOverview(ctx context.Context) (*models.SystemOverview, error) {
+	overview := &models.SystemOverview{
+		BatchesByStatus: map[string]int{},
+		GeneratedAt:     r.now(),
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM payout_batches GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("query batches by status: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan batch status count: %w", err)
+		}
+		overview.BatchesByStatus[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FILTER (WHERE status = $1), COUNT(*) FILTER (WHERE status = $2)
+		 FROM payouts WHERE status IN ($1, $2)`,
+		models.PayoutStatusPending, models.PayoutStatusProcessing,
+	).Scan(&overview.PendingPayouts, &overview.ProcessingPayouts)
+	if err != nil {
+		return nil, fmt.Errorf("count unfinished payouts: %w", err)
+	}
+
+	overview.InFlight, err = r.GetInFlightExposure(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM payout_attempts WHERE finished_at >= $1`,
+		overview.GeneratedAt.Add(-time.Minute),
+	).Scan(&overview.ThroughputPerMinute)
+	if err != nil {
+		return nil, fmt.Errorf("count recent attempts: %w", err)
+	}
+
+	return overview, nil
+}
+
+// GetInFlightExposure returns the amount currently in processing per currency:
+// transfers handed to the bank whose outcome has not been recorded yet.
+func (r *Repository) GetInFlightExposure(ctx context.Context) ([]models.CurrencyExposure, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT currency, COUNT(*), COALESCE(SUM(amount), 0), MIN(attempted_at)
+		 FROM payouts WHERE status = $1
+		 GROUP BY currency ORDER BY currency`,
+		models.PayoutStatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("query in-flight exposure: %w", err)
+	}
+	defer rows.Close()
+
+	exposure := []models.CurrencyExposure{}
+	for rows.Next() {
+		var e models.CurrencyExposure
+		if err := rows.Scan(&e.Currency, &e.PayoutCount, &e.Amount, &e.OldestSince); err != nil {
+			return nil, fmt.Errorf("scan exposure: %w", err)
+		}
+		exposure = append(exposure, e)
+	}
+	return exposure, rows.Err()
+}
+
+// ListUnfinishedPayouts returns pending and processing payouts by bank, for
+// one batch or, with uuid.Nil, across all batches.
+func (r *Repository) ListUnfinishedPayouts(ctx context.Context, batchID uuid.UUID) ([]models.SettlementPayout, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, batch_id, vendor_id, COALESCE(vendor_name, ''), COALESCE(bank_name, ''), amount, currency, status
+		 FROM payouts
+		 WHERE status IN ($1, $2) AND ($3 = '00000000-0000-0000-0000-000000000000'::uuid OR batch_id = $3)
+		 ORDER BY bank_name, created_at, seq`,
+		models.PayoutStatusPending, models.PayoutStatusProcessing, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("query unfinished payouts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := []models.SettlementPayout{}
+	for rows.Next() {
+		var p models.SettlementPayout
+		if err := rows.Scan(&p.ID, &p.BatchID, &p.VendorID, &p.VendorName, &p.BankName, &p.Amount, &p.Currency, &p.Status); err != nil {
+			return nil, fmt.Errorf("scan unfinished payout: %w", err)
+		}
+		payouts = append(payouts, p)
+	}
+	return payouts, rows.Err()
+}
+
+// GetBankVolumes returns a batch's pending and processing payouts counted
+// and summed per bank and currency.
+func (r *Repository) GetBankVolumes(ctx context.Context, batchID uuid.UUID) ([]models.BankVolume, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT COALESCE(bank_name, ''), currency, COUNT(*), COALESCE(SUM(amount), 0)
+		 FROM payouts WHERE batch_id = $1 AND status IN ($2, $3)
+		 GROUP BY 1, 2 ORDER BY 1, 2`,
+		batchID, models.PayoutStatusPending, models.PayoutStatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("query bank volumes: %w", err)
+	}
+	defer rows.Close()
+
+	volumes := []models.BankVolume{}
+	for rows.Next() {
+		var v models.BankVolume
+		if err := rows.Scan(&v.BankName, &v.Currency, &v.PayoutCount, &v.Amount); err != nil {
+			return nil, fmt.Errorf("scan bank volume: %w", err)
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, rows.Err()
+}
+
+// SearchVendors finds vendors whose name contains or closely resembles query.
+// Prefix matches rank first, then trigram similarity.
+func (r *Repository) SearchVendors(ctx context.Context, query string, limit int) ([]models.VendorMatch, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT vendor_id, MAX(vendor_name), COUNT(*), MAX(created_at),
+		       MAX(similarity(vendor_name, $1)) AS score,
+		       BOOL_OR(vendor_name ILIKE $2 || '%') AS prefix
+		FROM payouts
+		WHERE vendor_name ILIKE '%' || $2 || '%' OR vendor_name % $1
+		GROUP BY vendor_id
+		ORDER BY prefix DESC, score DESC, MAX(vendor_name) ASC
+		LIMIT $3`, query, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search vendors: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []models.VendorMatch{}
+	for rows.Next() {
+		var m models.VendorMatch
+		var prefix bool
+		if err := rows.Scan(&m.VendorID, &m.VendorName, &m.PayoutCount, &m.LastPayoutAt, &m.Score, &prefix); err != nil {
+			return nil, fmt.Errorf("scan vendor match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// --- Run Tracking ---
+
+// runLockClass namespaces the advisory locks that mark live runs of a batch.
+const runLockClass = 7301
+
+// RunLock marks a processing run of a batch as live for as long as it is
+// held. It is a PostgreSQL session advisory lock on a dedicated connection,
+// so it disappears with the process if it dies. Live runs hold it shared;
+// crash recovery needs it exclusively.
+type RunLock struct {
+	release func()
+}
+
+// NewRunLock returns a RunLock that calls release when released, for stores
+// that keep track of live runs themselves.
+func NewRunLock(release func()) *RunLock {
+	return &RunLock{release: release}
+}
+
+// LockRun takes the run lock for a batch. If no other live run holds it,
+// recoverStuck is called first with exclusive access — the only time it is safe
+// to reset payouts stuck in processing, since any such payout was claimed by
+// a run that no longer exists. Otherwise LockRun joins the live runs without
+// calling recoverStuck.
+func (r *Repository) LockRun(ctx context.Context, batchID uuid.UUID, recoverStuck func() error) (*RunLock, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("run lock connection: %w", err)
+	}
+	key := batchID.String()
+	// Session locks outlive conn.Close (it only returns the connection to the
+	// pool), so unlock explicitly.
+	lock := NewRunLock(func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock_shared($1, hashtext($2))`, runLockClass, key)
+		conn.Close()
+	})
+
+	var exclusive bool
+	if err := conn.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_lock($1, hashtext($2))`, runLockClass, key,
+	).Scan(&exclusive); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("try run lock: %w", err)
+	}
+
+	if exclusive {
+		if err := recoverStuck(); err != nil {
+			conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1, hashtext($2))`, runLockClass, key)
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	// Blocks while another run is recovering the batch.
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock_shared($1, hashtext($2))`, runLockClass, key); err != nil {
+		if exclusive {
+			conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1, hashtext($2))`, runLockClass, key)
+		}
+		conn.Close()
+		return nil, fmt.Errorf("share run lock: %w", err)
+	}
+	if exclusive {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1, hashtext($2))`, runLockClass, key); err != nil {
+			lock.Release()
+			return nil, fmt.Errorf("downgrade run lock: %w", err)
+		}
+	}
+	return lock, nil
+}
+
+// Release ends the run's hold on the batch.
+func (l *RunLock) Release() {
+	l.release()
+}
+
+// ErrEnvironmentMismatch is returned by PinEnvironment when a batch was
+// executed in a different bank environment.
+var ErrEnvironmentMismatch = errors.New("batch was executed in another bank environment")
+
+// PinEnvironment records the bank environment executing a batch, the first
+// time it runs, and returns ErrEnvironmentMismatch if an earlier run used a
+// different one, so sandbox batches are never carried on with real money
+// (or the other way round).
+func (r *Repository) PinEnvironment(ctx context.Context, batchID uuid.UUID, environment string) error {
+	var pinned string
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE payout_batches SET environment = COALESCE(environment, $2) WHERE id = $1 RETURNING environment`,
+		batchID, environment,
+	).Scan(&pinned)
+	if err != nil {
+		return fmt.Errorf("pin environment: %w", err)
+	}
+	if pinned != environment {
+		return fmt.Errorf("%w: %s, not %s", ErrEnvironmentMismatch, pinned, environment)
+	}
+	return nil
+}
+
+// CreateRun records the start of a processing run for a batch in the given
+// bank environment.
+func (r *Repository) CreateRun(ctx context.Context, batchID uuid.UUID, trigger, triggeredBy, environment string, settings models.RunSettings) (*models.BatchRun, error) {
+	run := &models.BatchRun{
+		ID:          uuid.New(),
+		BatchID:     batchID,
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Environment: environment,
+		Status:      models.RunStatusRunning,
+		StartedAt:   r.now(),
+		RunSettings: settings,
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO batch_runs (id, batch_id, trigger, triggered_by, environment, status, started_at, concurrency, chunk_size)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		run.ID, run.BatchID, run.Trigger, run.TriggeredBy, run.Environment, run.Status, run.StartedAt,
+		run.Concurrency, run.ChunkSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert run: %w", err)
+	}
+	return run, r.journal(ctx, audit.KindRunStarted, batchID, run)
+}
+
+// FinishRun records the outcome and counts of a processing run, and adds the
+// run to the throughput model.
+func (r *Repository) FinishRun(ctx context.Context, run *models.BatchRun) error {
+	now := r.now()
+	run.FinishedAt = &now
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE batch_runs SET status = $1, chunks_processed = $2, processed_count = $3, completed_count = $4,
+		        failed_count = $5, error = $6, finished_at = $7
+		 WHERE id = $8`,
+		run.Status, run.ChunksCount, run.ProcessedCount, run.CompletedCount,
+		run.FailedCount, run.Error, now, run.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := recordThroughput(ctx, tx, run); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return r.journal(ctx, audit.KindRunFinished, run.BatchID, run)
+}
+
+// ListRuns returns all processing runs of a batch, oldest first.
+func (r *Repository) ListRuns(ctx context.Context, batchID uuid.UUID) ([]models.BatchRun, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, batch_id, trigger, triggered_by, COALESCE(environment, ''), status, chunks_processed, processed_count,
+		        completed_count, failed_count, error, started_at, finished_at,
+		        COALESCE(concurrency, 0), COALESCE(chunk_size, 0)
+		 FROM batch_runs WHERE batch_id = $1
+		 ORDER BY started_at ASC`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("query runs: %w", err)
+	}
+	defer rows.Close()
+
+	now := r.now()
+	runs := []models.BatchRun{}
+	for rows.Next() {
+		var run models.BatchRun
+		err := rows.Scan(
+			&run.ID, &run.BatchID, &run.Trigger, &run.TriggeredBy, &run.Environment, &run.Status, &run.ChunksCount,
+			&run.ProcessedCount, &run.CompletedCount, &run.FailedCount, &run.Error,
+			&run.StartedAt, &run.FinishedAt, &run.Concurrency, &run.ChunkSize,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan run: %w", err)
+		}
+		end := now
+		if run.FinishedAt != nil {
+			end = *run.FinishedAt
+		}
+		run.DurationSeconds = end.Sub(run.StartedAt).Seconds()
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
 // --- Attempt Logging ---
 
-// LogAttempt records a payout attempt for audit.
+// LogAttempt records a payout attempt for audit. An attempt already
+// recorded is not inserted again, so a failed write can be retried; it is
+// journaled again, though.
 func (r *Repository) LogAttempt(ctx context.Context, attempt *models.PayoutAttempt) error {
 	_, err := r.db.ExecContext(ctx,
 		`INSERT INTO payout_attempts (id, payout_id, attempt_num, status, error, started_at, finished_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (id) DO NOTHING`,
 		attempt.ID, attempt.PayoutID, attempt.AttemptNum, attempt.Status, attempt.Error,
 		attempt.StartedAt, attempt.FinishedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return r.journal(ctx, audit.KindPayoutAttempt, attempt.PayoutID, attempt)
+}
+
+// GetPayoutAttempts returns the attempt history for a payout, oldest first.
+func (r *Repository) GetPayoutAttempts(ctx context.Context, payoutID uuid.UUID) ([]models.PayoutAttempt, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, payout_id, attempt_num, status, error, started_at, finished_at
+		 FROM payout_attempts WHERE payout_id = $1
+		 ORDER BY attempt_num ASC, started_at ASC`, payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("query attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.PayoutAttempt{}
+	for rows.Next() {
+		var a models.PayoutAttempt
+		if err := rows.Scan(&a.ID, &a.PayoutID, &a.AttemptNum, &a.Status, &a.Error, &a.StartedAt, &a.FinishedAt); err != nil {
+			return nil, fmt.Errorf("scan attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
 }
 
 // --- Helpers ---
 
+// batchColumns is the column list read by scanBatch, qualified with the "b" alias.
+const batchColumns = `b.id, b.status, b.total_count, b.completed_count, b.failed_count, b.pending_count,
+	b.payout_order, b.created_at, b.started_at, b.completed_at, b.updated_at, b.deleted_at, b.deleted_by,
+	b.owner, b.assigned_to, b.environment, b.progress_every, b.ingested_count, b.creation_error,
+	b.merchant_id, b.idempotency_key, b.request_hash, b.retry_policy, b.rejections`
+
+// scanBatch scans batchColumns into b.
+func scanBatch(row rowScanner, b *models.PayoutBatch) error {
+	var policy, rejections []byte
+	err := row.Scan(
+		&b.ID, &b.Status, &b.TotalCount, &b.CompletedCount, &b.FailedCount, &b.PendingCount,
+		&b.PayoutOrder, &b.CreatedAt, &b.StartedAt, &b.CompletedAt, &b.UpdatedAt, &b.DeletedAt, &b.DeletedBy,
+		&b.Owner, &b.AssignedTo, &b.Environment, &b.ProgressEvery, &b.IngestedCount, &b.CreationError,
+		&b.MerchantID, &b.IdempotencyKey, &b.RequestHash, &policy, &rejections,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("scan batch: %w", err)
+	}
+	if err == nil && len(policy) > 0 {
+		if err := json.Unmarshal(policy, &b.RetryPolicy); err != nil {
+			return fmt.Errorf("decode retry policy: %w", err)
+		}
+	}
+	if err == nil && len(rejections) > 0 {
+		if err := json.Unmarshal(rejections, &b.Rejections); err != nil {
+			return fmt.Errorf("decode rejections: %w", err)
+		}
+	}
+	return err
+}
+
+// payoutColumns is the column list read by scanPayout, qualified with the "p" alias.
+const payoutColumns = `p.id, p.batch_id, p.idempotency_key, p.vendor_id, p.vendor_name, p.amount, p.currency,
+	p.bank_account, p.bank_name, p.transaction_ids, p.status, p.failure_reason, p.attempt_count, p.max_retries,
+	p.created_at, p.attempted_at, p.completed_at, p.updated_at, p.metadata, p.split_group_id, p.split_percent,
+	p.supersedes, p.superseded_by, p.held_at, p.held_by, p.tags, p.next_retry_at,
+	p.reporting_country, p.reporting_approved_by, p.reporting_approved_at, p.purpose_code,
+	p.claimed_by, p.lease_expires_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// scanPayout scans payoutColumns into p, followed by any extra destinations.
+func scanPayout(row rowScanner, p *models.Payout, extra ...any) error {
+	var metadata []byte
+	dest := []any{
+		&p.ID, &p.BatchID, &p.IdempotencyKey, &p.VendorID, &p.VendorName,
+		&p.Amount, &p.Currency, &p.BankAccount, &p.BankName,
+		pq.Array(&p.TransactionIDs), &p.Status,
+		&p.FailureReason, &p.AttemptCount, &p.MaxRetries,
+		&p.CreatedAt, &p.AttemptedAt, &p.CompletedAt, &p.UpdatedAt, &metadata,
+		&p.SplitGroupID, &p.SplitPercent, &p.Supersedes, &p.SupersededBy,
+		&p.HeldAt, &p.HeldBy, pq.Array(&p.Tags), &p.NextRetryAt,
+		&p.ReportingCountry, &p.ReportingApprovedBy, &p.ReportingApprovedAt, &p.PurposeCode,
+		&p.ClaimedBy, &p.LeaseExpiresAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return fmt.Errorf("scan payout: %w", err)
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &p.Metadata); err != nil {
+			return fmt.Errorf("decode payout metadata: %w", err)
+		}
+	}
+	return nil
+}
+
 func scanPayouts(rows *sql.Rows) ([]models.Payout, error) {
 	var payouts []models.Payout
 	for rows.Next() {
 		var p models.Payout
-		err := rows.Scan(
-			&p.ID, &p.BatchID, &p.IdempotencyKey, &p.VendorID, &p.VendorName,
-			&p.Amount, &p.Currency, &p.BankAccount, &p.BankName,
-			pq.Array(&p.TransactionIDs), &p.Status,
-			&p.FailureReason, &p.AttemptCount, &p.MaxRetries,
-			&p.CreatedAt, &p.AttemptedAt, &p.CompletedAt, &p.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan payout: %w", err)
+		if err := scanPayout(rows, &p); err != nil {
+			return nil, err
 		}
 		payouts = append(payouts, p)
 	}
 	return payouts, rows.Err()
 }
+
+func scanPayoutListItems(rows *sql.Rows) ([]models.PayoutListItem, error) {
+	var items []models.PayoutListItem
+	for rows.Next() {
+		var it models.PayoutListItem
+		if err := scanPayout(rows, &it.Payout, &it.LastAttemptAt, &it.LastError); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// marshalMetadata encodes payout metadata for the JSONB column, storing an empty object for nil.
+// It returns a string because lib/pq sends []byte parameters as bytea.
+func marshalMetadata(m map[string]string) (string, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Statistics Snapshots ---
+
+// SnapshotStatistics records the batch's statistics as they stand now, for
+// run runID and the given reason (one of the models.Snapshot reasons).
+func (r *Repository) SnapshotStatistics(ctx context.Context, batchID, runID uuid.UUID, reason string) error {
+	stats, err := r.GetBatchStatistics(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("marshal statistics: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT INTO batch_statistics_snapshots (batch_id, run_id, taken_at, reason, batch_status, statistics)
+		 SELECT id, $2, $3, $4, status, $5 FROM payout_batches WHERE id = $1`,
+		batchID, runID, r.now(), reason, data); err != nil {
+		return fmt.Errorf("insert statistics snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetStatisticsSnapshot returns the batch's latest statistics snapshot taken
+// at or before at, or nil if there is none.
+func (r *Repository) GetStatisticsSnapshot(ctx context.Context, batchID uuid.UUID, at time.Time) (*models.StatisticsSnapshot, error) {
+	snap := &models.StatisticsSnapshot{BatchID: batchID}
+	var data []byte
+	err := r.db.QueryRowContext(ctx,
+		`SELECT run_id, taken_at, reason, batch_status, statistics FROM batch_statistics_snapshots
+		 WHERE batch_id = $1 AND taken_at <= $2
+		 ORDER BY taken_at DESC, id DESC LIMIT 1`,
+		batchID, at,
+	).Scan(&snap.RunID, &snap.TakenAt, &snap.Reason, &snap.BatchStatus, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get statistics snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &snap.Statistics); err != nil {
+		return nil, fmt.Errorf("decode statistics snapshot: %w", err)
+	}
+	return snap, nil
+}
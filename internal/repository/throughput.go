@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/models"
+)
+
+// --- Throughput Model ---
+
+// recordThroughput rolls up a finished run's attempts and final outcomes per
+// bank and currency into bank_throughput. Attempts of the batch started since
+// the run began belong to it, since only one run holds a batch at a time.
+func recordThroughput(ctx context.Context, tx *sql.Tx, run *models.BatchRun) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO bank_throughput (run_id, bank_name, currency, payouts, failed_payouts,
+		        attempts, attempt_seconds, run_seconds, finished_at)
+		 SELECT $1, COALESCE(p.bank_name, ''), p.currency,
+		        COUNT(DISTINCT p.id) FILTER (WHERE p.status IN ($5, $6) AND p.updated_at >= $3),
+		        COUNT(DISTINCT p.id) FILTER (WHERE p.status = $6 AND p.updated_at >= $3),
+		        COUNT(a.id),
+		        COALESCE(SUM(EXTRACT(EPOCH FROM (a.finished_at - a.started_at))), 0),
+		        EXTRACT(EPOCH FROM ($4::timestamptz - $3::timestamptz)), $4
+		 FROM payouts p
+		 JOIN payout_attempts a ON a.payout_id = p.id AND a.started_at >= $3 AND a.finished_at IS NOT NULL
+		 WHERE p.batch_id = $2
+		 GROUP BY 2, 3
+		 ON CONFLICT (run_id, bank_name, currency) DO NOTHING`,
+		run.ID, run.BatchID, run.StartedAt, *run.FinishedAt,
+		models.PayoutStatusCompleted, models.PayoutStatusFailed)
+	if err != nil {
+		return fmt.Errorf("record throughput: %w", err)
+	}
+	return nil
+}
+
+// GetThroughputModel returns, per bank and currency, the rollups of runs that
+// finished since the given time: payouts finished and failed, attempts made
+// and their average duration.
+func (r *Repository) GetThroughputModel(ctx context.Context, since time.Time) ([]models.BankHistory, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT bank_name, currency, SUM(payouts), SUM(failed_payouts), SUM(attempts),
+		        CASE WHEN SUM(attempts) > 0 THEN SUM(attempt_seconds) / SUM(attempts) ELSE 0 END
+		 FROM bank_throughput WHERE finished_at >= $1
+		 GROUP BY bank_name, currency ORDER BY bank_name, currency`, since)
+	if err != nil {
+		return nil, fmt.Errorf("query throughput model: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.BankHistory{}
+	for rows.Next() {
+		var h models.BankHistory
+		if err := rows.Scan(&h.BankName, &h.Currency, &h.Payouts, &h.FailedPayouts, &h.Attempts, &h.AvgAttemptSeconds); err != nil {
+			return nil, fmt.Errorf("scan throughput model: %w", err)
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"coding-challenge/internal/models"
+)
+
+// --- Usage Metering ---
+
+// RecordAPIUsage adds metered API calls to the per-merchant daily totals.
+func (r *Repository) RecordAPIUsage(ctx context.Context, usage []models.APIUsage) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, u := range usage {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO api_usage (merchant_id, day, calls, errors) VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (merchant_id, day) DO UPDATE SET
+			     calls = api_usage.calls + EXCLUDED.calls,
+			     errors = api_usage.errors + EXCLUDED.errors`,
+			u.MerchantID, u.Day.UTC().Format("2006-01-02"), u.Calls, u.Errors); err != nil {
+			return fmt.Errorf("record api usage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// GetUsageReport totals each merchant's API calls, the batches created for
+// it, and the payouts of those batches that completed or failed, with the
+// amounts paid per currency, between from and to (either may be nil).
+// merchant limits the report to one merchant; "" reports all of them.
+func (r *Repository) GetUsageReport(ctx context.Context, from, to *time.Time, merchant string) ([]models.MerchantUsage, error) {
+	byMerchant := map[string]*models.MerchantUsage{}
+	get := func(id string) *models.MerchantUsage {
+		u, ok := byMerchant[id]
+		if !ok {
+			u = &models.MerchantUsage{MerchantID: id, Amounts: []models.CurrencyAmount{}}
+			byMerchant[id] = u
+		}
+		return u
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT merchant_id, SUM(calls), SUM(errors) FROM api_usage
+		 WHERE ($1::timestamptz IS NULL OR day >= $1::date)
+		   AND ($2::timestamptz IS NULL OR day < $2::date)
+		   AND ($3 = '' OR merchant_id = $3)
+		 GROUP BY merchant_id`, from, to, merchant)
+	if err != nil {
+		return nil, fmt.Errorf("query api usage: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var calls, errs int64
+		if err := rows.Scan(&id, &calls, &errs); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan api usage: %w", err)
+		}
+		u := get(id)
+		u.APICalls, u.APIErrors = calls, errs
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query api usage: %w", err)
+	}
+
+	rows, err = r.db.QueryContext(ctx,
+		`SELECT merchant_id, COUNT(*) FROM payout_batches
+		 WHERE merchant_id IS NOT NULL
+		   AND ($1::timestamptz IS NULL OR created_at >= $1)
+		   AND ($2::timestamptz IS NULL OR created_at < $2)
+		   AND ($3 = '' OR merchant_id = $3)
+		 GROUP BY merchant_id`, from, to, merchant)
+	if err != nil {
+		return nil, fmt.Errorf("query batches created: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan batches created: %w", err)
+		}
+		get(id).BatchesCreated = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query batches created: %w", err)
+	}
+
+	rows, err = r.db.QueryContext(ctx,
+		`SELECT b.merchant_id, p.currency,
+		        COUNT(*) FILTER (WHERE p.status = $4),
+		        COUNT(*) FILTER (WHERE p.status = $5),
+		        COALESCE(SUM(p.amount) FILTER (WHERE p.status = $4), 0)
+		 FROM payouts p JOIN payout_batches b ON b.id = p.batch_id
+		 WHERE b.merchant_id IS NOT NULL AND p.status IN ($4, $5)
+		   AND ($1::timestamptz IS NULL OR COALESCE(p.completed_at, p.updated_at) >= $1)
+		   AND ($2::timestamptz IS NULL OR COALESCE(p.completed_at, p.updated_at) < $2)
+		   AND ($3 = '' OR b.merchant_id = $3)
+		 GROUP BY b.merchant_id, p.currency
+		 ORDER BY b.merchant_id, p.currency`,
+		from, to, merchant, models.PayoutStatusCompleted, models.PayoutStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("query payouts processed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var paid models.CurrencyAmount
+		var completed, failed int
+		if err := rows.Scan(&id, &paid.Currency, &completed, &failed, &paid.Amount); err != nil {
+			return nil, fmt.Errorf("scan payouts processed: %w", err)
+		}
+		u := get(id)
+		u.PayoutsCompleted += completed
+		u.PayoutsFailed += failed
+		if completed > 0 {
+			u.Amounts = append(u.Amounts, paid)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query payouts processed: %w", err)
+	}
+
+	usage := make([]models.MerchantUsage, 0, len(byMerchant))
+	for _, u := range byMerchant {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].MerchantID < usage[j].MerchantID })
+	return usage, nil
+}
+
+// --- Quotas ---
+
+// CountMerchantPayouts returns how many payouts a merchant submitted in
+// batches created since, for its monthly quota. Batches whose creation
+// failed do not count.
+func (r *Repository) CountMerchantPayouts(ctx context.Context, merchant string, since time.Time) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(total_count), 0) FROM payout_batches
+		 WHERE merchant_id = $1 AND created_at >= $2 AND creation_error IS NULL`,
+		merchant, since,
+	).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count merchant payouts: %w", err)
+	}
+	return n, nil
+}
+
+// CountMerchantRunning returns how many of a merchant's batches are in
+// progress, for its concurrent batch quota.
+func (r *Repository) CountMerchantRunning(ctx context.Context, merchant string) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM payout_batches WHERE merchant_id = $1 AND status = $2 AND deleted_at IS NULL`,
+		merchant, models.BatchStatusInProgress,
+	).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count merchant batches running: %w", err)
+	}
+	return n, nil
+}
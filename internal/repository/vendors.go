@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/taxid"
+
+	"github.com/google/uuid"
+)
+
+// --- Vendor Tax IDs ---
+
+// SetVendorTaxID records a vendor's tax ID, replacing any on file. taxID
+// must already be normalized by taxid.Normalize.
+func (r *Repository) SetVendorTaxID(ctx context.Context, vendorID, country, taxID, operator string) (*models.VendorTaxID, error) {
+	t := &models.VendorTaxID{VendorID: vendorID, Country: country, TaxID: taxID, UpdatedBy: operator, UpdatedAt: r.now()}
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT INTO vendor_tax_ids (vendor_id, country, tax_id, updated_by, updated_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (vendor_id) DO UPDATE
+		 SET country = EXCLUDED.country, tax_id = EXCLUDED.tax_id,
+		     updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		t.VendorID, t.Country, t.TaxID, t.UpdatedBy, t.UpdatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("save vendor tax ID: %w", err)
+	}
+	t.TaxID = taxid.Format(t.Country, t.TaxID)
+	return t, nil
+}
+
+// GetVendorTaxID returns a vendor's tax ID, or nil if none is on file.
+func (r *Repository) GetVendorTaxID(ctx context.Context, vendorID string) (*models.VendorTaxID, error) {
+	t := &models.VendorTaxID{}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT vendor_id, country, tax_id, updated_by, updated_at FROM vendor_tax_ids WHERE vendor_id = $1`, vendorID,
+	).Scan(&t.VendorID, &t.Country, &t.TaxID, &t.UpdatedBy, &t.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get vendor tax ID: %w", err)
+	}
+	t.TaxID = taxid.Format(t.Country, t.TaxID)
+	return t, nil
+}
+
+// BatchVendorTaxIDs returns the formatted tax IDs on file for a batch's
+// vendors, by vendor ID.
+func (r *Repository) BatchVendorTaxIDs(ctx context.Context, batchID uuid.UUID) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT t.vendor_id, t.country, t.tax_id FROM vendor_tax_ids t
+		 WHERE t.vendor_id IN (SELECT vendor_id FROM payouts WHERE batch_id = $1)`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("query batch vendor tax IDs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := map[string]string{}
+	for rows.Next() {
+		var vendorID, country, digits string
+		if err := rows.Scan(&vendorID, &country, &digits); err != nil {
+			return nil, fmt.Errorf("scan vendor tax ID: %w", err)
+		}
+		ids[vendorID] = taxid.Format(country, digits)
+	}
+	return ids, rows.Err()
+}
+
+// GetTaxSummary totals the payouts completed in [from, to) per vendor and
+// currency, with each vendor's tax ID if one is on file, largest amounts
+// first. Either bound may be nil.
+func (r *Repository) GetTaxSummary(ctx context.Context, from, to *time.Time) ([]models.TaxSummaryVendor, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.vendor_id, COALESCE(MAX(p.vendor_name), ''), COALESCE(t.country, ''), COALESCE(t.tax_id, ''),
+		       p.currency, COUNT(*), SUM(p.amount)
+		FROM payouts p
+		LEFT JOIN vendor_tax_ids t ON t.vendor_id = p.vendor_id
+		WHERE p.status = $1
+		  AND ($2::timestamptz IS NULL OR p.completed_at >= $2)
+		  AND ($3::timestamptz IS NULL OR p.completed_at < $3)
+		GROUP BY p.vendor_id, t.country, t.tax_id, p.currency
+		ORDER BY p.currency, SUM(p.amount) DESC, p.vendor_id`,
+		models.PayoutStatusCompleted, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query tax summary: %w", err)
+	}
+	defer rows.Close()
+
+	vendors := []models.TaxSummaryVendor{}
+	for rows.Next() {
+		var v models.TaxSummaryVendor
+		if err := rows.Scan(&v.VendorID, &v.VendorName, &v.Country, &v.TaxID, &v.Currency, &v.PayoutCount, &v.Amount); err != nil {
+			return nil, fmt.Errorf("scan tax summary: %w", err)
+		}
+		v.TaxID = taxid.Format(v.Country, v.TaxID)
+		vendors = append(vendors, v)
+	}
+	return vendors, rows.Err()
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// --- Payout Views ---
+
+const payoutViewColumns = `name, COALESCE(description, ''), filter, updated_by, created_at, updated_at`
+
+// SavePayoutView creates or replaces the payout view with v.Name.
+func (r *Repository) SavePayoutView(ctx context.Context, v models.PayoutView) (*models.PayoutView, error) {
+	filter, err := json.Marshal(v.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("marshal filter: %w", err)
+	}
+	now := r.now()
+	saved, err := scanPayoutView(r.db.QueryRowContext(ctx,
+		`INSERT INTO payout_views (name, description, filter, updated_by, created_at, updated_at)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5, $5)
+		 ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, filter = EXCLUDED.filter,
+		     updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		 RETURNING `+payoutViewColumns,
+		v.Name, v.Description, string(filter), v.UpdatedBy, now,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("save payout view: %w", err)
+	}
+	return saved, nil
+}
+
+// GetPayoutView returns the named payout view, or nil if it does not exist.
+func (r *Repository) GetPayoutView(ctx context.Context, name string) (*models.PayoutView, error) {
+	v, err := scanPayoutView(r.db.QueryRowContext(ctx,
+		`SELECT `+payoutViewColumns+` FROM payout_views WHERE name = $1`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payout view: %w", err)
+	}
+	return v, nil
+}
+
+// ListPayoutViews returns every payout view, by name.
+func (r *Repository) ListPayoutViews(ctx context.Context) ([]models.PayoutView, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+payoutViewColumns+` FROM payout_views ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("query payout views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []models.PayoutView{}
+	for rows.Next() {
+		v, err := scanPayoutView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan payout view: %w", err)
+		}
+		views = append(views, *v)
+	}
+	return views, rows.Err()
+}
+
+// DeletePayoutView removes the named view and reports whether it existed.
+func (r *Repository) DeletePayoutView(ctx context.Context, name string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM payout_views WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("delete payout view: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete payout view: %w", err)
+	}
+	return n > 0, nil
+}
+
+// payoutFilterWhere matches payouts p of live batches b against a filter
+// bound by payoutFilterArgs as $1-$11.
+const payoutFilterWhere = `b.deleted_at IS NULL
+	AND (cardinality($1::text[]) = 0 OR p.status = ANY($1))
+	AND (cardinality($2::text[]) = 0 OR p.currency = ANY($2))
+	AND (cardinality($3::text[]) = 0 OR p.failure_reason = ANY($3))
+	AND ($4::boolean IS NULL OR (p.failure_reason = ANY($5)) = $4)
+	AND ($6::numeric IS NULL OR p.amount >= $6)
+	AND ($7::numeric IS NULL OR p.amount <= $7)
+	AND (cardinality($8::text[]) = 0 OR p.bank_name = ANY($8))
+	AND p.tags @> $9::text[]
+	AND ($10::boolean IS NULL OR (p.held_at IS NOT NULL) = $10)
+	AND ($11::uuid IS NULL OR p.batch_id = $11)`
+
+func payoutFilterArgs(f models.PayoutFilter) []any {
+	return []any{
+		textArray(f.Statuses), textArray(f.Currencies), textArray(f.FailureReasons),
+		f.Retryable, textArray(models.RetryableFailures), f.MinAmount, f.MaxAmount,
+		textArray(f.BankNames), textArray(f.Tags), f.Held, f.BatchID,
+	}
+}
+
+// textArray binds s as a text array, empty rather than NULL when s is nil.
+func textArray(s []string) any {
+	if s == nil {
+		s = []string{}
+	}
+	return pq.Array(s)
+}
+
+// ListPayouts returns the payouts of every live batch that match f, oldest
+// first, paginated, with their last attempt.
+func (r *Repository) ListPayouts(ctx context.Context, f models.PayoutFilter, page, pageSize int) ([]models.PayoutListItem, int, error) {
+	args := payoutFilterArgs(f)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM payouts p JOIN payout_batches b ON b.id = p.batch_id WHERE `+payoutFilterWhere, args...,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count payouts: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+payoutColumns+`, a.started_at, a.error
+		 FROM payouts p
+		 JOIN payout_batches b ON b.id = p.batch_id
+		 LEFT JOIN LATERAL (
+		     SELECT started_at, error FROM payout_attempts
+		     WHERE payout_id = p.id ORDER BY attempt_num DESC LIMIT 1
+		 ) a ON true
+		 WHERE `+payoutFilterWhere+`
+		 ORDER BY p.created_at, p.id LIMIT $12 OFFSET $13`,
+		append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query payouts: %w", err)
+	}
+	defer rows.Close()
+
+	items, err := scanPayoutListItems(rows)
+	return items, total, err
+}
+
+// ListPayoutsAfter returns up to limit payouts of live batches matching f
+// that come after the cursor, oldest first, with their last attempt. A nil
+// cursor starts from the oldest.
+func (r *Repository) ListPayoutsAfter(ctx context.Context, f models.PayoutFilter, after *models.PageCursor, limit int) ([]models.PayoutListItem, error) {
+	afterAt, afterID := cursorArgs(after)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+payoutColumns+`, a.started_at, a.error
+		 FROM payouts p
+		 JOIN payout_batches b ON b.id = p.batch_id
+		 LEFT JOIN LATERAL (
+		     SELECT started_at, error FROM payout_attempts
+		     WHERE payout_id = p.id ORDER BY attempt_num DESC LIMIT 1
+		 ) a ON true
+		 WHERE `+payoutFilterWhere+`
+		   AND ($12::timestamptz IS NULL OR (p.created_at, p.id) > ($12, $13::uuid))
+		 ORDER BY p.created_at, p.id LIMIT $14`,
+		append(payoutFilterArgs(f), afterAt, afterID, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("query payouts: %w", err)
+	}
+	defer rows.Close()
+
+	items, err := scanPayoutListItems(rows)
+	if items == nil {
+		items = []models.PayoutListItem{}
+	}
+	return items, err
+}
+
+func scanPayoutView(row rowScanner) (*models.PayoutView, error) {
+	var v models.PayoutView
+	var filter []byte
+	if err := row.Scan(&v.Name, &v.Description, &filter, &v.UpdatedBy, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filter, &v.Filter); err != nil {
+		return nil, fmt.Errorf("decode payout view %s filter: %w", v.Name, err)
+	}
+	return &v, nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// --- Webhook Subscriptions ---
+
+const webhookColumns = `id, url, event_types, COALESCE(description, ''), secret, COALESCE(previous_secret, ''),
+	previous_secret_expires_at, active, created_by, created_at, updated_at`
+
+// CreateWebhook stores a new subscription, filling in its ID and timestamps.
+func (r *Repository) CreateWebhook(ctx context.Context, sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	now := r.now()
+	created, err := scanWebhook(r.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_subscriptions (id, url, event_types, description, secret, active, created_by, created_at, updated_at)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $8)
+		 RETURNING `+webhookColumns,
+		uuid.New(), sub.URL, pq.Array(sub.EventTypes), sub.Description, sub.Secret, sub.Active, sub.CreatedBy, now,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert webhook: %w", err)
+	}
+	return created, nil
+}
+
+// GetWebhook returns a subscription, or nil if it does not exist.
+func (r *Repository) GetWebhook(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	sub, err := scanWebhook(r.db.QueryRowContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook: %w", err)
+	}
+	return sub, nil
+}
+
+// ListWebhooks returns every subscription, oldest first.
+func (r *Repository) ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return r.queryWebhooks(ctx, `SELECT `+webhookColumns+` FROM webhook_subscriptions ORDER BY created_at, id`)
+}
+
+// ListActiveWebhooks returns the active subscriptions to eventType.
+func (r *Repository) ListActiveWebhooks(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	return r.queryWebhooks(ctx,
+		`SELECT `+webhookColumns+` FROM webhook_subscriptions
+		 WHERE active AND $1 = ANY(event_types) ORDER BY created_at, id`, eventType)
+}
+
+func (r *Repository) queryWebhooks(ctx context.Context, query string, args ...any) ([]models.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// UpdateWebhook applies the fields set in req and returns the subscription,
+// or nil if it does not exist.
+func (r *Repository) UpdateWebhook(ctx context.Context, id uuid.UUID, req models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	var eventTypes any
+	if req.EventTypes != nil {
+		eventTypes = pq.Array(req.EventTypes)
+	}
+	sub, err := scanWebhook(r.db.QueryRowContext(ctx,
+		`UPDATE webhook_subscriptions
+		 SET url = COALESCE($2, url), event_types = COALESCE($3, event_types),
+		     description = CASE WHEN $4::text IS NULL THEN description ELSE NULLIF($4, '') END,
+		     active = COALESCE($5, active), updated_at = $6
+		 WHERE id = $1
+		 RETURNING `+webhookColumns,
+		id, req.URL, eventTypes, req.Description, req.Active, r.now(),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update webhook: %w", err)
+	}
+	return sub, nil
+}
+
+// RotateWebhookSecret replaces a subscription's secret, keeping the old one
+// valid for grace so events are signed with both meanwhile. It returns nil
+// if the subscription does not exist.
+func (r *Repository) RotateWebhookSecret(ctx context.Context, id uuid.UUID, secret string, grace time.Duration) (*models.WebhookSubscription, error) {
+	now := r.now()
+	sub, err := scanWebhook(r.db.QueryRowContext(ctx,
+		`UPDATE webhook_subscriptions
+		 SET previous_secret = secret, previous_secret_expires_at = $3, secret = $2, updated_at = $4
+		 WHERE id = $1
+		 RETURNING `+webhookColumns,
+		id, secret, now.Add(grace), now,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rotate webhook secret: %w", err)
+	}
+	return sub, nil
+}
+
+// DeleteWebhook removes a subscription with its delivery history and
+// reports whether it existed.
+func (r *Repository) DeleteWebhook(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("delete webhook: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete webhook: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RecordWebhookDelivery stores one attempt to deliver an event.
+func (r *Repository) RecordWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, status, response_code, error, duration_ms, attempted_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		d.ID, d.SubscriptionID, d.EventID, d.EventType, d.Status, d.ResponseCode, d.Error, d.DurationMs, d.AttemptedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns a subscription's most recent delivery
+// attempts, newest first.
+func (r *Repository) ListWebhookDeliveries(ctx context.Context, id uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, subscription_id, event_id, event_type, status, response_code, error, duration_ms, attempted_at
+		 FROM webhook_deliveries WHERE subscription_id = $1
+		 ORDER BY attempted_at DESC, id LIMIT $2`, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status,
+			&d.ResponseCode, &d.Error, &d.DurationMs, &d.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookStats summarises a subscription's delivery attempts.
+func (r *Repository) GetWebhookStats(ctx context.Context, id uuid.UUID) (*models.WebhookStats, error) {
+	var s models.WebhookStats
+	var avg sql.NullFloat64
+	var lastStatus sql.NullString
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+		        COUNT(*) FILTER (WHERE status = $2),
+		        COUNT(*) FILTER (WHERE status = $3),
+		        COUNT(*) FILTER (WHERE status = $4),
+		        AVG(duration_ms) FILTER (WHERE status <> $4),
+		        MAX(attempted_at),
+		        (ARRAY_AGG(status ORDER BY attempted_at DESC))[1],
+		        (ARRAY_AGG(error ORDER BY attempted_at DESC))[1]
+		 FROM webhook_deliveries WHERE subscription_id = $1`,
+		id, models.WebhookStatusSent, models.WebhookStatusFailed, models.WebhookStatusDropped,
+	).Scan(&s.Deliveries, &s.Sent, &s.Failed, &s.Dropped, &avg, &s.LastAttemptAt, &lastStatus, &s.LastError); err != nil {
+		return nil, fmt.Errorf("webhook stats: %w", err)
+	}
+	s.AvgDurationMs = avg.Float64
+	s.LastStatus = lastStatus.String
+	if s.Deliveries > 0 {
+		s.SuccessRate = float64(s.Sent) / float64(s.Deliveries) * 100
+	}
+	return &s, nil
+}
+
+func scanWebhook(row rowScanner) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	if err := row.Scan(&s.ID, &s.URL, pq.Array(&s.EventTypes), &s.Description, &s.Secret, &s.PreviousSecret,
+		&s.PreviousSecretExpiresAt, &s.Active, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
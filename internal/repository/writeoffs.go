@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// --- Write-offs ---
+
+// ErrPayoutNotWriteOffable is returned when writing off a payout that is not
+// failed, was requeued elsewhere, or belongs to a deleted batch.
+var ErrPayoutNotWriteOffable = errors.New("payout is not failed or was requeued")
+
+// WriteOffPayout closes out a failed payout as written_off and records the
+// write-off. The batch's counters are unchanged, since they count written-off
+// payouts as failed. It returns nil if the payout does not exist.
+func (r *Repository) WriteOffPayout(ctx context.Context, payoutID uuid.UUID, req models.WriteOffRequest, requestedBy string) (*models.PayoutWriteOff, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	w := &models.PayoutWriteOff{
+		PayoutID:     payoutID,
+		ReasonCode:   req.ReasonCode,
+		Note:         req.Note,
+		RequestedBy:  requestedBy,
+		ApprovedBy:   req.ApprovedBy,
+		WrittenOffAt: r.now(),
+	}
+	var status string
+	var superseded, deleted bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT p.status, p.superseded_by IS NOT NULL, b.deleted_at IS NOT NULL, p.amount, p.currency
+		 FROM payouts p JOIN payout_batches b ON b.id = p.batch_id
+		 WHERE p.id = $1 FOR UPDATE OF p`, payoutID,
+	).Scan(&status, &superseded, &deleted, &w.Amount, &w.Currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payout: %w", err)
+	}
+	if status != models.PayoutStatusFailed || superseded || deleted {
+		return nil, ErrPayoutNotWriteOffable
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE payouts SET status = $1, updated_at = $2 WHERE id = $3`,
+		models.PayoutStatusWrittenOff, w.WrittenOffAt, payoutID); err != nil {
+		return nil, fmt.Errorf("write off payout: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO payout_write_offs (payout_id, reason_code, note, requested_by, approved_by, amount, currency, written_off_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)`,
+		w.PayoutID, w.ReasonCode, w.Note, w.RequestedBy, w.ApprovedBy, w.Amount, w.Currency, w.WrittenOffAt,
+	); err != nil {
+		return nil, fmt.Errorf("insert write-off: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return w, r.journal(ctx, audit.KindPayoutWrittenOff, payoutID, w)
+}
+
+// GetWriteOff returns a payout's write-off, or nil if it was not written off.
+func (r *Repository) GetWriteOff(ctx context.Context, payoutID uuid.UUID) (*models.PayoutWriteOff, error) {
+	w := &models.PayoutWriteOff{}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT payout_id, reason_code, COALESCE(note, ''), requested_by, approved_by, amount, currency, written_off_at
+		 FROM payout_write_offs WHERE payout_id = $1`, payoutID,
+	).Scan(&w.PayoutID, &w.ReasonCode, &w.Note, &w.RequestedBy, &w.ApprovedBy, &w.Amount, &w.Currency, &w.WrittenOffAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get write-off: %w", err)
+	}
+	return w, nil
+}
+
+// GetWriteOffTotals sums write-offs per interval (day, week or month, in
+// UTC) and currency, optionally limited to [from, to).
+func (r *Repository) GetWriteOffTotals(ctx context.Context, interval string, from, to *time.Time) ([]models.WriteOffPeriod, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT date_trunc($1, written_off_at AT TIME ZONE 'UTC') AS period, currency, COUNT(*), SUM(amount)
+		FROM payout_write_offs
+		WHERE ($2::timestamptz IS NULL OR written_off_at >= $2)
+		  AND ($3::timestamptz IS NULL OR written_off_at < $3)
+		GROUP BY 1, 2 ORDER BY 1, 2`, interval, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query write-off totals: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.WriteOffPeriod{}
+	for rows.Next() {
+		var p models.WriteOffPeriod
+		if err := rows.Scan(&p.PeriodStart, &p.Currency, &p.PayoutCount, &p.Amount); err != nil {
+			return nil, fmt.Errorf("scan write-off totals: %w", err)
+		}
+		p.PeriodStart = p.PeriodStart.UTC()
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
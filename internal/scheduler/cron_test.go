@@ -0,0 +1,51 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"coding-challenge/internal/scheduler"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, 3, 1, 9, 7, 30, 0, time.UTC) // a Friday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 1, 9, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 1, 9, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, 3, 1, 9, 25, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 3, 2, 2, 0, 0, 0, time.UTC)},
+		{"30 8-10 * * *", time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 1,3", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 1", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)}, // day of month or of week
+		{"@hourly", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, tc := range cases {
+		s, err := scheduler.ParseSchedule(tc.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", tc.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("Expected %q to run next at %s, got %s", tc.expr, tc.want, got)
+		}
+	}
+
+	if s, err := scheduler.ParseSchedule("0 0 30 2 *"); err != nil || !s.Next(from).IsZero() {
+		t.Errorf("Expected a schedule that never matches to have no next run, got %v", err)
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 10ms", "@often"} {
+		if _, err := scheduler.ParseSchedule(expr); err == nil {
+			t.Errorf("Expected %q to be refused", expr)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package scheduler runs background jobs in process on cron schedules,
+// records every run, and lets operators trigger a job by hand. Each
+// instance runs its own scheduler, so a job that must run once per
+// deployment is enabled on one instance only.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownJob is returned for a job name that was never added.
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrJobRunning is returned when a job is triggered while it runs.
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// Store persists the run history of jobs.
+type Store interface {
+	CreateJobRun(ctx context.Context, run *models.JobRun) error
+	FinishJobRun(ctx context.Context, run *models.JobRun) error
+	ListJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error)
+}
+
+// Job is a background task run on a schedule.
+type Job struct {
+	Name string
+	// Schedule is a cron expression; see ParseSchedule.
+	Schedule string
+	// Enabled jobs run on their schedule; disabled ones only when triggered.
+	Enabled bool
+	Run     func(ctx context.Context) error
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+	next     time.Time
+	running  bool
+}
+
+// Scheduler runs jobs on their schedules. A job still running when it is
+// due again is skipped for that time, not run twice.
+type Scheduler struct {
+	store Store
+	clock clock.Clock
+
+	mu   sync.Mutex
+	ctx  context.Context // that of Run, for the runs it starts and triggered ones
+	jobs []*entry
+	wg   sync.WaitGroup
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithClock sets the clock schedules are followed on; the default is the
+// system clock.
+func WithClock(c clock.Clock) Option {
+	return func(s *Scheduler) { s.clock = c }
+}
+
+// New creates a scheduler recording runs in store.
+func New(store Store, opts ...Option) *Scheduler {
+	s := &Scheduler{store: store, clock: clock.Real, ctx: context.Background()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add registers a job. Jobs are added before Run.
+func (s *Scheduler) Add(job Job) error {
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(job.Name) != nil {
+		return fmt.Errorf("job %s added twice", job.Name)
+	}
+	s.jobs = append(s.jobs, &entry{job: job, schedule: schedule})
+	return nil
+}
+
+func (s *Scheduler) find(name string) *entry {
+	for _, e := range s.jobs {
+		if e.job.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Run starts enabled jobs as they fall due until ctx is cancelled, then
+// waits for the runs in progress to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	now := s.clock.Now()
+	for _, e := range s.jobs {
+		if e.job.Enabled {
+			e.next = e.schedule.Next(now)
+			log.Printf("[scheduler] Job %s scheduled %q, next run at %s", e.job.Name, e.job.Schedule, e.next.UTC().Format(time.RFC3339))
+		}
+	}
+	s.mu.Unlock()
+	defer s.wg.Wait()
+
+	for {
+		next, ok := s.nextDue()
+		if !ok {
+			<-ctx.Done()
+			return
+		}
+		timer := s.clock.NewTimer(next.Sub(s.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		s.startDue()
+	}
+}
+
+// nextDue returns the earliest next run time among enabled jobs.
+func (s *Scheduler) nextDue() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next time.Time
+	for _, e := range s.jobs {
+		if e.job.Enabled && !e.next.IsZero() && (next.IsZero() || e.next.Before(next)) {
+			next = e.next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// startDue starts the enabled jobs whose time has come and schedules their
+// next runs.
+func (s *Scheduler) startDue() {
+	now := s.clock.Now()
+	s.mu.Lock()
+	var due []*entry
+	for _, e := range s.jobs {
+		if e.job.Enabled && !e.next.IsZero() && !e.next.After(now) {
+			due = append(due, e)
+			e.next = e.schedule.Next(now)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, e := range due {
+		if _, err := s.start(e, models.JobTriggerSchedule, ""); err != nil {
+			log.Printf("[scheduler] Skipping job %s: %v", e.job.Name, err)
+		}
+	}
+}
+
+// Trigger starts a run of the named job now, whether or not it is enabled,
+// and returns it without waiting for it to finish.
+func (s *Scheduler) Trigger(name, by string) (*models.JobRun, error) {
+	s.mu.Lock()
+	e := s.find(name)
+	s.mu.Unlock()
+	if e == nil {
+		return nil, ErrUnknownJob
+	}
+	return s.start(e, models.JobTriggerManual, by)
+}
+
+func (s *Scheduler) start(e *entry, trigger, by string) (*models.JobRun, error) {
+	s.mu.Lock()
+	if e.running {
+		s.mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	e.running = true
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	run := &models.JobRun{
+		ID:          uuid.New(),
+		Job:         e.job.Name,
+		Trigger:     trigger,
+		TriggeredBy: by,
+		Status:      models.JobRunRunning,
+		StartedAt:   s.clock.Now(),
+	}
+	if err := s.store.CreateJobRun(ctx, run); err != nil {
+		s.done(e)
+		return nil, fmt.Errorf("record job run: %w", err)
+	}
+
+	started := *run
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.done(e)
+
+		err := s.execute(ctx, e.job)
+		finished := s.clock.Now()
+		run.FinishedAt = &finished
+		run.Status = models.JobRunSucceeded
+		if err != nil {
+			msg := err.Error()
+			run.Status, run.Error = models.JobRunFailed, &msg
+			log.Printf("[scheduler] Job %s failed: %v", e.job.Name, err)
+		}
+		// Recorded even when ctx was cancelled by shutdown mid-run.
+		if err := s.store.FinishJobRun(context.WithoutCancel(ctx), run); err != nil {
+			log.Printf("[scheduler] Error recording the end of job %s run %s: %v", e.job.Name, run.ID, err)
+		}
+	}()
+	return &started, nil
+}
+
+// execute runs a job, turning a panic into its error.
+func (s *Scheduler) execute(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) done(e *entry) {
+	s.mu.Lock()
+	e.running = false
+	s.mu.Unlock()
+}
+
+// Runs returns up to limit of the named job's runs, newest first.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]models.JobRun, error) {
+	s.mu.Lock()
+	e := s.find(name)
+	s.mu.Unlock()
+	if e == nil {
+		return nil, ErrUnknownJob
+	}
+	runs, err := s.store.ListJobRuns(ctx, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("runs of job %s: %w", name, err)
+	}
+	return runs, nil
+}
+
+// Wait blocks until no job runs.
+func (s *Scheduler) Wait() { s.wg.Wait() }
+
+// Jobs describes every job in the order added, with its most recent run.
+func (s *Scheduler) Jobs(ctx context.Context) ([]models.ScheduledJob, error) {
+	s.mu.Lock()
+	jobs := make([]models.ScheduledJob, len(s.jobs))
+	for i, e := range s.jobs {
+		jobs[i] = models.ScheduledJob{
+			Name:     e.job.Name,
+			Schedule: e.job.Schedule,
+			Enabled:  e.job.Enabled,
+			Running:  e.running,
+		}
+		if e.job.Enabled && !e.next.IsZero() {
+			next := e.next
+			jobs[i].NextRunAt = &next
+		}
+	}
+	s.mu.Unlock()
+
+	for i := range jobs {
+		runs, err := s.store.ListJobRuns(ctx, jobs[i].Name, 1)
+		if err != nil {
+			return nil, fmt.Errorf("last run of job %s: %w", jobs[i].Name, err)
+		}
+		if len(runs) > 0 {
+			jobs[i].LastRun = &runs[0]
+		}
+	}
+	return jobs, nil
+}
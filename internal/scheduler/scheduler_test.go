@@ -0,0 +1,102 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/scheduler"
+)
+
+var epoch = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// TestSchedulerRunsDueJobs verifies an enabled job runs each time it falls
+// due and its runs are recorded with their outcome, that a disabled job
+// never runs on schedule but can be triggered, and that a job is not started
+// again while it runs.
+func TestSchedulerRunsDueJobs(t *testing.T) {
+	c := clock.NewFake(epoch)
+	store := memstore.New()
+	s := scheduler.New(store, scheduler.WithClock(c))
+
+	ran := make(chan struct{}, 10)
+	release := make(chan struct{})
+	if err := s.Add(scheduler.Job{Name: "sweep", Schedule: "*/5 * * * *", Enabled: true, Run: func(context.Context) error {
+		ran <- struct{}{}
+		return errors.New("bank unavailable")
+	}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.Add(scheduler.Job{Name: "archive", Schedule: "@hourly", Run: func(context.Context) error {
+		<-release
+		return nil
+	}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.Add(scheduler.Job{Name: "sweep", Schedule: "@daily"}); err == nil {
+		t.Error("Expected a second job of the same name to be refused")
+	}
+	if err := s.Add(scheduler.Job{Name: "bad", Schedule: "every day"}); err == nil {
+		t.Error("Expected a bad schedule to be refused")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(stopped)
+	}()
+
+	for i := 0; i < 2; i++ {
+		c.BlockUntil(1)
+		c.Advance(5 * time.Minute)
+		<-ran
+	}
+	s.Wait()
+
+	runs, _ := store.ListJobRuns(context.Background(), "sweep", 10)
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 runs of sweep, got %d", len(runs))
+	}
+	if r := runs[0]; r.Trigger != models.JobTriggerSchedule || r.Status != models.JobRunFailed ||
+		r.Error == nil || *r.Error != "bank unavailable" || r.FinishedAt == nil || !r.StartedAt.Equal(epoch.Add(10*time.Minute)) {
+		t.Errorf("Expected a failed scheduled run at 09:10, got %+v", r)
+	}
+
+	jobs, err := s.Jobs(context.Background())
+	if err != nil {
+		t.Fatalf("Jobs failed: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].NextRunAt == nil || !jobs[0].NextRunAt.Equal(epoch.Add(15*time.Minute)) || jobs[0].LastRun == nil {
+		t.Errorf("Expected sweep next due at 09:15 with its last run, got %+v", jobs)
+	}
+	if archive := jobs[1]; archive.Enabled || archive.NextRunAt != nil || archive.LastRun != nil {
+		t.Errorf("Expected archive disabled and never run, got %+v", archive)
+	}
+
+	run, err := s.Trigger("archive", "ops@example.com")
+	if err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+	if run.Trigger != models.JobTriggerManual || run.TriggeredBy != "ops@example.com" || run.Status != models.JobRunRunning {
+		t.Errorf("Expected a running manual run, got %+v", run)
+	}
+	if _, err := s.Trigger("archive", "ops@example.com"); !errors.Is(err, scheduler.ErrJobRunning) {
+		t.Errorf("Expected ErrJobRunning while archive runs, got %v", err)
+	}
+	if _, err := s.Trigger("reconcile", ""); !errors.Is(err, scheduler.ErrUnknownJob) {
+		t.Errorf("Expected ErrUnknownJob, got %v", err)
+	}
+	close(release)
+	s.Wait()
+	if runs, _ := store.ListJobRuns(context.Background(), "archive", 1); len(runs) != 1 || runs[0].Status != models.JobRunSucceeded {
+		t.Errorf("Expected the triggered run to succeed, got %+v", runs)
+	}
+
+	cancel()
+	<-stopped
+}
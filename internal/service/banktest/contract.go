@@ -0,0 +1,152 @@
+// Package banktest provides a conformance suite that every service.BankClient
+// implementation must pass before it is wired into the worker pool.
+package banktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// knownFailureCodes are the failure codes the worker understands.
+var knownFailureCodes = map[string]bool{
+	models.FailureInvalidBankAccount: true,
+	models.FailureInsufficientFunds:  true,
+	models.FailureBankTimeout:        true,
+	models.FailureAccountBlocked:     true,
+	models.FailureRateLimited:        true,
+}
+
+// sampleSize is how many transfers are made when checking result shape.
+const sampleSize = 200
+
+// returnWithin bounds how long a client may keep running after its context ends.
+const returnWithin = time.Second
+
+// RunContract runs the BankClient conformance suite. newClient is called once
+// per subtest and must return a fresh client; clients that sleep to simulate
+// latency should be configured with a small latency to keep the suite fast.
+func RunContract(t *testing.T, newClient func(t *testing.T) service.BankClient) {
+	t.Run("IdempotentSubmit", func(t *testing.T) { testIdempotentSubmit(t, newClient(t)) })
+	t.Run("ErrorMapping", func(t *testing.T) { testErrorMapping(t, newClient(t)) })
+	t.Run("ContextCancellation", func(t *testing.T) { testContextCancellation(t, newClient(t)) })
+	t.Run("Timeout", func(t *testing.T) { testTimeout(t, newClient(t)) })
+}
+
+// testIdempotentSubmit checks that resubmitting a successful transfer reports
+// success again instead of being re-evaluated.
+func testIdempotentSubmit(t *testing.T, client service.BankClient) {
+	ctx := context.Background()
+
+	var settled *models.Payout
+	for i := 0; i < sampleSize && settled == nil; i++ {
+		p := newPayout(i)
+		result, err := client.Transfer(ctx, p)
+		if err != nil {
+			t.Fatalf("Unexpected error on transfer %d: %v", i, err)
+		}
+		if result.Success {
+			settled = &p
+		}
+	}
+	if settled == nil {
+		t.Fatalf("Expected at least one successful transfer in %d attempts", sampleSize)
+	}
+
+	for i := 0; i < 5; i++ {
+		result, err := client.Transfer(ctx, *settled)
+		if err != nil {
+			t.Fatalf("Unexpected error on resubmit %d: %v", i, err)
+		}
+		if !result.Success {
+			t.Errorf("Expected resubmit %d of a settled transfer to succeed, got %s", i, result.FailureCode)
+		}
+	}
+}
+
+// testErrorMapping checks that declines use known failure codes and that
+// retryability matches models.IsRetryable.
+func testErrorMapping(t *testing.T, client service.BankClient) {
+	ctx := context.Background()
+
+	for i := 0; i < sampleSize; i++ {
+		result, err := client.Transfer(ctx, newPayout(i))
+		if err != nil {
+			t.Fatalf("Unexpected error on transfer %d: %v", i, err)
+		}
+		if result.Success {
+			if result.FailureCode != "" {
+				t.Errorf("Expected no failure code on success, got %s", result.FailureCode)
+			}
+			continue
+		}
+		if !knownFailureCodes[result.FailureCode] {
+			t.Errorf("Expected a known failure code, got %q", result.FailureCode)
+			continue
+		}
+		if result.IsRetryable != models.IsRetryable(result.FailureCode) {
+			t.Errorf("Expected IsRetryable=%v for %s, got %v",
+				models.IsRetryable(result.FailureCode), result.FailureCode, result.IsRetryable)
+		}
+	}
+}
+
+// testContextCancellation checks that a cancelled context yields an error
+// rather than a bank outcome.
+func testContextCancellation(t *testing.T, client service.BankClient) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := client.Transfer(ctx, newPayout(0))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got err=%v result=%+v", err, result)
+	}
+	if result.Success {
+		t.Error("Expected no success for a cancelled transfer")
+	}
+}
+
+// testTimeout checks that the client honours the context deadline and
+// returns promptly once it has passed.
+func testTimeout(t *testing.T, client service.BankClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	if _, err := client.Transfer(ctx, newPayout(0)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded for an expired deadline, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Transfer(ctx, newPayout(1))
+	if elapsed := time.Since(start); elapsed > returnWithin {
+		t.Fatalf("Expected transfer to return within %s of its deadline, took %s", returnWithin, elapsed)
+	}
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected nil or context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// newPayout builds a payout with a unique idempotency key.
+func newPayout(i int) models.Payout {
+	id := uuid.New()
+	return models.Payout{
+		ID:             id,
+		VendorID:       fmt.Sprintf("CONTRACT-%03d", i),
+		VendorName:     "Contract Vendor",
+		Amount:         100,
+		Currency:       "IDR",
+		BankAccount:    "ID****0001",
+		BankName:       "BCA",
+		IdempotencyKey: "contract-" + id.String(),
+	}
+}
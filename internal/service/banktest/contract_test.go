@@ -0,0 +1,48 @@
+package banktest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/service/banktest"
+)
+
+func TestSimulatorContract(t *testing.T) {
+	banktest.RunContract(t, func(t *testing.T) service.BankClient {
+		return service.NewSimulator(service.UniformLatency{Min: 0, Max: time.Millisecond}, nil)
+	})
+}
+
+func TestScenarioContract(t *testing.T) {
+	banktest.RunContract(t, func(t *testing.T) service.BankClient {
+		sc := service.NewScenario()
+		sc.For(service.VendorPattern(`5$`)).Fail(models.FailureBankTimeout, 1).ThenSucceed()
+		sc.For(service.VendorPattern(`7$`)).Always(models.FailureAccountBlocked)
+		return sc
+	})
+}
+
+func TestSimulatorAbandonsStalledTransfer(t *testing.T) {
+	// Every call stalls far past the deadline.
+	sim := service.NewSimulator(service.HeavyTailLatency{
+		Base:        service.UniformLatency{},
+		StallChance: 1,
+		Stall:       time.Minute,
+	}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := sim.Transfer(ctx, models.Payout{VendorID: "V1", IdempotencyKey: "stalled"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected transfer to be abandoned at the deadline, took %s", elapsed)
+	}
+}
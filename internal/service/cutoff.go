@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BankCutoffs holds each bank's daily settlement cutoff in one time zone.
+// Transfers submitted before a bank's cutoff on a weekday settle that day;
+// later ones settle on the next weekday. Public holidays are not modelled.
+// A nil *BankCutoffs has no cutoffs configured. It is safe for concurrent
+// use, including Replace while reports are being built.
+type BankCutoffs struct {
+	mu    sync.RWMutex
+	loc   *time.Location
+	times map[string]time.Duration // offset from local midnight
+}
+
+// Replace swaps in the cutoffs and time zone of other, e.g. on a config reload.
+func (c *BankCutoffs) Replace(other *BankCutoffs) {
+	other.mu.RLock()
+	loc, times := other.loc, other.times
+	other.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loc, c.times = loc, times
+}
+
+// ParseBankCutoffs parses per-bank cutoffs in the form "BCA=15:00,BDO=14:30",
+// interpreted in loc.
+func ParseBankCutoffs(spec string, loc *time.Location) (*BankCutoffs, error) {
+	c := &BankCutoffs{loc: loc, times: map[string]time.Duration{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		bank, clock, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid bank cutoff entry %q (want BANK=HH:MM)", entry)
+		}
+		at, err := time.Parse("15:04", strings.TrimSpace(clock))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cutoff time in %q: %w", entry, err)
+		}
+		c.times[strings.TrimSpace(bank)] = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+	return c, nil
+}
+
+// Location returns the time zone cutoffs are expressed in (UTC if none is configured).
+func (c *BankCutoffs) Location() *time.Location {
+	if c == nil {
+		return time.UTC
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.location()
+}
+
+func (c *BankCutoffs) location() *time.Location {
+	if c.loc == nil {
+		return time.UTC
+	}
+	return c.loc
+}
+
+// Cutoff returns the bank's cutoff as "HH:MM", if one is configured.
+func (c *BankCutoffs) Cutoff(bank string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	d, ok := c.times[bank]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60), true
+}
+
+// ExpectedSettlement returns the local date on which a transfer to bank
+// submitted at t settles. It reports false if the bank has no cutoff.
+func (c *BankCutoffs) ExpectedSettlement(bank string, t time.Time) (time.Time, bool) {
+	if c == nil {
+		return time.Time{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cutoff, ok := c.times[bank]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	loc := c.location()
+	local := t.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if !isWeekday(day) || local.Sub(day) >= cutoff {
+		day = day.AddDate(0, 0, 1)
+	}
+	for !isWeekday(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, true
+}
+
+func isWeekday(day time.Time) bool {
+	return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+}
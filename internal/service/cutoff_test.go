@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"coding-challenge/internal/service"
+)
+
+// TestExpectedSettlement verifies same-day settlement before the cutoff and
+// next-weekday settlement after it or on weekends.
+func TestExpectedSettlement(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	cutoffs, err := service.ParseBankCutoffs("BCA=15:00, BDO=09:30", jakarta)
+	if err != nil {
+		t.Fatalf("ParseBankCutoffs failed: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		bank string
+		at   time.Time
+		want string
+	}{
+		{"before cutoff", "BCA", time.Date(2026, 10, 14, 14, 59, 0, 0, jakarta), "2026-10-14"},
+		{"at cutoff", "BCA", time.Date(2026, 10, 14, 15, 0, 0, 0, jakarta), "2026-10-15"},
+		{"friday after cutoff", "BCA", time.Date(2026, 10, 16, 16, 0, 0, 0, jakarta), "2026-10-19"},
+		{"saturday", "BDO", time.Date(2026, 10, 17, 8, 0, 0, 0, jakarta), "2026-10-19"},
+		// 01:00 UTC is 08:00 in Jakarta, before the BDO cutoff.
+		{"utc input", "BDO", time.Date(2026, 10, 14, 1, 0, 0, 0, time.UTC), "2026-10-14"},
+	}
+	for _, tc := range cases {
+		got, ok := cutoffs.ExpectedSettlement(tc.bank, tc.at)
+		if !ok {
+			t.Fatalf("%s: expected a cutoff for %s", tc.name, tc.bank)
+		}
+		if got.Format("2006-01-02") != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got.Format("2006-01-02"))
+		}
+	}
+
+	if _, ok := cutoffs.ExpectedSettlement("Unknown Bank", time.Now()); ok {
+		t.Error("Expected no settlement date for a bank without a cutoff")
+	}
+	if cutoff, _ := cutoffs.Cutoff("BDO"); cutoff != "09:30" {
+		t.Errorf("Expected cutoff 09:30, got %q", cutoff)
+	}
+}
+
+// TestParseBankCutoffsRejectsBadEntries verifies malformed specs are errors.
+func TestParseBankCutoffsRejectsBadEntries(t *testing.T) {
+	for _, spec := range []string{"BCA", "BCA=3pm", "BCA=25:00"} {
+		if _, err := service.ParseBankCutoffs(spec, time.UTC); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"coding-challenge/internal/models"
+)
+
+// BankFees holds each bank's flat fee per completed transfer, charged in the
+// payout's currency. A nil *BankFees has no fees configured.
+type BankFees struct {
+	fees map[string]float64
+}
+
+// ParseBankFees parses per-bank fees in the form "BCA=2500,BDO=15".
+func ParseBankFees(spec string) (*BankFees, error) {
+	f := &BankFees{fees: map[string]float64{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		bank, amount, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid bank fee entry %q (want BANK=AMOUNT)", entry)
+		}
+		fee, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil || fee < 0 {
+			return nil, fmt.Errorf("invalid fee in %q: want an amount >= 0", entry)
+		}
+		f.fees[strings.TrimSpace(bank)] = fee
+	}
+	return f, nil
+}
+
+// Fee returns the bank's fee per completed transfer, if one is configured.
+func (f *BankFees) Fee(bank string) (float64, bool) {
+	if f == nil {
+		return 0, false
+	}
+	fee, ok := f.fees[bank]
+	return fee, ok
+}
+
+// EstimateBatch forecasts processing volumes at the given worker concurrency
+// from the throughput model. Each bank and currency uses its own history
+// where there is some, else the bank's across currencies, else that of all
+// banks together. The duration assumes every worker is busy for the whole
+// run, so it is a lower bound when a few slow banks dominate the tail.
+func EstimateBatch(volumes []models.BankVolume, history []models.BankHistory, concurrency int, fees *BankFees) models.BatchEstimate {
+	byCurrency := map[string]models.BankHistory{}
+	byBank := map[string]models.BankHistory{}
+	var all models.BankHistory
+	for _, h := range history {
+		byCurrency[h.BankName+"\x00"+h.Currency] = h
+		byBank[h.BankName] = combineHistory(byBank[h.BankName], h)
+		all = combineHistory(all, h)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	est := models.BatchEstimate{Concurrency: concurrency, ExpectedFees: []models.CurrencyAmount{}, Banks: []models.BankEstimate{}}
+	feesByCurrency := map[string]float64{}
+	var workSeconds, failures float64
+	for _, v := range volumes {
+		b := models.BankEstimate{BankName: v.BankName, Currency: v.Currency, PayoutCount: v.PayoutCount, Basis: models.EstimateBasisNone}
+		for _, basis := range []struct {
+			name string
+			h    models.BankHistory
+		}{
+			{models.EstimateBasisCurrency, byCurrency[v.BankName+"\x00"+v.Currency]},
+			{models.EstimateBasisBank, byBank[v.BankName]},
+			{models.EstimateBasisAll, all},
+		} {
+			if h := basis.h; h.Payouts > 0 {
+				b.Basis, b.HistoricalPayouts = basis.name, h.Payouts
+				b.FailureRate = float64(h.FailedPayouts) / float64(h.Payouts)
+				b.AttemptsPerPayout = float64(h.Attempts) / float64(h.Payouts)
+				b.AvgAttemptSeconds = h.AvgAttemptSeconds
+				break
+			}
+		}
+		b.ExpectedFailures = float64(v.PayoutCount) * b.FailureRate
+		est.PayoutCount += v.PayoutCount
+		failures += b.ExpectedFailures
+		workSeconds += float64(v.PayoutCount) * b.AttemptsPerPayout * b.AvgAttemptSeconds
+		if fee, ok := fees.Fee(v.BankName); ok {
+			feesByCurrency[v.Currency] += (float64(v.PayoutCount) - b.ExpectedFailures) * fee
+		}
+		est.Banks = append(est.Banks, b)
+	}
+
+	sort.Slice(est.Banks, func(i, j int) bool {
+		if est.Banks[i].BankName != est.Banks[j].BankName {
+			return est.Banks[i].BankName < est.Banks[j].BankName
+		}
+		return est.Banks[i].Currency < est.Banks[j].Currency
+	})
+	for currency, amount := range feesByCurrency {
+		est.ExpectedFees = append(est.ExpectedFees, models.CurrencyAmount{Currency: currency, Amount: amount})
+	}
+	sort.Slice(est.ExpectedFees, func(i, j int) bool { return est.ExpectedFees[i].Currency < est.ExpectedFees[j].Currency })
+
+	if all.Payouts > 0 {
+		duration := workSeconds / float64(concurrency)
+		est.ExpectedDurationSeconds = &duration
+		est.ExpectedFailures = &failures
+	}
+	return est
+}
+
+// combineHistory adds up two histories, weighting attempt durations by attempts.
+func combineHistory(a, b models.BankHistory) models.BankHistory {
+	out := models.BankHistory{
+		Payouts:       a.Payouts + b.Payouts,
+		FailedPayouts: a.FailedPayouts + b.FailedPayouts,
+		Attempts:      a.Attempts + b.Attempts,
+	}
+	if out.Attempts > 0 {
+		out.AvgAttemptSeconds = (a.AvgAttemptSeconds*float64(a.Attempts) + b.AvgAttemptSeconds*float64(b.Attempts)) / float64(out.Attempts)
+	}
+	return out
+}
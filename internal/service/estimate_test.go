@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"math"
+	"testing"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/service"
+)
+
+// TestEstimateBatch verifies each bank and currency's rates drive the
+// forecast, falling back to the bank's other currencies and then to all
+// banks, and that fees only count the payouts expected to complete.
+func TestEstimateBatch(t *testing.T) {
+	history := []models.BankHistory{
+		{BankName: "BCA", Currency: "IDR", Payouts: 100, FailedPayouts: 10, Attempts: 120, AvgAttemptSeconds: 0.5},
+		{BankName: "BDO", Currency: "PHP", Payouts: 100, FailedPayouts: 30, Attempts: 180, AvgAttemptSeconds: 2},
+	}
+	volumes := []models.BankVolume{
+		{BankName: "BCA", Currency: "IDR", PayoutCount: 50, Amount: 5000000},
+		{BankName: "BDO", Currency: "PHP", PayoutCount: 20, Amount: 20000},
+		{BankName: "BDO", Currency: "USD", PayoutCount: 10, Amount: 1000},
+		{BankName: "Maybank", Currency: "IDR", PayoutCount: 10, Amount: 100000},
+	}
+	fees, err := service.ParseBankFees("BCA=2500, BDO=15")
+	if err != nil {
+		t.Fatalf("ParseBankFees failed: %v", err)
+	}
+
+	est := service.EstimateBatch(volumes, history, 4, fees)
+
+	if est.PayoutCount != 90 || len(est.Banks) != 4 {
+		t.Fatalf("Expected 90 payouts in 4 bank currencies, got %d in %d", est.PayoutCount, len(est.Banks))
+	}
+	bca, bdoUSD, maybank := est.Banks[0], est.Banks[2], est.Banks[3]
+	if bca.Basis != models.EstimateBasisCurrency || bca.HistoricalPayouts != 100 {
+		t.Errorf("Expected BCA IDR to use its own history, got %+v", bca)
+	}
+	if bdoUSD.Currency != "USD" || bdoUSD.Basis != models.EstimateBasisBank || !near(bdoUSD.FailureRate, 0.3) {
+		t.Errorf("Expected BDO USD to use BDO's PHP history, got %+v", bdoUSD)
+	}
+	if maybank.Basis != models.EstimateBasisAll || maybank.HistoricalPayouts != 200 ||
+		!near(maybank.FailureRate, 0.2) || !near(maybank.AttemptsPerPayout, 1.5) {
+		t.Errorf("Expected Maybank to use the all-bank rates, got %+v", maybank)
+	}
+	// Pooled attempt time: (120*0.5 + 180*2) / 300 = 1.4s.
+	if !near(maybank.AvgAttemptSeconds, 1.4) {
+		t.Errorf("Expected a pooled attempt time of 1.4s, got %v", maybank.AvgAttemptSeconds)
+	}
+
+	// Failures: 50*0.1 + 20*0.3 + 10*0.3 + 10*0.2 = 16.
+	if est.ExpectedFailures == nil || !near(*est.ExpectedFailures, 16) {
+		t.Errorf("Expected 16 failures, got %v", est.ExpectedFailures)
+	}
+	// Work: 50*1.2*0.5 + 20*1.8*2 + 10*1.8*2 + 10*1.5*1.4 = 159s over 4 workers.
+	if est.ExpectedDurationSeconds == nil || !near(*est.ExpectedDurationSeconds, 159.0/4) {
+		t.Errorf("Expected %vs, got %v", 159.0/4, est.ExpectedDurationSeconds)
+	}
+
+	want := map[string]float64{"IDR": 45 * 2500, "PHP": 14 * 15, "USD": 7 * 15}
+	if len(est.ExpectedFees) != len(want) {
+		t.Fatalf("Expected fees in %d currencies, got %+v", len(want), est.ExpectedFees)
+	}
+	for _, fee := range est.ExpectedFees {
+		if !near(fee.Amount, want[fee.Currency]) {
+			t.Errorf("Expected %s fees %v, got %v", fee.Currency, want[fee.Currency], fee.Amount)
+		}
+	}
+}
+
+// TestEstimateWithoutHistory verifies no forecast is made from no data.
+func TestEstimateWithoutHistory(t *testing.T) {
+	est := service.EstimateBatch([]models.BankVolume{{BankName: "BCA", Currency: "IDR", PayoutCount: 5}}, nil, 10, nil)
+	if est.ExpectedDurationSeconds != nil || est.ExpectedFailures != nil || est.Banks[0].Basis != models.EstimateBasisNone {
+		t.Errorf("Expected no duration or failure forecast without history, got %+v", est)
+	}
+	if len(est.ExpectedFees) != 0 {
+		t.Errorf("Expected no fees without a fee schedule, got %+v", est.ExpectedFees)
+	}
+}
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
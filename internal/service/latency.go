@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// maxSimulatedLatency caps any single sampled latency.
+const maxSimulatedLatency = 30 * time.Second
+
+// LatencyProfile produces simulated bank API response times.
+type LatencyProfile interface {
+	Sample() time.Duration
+}
+
+// UniformLatency samples uniformly between Min and Max.
+type UniformLatency struct {
+	Min, Max time.Duration
+}
+
+// Sample implements LatencyProfile.
+func (u UniformLatency) Sample() time.Duration {
+	if u.Max <= u.Min {
+		return u.Min
+	}
+	return u.Min + time.Duration(rand.Int63n(int64(u.Max-u.Min)))
+}
+
+// LogNormalLatency samples a log-normal distribution around Median. Sigma
+// controls the spread; most real API latencies look like this.
+type LogNormalLatency struct {
+	Median time.Duration
+	Sigma  float64
+}
+
+// Sample implements LatencyProfile.
+func (l LogNormalLatency) Sample() time.Duration {
+	d := time.Duration(float64(l.Median) * math.Exp(l.Sigma*rand.NormFloat64()))
+	if d > maxSimulatedLatency {
+		return maxSimulatedLatency
+	}
+	return d
+}
+
+// HeavyTailLatency adds an occasional long stall on top of a base profile,
+// mimicking a bank API that mostly responds fast but sometimes hangs.
+type HeavyTailLatency struct {
+	Base        LatencyProfile
+	StallChance float64 // 0..1
+	Stall       time.Duration
+}
+
+// Sample implements LatencyProfile.
+func (h HeavyTailLatency) Sample() time.Duration {
+	d := h.Base.Sample()
+	if rand.Float64() < h.StallChance {
+		d += h.Stall
+	}
+	return d
+}
+
+// Named latency profiles selectable from configuration.
+var latencyProfiles = map[string]LatencyProfile{
+	"uniform":    UniformLatency{Min: 50 * time.Millisecond, Max: 500 * time.Millisecond},
+	"lognormal":  LogNormalLatency{Median: 150 * time.Millisecond, Sigma: 0.6},
+	"heavy_tail": HeavyTailLatency{Base: LogNormalLatency{Median: 150 * time.Millisecond, Sigma: 0.6}, StallChance: 0.02, Stall: 5 * time.Second},
+}
+
+// LatencyProfileByName returns a named profile: uniform, lognormal or heavy_tail.
+func LatencyProfileByName(name string) (LatencyProfile, error) {
+	p, ok := latencyProfiles[strings.TrimSpace(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown latency profile %q", name)
+	}
+	return p, nil
+}
+
+// ParseBankLatencyProfiles parses per-bank profiles in the form
+// "BCA:lognormal,BDO:heavy_tail".
+func ParseBankLatencyProfiles(spec string) (map[string]LatencyProfile, error) {
+	profiles := map[string]LatencyProfile{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		bank, name, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid bank latency entry %q (want BANK:profile)", entry)
+		}
+		p, err := LatencyProfileByName(name)
+		if err != nil {
+			return nil, err
+		}
+		profiles[strings.TrimSpace(bank)] = p
+	}
+	return profiles, nil
+}
@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/service"
+)
+
+// TestUniformLatencyBounds verifies uniform samples stay within [Min, Max).
+func TestUniformLatencyBounds(t *testing.T) {
+	u := service.UniformLatency{Min: 50 * time.Millisecond, Max: 500 * time.Millisecond}
+	for i := 0; i < 1000; i++ {
+		if d := u.Sample(); d < u.Min || d >= u.Max {
+			t.Fatalf("Sample %s outside [%s, %s)", d, u.Min, u.Max)
+		}
+	}
+}
+
+// TestHeavyTailStalls verifies stalls are added on top of the base latency.
+func TestHeavyTailStalls(t *testing.T) {
+	h := service.HeavyTailLatency{
+		Base:        service.UniformLatency{Min: time.Millisecond, Max: time.Millisecond},
+		StallChance: 1,
+		Stall:       5 * time.Second,
+	}
+	if d := h.Sample(); d != 5*time.Second+time.Millisecond {
+		t.Errorf("Expected stalled sample of 5.001s, got %s", d)
+	}
+}
+
+// TestParseBankLatencyProfiles verifies per-bank profile parsing.
+func TestParseBankLatencyProfiles(t *testing.T) {
+	profiles, err := service.ParseBankLatencyProfiles("BCA:lognormal, BDO:heavy_tail")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(profiles) != 2 || profiles["BCA"] == nil || profiles["BDO"] == nil {
+		t.Errorf("Expected profiles for BCA and BDO, got %v", profiles)
+	}
+
+	if _, err := service.ParseBankLatencyProfiles("BCA"); err == nil {
+		t.Error("Expected error for entry without profile")
+	}
+	if _, err := service.ParseBankLatencyProfiles("BCA:bogus"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+// TestLatencyScaleSkipsWaits verifies a zero scale returns immediately while
+// still reporting the simulated latency.
+func TestLatencyScaleSkipsWaits(t *testing.T) {
+	sim := service.NewSimulator(service.UniformLatency{Min: time.Second, Max: time.Second}, nil, service.WithLatencyScale(0))
+
+	start := time.Now()
+	result, err := sim.Transfer(context.Background(), models.Payout{})
+	if err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected no wait, took %s", elapsed)
+	}
+	if result.LatencyMs != 1000 {
+		t.Errorf("Expected reported latency 1000ms, got %d", result.LatencyMs)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"coding-challenge/internal/models"
+)
+
+// BankConfig selects and configures a bank adapter.
+type BankConfig struct {
+	Adapter string // registered adapter name
+	// Environment is models.EnvironmentSandbox (the default) or
+	// models.EnvironmentProduction, where transfers move real money.
+	Environment string
+	Credentials map[string]string // secrets such as API keys; never logged
+	Options     map[string]string // adapter-specific settings
+}
+
+// BankFactory builds a BankClient from its configuration. It should reject
+// unknown options and missing credentials rather than ignore them, and use
+// the sandbox or production endpoints of the provider as the environment
+// says, refusing an environment it cannot serve.
+type BankFactory func(cfg BankConfig) (BankClient, error)
+
+var (
+	banksMu sync.RWMutex
+	banks   = map[string]BankFactory{}
+)
+
+// RegisterBank makes a bank adapter available by name, typically from the
+// adapter package's init function; a blank import then enables it. It
+// panics if factory is nil or the name is already taken.
+func RegisterBank(name string, factory BankFactory) {
+	banksMu.Lock()
+	defer banksMu.Unlock()
+	if factory == nil {
+		panic("service: RegisterBank factory is nil")
+	}
+	if _, dup := banks[name]; dup {
+		panic("service: RegisterBank called twice for adapter " + name)
+	}
+	banks[name] = factory
+}
+
+// NewBank builds the bank adapter named in cfg, in the sandbox unless the
+// environment says otherwise.
+func NewBank(cfg BankConfig) (BankClient, error) {
+	switch cfg.Environment {
+	case "":
+		cfg.Environment = models.EnvironmentSandbox
+	case models.EnvironmentSandbox, models.EnvironmentProduction:
+	default:
+		return nil, fmt.Errorf("unknown bank environment %q (want %s or %s)",
+			cfg.Environment, models.EnvironmentSandbox, models.EnvironmentProduction)
+	}
+	banksMu.RLock()
+	factory, ok := banks[cfg.Adapter]
+	banksMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown bank adapter %q (want one of %s)", cfg.Adapter, strings.Join(Banks(), ", "))
+	}
+	client, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("bank adapter %s: %w", cfg.Adapter, err)
+	}
+	return client, nil
+}
+
+// Banks returns the names of the registered bank adapters, sorted.
+func Banks() []string {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+	names := make([]string, 0, len(banks))
+	for name := range banks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseBankSettings parses adapter options or credentials in the form
+// "key=value,key=value". Values may not contain commas.
+func ParseBankSettings(spec string) (map[string]string, error) {
+	settings := map[string]string{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid setting %q (want key=value)", entry)
+		}
+		settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return settings, nil
+}
+
+func init() {
+	RegisterBank("simulator", newSimulatorFromConfig)
+}
+
+// newSimulatorFromConfig builds the simulator from the options
+// latency_profile, bank_latency (per-bank profiles as "BCA:lognormal;BDO:heavy_tail")
+// and latency_scale. It takes no credentials and only runs in the sandbox,
+// so a production configuration can never pay out through it by mistake.
+func newSimulatorFromConfig(cfg BankConfig) (BankClient, error) {
+	if cfg.Environment == models.EnvironmentProduction {
+		return nil, fmt.Errorf("the simulator cannot run in %s", models.EnvironmentProduction)
+	}
+	if len(cfg.Credentials) > 0 {
+		return nil, fmt.Errorf("the simulator takes no credentials")
+	}
+	latency := latencyProfiles["uniform"]
+	var bankLatency map[string]LatencyProfile
+	var opts []SimulatorOption
+	for key, value := range cfg.Options {
+		switch key {
+		case "latency_profile":
+			p, err := LatencyProfileByName(value)
+			if err != nil {
+				return nil, err
+			}
+			latency = p
+		case "bank_latency":
+			p, err := ParseBankLatencyProfiles(strings.ReplaceAll(value, ";", ","))
+			if err != nil {
+				return nil, err
+			}
+			bankLatency = p
+		case "latency_scale":
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || f < 0 {
+				return nil, fmt.Errorf("latency_scale %q must be a factor >= 0", value)
+			}
+			opts = append(opts, WithLatencyScale(f))
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return NewSimulator(latency, bankLatency, opts...), nil
+}
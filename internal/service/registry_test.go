@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/service"
+)
+
+type stubBank struct{ apiKey string }
+
+func (stubBank) Transfer(ctx context.Context, payout models.Payout) (service.SimulatedBankResult, error) {
+	return service.SimulatedBankResult{Success: true}, nil
+}
+
+func init() {
+	service.RegisterBank("stub", func(cfg service.BankConfig) (service.BankClient, error) {
+		return stubBank{apiKey: cfg.Credentials["api_key"]}, nil
+	})
+}
+
+// TestNewBankFromConfig verifies registered adapters are built by name with
+// their credentials, and unknown names list the available adapters.
+func TestNewBankFromConfig(t *testing.T) {
+	client, err := service.NewBank(service.BankConfig{Adapter: "stub", Credentials: map[string]string{"api_key": "k1"}})
+	if err != nil {
+		t.Fatalf("NewBank failed: %v", err)
+	}
+	if stub, ok := client.(stubBank); !ok || stub.apiKey != "k1" {
+		t.Errorf("Expected the stub adapter with its api key, got %#v", client)
+	}
+
+	_, err = service.NewBank(service.BankConfig{Adapter: "bogus"})
+	if err == nil || !strings.Contains(err.Error(), "simulator") || !strings.Contains(err.Error(), "stub") {
+		t.Errorf("Expected an error listing the registered adapters, got %v", err)
+	}
+}
+
+// TestBankEnvironments verifies adapters are built in the sandbox or in
+// production, and that the simulator refuses production and credentials.
+func TestBankEnvironments(t *testing.T) {
+	if _, err := service.NewBank(service.BankConfig{Adapter: "stub", Environment: models.EnvironmentProduction}); err != nil {
+		t.Errorf("Expected the stub adapter in production, got %v", err)
+	}
+	if _, err := service.NewBank(service.BankConfig{Adapter: "simulator", Environment: models.EnvironmentSandbox}); err != nil {
+		t.Errorf("Expected the simulator in the sandbox, got %v", err)
+	}
+
+	cases := []struct {
+		cfg  service.BankConfig
+		want string
+	}{
+		{service.BankConfig{Adapter: "stub", Environment: "staging"}, "unknown bank environment"},
+		{service.BankConfig{Adapter: "simulator", Environment: models.EnvironmentProduction}, "cannot run in production"},
+		{service.BankConfig{Adapter: "simulator", Credentials: map[string]string{"api_key": "live"}}, "no credentials"},
+	}
+	for _, tc := range cases {
+		if _, err := service.NewBank(tc.cfg); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Expected %q, got %v", tc.want, err)
+		}
+	}
+}
+
+// TestRegisterBankTwicePanics verifies adapter names cannot be taken over.
+func TestRegisterBankTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a duplicate registration to panic")
+		}
+	}()
+	service.RegisterBank("simulator", func(service.BankConfig) (service.BankClient, error) { return nil, nil })
+}
+
+// TestSimulatorFromOptions verifies the built-in simulator is configured
+// from options and rejects unknown ones.
+func TestSimulatorFromOptions(t *testing.T) {
+	opts, err := service.ParseBankSettings("latency_profile=lognormal, bank_latency=BCA:heavy_tail;BDO:uniform, latency_scale=0")
+	if err != nil {
+		t.Fatalf("ParseBankSettings failed: %v", err)
+	}
+	client, err := service.NewBank(service.BankConfig{Adapter: "simulator", Options: opts})
+	if err != nil {
+		t.Fatalf("NewBank failed: %v", err)
+	}
+	if _, ok := client.(*service.Simulator); !ok {
+		t.Errorf("Expected a *Simulator, got %T", client)
+	}
+
+	for _, bad := range []map[string]string{
+		{"latency_profile": "bogus"},
+		{"latency_scale": "-1"},
+		{"colour": "blue"},
+	} {
+		if _, err := service.NewBank(service.BankConfig{Adapter: "simulator", Options: bad}); err == nil {
+			t.Errorf("Expected options %v to be rejected", bad)
+		}
+	}
+	if _, err := service.ParseBankSettings("novalue"); err == nil {
+		t.Error("Expected a setting without = to be rejected")
+	}
+}
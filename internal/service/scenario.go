@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"coding-challenge/internal/models"
+)
+
+// Scenario is a deterministic BankClient for tests. Outcomes are scripted per
+// vendor instead of drawn at random, so tests can assert exact end states:
+//
+//	sc := service.NewScenario()
+//	sc.For(service.VendorRange("vendor_%04d", 10, 15)).Fail(models.FailureBankTimeout, 2).ThenSucceed()
+//	sc.For(service.Vendors("vendor_0042")).Always(models.FailureAccountBlocked)
+//
+// Vendors not matched by any rule always succeed. Rules are checked in the
+// order they were added.
+type Scenario struct {
+	mu       sync.Mutex
+	rules    []*ScenarioRule
+	attempts map[string]int
+	settled  map[string]bool // idempotency keys of successful transfers
+}
+
+// NewScenario creates an empty scenario where every transfer succeeds.
+func NewScenario() *Scenario {
+	return &Scenario{attempts: map[string]int{}, settled: map[string]bool{}}
+}
+
+// VendorMatcher selects the vendors a scenario rule applies to.
+type VendorMatcher func(vendorID string) bool
+
+// Vendors matches the listed vendor IDs.
+func Vendors(ids ...string) VendorMatcher {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return func(vendorID string) bool { return set[vendorID] }
+}
+
+// VendorRange matches vendor IDs produced by fmt.Sprintf(format, i) for
+// from <= i < to, i.e. payouts by index when test data is generated that way.
+func VendorRange(format string, from, to int) VendorMatcher {
+	ids := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		ids = append(ids, fmt.Sprintf(format, i))
+	}
+	return Vendors(ids...)
+}
+
+// VendorPattern matches vendor IDs against a regular expression.
+func VendorPattern(expr string) VendorMatcher {
+	re := regexp.MustCompile(expr)
+	return re.MatchString
+}
+
+// ScenarioRule scripts the outcome of successive attempts for matching vendors.
+// An empty failure code means success.
+type ScenarioRule struct {
+	scenario *Scenario
+	match    VendorMatcher
+	steps    []string
+	then     string
+}
+
+// For adds a rule for the matched vendors.
+func (s *Scenario) For(match VendorMatcher) *ScenarioRule {
+	rule := &ScenarioRule{scenario: s, match: match}
+	s.mu.Lock()
+	s.rules = append(s.rules, rule)
+	s.mu.Unlock()
+	return rule
+}
+
+// Fail makes the next times attempts fail with code.
+func (r *ScenarioRule) Fail(code string, times int) *ScenarioRule {
+	for i := 0; i < times; i++ {
+		r.steps = append(r.steps, code)
+	}
+	return r
+}
+
+// Succeed makes the next times attempts succeed.
+func (r *ScenarioRule) Succeed(times int) *ScenarioRule {
+	return r.Fail("", times)
+}
+
+// ThenSucceed makes every attempt after the scripted steps succeed.
+func (r *ScenarioRule) ThenSucceed() *Scenario {
+	r.then = ""
+	return r.scenario
+}
+
+// ThenFail makes every attempt after the scripted steps fail with code.
+func (r *ScenarioRule) ThenFail(code string) *Scenario {
+	r.then = code
+	return r.scenario
+}
+
+// Always makes every attempt fail with code.
+func (r *ScenarioRule) Always(code string) *Scenario {
+	r.steps = nil
+	return r.ThenFail(code)
+}
+
+// Attempts returns how many transfers were made for a vendor.
+func (s *Scenario) Attempts(vendorID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[vendorID]
+}
+
+// Transfer implements BankClient. Resubmitting a payout that already
+// succeeded is acknowledged without counting as an attempt.
+func (s *Scenario) Transfer(ctx context.Context, payout models.Payout) (SimulatedBankResult, error) {
+	if err := ctx.Err(); err != nil {
+		return SimulatedBankResult{}, err
+	}
+
+	s.mu.Lock()
+	if payout.IdempotencyKey != "" && s.settled[payout.IdempotencyKey] {
+		s.mu.Unlock()
+		return SimulatedBankResult{Success: true}, nil
+	}
+	attempt := s.attempts[payout.VendorID]
+	s.attempts[payout.VendorID] = attempt + 1
+
+	code := ""
+	for _, rule := range s.rules {
+		if !rule.match(payout.VendorID) {
+			continue
+		}
+		if attempt < len(rule.steps) {
+			code = rule.steps[attempt]
+		} else {
+			code = rule.then
+		}
+		break
+	}
+	if code == "" && payout.IdempotencyKey != "" {
+		s.settled[payout.IdempotencyKey] = true
+	}
+	s.mu.Unlock()
+
+	if code == "" {
+		return SimulatedBankResult{Success: true}, nil
+	}
+	return SimulatedBankResult{
+		FailureCode: code,
+		IsRetryable: models.IsRetryable(code),
+	}, nil
+}
@@ -0,0 +1,51 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/service"
+)
+
+// TestScenarioScriptedOutcomes verifies per-vendor scripted attempt sequences.
+func TestScenarioScriptedOutcomes(t *testing.T) {
+	sc := service.NewScenario()
+	sc.For(service.VendorRange("v%02d", 10, 15)).Fail(models.FailureBankTimeout, 2).ThenSucceed()
+	sc.For(service.Vendors("blocked")).Always(models.FailureAccountBlocked)
+
+	transfer := func(vendorID string) service.SimulatedBankResult {
+		r, err := sc.Transfer(context.Background(), models.Payout{VendorID: vendorID})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return r
+	}
+
+	// Unmatched vendors always succeed
+	if r := transfer("v01"); !r.Success {
+		t.Errorf("Expected v01 to succeed, got %+v", r)
+	}
+
+	// v12 times out twice, then succeeds
+	for i := 0; i < 2; i++ {
+		r := transfer("v12")
+		if r.Success || r.FailureCode != models.FailureBankTimeout || !r.IsRetryable {
+			t.Fatalf("Attempt %d: expected retryable timeout, got %+v", i+1, r)
+		}
+	}
+	if r := transfer("v12"); !r.Success {
+		t.Errorf("Attempt 3: expected success, got %+v", r)
+	}
+	if n := sc.Attempts("v12"); n != 3 {
+		t.Errorf("Expected 3 attempts for v12, got %d", n)
+	}
+
+	// blocked never succeeds
+	for i := 0; i < 5; i++ {
+		r := transfer("blocked")
+		if r.Success || r.FailureCode != models.FailureAccountBlocked || r.IsRetryable {
+			t.Fatalf("Expected permanent ACCOUNT_BLOCKED, got %+v", r)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"context"
+	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"coding-challenge/internal/models"
@@ -9,13 +12,111 @@ import (
 
 // SimulatedBankResult represents the outcome of a simulated bank transfer.
 type SimulatedBankResult struct {
-	Success      bool
-	FailureCode  string
-	IsRetryable  bool
-	LatencyMs    int
+	Success     bool
+	FailureCode string
+	IsRetryable bool
+	LatencyMs   int
 }
 
-// SimulateBankTransfer simulates calling a bank API to transfer funds.
+// BankClient executes payout transfers against a bank.
+//
+// Implementations must:
+//   - be idempotent per payout IdempotencyKey: resubmitting a transfer that
+//     already succeeded reports success without moving money again;
+//   - report bank outcomes (including declines) in the result, using the
+//     models.Failure* codes with IsRetryable matching models.IsRetryable;
+//   - return an error only when the outcome is unknown, e.g. ctx was cancelled
+//     or its deadline passed before the bank answered, and do so promptly.
+//
+// banktest.RunContract checks these rules for any implementation.
+type BankClient interface {
+	Transfer(ctx context.Context, payout models.Payout) (SimulatedBankResult, error)
+}
+
+// Simulator is a fake bank API with configurable response latency.
+type Simulator struct {
+	latency      LatencyProfile
+	bankLatency  map[string]LatencyProfile
+	latencyScale float64
+	settled      sync.Map // idempotency key -> struct{}, for transfers that succeeded
+
+	chaosMu sync.RWMutex
+	chaos   Chaos
+}
+
+// Chaos injects faults into a running simulator, for game days and demos.
+// The zero value injects nothing.
+type Chaos struct {
+	FailureRate    float64 `json:"failure_rate"`     // share of transfers forced to fail, 0..1
+	FailureCode    string  `json:"failure_code"`     // code of forced failures (BANK_API_TIMEOUT by default)
+	ExtraLatencyMs int     `json:"extra_latency_ms"` // added to every simulated wait
+}
+
+// Validate checks the rate is a share and the code is one the worker knows.
+func (c Chaos) Validate() error {
+	if c.FailureRate < 0 || c.FailureRate > 1 {
+		return fmt.Errorf("failure_rate %v must be between 0 and 1", c.FailureRate)
+	}
+	if c.FailureCode != "" && !models.IsKnownFailure(c.FailureCode) {
+		return fmt.Errorf("unknown failure_code %q", c.FailureCode)
+	}
+	if c.ExtraLatencyMs < 0 {
+		return fmt.Errorf("extra_latency_ms %d must not be negative", c.ExtraLatencyMs)
+	}
+	return nil
+}
+
+// SetChaos replaces the injected faults; the zero Chaos turns them off.
+func (s *Simulator) SetChaos(c Chaos) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	s.chaosMu.Lock()
+	defer s.chaosMu.Unlock()
+	s.chaos = c
+	return nil
+}
+
+// Chaos returns the faults currently injected.
+func (s *Simulator) Chaos() Chaos {
+	s.chaosMu.RLock()
+	defer s.chaosMu.RUnlock()
+	return s.chaos
+}
+
+// SimulatorOption configures optional Simulator behaviour.
+type SimulatorOption func(*Simulator)
+
+// WithLatencyScale multiplies every simulated wait by factor: 0 skips the
+// waits entirely, 0.1 runs ten times faster. Results still report the
+// sampled (unscaled) latency, so only wall-clock time changes.
+func WithLatencyScale(factor float64) SimulatorOption {
+	return func(s *Simulator) {
+		if factor >= 0 {
+			s.latencyScale = factor
+		}
+	}
+}
+
+// NewSimulator creates a simulator using latency for all banks except those
+// listed in bankLatency.
+func NewSimulator(latency LatencyProfile, bankLatency map[string]LatencyProfile, opts ...SimulatorOption) *Simulator {
+	s := &Simulator{latency: latency, bankLatency: bankLatency, latencyScale: 1}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// defaultSimulator reproduces the original uniform 50-500ms behaviour.
+var defaultSimulator = NewSimulator(latencyProfiles["uniform"], nil)
+
+// DefaultSimulator returns the simulator with uniform 50-500ms latency.
+func DefaultSimulator() *Simulator {
+	return defaultSimulator
+}
+
+// Transfer simulates calling a bank API to transfer funds.
 // Realistic distribution:
 //   - 85% success
 //   - 5% INVALID_BANK_ACCOUNT (permanent)
@@ -23,11 +124,53 @@ type SimulatedBankResult struct {
 //   - 3% INSUFFICIENT_FUNDS (retryable)
 //   - 2% ACCOUNT_BLOCKED (permanent)
 //   - 2% RATE_LIMITED (retryable)
-func SimulateBankTransfer(vendorID string, amount float64) SimulatedBankResult {
-	// Simulate network latency: 50-500ms
-	latency := 50 + rand.Intn(450)
-	time.Sleep(time.Duration(latency) * time.Millisecond)
+func (s *Simulator) Transfer(ctx context.Context, payout models.Payout) (SimulatedBankResult, error) {
+	if err := ctx.Err(); err != nil {
+		return SimulatedBankResult{}, err
+	}
+
+	// Simulate network latency according to the bank's profile
+	profile := s.latency
+	if p, ok := s.bankLatency[payout.BankName]; ok {
+		profile = p
+	}
+	chaos := s.Chaos()
+	delay := profile.Sample() + time.Duration(chaos.ExtraLatencyMs)*time.Millisecond
+	if wait := time.Duration(float64(delay) * s.latencyScale); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return SimulatedBankResult{}, ctx.Err()
+		}
+	}
+	latency := int(delay / time.Millisecond)
+
+	// A resubmitted transfer that already went through is acknowledged, not repeated
+	if payout.IdempotencyKey != "" {
+		if _, ok := s.settled.Load(payout.IdempotencyKey); ok {
+			return SimulatedBankResult{Success: true, LatencyMs: latency}, nil
+		}
+	}
+
+	if chaos.FailureRate > 0 && rand.Float64() < chaos.FailureRate {
+		code := chaos.FailureCode
+		if code == "" {
+			code = models.FailureBankTimeout
+		}
+		return SimulatedBankResult{FailureCode: code, IsRetryable: models.IsRetryable(code), LatencyMs: latency}, nil
+	}
+
+	result := s.outcome(latency)
+	if result.Success && payout.IdempotencyKey != "" {
+		s.settled.Store(payout.IdempotencyKey, struct{}{})
+	}
+	return result, nil
+}
 
+// outcome draws a random result from the simulated distribution.
+func (s *Simulator) outcome(latency int) SimulatedBankResult {
 	roll := rand.Float64() * 100
 
 	switch {
@@ -0,0 +1,70 @@
+// Package statustoken issues and verifies the signed payout status tokens
+// embedded in vendor notifications.
+package statustoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalid is returned for tokens that are malformed or not signed by this Signer.
+var ErrInvalid = errors.New("invalid status token")
+
+// macSize is the number of HMAC-SHA256 bytes kept in a token (128 bits).
+const macSize = 16
+
+// Signer issues status tokens. A token is the payout ID followed by a
+// truncated HMAC of it, base64url-encoded: it cannot be guessed or forged
+// without the secret, and needs no storage.
+type Signer struct {
+	secret []byte
+}
+
+// New returns a Signer for secret.
+func New(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// NewRandom returns a Signer with a random secret. Its tokens stop verifying
+// once the process exits, so it is only suitable for tests and local runs.
+func NewRandom() *Signer {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return New(secret)
+}
+
+// Sign returns the status token of a payout.
+func (s *Signer) Sign(payoutID uuid.UUID) string {
+	buf := append(payoutID[:], s.mac(payoutID)...)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// Verify returns the payout a token was issued for.
+func (s *Signer) Verify(token string) (uuid.UUID, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(buf) != len(uuid.UUID{})+macSize {
+		return uuid.Nil, ErrInvalid
+	}
+	id, err := uuid.FromBytes(buf[:16])
+	if err != nil {
+		return uuid.Nil, ErrInvalid
+	}
+	if !hmac.Equal(buf[16:], s.mac(id)) {
+		return uuid.Nil, ErrInvalid
+	}
+	return id, nil
+}
+
+func (s *Signer) mac(payoutID uuid.UUID) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte("payout-status:"))
+	h.Write(payoutID[:])
+	return h.Sum(nil)[:macSize]
+}
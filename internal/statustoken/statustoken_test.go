@@ -0,0 +1,51 @@
+package statustoken_test
+
+import (
+	"errors"
+	"testing"
+
+	"coding-challenge/internal/statustoken"
+
+	"github.com/google/uuid"
+)
+
+// TestRoundTrip verifies a signed token resolves to its payout.
+func TestRoundTrip(t *testing.T) {
+	s := statustoken.New([]byte("secret"))
+	id := uuid.New()
+
+	got, err := s.Verify(s.Sign(id))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if got != id {
+		t.Errorf("Expected %s, got %s", id, got)
+	}
+}
+
+// TestRejectsForgedTokens verifies tokens from another secret, tampered
+// tokens and garbage are all rejected.
+func TestRejectsForgedTokens(t *testing.T) {
+	s := statustoken.New([]byte("secret"))
+	other := statustoken.New([]byte("other"))
+	token := s.Sign(uuid.New())
+
+	tampered := []byte(token)
+	if tampered[0] == 'A' {
+		tampered[0] = 'B'
+	} else {
+		tampered[0] = 'A'
+	}
+
+	for name, tok := range map[string]string{
+		"other secret": other.Sign(uuid.New()),
+		"tampered":     string(tampered),
+		"truncated":    token[:len(token)-2],
+		"garbage":      "not-a-token",
+		"bare uuid":    uuid.New().String(),
+	} {
+		if _, err := s.Verify(tok); !errors.Is(err, statustoken.ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
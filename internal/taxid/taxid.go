@@ -0,0 +1,102 @@
+// Package taxid validates and formats vendor tax identifiers: the NPWP in
+// Indonesia, the TIN in the Philippines and the MST in Vietnam, which
+// payouts above the reporting thresholds must be reported with.
+package taxid
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedCountry is returned for a country whose tax ID format is
+// not known.
+var ErrUnsupportedCountry = errors.New("no tax ID format for country")
+
+// formats lists the digit counts each country's tax IDs may have.
+var formats = map[string][]int{
+	"ID": {15, 16},    // NPWP; 16 digits since the NIK became the NPWP
+	"PH": {9, 12, 14}, // TIN, with a 3 or 5 digit branch code
+	"VN": {10, 13},    // MST, with a 3 digit branch suffix
+}
+
+// Countries returns the countries with a known tax ID format.
+func Countries() []string {
+	return []string{"ID", "PH", "VN"}
+}
+
+// Normalize checks a tax ID against its country's format and returns its
+// digits. Spaces, dots and dashes between them are ignored.
+func Normalize(country, raw string) (string, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	lengths, ok := formats[country]
+	if !ok {
+		return "", fmt.Errorf("%w %q (want one of %s)", ErrUnsupportedCountry, country, strings.Join(Countries(), ", "))
+	}
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-':
+			return -1
+		}
+		return r
+	}, raw)
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("tax ID %q has a character other than digits, dots and dashes", raw)
+		}
+	}
+	for _, n := range lengths {
+		if len(digits) == n {
+			return digits, nil
+		}
+	}
+	want := make([]string, len(lengths))
+	for i, n := range lengths {
+		want[i] = strconv.Itoa(n)
+	}
+	return "", fmt.Errorf("a %s tax ID has %s digits, got %d", country, strings.Join(want, " or "), len(digits))
+}
+
+// Format lays out normalized digits the way the country's tax authority
+// prints them, e.g. 01.234.567.8-901.000 for a 15 digit NPWP.
+func Format(country, digits string) string {
+	switch n := len(digits); {
+	case country == "ID" && n == 15:
+		return digits[0:2] + "." + digits[2:5] + "." + digits[5:8] + "." + digits[8:9] + "-" + digits[9:12] + "." + digits[12:15]
+	case country == "PH" && n >= 9:
+		out := digits[0:3] + "-" + digits[3:6] + "-" + digits[6:9]
+		if n > 9 {
+			out += "-" + digits[9:]
+		}
+		return out
+	case country == "VN" && n == 13:
+		return digits[0:10] + "-" + digits[10:13]
+	default:
+		return digits
+	}
+}
+
+// ParseThresholds parses per-currency reporting thresholds in the form
+// "IDR=60000000,PHP=250000": a vendor paid more than that in a currency
+// over a reporting period must be reported with a tax ID.
+func ParseThresholds(spec string) (map[string]float64, error) {
+	thresholds := map[string]float64{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		currency, amount, ok := strings.Cut(entry, "=")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !ok || len(currency) != 3 {
+			return nil, fmt.Errorf("invalid tax ID threshold entry %q (want CURRENCY=AMOUNT)", entry)
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid threshold in %q: want an amount >= 0", entry)
+		}
+		thresholds[currency] = threshold
+	}
+	return thresholds, nil
+}
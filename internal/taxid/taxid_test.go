@@ -0,0 +1,62 @@
+package taxid_test
+
+import (
+	"errors"
+	"testing"
+
+	"coding-challenge/internal/taxid"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		country, raw, digits, formatted string
+	}{
+		{"ID", "01.234.567.8-901.000", "012345678901000", "01.234.567.8-901.000"},
+		{"id", "3171 0123 4567 8901", "3171012345678901", "3171012345678901"},
+		{"PH", "123-456-789", "123456789", "123-456-789"},
+		{"PH", "123-456-789-000", "123456789000", "123-456-789-000"},
+		{"PH", "123456789 00000", "12345678900000", "123-456-789-00000"},
+		{"VN", "0101234567", "0101234567", "0101234567"},
+		{"VN", "0101234567-001", "0101234567001", "0101234567-001"},
+	}
+	for _, tc := range cases {
+		digits, err := taxid.Normalize(tc.country, tc.raw)
+		if err != nil || digits != tc.digits {
+			t.Errorf("%s %q: expected %s, got %q (%v)", tc.country, tc.raw, tc.digits, digits, err)
+			continue
+		}
+		if got := taxid.Format(tc.country, digits); got != tc.formatted {
+			t.Errorf("%s %q: expected it formatted as %s, got %s", tc.country, tc.raw, tc.formatted, got)
+		}
+	}
+
+	for _, tc := range []struct{ country, raw string }{
+		{"ID", "01.234.567.8-901"}, // 12 digits
+		{"PH", "123-456-78"},       // 8 digits
+		{"VN", "01012345678"},      // 11 digits
+		{"PH", "123-456-789-ABC"},  // letters
+		{"ID", "01/234/567/8/901/000"},
+	} {
+		if _, err := taxid.Normalize(tc.country, tc.raw); err == nil {
+			t.Errorf("%s %q: expected an error", tc.country, tc.raw)
+		}
+	}
+	if _, err := taxid.Normalize("SG", "200312345A"); !errors.Is(err, taxid.ErrUnsupportedCountry) {
+		t.Errorf("Expected ErrUnsupportedCountry for SG, got %v", err)
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	got, err := taxid.ParseThresholds("idr=60000000, PHP=250000")
+	if err != nil || len(got) != 2 || got["IDR"] != 60000000 || got["PHP"] != 250000 {
+		t.Errorf("Expected IDR and PHP thresholds, got %v (%v)", got, err)
+	}
+	if got, err := taxid.ParseThresholds(""); err != nil || len(got) != 0 {
+		t.Errorf("Expected no thresholds, got %v (%v)", got, err)
+	}
+	for _, spec := range []string{"IDR", "RUPIAH=1", "IDR=-1", "IDR=lots"} {
+		if _, err := taxid.ParseThresholds(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"coding-challenge/internal/models"
+)
+
+// attemptLog holds payout attempts whose LogAttempt failed, for
+// RetryAttemptLogs to write once the store is back. Attempt history is the
+// audit trail of every transfer, so instead of being lost with the error an
+// attempt waits here, up to a bound, and what is still waiting at shutdown
+// is spilled to a file and replayed by the next start.
+type attemptLog struct {
+	interval time.Duration
+	max      int
+	spill    string // "" drops what is waiting at shutdown
+
+	mu      sync.Mutex
+	queue   []models.PayoutAttempt
+	spilled bool // the queue went to the spill file; later failures follow it
+}
+
+// WithAttemptRetry queues attempts whose write failed, at most max of them,
+// and retries them every interval while RetryAttemptLogs runs. At shutdown
+// those still queued are appended to spillPath as JSON lines, which
+// RetryAttemptLogs replays on the next start. Attempts that find the queue
+// full, or cannot be spilled, are dropped and reported to
+// Metrics.AttemptLogsDropped.
+func WithAttemptRetry(interval time.Duration, max int, spillPath string) Option {
+	return func(p *Pool) {
+		p.attemptLog = &attemptLog{interval: interval, max: max, spill: spillPath}
+	}
+}
+
+// logAttempt writes a payout attempt, queueing it for a retry if the write
+// fails.
+func (p *Pool) logAttempt(ctx context.Context, attempt *models.PayoutAttempt) {
+	err := p.repo.LogAttempt(ctx, attempt)
+	if err == nil {
+		return
+	}
+	if p.attemptLog == nil {
+		log.Printf("[worker] Error logging attempt for payout %s: %v", attempt.PayoutID, err)
+		p.metrics.AttemptLogsDropped(1)
+		return
+	}
+	log.Printf("[worker] Error logging attempt for payout %s, queued for a retry: %v", attempt.PayoutID, err)
+	p.attemptLog.add(p, *attempt)
+}
+
+// add queues an attempt, or appends it to the spill file once the queue was
+// spilled.
+func (l *attemptLog) add(p *Pool, attempt models.PayoutAttempt) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.spilled {
+		l.writeSpill(p, []models.PayoutAttempt{attempt})
+		return
+	}
+	if len(l.queue) >= l.max {
+		log.Printf("[worker] ALERT: attempt log queue full (%d); dropped the attempt for payout %s", l.max, attempt.PayoutID)
+		p.metrics.AttemptLogsDropped(1)
+		return
+	}
+	l.queue = append(l.queue, attempt)
+}
+
+// RetryAttemptLogs replays attempts spilled by the last shutdown, then
+// retries queued attempts every interval until ctx is cancelled.
+func (p *Pool) RetryAttemptLogs(ctx context.Context) {
+	l := p.attemptLog
+	if l == nil {
+		return
+	}
+	if err := l.replay(); err != nil {
+		log.Printf("[worker] Error replaying spilled attempts from %s: %v", l.spill, err)
+	}
+	for {
+		if n, err := p.flushAttempts(ctx); n > 0 || err != nil {
+			log.Printf("[worker] Retried %d queued attempt logs (%v)", n, err)
+		}
+		timer := p.clock.NewTimer(l.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+// flushAttempts writes queued attempts in order until one fails again, and
+// returns how many were written.
+func (p *Pool) flushAttempts(ctx context.Context) (int, error) {
+	l := p.attemptLog
+	l.mu.Lock()
+	pending := l.queue
+	l.queue = nil
+	l.mu.Unlock()
+
+	written := 0
+	var err error
+	for _, attempt := range pending {
+		if err = p.repo.LogAttempt(ctx, &attempt); err != nil {
+			break
+		}
+		written++
+	}
+	if rest := pending[written:]; len(rest) > 0 {
+		l.mu.Lock()
+		l.queue = append(rest, l.queue...)
+		l.mu.Unlock()
+	}
+	return written, err
+}
+
+// spillAttempts makes a last try at writing the queued attempts and appends
+// those still failing to the spill file, at shutdown.
+func (p *Pool) spillAttempts(ctx context.Context) {
+	l := p.attemptLog
+	if l == nil {
+		return
+	}
+	if n, err := p.flushAttempts(ctx); n > 0 || err != nil {
+		log.Printf("[worker] Retried %d queued attempt logs at shutdown (%v)", n, err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writeSpill(p, l.queue)
+	l.queue, l.spilled = nil, true
+}
+
+// writeSpill appends attempts to the spill file, dropping them if there is
+// none or it cannot be written. l.mu must be held.
+func (l *attemptLog) writeSpill(p *Pool, attempts []models.PayoutAttempt) {
+	if len(attempts) == 0 {
+		return
+	}
+	err := errors.New("no spill file configured")
+	if l.spill != "" {
+		err = appendAttempts(l.spill, attempts)
+	}
+	if err != nil {
+		log.Printf("[worker] ALERT: dropped %d attempt logs that could not be written: %v", len(attempts), err)
+		p.metrics.AttemptLogsDropped(len(attempts))
+		return
+	}
+	log.Printf("[worker] Spilled %d attempt logs to %s for the next start", len(attempts), l.spill)
+}
+
+// appendAttempts appends attempts to path as JSON lines.
+func appendAttempts(path string, attempts []models.PayoutAttempt) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, attempt := range attempts {
+		if err := enc.Encode(attempt); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replay queues the attempts in the spill file and removes it.
+func (l *attemptLog) replay() error {
+	if l.spill == "" {
+		return nil
+	}
+	f, err := os.Open(l.spill)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var attempts []models.PayoutAttempt
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var attempt models.PayoutAttempt
+		if err := json.Unmarshal(scanner.Bytes(), &attempt); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.queue = append(attempts, l.queue...)
+	l.mu.Unlock()
+	log.Printf("[worker] Replaying %d attempt logs spilled to %s", len(attempts), l.spill)
+	return os.Remove(l.spill)
+}
@@ -0,0 +1,82 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+)
+
+// downAttempts fails every attempt write while down is set.
+type downAttempts struct {
+	*memstore.Store
+	down atomic.Bool
+}
+
+func (s *downAttempts) LogAttempt(ctx context.Context, attempt *models.PayoutAttempt) error {
+	if s.down.Load() {
+		return errors.New("connection refused")
+	}
+	return s.Store.LogAttempt(ctx, attempt)
+}
+
+// TestAttemptLogRetry verifies attempts whose write failed are queued up to
+// the bound, with the rest reported dropped, spilled to disk at shutdown,
+// and written by the next start once the store is back.
+func TestAttemptLogRetry(t *testing.T) {
+	ctx := context.Background()
+	store := &downAttempts{Store: memstore.New()}
+	batch := memBatch(t, store.Store, 5)
+	spill := filepath.Join(t.TempDir(), "attempts.ndjson")
+	m := &recordingMetrics{}
+
+	store.down.Store(true)
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(service.NewScenario()), worker.WithMetrics(m),
+		worker.WithAttemptRetry(time.Minute, 3, spill))
+	if err := pool.ProcessBatch(ctx, batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	if got := m.dropped.Load(); got != 2 {
+		t.Errorf("Expected 2 attempt logs dropped beyond the queue of 3, got %d", got)
+	}
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	data, err := os.ReadFile(spill)
+	if err != nil || strings.Count(string(data), "\n") != 3 {
+		t.Fatalf("Expected 3 attempts spilled, got %q (%v)", data, err)
+	}
+
+	store.down.Store(false)
+	restarted := worker.NewPool(store, 2, 10, worker.WithAttemptRetry(time.Minute, 3, spill))
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		restarted.RetryAttemptLogs(runCtx)
+		close(done)
+	}()
+	logged := func() int {
+		n := 0
+		items, _, _ := store.GetPayoutsByBatch(ctx, batch.ID, "", 1, 10)
+		for _, item := range items {
+			attempts, _ := store.GetPayoutAttempts(ctx, item.ID)
+			n += len(attempts)
+		}
+		return n
+	}
+	waitFor(t, "the spilled attempts written", func() bool { return logged() == 3 })
+	cancel()
+	<-done
+	if _, err := os.Stat(spill); !os.IsNotExist(err) {
+		t.Errorf("Expected the spill file removed once replayed, got %v", err)
+	}
+}
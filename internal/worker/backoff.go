@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryPoll is how often a run whose remaining payouts are all backing off
+// checks whether one has come due.
+const retryPoll = time.Second
+
+// RetryBackoff spaces out the retries of a payout that failed retryably:
+// after its nth attempt it waits Base·Multiplier^(n-1), give or take Jitter
+// (a fraction of the delay), and never more than Max. The zero value
+// retries at once.
+type RetryBackoff struct {
+	Base       time.Duration
+	Multiplier float64
+	Jitter     float64
+	Max        time.Duration
+}
+
+// DefaultRetryBackoff waits about 2s, 4s, 8s... between attempts, up to a
+// minute.
+var DefaultRetryBackoff = RetryBackoff{Base: 2 * time.Second, Multiplier: 2, Jitter: 0.2, Max: time.Minute}
+
+// WithRetryBackoff makes payouts requeued after a retryable failure wait
+// out the backoff before they can be claimed again, so a rate-limited or
+// timing-out bank isn't hit again straight away. Without it they are
+// retried in the next chunk.
+func WithRetryBackoff(b RetryBackoff) Option {
+	return func(p *Pool) { p.retryBackoff = b }
+}
+
+// enabled reports whether retries wait at all.
+func (b RetryBackoff) enabled() bool {
+	return b.Base > 0
+}
+
+// delay returns how long a payout waits after its attempt-th attempt,
+// with rnd a random number in [0, 1) for the jitter.
+func (b RetryBackoff) delay(attempt int, rnd float64) time.Duration {
+	if !b.enabled() {
+		return 0
+	}
+	d := float64(b.Base) * math.Pow(max(b.Multiplier, 1), float64(max(attempt-1, 0)))
+	d *= 1 + b.Jitter*(2*rnd-1)
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	return time.Duration(d)
+}
+
+// String formats the policy as ParseRetryBackoff reads it.
+func (b RetryBackoff) String() string {
+	if !b.enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("base=%s,multiplier=%g,jitter=%g,max=%s", b.Base, b.Multiplier, b.Jitter, b.Max)
+}
+
+// retryAt returns when a payout that failed its attempt-th attempt may be
+// claimed again under backoff b, or the zero time for at once.
+func (p *Pool) retryAt(b RetryBackoff, attempt int) time.Time {
+	d := b.delay(attempt, rand.Float64())
+	if d <= 0 {
+		return time.Time{}
+	}
+	return p.clock.Now().Add(d)
+}
+
+// ParseRetryBackoff parses a backoff policy such as
+// "base=2s,multiplier=2,jitter=0.2,max=1m"; settings left out keep their
+// DefaultRetryBackoff values, and "off" retries at once.
+func ParseRetryBackoff(spec string) (RetryBackoff, error) {
+	b := DefaultRetryBackoff
+	if strings.TrimSpace(spec) == "off" {
+		return RetryBackoff{}, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(entry, "=")
+		if !ok {
+			return RetryBackoff{}, fmt.Errorf("invalid retry backoff entry %q (want key=value)", entry)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		var err error
+		switch key {
+		case "base":
+			b.Base, err = time.ParseDuration(val)
+			if err == nil && b.Base <= 0 {
+				err = errors.New("want a duration > 0")
+			}
+		case "max":
+			b.Max, err = time.ParseDuration(val)
+			if err == nil && b.Max <= 0 {
+				err = errors.New("want a duration > 0")
+			}
+		case "multiplier":
+			b.Multiplier, err = strconv.ParseFloat(val, 64)
+			if err == nil && b.Multiplier < 1 {
+				err = errors.New("want a multiplier >= 1")
+			}
+		case "jitter":
+			b.Jitter, err = strconv.ParseFloat(val, 64)
+			if err == nil && (b.Jitter < 0 || b.Jitter > 1) {
+				err = errors.New("want a fraction between 0 and 1")
+			}
+		default:
+			return RetryBackoff{}, fmt.Errorf("unknown retry backoff setting %q (want base, multiplier, jitter or max)", key)
+		}
+		if err != nil {
+			return RetryBackoff{}, fmt.Errorf("invalid retry backoff %s %q: %w", key, val, err)
+		}
+	}
+	if b.Max < b.Base {
+		return RetryBackoff{}, fmt.Errorf("invalid retry backoff: max %s is below base %s", b.Max, b.Base)
+	}
+	return b, nil
+}
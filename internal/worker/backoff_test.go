@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBackoffDelay(t *testing.T) {
+	b := RetryBackoff{Base: 2 * time.Second, Multiplier: 2, Jitter: 0.5, Max: 10 * time.Second}
+	cases := []struct {
+		attempt int
+		rnd     float64
+		want    time.Duration
+	}{
+		{1, 0.5, 2 * time.Second},
+		{2, 0.5, 4 * time.Second},
+		{3, 0.5, 8 * time.Second},
+		{4, 0.5, 10 * time.Second}, // capped
+		{2, 0, 2 * time.Second},    // -50% jitter
+		{2, 0.75, 5 * time.Second}, // +25% jitter
+		{3, 0.99, 10 * time.Second},
+	}
+	for _, tc := range cases {
+		if got := b.delay(tc.attempt, tc.rnd); got != tc.want {
+			t.Errorf("attempt %d, rnd %v: expected %s, got %s", tc.attempt, tc.rnd, tc.want, got)
+		}
+	}
+	if got := (RetryBackoff{}).delay(3, 0.5); got != 0 {
+		t.Errorf("Expected no delay without a backoff, got %s", got)
+	}
+}
+
+func TestParseRetryBackoff(t *testing.T) {
+	cases := []struct {
+		spec string
+		want RetryBackoff
+	}{
+		{"", DefaultRetryBackoff},
+		{"off", RetryBackoff{}},
+		{"base=500ms, max=30s", RetryBackoff{Base: 500 * time.Millisecond, Multiplier: 2, Jitter: 0.2, Max: 30 * time.Second}},
+		{"multiplier=1.5,jitter=0", RetryBackoff{Base: 2 * time.Second, Multiplier: 1.5, Max: time.Minute}},
+	}
+	for _, tc := range cases {
+		got, err := ParseRetryBackoff(tc.spec)
+		if err != nil || got != tc.want {
+			t.Errorf("%q: expected %+v, got %+v (%v)", tc.spec, tc.want, got, err)
+		}
+	}
+	for _, spec := range []string{"base", "base=0s", "multiplier=0.5", "jitter=2", "delay=1s", "base=2m"} {
+		if _, err := ParseRetryBackoff(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
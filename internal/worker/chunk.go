@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Chunk-size tuning bounds. A chunk never grows or shrinks by more than
+// chunkTuneStep at once, so one unusually fast or slow chunk (a cache of
+// instant rejections, a bank hiccup) doesn't swing the size to an extreme.
+const (
+	minTunedChunk = 1
+	maxTunedChunk = 5000
+	chunkTuneStep = 4
+)
+
+// WithChunkTarget makes each run adapt its chunk size so a chunk takes
+// between low and high to process, starting from the pool's chunk size.
+// Short chunks mean many claims and count refreshes against the database;
+// long ones mean a stop or a crash waits on, or replays, a lot of work. With
+// a fixed size either can happen depending on how slow the bank is.
+func WithChunkTarget(low, high time.Duration) Option {
+	return func(p *Pool) { p.chunkTargetLow, p.chunkTargetHigh = low, high }
+}
+
+// ParseChunkTarget parses a chunk duration target such as "10s-30s"; a
+// single duration targets exactly that. Empty turns tuning off (zeros).
+func ParseChunkTarget(s string) (low, high time.Duration, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	lo, hi, found := strings.Cut(s, "-")
+	if !found {
+		hi = lo
+	}
+	if low, err = time.ParseDuration(strings.TrimSpace(lo)); err != nil {
+		return 0, 0, fmt.Errorf("invalid chunk target %q: %w", s, err)
+	}
+	if high, err = time.ParseDuration(strings.TrimSpace(hi)); err != nil {
+		return 0, 0, fmt.Errorf("invalid chunk target %q: %w", s, err)
+	}
+	if low <= 0 || high < low {
+		return 0, 0, fmt.Errorf("invalid chunk target %q: want 0 < low <= high", s)
+	}
+	return low, high, nil
+}
+
+// chunkTuner sizes a run's chunks from how long the previous ones took. A
+// chunk that took outside [low, high] resizes the next one to what would
+// have taken the middle of the range at the observed per-payout rate.
+type chunkTuner struct {
+	size      int
+	low, high time.Duration
+}
+
+func newChunkTuner(size int, low, high time.Duration) *chunkTuner {
+	return &chunkTuner{size: max(size, minTunedChunk), low: low, high: high}
+}
+
+// observe feeds back that n payouts took d to process. It returns true if
+// the chunk size changed.
+func (t *chunkTuner) observe(n int, d time.Duration) bool {
+	if t.high <= 0 || n == 0 {
+		return false
+	}
+	// A chunk cut short by the end of the batch or the in-flight cap says
+	// nothing about being too slow, only about being fast enough.
+	if d >= t.low && d <= t.high || n < t.size && d < t.low {
+		return false
+	}
+
+	size := t.size * chunkTuneStep
+	if d > 0 {
+		mid := (t.low + t.high) / 2
+		size = int(float64(n) * float64(mid) / float64(d))
+	}
+	size = min(max(size, t.size/chunkTuneStep, minTunedChunk), t.size*chunkTuneStep, maxTunedChunk)
+	if size == t.size {
+		return false
+	}
+	t.size = size
+	return true
+}
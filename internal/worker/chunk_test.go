@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+// TestChunkTunerAdaptsToChunkDuration verifies chunks are resized towards the
+// middle of the target range, by at most chunkTuneStep at a time.
+func TestChunkTunerAdaptsToChunkDuration(t *testing.T) {
+	tuner := newChunkTuner(100, 10*time.Second, 30*time.Second)
+
+	cases := []struct {
+		took time.Duration
+		want int
+	}{
+		{20 * time.Second, 100}, // in range
+		{40 * time.Second, 50},  // slow bank: 100 at 0.4s each, 20s worth is 50
+		{time.Second, 200},      // fast: 50 at 20ms each would be 1000, capped at 4x
+		{5 * time.Second, 800},  // still fast: 200 at 25ms each, 20s worth is 800
+		{10 * time.Minute, 200}, // bank incident: shrink by at most 4x
+	}
+	for _, tc := range cases {
+		tuner.observe(tuner.size, tc.took)
+		if tuner.size != tc.want {
+			t.Errorf("after a chunk taking %s: expected size %d, got %d", tc.took, tc.want, tuner.size)
+		}
+	}
+}
+
+// TestChunkTunerBounds verifies short chunks don't shrink the size, the size
+// stays within bounds and tuning is off without a target.
+func TestChunkTunerBounds(t *testing.T) {
+	tuner := newChunkTuner(100, 10*time.Second, 30*time.Second)
+	if tuner.observe(10, time.Second) {
+		t.Error("Expected a short, fast chunk at the end of a batch to leave the size alone")
+	}
+	if !tuner.observe(10, time.Minute) || tuner.size != 25 {
+		t.Errorf("Expected a short but slow chunk to shrink the size to 25, got %d", tuner.size)
+	}
+
+	tuner = newChunkTuner(4000, 10*time.Second, 30*time.Second)
+	tuner.observe(4000, 0)
+	if tuner.size != maxTunedChunk {
+		t.Errorf("Expected size capped at %d, got %d", maxTunedChunk, tuner.size)
+	}
+	tuner = newChunkTuner(2, 10*time.Second, 30*time.Second)
+	tuner.observe(2, time.Hour)
+	if tuner.size != minTunedChunk {
+		t.Errorf("Expected size floored at %d, got %d", minTunedChunk, tuner.size)
+	}
+
+	off := newChunkTuner(100, 0, 0)
+	if off.observe(100, time.Hour) || off.size != 100 {
+		t.Errorf("Expected no tuning without a target, got size %d", off.size)
+	}
+}
+
+// TestParseChunkTarget verifies ranges, single durations and bad input.
+func TestParseChunkTarget(t *testing.T) {
+	cases := []struct {
+		in        string
+		low, high time.Duration
+		ok        bool
+	}{
+		{"", 0, 0, true},
+		{"10s-30s", 10 * time.Second, 30 * time.Second, true},
+		{"20s", 20 * time.Second, 20 * time.Second, true},
+		{"30s-10s", 0, 0, false},
+		{"0s-10s", 0, 0, false},
+		{"fast", 0, 0, false},
+	}
+	for _, tc := range cases {
+		low, high, err := ParseChunkTarget(tc.in)
+		if (err == nil) != tc.ok || low != tc.low || high != tc.high {
+			t.Errorf("ParseChunkTarget(%q): expected %s-%s (ok=%v), got %s-%s (%v)", tc.in, tc.low, tc.high, tc.ok, low, high, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+)
+
+// Claim strategies: where in a fifo batch a run claims its next chunk.
+const (
+	// ClaimOrdered claims the first pending payouts, strictly in order.
+	ClaimOrdered = "ordered"
+	// ClaimRandomOffset claims each chunk from a random position onwards.
+	ClaimRandomOffset = "random_offset"
+	// ClaimHashBucket splits the batch into claimBuckets buckets by position
+	// and has each run claim from the bucket its run ID hashes to, moving on
+	// to the next bucket once it is empty.
+	ClaimHashBucket = "hash_bucket"
+)
+
+// claimBuckets is how many buckets ClaimHashBucket splits a batch into.
+const claimBuckets = 16
+
+// WithClaimStrategy sets how runs pick the payouts of a fifo batch they
+// claim (ClaimOrdered by default). Runs sharing a batch with ClaimOrdered
+// all go for the same first pending rows, and every claim skips over the
+// ones locked by the others; the spread strategies send them to different
+// parts of the batch instead, at the cost of processing it only roughly in
+// order. Other processing orders are always claimed strictly in order.
+func WithClaimStrategy(strategy string) Option {
+	return func(p *Pool) { p.claimStrategy = strategy }
+}
+
+// ParseClaimStrategy validates a claim strategy name; empty means
+// ClaimOrdered.
+func ParseClaimStrategy(s string) (string, error) {
+	switch s {
+	case "":
+		return ClaimOrdered, nil
+	case ClaimOrdered, ClaimRandomOffset, ClaimHashBucket:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown claim strategy %q (want %s, %s or %s)", s, ClaimOrdered, ClaimRandomOffset, ClaimHashBucket)
+}
+
+// claimer claims the chunks of one run according to the pool's strategy.
+type claimer struct {
+	p      *Pool
+	batch  *models.PayoutBatch
+	size   int // payouts per chunk
+	bucket int // ClaimHashBucket: the bucket currently claimed from
+	empty  int // ClaimHashBucket: buckets found empty in a row
+}
+
+func (p *Pool) newClaimer(run *models.BatchRun, batch *models.PayoutBatch) *claimer {
+	h := fnv.New32a()
+	h.Write(run.ID[:])
+	return &claimer{p: p, batch: batch, size: run.ChunkSize, bucket: int(h.Sum32() % claimBuckets)}
+}
+
+// next claims the run's next chunk of at most limit payouts. It only comes
+// back empty when nothing in the whole batch can be claimed.
+func (c *claimer) next(ctx context.Context, limit int) ([]models.Payout, error) {
+	p := c.p
+	var spread repository.ClaimSpread
+	switch p.claimStrategy {
+	case ClaimRandomOffset:
+		if c.batch.TotalCount > 1 {
+			spread.FromSeq = rand.Intn(c.batch.TotalCount)
+		}
+	case ClaimHashBucket:
+		for c.empty < claimBuckets {
+			spread = repository.ClaimSpread{Buckets: claimBuckets, Bucket: c.bucket}
+			payouts, err := p.repo.ClaimChunkSpread(ctx, c.batch.ID, c.batch.PayoutOrder, limit, p.inFlightLimits, spread)
+			if err != nil || len(payouts) > 0 {
+				c.empty = 0
+				return payouts, err
+			}
+			c.empty++
+			c.bucket = (c.bucket + 1) % claimBuckets
+		}
+		// Every bucket came up empty; claim plainly from here on.
+		spread = repository.ClaimSpread{}
+	}
+	if spread != (repository.ClaimSpread{}) {
+		payouts, err := p.repo.ClaimChunkSpread(ctx, c.batch.ID, c.batch.PayoutOrder, limit, p.inFlightLimits, spread)
+		if err != nil || len(payouts) > 0 {
+			return payouts, err
+		}
+	}
+	// Nothing claimable in the spread, but the rest of the batch may have some.
+	return p.repo.ClaimChunk(ctx, c.batch.ID, c.batch.PayoutOrder, limit, p.inFlightLimits)
+}
@@ -0,0 +1,150 @@
+package worker_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/database/dbtest"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// TestParseClaimStrategy verifies known strategies are accepted, empty
+// meaning ordered, and unknown ones rejected.
+func TestParseClaimStrategy(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                       worker.ClaimOrdered,
+		worker.ClaimOrdered:      worker.ClaimOrdered,
+		worker.ClaimRandomOffset: worker.ClaimRandomOffset,
+		worker.ClaimHashBucket:   worker.ClaimHashBucket,
+	} {
+		if got, err := worker.ParseClaimStrategy(in); got != want || err != nil {
+			t.Errorf("Expected %q for %q, got %q (%v)", want, in, got, err)
+		}
+	}
+	if _, err := worker.ParseClaimStrategy("random"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+// TestClaimSpread verifies a spread claim only takes payouts from its
+// offset or bucket.
+func TestClaimSpread(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 20)
+
+	fromSeq, err := repo.ClaimChunkSpread(ctx, batchID, models.PayoutOrderFIFO, 3, nil, repository.ClaimSpread{FromSeq: 15})
+	if err != nil {
+		t.Fatalf("ClaimChunkSpread failed: %v", err)
+	}
+	if got := vendors(fromSeq); got != "[test_vendor_0015 test_vendor_0016 test_vendor_0017]" {
+		t.Errorf("Expected the payouts from position 15, got %s", got)
+	}
+
+	bucket, _ := repo.ClaimChunkSpread(ctx, batchID, models.PayoutOrderFIFO, 10, nil, repository.ClaimSpread{Buckets: 4, Bucket: 1})
+	if got := vendors(bucket); got != "[test_vendor_0001 test_vendor_0005 test_vendor_0009 test_vendor_0013]" {
+		t.Errorf("Expected the pending payouts of bucket 1, got %s", got)
+	}
+
+	// Round-robin batches ignore the spread.
+	rr, _ := repo.ClaimChunkSpread(ctx, batchID, models.PayoutOrderBankRoundRobin, 1, nil, repository.ClaimSpread{FromSeq: 19})
+	if got := vendors(rr); got != "[test_vendor_0000]" {
+		t.Errorf("Expected the spread ignored outside fifo, got %s", got)
+	}
+}
+
+func vendors(payouts []models.Payout) string {
+	ids := make([]string, len(payouts))
+	for i, p := range payouts {
+		ids[i] = p.VendorID
+	}
+	return fmt.Sprint(ids)
+}
+
+// TestClaimStrategiesProcessEveryPayoutOnce verifies that runs sharing a
+// batch under each strategy process all of it, each payout exactly once.
+func TestClaimStrategiesProcessEveryPayoutOnce(t *testing.T) {
+	for _, strategy := range []string{worker.ClaimOrdered, worker.ClaimRandomOffset, worker.ClaimHashBucket} {
+		t.Run(strategy, func(t *testing.T) {
+			db := getTestDB(t)
+			batchID := createTestBatch(t, repository.New(db), 300)
+
+			if err := processShared(t, batchID, 4, strategy); err != nil {
+				t.Fatalf("ProcessBatch failed: %v", err)
+			}
+
+			var processed, doubled int
+			db.QueryRow(`SELECT COUNT(*) FROM payouts WHERE batch_id = $1 AND status IN ('completed', 'failed')`, batchID).Scan(&processed)
+			db.QueryRow(`
+				SELECT COUNT(*) FROM (
+					SELECT a.payout_id FROM payout_attempts a
+					JOIN payouts p ON p.id = a.payout_id
+					WHERE p.batch_id = $1
+					GROUP BY a.payout_id, a.attempt_num HAVING COUNT(*) > 1
+				) d`, batchID).Scan(&doubled)
+			if processed != 300 {
+				t.Errorf("Expected all 300 payouts processed, got %d", processed)
+			}
+			if doubled != 0 {
+				t.Errorf("Expected every attempt executed once, %d payouts had one executed twice", doubled)
+			}
+		})
+	}
+}
+
+// processShared runs a batch on several pools at once, each with its own
+// connections, as separate instances would.
+func processShared(t testing.TB, batchID uuid.UUID, instances int, strategy string) error {
+	bank := service.NewSimulator(service.UniformLatency{Min: 0, Max: time.Millisecond}, nil, service.WithLatencyScale(0))
+	errs := make(chan error, instances)
+	start := make(chan struct{})
+	for i := 0; i < instances; i++ {
+		pool := worker.NewPool(repository.New(dbtest.Connect(t)), 8, 100,
+			worker.WithBankClient(bank), worker.WithClaimStrategy(strategy))
+		go func() {
+			<-start
+			errs <- pool.ProcessBatch(context.Background(), batchID)
+		}()
+	}
+	close(start)
+	var err error
+	for i := 0; i < instances; i++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// BenchmarkClaimStrategies processes a 100k-payout batch on 8 instances
+// under each strategy, reporting payouts per second:
+//
+//	go test -run '^$' -bench ClaimStrategies -benchtime 1x ./internal/worker
+func BenchmarkClaimStrategies(b *testing.B) {
+	const payouts, instances = 100_000, 8
+	for _, strategy := range []string{worker.ClaimOrdered, worker.ClaimRandomOffset, worker.ClaimHashBucket} {
+		b.Run(strategy, func(b *testing.B) {
+			var elapsed time.Duration
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				batchID := createTestBatch(b, repository.New(dbtest.Open(b)), payouts)
+				b.StartTimer()
+
+				began := time.Now()
+				if err := processShared(b, batchID, instances, strategy); err != nil {
+					b.Fatalf("ProcessBatch failed: %v", err)
+				}
+				elapsed += time.Since(began)
+			}
+			b.ReportMetric(float64(payouts*b.N)/elapsed.Seconds(), "payouts/s")
+		})
+	}
+}
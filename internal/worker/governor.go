@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// governorPoll is how often a run held back by the governor's in-flight cap
+// checks whether payouts have finished.
+const governorPoll = time.Second
+
+// Reasons a run was throttled, as reported to Metrics.Throttled.
+const (
+	ThrottleInFlight = "in_flight"
+	ThrottleDBWrites = "db_writes"
+)
+
+// InFlightCounter counts the payouts in processing across every batch. The
+// repository and memstore.Store implement it.
+type InFlightCounter interface {
+	CountInFlight(ctx context.Context) (int, error)
+}
+
+// Governor bounds the load of every run it is shared by, whatever their
+// batches' own settings. Its in-flight cap is counted in the store, so it
+// holds across instances; its write rate is per process.
+type Governor struct {
+	store       InFlightCounter
+	maxInFlight int // 0 when uncapped
+
+	mu       sync.Mutex
+	interval time.Duration // between payout writes; 0 when unlimited
+	next     time.Time     // earliest time of the next write
+}
+
+// NewGovernor creates a governor letting at most maxInFlight payouts be in
+// processing at once, across all batches, and recording at most
+// writesPerSecond payout outcomes per second. Zero leaves either unbounded.
+func NewGovernor(store InFlightCounter, maxInFlight int, writesPerSecond float64) *Governor {
+	g := &Governor{store: store, maxInFlight: maxInFlight}
+	if writesPerSecond > 0 {
+		g.interval = time.Duration(float64(time.Second) / writesPerSecond)
+	}
+	return g
+}
+
+// WithGovernor makes the pool's runs share g, so pools sharing it are bound
+// together.
+func WithGovernor(g *Governor) Option {
+	return func(p *Pool) { p.governor = g }
+}
+
+// room returns how many of limit payouts a run may claim without going over
+// the in-flight cap.
+func (g *Governor) room(ctx context.Context, limit int) (int, error) {
+	if g.maxInFlight <= 0 {
+		return limit, nil
+	}
+	inFlight, err := g.store.CountInFlight(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return max(0, min(limit, g.maxInFlight-inFlight)), nil
+}
+
+// reserveWrite books the next payout write and returns how long to wait
+// for it. Writes are spaced evenly, without bursts.
+func (g *Governor) reserveWrite(now time.Time) time.Duration {
+	if g.interval == 0 {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.next.Before(now) {
+		g.next = now
+	}
+	wait := g.next.Sub(now)
+	g.next = g.next.Add(g.interval)
+	return wait
+}
+
+// governedLimit returns how many payouts the run may claim now, waiting while
+// the governor's in-flight cap leaves no room. It returns 0 once stopped.
+func (p *Pool) governedLimit(ctx context.Context, stopCh chan struct{}, limit int) (int, error) {
+	if p.governor == nil {
+		return limit, nil
+	}
+	var began time.Time // when the run was first held back
+	defer func() {
+		if !began.IsZero() {
+			p.metrics.Throttled(ThrottleInFlight, p.clock.Now().Sub(began))
+		}
+	}()
+	for {
+		room, err := p.governor.room(ctx, limit)
+		if err != nil || room > 0 {
+			return room, err
+		}
+		if began.IsZero() {
+			began = p.clock.Now()
+		}
+		timer := p.clock.NewTimer(governorPoll)
+		select {
+		case <-timer.C():
+		case <-stopCh:
+			timer.Stop()
+			return 0, nil
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// throttleWrite waits for the governor to allow recording another payout
+// outcome. It is called before the transfer, so an outcome is never kept
+// waiting to be written.
+func (p *Pool) throttleWrite(ctx context.Context) error {
+	if p.governor == nil {
+		return nil
+	}
+	wait := p.governor.reserveWrite(p.clock.Now())
+	if wait <= 0 {
+		return nil
+	}
+	p.metrics.Throttled(ThrottleDBWrites, wait)
+	timer := p.clock.NewTimer(wait)
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	}
+}
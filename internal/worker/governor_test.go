@@ -0,0 +1,116 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+)
+
+// recordingMetrics adds up the time reported throttled, by reason, and the
+// attempt logs dropped.
+type recordingMetrics struct {
+	mu        sync.Mutex
+	throttled map[string]time.Duration
+	dropped   atomic.Int32
+}
+
+func (m *recordingMetrics) BankCall(time.Duration)            {}
+func (m *recordingMetrics) ChunkProcessed(int, time.Duration) {}
+func (m *recordingMetrics) WorkerBusy(int)                    {}
+
+func (m *recordingMetrics) AttemptLogsDropped(n int) {
+	m.dropped.Add(int32(n))
+}
+
+func (m *recordingMetrics) Throttled(reason string, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.throttled == nil {
+		m.throttled = map[string]time.Duration{}
+	}
+	m.throttled[reason] += took
+}
+
+func (m *recordingMetrics) total(reason string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.throttled[reason]
+}
+
+// TestGovernorInFlightCap verifies runs of different batches sharing a
+// governor keep no more payouts in processing than its cap between them,
+// the run without room waiting until payouts finish.
+func TestGovernorInFlightCap(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	store := memstore.New(memstore.WithClock(clk))
+	first, second := memBatch(t, store, 10), memBatch(t, store, 10)
+	bank := &gateBank{open: make(chan struct{})}
+	gov := worker.NewGovernor(store, 6, 0)
+	m := &recordingMetrics{}
+	pools := []*worker.Pool{
+		worker.NewPool(store, 4, 10, worker.WithBankClient(bank), worker.WithClock(clk), worker.WithGovernor(gov), worker.WithMetrics(m)),
+		worker.NewPool(store, 4, 10, worker.WithBankClient(bank), worker.WithClock(clk), worker.WithGovernor(gov), worker.WithMetrics(m)),
+	}
+
+	if _, err := pools[0].Start(first.ID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "4 transfers in flight", func() bool { return bank.waiting.Load() == 4 })
+	if _, err := pools[1].Start(second.ID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	clk.BlockUntil(1) // the second run waiting for room
+	if n, _ := store.CountInFlight(ctx); n != 6 {
+		t.Errorf("Expected 6 payouts in flight, got %d", n)
+	}
+	if got := bank.waiting.Load(); got != 4 {
+		t.Errorf("Expected only the first run's transfers in flight, got %d", got)
+	}
+
+	close(bank.open)
+	waitFor(t, "the first run to end", func() bool { return !pools[0].IsRunning() })
+	clk.Advance(time.Second)
+	waitFor(t, "the second run to end", func() bool { return !pools[1].IsRunning() })
+
+	for _, b := range []*models.PayoutBatch{first, second} {
+		if got, _ := store.GetBatch(ctx, b.ID); got.Status != models.BatchStatusCompleted {
+			t.Errorf("Expected batch %s completed, got %s", b.ID, got.Status)
+		}
+	}
+	if got := m.total(worker.ThrottleInFlight); got != time.Second {
+		t.Errorf("Expected 1s throttled for in-flight payouts, got %s", got)
+	}
+}
+
+// TestGovernorWriteRate verifies a governor spaces payout writes out to its
+// rate and reports the time waited.
+func TestGovernorWriteRate(t *testing.T) {
+	store := memstore.New()
+	batch := memBatch(t, store, 10)
+	m := &recordingMetrics{}
+	pool := worker.NewPool(store, 4, 10, worker.WithBankClient(service.NewScenario()),
+		worker.WithGovernor(worker.NewGovernor(store, 0, 100)), worker.WithMetrics(m))
+
+	began := time.Now()
+	if err := pool.ProcessBatch(context.Background(), batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	if took := time.Since(began); took < 80*time.Millisecond {
+		t.Errorf("Expected 10 writes at 100/s to take at least 90ms, took %s", took)
+	}
+	if got := m.total(worker.ThrottleDBWrites); got < 100*time.Millisecond {
+		t.Errorf("Expected the waits for writes reported, got %s", got)
+	}
+	if got := m.total(worker.ThrottleInFlight); got != 0 {
+		t.Errorf("Expected no in-flight throttling without a cap, got %s", got)
+	}
+}
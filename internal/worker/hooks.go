@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/service"
+)
+
+// Hook plugs into the processing of every payout, so features such as
+// limits, screening, metrics or fraud checks can be added without changing
+// the worker. Any of the functions may be nil. Hooks run in the order they
+// were registered, and the first error stops the chain.
+//
+// A before-hook that returns a *Decline fails the payout without calling the
+// bank, exactly as if the bank had declined it with that code. Any other
+// error stops the run: the batch is paused and the run is recorded as
+// failed with the error, for an operator to resume once the cause is fixed.
+type Hook struct {
+	// Name identifies the hook in logs and run errors.
+	Name string
+	// BeforeClaim runs before a worker takes up a payout of the chunk the
+	// run has claimed; the payout is already reserved as processing, and is
+	// released back to pending if the hook stops the run. It may block,
+	// e.g. to wait for capacity, and should return promptly once ctx ends.
+	BeforeClaim func(ctx context.Context, payout models.Payout) error
+	// BeforeTransfer runs after the payout is claimed, just before the bank is called.
+	BeforeTransfer func(ctx context.Context, payout models.Payout) error
+	// AfterResult runs once the outcome of an attempt has been recorded.
+	// It is not called when a transfer is interrupted with its outcome unknown.
+	AfterResult func(ctx context.Context, payout models.Payout, outcome Outcome)
+}
+
+// Outcome is the recorded result of one attempt at a payout.
+type Outcome struct {
+	Attempt models.PayoutAttempt
+	// Result is the bank's answer; for declined payouts it carries the
+	// decline code and no latency.
+	Result service.SimulatedBankResult
+	// Status is the payout's status afterwards: completed, failed, or
+	// pending when a retryable failure was requeued.
+	Status string
+	// DeclinedBy names the hook that declined the payout, if one did.
+	DeclinedBy string
+}
+
+// Decline is returned by a before-hook to fail a payout without sending it.
+type Decline struct {
+	Code      string // failure code recorded on the payout and its attempt
+	Retryable bool   // requeue the payout while it has retries left
+}
+
+func (d *Decline) Error() string {
+	return "declined: " + d.Code
+}
+
+// WithHooks adds processing hooks, run after any registered before them.
+func WithHooks(hooks ...Hook) Option {
+	return func(p *Pool) { p.hooks = append(p.hooks, hooks...) }
+}
+
+// hookChain runs a pool's hooks in order.
+type hookChain []Hook
+
+// before runs one kind of before-hook selected by pick. It returns the
+// decline and the name of the hook that declined, or the error that must
+// stop the run.
+func (c hookChain) before(ctx context.Context, payout models.Payout, pick func(Hook) func(context.Context, models.Payout) error) (*Decline, string, error) {
+	for _, h := range c {
+		fn := pick(h)
+		if fn == nil {
+			continue
+		}
+		err := fn(ctx, payout)
+		if err == nil {
+			continue
+		}
+		var decline *Decline
+		if errors.As(err, &decline) {
+			return decline, h.Name, nil
+		}
+		return nil, h.Name, fmt.Errorf("hook %s: %w", h.Name, err)
+	}
+	return nil, "", nil
+}
+
+func (c hookChain) beforeClaim(ctx context.Context, payout models.Payout) (*Decline, string, error) {
+	return c.before(ctx, payout, func(h Hook) func(context.Context, models.Payout) error { return h.BeforeClaim })
+}
+
+func (c hookChain) beforeTransfer(ctx context.Context, payout models.Payout) (*Decline, string, error) {
+	return c.before(ctx, payout, func(h Hook) func(context.Context, models.Payout) error { return h.BeforeTransfer })
+}
+
+func (c hookChain) afterResult(ctx context.Context, payout models.Payout, outcome Outcome) {
+	for _, h := range c {
+		if h.AfterResult != nil {
+			h.AfterResult(ctx, payout, outcome)
+		}
+	}
+}
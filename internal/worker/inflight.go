@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inFlightPoll is how often a run capped by WithInFlightLimits checks
+// whether confirmations have made room for more payouts.
+const inFlightPoll = 2 * time.Second
+
+// WithInFlightLimits caps the total amount in processing per currency,
+// across all batches, bounding what is exposed if a provider incident forces
+// reversals. A run stops claiming payouts of a currency at its cap and waits
+// for confirmations to bring the amount back down. Currencies without a
+// limit are not capped.
+func WithInFlightLimits(limits map[string]float64) Option {
+	return func(p *Pool) { p.inFlightLimits = limits }
+}
+
+// ParseInFlightLimits parses per-currency in-flight limits in the form
+// "IDR=500000000,USD=25000".
+func ParseInFlightLimits(spec string) (map[string]float64, error) {
+	limits := map[string]float64{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		currency, amount, ok := strings.Cut(entry, "=")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !ok || len(currency) != 3 {
+			return nil, fmt.Errorf("invalid in-flight limit entry %q (want CURRENCY=AMOUNT)", entry)
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit in %q: want an amount > 0", entry)
+		}
+		limits[currency] = limit
+	}
+	return limits, nil
+}
+
+// InFlightLimits returns the per-currency in-flight limits, if any.
+func (p *Pool) InFlightLimits() map[string]float64 {
+	return p.inFlightLimits
+}
@@ -0,0 +1,28 @@
+package worker_test
+
+import (
+	"testing"
+
+	"coding-challenge/internal/worker"
+)
+
+// TestParseInFlightLimits verifies limits are read per currency and that
+// malformed entries are rejected.
+func TestParseInFlightLimits(t *testing.T) {
+	limits, err := worker.ParseInFlightLimits(" idr=500000000, USD=25000.50 ,")
+	if err != nil {
+		t.Fatalf("ParseInFlightLimits failed: %v", err)
+	}
+	if len(limits) != 2 || limits["IDR"] != 500000000 || limits["USD"] != 25000.50 {
+		t.Errorf("Expected IDR and USD limits, got %v", limits)
+	}
+
+	for _, spec := range []string{"USD", "USD=", "USD=0", "USD=-5", "DOLLAR=5", "=5"} {
+		if _, err := worker.ParseInFlightLimits(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+	if limits, err := worker.ParseInFlightLimits(""); err != nil || len(limits) != 0 {
+		t.Errorf("Expected no limits for an empty spec, got %v (%v)", limits, err)
+	}
+}
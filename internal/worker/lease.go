@@ -0,0 +1,207 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrLeaseLost ends a run whose instance could not renew the batch's lease
+// in time, so another instance may have taken the batch over.
+var ErrLeaseLost = errors.New("lost the batch lease to another instance")
+
+// takeoverOperator is recorded as triggered_by on runs taking over a batch
+// whose lease expired.
+const takeoverOperator = "system"
+
+// LeaseStore keeps the leases that let one instance process a batch at a
+// time. The repository implements it.
+type LeaseStore interface {
+	AcquireBatchLease(ctx context.Context, batchID uuid.UUID, holder string, ttl time.Duration) error
+	RenewBatchLease(ctx context.Context, batchID uuid.UUID, holder string, ttl time.Duration) (bool, error)
+	ReleaseBatchLease(ctx context.Context, batchID uuid.UUID, holder string) error
+	FindExpiredLeases(ctx context.Context) ([]uuid.UUID, error)
+}
+
+// WithLeases makes runs take the batch's lease as holder (e.g. the
+// instance's host name) before processing it, renewing it every third of
+// ttl. A batch leased by another instance is refused with
+// repository.ErrBatchLeased, instead of being shared by both; see
+// TakeOverExpired for batches whose holder died.
+func WithLeases(store LeaseStore, holder string, ttl time.Duration) Option {
+	return func(p *Pool) {
+		p.leases, p.leaseHolder, p.leaseTTL = store, holder, ttl
+	}
+}
+
+// acquireLease takes the batch's lease for a run about to start, if leases
+// are in use.
+func (p *Pool) acquireLease(ctx context.Context, batchID uuid.UUID) error {
+	if p.leases == nil {
+		return nil
+	}
+	return p.leases.AcquireBatchLease(ctx, batchID, p.leaseHolder, p.leaseTTL)
+}
+
+// releaseLease gives up the batch's lease once its run ended. A lease this
+// instance does not hold is left alone.
+func (p *Pool) releaseLease(batchID uuid.UUID) {
+	if p.leases == nil {
+		return
+	}
+	if err := p.leases.ReleaseBatchLease(context.Background(), batchID, p.leaseHolder); err != nil {
+		log.Printf("[processor] Warning: failed to release the lease on batch %s: %v", batchID, err)
+	}
+}
+
+// heartbeat renews the batch's lease until ctx is cancelled. Failed renewals
+// are retried at the next beat; once the lease is gone, lost is set for the
+// run to end after its chunk.
+func (p *Pool) heartbeat(ctx context.Context, batchID uuid.UUID, lost *atomic.Bool) {
+	for {
+		timer := p.clock.NewTimer(p.leaseTTL / 3)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		held, err := p.leases.RenewBatchLease(ctx, batchID, p.leaseHolder, p.leaseTTL)
+		if err != nil {
+			log.Printf("[processor] Warning: failed to renew the lease on batch %s: %v", batchID, err)
+			continue
+		}
+		if !held {
+			log.Printf("[processor] Lost the lease on batch %s", batchID)
+			lost.Store(true)
+			return
+		}
+	}
+}
+
+// TakeOverExpired starts or queues a run for every in_progress batch whose
+// lease expired without being released, e.g. because the instance holding
+// it died. As with any run, payouts stuck in processing are reset first
+// unless the old holder is still connected and running the batch. It
+// returns the number of batches started or queued; a batch another
+// instance took over first is skipped.
+func (p *Pool) TakeOverExpired(ctx context.Context) (int, error) {
+	if p.leases == nil {
+		return 0, nil
+	}
+	batches, err := p.leases.FindExpiredLeases(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	taken := 0
+	for _, batchID := range batches {
+		run, pos, err := p.Enqueue(batchID, models.RunTriggerTakeover, takeoverOperator)
+		if errors.Is(err, ErrBusy) || errors.Is(err, repository.ErrBatchLeased) {
+			continue // Already ours, or someone else's again
+		}
+		if err != nil {
+			log.Printf("[processor] Error taking over batch %s: %v", batchID, err)
+			continue
+		}
+		if run != nil {
+			log.Printf("[processor] Took over batch %s after its lease expired (run %s)", batchID, run.ID)
+		} else {
+			log.Printf("[processor] Queued batch %s for taking over at position %d", batchID, pos)
+		}
+		taken++
+	}
+	return taken, nil
+}
+
+// WatchLeases takes over batches whose lease expired, checking every half
+// lease until ctx is cancelled.
+func (p *Pool) WatchLeases(ctx context.Context) {
+	if p.leases == nil {
+		return
+	}
+	log.Printf("[processor] Holding batch leases as %s for %s; taking over expired ones", p.leaseHolder, p.leaseTTL)
+	for {
+		timer := p.clock.NewTimer(p.leaseTTL / 2)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+			if _, err := p.TakeOverExpired(ctx); err != nil {
+				log.Printf("[processor] Lease check failed: %v", err)
+			}
+		}
+	}
+}
+
+// ClaimLeaseStore keeps the leases on claimed payouts that tell recovery
+// which are still in flight. The repository implements it.
+type ClaimLeaseStore interface {
+	LeaseClaims(ctx context.Context, payoutIDs []uuid.UUID, holder string, ttl time.Duration) (int64, error)
+	ResetExpiredClaims(ctx context.Context, batchID uuid.UUID) (int64, error)
+}
+
+// WithClaimLeases makes runs lease every payout they claim as holder,
+// renewing the lease every third of ttl while its chunk is being processed.
+// Recovery then only resets payouts whose lease expired, rather than every
+// payout in processing, and a run that runs out of payouts first takes back
+// those whose holder stopped renewing.
+func WithClaimLeases(store ClaimLeaseStore, holder string, ttl time.Duration) Option {
+	return func(p *Pool) {
+		p.claimLeases, p.claimHolder, p.claimTTL = store, holder, ttl
+	}
+}
+
+// leaseClaims takes the lease on a chunk just claimed, if claim leases are
+// in use.
+func (p *Pool) leaseClaims(ctx context.Context, payouts []models.Payout) error {
+	if p.claimLeases == nil {
+		return nil
+	}
+	_, err := p.claimLeases.LeaseClaims(ctx, payoutIDs(payouts), p.claimHolder, p.claimTTL)
+	return err
+}
+
+// heartbeatClaims renews the lease on a chunk's payouts until ctx is
+// cancelled. Payouts finished meanwhile drop out of the renewal; failed
+// renewals are retried at the next beat.
+func (p *Pool) heartbeatClaims(ctx context.Context, payouts []models.Payout) {
+	ids := payoutIDs(payouts)
+	for {
+		timer := p.clock.NewTimer(p.claimTTL / 3)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		if _, err := p.claimLeases.LeaseClaims(ctx, ids, p.claimHolder, p.claimTTL); err != nil && ctx.Err() == nil {
+			log.Printf("[processor] Warning: failed to renew the claims on %d payouts: %v", len(ids), err)
+		}
+	}
+}
+
+// resetExpiredClaims hands the batch's payouts whose claim lease expired
+// back to pending, returning how many, if claim leases are in use.
+func (p *Pool) resetExpiredClaims(ctx context.Context, batchID uuid.UUID) (int64, error) {
+	if p.claimLeases == nil {
+		return 0, nil
+	}
+	return p.claimLeases.ResetExpiredClaims(ctx, batchID)
+}
+
+func payoutIDs(payouts []models.Payout) []uuid.UUID {
+	ids := make([]uuid.UUID, len(payouts))
+	for i, po := range payouts {
+		ids[i] = po.ID
+	}
+	return ids
+}
@@ -0,0 +1,337 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+// memLeases keeps batch leases in memory, without expiry: a lease is held
+// until released, and the batches in expired are reported expired.
+type memLeases struct {
+	mu       sync.Mutex
+	holders  map[uuid.UUID]string
+	renew    bool
+	renewals int
+	expired  []uuid.UUID
+}
+
+func newMemLeases() *memLeases {
+	return &memLeases{holders: map[uuid.UUID]string{}, renew: true}
+}
+
+func (l *memLeases) AcquireBatchLease(_ context.Context, batchID uuid.UUID, holder string, _ time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if h, ok := l.holders[batchID]; ok && h != holder {
+		return repository.ErrBatchLeased
+	}
+	l.holders[batchID] = holder
+	return nil
+}
+
+func (l *memLeases) RenewBatchLease(_ context.Context, batchID uuid.UUID, holder string, _ time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.renewals++
+	return l.renew && l.holders[batchID] == holder, nil
+}
+
+func (l *memLeases) ReleaseBatchLease(_ context.Context, batchID uuid.UUID, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holders[batchID] == holder {
+		delete(l.holders, batchID)
+	}
+	return nil
+}
+
+func (l *memLeases) FindExpiredLeases(context.Context) ([]uuid.UUID, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expired := l.expired
+	l.expired = nil
+	for _, id := range expired {
+		delete(l.holders, id)
+	}
+	return expired, nil
+}
+
+func (l *memLeases) holder(batchID uuid.UUID) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.holders[batchID]
+}
+
+// TestLeasedBatchIsRefused verifies a batch leased by another instance is
+// not processed, and that a run holds the lease only while it lasts.
+func TestLeasedBatchIsRefused(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	batch := memBatch(t, store, 10)
+	leases := newMemLeases()
+	pool := worker.NewPool(store, 2, 5, worker.WithBankClient(service.NewScenario()), worker.WithLeases(leases, "instance-a", time.Minute))
+
+	leases.AcquireBatchLease(ctx, batch.ID, "instance-b", time.Minute)
+	if _, err := pool.Start(batch.ID, models.RunTriggerStart, "tester"); !errors.Is(err, repository.ErrBatchLeased) {
+		t.Fatalf("Expected ErrBatchLeased starting another instance's batch, got %v", err)
+	}
+	if err := pool.ProcessBatch(ctx, batch.ID); !errors.Is(err, repository.ErrBatchLeased) {
+		t.Fatalf("Expected ErrBatchLeased processing another instance's batch, got %v", err)
+	}
+	if runs, _ := store.ListRuns(ctx, batch.ID); len(runs) != 0 {
+		t.Errorf("Expected no run recorded, got %d", len(runs))
+	}
+
+	leases.ReleaseBatchLease(ctx, batch.ID, "instance-b")
+	if err := pool.ProcessBatch(ctx, batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	if got, _ := store.GetBatch(ctx, batch.ID); got.Status != models.BatchStatusCompleted {
+		t.Errorf("Expected the batch completed, got %s", got.Status)
+	}
+	if h := leases.holder(batch.ID); h != "" {
+		t.Errorf("Expected the lease released after the run, held by %q", h)
+	}
+}
+
+// TestLostLeaseEndsRun verifies a run that cannot renew its lease ends
+// after its chunk, leaving the batch in_progress for the new holder.
+func TestLostLeaseEndsRun(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	batch := memBatch(t, store, 20)
+	leases := newMemLeases()
+	clk := clock.NewFake(time.Now())
+	bank := &gateBank{open: make(chan struct{})}
+	pool := worker.NewPool(store, 2, 2, worker.WithBankClient(bank), worker.WithClock(clk),
+		worker.WithLeases(leases, "instance-a", 30*time.Second))
+
+	if _, err := pool.Start(batch.ID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "2 transfers in flight", func() bool { return bank.waiting.Load() == 2 })
+
+	leases.mu.Lock()
+	leases.renew = false
+	leases.mu.Unlock()
+	clk.BlockUntil(1)
+	clk.Advance(10 * time.Second) // the next heartbeat
+	waitFor(t, "the lease renewal", func() bool {
+		leases.mu.Lock()
+		defer leases.mu.Unlock()
+		return leases.renewals > 0
+	})
+	close(bank.open)
+	waitFor(t, "the run to end", func() bool { return !pool.IsRunning() })
+
+	runs, _ := store.ListRuns(ctx, batch.ID)
+	if len(runs) != 1 || runs[0].Status != models.RunStatusFailed || runs[0].ProcessedCount != 2 {
+		t.Fatalf("Expected one failed run after its first chunk, got %+v", runs)
+	}
+	if runs[0].Error == nil || *runs[0].Error != worker.ErrLeaseLost.Error() {
+		t.Errorf("Expected the run to fail with %q, got %v", worker.ErrLeaseLost, runs[0].Error)
+	}
+	if got, _ := store.GetBatch(ctx, batch.ID); got.Status != models.BatchStatusInProgress {
+		t.Errorf("Expected the batch left in_progress, got %s", got.Status)
+	}
+}
+
+// TestTakeOverExpired verifies a batch whose lease expired is picked up and
+// finished by a run triggered as a takeover.
+func TestTakeOverExpired(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	batch := memBatch(t, store, 10)
+	store.UpdateBatchStatus(ctx, batch.ID, models.BatchStatusInProgress)
+	leases := newMemLeases()
+	leases.AcquireBatchLease(ctx, batch.ID, "instance-b", time.Minute)
+	leases.expired = []uuid.UUID{batch.ID}
+	pool := worker.NewPool(store, 2, 5, worker.WithBankClient(service.NewScenario()), worker.WithLeases(leases, "instance-a", time.Minute))
+
+	taken, err := pool.TakeOverExpired(ctx)
+	if err != nil || taken != 1 {
+		t.Fatalf("Expected 1 batch taken over, got %d (%v)", taken, err)
+	}
+	waitFor(t, "the run to end", func() bool { return !pool.IsRunning() })
+
+	runs, _ := store.ListRuns(ctx, batch.ID)
+	if len(runs) != 1 || runs[0].Trigger != models.RunTriggerTakeover {
+		t.Fatalf("Expected one takeover run, got %+v", runs)
+	}
+	if got, _ := store.GetBatch(ctx, batch.ID); got.Status != models.BatchStatusCompleted {
+		t.Errorf("Expected the batch completed, got %s", got.Status)
+	}
+	if taken, _ := pool.TakeOverExpired(ctx); taken != 0 {
+		t.Errorf("Expected nothing left to take over, got %d", taken)
+	}
+}
+
+// TestBatchLeases verifies leases in PostgreSQL: one holder at a time,
+// renewals only by the holder, and takeover once a lease expired.
+func TestBatchLeases(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+
+	clk := clock.NewFake(time.Now())
+	repo := repository.New(db, repository.WithClock(clk))
+	batchID := createTestBatch(t, repo, 1)
+
+	if err := repo.AcquireBatchLease(ctx, batchID, "instance-a", time.Minute); err != nil {
+		t.Fatalf("Expected instance-a to get the lease, got %v", err)
+	}
+	if err := repo.AcquireBatchLease(ctx, batchID, "instance-b", time.Minute); !errors.Is(err, repository.ErrBatchLeased) {
+		t.Errorf("Expected ErrBatchLeased for instance-b, got %v", err)
+	}
+	if ok, err := repo.RenewBatchLease(ctx, batchID, "instance-b", time.Minute); ok || err != nil {
+		t.Errorf("Expected instance-b unable to renew, got %v (%v)", ok, err)
+	}
+
+	clk.Advance(50 * time.Second)
+	if ok, _ := repo.RenewBatchLease(ctx, batchID, "instance-a", time.Minute); !ok {
+		t.Error("Expected instance-a to renew its lease")
+	}
+	clk.Advance(50 * time.Second)
+	if err := repo.UpdateBatchStatus(ctx, batchID, models.BatchStatusInProgress); err != nil {
+		t.Fatalf("UpdateBatchStatus failed: %v", err)
+	}
+	if expired, _ := repo.FindExpiredLeases(ctx); len(expired) != 0 {
+		t.Errorf("Expected the renewed lease still live, got %v", expired)
+	}
+
+	clk.Advance(time.Minute)
+	if expired, _ := repo.FindExpiredLeases(ctx); len(expired) != 1 || expired[0] != batchID {
+		t.Errorf("Expected the batch's lease expired, got %v", expired)
+	}
+	if err := repo.AcquireBatchLease(ctx, batchID, "instance-b", time.Minute); err != nil {
+		t.Fatalf("Expected instance-b to take over the expired lease, got %v", err)
+	}
+	if ok, _ := repo.RenewBatchLease(ctx, batchID, "instance-a", time.Minute); ok {
+		t.Error("Expected instance-a to have lost its lease")
+	}
+	repo.ReleaseBatchLease(ctx, batchID, "instance-a")
+	if err := repo.AcquireBatchLease(ctx, batchID, "instance-a", time.Minute); !errors.Is(err, repository.ErrBatchLeased) {
+		t.Errorf("Expected instance-a's release to leave instance-b's lease, got %v", err)
+	}
+}
+
+// memClaims records the claim leases a run takes and renews.
+type memClaims struct {
+	mu     sync.Mutex
+	leases [][]uuid.UUID
+	resets int
+}
+
+func (c *memClaims) LeaseClaims(_ context.Context, payoutIDs []uuid.UUID, _ string, _ time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.leases = append(c.leases, payoutIDs)
+	return int64(len(payoutIDs)), nil
+}
+
+func (c *memClaims) ResetExpiredClaims(context.Context, uuid.UUID) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resets++
+	return 0, nil
+}
+
+func (c *memClaims) calls() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.leases), c.resets
+}
+
+// TestClaimLeasesRenewed verifies a run leases each chunk it claims, renews
+// the lease while the transfers are in flight, and looks for expired claims
+// before taking the batch as done.
+func TestClaimLeasesRenewed(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	batch := memBatch(t, store, 2)
+	claims := &memClaims{}
+	clk := clock.NewFake(time.Now())
+	bank := &gateBank{open: make(chan struct{})}
+	pool := worker.NewPool(store, 2, 2, worker.WithBankClient(bank), worker.WithClock(clk),
+		worker.WithClaimLeases(claims, "instance-a", 30*time.Second))
+
+	if _, err := pool.Start(batch.ID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "2 transfers in flight", func() bool { return bank.waiting.Load() == 2 })
+	if leases, _ := claims.calls(); leases != 1 || len(claims.leases[0]) != 2 {
+		t.Fatalf("Expected the chunk of 2 leased once, got %v", claims.leases)
+	}
+
+	clk.BlockUntil(1)
+	clk.Advance(10 * time.Second) // the next heartbeat
+	waitFor(t, "the claims renewed", func() bool {
+		leases, _ := claims.calls()
+		return leases == 2
+	})
+	close(bank.open)
+	waitFor(t, "the run to end", func() bool { return !pool.IsRunning() })
+
+	if _, resets := claims.calls(); resets == 0 {
+		t.Error("Expected expired claims looked for once nothing was left to claim")
+	}
+	if got, _ := store.GetBatch(ctx, batch.ID); got.Status != models.BatchStatusCompleted {
+		t.Errorf("Expected the batch completed, got %s", got.Status)
+	}
+}
+
+// TestPayoutClaimLeases verifies in PostgreSQL that payouts leased by a live
+// holder survive recovery, and are reset once their lease expired.
+func TestPayoutClaimLeases(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+
+	clk := clock.NewFake(time.Now())
+	repo := repository.New(db, repository.WithClock(clk))
+	batchID := createTestBatch(t, repo, 3)
+
+	claimed, err := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 2, nil)
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("Expected 2 payouts claimed, got %d (%v)", len(claimed), err)
+	}
+	ids := []uuid.UUID{claimed[0].ID, claimed[1].ID}
+	if n, err := repo.LeaseClaims(ctx, ids, "instance-a", time.Minute); n != 2 || err != nil {
+		t.Fatalf("Expected instance-a to lease 2 payouts, got %d (%v)", n, err)
+	}
+	if n, _ := repo.LeaseClaims(ctx, ids, "instance-b", time.Minute); n != 0 {
+		t.Errorf("Expected instance-b unable to lease live claims, got %d", n)
+	}
+
+	if n, _ := repo.ResetStuckProcessing(ctx, batchID); n != 0 {
+		t.Errorf("Expected live claims left in processing, got %d reset", n)
+	}
+	if n, _ := repo.ResetExpiredClaims(ctx, batchID); n != 0 {
+		t.Errorf("Expected no expired claims, got %d", n)
+	}
+
+	clk.Advance(50 * time.Second)
+	repo.LeaseClaims(ctx, ids[:1], "instance-a", time.Minute)
+	clk.Advance(20 * time.Second)
+	if n, _ := repo.ResetExpiredClaims(ctx, batchID); n != 1 {
+		t.Errorf("Expected the unrenewed claim reset, got %d", n)
+	}
+	p, err := repo.GetPayout(ctx, ids[1])
+	if err != nil || p.Status != models.PayoutStatusPending || p.ClaimedBy != nil {
+		t.Errorf("Expected the expired payout pending and unclaimed, got %+v (%v)", p, err)
+	}
+	p, _ = repo.GetPayout(ctx, ids[0])
+	if p.Status != models.PayoutStatusProcessing || p.ClaimedBy == nil || *p.ClaimedBy != "instance-a" {
+		t.Errorf("Expected the renewed payout still held by instance-a, got %+v", p)
+	}
+}
@@ -0,0 +1,595 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository/memstore"
+	"coding-challenge/internal/service"
+	"coding-challenge/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+func memBatch(t *testing.T, store *memstore.Store, count int) *models.PayoutBatch {
+	return memBatchWith(t, store, count, models.BatchOptions{})
+}
+
+func memBatchWith(t *testing.T, store *memstore.Store, count int, opts models.BatchOptions) *models.PayoutBatch {
+	items := make([]models.CreatePayoutItem, count)
+	for i := range items {
+		items[i] = models.CreatePayoutItem{
+			VendorID:    fmt.Sprintf("mem_vendor_%04d", i),
+			Amount:      100,
+			Currency:    "USD",
+			BankAccount: fmt.Sprintf("ACC%010d", i),
+			BankName:    "Test Bank",
+		}
+	}
+	batch, err := store.CreateBatch(context.Background(), items, opts)
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	return batch
+}
+
+// TestPoolWithMemStore verifies the pool processes a batch end to end
+// against the in-memory store, without a database.
+func TestPoolWithMemStore(t *testing.T) {
+	store := memstore.New()
+	batch := memBatch(t, store, 40)
+
+	sc := service.NewScenario()
+	sc.For(service.Vendors("mem_vendor_0001")).Fail(models.FailureBankTimeout, 1).ThenSucceed()
+	sc.For(service.Vendors("mem_vendor_0002")).Always(models.FailureAccountBlocked)
+	pool := worker.NewPool(store, 4, 10, worker.WithBankClient(sc))
+	if err := pool.ProcessBatch(context.Background(), batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	ctx := context.Background()
+	got, _ := store.GetBatch(ctx, batch.ID)
+	if got.Status != models.BatchStatusPartiallyCompleted || got.CompletedCount != 39 || got.FailedCount != 1 || got.PendingCount != 0 {
+		t.Errorf("Expected a partially completed batch with 39 completed and 1 failed, got %s (%d/%d/%d)",
+			got.Status, got.CompletedCount, got.FailedCount, got.PendingCount)
+	}
+	runs, _ := store.ListRuns(ctx, batch.ID)
+	if len(runs) != 1 || runs[0].Status != models.RunStatusFinished || runs[0].ProcessedCount != 41 {
+		t.Errorf("Expected one finished run with 41 attempts (40 payouts and a retry), got %+v", runs)
+	}
+	items, _, _ := store.GetPayoutsByBatch(ctx, batch.ID, "", 1, 100)
+	for _, item := range items {
+		attempts, _ := store.GetPayoutAttempts(ctx, item.ID)
+		if len(attempts) != item.AttemptCount {
+			t.Errorf("Expected %d attempts logged for %s, got %d", item.AttemptCount, item.VendorID, len(attempts))
+		}
+	}
+}
+
+// TestMemStoreSharedRuns verifies runs sharing a batch in the in-memory
+// store process all of it without executing a payout attempt twice.
+func TestMemStoreSharedRuns(t *testing.T) {
+	store := memstore.New()
+	batch := memBatch(t, store, 200)
+
+	bank := service.NewSimulator(service.UniformLatency{Min: 0, Max: time.Millisecond}, nil, service.WithLatencyScale(0))
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		pool := worker.NewPool(store, 4, 10, worker.WithBankClient(bank), worker.WithClaimStrategy(worker.ClaimHashBucket))
+		go func() { errs <- pool.ProcessBatch(context.Background(), batch.ID) }()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("ProcessBatch failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	stats, _ := store.GetBatchStatistics(ctx, batch.ID)
+	if stats.Completed+stats.Failed != 200 {
+		t.Errorf("Expected all 200 payouts processed, got %+v", stats)
+	}
+	items, _, _ := store.GetPayoutsByBatch(ctx, batch.ID, "", 1, 200)
+	for _, item := range items {
+		seen := map[int]bool{}
+		attempts, _ := store.GetPayoutAttempts(ctx, item.ID)
+		for _, a := range attempts {
+			if seen[a.AttemptNum] {
+				t.Errorf("Expected attempt %d of %s executed once", a.AttemptNum, item.VendorID)
+			}
+			seen[a.AttemptNum] = true
+		}
+	}
+}
+
+// gateBank holds every transfer until open is closed or its context ends.
+type gateBank struct {
+	open    chan struct{}
+	waiting atomic.Int32
+}
+
+func (b *gateBank) Transfer(ctx context.Context, payout models.Payout) (service.SimulatedBankResult, error) {
+	b.waiting.Add(1)
+	defer b.waiting.Add(-1)
+	select {
+	case <-b.open:
+		return service.SimulatedBankResult{Success: true}, nil
+	case <-ctx.Done():
+		return service.SimulatedBankResult{}, ctx.Err()
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestShutdownDrainsInFlightPayouts verifies Shutdown waits for the
+// transfers in progress, releases the rest of the chunk, leaves the batch
+// paused with nothing in processing and refuses new starts.
+func TestShutdownDrainsInFlightPayouts(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	batch := memBatch(t, store, 40)
+	bank := &gateBank{open: make(chan struct{})}
+	pool := worker.NewPool(store, 4, 10, worker.WithBankClient(bank))
+
+	if _, err := pool.Start(batch.ID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "4 transfers in flight", func() bool { return bank.waiting.Load() == 4 })
+
+	done := make(chan error, 1)
+	go func() { done <- pool.Shutdown(ctx) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected Shutdown to wait for the transfers in flight, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(bank.open)
+	if err := <-done; err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	got, _ := store.GetBatch(ctx, batch.ID)
+	stats, _ := store.GetBatchStatistics(ctx, batch.ID)
+	if got.Status != models.BatchStatusPaused || stats.Completed != 4 || stats.Pending != 36 || stats.Processing != 0 {
+		t.Errorf("Expected a paused batch with 4 completed and 36 pending, got %s %+v", got.Status, stats)
+	}
+	runs, _ := store.ListRuns(ctx, batch.ID)
+	if len(runs) != 1 || runs[0].Status != models.RunStatusStopped {
+		t.Errorf("Expected one stopped run, got %+v", runs)
+	}
+	if _, err := pool.Start(batch.ID, models.RunTriggerStart, "tester"); !errors.Is(err, worker.ErrShuttingDown) {
+		t.Errorf("Expected Start after Shutdown to fail with ErrShuttingDown, got %v", err)
+	}
+	if _, _, err := pool.Enqueue(batch.ID, models.RunTriggerStart, "tester"); !errors.Is(err, worker.ErrShuttingDown) {
+		t.Errorf("Expected Enqueue after Shutdown to fail with ErrShuttingDown, got %v", err)
+	}
+}
+
+// TestShutdownTimeout verifies Shutdown gives up at its deadline and
+// cancels the transfers still waiting on the bank.
+func TestShutdownTimeout(t *testing.T) {
+	store := memstore.New()
+	batch := memBatch(t, store, 10)
+	bank := &gateBank{open: make(chan struct{})}
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(bank))
+
+	if _, err := pool.Start(batch.ID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(t, "2 transfers in flight", func() bool { return bank.waiting.Load() == 2 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected Shutdown to time out, got %v", err)
+	}
+	waitFor(t, "the cancelled transfers to return", func() bool { return bank.waiting.Load() == 0 })
+	waitFor(t, "the run to end", func() bool { return !pool.IsRunning() })
+}
+
+// TestRunSettings verifies a run started with its own concurrency and chunk
+// size uses them instead of the pool's, and records them.
+func TestRunSettings(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	batch := memBatch(t, store, 20)
+	bank := &gateBank{open: make(chan struct{})}
+	pool := worker.NewPool(store, 4, 10, worker.WithBankClient(bank))
+
+	run, _, err := pool.EnqueueWith(batch.ID, models.RunTriggerStart, "tester", models.RunSettings{Concurrency: 2, ChunkSize: 5})
+	if err != nil || run == nil {
+		t.Fatalf("EnqueueWith failed: %v", err)
+	}
+	waitFor(t, "2 transfers in flight", func() bool { return bank.waiting.Load() == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if got := bank.waiting.Load(); got != 2 {
+		t.Errorf("Expected the run to send 2 transfers at once, got %d", got)
+	}
+	close(bank.open)
+	waitFor(t, "the run to end", func() bool { return !pool.IsRunning() })
+
+	runs, _ := store.ListRuns(ctx, batch.ID)
+	if len(runs) != 1 || runs[0].Concurrency != 2 || runs[0].ChunkSize != 5 || runs[0].ChunksCount != 4 {
+		t.Fatalf("Expected one run with concurrency 2 and 4 chunks of 5, got %+v", runs)
+	}
+
+	// Without settings a run takes the pool's.
+	other := memBatch(t, store, 5)
+	if err := pool.ProcessBatch(ctx, other.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	runs, _ = store.ListRuns(ctx, other.ID)
+	if len(runs) != 1 || runs[0].Concurrency != 4 || runs[0].ChunkSize != 10 {
+		t.Errorf("Expected the pool's concurrency 4 and chunk size 10, got %+v", runs)
+	}
+}
+
+// TestUpdateConfigMidRun verifies a run takes up a changed chunk size from
+// its next chunk, unless it was started with its own.
+func TestUpdateConfigMidRun(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+
+	for _, tc := range []struct {
+		name     string
+		settings models.RunSettings
+		chunks   int
+	}{
+		{"pool's chunk size", models.RunSettings{}, 7}, // the first chunk of 10, then 30 payouts in chunks of 5
+		{"own chunk size", models.RunSettings{ChunkSize: 10}, 4},
+	} {
+		batch := memBatch(t, store, 40)
+		bank := &gateBank{open: make(chan struct{})}
+		pool := worker.NewPool(store, 4, 10, worker.WithBankClient(bank))
+
+		if _, _, err := pool.EnqueueWith(batch.ID, models.RunTriggerStart, "tester", tc.settings); err != nil {
+			t.Fatalf("%s: EnqueueWith failed: %v", tc.name, err)
+		}
+		waitFor(t, "4 transfers in flight", func() bool { return bank.waiting.Load() == 4 })
+		five := 5
+		if _, err := pool.UpdateConfig(models.UpdateWorkerConfigRequest{ChunkSize: &five}); err != nil {
+			t.Fatalf("%s: UpdateConfig failed: %v", tc.name, err)
+		}
+		close(bank.open)
+		waitFor(t, "the run to end", func() bool { return !pool.IsRunning() })
+
+		runs, _ := store.ListRuns(ctx, batch.ID)
+		if len(runs) != 1 || runs[0].ChunksCount != tc.chunks || runs[0].CompletedCount != 40 {
+			t.Errorf("%s: expected one run completing 40 payouts in %d chunks, got %+v", tc.name, tc.chunks, runs)
+		}
+
+		bad := "max=1ms"
+		if _, err := pool.UpdateConfig(models.UpdateWorkerConfigRequest{RetryBackoff: &bad}); err == nil {
+			t.Errorf("%s: expected a backoff with max below base to be refused", tc.name)
+		}
+		if got := pool.Config().ChunkSize; got != 5 {
+			t.Errorf("%s: expected chunk size 5 after the refused change, got %d", tc.name, got)
+		}
+	}
+}
+
+// countingStore counts count refreshes.
+type countingStore struct {
+	*memstore.Store
+	refreshes atomic.Int32
+}
+
+func (s *countingStore) RefreshBatchCounts(ctx context.Context, batchID uuid.UUID) error {
+	s.refreshes.Add(1)
+	return s.Store.RefreshBatchCounts(ctx, batchID)
+}
+
+// TestProgressEvery verifies a batch's progress_every decides how often a
+// run refreshes its counts: once per chunk by default, or every N attempts.
+func TestProgressEvery(t *testing.T) {
+	for _, tc := range []struct {
+		every, want int32
+	}{
+		{0, 5},  // 4 chunks, then once when finished
+		{1, 41}, // every attempt
+		{8, 6},  // 40 attempts
+	} {
+		store := &countingStore{Store: memstore.New()}
+		batch := memBatchWith(t, store.Store, 40, models.BatchOptions{ProgressEvery: int(tc.every)})
+
+		pool := worker.NewPool(store, 4, 10, worker.WithBankClient(service.NewScenario()))
+		if err := pool.ProcessBatch(context.Background(), batch.ID); err != nil {
+			t.Fatalf("ProcessBatch failed: %v", err)
+		}
+		if got := store.refreshes.Load(); got != tc.want {
+			t.Errorf("progress_every=%d: expected %d count refreshes, got %d", tc.every, tc.want, got)
+		}
+	}
+}
+
+// TestResumeInProgress verifies batches left in progress by a crash are
+// resumed, stuck payouts included, one after the other, and that batches
+// that are pending or paused are left alone.
+func TestResumeInProgress(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	crashed := []*models.PayoutBatch{memBatch(t, store, 20), memBatch(t, store, 20)}
+	for _, b := range crashed {
+		if err := store.UpdateBatchStatus(ctx, b.ID, models.BatchStatusInProgress); err != nil {
+			t.Fatalf("UpdateBatchStatus failed: %v", err)
+		}
+		// Claimed by the crashed process and never finished.
+		if _, err := store.ClaimChunk(ctx, b.ID, models.PayoutOrderFIFO, 5, nil); err != nil {
+			t.Fatalf("ClaimChunk failed: %v", err)
+		}
+	}
+	pending := memBatch(t, store, 5)
+	paused := memBatch(t, store, 5)
+	if err := store.UpdateBatchStatus(ctx, paused.ID, models.BatchStatusPaused); err != nil {
+		t.Fatalf("UpdateBatchStatus failed: %v", err)
+	}
+
+	pool := worker.NewPool(store, 4, 10, worker.WithBankClient(service.NewScenario()))
+	resumed, err := pool.ResumeInProgress(ctx)
+	if err != nil || resumed != 2 {
+		t.Fatalf("Expected 2 batches resumed, got %d (%v)", resumed, err)
+	}
+	for _, b := range crashed {
+		waitFor(t, "the batch to complete", func() bool {
+			got, _ := store.GetBatch(ctx, b.ID)
+			return got.Status == models.BatchStatusCompleted
+		})
+		runs, _ := store.ListRuns(ctx, b.ID)
+		if len(runs) != 1 || runs[0].Trigger != models.RunTriggerAutoResume || runs[0].CompletedCount != 20 {
+			t.Errorf("Expected one auto_resume run completing all 20 payouts, got %+v", runs)
+		}
+	}
+	for _, b := range []*models.PayoutBatch{pending, paused} {
+		if runs, _ := store.ListRuns(ctx, b.ID); len(runs) != 0 {
+			t.Errorf("Expected batch %s left alone, got %d runs", b.ID, len(runs))
+		}
+	}
+}
+
+// TestRetryBackoff verifies a payout that failed retryably is only claimed
+// again once its backoff is over, waiting longer after each attempt, and
+// that the run waits for it rather than finishing.
+func TestRetryBackoff(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	fc := clock.NewFake(start)
+	store := memstore.New(memstore.WithClock(fc))
+	batch := memBatch(t, store, 3)
+
+	sc := service.NewScenario()
+	sc.For(service.Vendors("mem_vendor_0000")).Fail(models.FailureRateLimited, 2).ThenSucceed()
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(sc), worker.WithClock(fc),
+		worker.WithRetryBackoff(worker.RetryBackoff{Base: 10 * time.Second, Multiplier: 3, Max: time.Minute}))
+	done := make(chan error, 1)
+	go func() { done <- pool.ProcessBatch(context.Background(), batch.ID) }()
+
+	ctx := context.Background()
+	items, _, _ := store.GetPayoutsByBatch(ctx, batch.ID, "", 1, 10)
+	var payoutID uuid.UUID
+	for _, item := range items {
+		if item.VendorID == "mem_vendor_0000" {
+			payoutID = item.ID
+		}
+	}
+	expectBackoff := func(attempts int, until time.Time) {
+		t.Helper()
+		fc.BlockUntil(1)
+		p, _ := store.GetPayout(ctx, payoutID)
+		if p.Status != models.PayoutStatusPending || p.AttemptCount != attempts || p.NextRetryAt == nil || !p.NextRetryAt.Equal(until) {
+			t.Fatalf("Expected pending after %d attempts until %s, got %s after %d until %v",
+				attempts, until, p.Status, p.AttemptCount, p.NextRetryAt)
+		}
+	}
+
+	expectBackoff(1, start.Add(10*time.Second))
+	fc.Advance(5 * time.Second)
+	expectBackoff(1, start.Add(10*time.Second))
+	fc.Advance(5 * time.Second)
+	expectBackoff(2, start.Add(40*time.Second))
+	fc.Advance(30 * time.Second)
+
+	if err := <-done; err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	p, _ := store.GetPayout(ctx, payoutID)
+	if p.Status != models.PayoutStatusCompleted || p.AttemptCount != 3 || p.NextRetryAt != nil {
+		t.Errorf("Expected completed on the third attempt, got %s after %d (retry at %v)", p.Status, p.AttemptCount, p.NextRetryAt)
+	}
+	got, _ := store.GetBatch(ctx, batch.ID)
+	if got.Status != models.BatchStatusCompleted {
+		t.Errorf("Expected the batch completed, got %s", got.Status)
+	}
+}
+
+// TestBatchRetryPolicy verifies a batch's retry policy replaces the pool's
+// retry settings: its attempt budget, its backoff, and which retryable
+// failures are retried at all.
+func TestBatchRetryPolicy(t *testing.T) {
+	store := memstore.New()
+	sc := service.NewScenario()
+	sc.For(service.Vendors("policy_rate")).Fail(models.FailureRateLimited, 4).ThenSucceed()
+	sc.For(service.Vendors("policy_timeout")).Always(models.FailureBankTimeout)
+	sc.For(service.Vendors("policy_fast")).Fail(models.FailureRateLimited, 1).ThenSucceed()
+	// The pool's backoff would hold retries for an hour; the batches retry
+	// at once or not at all.
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(sc),
+		worker.WithRetryBackoff(worker.RetryBackoff{Base: time.Hour, Multiplier: 1, Max: time.Hour}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item := func(vendorID string) models.CreatePayoutItem {
+		return models.CreatePayoutItem{VendorID: vendorID, Amount: 10, Currency: "IDR", BankAccount: "1"}
+	}
+	payout := func(batchID uuid.UUID, vendorID string) models.Payout {
+		t.Helper()
+		var found models.Payout
+		store.EachPayout(ctx, batchID, func(p models.Payout) error {
+			if p.VendorID == vendorID {
+				found = p
+			}
+			return nil
+		})
+		return found
+	}
+
+	patient, err := store.CreateBatch(ctx, []models.CreatePayoutItem{item("policy_rate"), item("policy_timeout")}, models.BatchOptions{
+		RetryPolicy: &models.RetryPolicy{MaxAttempts: 5, Backoff: "off", RetryableCodes: []string{models.FailureRateLimited}},
+	})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(ctx, patient.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	if p := payout(patient.ID, "policy_rate"); p.Status != models.PayoutStatusCompleted || p.AttemptCount != 5 {
+		t.Errorf("Expected the rate-limited payout completed on its fifth attempt, got %s after %d", p.Status, p.AttemptCount)
+	}
+	if p := payout(patient.ID, "policy_timeout"); p.Status != models.PayoutStatusFailed || p.AttemptCount != 1 {
+		t.Errorf("Expected the timed-out payout failed without a retry, got %s after %d", p.Status, p.AttemptCount)
+	}
+
+	fast, err := store.CreateBatch(ctx, []models.CreatePayoutItem{item("policy_fast")}, models.BatchOptions{
+		RetryPolicy: &models.RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(ctx, fast.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	if p := payout(fast.ID, "policy_fast"); p.Status != models.PayoutStatusFailed || p.AttemptCount != 1 {
+		t.Errorf("Expected the fail-fast payout failed after one attempt, got %s after %d", p.Status, p.AttemptCount)
+	}
+	store.RetryFailedPayouts(ctx, fast.ID)
+	if p := payout(fast.ID, "policy_fast"); p.Status != models.PayoutStatusPending || p.MaxRetries != 2 {
+		t.Errorf("Expected a manual retry to grant one more attempt, got %s with a budget of %d", p.Status, p.MaxRetries)
+	}
+}
+
+// memSnapshots records the reasons statistics were snapshotted for.
+type memSnapshots struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (s *memSnapshots) SnapshotStatistics(_ context.Context, _, _ uuid.UUID, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reasons = append(s.reasons, reason)
+	return nil
+}
+
+// TestStatisticsSnapshots verifies a run snapshots its batch's statistics
+// when it starts, between chunks and when it ends.
+func TestStatisticsSnapshots(t *testing.T) {
+	store := memstore.New()
+	batch := memBatch(t, store, 10)
+	snapshots := &memSnapshots{}
+	pool := worker.NewPool(store, 2, 5, worker.WithBankClient(service.NewScenario()),
+		worker.WithStatisticsSnapshots(snapshots, 0))
+
+	if err := pool.ProcessBatch(context.Background(), batch.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	want := []string{models.SnapshotRunStarted, models.SnapshotInterval, models.SnapshotInterval, models.SnapshotRunEnded}
+	if strings.Join(snapshots.reasons, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected snapshots %v, got %v", want, snapshots.reasons)
+	}
+}
+
+// TestWatchdogFailsStalledCreation verifies the watchdog fails a batch whose
+// asynchronous creation made no progress within the stall window, and
+// leaves one still ingesting alone.
+func TestWatchdogFailsStalledCreation(t *testing.T) {
+	ctx := context.Background()
+	fc := clock.NewFake(time.Now())
+	store := memstore.New(memstore.WithClock(fc))
+	items := []models.CreatePayoutItem{{VendorID: "V-1", Amount: 1, Currency: "IDR"}}
+	stalled, _ := store.BeginBatch(ctx, items, models.BatchOptions{})
+	fc.Advance(8 * time.Minute)
+	fresh, _ := store.BeginBatch(ctx, items, models.BatchOptions{})
+	fc.Advance(3 * time.Minute)
+
+	pool := worker.NewPool(store, 2, 10, worker.WithClock(fc))
+	if _, err := worker.NewWatchdog(store, pool, time.Minute, 10*time.Minute).Check(ctx); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if got, _ := store.GetBatch(ctx, stalled.ID); got.Status != models.BatchStatusFailed || got.CreationError == nil {
+		t.Errorf("Expected the stalled creation failed, got %s", got.Status)
+	}
+	if got, _ := store.GetBatch(ctx, fresh.ID); got.Status != models.BatchStatusCreating {
+		t.Errorf("Expected the recent creation left creating, got %s", got.Status)
+	}
+}
+
+// settlementRecorder records the settlement reports sent.
+type settlementRecorder struct {
+	mu      sync.Mutex
+	reports []models.SettlementReport
+}
+
+func (s *settlementRecorder) PayoutSent(context.Context, models.Payout)           {}
+func (s *settlementRecorder) PayoutFailed(context.Context, models.Payout, string) {}
+func (s *settlementRecorder) BatchFinished(context.Context, models.PayoutBatch)   {}
+func (s *settlementRecorder) SettlementReport(_ context.Context, _ models.PayoutBatch, r models.SettlementReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = append(s.reports, r)
+}
+
+// TestSettlementReport verifies a batch ending partially completed sends
+// one settlement report listing who was paid, who was not and what to do
+// about it, and that a fully paid batch sends none.
+func TestSettlementReport(t *testing.T) {
+	store := memstore.New()
+	sc := service.NewScenario()
+	sc.For(service.Vendors("mem_vendor_0001")).Always(models.FailureInvalidBankAccount)
+	recorder := &settlementRecorder{}
+	pool := worker.NewPool(store, 2, 10, worker.WithBankClient(sc), worker.WithNotifier(recorder))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	partial := memBatch(t, store, 3)
+	if err := pool.ProcessBatch(ctx, partial.ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	if err := pool.ProcessBatch(ctx, memBatch(t, store, 1).ID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.reports) != 1 {
+		t.Fatalf("Expected 1 settlement report, got %d", len(recorder.reports))
+	}
+	report := recorder.reports[0]
+	if report.BatchID != partial.ID || report.Status != models.BatchStatusPartiallyCompleted {
+		t.Errorf("Expected the report of the partial batch, got %s (%s)", report.BatchID, report.Status)
+	}
+	if report.PaidCount != 2 || len(report.Paid) != 2 || report.Paid[0].Reference == "" {
+		t.Errorf("Expected 2 paid payouts with their references, got %d: %+v", report.PaidCount, report.Paid)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].VendorID != "mem_vendor_0001" ||
+		report.Failed[0].FailureReason != models.FailureInvalidBankAccount || report.Failed[0].Action != models.ActionUpdateBankDetails {
+		t.Errorf("Expected mem_vendor_0001 unpaid, to update bank details, got %+v", report.Failed)
+	}
+	if len(report.FailedAmounts) != 1 || report.FailedAmounts[0].Amount != 100 {
+		t.Errorf("Expected 100 USD unpaid, got %+v", report.FailedAmounts)
+	}
+}
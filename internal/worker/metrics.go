@@ -0,0 +1,35 @@
+package worker
+
+import "time"
+
+// Metrics is told what the pool spends its time on, for monitoring. Payout
+// outcomes are left to hooks (AfterResult). Implementations must not block.
+type Metrics interface {
+	// BankCall reports how long a transfer the bank answered took.
+	BankCall(took time.Duration)
+	// ChunkProcessed reports a chunk of payouts taken up and how long it took.
+	ChunkProcessed(payouts int, took time.Duration)
+	// WorkerBusy reports a worker taking up (+1) or finishing (-1) a payout.
+	WorkerBusy(delta int)
+	// Throttled reports time a run was held back by the governor, for
+	// reason ThrottleInFlight or ThrottleDBWrites.
+	Throttled(reason string, took time.Duration)
+	// AttemptLogsDropped reports payout attempts whose write failed and
+	// that could be neither retried nor spilled.
+	AttemptLogsDropped(n int)
+}
+
+// WithMetrics sets where the pool reports bank latency, chunk durations,
+// busy workers and throttling (nowhere by default).
+func WithMetrics(m Metrics) Option {
+	return func(p *Pool) { p.metrics = m }
+}
+
+// noMetrics is the default Metrics, which discards everything.
+type noMetrics struct{}
+
+func (noMetrics) BankCall(time.Duration)            {}
+func (noMetrics) ChunkProcessed(int, time.Duration) {}
+func (noMetrics) WorkerBusy(int)                    {}
+func (noMetrics) Throttled(string, time.Duration)   {}
+func (noMetrics) AttemptLogsDropped(int)            {}
@@ -2,224 +2,934 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"coding-challenge/internal/clock"
 	"coding-challenge/internal/models"
-	"coding-challenge/internal/repository"
 	"coding-challenge/internal/service"
 
 	"github.com/google/uuid"
 )
 
+// ErrBusy is returned by Start when the pool is already processing a batch.
+var ErrBusy = errors.New("a batch is already being processed")
+
+// ErrShuttingDown is returned when a batch is started after Shutdown.
+var ErrShuttingDown = errors.New("the worker pool is shutting down")
+
+// ErrBatchCancelled ends a run on a batch that was cancelled before it began.
+var ErrBatchCancelled = errors.New("the batch is cancelled")
+
 // Pool manages concurrent payout processing workers.
 type Pool struct {
-	repo        *repository.Repository
+	repo        Store
+	cfgMu       sync.RWMutex // protects concurrency, chunkSize and retryBackoff
 	concurrency int
 	chunkSize   int
-	mu          sync.Mutex  // protects stopCh
-	stopCh      chan struct{}
+	mu          sync.Mutex                  // protects runs, queue and closing
+	runs        map[uuid.UUID]chan struct{} // stop channel of each batch being processed
+	queue       []queuedStart               // batches waiting for the pool, first to run first
+	closing     bool                        // set by Shutdown; no new runs start
+	active      sync.WaitGroup              // runs being processed, for Shutdown to wait on
 	running     atomic.Bool
+	ctx         context.Context // of runs started with Start; cancelled when Shutdown gives up
+	cancel      context.CancelFunc
+	rampPeriod  time.Duration
+	bank        service.BankClient
+	environment string
+	notifiers   []Notifier
+	clock       clock.Clock
+	hooks       hookChain
+	metrics     Metrics
+
+	claimStrategy string
+
+	chunkTargetLow, chunkTargetHigh time.Duration // chunk size tuning; zero when fixed
+
+	inFlightLimits map[string]float64 // per currency; nil when uncapped
+	retryBackoff   RetryBackoff       // zero retries at once
+
+	leases      LeaseStore // nil when instances share batches
+	leaseHolder string
+	leaseTTL    time.Duration
+
+	claimLeases ClaimLeaseStore // nil when claims are not leased
+	claimHolder string
+	claimTTL    time.Duration
+
+	snapshots     SnapshotStore // nil when statistics are not snapshotted
+	snapshotEvery time.Duration
+
+	governor *Governor // nil when runs are bound by their own settings only
+
+	attemptLog *attemptLog // nil when failed attempt writes are dropped
+}
+
+// Notifier is told when a payout reaches a final outcome, e.g. to email the
+// vendor, and when a run leaves a batch finished or paused on held payouts,
+// e.g. to email its assignee. Implementations must not block.
+type Notifier interface {
+	PayoutSent(ctx context.Context, payout models.Payout)
+	PayoutFailed(ctx context.Context, payout models.Payout, reason string)
+	BatchFinished(ctx context.Context, batch models.PayoutBatch)
+}
+
+// SettlementNotifier is a Notifier that is also sent the settlement report
+// of a batch a run leaves partially completed, e.g. to post it to the
+// merchant. Implementations must not block.
+type SettlementNotifier interface {
+	SettlementReport(ctx context.Context, batch models.PayoutBatch, report models.SettlementReport)
+}
+
+// settlementReportLimit caps the paid and the failed payouts listed in a
+// settlement report sent to notifiers; the full report is served by the API.
+const settlementReportLimit = 10000
+
+// Option configures optional Pool behaviour.
+type Option func(*Pool)
+
+// WithRampUp makes each run start at a concurrency of 1 and grow to the full
+// concurrency over period, backing off when transient failures spike.
+func WithRampUp(period time.Duration) Option {
+	return func(p *Pool) { p.rampPeriod = period }
+}
+
+// WithBankClient sets the bank used for transfers (the default simulator otherwise).
+func WithBankClient(bank service.BankClient) Option {
+	return func(p *Pool) { p.bank = bank }
+}
+
+// WithEnvironment sets the bank environment the bank client transfers in,
+// recorded on every run and pinned on each batch by its first run
+// (models.EnvironmentSandbox by default).
+func WithEnvironment(environment string) Option {
+	return func(p *Pool) { p.environment = environment }
+}
+
+// WithNotifier adds someone to tell about completed and permanently failed
+// payouts and finished batches, after any added before.
+func WithNotifier(n Notifier) Option {
+	return func(p *Pool) { p.notifiers = append(p.notifiers, n) }
+}
+
+// WithClock sets the clock used for ramp-up timing, attempt timestamps and
+// the watchdog (the system clock by default).
+func WithClock(c clock.Clock) Option {
+	return func(p *Pool) { p.clock = c }
 }
 
 // NewPool creates a new worker pool.
-func NewPool(repo *repository.Repository, concurrency, chunkSize int) *Pool {
-	return &Pool{
+func NewPool(repo Store, concurrency, chunkSize int, opts ...Option) *Pool {
+	p := &Pool{
 		repo:        repo,
 		concurrency: concurrency,
 		chunkSize:   chunkSize,
-		stopCh:      make(chan struct{}),
+		runs:        make(map[uuid.UUID]chan struct{}),
+		bank:        service.DefaultSimulator(),
+		environment: models.EnvironmentSandbox,
+		clock:       clock.Real,
+		metrics:     noMetrics{},
+
+		claimStrategy: ClaimOrdered,
 	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// runCounters tallies payout outcomes within a single run.
+type runCounters struct {
+	chunks    atomic.Int64
+	processed atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+	transient atomic.Int64 // retryable failures (timeouts, rate limits)
+	recorded  atomic.Int64 // attempts recorded, for progressEvery
+
+	progressEvery int64 // refresh counts every this many attempts; 0 once per chunk
+	policy        *models.RetryPolicy
+	backoff       *RetryBackoff // the batch's own, in place of the pool's
+
+	mu      sync.Mutex
+	haltErr error // first hook error, which stops the run
+}
+
+// halt records the error that stops the run; later ones are dropped.
+func (c *runCounters) halt(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.haltErr == nil {
+		c.haltErr = err
+	}
+}
+
+// halted returns the error that stopped the run, if any.
+func (c *runCounters) halted() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.haltErr
+}
+
+// Start reserves funding for the batch, records a new run and processes it in
+// the background, bypassing the queue (see Enqueue). triggeredBy identifies
+// the operator or system that requested the run. It returns ErrBusy if the
+// pool is already processing a batch, repository.ErrEnvironmentMismatch if
+// the batch was executed in another bank environment, and
+// repository.ErrInsufficientFunding if the batch cannot be funded, and
+// ErrShuttingDown once Shutdown has been called.
+func (p *Pool) Start(batchID uuid.UUID, trigger, triggeredBy string) (*models.BatchRun, error) {
+	if !p.running.CompareAndSwap(false, true) {
+		return nil, ErrBusy
+	}
+	return p.begin(batchID, trigger, triggeredBy, models.RunSettings{})
+}
+
+// begin starts a run once the pool has been claimed for it.
+func (p *Pool) begin(batchID uuid.UUID, trigger, triggeredBy string, settings models.RunSettings) (*models.BatchRun, error) {
+	stopCh, err := p.track(batchID)
+	if err != nil {
+		p.running.Store(false)
+		return nil, err
+	}
+
+	ctx := p.ctx
+	if err := p.acquireLease(ctx, batchID); err != nil {
+		p.finish(batchID)
+		return nil, err
+	}
+	if err := p.repo.PinEnvironment(ctx, batchID, p.environment); err != nil {
+		p.finish(batchID)
+		return nil, err
+	}
+	if err := p.repo.ReserveFunding(ctx, batchID); err != nil {
+		p.finish(batchID)
+		return nil, err
+	}
+	run, err := p.repo.CreateRun(ctx, batchID, trigger, triggeredBy, p.environment, p.runSettings(settings))
+	if err != nil {
+		p.finish(batchID)
+		return nil, err
+	}
+
+	go func() {
+		defer p.finish(batchID)
+		if err := p.execute(ctx, stopCh, run, settings); err != nil {
+			log.Printf("[processor] Error processing batch %s (run %s): %v", batchID, run.ID, err)
+		}
+	}()
+	return run, nil
 }
 
-// ProcessBatch processes all pending payouts in a batch using a worker pool.
-// It is resumable — only processes pending/stuck payouts.
+// ProcessBatch processes all pending payouts in a batch using a worker pool,
+// blocking until the run ends. It is resumable — only processes pending/stuck payouts.
 func (p *Pool) ProcessBatch(ctx context.Context, batchID uuid.UUID) error {
 	if !p.running.CompareAndSwap(false, true) {
 		return nil // Already running
 	}
-	defer p.running.Store(false)
+	stopCh, err := p.track(batchID)
+	if err != nil {
+		p.running.Store(false)
+		return err
+	}
+	defer p.finish(batchID)
+
+	if err := p.acquireLease(ctx, batchID); err != nil {
+		return err
+	}
+	if err := p.repo.PinEnvironment(ctx, batchID, p.environment); err != nil {
+		return err
+	}
+	if err := p.repo.ReserveFunding(ctx, batchID); err != nil {
+		return err
+	}
+	run, err := p.repo.CreateRun(ctx, batchID, models.RunTriggerStart, models.AnonymousOperator, p.environment, p.runSettings(models.RunSettings{}))
+	if err != nil {
+		return err
+	}
+	return p.execute(ctx, stopCh, run, models.RunSettings{})
+}
+
+// track records a batch as being processed and returns the channel that
+// StopBatch closes to stop its run, or ErrShuttingDown after Shutdown.
+func (p *Pool) track(batchID uuid.UUID) (chan struct{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closing {
+		return nil, ErrShuttingDown
+	}
+	stopCh := make(chan struct{})
+	p.runs[batchID] = stopCh
+	p.active.Add(1)
+	return stopCh, nil
+}
 
-	// Create a fresh stop channel for this run so the pool can be reused after Stop().
+// finish forgets a batch once its run ends, releasing its lease and marking
+// the pool idle, and starts the next queued batch, if any.
+func (p *Pool) finish(batchID uuid.UUID) {
+	p.releaseLease(batchID)
 	p.mu.Lock()
-	p.stopCh = make(chan struct{})
-	stopCh := p.stopCh
+	delete(p.runs, batchID)
 	p.mu.Unlock()
+	p.running.Store(false)
+	p.active.Done()
+	go p.startNext()
+}
 
-	log.Printf("[processor] Starting batch %s with concurrency=%d, chunk=%d", batchID, p.concurrency, p.chunkSize)
+// execute runs the batch and records the run outcome. requested holds the
+// settings the run was started with, which config changes leave alone.
+func (p *Pool) execute(ctx context.Context, stopCh chan struct{}, run *models.BatchRun, requested models.RunSettings) error {
+	counters := &runCounters{}
+	stopped, err := p.process(ctx, stopCh, run, requested, counters)
 
-	// Step 1: Reset any payouts stuck in "processing" from a previous crash
-	reset, err := p.repo.ResetStuckProcessing(ctx, batchID)
-	if err != nil {
+	run.ChunksCount = int(counters.chunks.Load())
+	run.ProcessedCount = int(counters.processed.Load())
+	run.CompletedCount = int(counters.completed.Load())
+	run.FailedCount = int(counters.failed.Load())
+	switch {
+	case err != nil:
+		run.Status = models.RunStatusFailed
+		msg := err.Error()
+		run.Error = &msg
+	case stopped:
+		run.Status = models.RunStatusStopped
+	default:
+		run.Status = models.RunStatusFinished
+	}
+
+	// Record the outcome even if the processing context was cancelled.
+	if ferr := p.repo.FinishRun(context.Background(), run); ferr != nil {
+		log.Printf("[processor] Warning: failed to record run %s: %v", run.ID, ferr)
+	}
+	p.snapshot(context.Background(), run, models.SnapshotRunEnded)
+	return err
+}
+
+// process works through the batch until no pending payouts remain or it is
+// stopped. It reports whether processing ended because of a stop signal.
+// Changes to the pool's config are picked up between chunks.
+func (p *Pool) process(ctx context.Context, stopCh chan struct{}, run *models.BatchRun, requested models.RunSettings, counters *runCounters) (bool, error) {
+	batchID := run.BatchID
+	log.Printf("[processor] Starting batch %s with concurrency=%d, chunk=%d", batchID, run.Concurrency, run.ChunkSize)
+
+	// Step 1: Join the batch's live runs. If there are none, any payout stuck
+	// in "processing" is left over from a crash and is reset first.
+	var reset int64
+	lock, err := p.repo.LockRun(ctx, batchID, func() error {
+		var err error
+		reset, err = p.repo.ResetStuckProcessing(ctx, batchID)
 		return err
+	})
+	if err != nil {
+		return false, err
 	}
+	defer lock.Release()
 	if reset > 0 {
 		log.Printf("[processor] Reset %d stuck payouts back to pending", reset)
 	}
 
+	// Keep the batch's lease while the run lasts.
+	var leaseLost atomic.Bool
+	if p.leases != nil {
+		beatCtx, stopBeats := context.WithCancel(ctx)
+		defer stopBeats()
+		go p.heartbeat(beatCtx, batchID, &leaseLost)
+	}
+
+	batch, err := p.repo.GetBatch(ctx, batchID)
+	if err != nil {
+		return false, err
+	}
+	if batch == nil {
+		return false, fmt.Errorf("batch %s not found", batchID)
+	}
+	if batch.Status == models.BatchStatusCancelled {
+		return false, ErrBatchCancelled
+	}
+
+	counters.progressEvery = int64(batch.ProgressEvery)
+	counters.policy = batch.RetryPolicy
+	if batch.RetryPolicy != nil && batch.RetryPolicy.Backoff != "" {
+		b, err := ParseRetryBackoff(batch.RetryPolicy.Backoff)
+		if err != nil {
+			log.Printf("[processor] Warning: ignoring the retry backoff of batch %s: %v", batchID, err)
+		} else {
+			counters.backoff = &b
+		}
+	}
+
 	// Step 2: Mark batch as in_progress
 	if err := p.repo.UpdateBatchStatus(ctx, batchID, models.BatchStatusInProgress); err != nil {
-		return err
+		return false, err
 	}
+	p.snapshot(ctx, run, models.SnapshotRunStarted)
+	lastSnapshot := p.clock.Now()
 
 	// Step 3: Process in chunks
+	ramp := newRampUp(run.Concurrency, p.rampPeriod, p.clock.Now())
+	claims := p.newClaimer(run, batch)
+	tuner := newChunkTuner(run.ChunkSize, p.chunkTargetLow, p.chunkTargetHigh)
+	current := run.RunSettings
+	capped := false
 	for {
 		select {
 		case <-stopCh:
 			log.Printf("[processor] Received stop signal, pausing batch %s", batchID)
-			return nil
+			// Park the batch as paused so it isn't mistaken for a stalled one.
+			paused, err := p.repo.PauseBatch(ctx, batchID)
+			if err != nil {
+				return true, err
+			}
+			if !paused {
+				// No longer in progress, e.g. cancelled: settle the transfers
+				// that were in flight.
+				if _, _, err := p.repo.FinalizeBatch(ctx, batchID); err != nil {
+					return true, err
+				}
+			}
+			_ = p.repo.RefreshBatchCounts(ctx, batchID)
+			return true, nil
 		case <-ctx.Done():
-			return ctx.Err()
+			return false, ctx.Err()
 		default:
 		}
+		if leaseLost.Load() {
+			// The batch is another instance's now: leave it in_progress.
+			log.Printf("[processor] Ending run on batch %s: %v", batchID, ErrLeaseLost)
+			return false, ErrLeaseLost
+		}
+
+		// Pick up a config change since the last chunk
+		if live := p.runSettings(requested); live != current {
+			log.Printf("[processor] Worker config changed, batch %s continues with concurrency=%d, chunk=%d",
+				batchID, live.Concurrency, live.ChunkSize)
+			ramp.max = live.Concurrency
+			if live.ChunkSize != current.ChunkSize {
+				tuner = newChunkTuner(live.ChunkSize, p.chunkTargetLow, p.chunkTargetHigh)
+				claims.size = live.ChunkSize
+			}
+			current = live
+		}
 
-		// Fetch next chunk of pending payouts
-		payouts, err := p.repo.GetPendingPayouts(ctx, batchID, p.chunkSize)
+		// Claim the next chunk of pending payouts, as far as the governor
+		// leaves room
+		room, err := p.governedLimit(ctx, stopCh, claims.size)
 		if err != nil {
-			return err
+			return false, err
+		}
+		if room == 0 {
+			continue // Stopped while held back
+		}
+		payouts, err := claims.next(ctx, room)
+		if err != nil {
+			return false, err
 		}
 
 		if len(payouts) == 0 {
-			break // All done
+			// Take back payouts whose holder stopped renewing their claims.
+			expired, err := p.resetExpiredClaims(ctx, batchID)
+			if err != nil {
+				return false, err
+			}
+			if expired > 0 {
+				log.Printf("[processor] Reset %d payouts whose claim lease expired back to pending", expired)
+				continue
+			}
+			backoff := p.backoffFor(counters)
+			if len(p.inFlightLimits) == 0 && !backoff.enabled() {
+				break // All done
+			}
+			left, err := p.repo.HasClaimablePayouts(ctx, batchID)
+			if err != nil {
+				return false, err
+			}
+			if !left {
+				break // All done
+			}
+			// At the in-flight limit or with every payout left backing off:
+			// wait for confirmations or a retry to come due, then try again.
+			if !capped {
+				log.Printf("[processor] Nothing claimable yet, batch %s waits for confirmations or retries", batchID)
+			}
+			capped = true
+			poll := inFlightPoll
+			if backoff.enabled() {
+				poll = retryPoll
+			}
+			timer := p.clock.NewTimer(poll)
+			select {
+			case <-timer.C():
+			case <-stopCh:
+				timer.Stop()
+			case <-ctx.Done():
+				timer.Stop()
+			}
+			continue
+		}
+		capped = false
+		if err := p.leaseClaims(ctx, payouts); err != nil {
+			if _, rerr := p.repo.ReleaseClaims(context.Background(), payoutIDs(payouts)); rerr != nil {
+				log.Printf("[processor] Warning: failed to release unleased claims: %v", rerr)
+			}
+			return false, err
 		}
 
-		log.Printf("[processor] Processing chunk of %d payouts", len(payouts))
+		limit := ramp.limit(p.clock.Now())
+		log.Printf("[processor] Processing chunk of %d payouts (concurrency=%d)", len(payouts), limit)
 
 		// Process chunk with worker pool
-		p.processChunk(ctx, stopCh, payouts)
+		processedBefore, transientBefore := counters.processed.Load(), counters.transient.Load()
+		chunkStart := p.clock.Now()
+		// Keep the chunk's claims while its transfers are in flight.
+		beatCtx, stopBeats := context.WithCancel(ctx)
+		if p.claimLeases != nil {
+			go p.heartbeatClaims(beatCtx, payouts)
+		}
+		p.processChunk(ctx, stopCh, payouts, counters, limit)
+		stopBeats()
+		counters.chunks.Add(1)
+
+		if err := counters.halted(); err != nil {
+			log.Printf("[processor] Pausing batch %s: %v", batchID, err)
+			if _, perr := p.repo.PauseBatch(ctx, batchID); perr != nil {
+				log.Printf("[processor] Warning: failed to pause batch %s: %v", batchID, perr)
+			}
+			_ = p.repo.RefreshBatchCounts(ctx, batchID)
+			return false, err
+		}
+
+		attempts := int(counters.processed.Load() - processedBefore)
+		transient := int(counters.transient.Load() - transientBefore)
+		if ramp.observe(p.clock.Now(), attempts, transient) {
+			log.Printf("[processor] %d/%d transient failures in chunk, ramping concurrency back to %d",
+				transient, attempts, ramp.limit(p.clock.Now()))
+		}
+		took := p.clock.Now().Sub(chunkStart)
+		p.metrics.ChunkProcessed(len(payouts), took)
+		if tuner.observe(len(payouts), took) {
+			log.Printf("[processor] Chunk of %d payouts took %s, claiming %d per chunk from now on", len(payouts), took.Round(time.Millisecond), tuner.size)
+			claims.size = tuner.size
+		}
 
-		// Refresh batch counts
-		if err := p.repo.RefreshBatchCounts(ctx, batchID); err != nil {
-			log.Printf("[processor] Warning: failed to refresh counts: %v", err)
+		// Refresh batch counts, unless the batch has them refreshed by payout
+		if counters.progressEvery == 0 {
+			if err := p.repo.RefreshBatchCounts(ctx, batchID); err != nil {
+				log.Printf("[processor] Warning: failed to refresh counts: %v", err)
+			}
+		}
+		if p.snapshots != nil && p.clock.Now().Sub(lastSnapshot) >= p.snapshotEvery {
+			p.snapshot(ctx, run, models.SnapshotInterval)
+			lastSnapshot = p.clock.Now()
 		}
 	}
 
-	// Step 4: Determine final batch status
-	stats, err := p.repo.GetBatchStatistics(ctx, batchID)
+	// Step 4: Determine final batch status, once across every run of the batch
+	status, finalized, err := p.repo.FinalizeBatch(ctx, batchID)
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	var finalStatus string
-	switch {
-	case stats.Failed == 0:
-		finalStatus = models.BatchStatusCompleted
-	case stats.Completed == 0:
-		finalStatus = models.BatchStatusFailed
-	default:
-		finalStatus = models.BatchStatusPartiallyCompleted
+	_ = p.repo.RefreshBatchCounts(ctx, batchID)
+	if !finalized {
+		log.Printf("[processor] Batch %s left to its other runs to finalize (status %s)", batchID, status)
+		return false, nil
 	}
 
-	if err := p.repo.UpdateBatchStatus(ctx, batchID, finalStatus); err != nil {
-		return err
+	if status == models.BatchStatusPaused {
+		log.Printf("[processor] Pausing batch %s: payouts on hold", batchID)
+	} else {
+		stats, err := p.repo.GetBatchStatistics(ctx, batchID)
+		if err != nil {
+			return false, err
+		}
+		log.Printf("[processor] Batch %s finished: %s (completed=%d, failed=%d)",
+			batchID, status, stats.Completed, stats.Failed)
 	}
+	p.notifyBatch(ctx, batchID)
 
-	// Final count refresh
-	_ = p.repo.RefreshBatchCounts(ctx, batchID)
+	return false, nil
+}
 
-	log.Printf("[processor] Batch %s finished: %s (completed=%d, failed=%d)",
-		batchID, finalStatus, stats.Completed, stats.Failed)
+// notifyBatch tells the notifiers, if any, how a run left the batch.
+func (p *Pool) notifyBatch(ctx context.Context, batchID uuid.UUID) {
+	if len(p.notifiers) == 0 {
+		return
+	}
+	batch, err := p.repo.GetBatch(ctx, batchID)
+	if err != nil || batch == nil {
+		log.Printf("[processor] Warning: failed to load batch %s to notify: %v", batchID, err)
+		return
+	}
+	for _, n := range p.notifiers {
+		n.BatchFinished(ctx, *batch)
+	}
+	if batch.Status == models.BatchStatusPartiallyCompleted {
+		p.notifySettlement(ctx, *batch)
+	}
+}
 
-	return nil
+// notifySettlement sends the settlement report of a partially completed
+// batch to the notifiers that take one.
+func (p *Pool) notifySettlement(ctx context.Context, batch models.PayoutBatch) {
+	var sinks []SettlementNotifier
+	for _, n := range p.notifiers {
+		if s, ok := n.(SettlementNotifier); ok {
+			sinks = append(sinks, s)
+		}
+	}
+	if len(sinks) == 0 {
+		return
+	}
+	report := models.NewSettlementReport(batch, settlementReportLimit, p.clock.Now().UTC())
+	if err := p.repo.EachPayout(ctx, batch.ID, func(payout models.Payout) error {
+		report.Add(payout)
+		return nil
+	}); err != nil {
+		log.Printf("[processor] Warning: failed to build the settlement report of batch %s: %v", batch.ID, err)
+		return
+	}
+	for _, s := range sinks {
+		s.SettlementReport(ctx, batch, *report)
+	}
 }
 
-// processChunk processes a slice of payouts concurrently.
-func (p *Pool) processChunk(ctx context.Context, stopCh chan struct{}, payouts []models.Payout) {
+// processChunk processes a claimed chunk of payouts concurrently. Payouts
+// left unattempted by a stop or a halt are released back to pending.
+func (p *Pool) processChunk(ctx context.Context, stopCh chan struct{}, payouts []models.Payout, counters *runCounters, concurrency int) {
 	var wg sync.WaitGroup
-	sem := make(chan struct{}, p.concurrency)
-
-outer:
-	for _, payout := range payouts {
+	var mu sync.Mutex
+	var unattempted []uuid.UUID
+	release := func(ps ...models.Payout) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, po := range ps {
+			unattempted = append(unattempted, po.ID)
+		}
+	}
+	stopped := func() bool {
 		select {
 		case <-stopCh:
-			break outer
+			return true
 		case <-ctx.Done():
-			break outer
+			return true
 		default:
+			return counters.halted() != nil
 		}
+	}
+	sem := make(chan struct{}, concurrency)
 
-		wg.Add(1)
+	for i, payout := range payouts {
 		sem <- struct{}{} // Acquire slot
+		// Checked once a slot is free, so a stop that came while waiting
+		// for one doesn't start another payout.
+		if stopped() {
+			<-sem
+			release(payouts[i:]...)
+			break
+		}
 
+		wg.Add(1)
 		go func(po models.Payout) {
 			defer wg.Done()
 			defer func() { <-sem }() // Release slot
+			p.metrics.WorkerBusy(1)
+			defer p.metrics.WorkerBusy(-1)
 
-			p.processSinglePayout(ctx, po)
+			if !p.processSinglePayout(ctx, po, counters) {
+				release(po)
+			}
 		}(payout)
 	}
 
 	wg.Wait()
+	if len(unattempted) == 0 {
+		return
+	}
+	// Released even if the run was cancelled, so the next run sees them pending.
+	if _, err := p.repo.ReleaseClaims(context.WithoutCancel(ctx), unattempted); err != nil {
+		log.Printf("[processor] Warning: failed to release %d claimed payouts: %v", len(unattempted), err)
+	}
 }
 
-// processSinglePayout handles one payout with claim → execute → record.
-func (p *Pool) processSinglePayout(ctx context.Context, payout models.Payout) {
-	// Step 1: Claim the payout (atomic transition to "processing")
-	claimed, err := p.repo.ClaimPayout(ctx, payout.ID)
-	if err != nil {
-		log.Printf("[worker] Error claiming payout %s: %v", payout.ID, err)
-		return
+// processSinglePayout handles one claimed payout with execute → record,
+// running the registered hooks around each step. The payout arrives as
+// claimed, already in processing with this attempt counted. It reports
+// false if the payout was not attempted and must be released.
+func (p *Pool) processSinglePayout(ctx context.Context, payout models.Payout, counters *runCounters) bool {
+	if err := p.throttleWrite(ctx); err != nil {
+		return false
 	}
-	if !claimed {
-		return // Already being processed by another worker
+
+	// Step 1: Take up the payout, unless a hook stops the run
+	decline, declinedBy, err := p.hooks.beforeClaim(ctx, payout)
+	if err != nil {
+		counters.halt(err)
+		return false
 	}
 
-	attemptStart := time.Now().UTC()
+	counters.processed.Add(1)
+	attemptStart := p.clock.Now().UTC()
 
-	// Step 2: Simulate the bank transfer
-	result := service.SimulateBankTransfer(payout.VendorID, payout.Amount)
+	// Step 2: Execute the bank transfer, unless a hook declined it
+	if decline == nil {
+		if decline, declinedBy, err = p.hooks.beforeTransfer(ctx, payout); err != nil {
+			// Left in processing, like an interrupted transfer, so the next
+			// run resets it.
+			counters.halt(err)
+			return true
+		}
+	}
+	var result service.SimulatedBankResult
+	if decline != nil {
+		result = service.SimulatedBankResult{FailureCode: decline.Code, IsRetryable: decline.Retryable}
+	} else {
+		callStart := p.clock.Now()
+		if result, err = p.bank.Transfer(ctx, payout); err != nil {
+			// Outcome unknown (e.g. shutdown mid-call): leave the payout in
+			// processing so crash recovery resets it on the next run.
+			log.Printf("[worker] Transfer for payout %s interrupted: %v", payout.ID, err)
+			return true
+		}
+		p.metrics.BankCall(p.clock.Now().Sub(callStart))
+	}
 
-	attemptEnd := time.Now().UTC()
+	attemptEnd := p.clock.Now().UTC()
 
 	// Step 3: Record the attempt
 	attempt := &models.PayoutAttempt{
 		ID:         uuid.New(),
 		PayoutID:   payout.ID,
-		AttemptNum: payout.AttemptCount + 1,
+		AttemptNum: payout.AttemptCount,
 		StartedAt:  attemptStart,
 		FinishedAt: &attemptEnd,
 	}
+	outcome := Outcome{Result: result, DeclinedBy: declinedBy}
 
 	if result.Success {
 		attempt.Status = models.PayoutStatusCompleted
+		outcome.Status = models.PayoutStatusCompleted
+		counters.completed.Add(1)
 		if err := p.repo.CompletePayout(ctx, payout.ID); err != nil {
 			log.Printf("[worker] Error completing payout %s: %v", payout.ID, err)
+		} else {
+			for _, n := range p.notifiers {
+				n.PayoutSent(ctx, payout)
+			}
 		}
 	} else {
 		attempt.Status = models.PayoutStatusFailed
 		attempt.Error = &result.FailureCode
+		if result.IsRetryable {
+			counters.transient.Add(1)
+		}
 
-		if result.IsRetryable && payout.AttemptCount+1 < payout.MaxRetries {
-			// Retryable: put back to pending
-			if err := p.repo.RequeuePayout(ctx, payout.ID); err != nil {
+		if result.IsRetryable && counters.policy.Retries(result.FailureCode) && payout.AttemptCount < payout.MaxRetries {
+			// Retryable: put back to pending, once its backoff is over
+			outcome.Status = models.PayoutStatusPending
+			if err := p.repo.RequeuePayout(ctx, payout.ID, p.retryAt(p.backoffFor(counters), payout.AttemptCount)); err != nil {
 				log.Printf("[worker] Error requeuing payout %s: %v", payout.ID, err)
 			}
 		} else {
 			// Permanent failure or max retries exceeded
+			outcome.Status = models.PayoutStatusFailed
+			counters.failed.Add(1)
 			if err := p.repo.FailPayout(ctx, payout.ID, result.FailureCode); err != nil {
 				log.Printf("[worker] Error failing payout %s: %v", payout.ID, err)
+			} else {
+				for _, n := range p.notifiers {
+					n.PayoutFailed(ctx, payout, result.FailureCode)
+				}
 			}
 		}
 	}
 
 	// Log the attempt
-	if err := p.repo.LogAttempt(ctx, attempt); err != nil {
-		log.Printf("[worker] Error logging attempt for payout %s: %v", payout.ID, err)
+	p.logAttempt(ctx, attempt)
+	if every := counters.progressEvery; every > 0 && counters.recorded.Add(1)%every == 0 {
+		if err := p.repo.RefreshBatchCounts(ctx, payout.BatchID); err != nil {
+			log.Printf("[worker] Warning: failed to refresh counts: %v", err)
+		}
 	}
+
+	outcome.Attempt = *attempt
+	p.hooks.afterResult(ctx, payout, outcome)
+	return true
 }
 
-// Stop signals the pool to stop processing after the current chunk.
+// Stop signals every batch being processed to stop once the payouts being
+// transferred are done; the rest of the chunk is released.
 func (p *Pool) Stop() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	for _, stopCh := range p.runs {
+		closeStop(stopCh)
+	}
+}
+
+// StopBatch signals the run of one batch to stop after the current chunk,
+// leaving any other batch running, or takes the batch out of the queue. It
+// reports false if the pool is neither processing nor queueing the batch.
+func (p *Pool) StopBatch(batchID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if stopCh, ok := p.runs[batchID]; ok {
+		closeStop(stopCh)
+		return true
+	}
+	return p.dequeue(batchID)
+}
+
+// Shutdown stops the pool for good: batches can no longer be started,
+// queued batches are dropped (they stay pending) and every run is stopped.
+// Workers finish the transfers they are in the middle of and release the
+// rest of their chunk, so each run pauses its batch with nothing left in
+// processing. Shutdown waits for the runs to end until ctx is done; it then
+// cancels the runs started with Start or Enqueue, leaving interrupted
+// transfers in processing for the next run to reset, and returns ctx's error.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closing = true
+	if len(p.queue) > 0 {
+		log.Printf("[processor] Dropping %d queued batches; they stay pending until started again", len(p.queue))
+		p.queue = nil
+	}
+	for _, stopCh := range p.runs {
+		closeStop(stopCh)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.active.Wait()
+		close(done)
+	}()
 	select {
-	case <-p.stopCh:
+	case <-done:
+		p.spillAttempts(ctx)
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		p.spillAttempts(context.Background())
+		return ctx.Err()
+	}
+}
+
+func closeStop(stopCh chan struct{}) {
+	select {
+	case <-stopCh:
 		// Already closed, no-op
 	default:
-		close(p.stopCh)
+		close(stopCh)
+	}
+}
+
+// Concurrency returns the number of workers a run uses once fully ramped
+// up, unless it was started with its own.
+func (p *Pool) Concurrency() int {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	return p.concurrency
+}
+
+// runSettings fills in the settings a run was not given from the pool's.
+func (p *Pool) runSettings(s models.RunSettings) models.RunSettings {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	if s.Concurrency <= 0 {
+		s.Concurrency = p.concurrency
+	}
+	if s.ChunkSize <= 0 {
+		s.ChunkSize = p.chunkSize
+	}
+	return s
+}
+
+// backoffFor returns the retry backoff of a run: its batch's own, or the
+// pool's current one.
+func (p *Pool) backoffFor(c *runCounters) RetryBackoff {
+	if c.backoff != nil {
+		return *c.backoff
+	}
+	return p.backoff()
+}
+
+// backoff returns the current retry backoff policy.
+func (p *Pool) backoff() RetryBackoff {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	return p.retryBackoff
+}
+
+// Config returns the pool's configuration that can change while it runs.
+func (p *Pool) Config() models.WorkerConfig {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	return models.WorkerConfig{
+		Concurrency:  p.concurrency,
+		ChunkSize:    p.chunkSize,
+		RetryBackoff: p.retryBackoff.String(),
+	}
+}
+
+// UpdateConfig changes the settings given in req and returns the resulting
+// configuration. Runs in progress take up the new concurrency and chunk
+// size from their next chunk, unless they were started with their own, and
+// payouts failing from then on wait out the new retry backoff.
+func (p *Pool) UpdateConfig(req models.UpdateWorkerConfigRequest) (models.WorkerConfig, error) {
+	var backoff *RetryBackoff
+	if req.RetryBackoff != nil {
+		b, err := ParseRetryBackoff(*req.RetryBackoff)
+		if err != nil {
+			return models.WorkerConfig{}, err
+		}
+		backoff = &b
+	}
+
+	p.cfgMu.Lock()
+	if req.Concurrency != nil {
+		p.concurrency = *req.Concurrency
+	}
+	if req.ChunkSize != nil {
+		p.chunkSize = *req.ChunkSize
 	}
+	if backoff != nil {
+		p.retryBackoff = *backoff
+	}
+	p.cfgMu.Unlock()
+	return p.Config(), nil
+}
+
+// Bank returns the bank client transfers are sent to.
+func (p *Pool) Bank() service.BankClient {
+	return p.bank
+}
+
+// Environment returns the bank environment transfers are sent in.
+func (p *Pool) Environment() string {
+	return p.environment
+}
+
+// ActiveBatch returns the batch currently being processed, if any.
+func (p *Pool) ActiveBatch() (uuid.UUID, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id := range p.runs {
+		return id, true
+	}
+	return uuid.Nil, false
+}
+
+// Processing reports whether the pool is processing a batch.
+func (p *Pool) Processing(batchID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.runs[batchID]
+	return ok
 }
 
 // IsRunning returns whether the pool is currently processing.
@@ -3,45 +3,43 @@ package worker_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"coding-challenge/internal/audit"
+	"coding-challenge/internal/clock"
+	"coding-challenge/internal/database/dbtest"
 	"coding-challenge/internal/models"
 	"coding-challenge/internal/repository"
+	"coding-challenge/internal/service"
 	"coding-challenge/internal/worker"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
 )
 
-// getTestDB returns a database connection for testing.
-// Requires a running PostgreSQL with kaveri_payouts_test database.
-// Set TEST_DB_DSN env var to override.
-func getTestDB(t *testing.T) *sql.DB {
-	dsn := os.Getenv("TEST_DB_DSN")
-	if dsn == "" {
-		dsn = "host=localhost port=5432 user=postgres password=postgres dbname=kaveri_payouts_test sslmode=disable"
-	}
-
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		t.Skipf("Skipping integration test: cannot connect to DB: %v", err)
-	}
-	if err := db.Ping(); err != nil {
-		t.Skipf("Skipping integration test: DB not reachable: %v", err)
-	}
-
-	// Clean tables before test
-	db.Exec("DELETE FROM payout_attempts")
-	db.Exec("DELETE FROM payouts")
-	db.Exec("DELETE FROM payout_batches")
+func TestMain(m *testing.M) {
+	code := m.Run()
+	dbtest.Close()
+	os.Exit(code)
+}
 
-	return db
+// getTestDB returns a clean database for an integration test, skipping the
+// test when none is reachable. See dbtest for configuration.
+func getTestDB(t *testing.T) *sql.DB {
+	return dbtest.Open(t)
 }
 
-func createTestBatch(t *testing.T, repo *repository.Repository, count int) uuid.UUID {
+// fastBank is the default simulator with its latency waits skipped, for
+// tests that only care about outcomes.
+var fastBank = worker.WithBankClient(service.NewSimulator(
+	service.UniformLatency{Min: 50 * time.Millisecond, Max: 500 * time.Millisecond}, nil, service.WithLatencyScale(0)))
+
+func createTestBatch(t testing.TB, repo *repository.Repository, count int) uuid.UUID {
 	items := make([]models.CreatePayoutItem, count)
 	for i := 0; i < count; i++ {
 		items[i] = models.CreatePayoutItem{
@@ -54,7 +52,7 @@ func createTestBatch(t *testing.T, repo *repository.Repository, count int) uuid.
 		}
 	}
 
-	batch, err := repo.CreateBatch(context.Background(), items)
+	batch, err := repo.CreateBatch(context.Background(), items, models.BatchOptions{})
 	if err != nil {
 		t.Fatalf("Failed to create test batch: %v", err)
 	}
@@ -64,12 +62,11 @@ func createTestBatch(t *testing.T, repo *repository.Repository, count int) uuid.
 // TestBatchProcessingCompletesAll verifies that all payouts are processed.
 func TestBatchProcessingCompletesAll(t *testing.T) {
 	db := getTestDB(t)
-	defer db.Close()
 
 	repo := repository.New(db)
 	batchID := createTestBatch(t, repo, 50)
 
-	pool := worker.NewPool(repo, 5, 20)
+	pool := worker.NewPool(repo, 5, 20, fastBank)
 	err := pool.ProcessBatch(context.Background(), batchID)
 	if err != nil {
 		t.Fatalf("ProcessBatch failed: %v", err)
@@ -97,13 +94,12 @@ func TestBatchProcessingCompletesAll(t *testing.T) {
 // TestIdempotency verifies running the same batch twice doesn't create duplicates.
 func TestIdempotency(t *testing.T) {
 	db := getTestDB(t)
-	defer db.Close()
 
 	repo := repository.New(db)
 	batchID := createTestBatch(t, repo, 20)
 
 	// Process the batch
-	pool := worker.NewPool(repo, 5, 10)
+	pool := worker.NewPool(repo, 5, 10, fastBank)
 	pool.ProcessBatch(context.Background(), batchID)
 
 	// Get stats after first run
@@ -111,7 +107,7 @@ func TestIdempotency(t *testing.T) {
 	completed1 := stats1.Completed
 
 	// Try to process again (should be a no-op for completed payouts)
-	pool2 := worker.NewPool(repo, 5, 10)
+	pool2 := worker.NewPool(repo, 5, 10, fastBank)
 	pool2.ProcessBatch(context.Background(), batchID)
 
 	stats2, _ := repo.GetBatchStatistics(context.Background(), batchID)
@@ -132,16 +128,17 @@ func TestIdempotency(t *testing.T) {
 // TestResumability verifies that a stopped batch can be resumed.
 func TestResumability(t *testing.T) {
 	db := getTestDB(t)
-	defer db.Close()
 
 	repo := repository.New(db)
 	batchID := createTestBatch(t, repo, 100)
 
-	// Process with a context that cancels quickly (simulates crash)
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	// Process with a context that cancels quickly (simulates crash), at a
+	// tenth of the simulated latency so the rest of the run stays short
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 	defer cancel()
 
-	pool1 := worker.NewPool(repo, 3, 10)
+	tenth := service.NewSimulator(service.UniformLatency{Min: 50 * time.Millisecond, Max: 500 * time.Millisecond}, nil, service.WithLatencyScale(0.1))
+	pool1 := worker.NewPool(repo, 3, 10, worker.WithBankClient(tenth))
 	pool1.ProcessBatch(ctx, batchID)
 
 	// Check partial progress
@@ -156,7 +153,7 @@ func TestResumability(t *testing.T) {
 	}
 
 	// Resume processing
-	pool2 := worker.NewPool(repo, 5, 20)
+	pool2 := worker.NewPool(repo, 5, 20, fastBank)
 	pool2.ProcessBatch(context.Background(), batchID)
 
 	// All should be processed now
@@ -174,3 +171,898 @@ func TestResumability(t *testing.T) {
 
 	t.Logf("After resume: completed=%d, failed=%d (total=%d)", stats2.Completed, stats2.Failed, totalProcessed)
 }
+
+// TestScenarioExactEndState verifies retries and permanent failures against a
+// scripted bank, asserting exact counts rather than statistical ranges.
+func TestScenarioExactEndState(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 20)
+
+	// First 10 succeed, next 5 time out twice then succeed, vendor 15 is blocked.
+	sc := service.NewScenario()
+	sc.For(service.VendorRange("test_vendor_%04d", 10, 15)).Fail(models.FailureBankTimeout, 2).ThenSucceed()
+	sc.For(service.Vendors("test_vendor_0015")).Always(models.FailureAccountBlocked)
+
+	pool := worker.NewPool(repo, 5, 10, worker.WithBankClient(sc))
+	if err := pool.ProcessBatch(context.Background(), batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	stats, err := repo.GetBatchStatistics(context.Background(), batchID)
+	if err != nil {
+		t.Fatalf("GetBatchStatistics failed: %v", err)
+	}
+	if stats.Completed != 19 || stats.Failed != 1 {
+		t.Errorf("Expected completed=19 failed=1, got completed=%d failed=%d", stats.Completed, stats.Failed)
+	}
+	if n := sc.Attempts("test_vendor_0012"); n != 3 {
+		t.Errorf("Expected 3 attempts for a twice-timed-out vendor, got %d", n)
+	}
+	if n := sc.Attempts("test_vendor_0015"); n != 1 {
+		t.Errorf("Expected a single attempt for a permanently blocked vendor, got %d", n)
+	}
+}
+
+// TestHooksDeclineAndObserve verifies a before-hook can decline a payout
+// without calling the bank and that AfterResult sees every recorded outcome.
+func TestHooksDeclineAndObserve(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 5)
+
+	var mu sync.Mutex
+	outcomes := map[string]worker.Outcome{}
+	screening := worker.Hook{
+		Name: "screening",
+		BeforeTransfer: func(ctx context.Context, payout models.Payout) error {
+			if payout.VendorID == "test_vendor_0003" {
+				return &worker.Decline{Code: "SCREENING_HIT"}
+			}
+			return nil
+		},
+	}
+	recorder := worker.Hook{
+		Name: "recorder",
+		AfterResult: func(ctx context.Context, payout models.Payout, outcome worker.Outcome) {
+			mu.Lock()
+			defer mu.Unlock()
+			outcomes[payout.VendorID] = outcome
+		},
+	}
+
+	sc := service.NewScenario()
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(sc), worker.WithHooks(screening, recorder))
+	if err := pool.ProcessBatch(ctx, batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	if n := sc.Attempts("test_vendor_0003"); n != 0 {
+		t.Errorf("Expected the declined payout never to reach the bank, got %d attempts", n)
+	}
+	if len(outcomes) != 5 {
+		t.Fatalf("Expected 5 outcomes, got %d", len(outcomes))
+	}
+	declined := outcomes["test_vendor_0003"]
+	if declined.Status != models.PayoutStatusFailed || declined.DeclinedBy != "screening" ||
+		declined.Attempt.Error == nil || *declined.Attempt.Error != "SCREENING_HIT" {
+		t.Errorf("Expected a failed attempt declined by screening, got %+v", declined)
+	}
+	stats, _ := repo.GetBatchStatistics(ctx, batchID)
+	if stats.Completed != 4 || stats.Failed != 1 {
+		t.Errorf("Expected completed=4 failed=1, got completed=%d failed=%d", stats.Completed, stats.Failed)
+	}
+}
+
+// TestHookErrorPausesBatch verifies a hook error stops the run, pauses the
+// batch and leaves unprocessed payouts pending.
+func TestHookErrorPausesBatch(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 5)
+
+	down := worker.Hook{
+		Name: "screening",
+		BeforeClaim: func(ctx context.Context, payout models.Payout) error {
+			return errors.New("screening service unavailable")
+		},
+	}
+	pool := worker.NewPool(repo, 1, 10, worker.WithBankClient(service.NewScenario()), worker.WithHooks(down))
+	if err := pool.ProcessBatch(ctx, batchID); err == nil {
+		t.Fatal("Expected the hook error to fail the run")
+	}
+
+	batch, _ := repo.GetBatch(ctx, batchID)
+	if batch.Status != models.BatchStatusPaused {
+		t.Errorf("Expected status paused, got %s", batch.Status)
+	}
+	stats, _ := repo.GetBatchStatistics(ctx, batchID)
+	if stats.Pending != 5 {
+		t.Errorf("Expected 5 pending payouts, got %d", stats.Pending)
+	}
+	runs, _ := repo.ListRuns(ctx, batchID)
+	if len(runs) != 1 || runs[0].Status != models.RunStatusFailed || runs[0].Error == nil {
+		t.Errorf("Expected one failed run with an error, got %+v", runs)
+	}
+}
+
+// backdate moves a batch and all its payouts' last activity into the past.
+func backdate(t *testing.T, db *sql.DB, batchID uuid.UUID, by time.Duration) {
+	past := time.Now().UTC().Add(-by)
+	if _, err := db.Exec(`UPDATE payout_batches SET updated_at = $1 WHERE id = $2`, past, batchID); err != nil {
+		t.Fatalf("Failed to backdate batch: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE payouts SET updated_at = $1 WHERE batch_id = $2`, past, batchID); err != nil {
+		t.Fatalf("Failed to backdate payouts: %v", err)
+	}
+}
+
+// TestWatchdogPausesStalledBatch verifies an idle in-progress batch is paused
+// and its stuck payouts are reset for the next run.
+func TestWatchdogPausesStalledBatch(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 5)
+
+	// Simulate a process that died mid-chunk an hour ago.
+	if err := repo.UpdateBatchStatus(ctx, batchID, models.BatchStatusInProgress); err != nil {
+		t.Fatalf("UpdateBatchStatus failed: %v", err)
+	}
+	db.Exec(`UPDATE payouts SET status = 'processing', attempt_count = 1
+	         WHERE id IN (SELECT id FROM payouts WHERE batch_id = $1 LIMIT 2)`, batchID)
+	backdate(t, db, batchID, time.Hour)
+
+	stalled, err := repo.FindStalledBatches(ctx, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("FindStalledBatches failed: %v", err)
+	}
+	if len(stalled) != 1 || stalled[0].ID != batchID {
+		t.Fatalf("Expected batch %s to be stalled, got %d batches", batchID, len(stalled))
+	}
+
+	pool := worker.NewPool(repo, 1, 10, fastBank)
+	paused, err := worker.NewWatchdog(repo, pool, time.Minute, 10*time.Minute).Check(ctx)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if paused != 1 {
+		t.Errorf("Expected 1 batch paused, got %d", paused)
+	}
+
+	batch, _ := repo.GetBatch(ctx, batchID)
+	if batch.Status != models.BatchStatusPaused {
+		t.Errorf("Expected status paused, got %s", batch.Status)
+	}
+	stats, _ := repo.GetBatchStatistics(ctx, batchID)
+	if stats.Processing != 0 || stats.Pending != 5 {
+		t.Errorf("Expected 5 pending and 0 processing after reset, got pending=%d processing=%d",
+			stats.Pending, stats.Processing)
+	}
+}
+
+// TestWatchdogIgnoresActiveBatch verifies a batch with a fresh claim is not
+// treated as stalled, even if the batch row itself is old.
+func TestWatchdogIgnoresActiveBatch(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 5)
+
+	repo.UpdateBatchStatus(ctx, batchID, models.BatchStatusInProgress)
+	backdate(t, db, batchID, time.Hour)
+
+	// Another instance claims a payout just now.
+	db.Exec(`UPDATE payouts SET status = 'processing', attempt_count = 1, updated_at = NOW()
+	         WHERE id IN (SELECT id FROM payouts WHERE batch_id = $1 LIMIT 1)`, batchID)
+
+	pool := worker.NewPool(repo, 1, 10, fastBank)
+	paused, err := worker.NewWatchdog(repo, pool, time.Minute, 10*time.Minute).Check(ctx)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if paused != 0 {
+		t.Errorf("Expected no batch paused, got %d", paused)
+	}
+
+	stats, _ := repo.GetBatchStatistics(ctx, batchID)
+	if stats.Processing != 1 {
+		t.Errorf("Expected the fresh claim to be left alone, got processing=%d", stats.Processing)
+	}
+}
+
+// TestWatchdogRunsOnClock verifies stall detection follows the injected
+// clock: a batch whose claims went quiet is only paused once the fake time
+// passes the stall threshold, with no real waiting.
+func TestWatchdogRunsOnClock(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	repo := repository.New(db, repository.WithClock(fake))
+	pool := worker.NewPool(repo, 1, 10, worker.WithClock(fake), fastBank)
+	batchID := createTestBatch(t, repo, 3)
+
+	repo.UpdateBatchStatus(ctx, batchID, models.BatchStatusInProgress)
+	if claimed, err := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 1, nil); len(claimed) != 1 || err != nil {
+		t.Fatalf("ClaimChunk failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		worker.NewWatchdog(repo, pool, time.Minute, 10*time.Minute).Run(ctx)
+		close(done)
+	}()
+	status := func() string {
+		batch, _ := repo.GetBatch(ctx, batchID)
+		return batch.Status
+	}
+
+	// Each tick is done once the watchdog waits on its next timer.
+	for i := 0; i < 9; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+	}
+	fake.BlockUntil(1)
+	if got := status(); got != models.BatchStatusInProgress {
+		t.Fatalf("Expected in_progress after 9 idle minutes, got %s", got)
+	}
+
+	fake.Advance(2 * time.Minute)
+	fake.BlockUntil(1)
+	if got := status(); got != models.BatchStatusPaused {
+		t.Errorf("Expected paused after 11 idle minutes, got %s", got)
+	}
+	stats, _ := repo.GetBatchStatistics(ctx, batchID)
+	if stats.Processing != 0 {
+		t.Errorf("Expected the stuck claim to be reset, got processing=%d", stats.Processing)
+	}
+
+	cancel()
+	<-done
+}
+
+// TestStoppedBatchIsNotStalled verifies a batch stopped by an operator is
+// parked as paused and never reported by the watchdog.
+func TestStoppedBatchIsNotStalled(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 50)
+
+	slow := service.NewSimulator(service.UniformLatency{Min: 20 * time.Millisecond, Max: 20 * time.Millisecond}, nil)
+	pool := worker.NewPool(repo, 1, 5, worker.WithBankClient(slow))
+	if _, err := pool.Start(batchID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	pool.Stop()
+	for pool.IsRunning() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	batch, _ := repo.GetBatch(ctx, batchID)
+	if batch.Status != models.BatchStatusPaused {
+		t.Fatalf("Expected stopped batch to be paused, got %s", batch.Status)
+	}
+
+	backdate(t, db, batchID, time.Hour)
+	stalled, err := repo.FindStalledBatches(ctx, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("FindStalledBatches failed: %v", err)
+	}
+	if len(stalled) != 0 {
+		t.Errorf("Expected no stalled batches after a stop, got %d", len(stalled))
+	}
+}
+
+// TestStopBatch verifies stopping a batch the pool is not processing leaves
+// the running one alone, and stopping the running one pauses it.
+func TestStopBatch(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 50)
+
+	slow := service.NewSimulator(service.UniformLatency{Min: 20 * time.Millisecond, Max: 20 * time.Millisecond}, nil)
+	pool := worker.NewPool(repo, 1, 5, worker.WithBankClient(slow))
+	if _, err := pool.Start(batchID, models.RunTriggerStart, "tester"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if pool.StopBatch(uuid.New()) {
+		t.Error("Expected no stop for a batch the pool is not processing")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !pool.Processing(batchID) {
+		t.Fatal("Expected the running batch to be unaffected")
+	}
+
+	if !pool.StopBatch(batchID) {
+		t.Fatal("Expected the running batch to be stopped")
+	}
+	for pool.IsRunning() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pool.Processing(batchID) {
+		t.Error("Expected the batch to be forgotten once its run ended")
+	}
+	batch, _ := repo.GetBatch(ctx, batchID)
+	if batch.Status != models.BatchStatusPaused {
+		t.Errorf("Expected stopped batch to be paused, got %s", batch.Status)
+	}
+}
+
+// TestEnqueue verifies batches started while the pool is busy wait in FIFO
+// order, keep their place when started again, can be taken out of the
+// queue, and start on their own once the running batch finishes.
+func TestEnqueue(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	first, second, third := createTestBatch(t, repo, 50), createTestBatch(t, repo, 50), createTestBatch(t, repo, 50)
+
+	slow := service.NewSimulator(service.UniformLatency{Min: 20 * time.Millisecond, Max: 20 * time.Millisecond}, nil)
+	pool := worker.NewPool(repo, 1, 5, worker.WithBankClient(slow))
+	if run, pos, err := pool.Enqueue(first, models.RunTriggerStart, "tester"); err != nil || run == nil || pos != 0 {
+		t.Fatalf("Expected the first batch to start at once, got run=%v pos=%d err=%v", run, pos, err)
+	}
+	if _, _, err := pool.Enqueue(first, models.RunTriggerStart, "tester"); !errors.Is(err, worker.ErrBusy) {
+		t.Errorf("Expected ErrBusy enqueueing the running batch, got %v", err)
+	}
+	for i, id := range []uuid.UUID{second, third, second} {
+		if run, pos, err := pool.Enqueue(id, models.RunTriggerStart, "tester"); err != nil || run != nil || pos != []int{1, 2, 1}[i] {
+			t.Errorf("Expected enqueue %d at position %d, got run=%v pos=%d err=%v", i, []int{1, 2, 1}[i], run, pos, err)
+		}
+	}
+
+	if !pool.StopBatch(third) {
+		t.Error("Expected the third batch to be taken out of the queue")
+	}
+	if queued := pool.Queued(); len(queued) != 1 || queued[0] != second {
+		t.Fatalf("Expected only the second batch queued, got %v", queued)
+	}
+	if pos, ok := pool.QueuePosition(second); !ok || pos != 1 {
+		t.Errorf("Expected the second batch next, got %d %v", pos, ok)
+	}
+
+	pool.StopBatch(first)
+	deadline := time.Now().Add(10 * time.Second)
+	for !pool.Processing(second) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !pool.Processing(second) {
+		t.Fatal("Expected the queued batch to start once the first one stopped")
+	}
+	if _, ok := pool.QueuePosition(second); ok {
+		t.Error("Expected the started batch to leave the queue")
+	}
+	pool.StopBatch(second)
+	for pool.IsRunning() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if batch, _ := repo.GetBatch(ctx, third); batch.Status != models.BatchStatusPending {
+		t.Errorf("Expected the dequeued batch to stay pending, got %s", batch.Status)
+	}
+	runs, _ := repo.ListRuns(ctx, second)
+	if len(runs) != 1 || runs[0].TriggeredBy != "tester" {
+		t.Errorf("Expected one run of the queued batch triggered by tester, got %+v", runs)
+	}
+}
+
+// TestPayoutOrders verifies each processing order claims pending payouts in
+// the documented sequence.
+func TestPayoutOrders(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+
+	items := []models.CreatePayoutItem{
+		{VendorID: "v0", Amount: 300, BankName: "Bank A"},
+		{VendorID: "v1", Amount: 100, BankName: "Bank A"},
+		{VendorID: "v2", Amount: 200, BankName: "Bank B"},
+		{VendorID: "v3", Amount: 400, BankName: "Bank A"},
+		{VendorID: "v4", Amount: 50, BankName: "Bank C"},
+	}
+	for i := range items {
+		items[i].Currency = "USD"
+		items[i].BankAccount = fmt.Sprintf("ACC%010d", i)
+	}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{models.PayoutOrderFIFO, []string{"v0", "v1", "v2", "v3", "v4"}},
+		{models.PayoutOrderLargestFirst, []string{"v3", "v0", "v2", "v1", "v4"}},
+		{models.PayoutOrderSmallestFirst, []string{"v4", "v1", "v2", "v0", "v3"}},
+		{models.PayoutOrderBankRoundRobin, []string{"v0", "v2", "v4", "v1", "v3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			batch, err := repo.CreateBatch(ctx, items, models.BatchOptions{PayoutOrder: tt.order})
+			if err != nil {
+				t.Fatalf("CreateBatch failed: %v", err)
+			}
+
+			payouts, err := repo.ClaimChunk(ctx, batch.ID, tt.order, 10, nil)
+			if err != nil {
+				t.Fatalf("ClaimChunk failed: %v", err)
+			}
+			got := make([]string, len(payouts))
+			for i, p := range payouts {
+				got[i] = p.VendorID
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected order %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestClaimChunkAndRelease verifies a claimed chunk is taken out of the
+// pending pool with its attempt counted, and that released claims go back
+// to pending with the attempt undone.
+func TestClaimChunkAndRelease(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 5)
+
+	first, err := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 3, nil)
+	if err != nil || len(first) != 3 {
+		t.Fatalf("Expected 3 payouts claimed, got %d (%v)", len(first), err)
+	}
+	if first[0].Status != models.PayoutStatusProcessing || first[0].AttemptCount != 1 {
+		t.Errorf("Expected a claimed payout in processing on attempt 1, got %s on %d", first[0].Status, first[0].AttemptCount)
+	}
+	second, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 3, nil)
+	if len(second) != 2 {
+		t.Fatalf("Expected the 2 unclaimed payouts, got %d", len(second))
+	}
+
+	released, err := repo.ReleaseClaims(ctx, []uuid.UUID{first[1].ID, first[2].ID})
+	if err != nil || released != 2 {
+		t.Fatalf("Expected 2 claims released, got %d (%v)", released, err)
+	}
+	again, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, nil)
+	if len(again) != 2 || again[0].ID != first[1].ID || again[0].AttemptCount != 1 {
+		t.Errorf("Expected the released payouts claimed again on attempt 1, got %+v", again)
+	}
+}
+
+// TestClaimChunkSkipsBackoff verifies a requeued payout is not claimed
+// before its retry time, and is claimed again, with the time cleared, once
+// it comes due.
+func TestClaimChunkSkipsBackoff(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	fc := clock.NewFake(time.Now().UTC().Truncate(time.Second))
+	repo := repository.New(db, repository.WithClock(fc))
+	batchID := createTestBatch(t, repo, 1)
+
+	claimed, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, nil)
+	if len(claimed) != 1 {
+		t.Fatalf("Expected the payout claimed, got %d", len(claimed))
+	}
+	if err := repo.RequeuePayout(ctx, claimed[0].ID, fc.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("RequeuePayout failed: %v", err)
+	}
+	if early, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderBankRoundRobin, 10, nil); len(early) != 0 {
+		t.Errorf("Expected nothing claimed while the payout backs off, got %d", len(early))
+	}
+	if left, _ := repo.HasClaimablePayouts(ctx, batchID); !left {
+		t.Error("Expected the backing-off payout still counted as claimable")
+	}
+
+	fc.Advance(time.Minute)
+	due, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, nil)
+	if len(due) != 1 || due[0].AttemptCount != 2 || due[0].NextRetryAt != nil {
+		t.Errorf("Expected the payout claimed on attempt 2 once due, got %+v", due)
+	}
+}
+
+// TestClaimChunkInFlightLimit verifies claims stop at a currency's in-flight
+// limit and resume as claimed payouts are confirmed, and that a payout over
+// the limit is still claimed once nothing else is in flight.
+func TestClaimChunkInFlightLimit(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 5) // USD 100, 101, 102, 103, 104
+	limits := map[string]float64{"USD": 250}
+
+	first, err := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, limits)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("Expected 2 payouts claimed under the limit, got %d (%v)", len(first), err)
+	}
+	if capped, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, limits); len(capped) != 0 {
+		t.Errorf("Expected nothing claimed at the limit, got %d", len(capped))
+	}
+	if left, _ := repo.HasClaimablePayouts(ctx, batchID); !left {
+		t.Error("Expected claimable payouts left behind by the limit")
+	}
+
+	repo.CompletePayout(ctx, first[0].ID)
+	next, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, limits)
+	if len(next) != 1 || next[0].Amount != 102 {
+		t.Fatalf("Expected the next payout claimed once one was confirmed, got %+v", next)
+	}
+
+	repo.CompletePayout(ctx, first[1].ID)
+	repo.CompletePayout(ctx, next[0].ID)
+	small := map[string]float64{"USD": 50}
+	if over, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, small); len(over) != 1 {
+		t.Errorf("Expected a payout over the limit claimed alone, got %d", len(over))
+	}
+	if capped, _ := repo.ClaimChunk(ctx, batchID, models.PayoutOrderFIFO, 10, small); len(capped) != 0 {
+		t.Errorf("Expected nothing more claimed while it is in flight, got %d", len(capped))
+	}
+}
+
+// TestPoolWaitsForInFlightLimit verifies a run whose currency is at its
+// in-flight limit waits, and carries on once the money in flight elsewhere
+// is confirmed.
+func TestPoolWaitsForInFlightLimit(t *testing.T) {
+	db := getTestDB(t)
+
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now())
+	repo := repository.New(db)
+	other := createTestBatch(t, repo, 1)
+	inFlight, err := repo.ClaimChunk(ctx, other, models.PayoutOrderFIFO, 1, nil)
+	if err != nil || len(inFlight) != 1 {
+		t.Fatalf("ClaimChunk failed: %v", err)
+	}
+
+	batchID := createTestBatch(t, repo, 2)
+	pool := worker.NewPool(repo, 2, 10, fastBank, worker.WithClock(fake),
+		worker.WithInFlightLimits(map[string]float64{"USD": 150}))
+	done := make(chan error)
+	go func() { done <- pool.ProcessBatch(ctx, batchID) }()
+
+	fake.BlockUntil(1)
+	if stats, _ := repo.GetBatchStatistics(ctx, batchID); stats.Pending != 2 {
+		t.Fatalf("Expected both payouts pending at the limit, got %+v", stats)
+	}
+
+	repo.CompletePayout(ctx, inFlight[0].ID)
+	fake.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	stats, _ := repo.GetBatchStatistics(ctx, batchID)
+	if stats.Pending != 0 || stats.Completed+stats.Failed != 2 {
+		t.Errorf("Expected both payouts processed after the limit cleared, got %+v", stats)
+	}
+}
+
+// TestConcurrentPoolsNeverDoublePay runs several pools, each with its own
+// connection pool as if in separate processes, against the same batch at
+// once, and checks the attempts table for any payout executed twice.
+func TestConcurrentPoolsNeverDoublePay(t *testing.T) {
+	db := getTestDB(t)
+	batchID := createTestBatch(t, repository.New(db), 300)
+
+	bank := service.NewSimulator(service.UniformLatency{Min: 0, Max: 2 * time.Millisecond}, nil)
+
+	const instances = 4
+	start := make(chan struct{})
+	errs := make(chan error, instances)
+	for i := 0; i < instances; i++ {
+		repo := repository.New(dbtest.Connect(t))
+		pool := worker.NewPool(repo, 8, 25, worker.WithBankClient(bank))
+		go func() {
+			<-start
+			errs <- pool.ProcessBatch(context.Background(), batchID)
+		}()
+	}
+	close(start)
+	for i := 0; i < instances; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("ProcessBatch failed: %v", err)
+		}
+	}
+
+	// Two claims of the same attempt mean two workers executed it.
+	var doubled int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT a.payout_id FROM payout_attempts a
+			JOIN payouts p ON p.id = a.payout_id
+			WHERE p.batch_id = $1
+			GROUP BY a.payout_id, a.attempt_num HAVING COUNT(*) > 1
+		) d`, batchID).Scan(&doubled)
+	if err != nil {
+		t.Fatalf("Attempt query failed: %v", err)
+	}
+	if doubled != 0 {
+		t.Errorf("Expected every attempt to be executed once, %d payouts had an attempt executed twice", doubled)
+	}
+
+	// Every claim produced exactly one logged attempt, and nothing completed twice.
+	var mismatched, paidTwice int
+	db.QueryRow(`
+		SELECT COUNT(*) FROM payouts p
+		WHERE p.batch_id = $1
+		  AND p.attempt_count <> (SELECT COUNT(*) FROM payout_attempts a WHERE a.payout_id = p.id)`,
+		batchID).Scan(&mismatched)
+	db.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT a.payout_id FROM payout_attempts a
+			JOIN payouts p ON p.id = a.payout_id
+			WHERE p.batch_id = $1 AND a.status = 'completed'
+			GROUP BY a.payout_id HAVING COUNT(*) > 1
+		) d`, batchID).Scan(&paidTwice)
+	if mismatched != 0 {
+		t.Errorf("Expected one attempt per claim, %d payouts disagree", mismatched)
+	}
+	if paidTwice != 0 {
+		t.Errorf("Expected no payout completed twice, got %d", paidTwice)
+	}
+
+	stats, _ := repository.New(db).GetBatchStatistics(context.Background(), batchID)
+	if stats.Completed+stats.Failed != 300 {
+		t.Errorf("Expected all 300 payouts processed, got completed=%d failed=%d pending=%d processing=%d",
+			stats.Completed, stats.Failed, stats.Pending, stats.Processing)
+	}
+}
+
+// finishCounter counts BatchFinished notifications.
+type finishCounter struct{ finished atomic.Int64 }
+
+func (f *finishCounter) PayoutSent(context.Context, models.Payout)           {}
+func (f *finishCounter) PayoutFailed(context.Context, models.Payout, string) {}
+func (f *finishCounter) BatchFinished(context.Context, models.PayoutBatch)   { f.finished.Add(1) }
+
+// TestBatchFinalizedOnce verifies that when several instances run a batch,
+// only one finalizes it: the terminal status is set once, funding is
+// settled once, and the batch is announced once.
+func TestBatchFinalizedOnce(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+	repo := repository.New(db)
+	if _, err := repo.SetFundingBalance(ctx, "USD", 100000); err != nil {
+		t.Fatalf("SetFundingBalance failed: %v", err)
+	}
+	batchID := createTestBatch(t, repo, 200)
+
+	sc := service.NewScenario()
+	counter := &finishCounter{}
+	const instances = 4
+	start := make(chan struct{})
+	errs := make(chan error, instances)
+	for i := 0; i < instances; i++ {
+		pool := worker.NewPool(repository.New(dbtest.Connect(t)), 8, 10, worker.WithBankClient(sc), worker.WithNotifier(counter))
+		go func() {
+			<-start
+			errs <- pool.ProcessBatch(ctx, batchID)
+		}()
+	}
+	close(start)
+	for i := 0; i < instances; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("ProcessBatch failed: %v", err)
+		}
+	}
+
+	if n := counter.finished.Load(); n != 1 {
+		t.Errorf("Expected the batch announced once, got %d", n)
+	}
+	batch, _ := repo.GetBatch(ctx, batchID)
+	if batch.Status != models.BatchStatusCompleted || batch.CompletedAt == nil {
+		t.Errorf("Expected the batch completed, got %s", batch.Status)
+	}
+	total := 0.0
+	for i := 0; i < 200; i++ {
+		total += float64(100 + i)
+	}
+	if a := fundingAccount(t, repo, "USD"); a.Balance != 100000-total || a.Reserved != 0 {
+		t.Errorf("Expected balance=%.2f reserved=0, got balance=%.2f reserved=%.2f", 100000-total, a.Balance, a.Reserved)
+	}
+
+	if _, finalized, err := repo.FinalizeBatch(ctx, batchID); err != nil || finalized {
+		t.Errorf("Expected a finished batch not to be finalized again, got %v %v", finalized, err)
+	}
+}
+
+// TestFinalizeBatchWaitsForOtherRuns verifies a batch with a payout still
+// in another run's hands is left in progress for that run to finalize.
+func TestFinalizeBatchWaitsForOtherRuns(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 3)
+
+	repo.UpdateBatchStatus(ctx, batchID, models.BatchStatusInProgress)
+	db.Exec(`UPDATE payouts SET status = 'completed' WHERE batch_id = $1`, batchID)
+	db.Exec(`UPDATE payouts SET status = 'processing' WHERE id = (SELECT id FROM payouts WHERE batch_id = $1 LIMIT 1)`, batchID)
+
+	status, finalized, err := repo.FinalizeBatch(ctx, batchID)
+	if err != nil || finalized || status != models.BatchStatusInProgress {
+		t.Fatalf("Expected the batch left in progress, got %s %v %v", status, finalized, err)
+	}
+
+	db.Exec(`UPDATE payouts SET status = 'failed' WHERE batch_id = $1 AND status = 'processing'`, batchID)
+	status, finalized, err = repo.FinalizeBatch(ctx, batchID)
+	if err != nil || !finalized || status != models.BatchStatusPartiallyCompleted {
+		t.Errorf("Expected the batch partially completed, got %s %v %v", status, finalized, err)
+	}
+}
+
+// fundingAccount returns the funding account of currency, failing the test if missing.
+func fundingAccount(t *testing.T, repo *repository.Repository, currency string) models.FundingAccount {
+	accounts, err := repo.ListFundingAccounts(context.Background())
+	if err != nil {
+		t.Fatalf("ListFundingAccounts failed: %v", err)
+	}
+	for _, a := range accounts {
+		if a.Currency == currency {
+			return a
+		}
+	}
+	t.Fatalf("No funding account for %s", currency)
+	return models.FundingAccount{}
+}
+
+// TestFundingSettledOnCompletion verifies that a batch's total is reserved
+// while it runs, completed payouts are debited and failed ones released.
+func TestFundingSettledOnCompletion(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	if _, err := repo.SetFundingBalance(context.Background(), "USD", 1000); err != nil {
+		t.Fatalf("SetFundingBalance failed: %v", err)
+	}
+	batchID := createTestBatch(t, repo, 3) // 100 + 101 + 102
+
+	if err := repo.ReserveFunding(context.Background(), batchID); err != nil {
+		t.Fatalf("ReserveFunding failed: %v", err)
+	}
+	if a := fundingAccount(t, repo, "USD"); a.Reserved != 303 || a.Available != 697 {
+		t.Errorf("Expected reserved=303 available=697, got reserved=%.2f available=%.2f", a.Reserved, a.Available)
+	}
+
+	sc := service.NewScenario()
+	sc.For(service.Vendors("test_vendor_0001")).Always(models.FailureAccountBlocked)
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(sc))
+	if err := pool.ProcessBatch(context.Background(), batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	a := fundingAccount(t, repo, "USD")
+	if a.Balance != 798 || a.Reserved != 0 {
+		t.Errorf("Expected balance=798 reserved=0, got balance=%.2f reserved=%.2f", a.Balance, a.Reserved)
+	}
+}
+
+// TestFundingPreventsOverdraw verifies that overlapping batches cannot
+// reserve more than the funding account holds.
+func TestFundingPreventsOverdraw(t *testing.T) {
+	db := getTestDB(t)
+
+	repo := repository.New(db)
+	if _, err := repo.SetFundingBalance(context.Background(), "USD", 400); err != nil {
+		t.Fatalf("SetFundingBalance failed: %v", err)
+	}
+	first := createTestBatch(t, repo, 2) // 201
+	second := createTestBatch(t, repo, 2)
+
+	if err := repo.ReserveFunding(context.Background(), first); err != nil {
+		t.Fatalf("ReserveFunding failed: %v", err)
+	}
+	// Reserving the same batch again holds nothing extra.
+	if err := repo.ReserveFunding(context.Background(), first); err != nil {
+		t.Fatalf("Second ReserveFunding failed: %v", err)
+	}
+
+	pool := worker.NewPool(repo, 2, 10, fastBank)
+	err := pool.ProcessBatch(context.Background(), second)
+	if !errors.Is(err, repository.ErrInsufficientFunding) {
+		t.Fatalf("Expected ErrInsufficientFunding, got %v", err)
+	}
+	stats, _ := repo.GetBatchStatistics(context.Background(), second)
+	if stats.Pending != 2 {
+		t.Errorf("Expected the unfunded batch to stay pending, got %+v", stats)
+	}
+	if a := fundingAccount(t, repo, "USD"); a.Reserved != 201 {
+		t.Errorf("Expected reserved=201, got %.2f", a.Reserved)
+	}
+}
+
+// TestRepairRebuildsFromAttempts verifies drifted statuses and counters are
+// found and rebuilt from the attempt history, and that a dry run writes
+// nothing.
+func TestRepairRebuildsFromAttempts(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+
+	repo := repository.New(db)
+	batchID := createTestBatch(t, repo, 5)
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()))
+	if err := pool.ProcessBatch(ctx, batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	// Drift: one paid payout lost its completion, and the counters are stale.
+	if _, err := db.Exec(
+		`UPDATE payouts SET status = 'failed', failure_reason = 'BANK_API_TIMEOUT'
+		 WHERE id = (SELECT id FROM payouts WHERE batch_id = $1 ORDER BY seq LIMIT 1)`, batchID); err != nil {
+		t.Fatalf("Failed to corrupt payout: %v", err)
+	}
+	if _, err := db.Exec(
+		`UPDATE payout_batches SET status = 'partially_completed', completed_count = 1 WHERE id = $1`, batchID); err != nil {
+		t.Fatalf("Failed to corrupt batch: %v", err)
+	}
+
+	found, err := repo.FindInconsistencies(ctx, batchID)
+	if err != nil {
+		t.Fatalf("FindInconsistencies failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected success_not_recorded and failed_without_attempt, got %+v", found)
+	}
+
+	dry, err := repo.RepairBatch(ctx, batchID, false)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if dry.StatusAfter != models.BatchStatusCompleted || dry.PayoutsRepaired != 1 {
+		t.Errorf("Expected the dry run to plan completed with 1 payout repaired, got %+v", dry)
+	}
+	if batch, _ := repo.GetBatch(ctx, batchID); batch.Status != models.BatchStatusPartiallyCompleted {
+		t.Errorf("Expected the dry run to write nothing, got status %s", batch.Status)
+	}
+
+	if _, err := repo.RepairBatch(ctx, batchID, true); err != nil {
+		t.Fatalf("RepairBatch failed: %v", err)
+	}
+	batch, _ := repo.GetBatch(ctx, batchID)
+	if batch.Status != models.BatchStatusCompleted || batch.CompletedCount != 5 || batch.FailedCount != 0 {
+		t.Errorf("Expected completed with 5/0, got %s with %d/%d", batch.Status, batch.CompletedCount, batch.FailedCount)
+	}
+	if found, _ := repo.FindInconsistencies(ctx, batchID); len(found) != 0 {
+		t.Errorf("Expected no inconsistencies after repair, got %+v", found)
+	}
+}
+
+// TestAttemptsCopiedToAuditLog verifies runs and attempts reach the
+// append-only audit log when one is configured.
+func TestAttemptsCopiedToAuditLog(t *testing.T) {
+	db := getTestDB(t)
+	ctx := context.Background()
+
+	store := audit.NewPostgres(db)
+	repo := repository.New(db, repository.WithAuditStore(store))
+	batchID := createTestBatch(t, repo, 4)
+	pool := worker.NewPool(repo, 2, 10, worker.WithBankClient(service.NewScenario()))
+	if err := pool.ProcessBatch(ctx, batchID); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	res, err := store.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	// 4 attempts plus the run's start and finish.
+	if !res.Valid || res.Records != 6 {
+		t.Errorf("Expected 6 valid audit records, got %+v", res)
+	}
+}
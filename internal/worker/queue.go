@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"log"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// queuedStart is a start request waiting for the pool.
+type queuedStart struct {
+	batchID     uuid.UUID
+	trigger     string
+	triggeredBy string
+	settings    models.RunSettings
+}
+
+// Enqueue starts the batch like Start if the pool is idle and nothing is
+// queued. Otherwise it queues the batch and returns its 1-based position;
+// queued batches are started first come, first served as runs finish, and
+// an error starting one is logged and the next is started instead. A batch
+// already queued keeps its place. It returns ErrBusy if the pool is
+// processing the batch right now, and ErrShuttingDown after Shutdown.
+//
+// The queue is held in memory: after a restart queued batches stay pending
+// until started again.
+func (p *Pool) Enqueue(batchID uuid.UUID, trigger, triggeredBy string) (*models.BatchRun, int, error) {
+	return p.EnqueueWith(batchID, trigger, triggeredBy, models.RunSettings{})
+}
+
+// EnqueueWith is Enqueue for a run with its own concurrency and chunk size;
+// zero fields keep the pool's. A batch already queued keeps the settings it
+// was queued with.
+func (p *Pool) EnqueueWith(batchID uuid.UUID, trigger, triggeredBy string, settings models.RunSettings) (*models.BatchRun, int, error) {
+	p.mu.Lock()
+	if p.closing {
+		p.mu.Unlock()
+		return nil, 0, ErrShuttingDown
+	}
+	if _, ok := p.runs[batchID]; ok {
+		p.mu.Unlock()
+		return nil, 0, ErrBusy
+	}
+	if pos := p.position(batchID); pos > 0 {
+		p.mu.Unlock()
+		return nil, pos, nil
+	}
+	if len(p.queue) > 0 || !p.running.CompareAndSwap(false, true) {
+		p.queue = append(p.queue, queuedStart{batchID: batchID, trigger: trigger, triggeredBy: triggeredBy, settings: settings})
+		pos := len(p.queue)
+		p.mu.Unlock()
+		log.Printf("[processor] Batch %s queued at position %d", batchID, pos)
+		return nil, pos, nil
+	}
+	p.mu.Unlock()
+
+	run, err := p.begin(batchID, trigger, triggeredBy, settings)
+	return run, 0, err
+}
+
+// startNext starts the first queued batch if the pool is idle.
+func (p *Pool) startNext() {
+	p.mu.Lock()
+	if p.closing || len(p.queue) == 0 || !p.running.CompareAndSwap(false, true) {
+		p.mu.Unlock()
+		return
+	}
+	next := p.queue[0]
+	p.queue = p.queue[1:]
+	p.mu.Unlock()
+
+	// A failed start calls finish, which moves on to the next batch.
+	if _, err := p.begin(next.batchID, next.trigger, next.triggeredBy, next.settings); err != nil {
+		log.Printf("[processor] Error starting queued batch %s: %v", next.batchID, err)
+	}
+}
+
+// QueuePosition returns the 1-based position of a queued batch; 1 runs next.
+func (p *Pool) QueuePosition(batchID uuid.UUID) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pos := p.position(batchID)
+	return pos, pos > 0
+}
+
+// Queued returns the queued batches in the order they will run.
+func (p *Pool) Queued() []uuid.UUID {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]uuid.UUID, len(p.queue))
+	for i, q := range p.queue {
+		ids[i] = q.batchID
+	}
+	return ids
+}
+
+// position returns the 1-based queue position of a batch, 0 if not queued.
+// p.mu must be held.
+func (p *Pool) position(batchID uuid.UUID) int {
+	for i, q := range p.queue {
+		if q.batchID == batchID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// dequeue takes a batch out of the queue. p.mu must be held.
+func (p *Pool) dequeue(batchID uuid.UUID) bool {
+	pos := p.position(batchID)
+	if pos == 0 {
+		return false
+	}
+	p.queue = append(p.queue[:pos-1], p.queue[pos:]...)
+	log.Printf("[processor] Batch %s taken out of the queue", batchID)
+	return true
+}
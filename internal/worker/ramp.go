@@ -0,0 +1,59 @@
+package worker
+
+import "time"
+
+// rampFailureThreshold is the share of transient failures (timeouts, rate
+// limits) in a chunk above which the ramp backs off.
+const rampFailureThreshold = 0.10
+
+// rampUp raises effective concurrency linearly from 1 to max over period so a
+// large batch doesn't hit a cold bank API with full concurrency at once. When a
+// chunk sees too many transient failures the level is halved and the ramp
+// continues upward from there.
+type rampUp struct {
+	max    int
+	period time.Duration
+	start  time.Time
+}
+
+func newRampUp(max int, period time.Duration, now time.Time) *rampUp {
+	return &rampUp{max: max, period: period, start: now}
+}
+
+// limit returns the concurrency to use at the given time.
+func (r *rampUp) limit(now time.Time) int {
+	if r.max <= 1 || r.period <= 0 {
+		return r.max
+	}
+	elapsed := now.Sub(r.start)
+	if elapsed >= r.period {
+		return r.max
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	level := 1 + int(float64(r.max-1)*float64(elapsed)/float64(r.period))
+	if level > r.max {
+		level = r.max
+	}
+	return level
+}
+
+// observe feeds back the outcome of a chunk. It returns true if the ramp backed off.
+func (r *rampUp) observe(now time.Time, attempts, transientFailures int) bool {
+	if attempts == 0 || r.max <= 1 || r.period <= 0 {
+		return false
+	}
+	if float64(transientFailures)/float64(attempts) <= rampFailureThreshold {
+		return false
+	}
+
+	level := r.limit(now) / 2
+	if level < 1 {
+		level = 1
+	}
+	// Shift the ramp so it passes through the halved level now.
+	progress := time.Duration(float64(r.period) * float64(level-1) / float64(r.max-1))
+	r.start = now.Add(-progress)
+	return true
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRampUpLinear verifies concurrency rises from 1 to max over the period.
+func TestRampUpLinear(t *testing.T) {
+	start := time.Now()
+	r := newRampUp(11, 10*time.Minute, start)
+
+	cases := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 1},
+		{5 * time.Minute, 6},
+		{10 * time.Minute, 11},
+		{time.Hour, 11},
+	}
+	for _, tc := range cases {
+		if got := r.limit(start.Add(tc.elapsed)); got != tc.want {
+			t.Errorf("limit after %s: expected %d, got %d", tc.elapsed, tc.want, got)
+		}
+	}
+}
+
+// TestRampUpBacksOffOnTransientFailures verifies a bad chunk halves the level.
+func TestRampUpBacksOffOnTransientFailures(t *testing.T) {
+	start := time.Now()
+	r := newRampUp(11, 10*time.Minute, start)
+	now := start.Add(10 * time.Minute)
+
+	if r.observe(now, 100, 5) {
+		t.Fatal("Expected no back-off at 5% transient failures")
+	}
+	if !r.observe(now, 100, 30) {
+		t.Fatal("Expected back-off at 30% transient failures")
+	}
+	if got := r.limit(now); got != 5 {
+		t.Errorf("Expected level 5 after back-off from 11, got %d", got)
+	}
+	if got := r.limit(now.Add(10 * time.Minute)); got != 11 {
+		t.Errorf("Expected ramp to recover to 11, got %d", got)
+	}
+}
+
+// TestRampUpDisabled verifies a zero period means full concurrency immediately.
+func TestRampUpDisabled(t *testing.T) {
+	r := newRampUp(8, 0, time.Now())
+	if got := r.limit(time.Now()); got != 8 {
+		t.Errorf("Expected 8, got %d", got)
+	}
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+)
+
+// resumeOperator is recorded as triggered_by on runs resumed on startup.
+const resumeOperator = "system"
+
+// ResumeInProgress starts or queues a run for every batch left in_progress,
+// e.g. by a crash, so nobody has to start them again by hand. As with any
+// run, payouts stuck in processing are reset first unless another instance
+// is still running the batch, in which case the run joins it. It returns
+// the number of batches started or queued; a batch that cannot be resumed
+// is logged and skipped.
+func (p *Pool) ResumeInProgress(ctx context.Context) (int, error) {
+	// Idle for at least nothing: every batch in progress.
+	batches, err := p.repo.FindStalledBatches(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	resumed := 0
+	for _, b := range batches {
+		run, pos, err := p.Enqueue(b.ID, models.RunTriggerAutoResume, resumeOperator)
+		if errors.Is(err, ErrBusy) {
+			continue // Already ours
+		}
+		if errors.Is(err, repository.ErrBatchLeased) {
+			continue // Another instance's
+		}
+		if err != nil {
+			log.Printf("[processor] Error resuming batch %s: %v", b.ID, err)
+			continue
+		}
+		if run != nil {
+			log.Printf("[processor] Resumed in-progress batch %s (run %s)", b.ID, run.ID)
+		} else {
+			log.Printf("[processor] Queued in-progress batch %s for resuming at position %d", b.ID, pos)
+		}
+		resumed++
+	}
+	return resumed, nil
+}
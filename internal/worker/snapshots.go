@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"coding-challenge/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SnapshotStore records batch statistics as they stand, for looking back at
+// them later. The repository implements it.
+type SnapshotStore interface {
+	SnapshotStatistics(ctx context.Context, batchID, runID uuid.UUID, reason string) error
+}
+
+// WithStatisticsSnapshots makes runs record their batch's statistics when
+// they start and end, and between chunks at most every interval.
+func WithStatisticsSnapshots(store SnapshotStore, every time.Duration) Option {
+	return func(p *Pool) { p.snapshots, p.snapshotEvery = store, every }
+}
+
+// snapshot records the run's batch statistics, if snapshots are in use. A
+// failed snapshot is logged; the run carries on.
+func (p *Pool) snapshot(ctx context.Context, run *models.BatchRun, reason string) {
+	if p.snapshots == nil {
+		return
+	}
+	if err := p.snapshots.SnapshotStatistics(ctx, run.BatchID, run.ID, reason); err != nil {
+		log.Printf("[processor] Warning: failed to snapshot statistics of batch %s: %v", run.BatchID, err)
+	}
+}
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"coding-challenge/internal/models"
+	"coding-challenge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Store is the storage the pool and the watchdog work against. The
+// repository implements it, and memstore.Store does so in memory for tests
+// that run without a database.
+type Store interface {
+	GetBatch(ctx context.Context, batchID uuid.UUID) (*models.PayoutBatch, error)
+	GetBatchStatistics(ctx context.Context, batchID uuid.UUID) (*models.BatchStatistics, error)
+	EachPayout(ctx context.Context, batchID uuid.UUID, fn func(models.Payout) error) error
+	UpdateBatchStatus(ctx context.Context, batchID uuid.UUID, status string) error
+	PauseBatch(ctx context.Context, batchID uuid.UUID) (bool, error)
+	RefreshBatchCounts(ctx context.Context, batchID uuid.UUID) error
+	FinalizeBatch(ctx context.Context, batchID uuid.UUID) (string, bool, error)
+	PinEnvironment(ctx context.Context, batchID uuid.UUID, environment string) error
+	ReserveFunding(ctx context.Context, batchID uuid.UUID) error
+
+	CreateRun(ctx context.Context, batchID uuid.UUID, trigger, triggeredBy, environment string, settings models.RunSettings) (*models.BatchRun, error)
+	FinishRun(ctx context.Context, run *models.BatchRun) error
+	LockRun(ctx context.Context, batchID uuid.UUID, recoverStuck func() error) (*repository.RunLock, error)
+	ResetStuckProcessing(ctx context.Context, batchID uuid.UUID) (int64, error)
+
+	ClaimChunk(ctx context.Context, batchID uuid.UUID, order string, limit int, inFlightCaps map[string]float64) ([]models.Payout, error)
+	ClaimChunkSpread(ctx context.Context, batchID uuid.UUID, order string, limit int, inFlightCaps map[string]float64, spread repository.ClaimSpread) ([]models.Payout, error)
+	HasClaimablePayouts(ctx context.Context, batchID uuid.UUID) (bool, error)
+	ReleaseClaims(ctx context.Context, payoutIDs []uuid.UUID) (int64, error)
+	LogAttempt(ctx context.Context, attempt *models.PayoutAttempt) error
+	CompletePayout(ctx context.Context, payoutID uuid.UUID) error
+	FailPayout(ctx context.Context, payoutID uuid.UUID, reason string) error
+	RequeuePayout(ctx context.Context, payoutID uuid.UUID, retryAt time.Time) error
+
+	FindStalledBatches(ctx context.Context, idleFor time.Duration) ([]models.PayoutBatch, error)
+	PauseStalledBatch(ctx context.Context, batchID uuid.UUID, idleFor time.Duration) (bool, int64, error)
+	FailStalledCreations(ctx context.Context, idleFor time.Duration) (int64, error)
+}
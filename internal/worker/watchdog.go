@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Watchdog periodically looks for batches stuck in_progress with no payout
+// activity (e.g. the process that owned them died), resets their stuck
+// payouts and parks them as paused so they can be resumed explicitly. Batches
+// whose asynchronous creation stopped making progress are failed.
+type Watchdog struct {
+	repo       Store
+	pool       *Pool
+	interval   time.Duration
+	stallAfter time.Duration
+}
+
+// NewWatchdog creates a watchdog that checks every interval for batches idle
+// for at least stallAfter. Batches this process's pool is working on are skipped.
+func NewWatchdog(repo Store, pool *Pool, interval, stallAfter time.Duration) *Watchdog {
+	return &Watchdog{
+		repo:       repo,
+		pool:       pool,
+		interval:   interval,
+		stallAfter: stallAfter,
+	}
+}
+
+// Run checks for stalled batches until ctx is cancelled, waiting interval
+// on the pool's clock between checks.
+func (w *Watchdog) Run(ctx context.Context) {
+	log.Printf("[watchdog] Checking every %s for batches idle longer than %s", w.interval, w.stallAfter)
+	for {
+		timer := w.pool.clock.NewTimer(w.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+			if _, err := w.Check(ctx); err != nil {
+				log.Printf("[watchdog] Check failed: %v", err)
+			}
+		}
+	}
+}
+
+// Check runs one detection pass and returns the number of batches paused.
+func (w *Watchdog) Check(ctx context.Context) (int, error) {
+	if failed, err := w.repo.FailStalledCreations(ctx, w.stallAfter); err != nil {
+		log.Printf("[watchdog] Error failing stalled batch creations: %v", err)
+	} else if failed > 0 {
+		log.Printf("[watchdog] ALERT: failed %d batches whose creation stalled", failed)
+	}
+
+	batches, err := w.repo.FindStalledBatches(ctx, w.stallAfter)
+	if err != nil {
+		return 0, err
+	}
+
+	paused := 0
+	for _, b := range batches {
+		if w.pool.Processing(b.ID) {
+			continue // Still ours and alive, just slow
+		}
+
+		// Re-checks idleness under the batch row lock, so a run that resumed
+		// the batch since FindStalledBatches never has its claims reset.
+		ok, reset, err := w.repo.PauseStalledBatch(ctx, b.ID, w.stallAfter)
+		if err != nil {
+			log.Printf("[watchdog] Error pausing batch %s: %v", b.ID, err)
+			continue
+		}
+		if !ok {
+			continue // Resumed, finished or active again in the meantime
+		}
+		_ = w.repo.RefreshBatchCounts(ctx, b.ID)
+
+		paused++
+		log.Printf("[watchdog] ALERT: batch %s stalled (no activity since %s); reset %d stuck payouts and paused it",
+			b.ID, b.UpdatedAt.Format(time.RFC3339), reset)
+	}
+	return paused, nil
+}
@@ -0,0 +1,5 @@
+-- Free-form vendor attributes (country, category, ...) used for segment statistics
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_payouts_metadata ON payouts USING GIN (metadata);
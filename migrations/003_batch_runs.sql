@@ -0,0 +1,17 @@
+-- One row per processing execution of a batch (start, resume, retry-failed)
+
+CREATE TABLE IF NOT EXISTS batch_runs (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    batch_id        UUID NOT NULL REFERENCES payout_batches(id),
+    trigger         VARCHAR(30) NOT NULL,
+    status          VARCHAR(20) NOT NULL DEFAULT 'running'
+                    CHECK (status IN ('running', 'finished', 'stopped', 'failed')),
+    processed_count INT NOT NULL DEFAULT 0,
+    completed_count INT NOT NULL DEFAULT 0,
+    failed_count    INT NOT NULL DEFAULT 0,
+    error           TEXT,
+    started_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_runs_batch_id ON batch_runs(batch_id, started_at);
@@ -0,0 +1,4 @@
+-- Chunk counts and operator attribution for processing runs
+
+ALTER TABLE batch_runs ADD COLUMN IF NOT EXISTS chunks_processed INT NOT NULL DEFAULT 0;
+ALTER TABLE batch_runs ADD COLUMN IF NOT EXISTS triggered_by VARCHAR(255) NOT NULL DEFAULT '';
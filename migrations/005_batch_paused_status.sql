@@ -0,0 +1,5 @@
+-- Batches whose processing stalled are parked in "paused" until resumed
+
+ALTER TABLE payout_batches DROP CONSTRAINT IF EXISTS payout_batches_status_check;
+ALTER TABLE payout_batches ADD CONSTRAINT payout_batches_status_check
+    CHECK (status IN ('pending', 'in_progress', 'paused', 'completed', 'failed', 'partially_completed'));
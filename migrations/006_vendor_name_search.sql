@@ -0,0 +1,5 @@
+-- Trigram index for fuzzy / search-as-you-type vendor name lookup
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_payouts_vendor_name_trgm ON payouts USING GIN (vendor_name gin_trgm_ops);
@@ -0,0 +1,4 @@
+-- Per-batch processing order for pending payouts
+
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS payout_order VARCHAR(30) NOT NULL DEFAULT 'fifo'
+    CHECK (payout_order IN ('fifo', 'largest_first', 'smallest_first', 'bank_round_robin'));
@@ -0,0 +1,5 @@
+-- Position of each payout in its batch request. All payouts of a batch share
+-- created_at, so this keeps FIFO order (and tie-breaks in the other orders)
+-- in the order payouts were submitted.
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS seq INT NOT NULL DEFAULT 0;
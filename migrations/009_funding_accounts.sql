@@ -0,0 +1,20 @@
+-- Funding accounts per currency, and the amounts each batch holds against them
+
+CREATE TABLE IF NOT EXISTS funding_accounts (
+    currency   VARCHAR(3) PRIMARY KEY,
+    balance    DECIMAL(15,2) NOT NULL DEFAULT 0,
+    reserved   DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (reserved <= balance)
+);
+
+-- held: reserved for the batch's pending/processing payouts
+-- debited: taken from the balance for its completed payouts
+CREATE TABLE IF NOT EXISTS funding_reservations (
+    batch_id   UUID NOT NULL REFERENCES payout_batches(id),
+    currency   VARCHAR(3) NOT NULL REFERENCES funding_accounts(currency),
+    held       DECIMAL(15,2) NOT NULL DEFAULT 0,
+    debited    DECIMAL(15,2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (batch_id, currency)
+);
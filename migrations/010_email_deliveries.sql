@@ -0,0 +1,16 @@
+-- One row per attempt to email a vendor about a payout
+
+CREATE TABLE IF NOT EXISTS email_deliveries (
+    id                  UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payout_id           UUID NOT NULL REFERENCES payouts(id),
+    template            VARCHAR(50) NOT NULL,
+    locale              VARCHAR(10) NOT NULL,
+    recipient           VARCHAR(255) NOT NULL,
+    provider            VARCHAR(30) NOT NULL,
+    status              VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'failed', 'dropped')),
+    provider_message_id VARCHAR(255),
+    error               TEXT,
+    attempted_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_deliveries_payout_id ON email_deliveries(payout_id);
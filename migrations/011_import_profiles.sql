@@ -0,0 +1,12 @@
+-- Column-mapping profiles for partner CSV imports
+
+CREATE TABLE IF NOT EXISTS import_profiles (
+    name              VARCHAR(100) PRIMARY KEY,
+    columns           JSONB NOT NULL DEFAULT '{}',
+    metadata          JSONB NOT NULL DEFAULT '{}',
+    defaults          JSONB NOT NULL DEFAULT '{}',
+    delimiter         VARCHAR(1) NOT NULL DEFAULT ',',
+    decimal_separator VARCHAR(1) NOT NULL DEFAULT '.' CHECK (decimal_separator IN ('.', ',')),
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
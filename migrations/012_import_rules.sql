@@ -0,0 +1,3 @@
+-- Per-profile validation rules applied during import
+
+ALTER TABLE import_profiles ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+-- Soft deletion of finished batches; deleted batches can be restored
+
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_payout_batches_created_at ON payout_batches(created_at DESC) WHERE deleted_at IS NULL;
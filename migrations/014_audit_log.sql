@@ -0,0 +1,31 @@
+-- Append-only, hash-chained audit log (written when AUDIT_STORE=postgres)
+
+CREATE SCHEMA IF NOT EXISTS audit;
+
+CREATE TABLE IF NOT EXISTS audit.records (
+    seq         BIGSERIAL PRIMARY KEY,
+    kind        VARCHAR(50) NOT NULL,
+    subject_id  UUID NOT NULL,
+    payload     JSON NOT NULL,  -- JSON, not JSONB: the stored text is what is hashed
+    recorded_at TIMESTAMPTZ NOT NULL,
+    prev_hash   CHAR(64) NOT NULL,
+    hash        CHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_records_subject ON audit.records(subject_id);
+
+CREATE OR REPLACE FUNCTION audit.reject_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit.records is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS records_no_update ON audit.records;
+CREATE TRIGGER records_no_update BEFORE UPDATE OR DELETE ON audit.records
+    FOR EACH ROW EXECUTE FUNCTION audit.reject_change();
+
+DROP TRIGGER IF EXISTS records_no_truncate ON audit.records;
+CREATE TRIGGER records_no_truncate BEFORE TRUNCATE ON audit.records
+    FOR EACH STATEMENT EXECUTE FUNCTION audit.reject_change();
+
+REVOKE UPDATE, DELETE, TRUNCATE ON audit.records FROM PUBLIC;
@@ -0,0 +1,11 @@
+-- Nonces of signed API requests, kept until their timestamp can no longer be
+-- accepted, so a captured request cannot be replayed
+
+CREATE TABLE IF NOT EXISTS request_nonces (
+    key_id     VARCHAR(100) NOT NULL,
+    nonce      VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_nonces_expires_at ON request_nonces(expires_at);
@@ -0,0 +1,17 @@
+-- Throughput and failure rollups per run, bank and currency, written when a
+-- run finishes. Batch estimates and ETAs read these instead of raw attempts.
+
+CREATE TABLE IF NOT EXISTS bank_throughput (
+    run_id          UUID NOT NULL REFERENCES batch_runs(id),
+    bank_name       VARCHAR(255) NOT NULL,
+    currency        VARCHAR(3) NOT NULL,
+    payouts         INT NOT NULL,              -- payouts that reached completed/failed in the run
+    failed_payouts  INT NOT NULL,
+    attempts        INT NOT NULL,              -- attempts made in the run, including retries
+    attempt_seconds DOUBLE PRECISION NOT NULL, -- summed attempt durations
+    run_seconds     DOUBLE PRECISION NOT NULL,
+    finished_at     TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (run_id, bank_name, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_throughput_finished_at ON bank_throughput(finished_at);
@@ -0,0 +1,7 @@
+-- Payouts split across several vendor accounts: each share is its own payout,
+-- linked to the others by the logical payout's split_group_id
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS split_group_id UUID;
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS split_percent NUMERIC(5,2);
+
+CREATE INDEX IF NOT EXISTS idx_payouts_split_group ON payouts(split_group_id) WHERE split_group_id IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Correction chains: a failed payout requeued into a new batch, possibly to a
+-- backup account, is linked to its replacement in both directions
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS supersedes UUID REFERENCES payouts(id);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS superseded_by UUID REFERENCES payouts(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payouts_supersedes ON payouts(supersedes) WHERE supersedes IS NOT NULL;
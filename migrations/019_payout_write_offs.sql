@@ -0,0 +1,19 @@
+-- Write-offs close out unrecoverable failed payouts: "written_off" is a
+-- terminal payout status, and each write-off records its reason and approver
+
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_status_check;
+ALTER TABLE payouts ADD CONSTRAINT payouts_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'written_off'));
+
+CREATE TABLE IF NOT EXISTS payout_write_offs (
+    payout_id      UUID PRIMARY KEY REFERENCES payouts(id),
+    reason_code    VARCHAR(30) NOT NULL,
+    note           TEXT,
+    requested_by   VARCHAR(100) NOT NULL,
+    approved_by    VARCHAR(100) NOT NULL,
+    amount         DECIMAL(15,2) NOT NULL,
+    currency       VARCHAR(3) NOT NULL,
+    written_off_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_payout_write_offs_at ON payout_write_offs(written_off_at);
@@ -0,0 +1,13 @@
+-- Outreach to vendors about failed payouts, e.g. asking for new bank details
+
+CREATE TABLE IF NOT EXISTS vendor_outreach (
+    id           UUID PRIMARY KEY,
+    payout_id    UUID NOT NULL REFERENCES payouts(id),
+    channel      VARCHAR(20) NOT NULL CHECK (channel IN ('email', 'phone', 'sms', 'chat', 'other')),
+    note         TEXT,
+    contacted_at TIMESTAMPTZ NOT NULL,
+    recorded_by  VARCHAR(100) NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vendor_outreach_payout ON vendor_outreach(payout_id, contacted_at);
@@ -0,0 +1,13 @@
+-- Operator actions on payouts: holds keep pending payouts from being
+-- processed, cancelled closes out a payout before it is sent, and tags label
+-- payouts for triage
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS held_at TIMESTAMPTZ;
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS held_by VARCHAR(100);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_status_check;
+ALTER TABLE payouts ADD CONSTRAINT payouts_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'written_off', 'cancelled'));
+
+CREATE INDEX IF NOT EXISTS idx_payouts_tags ON payouts USING GIN (tags);
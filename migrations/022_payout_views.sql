@@ -0,0 +1,11 @@
+-- Saved payout filters ("views") shared by the dashboard and payoutctl as
+-- triage queues
+
+CREATE TABLE IF NOT EXISTS payout_views (
+    name        VARCHAR(100) PRIMARY KEY,
+    description TEXT,
+    filter      JSONB NOT NULL DEFAULT '{}',
+    updated_by  VARCHAR(100) NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,12 @@
+-- Batch ownership: who created a batch and who is shepherding it now. Batch
+-- outcome emails to the assignee are recorded alongside vendor emails.
+
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS owner VARCHAR(100);
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS assigned_to VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_payout_batches_assigned_to ON payout_batches(assigned_to) WHERE deleted_at IS NULL;
+
+ALTER TABLE email_deliveries ALTER COLUMN payout_id DROP NOT NULL;
+ALTER TABLE email_deliveries ADD COLUMN IF NOT EXISTS batch_id UUID REFERENCES payout_batches(id);
+
+CREATE INDEX IF NOT EXISTS idx_email_deliveries_batch_id ON email_deliveries(batch_id);
@@ -0,0 +1,6 @@
+-- Bank environments: which environment (sandbox or production) executed a
+-- batch's payouts, pinned by its first run, and which one each run used.
+-- Runs from before this migration are left without one.
+
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS environment VARCHAR(20);
+ALTER TABLE batch_runs ADD COLUMN IF NOT EXISTS environment VARCHAR(20);
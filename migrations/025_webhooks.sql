@@ -0,0 +1,31 @@
+-- Webhook subscriptions: endpoints told about payout and batch events, and
+-- one row per attempt to deliver an event to one of them
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id                         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    url                        TEXT NOT NULL,
+    event_types                TEXT[] NOT NULL,
+    description                TEXT,
+    secret                     VARCHAR(100) NOT NULL,
+    -- Still signed with until it expires, so receivers can roll over
+    previous_secret            VARCHAR(100),
+    previous_secret_expires_at TIMESTAMPTZ,
+    active                     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by                 VARCHAR(100) NOT NULL,
+    created_at                 TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at                 TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id        UUID NOT NULL,
+    event_type      VARCHAR(50) NOT NULL,
+    status          VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'failed', 'dropped')),
+    response_code   INT,
+    error           TEXT,
+    duration_ms     INT NOT NULL DEFAULT 0,
+    attempted_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, attempted_at);
@@ -0,0 +1,6 @@
+-- Claimable payouts in fifo order. Claims, including the spread ones of
+-- WORKER_CLAIM_STRATEGY, read this instead of walking past the payouts of
+-- a batch that are already processed or on hold.
+
+CREATE INDEX IF NOT EXISTS idx_payouts_claimable ON payouts (batch_id, created_at, seq)
+    WHERE status = 'pending' AND held_at IS NULL;
@@ -0,0 +1,4 @@
+-- How often a run persists a batch's progress counts: 0 refreshes them once
+-- per chunk, N every N recorded payout attempts (1 is every payout).
+
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS progress_every INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- When a payout requeued after a retryable failure may be claimed again,
+-- so retries back off instead of hitting the bank again in the next chunk.
+-- NULL means it is claimable at once.
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMPTZ;
@@ -0,0 +1,34 @@
+-- Payment files: a batch's pending payouts written to a file for a bank's
+-- file-based channel (e.g. an SFTP drop), tracked from generation through
+-- delivery to the bank's acknowledgment of each payout. Filed payouts are
+-- held so runs never also send them through the bank API.
+
+CREATE TABLE IF NOT EXISTS payment_files (
+    id                  UUID PRIMARY KEY,
+    batch_id            UUID NOT NULL REFERENCES payout_batches(id),
+    filename            VARCHAR(100) NOT NULL,
+    status              VARCHAR(20) NOT NULL CHECK (status IN ('generated', 'delivered', 'acknowledged')),
+    payout_count        INT NOT NULL,
+    -- Fingerprint of the public key the file was encrypted to, if any
+    encrypted_to        VARCHAR(64),
+    -- SHA-256 of the bytes served, set once the file has been written out
+    checksum            VARCHAR(64),
+    generated_by        VARCHAR(100) NOT NULL,
+    generated_at        TIMESTAMPTZ NOT NULL,
+    delivered_by        VARCHAR(100),
+    delivered_at        TIMESTAMPTZ,
+    delivery_reference  TEXT,
+    acknowledged_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_files_batch ON payment_files(batch_id, generated_at);
+
+CREATE TABLE IF NOT EXISTS payment_file_items (
+    file_id     UUID NOT NULL REFERENCES payment_files(id),
+    payout_id   UUID NOT NULL REFERENCES payouts(id),
+    -- The bank's answer; NULL until acknowledged
+    result      VARCHAR(20) CHECK (result IN ('accepted', 'rejected')),
+    reason      TEXT,
+    answered_at TIMESTAMPTZ,
+    PRIMARY KEY (file_id, payout_id)
+);
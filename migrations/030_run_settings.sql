@@ -0,0 +1,6 @@
+-- The concurrency and starting chunk size each run processed with, which a
+-- start may set for its run instead of the server's. NULL for runs from
+-- before they were recorded.
+
+ALTER TABLE batch_runs ADD COLUMN IF NOT EXISTS concurrency INT;
+ALTER TABLE batch_runs ADD COLUMN IF NOT EXISTS chunk_size INT;
@@ -0,0 +1,32 @@
+-- Bank acknowledgment and return files (pain.002, NACHA returns) received
+-- for payment files, with what each answer did. A payout is found in a bank
+-- file by its reference: its ID as 32 hex digits, of which NACHA entries
+-- carry the first 15 in the individual identification number.
+
+ALTER TABLE payment_file_items ADD COLUMN IF NOT EXISTS reference VARCHAR(32);
+UPDATE payment_file_items SET reference = replace(payout_id::text, '-', '') WHERE reference IS NULL;
+ALTER TABLE payment_file_items ALTER COLUMN reference SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payment_file_items_reference ON payment_file_items (left(reference, 15));
+
+CREATE TABLE IF NOT EXISTS bank_files (
+    id               UUID PRIMARY KEY,
+    format           VARCHAR(20) NOT NULL,
+    -- The bank's identifier for the file (pain.002 MsgId, NACHA file header)
+    message_id       TEXT,
+    -- The payment file answered for as a whole, if the file says so
+    payment_file_id  UUID REFERENCES payment_files(id),
+    file_result      VARCHAR(20),
+    file_reason      TEXT,
+    -- SHA-256 of the file as uploaded; the same file is only applied once
+    checksum         VARCHAR(64) NOT NULL UNIQUE,
+    received_by      VARCHAR(100) NOT NULL,
+    received_at      TIMESTAMPTZ NOT NULL,
+    applied          INT NOT NULL,
+    already_answered INT NOT NULL,
+    conflicts        INT NOT NULL,
+    unmatched        INT NOT NULL,
+    pending          INT NOT NULL,
+    -- Every answer in the file and its outcome
+    answers          JSONB NOT NULL
+);
@@ -0,0 +1,10 @@
+-- Vendors' tax identifiers (NPWP, TIN, MST), required to report payouts
+-- above the tax thresholds. Stored as digits; formatted when read.
+
+CREATE TABLE IF NOT EXISTS vendor_tax_ids (
+    vendor_id  VARCHAR(100) PRIMARY KEY,
+    country    CHAR(2) NOT NULL,
+    tax_id     VARCHAR(20) NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+-- Payouts above a country's regulatory reporting threshold, flagged at
+-- creation, and the approval that lets them be sent when one is required.
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS reporting_country     CHAR(2);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS reporting_approved_by VARCHAR(100);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS reporting_approved_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_payouts_reporting ON payouts(reporting_country, created_at)
+    WHERE reporting_country IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Leases giving one server instance a batch at a time. The holder renews
+-- its lease while it processes the batch; once it expires, another
+-- instance may take the batch over.
+
+CREATE TABLE IF NOT EXISTS batch_leases (
+    batch_id     UUID PRIMARY KEY REFERENCES payout_batches(id) ON DELETE CASCADE,
+    holder       VARCHAR(100) NOT NULL,
+    acquired_at  TIMESTAMPTZ NOT NULL,
+    heartbeat_at TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_leases_expires ON batch_leases(expires_at);
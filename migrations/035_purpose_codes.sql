@@ -0,0 +1,4 @@
+-- The purpose-of-payment code sent to the bank with each payout, as the
+-- rail's country requires.
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS purpose_code VARCHAR(10);
@@ -0,0 +1,9 @@
+-- Which instance holds each payout in processing, and until when. Holders
+-- renew the lease while the transfer is in flight, so recovery only resets
+-- payouts whose holder stopped renewing.
+
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS claimed_by       VARCHAR(100);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_payouts_claim_lease ON payouts(batch_id, lease_expires_at)
+    WHERE status = 'processing';
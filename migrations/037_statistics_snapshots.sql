@@ -0,0 +1,14 @@
+-- Batch statistics recorded during runs, so the counts at any past moment
+-- (e.g. when an operator stopped a batch) can be looked up afterwards.
+
+CREATE TABLE IF NOT EXISTS batch_statistics_snapshots (
+    id           BIGSERIAL PRIMARY KEY,
+    batch_id     UUID NOT NULL REFERENCES payout_batches(id) ON DELETE CASCADE,
+    run_id       UUID REFERENCES batch_runs(id) ON DELETE SET NULL,
+    taken_at     TIMESTAMPTZ NOT NULL,
+    reason       VARCHAR(20) NOT NULL,
+    batch_status VARCHAR(20) NOT NULL,
+    statistics   JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_statistics_snapshots_batch ON batch_statistics_snapshots(batch_id, taken_at);
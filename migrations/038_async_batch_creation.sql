@@ -0,0 +1,10 @@
+-- Batches created asynchronously are "creating" while their payouts are
+-- ingested; ingested_count tracks the progress and creation_error says why
+-- an ingestion failed. Both stay NULL for batches created in one request.
+
+ALTER TABLE payout_batches DROP CONSTRAINT IF EXISTS payout_batches_status_check;
+ALTER TABLE payout_batches ADD CONSTRAINT payout_batches_status_check
+    CHECK (status IN ('creating', 'pending', 'in_progress', 'paused', 'completed', 'failed', 'partially_completed'));
+
+ALTER TABLE payout_batches ADD COLUMN ingested_count INTEGER;
+ALTER TABLE payout_batches ADD COLUMN creation_error TEXT;
@@ -0,0 +1,16 @@
+-- API usage metered per merchant (the request's signing key ID or
+-- X-Merchant header) and UTC day, for chargeback and billing. Batches
+-- record the merchant that created them, so payouts processed and amounts
+-- paid can be attributed too; merchant_id stays NULL for batches created
+-- without one.
+
+CREATE TABLE IF NOT EXISTS api_usage (
+    merchant_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    calls BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (merchant_id, day)
+);
+
+ALTER TABLE payout_batches ADD COLUMN merchant_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_payout_batches_merchant ON payout_batches (merchant_id) WHERE merchant_id IS NOT NULL;
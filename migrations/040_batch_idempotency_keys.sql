@@ -0,0 +1,10 @@
+-- A client retrying a batch creation after a timeout sends the same
+-- Idempotency-Key header, so the retry returns the batch the first request
+-- created instead of a duplicate. Keys are unique per merchant (batches
+-- created without one share a scope). request_hash fingerprints the
+-- request, so a key reused for a different batch can be refused.
+
+ALTER TABLE payout_batches ADD COLUMN idempotency_key TEXT;
+ALTER TABLE payout_batches ADD COLUMN request_hash TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payout_batches_idempotency_key
+    ON payout_batches (COALESCE(merchant_id, ''), idempotency_key) WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,7 @@
+-- A batch can carry its own retry policy (max attempts, backoff, the
+-- retryable failures actually retried), so one client can retry
+-- aggressively while another fails fast for manual review. NULL keeps the
+-- worker's settings. Max attempts is also written to each payout's
+-- max_retries when the payouts are created.
+
+ALTER TABLE payout_batches ADD COLUMN retry_policy JSONB;
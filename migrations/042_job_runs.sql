@@ -0,0 +1,15 @@
+-- Runs of the background jobs of the in-process scheduler, on schedule or
+-- triggered by hand, so their history survives restarts.
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id           UUID PRIMARY KEY,
+    job          VARCHAR(100) NOT NULL,
+    trigger      VARCHAR(20) NOT NULL,
+    triggered_by VARCHAR(100),
+    status       VARCHAR(20) NOT NULL,
+    error        TEXT,
+    started_at   TIMESTAMPTZ NOT NULL,
+    finished_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job ON job_runs (job, started_at DESC);
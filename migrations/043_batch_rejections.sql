@@ -0,0 +1,5 @@
+-- The payouts a partial-mode creation request left out of the batch it
+-- created, with why: counts per check and the rejected payouts by index in
+-- the request. NULL for batches created whole.
+
+ALTER TABLE payout_batches ADD COLUMN rejections JSONB;
@@ -0,0 +1,6 @@
+-- Batches can be cancelled: their pending payouts are cancelled and they are
+-- never processed again.
+
+ALTER TABLE payout_batches DROP CONSTRAINT IF EXISTS payout_batches_status_check;
+ALTER TABLE payout_batches ADD CONSTRAINT payout_batches_status_check
+    CHECK (status IN ('creating', 'pending', 'in_progress', 'paused', 'completed', 'failed', 'partially_completed', 'cancelled'));
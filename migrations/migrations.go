@@ -0,0 +1,36 @@
+// Package migrations embeds the SQL schema so it can be applied without psql.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Apply runs every migration against db in filename order. It is meant for
+// fresh databases (embedded dev/test instances); Docker and `make migrate`
+// apply the same files directly.
+func Apply(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return fmt.Errorf("list migrations: %w", err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		script, err := files.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("read migration %s: %w", name, err)
+		}
+		if _, err := db.ExecContext(ctx, string(script)); err != nil {
+			return fmt.Errorf("apply migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -14,13 +14,14 @@ import (
 )
 
 type PayoutItem struct {
-	VendorID       string   `json:"vendor_id"`
-	VendorName     string   `json:"vendor_name"`
-	Amount         float64  `json:"amount"`
-	Currency       string   `json:"currency"`
-	BankAccount    string   `json:"bank_account"`
-	BankName       string   `json:"bank_name"`
-	TransactionIDs []string `json:"transaction_ids"`
+	VendorID       string            `json:"vendor_id"`
+	VendorName     string            `json:"vendor_name"`
+	Amount         float64           `json:"amount"`
+	Currency       string            `json:"currency"`
+	BankAccount    string            `json:"bank_account"`
+	BankName       string            `json:"bank_name"`
+	TransactionIDs []string          `json:"transaction_ids"`
+	Metadata       map[string]string `json:"metadata"`
 }
 
 type CreateBatchReq struct {
@@ -107,7 +108,7 @@ func createBatch(baseURL string, count, batchNum int) string {
 		var amount float64
 		switch currency {
 		case "IDR":
-			amount = float64(50000+rand.Intn(9950000)) // 50K - 10M IDR
+			amount = float64(50000 + rand.Intn(9950000)) // 50K - 10M IDR
 		case "PHP":
 			amount = float64(500+rand.Intn(49500)) + float64(rand.Intn(100))/100.0
 		case "VND":
@@ -122,6 +123,7 @@ func createBatch(baseURL string, count, batchNum int) string {
 			BankAccount:    fmt.Sprintf("%s****%04d", region, rand.Intn(10000)),
 			BankName:       bankList[rand.Intn(len(bankList))],
 			TransactionIDs: txnIDs,
+			Metadata:       map[string]string{"country": region, "category": category},
 		}
 	}
 